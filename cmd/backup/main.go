@@ -0,0 +1,127 @@
+// backup 备份和恢复 TaskFlow 的 SQLite 数据库，数据库路径按服务的配置（TASKFLOW_DB_PATH、sharding.shards）确定：
+//
+//	go run ./cmd/backup create -o taskflow.db.bak               # 文件快照，服务运行时也可以执行
+//	go run ./cmd/backup create -format json -o taskflow.jsonl   # 逻辑导出
+//	go run ./cmd/backup restore -i taskflow.jsonl               # 恢复为新的数据库，目标已存在时拒绝
+//
+// 启用分片时用 -shard 指定分片的命名空间，每个分片单独备份和恢复
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"taskflow/internal/config"
+	"taskflow/internal/repository"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cfg := config.LoadConfig()
+	if cfg.Server.Storage != "sqlite" {
+		log.Fatalf("备份只支持 sqlite 存储，当前为 %s", cfg.Server.Storage)
+	}
+
+	switch os.Args[1] {
+	case "create":
+		fs := flag.NewFlagSet("create", flag.ExitOnError)
+		format := fs.String("format", "sqlite", "备份格式：sqlite（文件快照）或 json（逻辑导出）")
+		out := fs.String("o", "", "输出文件，json 格式为空时输出到标准输出")
+		shard := fs.String("shard", "", "分片的命名空间，为空时备份主库")
+		fs.Parse(os.Args[2:])
+		create(dbPath(cfg, *shard), *format, *out)
+	case "restore":
+		fs := flag.NewFlagSet("restore", flag.ExitOnError)
+		in := fs.String("i", "", "备份文件，为空时从标准输入读取")
+		shard := fs.String("shard", "", "分片的命名空间，为空时恢复主库")
+		fs.Parse(os.Args[2:])
+		restore(dbPath(cfg, *shard), *in)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: backup create [-format sqlite|json] [-o FILE] [-shard NAMESPACE]")
+	fmt.Fprintln(os.Stderr, "       backup restore [-i FILE] [-shard NAMESPACE]")
+	os.Exit(2)
+}
+
+// dbPath 主库或分片的数据库路径
+func dbPath(cfg *config.Config, shard string) string {
+	if shard == "" {
+		return config.ExpandPath(cfg.Server.DBPath)
+	}
+	for _, s := range cfg.Sharding.Shards {
+		if s.Namespace == shard {
+			return config.ExpandPath(s.DBPath)
+		}
+	}
+	log.Fatalf("未配置分片 %s", shard)
+	return ""
+}
+
+func create(path, format, out string) {
+	if _, err := os.Stat(path); err != nil {
+		log.Fatalf("数据库 %s 不可用: %v", path, err)
+	}
+	db, err := repository.NewSQLite(path)
+	if err != nil {
+		log.Fatalf("打开 %s 失败: %v", path, err)
+	}
+	defer db.Close()
+
+	switch format {
+	case "sqlite":
+		if out == "" {
+			log.Fatalf("sqlite 格式须用 -o 指定输出文件")
+		}
+		if err := db.Snapshot(out); err != nil {
+			log.Fatalf("备份失败: %v", err)
+		}
+		log.Printf("已备份 %s 到 %s", path, out)
+	case "json":
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+			if err != nil {
+				log.Fatalf("创建 %s 失败: %v", out, err)
+			}
+			defer f.Close()
+			w = f
+		}
+		header, err := db.Export(w)
+		if err != nil {
+			log.Fatalf("导出失败: %v", err)
+		}
+		log.Printf("已导出 %s（表结构版本 %d）", path, header.SchemaVersion)
+	default:
+		log.Fatalf("未知的备份格式 %s", format)
+	}
+}
+
+func restore(path, in string) {
+	var r io.Reader = os.Stdin
+	if in != "" {
+		f, err := os.Open(in)
+		if err != nil {
+			log.Fatalf("打开 %s 失败: %v", in, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Fatalf("创建数据库目录失败: %v", err)
+	}
+	header, err := repository.Restore(path, r)
+	if err != nil {
+		log.Fatalf("恢复失败: %v", err)
+	}
+	log.Printf("已恢复到 %s（备份的表结构版本 %d，已迁移到当前版本）", path, header.SchemaVersion)
+}
@@ -0,0 +1,340 @@
+// loadgen 按固定速率向运行中的服务创建任务，统计调度延迟和数据库吞吐，用于发现调度器和仓储的性能回退：
+//
+//	go run ./cmd/loadgen -addr localhost:8080 -metrics http://localhost:8090/metrics -rate 100 -duration 30s
+//
+// 任务须由共用同一数据库的调度器执行 -type 类型（如嵌入 engine 的进程）；加 -complete 时由 loadgen
+// 通过 UpdateTask 把任务依次改为 RUNNING、SUCCEEDED，不依赖调度器，只压测 API 和仓储。
+// 延迟按 loadgen 本地时钟计算：从发出 CreateTask 到从 StreamChanges 收到对应状态
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	pb "taskflow/proto"
+)
+
+// queryCountMetric 仓储查询计数，两次抓取之差即期间的数据库操作数
+const queryCountMetric = "taskflow_repository_query_duration_seconds_count"
+
+// options 命令行参数
+type options struct {
+	addr     string
+	metrics  string
+	token    string
+	taskType string
+	rate     float64
+	duration time.Duration
+	wait     time.Duration
+	workers  int
+	complete bool
+}
+
+// run 一次压测的状态
+type run struct {
+	opts   options
+	client pb.TaskServiceClient
+	prefix string // 本次创建的任务名前缀
+
+	mu       sync.Mutex
+	sentAt   map[string]time.Time // 任务名 -> 发出 CreateTask 的时间
+	running  map[string]bool
+	created  int
+	finished int
+	failed   int // 创建失败或以非 SUCCEEDED 结束
+	create   []time.Duration
+	schedule []time.Duration
+	total    []time.Duration
+	done     chan struct{} // 所有已创建的任务结束时关闭
+	closed   bool
+	sending  bool
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.addr, "addr", "localhost:8080", "gRPC 地址")
+	flag.StringVar(&opts.metrics, "metrics", "http://localhost:8090/metrics", "Prometheus 指标地址，用于统计数据库吞吐，为空时不统计")
+	flag.StringVar(&opts.token, "token", "", "Bearer 令牌，服务启用认证时需要")
+	flag.StringVar(&opts.taskType, "type", "loadgen", "创建的任务类型")
+	flag.Float64Var(&opts.rate, "rate", 50, "每秒创建的任务数")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "持续创建任务的时长")
+	flag.DurationVar(&opts.wait, "wait", 30*time.Second, "停止创建后等待任务结束的最长时间")
+	flag.IntVar(&opts.workers, "workers", 16, "并发请求数")
+	flag.BoolVar(&opts.complete, "complete", false, "由 loadgen 通过 UpdateTask 完成任务，不依赖调度器")
+	flag.Parse()
+
+	if opts.rate <= 0 || opts.workers <= 0 {
+		log.Fatal("-rate 和 -workers 必须大于 0")
+	}
+
+	conn, err := grpc.NewClient(opts.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	r := &run{
+		opts:    opts,
+		client:  pb.NewTaskServiceClient(conn),
+		prefix:  "loadgen-" + uuid.NewString()[:8] + "-",
+		sentAt:  make(map[string]time.Time),
+		running: make(map[string]bool),
+		done:    make(chan struct{}),
+	}
+	if err := r.execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// execute 创建任务、等待结束并输出报告
+func (r *run) execute() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if r.opts.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+r.opts.token)
+	}
+
+	// 从当前游标开始跟踪变更，只关心本次创建的任务
+	snapshot, err := r.client.SnapshotTasks(ctx, &pb.SnapshotTasksRequest{PageSize: 1})
+	if err != nil {
+		return fmt.Errorf("获取变更游标失败: %w", err)
+	}
+	stream, err := r.client.StreamChanges(ctx, &pb.StreamChangesRequest{SinceSeq: snapshot.Cursor})
+	if err != nil {
+		return fmt.Errorf("订阅变更失败: %w", err)
+	}
+	go r.follow(stream)
+
+	queriesBefore, err := scrapeQueryCount(r.opts.metrics)
+	if err != nil {
+		log.Printf("读取指标失败，不统计数据库吞吐: %v", err)
+	}
+	start := time.Now()
+
+	r.produce(ctx)
+	produced := time.Since(start)
+
+	select {
+	case <-r.done:
+	case <-time.After(r.opts.wait):
+		log.Printf("等待 %s 后仍有任务未结束", r.opts.wait)
+	}
+	elapsed := time.Since(start)
+
+	queriesAfter, scrapeErr := scrapeQueryCount(r.opts.metrics)
+	r.report(produced, elapsed)
+	if err == nil && scrapeErr == nil && r.opts.metrics != "" {
+		queries := queriesAfter - queriesBefore
+		fmt.Printf("数据库操作: %.0f 次，%.1f 次/秒\n", queries, queries/elapsed.Seconds())
+	}
+	return nil
+}
+
+// produce 在 duration 内按 rate 创建任务，-complete 时随后完成任务
+func (r *run) produce(ctx context.Context) {
+	r.mu.Lock()
+	r.sending = true
+	r.mu.Unlock()
+
+	sem := make(chan struct{}, r.opts.workers)
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / r.opts.rate))
+	defer ticker.Stop()
+	deadline := time.After(r.opts.duration)
+
+	for i := 0; ; i++ {
+		select {
+		case <-deadline:
+			wg.Wait()
+			r.mu.Lock()
+			r.sending = false
+			r.checkDone()
+			r.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			// 并发请求已满，跳过本次以免积压，报告中的实际速率会低于目标速率
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.createTask(ctx, name)
+		}(r.prefix + strconv.Itoa(i))
+	}
+}
+
+// createTask 创建一个任务，-complete 时依次改为 RUNNING、SUCCEEDED
+func (r *run) createTask(ctx context.Context, name string) {
+	sent := time.Now()
+	r.mu.Lock()
+	r.sentAt[name] = sent
+	r.mu.Unlock()
+
+	task, err := r.client.CreateTask(ctx, &pb.CreateTaskRequest{
+		Name:     name,
+		TaskType: r.opts.taskType,
+		Priority: pb.TaskPriority_TASK_PRIORITY_NORMAL,
+	})
+	r.mu.Lock()
+	if err != nil {
+		delete(r.sentAt, name)
+		r.failed++
+		r.mu.Unlock()
+		log.Printf("创建任务失败: %v", err)
+		return
+	}
+	r.created++
+	r.create = append(r.create, time.Since(sent))
+	r.mu.Unlock()
+
+	if !r.opts.complete {
+		return
+	}
+	for _, status := range []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_RUNNING, pb.TaskStatus_TASK_STATUS_SUCCEEDED} {
+		if _, err := r.client.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: task.Id, Status: status}); err != nil {
+			log.Printf("更新任务 %s 为 %s 失败: %v", task.Id, status, err)
+			return
+		}
+	}
+}
+
+// follow 记录本次任务进入 RUNNING 和结束的时间
+func (r *run) follow(stream pb.TaskService_StreamChangesClient) {
+	for {
+		change, err := stream.Recv()
+		if err != nil {
+			return
+		}
+		name := change.GetTask().GetName()
+		if !strings.HasPrefix(name, r.prefix) {
+			continue
+		}
+		now := time.Now()
+
+		r.mu.Lock()
+		sent, ok := r.sentAt[name]
+		if ok {
+			switch status := change.GetEvent().GetToStatus(); {
+			case status == pb.TaskStatus_TASK_STATUS_RUNNING && !r.running[name]:
+				r.running[name] = true
+				r.schedule = append(r.schedule, now.Sub(sent))
+			case isTerminal(status):
+				delete(r.sentAt, name)
+				delete(r.running, name)
+				r.finished++
+				r.total = append(r.total, now.Sub(sent))
+				if status != pb.TaskStatus_TASK_STATUS_SUCCEEDED {
+					r.failed++
+				}
+				r.checkDone()
+			}
+		}
+		r.mu.Unlock()
+	}
+}
+
+// checkDone 停止创建且所有任务都已结束时通知等待方，须持有 r.mu
+func (r *run) checkDone() {
+	if !r.sending && len(r.sentAt) == 0 && !r.closed {
+		r.closed = true
+		close(r.done)
+	}
+}
+
+// report 输出吞吐和延迟分位数
+func (r *run) report(produced, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fmt.Printf("创建任务: %d（目标 %.1f/秒，实际 %.1f/秒）\n", r.created, r.opts.rate, float64(r.created)/produced.Seconds())
+	fmt.Printf("结束任务: %d（%.1f/秒），失败 %d，未结束 %d\n",
+		r.finished, float64(r.finished)/elapsed.Seconds(), r.failed, len(r.sentAt))
+	printLatency("CreateTask 延迟", r.create)
+	printLatency("调度延迟（创建到 RUNNING）", r.schedule)
+	printLatency("完成延迟（创建到结束）", r.total)
+}
+
+// printLatency 输出 P50/P99
+func printLatency(label string, samples []time.Duration) {
+	if len(samples) == 0 {
+		fmt.Printf("%s: 无样本\n", label)
+		return
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	fmt.Printf("%s: P50 %s，P99 %s，最大 %s（%d 个样本）\n", label,
+		percentile(sorted, 0.50), percentile(sorted, 0.99), sorted[len(sorted)-1].Round(time.Microsecond), len(sorted))
+}
+
+// percentile 已排序样本的分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(p*float64(len(sorted)) + 0.5)
+	if i > 0 {
+		i--
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i].Round(time.Microsecond)
+}
+
+// isTerminal 是否为终态
+func isTerminal(status pb.TaskStatus) bool {
+	switch status {
+	case pb.TaskStatus_TASK_STATUS_SUCCEEDED, pb.TaskStatus_TASK_STATUS_FAILED, pb.TaskStatus_TASK_STATUS_CANCELLED,
+		pb.TaskStatus_TASK_STATUS_TIMEOUT, pb.TaskStatus_TASK_STATUS_SKIPPED:
+		return true
+	}
+	return false
+}
+
+// scrapeQueryCount 读取服务端累计的仓储查询次数
+func scrapeQueryCount(url string) (float64, error) {
+	if url == "" {
+		return 0, nil
+	}
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	var total float64
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, queryCountMetric) {
+			continue
+		}
+		fields := strings.Fields(line)
+		v, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse %q: %w", line, err)
+		}
+		total += v
+	}
+	return total, scanner.Err()
+}
@@ -0,0 +1,34 @@
+// openapi 输出 HTTP API 的 OpenAPI 文档，供客户端生成 SDK：
+//
+//	go run ./cmd/openapi -o openapi.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"taskflow/internal/config"
+	"taskflow/internal/server"
+)
+
+func main() {
+	out := flag.String("o", "", "输出文件，默认标准输出")
+	flag.Parse()
+
+	doc := server.NewServer(config.LoadConfig()).OpenAPI()
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatalf("生成 OpenAPI 文档失败: %v", err)
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("写入 %s 失败: %v", *out, err)
+	}
+}
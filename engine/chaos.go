@@ -0,0 +1,61 @@
+//go:build chaos
+
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"taskflow/internal/executor"
+)
+
+// ChaosTaskType 故障注入构建（-tags chaos）中注册的测试任务类型，执行时按 ChaosFaults 注入延迟、panic 或失败，
+// 用于端到端验证重试、失败处理和告警。生产构建不包含该任务类型和管理接口
+const ChaosTaskType = "chaos"
+
+// ChaosFaults 故障注入参数
+type ChaosFaults = executor.ChaosFaults
+
+// setupChaos 注册故障注入任务类型，插件目录和 RegisterExecutor 可以覆盖
+func (e *Engine) setupChaos() {
+	e.chaos = executor.NewChaos(nil)
+	e.svc.RegisterExecutor(ChaosTaskType, e.chaos)
+}
+
+// ChaosFaults 当前的故障注入参数
+func (e *Engine) ChaosFaults() ChaosFaults {
+	return e.chaos.Faults()
+}
+
+// SetChaosFaults 调整故障注入参数，对之后开始执行的 chaos 任务生效
+func (e *Engine) SetChaosFaults(f ChaosFaults) error {
+	return e.chaos.SetFaults(f)
+}
+
+// ChaosHandler 故障注入的管理接口，由宿主挂载到受保护的管理端口：
+// GET 返回当前参数，PUT 以 JSON 请求体替换参数，DELETE 清除所有故障
+func (e *Engine) ChaosHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var f ChaosFaults
+			if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+				http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := e.SetChaosFaults(f); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			e.SetChaosFaults(ChaosFaults{})
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(e.ChaosFaults())
+	})
+}
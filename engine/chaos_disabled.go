@@ -0,0 +1,6 @@
+//go:build !chaos
+
+package engine
+
+// setupChaos 生产构建不包含故障注入
+func (e *Engine) setupChaos() {}
@@ -0,0 +1,62 @@
+//go:build chaos
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngine_ChaosHandler(t *testing.T) {
+	eng, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer eng.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := eng.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	admin := httptest.NewServer(eng.ChaosHandler())
+	defer admin.Close()
+	do := func(method, body string) (int, ChaosFaults) {
+		req, _ := http.NewRequest(method, admin.URL, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		defer resp.Body.Close()
+		var f ChaosFaults
+		json.NewDecoder(resp.Body).Decode(&f)
+		return resp.StatusCode, f
+	}
+
+	if code, _ := do(http.MethodPut, `{"failure_rate": 2}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid faults, got %d", code)
+	}
+	code, f := do(http.MethodPut, `{"failure_rate": 1, "error_class": "fatal"}`)
+	if code != http.StatusOK || f.FailureRate != 1 || f.ErrorClass != "fatal" {
+		t.Fatalf("PUT = %d %+v", code, f)
+	}
+
+	failed, _ := eng.Submit(ctx, TaskSpec{Name: "doomed", Type: ChaosTaskType, MaxRetries: 3})
+	if done, err := eng.Wait(ctx, failed.ID); err != nil || done.Status != StatusFailed || done.RetryCount != 0 {
+		t.Fatalf("expected injected fatal failure without retries, got %+v, %v", done, err)
+	}
+
+	if code, f := do(http.MethodDelete, ""); code != http.StatusOK || f != (ChaosFaults{}) {
+		t.Fatalf("DELETE = %d %+v", code, f)
+	}
+	ok, _ := eng.Submit(ctx, TaskSpec{Name: "healthy", Type: ChaosTaskType})
+	if done, err := eng.Wait(ctx, ok.ID); err != nil || done.Status != StatusSucceeded {
+		t.Fatalf("expected chaos task to succeed without faults, got %+v, %v", done, err)
+	}
+}
@@ -0,0 +1,433 @@
+// Package engine 以 Go 库的形式嵌入 taskflow：在宿主进程内运行仓储、调度器和任务服务，
+// 不启动 gRPC/HTTP 服务。
+//
+//	eng, err := engine.New(engine.Options{DSN: "tasks.db"})
+//	eng.RegisterExecutor("email", engine.ExecutorFunc(sendEmail))
+//	eng.Start(ctx)
+//	defer eng.Stop()
+//	task, err := eng.Submit(ctx, engine.TaskSpec{Name: "welcome", Type: "email"})
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/executor"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+)
+
+// 对外暴露的任务模型和执行器类型
+type (
+	Task             = model.Task
+	TaskStatus       = model.TaskStatus
+	TaskPriority     = model.TaskPriority
+	RetryPolicy      = model.RetryPolicy
+	Executor         = service.Executor
+	ExecutorFunc     = service.ExecutorFunc
+	ExecutionContext = service.ExecutionContext
+
+	// SchedulingDecision 调度器对任务的只读评估，列出任务暂不能调度的原因，见 Engine.GetSchedulingDecision
+	SchedulingDecision = model.SchedulingDecision
+	SchedulingReason   = model.SchedulingReason
+
+	// SubprocessExecutor 以子进程运行插件程序的执行器，协议见 internal/executor
+	SubprocessExecutor = executor.Subprocess
+	// WASMExecutor 在 WASM 沙箱中运行用户模块的执行器
+	WASMExecutor = executor.WASM
+	// DockerExecutor 按任务参数启动容器的执行器
+	DockerExecutor = executor.Docker
+	// KubernetesExecutor 为每个任务创建 Kubernetes Job 的执行器
+	KubernetesExecutor = executor.Kubernetes
+	// SQLExecutor 在外部数据库上执行参数化 SQL 的执行器
+	SQLExecutor   = executor.SQL
+	SQLConnection = executor.SQLConnection
+	// SecretResolver 按名称解析密钥明文，用于 SQL 连接的 DSN
+	SecretResolver = executor.SecretResolver
+	// GRPCExecutor 通过 server reflection 调用任意 gRPC 一元方法的执行器
+	GRPCExecutor = executor.GRPC
+
+	// Clock 调度使用的时钟，见 Options.Clock
+	Clock = clock.Clock
+	// FakeClock 手动推进的时钟，用于在测试中确定性地驱动重试退避和轮询
+	FakeClock = clock.Fake
+)
+
+// 任务状态
+const (
+	StatusPending      = model.TaskStatusPending
+	StatusRunning      = model.TaskStatusRunning
+	StatusSucceeded    = model.TaskStatusSucceeded
+	StatusFailed       = model.TaskStatusFailed
+	StatusCancelled    = model.TaskStatusCancelled
+	StatusTimeout      = model.TaskStatusTimeout
+	StatusSkipped      = model.TaskStatusSkipped
+	StatusWaitingInput = model.TaskStatusWaitingInput
+)
+
+// 任务优先级
+const (
+	PriorityLow    = model.TaskPriorityLow
+	PriorityNormal = model.TaskPriorityNormal
+	PriorityHigh   = model.TaskPriorityHigh
+	PriorityUrgent = model.TaskPriorityUrgent
+)
+
+// NewSubprocessExecutor 创建子进程执行器，插件可以用任意语言编写
+func NewSubprocessExecutor(command string, args ...string) *SubprocessExecutor {
+	return executor.NewSubprocess(command, args...)
+}
+
+// NewWASMExecutor 创建 WASM 沙箱执行器，模块无文件系统和网络访问权限
+func NewWASMExecutor(module string) *WASMExecutor {
+	return executor.NewWASM(module)
+}
+
+// NewDockerExecutor 创建容器执行器，host 为空时使用 DOCKER_HOST 或本机 Docker 套接字
+func NewDockerExecutor(host string) (*DockerExecutor, error) {
+	return executor.NewDocker(host)
+}
+
+// NewKubernetesExecutor 使用集群内的 ServiceAccount 凭据创建 Kubernetes Job 执行器
+func NewKubernetesExecutor() (*KubernetesExecutor, error) {
+	return executor.NewKubernetesInCluster()
+}
+
+// NewSQLExecutor 创建 SQL 执行器，secrets 解析各连接的 DSNSecret
+func NewSQLExecutor(secrets SecretResolver, connections ...SQLConnection) (*SQLExecutor, error) {
+	return executor.NewSQL(secrets, connections...)
+}
+
+// NewGRPCExecutor 创建 gRPC 调用执行器，只允许调用 allowedTargets 中的地址（"*" 表示不限制）
+func NewGRPCExecutor(allowedTargets ...string) *GRPCExecutor {
+	return executor.NewGRPC(allowedTargets...)
+}
+
+// NewFakeClock 创建从 now 开始、只在 Advance 或 Set 时前进的时钟
+func NewFakeClock(now time.Time) *FakeClock {
+	return clock.NewFake(now)
+}
+
+// ExecutionContextFrom 执行器中获取执行上下文，用于写入任务日志、上报进度和心跳、读取密钥和提交子任务
+func ExecutionContextFrom(ctx context.Context) *ExecutionContext {
+	return service.ExecutionContextFrom(ctx)
+}
+
+// WaitForInput 执行器返回它以挂起任务（WAITING_INPUT），等待宿主通过 Engine.Signal 送达 prompt 描述的输入；
+// 任务随后再次执行，执行器通过执行上下文的 Input 读取送达的输入
+func WaitForInput(prompt string) error {
+	return service.WaitForInput(prompt)
+}
+
+var (
+	// ErrStopped 引擎已停止
+	ErrStopped = errors.New("engine has been stopped")
+	// ErrNotRunning 引擎未启动，Pause、Resume 返回
+	ErrNotRunning = errors.New("engine is not running")
+	// ErrTaskNotFound 任务不存在，Get、Wait、Cancel 返回的错误均可用 errors.Is 判断
+	ErrTaskNotFound = repository.ErrTaskNotFound
+	// ErrStatusMismatch 任务状态已变化，执行上下文的 Progress、Heartbeat 在任务不再运行（如已被取消）时返回
+	ErrStatusMismatch = repository.ErrStatusMismatch
+	// ErrNoScheduler 执行器不是由引擎调用时，执行上下文的 Secret、SubmitChild 返回
+	ErrNoScheduler = service.ErrNoScheduler
+)
+
+// Options 引擎配置
+type Options struct {
+	DSN          string        // SQLite DSN，如 "tasks.db?_journal_mode=WAL"；为空时使用内存存储，进程退出后任务丢失
+	Workers      int           // 同时执行的任务数，默认 10
+	PollInterval time.Duration // 兜底轮询间隔，默认 5s；新提交和依赖完成的任务会立即唤醒调度
+
+	PluginDir     string        // 插件目录，其中每个可执行文件和 .wasm 模块按文件名（去掉扩展名）注册为同名任务类型的执行器
+	PluginTimeout time.Duration // 插件单次执行超时，0 表示不限制
+
+	WASMMaxMemory int64  // .wasm 插件的内存上限（字节），默认 64MiB
+	WASMFuel      uint64 // .wasm 插件的函数调用预算，0 表示不限制
+
+	Clock Clock // 轮询、重试退避和任务时间戳使用的时钟，默认系统时间；测试中可传入 NewFakeClock
+
+	CheckpointName string // 停止时以该名称保存调度器运行状态（工作池大小、调度计数），以相同名称启动时恢复；为空时不保存
+
+	WorkerLabels map[string]string // 本实例的标签，声明了 TaskSpec.NodeSelector 的任务只由标签包含其全部键值的实例执行
+
+	PriorityInheritance TaskPriority // 不低于该优先级的任务等待上游时，临时把优先级更低的上游提升到与之相同；为空时不启用
+}
+
+// TaskSpec 提交任务的参数
+type TaskSpec struct {
+	Name          string
+	Description   string
+	Type          string // 按 RegisterExecutor 注册的任务类型选择执行器
+	Priority      TaskPriority
+	Params        map[string]string
+	Dependencies  []string // 上游任务 ID，全部成功后才调度
+	MaxRetries    int32
+	RetryPolicy   *RetryPolicy
+	GroupKey      string // 分组键相同的任务串行执行
+	ResourceSlots int32
+	CreatedBy     string
+	NodeSelector  map[string]string // 只由 Options.WorkerLabels 包含全部键值的引擎实例执行
+	TargetWorker  string            // 只由实例 ID 或主机名与之相同的引擎实例执行
+}
+
+// Engine 嵌入式任务引擎，启动、停止各只能调用一次
+type Engine struct {
+	svc   *service.TaskService
+	close func() error
+	chaos *executor.Chaos // 仅故障注入构建中创建
+
+	mu      sync.Mutex
+	running bool
+	stopped bool
+	changed chan struct{} // 任务状态变更时关闭并替换，唤醒 Wait
+}
+
+// New 打开存储并创建引擎，执行器需在 Start 之前注册
+func New(opts Options) (*Engine, error) {
+	e := &Engine{changed: make(chan struct{})}
+
+	var plugins []executor.Plugin
+	if opts.PluginDir != "" {
+		var err error
+		plugins, err = executor.LoadDir(opts.PluginDir, executor.LoadOptions{
+			Timeout:       opts.PluginTimeout,
+			WASMMaxMemory: opts.WASMMaxMemory,
+			WASMFuel:      opts.WASMFuel,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var store repository.TaskStore
+	if opts.DSN == "" {
+		store, _ = repository.NewMemoryRepositories()
+		e.close = func() error { return nil }
+	} else {
+		db, err := repository.NewSQLite(opts.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		if _, err := db.Migrate(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+		store = repository.NewTaskRepository(db)
+		e.close = db.Close
+	}
+
+	e.svc = service.NewTaskService(store)
+	e.setupChaos()
+	for _, p := range plugins {
+		e.svc.RegisterExecutor(p.TaskType, p.Executor)
+	}
+	scheduler := e.svc.Scheduler()
+	if opts.Workers > 0 {
+		scheduler.SetWorkerCount(opts.Workers)
+	}
+	if opts.PollInterval > 0 {
+		scheduler.SetPollingInterval(opts.PollInterval)
+	}
+	if opts.Clock != nil {
+		scheduler.SetClock(opts.Clock)
+	}
+	if opts.CheckpointName != "" {
+		scheduler.SetCheckpointName(opts.CheckpointName)
+	}
+	if err := scheduler.SetWorkerLabels(opts.WorkerLabels); err != nil {
+		e.close()
+		return nil, fmt.Errorf("invalid worker labels: %w", err)
+	}
+	if err := scheduler.SetPriorityInheritance(opts.PriorityInheritance); err != nil {
+		e.close()
+		return nil, fmt.Errorf("invalid priority inheritance: %w", err)
+	}
+	scheduler.OnTaskChange(func(*model.Task, model.TaskStatus, model.TaskStatus) {
+		e.mu.Lock()
+		close(e.changed)
+		e.changed = make(chan struct{})
+		e.mu.Unlock()
+	})
+	return e, nil
+}
+
+// RegisterExecutor 注册任务类型的执行器，覆盖插件目录中的同名插件；未注册的类型使用内置的模拟执行器
+func (e *Engine) RegisterExecutor(taskType string, exec Executor) {
+	e.svc.RegisterExecutor(taskType, exec)
+}
+
+// InstanceID 本引擎的调度器实例 ID，可用作 TaskSpec.TargetWorker 把任务固定到本实例
+func (e *Engine) InstanceID() string {
+	return e.svc.Scheduler().InstanceID()
+}
+
+// Start 启动调度器，ctx 取消时调度器停止派发新任务；存储中遗留的 PENDING 任务随后被调度
+func (e *Engine) Start(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.running {
+		return errors.New("engine already started")
+	}
+	if e.stopped {
+		return ErrStopped
+	}
+	e.svc.StartScheduler(ctx)
+	e.running = true
+	e.svc.Scheduler().Wake()
+	return nil
+}
+
+// Stop 停止调度器并关闭存储，运行中的任务被取消
+func (e *Engine) Stop() error {
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return nil
+	}
+	e.stopped = true
+	e.running = false
+	e.mu.Unlock()
+
+	e.svc.StopScheduler()
+	return e.close()
+}
+
+// Pause 暂停派发新任务，运行中的任务继续执行，暂停期间提交的任务保持 PENDING
+func (e *Engine) Pause() error {
+	if err := e.checkRunning(); err != nil {
+		return err
+	}
+	return e.svc.PauseScheduler()
+}
+
+// Resume 恢复派发任务
+func (e *Engine) Resume() error {
+	if err := e.checkRunning(); err != nil {
+		return err
+	}
+	return e.svc.ResumeScheduler()
+}
+
+// checkRunning 引擎已停止时返回 ErrStopped，未启动时返回 ErrNotRunning
+func (e *Engine) checkRunning() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return ErrStopped
+	}
+	if !e.running {
+		return ErrNotRunning
+	}
+	return nil
+}
+
+// Submit 提交任务，引擎运行时依赖已满足的任务立即调度
+func (e *Engine) Submit(ctx context.Context, spec TaskSpec) (*Task, error) {
+	if spec.Name == "" {
+		return nil, errors.New("task name is required")
+	}
+	if spec.RetryPolicy != nil {
+		if err := spec.RetryPolicy.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid retry policy: %w", err)
+		}
+	}
+	if err := model.ValidateRouting(spec.NodeSelector, spec.TargetWorker); err != nil {
+		return nil, fmt.Errorf("invalid routing: %w", err)
+	}
+	if e.isStopped() {
+		return nil, ErrStopped
+	}
+
+	priority := spec.Priority
+	if priority == model.TaskPriorityUnspecified {
+		priority = PriorityNormal
+	}
+	task := model.NewTask(spec.Name, spec.Description, priority, spec.Type, spec.Params, spec.Dependencies, spec.MaxRetries, spec.CreatedBy)
+	task.RetryPolicy = spec.RetryPolicy
+	task.GroupKey = spec.GroupKey
+	task.ResourceSlots = spec.ResourceSlots
+	task.NodeSelector = spec.NodeSelector
+	task.TargetWorker = spec.TargetWorker
+
+	if err := e.svc.SubmitTask(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// Get 获取任务
+func (e *Engine) Get(ctx context.Context, id string) (*Task, error) {
+	task, err := e.svc.GetTask(ctx, id)
+	if errors.Is(err, ErrTaskNotFound) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// GetSchedulingDecision 说明任务当前为什么没有运行：依赖未满足、重试退避、暂停窗口、资源槽位、互斥任务、工作池队列已满等
+func (e *Engine) GetSchedulingDecision(ctx context.Context, id string) (*SchedulingDecision, error) {
+	decision, err := e.svc.GetSchedulingDecision(ctx, id)
+	if errors.Is(err, ErrTaskNotFound) {
+		return nil, ErrTaskNotFound
+	}
+	return decision, err
+}
+
+// Cancel 取消任务，运行中的任务通过执行器的 ctx 收到取消信号
+func (e *Engine) Cancel(ctx context.Context, id string) error {
+	return e.svc.CancelTask(ctx, id, "engine")
+}
+
+// Signal 向等待输入（WAITING_INPUT）的任务送达输入，任务重新进入 PENDING 并再次执行
+func (e *Engine) Signal(ctx context.Context, id string, payload map[string]string) (*Task, error) {
+	task, err := e.svc.SignalTask(ctx, id, payload, "engine")
+	if errors.Is(err, ErrTaskNotFound) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// waitRecheckInterval Wait 在没有状态变更通知时重新读取任务的间隔（兜底不经调度器的状态修改）
+const waitRecheckInterval = time.Second
+
+// Wait 阻塞直到任务进入终态或 ctx 结束；等待输入的任务收到信号并执行结束后才返回
+func (e *Engine) Wait(ctx context.Context, id string) (*Task, error) {
+	for {
+		e.mu.Lock()
+		changed := e.changed
+		e.mu.Unlock()
+
+		task, err := e.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if task.IsTerminal() {
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return task, ctx.Err()
+		case <-changed:
+		case <-time.After(waitRecheckInterval):
+		}
+	}
+}
+
+func (e *Engine) isStopped() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stopped
+}
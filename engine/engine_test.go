@@ -0,0 +1,382 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEngine_SubmitAndWait(t *testing.T) {
+	eng, err := New(Options{Workers: 2})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer eng.Stop()
+
+	eng.RegisterExecutor("greet", ExecutorFunc(func(ctx context.Context, task *Task) (map[string]string, error) {
+		ExecutionContextFrom(ctx).Logf("greeting %s", task.InputParams["name"])
+		return map[string]string{"greeting": "hello " + task.InputParams["name"]}, nil
+	}))
+	eng.RegisterExecutor("fail", ExecutorFunc(func(ctx context.Context, task *Task) (map[string]string, error) {
+		return nil, errors.New("boom")
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := eng.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	first, err := eng.Submit(ctx, TaskSpec{Name: "first", Type: "greet", Params: map[string]string{"name": "ada"}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	second, err := eng.Submit(ctx, TaskSpec{Name: "second", Type: "greet", Dependencies: []string{first.ID}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	done, err := eng.Wait(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if done.Status != StatusSucceeded {
+		t.Errorf("second status = %v", done.Status)
+	}
+	got, _ := eng.Get(ctx, first.ID)
+	if got.Status != StatusSucceeded || got.OutputResult["greeting"] != "hello ada" {
+		t.Errorf("first = %v %v", got.Status, got.OutputResult)
+	}
+
+	failed, _ := eng.Submit(ctx, TaskSpec{Name: "broken", Type: "fail"})
+	if done, err := eng.Wait(ctx, failed.ID); err != nil || done.Status != StatusFailed {
+		t.Errorf("failing task = %v, %v", done, err)
+	}
+
+	if _, err := eng.Get(ctx, "missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Get missing = %v", err)
+	}
+	if _, err := eng.Submit(ctx, TaskSpec{}); err == nil {
+		t.Error("expected error for task without name")
+	}
+	if _, err := eng.Submit(ctx, TaskSpec{Name: "orphan", Dependencies: []string{"missing"}}); err == nil {
+		t.Error("expected error for missing dependency")
+	}
+}
+
+func TestEngine_SignalWaitingTask(t *testing.T) {
+	eng, err := New(Options{Workers: 1, PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer eng.Stop()
+
+	eng.RegisterExecutor("approve", ExecutorFunc(func(ctx context.Context, task *Task) (map[string]string, error) {
+		decision := ExecutionContextFrom(ctx).Input()["decision"]
+		if decision == "" {
+			return nil, WaitForInput("approve or reject")
+		}
+		return map[string]string{"decision": decision}, nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := eng.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	task, err := eng.Submit(ctx, TaskSpec{Name: "deploy", Type: "approve"})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	for {
+		got, _ := eng.Get(ctx, task.ID)
+		if got.Status == StatusWaitingInput {
+			if got.InputRequest != "approve or reject" {
+				t.Errorf("InputRequest = %q", got.InputRequest)
+			}
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("task never waited for input, status %v", got.Status)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if _, err := eng.Signal(ctx, task.ID, map[string]string{"decision": "approved"}); err != nil {
+		t.Fatalf("Signal: %v", err)
+	}
+	done, err := eng.Wait(ctx, task.ID)
+	if err != nil || done.Status != StatusSucceeded || done.OutputResult["decision"] != "approved" {
+		t.Errorf("signalled task = %v, %v", done, err)
+	}
+	if _, err := eng.Signal(ctx, "missing", map[string]string{"decision": "approved"}); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Signal missing = %v", err)
+	}
+}
+
+func TestEngine_WorkerLabels(t *testing.T) {
+	if _, err := New(Options{WorkerLabels: map[string]string{"-bad": "x"}}); err == nil {
+		t.Error("expected error for invalid worker labels")
+	}
+
+	eng, err := New(Options{Workers: 2, PollInterval: 10 * time.Millisecond, WorkerLabels: map[string]string{"gpu": "true"}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer eng.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := eng.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	for _, spec := range []TaskSpec{
+		{Name: "gpu", NodeSelector: map[string]string{"gpu": "true"}},
+		{Name: "pinned", TargetWorker: eng.InstanceID()},
+	} {
+		task, err := eng.Submit(ctx, spec)
+		if err != nil {
+			t.Fatalf("Submit %s: %v", spec.Name, err)
+		}
+		if done, err := eng.Wait(ctx, task.ID); err != nil || done.Status != StatusSucceeded || done.ExecutedBy != eng.InstanceID() {
+			t.Errorf("%s task = %v, %v", spec.Name, done, err)
+		}
+	}
+
+	// 标签不匹配的任务保持 PENDING，调度决策说明原因
+	cpu, err := eng.Submit(ctx, TaskSpec{Name: "cpu", NodeSelector: map[string]string{"gpu": "false"}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	decision, err := eng.GetSchedulingDecision(ctx, cpu.ID)
+	if err != nil || len(decision.Reasons) != 1 || decision.Reasons[0].Code != "worker_mismatch" {
+		t.Errorf("decision = %+v, %v", decision, err)
+	}
+	if _, err := eng.Submit(ctx, TaskSpec{Name: "bad", NodeSelector: map[string]string{"": "x"}}); err == nil {
+		t.Error("expected error for invalid node selector")
+	}
+}
+
+func TestEngine_PriorityInheritance(t *testing.T) {
+	if _, err := New(Options{PriorityInheritance: TaskPriority(9)}); err == nil {
+		t.Error("expected error for unknown priority")
+	}
+
+	eng, err := New(Options{Workers: 1, PollInterval: 10 * time.Millisecond, PriorityInheritance: PriorityHigh})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer eng.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 启动前提交，首轮轮询即把 LOW 上游提升到 URGENT
+	up, err := eng.Submit(ctx, TaskSpec{Name: "up", Priority: PriorityLow})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	down, err := eng.Submit(ctx, TaskSpec{Name: "down", Priority: PriorityUrgent, Dependencies: []string{up.ID}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if err := eng.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if done, err := eng.Wait(ctx, down.ID); err != nil || done.Status != StatusSucceeded {
+		t.Fatalf("down task = %v, %v", done, err)
+	}
+
+	got, err := eng.Get(ctx, up.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Priority != PriorityLow {
+		t.Errorf("priority = %s, want restored LOW", got.Priority)
+	}
+	var boosted bool
+	for _, e := range got.Events {
+		boosted = boosted || strings.HasPrefix(e.Message, "priority boosted from LOW to URGENT")
+	}
+	if !boosted {
+		t.Errorf("expected a priority boost event, got %+v", got.Events)
+	}
+}
+
+func TestEngine_PersistsAcrossRestarts(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "tasks.db")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 未启动时提交的任务保存在存储中
+	eng, err := New(Options{DSN: dsn})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	task, err := eng.Submit(ctx, TaskSpec{Name: "queued", Type: "noop"})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if err := eng.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if _, err := eng.Submit(ctx, TaskSpec{Name: "late"}); !errors.Is(err, ErrStopped) {
+		t.Errorf("Submit after Stop = %v, want ErrStopped", err)
+	}
+
+	eng, err = New(Options{DSN: dsn, PollInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer eng.Stop()
+	eng.RegisterExecutor("noop", ExecutorFunc(func(ctx context.Context, task *Task) (map[string]string, error) {
+		return nil, nil
+	}))
+	if err := eng.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if done, err := eng.Wait(ctx, task.ID); err != nil || done.Status != StatusSucceeded {
+		t.Fatalf("Wait = %v, %v", done, err)
+	}
+}
+
+func TestEngine_PauseAndResume(t *testing.T) {
+	eng, err := New(Options{PollInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer eng.Stop()
+	eng.RegisterExecutor("noop", ExecutorFunc(func(ctx context.Context, task *Task) (map[string]string, error) {
+		return nil, nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := eng.Pause(); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("Pause before Start = %v, want ErrNotRunning", err)
+	}
+	if err := eng.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := eng.Pause(); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+
+	task, err := eng.Submit(ctx, TaskSpec{Name: "paused", Type: "noop"})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if got, err := eng.Get(ctx, task.ID); err != nil || got.Status != StatusPending {
+		t.Fatalf("Get while paused = %v, %v", got, err)
+	}
+
+	if err := eng.Resume(); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if done, err := eng.Wait(ctx, task.ID); err != nil || done.Status != StatusSucceeded {
+		t.Fatalf("Wait = %v, %v", done, err)
+	}
+
+	eng.Stop()
+	if err := eng.Resume(); !errors.Is(err, ErrStopped) {
+		t.Errorf("Resume after Stop = %v, want ErrStopped", err)
+	}
+}
+
+func TestEngine_PluginDir(t *testing.T) {
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncat >/dev/null\necho '{\"type\":\"result\",\"output\":{\"handled_by\":\"plugin\"}}'\n"
+	if err := os.WriteFile(filepath.Join(dir, "shell.sh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	eng, err := New(Options{PluginDir: dir, PluginTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer eng.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := eng.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	task, err := eng.Submit(ctx, TaskSpec{Name: "via plugin", Type: "shell"})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	done, err := eng.Wait(ctx, task.ID)
+	if err != nil || done.Status != StatusSucceeded || done.OutputResult["handled_by"] != "plugin" {
+		t.Fatalf("Wait = %+v, %v", done, err)
+	}
+
+	if _, err := New(Options{PluginDir: filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected error for missing plugin dir")
+	}
+}
+
+func TestEngine_FakeClock(t *testing.T) {
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := NewFakeClock(start)
+	eng, err := New(Options{Clock: clk})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer eng.Stop()
+
+	var attempts atomic.Int32
+	eng.RegisterExecutor("flaky", ExecutorFunc(func(ctx context.Context, task *Task) (map[string]string, error) {
+		if attempts.Add(1) == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return nil, nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := eng.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	task, err := eng.Submit(ctx, TaskSpec{Name: "flaky", Type: "flaky", RetryPolicy: &RetryPolicy{
+		MaxAttempts: 2, Backoff: "fixed", InitialDelayMs: (5 * time.Minute).Milliseconds(),
+	}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if !task.CreatedAt.Equal(start) {
+		t.Errorf("CreatedAt = %s, want %s", task.CreatedAt, start)
+	}
+
+	// 首次失败后等待退避，推进时钟后重试
+	for {
+		got, err := eng.Get(ctx, task.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.RetryCount == 1 {
+			if got.NextRunAt == nil || !got.NextRunAt.Equal(start.Add(5*time.Minute)) {
+				t.Fatalf("NextRunAt = %v", got.NextRunAt)
+			}
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("task was not retried: %+v", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	clk.Advance(5 * time.Minute)
+
+	done, err := eng.Wait(ctx, task.ID)
+	if err != nil || done.Status != StatusSucceeded || attempts.Load() != 2 {
+		t.Fatalf("Wait = %v, %v after %d attempts", done, err, attempts.Load())
+	}
+}
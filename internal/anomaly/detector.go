@@ -0,0 +1,211 @@
+// Package anomaly 失败率异常检测：定期按任务类型比较最近一个窗口的失败率与此前若干窗口的失败率，
+// 超过基线均值若干个标准差时发出告警（日志、指标和 failure_spike 通知），失败率回落后解除
+package anomaly
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/notify"
+	"taskflow/internal/repository"
+)
+
+// minStdDev 基线标准差的下限：基线失败率一直稳定（如始终为 0）时，避免少量失败就触发告警
+const minStdDev = 0.05
+
+// minBaselineWindows 至少有多少个历史窗口有任务结束才计算基线，新出现的任务类型在此之前不告警
+const minBaselineWindows = 3
+
+// Options 检测参数
+type Options struct {
+	CheckInterval   time.Duration              // 检查间隔，默认 1 分钟
+	Window          time.Duration              // 统计窗口，默认 10 分钟
+	BaselineWindows int                        // 计算基线的历史窗口数，默认 24
+	Threshold       float64                    // 超过基线均值多少个标准差视为异常，默认 3
+	MinSamples      int                        // 窗口内至少结束多少个任务才参与判断，默认 10
+	OnAlert         func(*notify.FailureSpike) // 发出告警后调用
+}
+
+// Detector 失败率异常检测器。告警状态保存在内存中，每个实例独立检测；
+// 多实例部署时同一异常会由每个启用了检测的实例各告警一次
+type Detector struct {
+	repo     repository.TaskStore
+	notifier *notify.Notifier
+	opts     Options
+	now      func() time.Time
+
+	// buckets 已结束的历史窗口的统计结果，按窗口开始时间缓存，避免每轮重新查询整个基线区间
+	buckets map[int64]map[string]repository.OutcomeCount
+	// active 当前处于异常状态的任务类型
+	active map[string]bool
+}
+
+// NewDetector 创建检测器，notifier 可以为 nil
+func NewDetector(repo repository.TaskStore, notifier *notify.Notifier, opts Options) *Detector {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = time.Minute
+	}
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Minute
+	}
+	if opts.BaselineWindows < 2 {
+		opts.BaselineWindows = 24
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = 3
+	}
+	if opts.MinSamples < 1 {
+		opts.MinSamples = 10
+	}
+	return &Detector{
+		repo:     repo,
+		notifier: notifier,
+		opts:     opts,
+		now:      time.Now,
+		buckets:  make(map[int64]map[string]repository.OutcomeCount),
+		active:   make(map[string]bool),
+	}
+}
+
+// Run 定期检查，直到 ctx 取消
+func (d *Detector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.CheckInterval)
+	defer ticker.Stop()
+
+	logger.Infof("Failure rate anomaly detector started, checking every %s over %s windows", d.opts.CheckInterval, d.opts.Window)
+	for {
+		d.Check()
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("Failure rate anomaly detector stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check 检查一轮，返回本轮新发出的告警
+func (d *Detector) Check() []*notify.FailureSpike {
+	now := d.now()
+	current, err := d.repo.CountOutcomesByType(now.Add(-d.opts.Window), now)
+	if err != nil {
+		logger.Errorf("Failed to count task outcomes: %v", err)
+		return nil
+	}
+	baseline, err := d.baseline(now)
+	if err != nil {
+		logger.Errorf("Failed to count task outcomes for the failure rate baseline: %v", err)
+		return nil
+	}
+
+	taskTypes := make([]string, 0, len(current)+len(d.active))
+	for taskType := range current {
+		taskTypes = append(taskTypes, taskType)
+	}
+	for taskType := range d.active {
+		if _, ok := current[taskType]; !ok {
+			taskTypes = append(taskTypes, taskType)
+		}
+	}
+	sort.Strings(taskTypes)
+
+	var alerts []*notify.FailureSpike
+	for _, taskType := range taskTypes {
+		count := current[taskType]
+		rate := failureRate(count)
+		mean, stdDev, ok := meanStdDev(baseline, taskType)
+		anomalous := ok && count.Total() >= d.opts.MinSamples &&
+			rate > mean+d.opts.Threshold*math.Max(stdDev, minStdDev)
+		metrics.RecordFailureRate(taskType, rate, anomalous)
+
+		switch {
+		case anomalous && !d.active[taskType]:
+			d.active[taskType] = true
+			spike := &notify.FailureSpike{
+				TaskType: taskType,
+				Window:   d.opts.Window,
+				Total:    count.Total(),
+				Failed:   count.Failed,
+				Rate:     rate,
+				Baseline: mean,
+				StdDev:   stdDev,
+			}
+			alerts = append(alerts, spike)
+
+			logger.Warnf("Failure rate of task type %q spiked to %.1f%% (%d of %d in %s), baseline %.1f%% ± %.1f%%",
+				taskType, rate*100, count.Failed, count.Total(), d.opts.Window, mean*100, stdDev*100)
+			metrics.RecordFailureRateAnomaly(taskType)
+			d.notifier.NotifyFailureSpike(spike, now)
+			if d.opts.OnAlert != nil {
+				d.opts.OnAlert(spike)
+			}
+		case !anomalous && d.active[taskType]:
+			delete(d.active, taskType)
+			logger.Infof("Failure rate of task type %q recovered to %.1f%%", taskType, rate*100)
+		}
+	}
+	return alerts
+}
+
+// baseline 返回最近窗口之前 BaselineWindows 个按窗口长度对齐的历史窗口的统计结果，从旧到新。
+// 已结束的窗口只查询一次，超出基线范围的缓存随之淘汰
+func (d *Detector) baseline(now time.Time) ([]map[string]repository.OutcomeCount, error) {
+	end := now.Add(-d.opts.Window).Truncate(d.opts.Window)
+	oldest := end.Add(-time.Duration(d.opts.BaselineWindows) * d.opts.Window)
+
+	windows := make([]map[string]repository.OutcomeCount, 0, d.opts.BaselineWindows)
+	for start := oldest; start.Before(end); start = start.Add(d.opts.Window) {
+		counts, ok := d.buckets[start.Unix()]
+		if !ok {
+			var err error
+			if counts, err = d.repo.CountOutcomesByType(start, start.Add(d.opts.Window)); err != nil {
+				return nil, err
+			}
+			d.buckets[start.Unix()] = counts
+		}
+		windows = append(windows, counts)
+	}
+	for start := range d.buckets {
+		if start < oldest.Unix() {
+			delete(d.buckets, start)
+		}
+	}
+	return windows, nil
+}
+
+// failureRate 失败任务占结束任务的比例
+func failureRate(c repository.OutcomeCount) float64 {
+	if c.Total() == 0 {
+		return 0
+	}
+	return float64(c.Failed) / float64(c.Total())
+}
+
+// meanStdDev 计算任务类型在各历史窗口中失败率的均值和总体标准差，没有任务结束的窗口不计入；
+// 有数据的窗口少于 minBaselineWindows 时 ok 为 false
+func meanStdDev(windows []map[string]repository.OutcomeCount, taskType string) (mean, stdDev float64, ok bool) {
+	var rates []float64
+	for _, w := range windows {
+		if c := w[taskType]; c.Total() > 0 {
+			rates = append(rates, failureRate(c))
+		}
+	}
+	if len(rates) < minBaselineWindows {
+		return 0, 0, false
+	}
+
+	for _, r := range rates {
+		mean += r
+	}
+	mean /= float64(len(rates))
+	for _, r := range rates {
+		stdDev += (r - mean) * (r - mean)
+	}
+	stdDev = math.Sqrt(stdDev / float64(len(rates)))
+	return mean, stdDev, true
+}
@@ -0,0 +1,97 @@
+package anomaly
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/notify"
+	"taskflow/internal/repository"
+)
+
+func TestDetector_Check(t *testing.T) {
+	repo, _ := repository.NewMemoryRepositories()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	window := 10 * time.Minute
+
+	seq := 0
+	add := func(taskType string, status model.TaskStatus, completed time.Time, n int) {
+		for i := 0; i < n; i++ {
+			seq++
+			task := model.NewTask("t", "", model.TaskPriorityNormal, taskType, nil, nil, 0, "alice")
+			task.ID = fmt.Sprintf("task-%d", seq)
+			task.Status = status
+			task.CompletedAt = &completed
+			if err := repo.Create(task); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+		}
+	}
+
+	// 基线：etl 每个窗口 10 个任务中失败 1 个，report 始终成功
+	for k := 2; k <= 7; k++ {
+		at := now.Add(-time.Duration(k)*window + time.Minute)
+		add("etl", model.TaskStatusSucceeded, at, 9)
+		add("etl", model.TaskStatusFailed, at, 1)
+		add("report", model.TaskStatusSucceeded, at, 10)
+	}
+	// 最近窗口：etl 失败率升至 60%，report 只有 2 个失败任务（样本不足）
+	add("etl", model.TaskStatusSucceeded, now.Add(-time.Minute), 4)
+	add("etl", model.TaskStatusTimeout, now.Add(-time.Minute), 6)
+	add("report", model.TaskStatusFailed, now.Add(-time.Minute), 2)
+
+	var notified []string
+	d := NewDetector(repo, nil, Options{Window: window, BaselineWindows: 6, OnAlert: func(s *notify.FailureSpike) {
+		notified = append(notified, s.TaskType)
+	}})
+	d.now = func() time.Time { return now }
+
+	alerts := d.Check()
+	if len(alerts) != 1 || alerts[0].TaskType != "etl" {
+		t.Fatalf("expected one etl alert, got %+v", alerts)
+	}
+	if a := alerts[0]; a.Total != 10 || a.Failed != 6 || a.Rate != 0.6 || a.Baseline < 0.099 || a.Baseline > 0.101 {
+		t.Errorf("unexpected alert: %+v", a)
+	}
+	if len(notified) != 1 {
+		t.Errorf("OnAlert should be called once, got %v", notified)
+	}
+
+	// 异常持续期间不重复告警
+	if alerts := d.Check(); len(alerts) != 0 {
+		t.Errorf("expected no repeated alert, got %+v", alerts)
+	}
+
+	// 失败率回落后解除，再次升高时重新告警
+	d.now = func() time.Time { return now.Add(window) }
+	add("etl", model.TaskStatusSucceeded, now.Add(window-time.Minute), 10)
+	if alerts := d.Check(); len(alerts) != 0 || d.active["etl"] {
+		t.Errorf("expected etl to recover, got alerts %+v active %v", alerts, d.active)
+	}
+	d.now = func() time.Time { return now.Add(2 * window) }
+	add("etl", model.TaskStatusFailed, now.Add(2*window-time.Minute), 10)
+	if alerts := d.Check(); len(alerts) != 1 || alerts[0].TaskType != "etl" {
+		t.Errorf("expected etl to alert again, got %+v", alerts)
+	}
+	if len(d.buckets) > 6 {
+		t.Errorf("expected cached windows to be bounded by the baseline, got %d", len(d.buckets))
+	}
+}
+
+func TestMeanStdDev(t *testing.T) {
+	windows := []map[string]repository.OutcomeCount{
+		{"etl": {Succeeded: 1, Failed: 1}},
+		{"etl": {Succeeded: 2}},
+		{},
+		{"etl": {Succeeded: 1, Failed: 1}},
+		{"etl": {Succeeded: 2}},
+	}
+	mean, stdDev, ok := meanStdDev(windows, "etl")
+	if !ok || mean != 0.25 || stdDev != 0.25 {
+		t.Errorf("meanStdDev = %v, %v, %v; want 0.25, 0.25, true", mean, stdDev, ok)
+	}
+	if _, _, ok := meanStdDev(windows[:2], "etl"); ok {
+		t.Error("expected too few baseline windows to be rejected")
+	}
+}
@@ -0,0 +1,107 @@
+// Package apiversion REST 与 gRPC 共用的 API 版本描述：破坏性变更以新版本（/api/v2、taskflow.v2 包）发布，
+// 与旧版本并存，旧版本通过 Deprecation/Sunset 响应头（RFC 9745、RFC 8594）提示迁移
+package apiversion
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 已发布的 API 版本
+const (
+	V1 = "v1"
+	V2 = "v2"
+)
+
+// 版本相关响应头，gRPC 以小写形式写入响应头 metadata
+const (
+	HeaderVersion     = "API-Version"
+	HeaderSupported   = "API-Supported-Versions"
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+	HeaderLink        = "Link"
+)
+
+// Version 一个 API 版本及其弃用计划
+type Version struct {
+	Name         string    // 版本号，如 v1
+	DeprecatedAt time.Time // 弃用时间，零值表示未弃用
+	SunsetAt     time.Time // 计划下线时间，零值表示未定
+	Link         string    // 迁移说明地址
+}
+
+// Deprecated 是否已标记弃用
+func (v Version) Deprecated() bool {
+	return !v.DeprecatedAt.IsZero()
+}
+
+// Enveloped 成功响应是否包裹在版本信封中，v1 保持原有的裸响应
+func (v Version) Enveloped() bool {
+	return v.Name != V1
+}
+
+// Headers 返回该版本应附加的响应头，未弃用时只包含 API-Version
+func (v Version) Headers() map[string]string {
+	headers := map[string]string{HeaderVersion: v.Name}
+	if v.Deprecated() {
+		headers[HeaderDeprecation] = "@" + strconv.FormatInt(v.DeprecatedAt.Unix(), 10)
+		if v.Link != "" {
+			headers[HeaderLink] = "<" + v.Link + `>; rel="deprecation"`
+		}
+	}
+	if !v.SunsetAt.IsZero() {
+		headers[HeaderSunset] = v.SunsetAt.UTC().Format(http.TimeFormat)
+	}
+	return headers
+}
+
+// Set 服务同时提供的 API 版本，按发布顺序排列
+type Set []Version
+
+// Names 逗号分隔的版本号列表，用于 API-Supported-Versions 响应头
+func (s Set) Names() string {
+	names := make([]string, len(s))
+	for i, v := range s {
+		names[i] = v.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Lookup 按版本号查找
+func (s Set) Lookup(name string) (Version, bool) {
+	for _, v := range s {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+// FromGRPCMethod 从 gRPC 方法全名（/taskflow.v2.TaskService/GetTask）的包名中解析版本号，
+// 不带版本段的包（taskflow.TaskService）视为 v1
+func FromGRPCMethod(fullMethod string) string {
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	parts := strings.Split(service, ".")
+	// 最后一段是服务名，只检查包名部分
+	for i := len(parts) - 2; i >= 0; i-- {
+		if isVersion(parts[i]) {
+			return parts[i]
+		}
+	}
+	return V1
+}
+
+// isVersion 判断包名段是否为版本号（v 后接数字）
+func isVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
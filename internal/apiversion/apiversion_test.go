@@ -0,0 +1,18 @@
+package apiversion
+
+import "testing"
+
+func TestFromGRPCMethod(t *testing.T) {
+	cases := map[string]string{
+		"/taskflow.TaskService/GetTask":     V1,
+		"/taskflow.v2.TaskService/GetTask":  V2,
+		"/taskflow.v10.TaskService/GetTask": "v10",
+		"/taskflow.vx.TaskService/GetTask":  V1,
+		"/v3/GetTask":                       V1,
+	}
+	for method, want := range cases {
+		if got := FromGRPCMethod(method); got != want {
+			t.Errorf("FromGRPCMethod(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package clock 可替换的时钟。调度器、状态机、任务服务和后台压缩器通过 Clock 读取当前时间、
+// 创建定时器，测试中注入 Fake 后重试退避、暂停窗口、心跳和保留期等逻辑不再依赖真实时间流逝
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 时钟，实现需并发安全
+type Clock interface {
+	// Now 当前时间，UTC
+	Now() time.Time
+	// NewTicker 每隔 d 触发一次的 ticker，d 须大于 0
+	NewTicker(d time.Duration) Ticker
+	// AfterFunc d 之后调用 f
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Ticker 周期触发器，接收方处理不及时时丢弃触发，与 time.Ticker 一致
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer AfterFunc 返回的定时器
+type Timer interface {
+	// Stop 取消尚未触发的定时器，已触发或已取消时返回 false
+	Stop() bool
+}
+
+// Real 使用系统时间的时钟
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now().UTC() }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+// Fake 手动推进的时钟，时间只在调用 Advance 或 Set 时变化。
+// 到期的定时器和 ticker 在 Advance 返回前按到期时间顺序触发，AfterFunc 的回调在调用 Advance 的 goroutine 中执行
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	seq     int
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter 未触发的定时器或 ticker
+type fakeWaiter struct {
+	at     time.Time
+	seq    int           // 同一时刻到期时按创建顺序触发
+	period time.Duration // ticker 的周期，定时器为 0
+	fn     func()        // 定时器回调
+	ch     chan time.Time
+}
+
+// NewFake 创建从 now 开始的手动时钟
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now.UTC()}
+}
+
+// Now 当前时间
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTicker 创建 ticker，首次在 d 之后触发
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{period: d, ch: make(chan time.Time, 1)}
+	f.add(w, d)
+	return &fakeTicker{f: f, w: w}
+}
+
+// AfterFunc 创建定时器，d 不大于 0 时在下一次 Advance 时触发
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	w := &fakeWaiter{fn: fn}
+	f.add(w, d)
+	return &fakeTimer{f: f, w: w}
+}
+
+// Advance 把时间推进 d，并触发期间到期的定时器和 ticker
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set 把时间设置为 t（不早于当前时间），并触发到期的定时器和 ticker
+func (f *Fake) Set(t time.Time) {
+	t = t.UTC()
+	for {
+		f.mu.Lock()
+		if len(f.waiters) == 0 || f.waiters[0].at.After(t) {
+			if t.After(f.now) {
+				f.now = t
+			}
+			f.mu.Unlock()
+			return
+		}
+		w := f.waiters[0]
+		f.waiters = f.waiters[1:]
+		if w.at.After(f.now) {
+			f.now = w.at
+		}
+		now := f.now
+		if w.period > 0 {
+			f.insert(w, w.at.Add(w.period))
+		}
+		f.mu.Unlock()
+
+		if w.fn != nil {
+			w.fn()
+		} else {
+			select {
+			case w.ch <- now:
+			default:
+			}
+		}
+	}
+}
+
+// Waiters 未触发的定时器和 ticker 数量，测试用于等待后台 goroutine 创建好 ticker 后再推进时间
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+func (f *Fake) add(w *fakeWaiter, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.insert(w, f.now.Add(d))
+}
+
+// insert 按到期时间有序插入，调用方持有 f.mu
+func (f *Fake) insert(w *fakeWaiter, at time.Time) {
+	f.seq++
+	w.at, w.seq = at, f.seq
+	i := sort.Search(len(f.waiters), func(i int) bool {
+		o := f.waiters[i]
+		return o.at.After(at) || o.at.Equal(at) && o.seq > w.seq
+	})
+	f.waiters = append(f.waiters, nil)
+	copy(f.waiters[i+1:], f.waiters[i:])
+	f.waiters[i] = w
+}
+
+// remove 删除未触发的 w，不存在时返回 false
+func (f *Fake) remove(w *fakeWaiter) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, o := range f.waiters {
+		if o == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.ch }
+func (t *fakeTicker) Stop()               { t.f.remove(t.w) }
+
+type fakeTimer struct {
+	f *Fake
+	w *fakeWaiter
+}
+
+func (t *fakeTimer) Stop() bool { return t.f.remove(t.w) }
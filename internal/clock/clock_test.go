@@ -0,0 +1,87 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_AdvanceFiresTimersInOrder(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	var fired []string
+	var firedAt []time.Time
+	record := func(name string) func() {
+		return func() {
+			fired = append(fired, name)
+			firedAt = append(firedAt, f.Now())
+		}
+	}
+	f.AfterFunc(3*time.Second, record("c"))
+	f.AfterFunc(time.Second, record("a"))
+	f.AfterFunc(2*time.Second, record("b"))
+	stopped := f.AfterFunc(2*time.Second, record("stopped"))
+	if !stopped.Stop() || stopped.Stop() {
+		t.Fatal("expected Stop to report true once for a pending timer")
+	}
+
+	f.Advance(2500 * time.Millisecond)
+	if len(fired) != 2 || fired[0] != "a" || fired[1] != "b" {
+		t.Fatalf("expected a, b to fire, got %v", fired)
+	}
+	// 回调看到的是各自的到期时间
+	if !firedAt[0].Equal(start.Add(time.Second)) || !firedAt[1].Equal(start.Add(2*time.Second)) {
+		t.Errorf("unexpected fire times %v", firedAt)
+	}
+	if got := f.Now(); !got.Equal(start.Add(2500 * time.Millisecond)) {
+		t.Errorf("Now = %s", got)
+	}
+	if f.Waiters() != 1 {
+		t.Errorf("expected 1 pending timer, got %d", f.Waiters())
+	}
+
+	f.Advance(time.Second)
+	if len(fired) != 3 || fired[2] != "c" {
+		t.Errorf("expected c to fire, got %v", fired)
+	}
+}
+
+func TestFake_Ticker(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	ticker := f.NewTicker(time.Minute)
+
+	f.Advance(59 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired early")
+	default:
+	}
+
+	// 一次推进多个周期时只保留一个未读的触发，与 time.Ticker 一致
+	f.Advance(3 * time.Minute)
+	if got := <-ticker.C(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("first tick at %s", got)
+	}
+	select {
+	case <-ticker.C():
+		t.Fatal("expected dropped ticks")
+	default:
+	}
+
+	f.Advance(time.Minute)
+	if got := <-ticker.C(); !got.Equal(start.Add(4 * time.Minute)) {
+		t.Errorf("tick after drain at %s", got)
+	}
+
+	ticker.Stop()
+	if f.Waiters() != 0 {
+		t.Errorf("expected stopped ticker to be removed, got %d waiters", f.Waiters())
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
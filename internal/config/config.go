@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,8 +25,10 @@ const (
 	DefaultGRPCPort     = "9000"
 	DefaultHTTPPort     = "9001"
 	DefaultDBPath       = "~/.taskflow/taskflow.db"
+	DefaultStorage      = "sqlite"
 	DefaultTimeout      = 30  // seconds
 	DefaultMaxConns     = 1000
+	DefaultMaxStreams   = 1000
 	DefaultLogLevel     = "info"
 	DefaultMaxGreetings = 100
 
@@ -46,17 +50,118 @@ const (
 	DefaultDBMaxOpenConns = 25
 	DefaultDBMaxIdleConns = 5
 	DefaultDBConnMaxLifetime = 300 // seconds
+	DefaultDBSlowQueryThreshold = 200 // milliseconds
+	DefaultDBReplicaMaxStaleness      = 5    // seconds
+	DefaultDBReplicaStatsMaxStaleness = 60   // seconds
+	DefaultDBReplicaCheckInterval     = 1000 // milliseconds
+
+	// Notification defaults
+	DefaultNotifyTimeout = 10 // seconds
+
+	// Attachment defaults
+	DefaultAttachmentBackend = "local"
+	DefaultAttachmentDir     = "~/.taskflow/attachments"
+	DefaultAttachmentMaxSize = 10 << 20 // bytes
+
+	// Artifact defaults
+	DefaultArtifactBackend         = "none"
+	DefaultArtifactDir             = "~/.taskflow/artifacts"
+	DefaultArtifactMaxInlineOutput = 64 << 10 // bytes
+
+	// Outbox defaults
+	DefaultOutboxPollInterval = 1000 // milliseconds
+	DefaultOutboxBatchSize    = 100
+	DefaultOutboxRetention    = 24 // hours
+
+	// Task event retention defaults
+	DefaultEventRetention       = 0 // hours, 0 keeps events forever
+	DefaultEventKeepLatest      = 100
+	DefaultEventCompactInterval = 3600 // seconds
+
+	// ID generator defaults
+	DefaultIDGenerator = "uuid"
+	DefaultIDNodeID    = 0
+
+	// SLA defaults
+	DefaultSLACheckInterval = 30 // seconds
+	DefaultSLABatchSize     = 500
+
+	// Queue time monitor defaults
+	DefaultQueueTimeCheckInterval = 60 // seconds
+	DefaultQueueTimeBatchSize     = 500
+
+	// Workflow deadline monitor defaults
+	DefaultWorkflowCheckInterval = 30 // seconds
+	DefaultWorkflowBatchSize     = 500
+
+	// Failure rate anomaly detection defaults
+	DefaultAnomalyCheckInterval   = 60  // seconds
+	DefaultAnomalyWindow          = 600 // seconds
+	DefaultAnomalyBaselineWindows = 24
+	DefaultAnomalyThreshold       = 3.0 // standard deviations
+	DefaultAnomalyMinSamples      = 10
+
+	// Data integrity check defaults
+	DefaultIntegrityCheckInterval = 0  // seconds, 0 disables scheduled checks
+	DefaultIntegrityLeaseTimeout  = 60 // seconds
+
+	// API response defaults
+	DefaultResponseNaming = "snake"
+
+	// HTTP response compression defaults
+	DefaultCompressMinSize = 1024 // bytes
+	DefaultCompressLevel   = -1   // compress/flate default level
+
+	// Task cache defaults
+	DefaultTaskCacheBackend           = "memory"
+	DefaultTaskCacheSize              = 10000
+	DefaultTaskCacheTTL               = 30   // seconds
+	DefaultTaskCacheEventPollInterval = 1000 // milliseconds
+
+	// Kafka defaults
+	DefaultKafkaTopic    = "taskflow.task-events"
+	DefaultKafkaFormat   = "json"
+	DefaultKafkaClientID = "taskflow"
 )
 
+// DefaultAttachmentTypes 默认允许的附件 MIME 类型
+var DefaultAttachmentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/x-yaml",
+	"application/gzip",
+	"application/zip",
+	"application/pdf",
+	"image/png",
+	"image/jpeg",
+}
+
+// DefaultCompressTypes 默认压缩的响应内容类型，以 / 结尾的按前缀匹配
+var DefaultCompressTypes = []string{"application/json", "text/"}
+
+// DefaultSensitiveKeys 默认脱敏的 input_params 键名模式
+var DefaultSensitiveKeys = []string{
+	"*password*",
+	"*secret*",
+	"*token*",
+	"*api_key*",
+	"*apikey*",
+	"*credential*",
+}
+
 // ServerConfig 服务配置
 //goland:noinspection GoDeprecation
 type ServerConfig struct {
 	GRPCPort    string `yaml:"grpc_port" env:"GRPC_PORT"`       // gRPC服务端口 (1-65535)
 	HTTPPort    string `yaml:"http_port" env:"HTTP_PORT"`       // HTTP服务端口 (1-65535)
 	DBPath      string `yaml:"db_path" env:"TASKFLOW_DB_PATH"`  // 数据库文件路径
-	EnableDebug bool   `yaml:"enable_debug" env:"ENABLE_DEBUG"` // 启用调试模式
+	Storage     string `yaml:"storage" env:"TASKFLOW_STORAGE"`  // 存储后端：sqlite（默认）, memory（不持久化，用于嵌入和测试）
+	EnableDebug bool   `yaml:"enable_debug" env:"ENABLE_DEBUG"` // 启用调试模式，配合 DebugToken 在 HTTP 服务上开放 /debug/pprof 和 /debug/runtime
+	DebugToken  string `yaml:"debug_token" env:"DEBUG_TOKEN"`   // 访问调试端点的 Bearer 令牌，至少 16 个字符，为空时不开放调试端点
+	AdminToken  string `yaml:"admin_token" env:"ADMIN_TOKEN"`   // 访问管理端点（/admin/backup）的 Bearer 令牌，至少 16 个字符，为空时不开放管理端点
 	Timeout     int    `yaml:"timeout" env:"SERVER_TIMEOUT"`     // 请求超时时间（秒），默认30秒
-	MaxConns    int    `yaml:"max_conns" env:"MAX_CONNECTIONS"` // 最大连接数，默认1000
+	MaxConns    int    `yaml:"max_conns" env:"MAX_CONNECTIONS"` // 最大并发请求数，超出时 HTTP 返回 503、gRPC 返回 RESOURCE_EXHAUSTED，默认1000；gRPC 流式调用单独计数
+	MaxStreams  int    `yaml:"max_streams" env:"MAX_STREAMS"`   // 最大同时打开的 gRPC 流（WatchTask 等），超出时返回 RESOURCE_EXHAUSTED，默认1000
 	LogLevel    string `yaml:"log_level" env:"LOG_LEVEL"`       // 日志级别：debug, info, warn, error
 }
 
@@ -96,6 +201,10 @@ type QueueConfig struct {
 	DeadLetterExchange string `yaml:"dead_letter_exchange" env:"QUEUE_DLX"`         // 死信交换机
 	DeadLetterQueue    string `yaml:"dead_letter_queue" env:"QUEUE_DLQ"`           // 死信队列
 	TTL            int    `yaml:"ttl" env:"QUEUE_TTL"`                             // 消息TTL（毫秒）
+	Backend        string `yaml:"backend" env:"QUEUE_BACKEND"`                     // 就绪任务队列后端：none、memory、redis，默认none（轮询数据库）
+	RedisAddr      string `yaml:"redis_addr" env:"QUEUE_REDIS_ADDR"`               // Redis 地址（host:port）
+	RedisPassword  string `yaml:"redis_password" env:"QUEUE_REDIS_PASSWORD"`       // Redis 密码
+	RedisDB        int    `yaml:"redis_db" env:"QUEUE_REDIS_DB"`                   // Redis 数据库编号
 }
 
 // DatabaseConfig 数据库配置
@@ -115,16 +224,236 @@ type DatabaseConfig struct {
 	TablePrefix     string `yaml:"table_prefix" env:"DB_TABLE_PREFIX"`        // 表前缀，默认空
 	PoolSize        int    `yaml:"pool_size" env:"DB_POOL_SIZE"`              // 连接池大小
 	MinIdleConns    int    `yaml:"min_idle_conns" env:"DB_MIN_IDLE_CONNS"`    // 最小空闲连接数
+	SlowQueryThreshold int `yaml:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD"` // 慢查询日志阈值（毫秒），0表示不记录，默认200
+	FieldEncryptionKey  string   `yaml:"field_encryption_key" env:"DB_FIELD_ENCRYPTION_KEY"`   // 任务 input_params/output_result 列加密主密钥，格式 key_id:base64(32字节)，为空时不加密
+	FieldDecryptionKeys []string `yaml:"field_decryption_keys" env:"DB_FIELD_DECRYPTION_KEYS"` // 轮换前的旧主密钥（同格式，逗号分隔），只用于解密
+	ReadReplicas             []string `yaml:"read_replicas" env:"DB_READ_REPLICAS"`                             // 只读副本数据库路径（逗号分隔），由外部复制工具同步，列表、搜索和统计在延迟允许时读副本
+	ReplicaMaxStaleness      int      `yaml:"replica_max_staleness" env:"DB_REPLICA_MAX_STALENESS"`             // 任务列表和搜索可容忍的副本延迟（秒），默认5，0 表示始终读主库
+	ReplicaStatsMaxStaleness int      `yaml:"replica_stats_max_staleness" env:"DB_REPLICA_STATS_MAX_STALENESS"` // 统计和耗时估算可容忍的副本延迟（秒），默认60，0 表示始终读主库
+	ReplicaCheckInterval     int      `yaml:"replica_check_interval" env:"DB_REPLICA_CHECK_INTERVAL"`           // 测量副本延迟的间隔（毫秒），默认1000
+}
+
+// NotificationChannel 通知渠道配置
+type NotificationChannel struct {
+	Name      string            `yaml:"name" mapstructure:"name"`             // 渠道名称
+	Type      string            `yaml:"type" mapstructure:"type"`             // 渠道类型：webhook, slack
+	URL       string            `yaml:"url" mapstructure:"url"`               // 投递地址
+	Events    []string          `yaml:"events" mapstructure:"events"`         // 订阅的事件（目标状态，如 succeeded, failed），为空表示全部
+	TaskTypes []string          `yaml:"task_types" mapstructure:"task_types"` // 订阅的任务类型，为空表示全部
+	Template  string            `yaml:"template" mapstructure:"template"`     // Go 模板，为空时使用渠道类型的默认模板
+	Headers   map[string]string `yaml:"headers" mapstructure:"headers"`       // 附加请求头
+}
+
+// NotificationConfig 通知配置
+type NotificationConfig struct {
+	Timeout  int                   `yaml:"timeout" env:"NOTIFY_TIMEOUT"` // 投递超时（秒），默认10
+	Channels []NotificationChannel `yaml:"channels"`                     // 通知渠道，仅支持配置文件
+}
+
+// S3Config S3 兼容对象存储配置
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint" mapstructure:"endpoint" env:"S3_ENDPOINT"`                             // 服务地址，默认 AWS 区域地址
+	Region          string `yaml:"region" mapstructure:"region" env:"S3_REGION"`                                   // 区域，默认 us-east-1
+	Bucket          string `yaml:"bucket" mapstructure:"bucket" env:"S3_BUCKET"`                                   // 桶名称
+	Prefix          string `yaml:"prefix" mapstructure:"prefix" env:"S3_PREFIX"`                                   // 对象 key 前缀
+	AccessKeyID     string `yaml:"access_key_id" mapstructure:"access_key_id" env:"AWS_ACCESS_KEY_ID"`             // 访问密钥 ID
+	SecretAccessKey string `yaml:"secret_access_key" mapstructure:"secret_access_key" env:"AWS_SECRET_ACCESS_KEY"` // 访问密钥
+	UsePathStyle    bool   `yaml:"use_path_style" mapstructure:"use_path_style" env:"S3_USE_PATH_STYLE"`           // 使用 path-style 地址（MinIO 等）
+}
+
+// AttachmentConfig 任务附件配置
+type AttachmentConfig struct {
+	Backend      string   `yaml:"backend" env:"ATTACHMENT_BACKEND"`                   // 存储后端：local, s3, gcs, none（禁用），默认local
+	Dir          string   `yaml:"dir" mapstructure:"dir" env:"ATTACHMENT_DIR"`                               // local 后端的存储目录
+	MaxSize      int64    `yaml:"max_size" mapstructure:"max_size" env:"ATTACHMENT_MAX_SIZE"`                // 单个附件最大字节数，默认10MB
+	AllowedTypes []string `yaml:"allowed_types" mapstructure:"allowed_types" env:"ATTACHMENT_ALLOWED_TYPES"` // 允许的 MIME 类型（支持 text/* 通配），逗号分隔
+	S3           S3Config `yaml:"s3" mapstructure:"s3"`
+}
+
+// OutboxConfig 发件箱中继配置：任务状态变更事件与状态同事务写入发件箱，由中继至少一次投递
+type OutboxConfig struct {
+	Enabled      bool        `yaml:"enabled" mapstructure:"enabled" env:"OUTBOX_ENABLED"`                   // 是否启动中继
+	PollInterval int         `yaml:"poll_interval" mapstructure:"poll_interval" env:"OUTBOX_POLL_INTERVAL"` // 轮询间隔（毫秒），默认1000
+	BatchSize    int         `yaml:"batch_size" mapstructure:"batch_size" env:"OUTBOX_BATCH_SIZE"`          // 每轮最多投递的事件数，默认100
+	Retention    int         `yaml:"retention" mapstructure:"retention" env:"OUTBOX_RETENTION"`             // 已投递事件保留时长（小时），默认24
+	WebhookURLs  []string    `yaml:"webhook_urls" mapstructure:"webhook_urls" env:"OUTBOX_WEBHOOK_URLS"`    // 接收事件的 webhook 地址，逗号分隔
+	Kafka        KafkaConfig `yaml:"kafka" mapstructure:"kafka"`
+}
+
+// KafkaConfig Kafka 事件接收端配置，配置了 brokers 时启用
+type KafkaConfig struct {
+	Brokers  []string `yaml:"brokers" mapstructure:"brokers" env:"KAFKA_BROKERS"`       // 引导 broker 地址（host:port），逗号分隔
+	Topic    string   `yaml:"topic" mapstructure:"topic" env:"KAFKA_TOPIC"`             // 主题，默认taskflow.task-events
+	Format   string   `yaml:"format" mapstructure:"format" env:"KAFKA_FORMAT"`          // 消息格式：json, proto，默认json
+	ClientID string   `yaml:"client_id" mapstructure:"client_id" env:"KAFKA_CLIENT_ID"` // 客户端 ID，默认taskflow
+}
+
+// BlobStoreConfig 对象存储后端配置，附件和产物各自持有一份
+type BlobStoreConfig struct {
+	Backend string   // local, s3, gcs, none
+	Dir     string   // local 后端的存储目录
+	S3      S3Config // s3 和 gcs（HMAC 密钥、S3 兼容接口）后端使用
+}
+
+// BlobStore 附件存储后端配置
+func (a AttachmentConfig) BlobStore() BlobStoreConfig {
+	return BlobStoreConfig{Backend: a.Backend, Dir: a.Dir, S3: a.S3}
+}
+
+// ArtifactConfig 任务产物存储配置：超出内联上限的执行输出和归档导出写入该存储
+type ArtifactConfig struct {
+	Backend         string   `yaml:"backend" mapstructure:"backend" env:"ARTIFACT_BACKEND"`                               // 存储后端：local, s3, gcs, none（禁用），默认none
+	Dir             string   `yaml:"dir" mapstructure:"dir" env:"ARTIFACT_DIR"`                                           // local 后端的存储目录
+	MaxInlineOutput int      `yaml:"max_inline_output" mapstructure:"max_inline_output" env:"ARTIFACT_MAX_INLINE_OUTPUT"` // 执行输出超过该字节数时写入产物存储，默认64KB
+	S3              S3Config `yaml:"s3" mapstructure:"s3"`
+}
+
+// BlobStore 产物存储后端配置
+func (a ArtifactConfig) BlobStore() BlobStoreConfig {
+	return BlobStoreConfig{Backend: a.Backend, Dir: a.Dir, S3: a.S3}
+}
+
+// EventConfig 任务事件保留配置：定期删除超过保留期的任务事件，每个任务最近的若干条事件始终保留
+type EventConfig struct {
+	Retention       int `yaml:"retention" mapstructure:"retention" env:"EVENT_RETENTION"`                      // 事件保留时长（小时），默认0，0 表示永久保留
+	KeepLatest      int `yaml:"keep_latest" mapstructure:"keep_latest" env:"EVENT_KEEP_LATEST"`                // 每个任务始终保留的最近事件数，默认100
+	CompactInterval int `yaml:"compact_interval" mapstructure:"compact_interval" env:"EVENT_COMPACT_INTERVAL"` // 压缩间隔（秒），默认3600
+}
+
+// IDConfig 任务和事件 ID 生成配置
+type IDConfig struct {
+	Generator string `yaml:"generator" mapstructure:"generator" env:"ID_GENERATOR"` // 生成器：uuid（默认）、ulid 或 snowflake，后两者按创建时间排序
+	NodeID    int    `yaml:"node_id" mapstructure:"node_id" env:"ID_NODE_ID"`       // snowflake 节点 ID（0-1023），多个实例需各不相同
+}
+
+// SLAConfig SLA 监控配置：定期检查声明了截止时间的任务并记录违约
+type SLAConfig struct {
+	CheckInterval int `yaml:"check_interval" mapstructure:"check_interval" env:"SLA_CHECK_INTERVAL"` // 检查间隔（秒），默认30，0 表示不启动监控
+	BatchSize     int `yaml:"batch_size" mapstructure:"batch_size" env:"SLA_BATCH_SIZE"`             // 每轮最多检查的任务数，默认500
+}
+
+// QueueTimeConfig 排队时长监控配置：尚未开始执行的 PENDING 任务排队超过上限时告警，可选提高优先级
+type QueueTimeConfig struct {
+	CheckInterval    int            `yaml:"check_interval" mapstructure:"check_interval" env:"QUEUE_TIME_CHECK_INTERVAL"`          // 检查间隔（秒），默认60，0 表示不启动监控
+	MaxAge           int            `yaml:"max_age" mapstructure:"max_age" env:"QUEUE_TIME_MAX_AGE"`                               // 排队时长上限（秒），默认0，0 表示只检查 max_age_by_type 中的任务类型
+	MaxAgeByType     map[string]int `yaml:"max_age_by_type" mapstructure:"max_age_by_type"`                                        // 按任务类型（不区分大小写）覆盖排队时长上限（秒），0 表示不检查该类型，仅从配置文件读取
+	EscalatePriority string         `yaml:"escalate_priority" mapstructure:"escalate_priority" env:"QUEUE_TIME_ESCALATE_PRIORITY"` // 排队超时后把任务自身优先级提高到该优先级（LOW/NORMAL/HIGH/URGENT），为空时不提高
+	BatchSize        int            `yaml:"batch_size" mapstructure:"batch_size" env:"QUEUE_TIME_BATCH_SIZE"`                      // 每个上限每轮最多检查的任务数，默认500
+}
+
+// WorkflowConfig 工作流截止时间监控配置：取消截止时间已过的工作流中未结束的任务
+type WorkflowConfig struct {
+	CheckInterval int `yaml:"check_interval" mapstructure:"check_interval" env:"WORKFLOW_CHECK_INTERVAL"` // 检查间隔（秒），默认30，0 表示不启动监控
+	BatchSize     int `yaml:"batch_size" mapstructure:"batch_size" env:"WORKFLOW_BATCH_SIZE"`             // 每轮最多处理的超时任务数，默认500
+}
+
+// AnomalyConfig 失败率异常检测配置：按任务类型比较最近窗口与此前若干窗口的失败率
+type AnomalyConfig struct {
+	CheckInterval   int     `yaml:"check_interval" mapstructure:"check_interval" env:"ANOMALY_CHECK_INTERVAL"`       // 检查间隔（秒），默认60，0 表示不启动检测
+	Window          int     `yaml:"window" mapstructure:"window" env:"ANOMALY_WINDOW"`                               // 统计窗口（秒），默认600
+	BaselineWindows int     `yaml:"baseline_windows" mapstructure:"baseline_windows" env:"ANOMALY_BASELINE_WINDOWS"` // 计算基线的历史窗口数，默认24
+	Threshold       float64 `yaml:"threshold" mapstructure:"threshold" env:"ANOMALY_THRESHOLD"`                      // 失败率超过基线均值多少个标准差视为异常，默认3
+	MinSamples      int     `yaml:"min_samples" mapstructure:"min_samples" env:"ANOMALY_MIN_SAMPLES"`                // 窗口内至少结束多少个任务才参与判断，默认10
+}
+
+// IntegrityConfig 数据完整性检查配置：定期扫描任务数据中的不一致并报告或修复，也可通过 /admin/integrity 按需运行
+type IntegrityConfig struct {
+	CheckInterval int  `yaml:"check_interval" mapstructure:"check_interval" env:"INTEGRITY_CHECK_INTERVAL"` // 定期检查间隔（秒），默认0，0 表示不定期检查
+	Repair        bool `yaml:"repair" mapstructure:"repair" env:"INTEGRITY_REPAIR"`                         // 定期检查时修复发现的不一致，默认只报告
+	LeaseTimeout  int  `yaml:"lease_timeout" mapstructure:"lease_timeout" env:"INTEGRITY_LEASE_TIMEOUT"`    // 调度器实例心跳超过该时长（秒）视为下线，其 RUNNING 任务没有租约，默认60
+}
+
+// SecretsConfig 密钥子系统配置，未配置主密钥时禁用
+type SecretsConfig struct {
+	MasterKey string `yaml:"master_key" mapstructure:"master_key" env:"SECRETS_MASTER_KEY"` // base64 编码的 32 字节 AES-256 主密钥，建议由 KMS 或密钥管理服务注入环境变量
+}
+
+// RedactionConfig 敏感参数脱敏配置：键名匹配的 input_params、signal_input 值在任务响应、变更事件和执行日志中替换为掩码
+type RedactionConfig struct {
+	SensitiveKeys []string `yaml:"sensitive_keys" mapstructure:"sensitive_keys" env:"REDACT_SENSITIVE_KEYS"` // 敏感键名通配模式（不区分大小写），逗号分隔，为空时不脱敏
+	AdminUsers    []string `yaml:"admin_users" mapstructure:"admin_users" env:"REDACT_ADMIN_USERS"`          // 可查看未脱敏参数的用户 ID，需启用认证
+}
+
+// AccessConfig 任务可见性配置：认证用户默认只能查看本人创建或所在团队的任务
+type AccessConfig struct {
+	AdminUsers       []string `yaml:"admin_users" mapstructure:"admin_users" env:"ACCESS_ADMIN_USERS"`                   // 可查看所有任务的用户 ID，需启用认证
+	PublicUnassigned bool     `yaml:"public_unassigned" mapstructure:"public_unassigned" env:"ACCESS_PUBLIC_UNASSIGNED"` // 未归属团队的任务对所有认证用户可见（旧行为）
+}
+
+// CacheConfig 任务读穿缓存配置：缓存按 ID 查询任务的结果，经由本实例写入任务时立即失效
+type CacheConfig struct {
+	Enabled           bool   `yaml:"enabled" mapstructure:"enabled" env:"TASK_CACHE_ENABLED"`                                     // 是否启用缓存
+	Backend           string `yaml:"backend" mapstructure:"backend" env:"TASK_CACHE_BACKEND"`                                     // 缓存后端：memory（进程内 LRU）、redis（多实例共享），默认memory
+	Size              int    `yaml:"size" mapstructure:"size" env:"TASK_CACHE_SIZE"`                                              // memory 后端最多缓存的任务数，默认10000
+	TTL               int    `yaml:"ttl" mapstructure:"ttl" env:"TASK_CACHE_TTL"`                                                 // 缓存有效期（秒），默认30，0 表示不过期
+	EventPollInterval int    `yaml:"event_poll_interval" mapstructure:"event_poll_interval" env:"TASK_CACHE_EVENT_POLL_INTERVAL"` // memory 后端轮询任务事件、失效其他实例修改的任务的间隔（毫秒），默认1000，0 表示不轮询
+	RedisAddr         string `yaml:"redis_addr" mapstructure:"redis_addr" env:"TASK_CACHE_REDIS_ADDR"`                            // Redis 地址（host:port）
+	RedisPassword     string `yaml:"redis_password" mapstructure:"redis_password" env:"TASK_CACHE_REDIS_PASSWORD"`                // Redis 密码
+	RedisDB           int    `yaml:"redis_db" mapstructure:"redis_db" env:"TASK_CACHE_REDIS_DB"`                                  // Redis 数据库编号
+}
+
+// ShardConfig 一个任务分片：命名空间（团队 ID）的任务存放在独立的 SQLite 数据库
+type ShardConfig struct {
+	Namespace string `yaml:"namespace" mapstructure:"namespace"` // 命名空间，即团队 ID
+	DBPath    string `yaml:"db_path" mapstructure:"db_path"`     // 分片数据库文件路径
+}
+
+// ShardingConfig 任务分片配置，仅从配置文件读取。未列出的命名空间、未归属团队的任务以及团队、密钥等全局数据留在主数据库
+type ShardingConfig struct {
+	Shards []ShardConfig `yaml:"shards" mapstructure:"shards"`
+}
+
+// APIConfig API 版本配置：设置 v1 的弃用/下线时间后，v1 响应携带 Deprecation、Sunset 响应头
+type APIConfig struct {
+	V1DeprecatedAt  string `yaml:"v1_deprecated_at" mapstructure:"v1_deprecated_at" env:"API_V1_DEPRECATED_AT"` // v1 弃用时间（RFC3339），为空表示未弃用
+	V1SunsetAt      string `yaml:"v1_sunset_at" mapstructure:"v1_sunset_at" env:"API_V1_SUNSET_AT"`             // v1 计划下线时间（RFC3339）
+	DeprecationLink string `yaml:"deprecation_link" mapstructure:"deprecation_link" env:"API_DEPRECATION_LINK"` // 迁移说明地址，写入 Link 响应头
+
+	// 成功响应的默认格式，请求可以用 Accept-Profile 请求头覆盖
+	ResponseNaming   string `yaml:"response_naming" mapstructure:"response_naming" env:"API_RESPONSE_NAMING"`       // 字段命名：snake（默认）或 camel
+	ResponseEnvelope string `yaml:"response_envelope" mapstructure:"response_envelope" env:"API_RESPONSE_ENVELOPE"` // 信封：enveloped 或 bare，为空时按 API 版本决定
+}
+
+// V1Schedule 解析 v1 的弃用和下线时间，未设置的返回零值
+func (a APIConfig) V1Schedule() (deprecatedAt, sunsetAt time.Time) {
+	deprecatedAt, _ = time.Parse(time.RFC3339, a.V1DeprecatedAt)
+	sunsetAt, _ = time.Parse(time.RFC3339, a.V1SunsetAt)
+	return deprecatedAt, sunsetAt
+}
+
+// CompressionConfig HTTP 响应压缩配置，按客户端的 Accept-Encoding 使用 gzip 或 deflate
+type CompressionConfig struct {
+	MinSize      int      `yaml:"min_size" mapstructure:"min_size" env:"HTTP_COMPRESS_MIN_SIZE"`         // 响应体达到该字节数才压缩，0 关闭压缩
+	Level        int      `yaml:"level" mapstructure:"level" env:"HTTP_COMPRESS_LEVEL"`                  // 压缩级别 0~9，-1 使用默认级别
+	ContentTypes []string `yaml:"content_types" mapstructure:"content_types" env:"HTTP_COMPRESS_TYPES"` // 允许压缩的内容类型，以 / 结尾的按前缀匹配
 }
 
 // Config 配置
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Features FeatureFlags   `yaml:"features"`
-	Worker   WorkerConfig   `yaml:"worker"`
-	Queue    QueueConfig    `yaml:"queue"`
-	Database DatabaseConfig `yaml:"database"`
-	mu       sync.RWMutex   // 用于配置热加载
+	Server        ServerConfig       `yaml:"server"`
+	Features      FeatureFlags       `yaml:"features"`
+	Worker        WorkerConfig       `yaml:"worker"`
+	Queue         QueueConfig        `yaml:"queue"`
+	Database      DatabaseConfig     `yaml:"database"`
+	Notifications NotificationConfig `yaml:"notifications"`
+	Attachments   AttachmentConfig   `yaml:"attachments"`
+	Artifacts     ArtifactConfig     `yaml:"artifacts"`
+	Outbox        OutboxConfig       `yaml:"outbox"`
+	Events        EventConfig        `yaml:"events"`
+	IDs           IDConfig           `yaml:"ids"`
+	SLA           SLAConfig          `yaml:"sla"`
+	QueueTime     QueueTimeConfig    `yaml:"queue_time"`
+	Workflow      WorkflowConfig     `yaml:"workflow"`
+	Anomaly       AnomalyConfig      `yaml:"anomaly"`
+	Integrity     IntegrityConfig    `yaml:"integrity"`
+	Secrets       SecretsConfig      `yaml:"secrets"`
+	Redaction     RedactionConfig    `yaml:"redaction"`
+	Access        AccessConfig       `yaml:"access"`
+	API           APIConfig          `yaml:"api"`
+	Compression   CompressionConfig  `yaml:"compression"`
+	Cache         CacheConfig        `yaml:"cache"`
+	Sharding      ShardingConfig     `yaml:"sharding"`
+	mu            sync.RWMutex       // 用于配置热加载
 }
 
 // LoadConfig 加载配置（支持环境变量覆盖）
@@ -168,6 +497,7 @@ func LoadConfig() *Config {
 	grpcAddr := getEnv("TASKFLOW_GRPC_ADDR", "")
 	httpAddr := getEnv("TASKFLOW_HTTP_ADDR", "")
 	dbPath := getEnv("TASKFLOW_DB_PATH", "")
+	storage := getEnv("TASKFLOW_STORAGE", DefaultStorage)
 
 	// 解析地址获取端口
 	grpcPort := DefaultGRPCPort
@@ -190,6 +520,9 @@ func LoadConfig() *Config {
 	if v.IsSet("server.db_path") {
 		dbPath = v.GetString("server.db_path")
 	}
+	if v.IsSet("server.storage") {
+		storage = v.GetString("server.storage")
+	}
 
 	// 如果没有设置 DBPath，使用默认值
 	if dbPath == "" {
@@ -201,9 +534,13 @@ func LoadConfig() *Config {
 			GRPCPort:    grpcPort,
 			HTTPPort:    httpPort,
 			DBPath:      dbPath,
+			Storage:     storage,
 			EnableDebug: getEnvBool("ENABLE_DEBUG"),
+			DebugToken:  getEnv("DEBUG_TOKEN", ""),
+			AdminToken:  getEnv("ADMIN_TOKEN", ""),
 			Timeout:     getEnvInt("SERVER_TIMEOUT", DefaultTimeout),
 			MaxConns:    getEnvInt("MAX_CONNECTIONS", DefaultMaxConns),
+			MaxStreams:  getEnvInt("MAX_STREAMS", DefaultMaxStreams),
 			LogLevel:    getEnv("LOG_LEVEL", DefaultLogLevel),
 		},
 		Features: FeatureFlags{
@@ -237,6 +574,10 @@ func LoadConfig() *Config {
 			DeadLetterExchange: getEnv("QUEUE_DLX", ""),
 			DeadLetterQueue:    getEnv("QUEUE_DLQ", ""),
 			TTL:                getEnvInt("QUEUE_TTL", 0),
+			Backend:            getEnv("QUEUE_BACKEND", "none"),
+			RedisAddr:          getEnv("QUEUE_REDIS_ADDR", ""),
+			RedisPassword:      getEnv("QUEUE_REDIS_PASSWORD", ""),
+			RedisDB:            getEnvInt("QUEUE_REDIS_DB", 0),
 		},
 		Database: DatabaseConfig{
 			Host:             getEnv("DB_HOST", DefaultDBHost),
@@ -254,7 +595,228 @@ func LoadConfig() *Config {
 			TablePrefix:      getEnv("DB_TABLE_PREFIX", ""),
 			PoolSize:         getEnvInt("DB_POOL_SIZE", DefaultDBMaxOpenConns),
 			MinIdleConns:     getEnvInt("DB_MIN_IDLE_CONNS", DefaultDBMaxIdleConns),
+			SlowQueryThreshold: getEnvInt("DB_SLOW_QUERY_THRESHOLD", DefaultDBSlowQueryThreshold),
+			FieldEncryptionKey:  getEnv("DB_FIELD_ENCRYPTION_KEY", ""),
+			FieldDecryptionKeys: getEnvList("DB_FIELD_DECRYPTION_KEYS", nil),
+			ReadReplicas:             getEnvList("DB_READ_REPLICAS", nil),
+			ReplicaMaxStaleness:      getEnvInt("DB_REPLICA_MAX_STALENESS", DefaultDBReplicaMaxStaleness),
+			ReplicaStatsMaxStaleness: getEnvInt("DB_REPLICA_STATS_MAX_STALENESS", DefaultDBReplicaStatsMaxStaleness),
+			ReplicaCheckInterval:     getEnvInt("DB_REPLICA_CHECK_INTERVAL", DefaultDBReplicaCheckInterval),
+		},
+		Notifications: NotificationConfig{
+			Timeout: getEnvInt("NOTIFY_TIMEOUT", DefaultNotifyTimeout),
+		},
+		Attachments: AttachmentConfig{
+			Backend:      getEnv("ATTACHMENT_BACKEND", DefaultAttachmentBackend),
+			Dir:          getEnv("ATTACHMENT_DIR", DefaultAttachmentDir),
+			MaxSize:      int64(getEnvInt("ATTACHMENT_MAX_SIZE", DefaultAttachmentMaxSize)),
+			AllowedTypes: getEnvList("ATTACHMENT_ALLOWED_TYPES", DefaultAttachmentTypes),
+			S3: S3Config{
+				Endpoint:        getEnv("S3_ENDPOINT", ""),
+				Region:          getEnv("S3_REGION", ""),
+				Bucket:          getEnv("S3_BUCKET", ""),
+				Prefix:          getEnv("S3_PREFIX", ""),
+				AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+				SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+				UsePathStyle:    getEnvBool("S3_USE_PATH_STYLE"),
+			},
+		},
+		Artifacts: ArtifactConfig{
+			Backend:         getEnv("ARTIFACT_BACKEND", DefaultArtifactBackend),
+			Dir:             getEnv("ARTIFACT_DIR", DefaultArtifactDir),
+			MaxInlineOutput: getEnvInt("ARTIFACT_MAX_INLINE_OUTPUT", DefaultArtifactMaxInlineOutput),
+			S3: S3Config{
+				Endpoint:        getEnv("S3_ENDPOINT", ""),
+				Region:          getEnv("S3_REGION", ""),
+				Bucket:          getEnv("ARTIFACT_S3_BUCKET", ""),
+				Prefix:          getEnv("ARTIFACT_S3_PREFIX", ""),
+				AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+				SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+				UsePathStyle:    getEnvBool("S3_USE_PATH_STYLE"),
+			},
 		},
+		Outbox: OutboxConfig{
+			Enabled:      getEnvBool("OUTBOX_ENABLED"),
+			PollInterval: getEnvInt("OUTBOX_POLL_INTERVAL", DefaultOutboxPollInterval),
+			BatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", DefaultOutboxBatchSize),
+			Retention:    getEnvInt("OUTBOX_RETENTION", DefaultOutboxRetention),
+			WebhookURLs:  getEnvList("OUTBOX_WEBHOOK_URLS", nil),
+			Kafka: KafkaConfig{
+				Brokers:  getEnvList("KAFKA_BROKERS", nil),
+				Topic:    getEnv("KAFKA_TOPIC", DefaultKafkaTopic),
+				Format:   getEnv("KAFKA_FORMAT", DefaultKafkaFormat),
+				ClientID: getEnv("KAFKA_CLIENT_ID", DefaultKafkaClientID),
+			},
+		},
+		Events: EventConfig{
+			Retention:       getEnvInt("EVENT_RETENTION", DefaultEventRetention),
+			KeepLatest:      getEnvInt("EVENT_KEEP_LATEST", DefaultEventKeepLatest),
+			CompactInterval: getEnvInt("EVENT_COMPACT_INTERVAL", DefaultEventCompactInterval),
+		},
+		IDs: IDConfig{
+			Generator: getEnv("ID_GENERATOR", DefaultIDGenerator),
+			NodeID:    getEnvInt("ID_NODE_ID", DefaultIDNodeID),
+		},
+		SLA: SLAConfig{
+			CheckInterval: getEnvInt("SLA_CHECK_INTERVAL", DefaultSLACheckInterval),
+			BatchSize:     getEnvInt("SLA_BATCH_SIZE", DefaultSLABatchSize),
+		},
+		QueueTime: QueueTimeConfig{
+			CheckInterval:    getEnvInt("QUEUE_TIME_CHECK_INTERVAL", DefaultQueueTimeCheckInterval),
+			MaxAge:           getEnvInt("QUEUE_TIME_MAX_AGE", 0),
+			EscalatePriority: getEnv("QUEUE_TIME_ESCALATE_PRIORITY", ""),
+			BatchSize:        getEnvInt("QUEUE_TIME_BATCH_SIZE", DefaultQueueTimeBatchSize),
+		},
+		Workflow: WorkflowConfig{
+			CheckInterval: getEnvInt("WORKFLOW_CHECK_INTERVAL", DefaultWorkflowCheckInterval),
+			BatchSize:     getEnvInt("WORKFLOW_BATCH_SIZE", DefaultWorkflowBatchSize),
+		},
+		Integrity: IntegrityConfig{
+			CheckInterval: getEnvInt("INTEGRITY_CHECK_INTERVAL", DefaultIntegrityCheckInterval),
+			Repair:        getEnvBool("INTEGRITY_REPAIR"),
+			LeaseTimeout:  getEnvInt("INTEGRITY_LEASE_TIMEOUT", DefaultIntegrityLeaseTimeout),
+		},
+		Anomaly: AnomalyConfig{
+			CheckInterval:   getEnvInt("ANOMALY_CHECK_INTERVAL", DefaultAnomalyCheckInterval),
+			Window:          getEnvInt("ANOMALY_WINDOW", DefaultAnomalyWindow),
+			BaselineWindows: getEnvInt("ANOMALY_BASELINE_WINDOWS", DefaultAnomalyBaselineWindows),
+			Threshold:       getEnvFloat("ANOMALY_THRESHOLD", DefaultAnomalyThreshold),
+			MinSamples:      getEnvInt("ANOMALY_MIN_SAMPLES", DefaultAnomalyMinSamples),
+		},
+		Secrets: SecretsConfig{
+			MasterKey: getEnv("SECRETS_MASTER_KEY", ""),
+		},
+		Redaction: RedactionConfig{
+			SensitiveKeys: getEnvList("REDACT_SENSITIVE_KEYS", DefaultSensitiveKeys),
+			AdminUsers:    getEnvList("REDACT_ADMIN_USERS", nil),
+		},
+		Access: AccessConfig{
+			AdminUsers:       getEnvList("ACCESS_ADMIN_USERS", nil),
+			PublicUnassigned: getEnvBool("ACCESS_PUBLIC_UNASSIGNED"),
+		},
+		API: APIConfig{
+			V1DeprecatedAt:   getEnv("API_V1_DEPRECATED_AT", ""),
+			V1SunsetAt:       getEnv("API_V1_SUNSET_AT", ""),
+			DeprecationLink:  getEnv("API_DEPRECATION_LINK", ""),
+			ResponseNaming:   getEnv("API_RESPONSE_NAMING", DefaultResponseNaming),
+			ResponseEnvelope: getEnv("API_RESPONSE_ENVELOPE", ""),
+		},
+		Compression: CompressionConfig{
+			MinSize:      getEnvInt("HTTP_COMPRESS_MIN_SIZE", DefaultCompressMinSize),
+			Level:        getEnvInt("HTTP_COMPRESS_LEVEL", DefaultCompressLevel),
+			ContentTypes: getEnvList("HTTP_COMPRESS_TYPES", DefaultCompressTypes),
+		},
+		Cache: CacheConfig{
+			Enabled:           getEnvBool("TASK_CACHE_ENABLED"),
+			Backend:           getEnv("TASK_CACHE_BACKEND", DefaultTaskCacheBackend),
+			Size:              getEnvInt("TASK_CACHE_SIZE", DefaultTaskCacheSize),
+			TTL:               getEnvInt("TASK_CACHE_TTL", DefaultTaskCacheTTL),
+			EventPollInterval: getEnvInt("TASK_CACHE_EVENT_POLL_INTERVAL", DefaultTaskCacheEventPollInterval),
+			RedisAddr:         getEnv("TASK_CACHE_REDIS_ADDR", ""),
+			RedisPassword:     getEnv("TASK_CACHE_REDIS_PASSWORD", ""),
+			RedisDB:           getEnvInt("TASK_CACHE_REDIS_DB", 0),
+		},
+	}
+
+	// 通知渠道仅从配置文件读取
+	if v.IsSet("notifications.channels") {
+		_ = v.UnmarshalKey("notifications.channels", &cfg.Notifications.Channels)
+	}
+
+	// 配置文件中的附件配置覆盖环境变量默认值
+	if v.IsSet("attachments") {
+		_ = v.UnmarshalKey("attachments", &cfg.Attachments)
+	}
+
+	// 配置文件中的产物存储配置覆盖环境变量默认值
+	if v.IsSet("artifacts") {
+		_ = v.UnmarshalKey("artifacts", &cfg.Artifacts)
+	}
+
+	// 配置文件中的发件箱配置覆盖环境变量默认值
+	if v.IsSet("outbox") {
+		_ = v.UnmarshalKey("outbox", &cfg.Outbox)
+	}
+
+	// 配置文件中的任务事件保留配置覆盖环境变量默认值
+	if v.IsSet("events") {
+		_ = v.UnmarshalKey("events", &cfg.Events)
+	}
+
+	// 配置文件中的 ID 生成配置覆盖环境变量默认值
+	if v.IsSet("ids") {
+		_ = v.UnmarshalKey("ids", &cfg.IDs)
+	}
+
+	// 配置文件中的 SLA 监控配置覆盖环境变量默认值
+	if v.IsSet("sla") {
+		_ = v.UnmarshalKey("sla", &cfg.SLA)
+	}
+
+	// 配置文件中的排队时长监控配置覆盖环境变量默认值
+	if v.IsSet("queue_time") {
+		_ = v.UnmarshalKey("queue_time", &cfg.QueueTime)
+	}
+
+	// 配置文件中的工作流截止时间监控配置覆盖环境变量默认值
+	if v.IsSet("workflow") {
+		_ = v.UnmarshalKey("workflow", &cfg.Workflow)
+	}
+
+	// 配置文件中的失败率异常检测配置覆盖环境变量默认值
+	if v.IsSet("anomaly") {
+		_ = v.UnmarshalKey("anomaly", &cfg.Anomaly)
+	}
+
+	// 配置文件中的数据完整性检查配置覆盖环境变量默认值
+	if v.IsSet("integrity") {
+		_ = v.UnmarshalKey("integrity", &cfg.Integrity)
+	}
+
+	// 配置文件中的脱敏配置覆盖环境变量默认值
+	if v.IsSet("redaction") {
+		_ = v.UnmarshalKey("redaction", &cfg.Redaction)
+	}
+
+	// 配置文件中的任务可见性配置覆盖环境变量默认值
+	if v.IsSet("access") {
+		_ = v.UnmarshalKey("access", &cfg.Access)
+	}
+
+	// 配置文件中的 API 版本配置覆盖环境变量默认值
+	if v.IsSet("api") {
+		_ = v.UnmarshalKey("api", &cfg.API)
+	}
+
+	// 配置文件中的响应压缩配置覆盖环境变量默认值
+	if v.IsSet("compression") {
+		_ = v.UnmarshalKey("compression", &cfg.Compression)
+	}
+
+	// 配置文件中的任务缓存配置覆盖环境变量默认值
+	if v.IsSet("cache") {
+		_ = v.UnmarshalKey("cache", &cfg.Cache)
+	}
+
+	// 任务分片仅从配置文件读取
+	if v.IsSet("sharding") {
+		_ = v.UnmarshalKey("sharding", &cfg.Sharding)
+	}
+
+	// 配置文件中的密钥配置覆盖环境变量默认值
+	if v.IsSet("secrets.master_key") {
+		cfg.Secrets.MasterKey = v.GetString("secrets.master_key")
+	}
+
+	// 配置文件中的就绪队列后端配置覆盖环境变量默认值
+	if v.IsSet("queue.backend") {
+		cfg.Queue.Backend = v.GetString("queue.backend")
+	}
+	if v.IsSet("queue.redis_addr") {
+		cfg.Queue.RedisAddr = v.GetString("queue.redis_addr")
+	}
+	if v.IsSet("queue.redis_db") {
+		cfg.Queue.RedisDB = v.GetInt("queue.redis_db")
 	}
 	return cfg
 }
@@ -296,6 +858,12 @@ func (c *Config) Validate() error {
 	if c.Server.MaxConns > 10000 {
 		errs = append(errs, fmt.Sprintf("MAX_CONNECTIONS should not exceed 10000, got %d", c.Server.MaxConns))
 	}
+	if c.Server.MaxStreams <= 0 {
+		errs = append(errs, fmt.Sprintf("MAX_STREAMS must be greater than 0, got %d", c.Server.MaxStreams))
+	}
+	if c.Server.MaxStreams > 10000 {
+		errs = append(errs, fmt.Sprintf("MAX_STREAMS should not exceed 10000, got %d", c.Server.MaxStreams))
+	}
 
 	// 验证LogLevel
 	validLogLevels := map[string]bool{
@@ -305,6 +873,19 @@ func (c *Config) Validate() error {
 		errs = append(errs, fmt.Sprintf("LOG_LEVEL must be one of [debug, info, warn, error], got %s", c.Server.LogLevel))
 	}
 
+	// 验证Storage
+	if c.Server.Storage != "sqlite" && c.Server.Storage != "memory" {
+		errs = append(errs, fmt.Sprintf("TASKFLOW_STORAGE must be one of [sqlite, memory], got %s", c.Server.Storage))
+	}
+
+	// 验证调试令牌，过短的令牌容易被猜中
+	if c.Server.DebugToken != "" && len(c.Server.DebugToken) < 16 {
+		errs = append(errs, fmt.Sprintf("DEBUG_TOKEN must be at least 16 characters, got %d", len(c.Server.DebugToken)))
+	}
+	if c.Server.AdminToken != "" && len(c.Server.AdminToken) < 16 {
+		errs = append(errs, fmt.Sprintf("ADMIN_TOKEN must be at least 16 characters, got %d", len(c.Server.AdminToken)))
+	}
+
 	// 验证Worker配置
 	if c.Worker.Count <= 0 {
 		errs = append(errs, fmt.Sprintf("WORKER_COUNT must be greater than 0, got %d", c.Worker.Count))
@@ -378,6 +959,276 @@ func (c *Config) Validate() error {
 	if c.Database.MaxRetries < 0 {
 		errs = append(errs, fmt.Sprintf("DB_MAX_RETRIES must be non-negative, got %d", c.Database.MaxRetries))
 	}
+	if c.Database.SlowQueryThreshold < 0 {
+		errs = append(errs, fmt.Sprintf("DB_SLOW_QUERY_THRESHOLD must be non-negative, got %d", c.Database.SlowQueryThreshold))
+	}
+	errs = append(errs, c.Database.validateFieldKeys()...)
+
+	// 验证通知渠道
+	for i, ch := range c.Notifications.Channels {
+		if ch.Name == "" {
+			errs = append(errs, fmt.Sprintf("notifications.channels[%d].name cannot be empty", i))
+		}
+		if ch.URL == "" {
+			errs = append(errs, fmt.Sprintf("notifications.channels[%d].url cannot be empty", i))
+		}
+		if ch.Type != "" && ch.Type != "webhook" && ch.Type != "slack" {
+			errs = append(errs, fmt.Sprintf("notifications.channels[%d].type must be one of [webhook, slack], got %s", i, ch.Type))
+		}
+	}
+
+	// 验证附件存储
+	switch c.Attachments.Backend {
+	case "", "none", "local":
+	case "s3", "gcs":
+		if c.Attachments.S3.Bucket == "" {
+			errs = append(errs, fmt.Sprintf("S3_BUCKET is required when ATTACHMENT_BACKEND is %s", c.Attachments.Backend))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("ATTACHMENT_BACKEND must be one of [local, s3, gcs, none], got %s", c.Attachments.Backend))
+	}
+
+	// 验证产物存储
+	switch c.Artifacts.Backend {
+	case "", "none", "local":
+	case "s3", "gcs":
+		if c.Artifacts.S3.Bucket == "" {
+			errs = append(errs, fmt.Sprintf("ARTIFACT_S3_BUCKET is required when ARTIFACT_BACKEND is %s", c.Artifacts.Backend))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("ARTIFACT_BACKEND must be one of [local, s3, gcs, none], got %s", c.Artifacts.Backend))
+	}
+	if c.Artifacts.MaxInlineOutput < 0 {
+		errs = append(errs, fmt.Sprintf("ARTIFACT_MAX_INLINE_OUTPUT must be non-negative, got %d", c.Artifacts.MaxInlineOutput))
+	}
+	if c.Attachments.MaxSize < 0 {
+		errs = append(errs, fmt.Sprintf("ATTACHMENT_MAX_SIZE must be non-negative, got %d", c.Attachments.MaxSize))
+	}
+
+	// 验证 ID 生成器
+	switch c.IDs.Generator {
+	case "uuid", "ulid", "snowflake":
+	default:
+		errs = append(errs, fmt.Sprintf("ID_GENERATOR must be one of [uuid, ulid, snowflake], got %s", c.IDs.Generator))
+	}
+	if c.IDs.NodeID < 0 || c.IDs.NodeID > 1023 {
+		errs = append(errs, fmt.Sprintf("ID_NODE_ID must be between 0 and 1023, got %d", c.IDs.NodeID))
+	}
+
+	// 验证 SLA 监控
+	if c.SLA.CheckInterval < 0 {
+		errs = append(errs, fmt.Sprintf("SLA_CHECK_INTERVAL must be non-negative, got %d", c.SLA.CheckInterval))
+	}
+	if c.SLA.BatchSize < 0 {
+		errs = append(errs, fmt.Sprintf("SLA_BATCH_SIZE must be non-negative, got %d", c.SLA.BatchSize))
+	}
+
+	// 验证排队时长监控
+	if c.QueueTime.CheckInterval < 0 {
+		errs = append(errs, fmt.Sprintf("QUEUE_TIME_CHECK_INTERVAL must be non-negative, got %d", c.QueueTime.CheckInterval))
+	}
+	if c.QueueTime.MaxAge < 0 {
+		errs = append(errs, fmt.Sprintf("QUEUE_TIME_MAX_AGE must be non-negative, got %d", c.QueueTime.MaxAge))
+	}
+	for taskType, maxAge := range c.QueueTime.MaxAgeByType {
+		if maxAge < 0 {
+			errs = append(errs, fmt.Sprintf("queue_time.max_age_by_type.%s must be non-negative, got %d", taskType, maxAge))
+		}
+	}
+	validPriorities := map[string]bool{"LOW": true, "NORMAL": true, "HIGH": true, "URGENT": true}
+	if p := c.QueueTime.EscalatePriority; p != "" && !validPriorities[strings.ToUpper(p)] {
+		errs = append(errs, fmt.Sprintf("QUEUE_TIME_ESCALATE_PRIORITY must be one of LOW, NORMAL, HIGH, URGENT, got %q", p))
+	}
+	if c.QueueTime.BatchSize < 0 {
+		errs = append(errs, fmt.Sprintf("QUEUE_TIME_BATCH_SIZE must be non-negative, got %d", c.QueueTime.BatchSize))
+	}
+
+	// 验证工作流截止时间监控
+	if c.Workflow.CheckInterval < 0 {
+		errs = append(errs, fmt.Sprintf("WORKFLOW_CHECK_INTERVAL must be non-negative, got %d", c.Workflow.CheckInterval))
+	}
+	if c.Workflow.BatchSize < 0 {
+		errs = append(errs, fmt.Sprintf("WORKFLOW_BATCH_SIZE must be non-negative, got %d", c.Workflow.BatchSize))
+	}
+
+	// 验证数据完整性检查
+	if c.Integrity.CheckInterval < 0 {
+		errs = append(errs, fmt.Sprintf("INTEGRITY_CHECK_INTERVAL must be non-negative, got %d", c.Integrity.CheckInterval))
+	}
+	if c.Integrity.LeaseTimeout <= 0 {
+		errs = append(errs, fmt.Sprintf("INTEGRITY_LEASE_TIMEOUT must be greater than 0, got %d", c.Integrity.LeaseTimeout))
+	}
+
+	// 验证失败率异常检测
+	if c.Anomaly.CheckInterval < 0 {
+		errs = append(errs, fmt.Sprintf("ANOMALY_CHECK_INTERVAL must be non-negative, got %d", c.Anomaly.CheckInterval))
+	}
+	if c.Anomaly.CheckInterval > 0 {
+		if c.Anomaly.Window <= 0 {
+			errs = append(errs, fmt.Sprintf("ANOMALY_WINDOW must be greater than 0, got %d", c.Anomaly.Window))
+		}
+		if c.Anomaly.BaselineWindows < 2 {
+			errs = append(errs, fmt.Sprintf("ANOMALY_BASELINE_WINDOWS must be at least 2, got %d", c.Anomaly.BaselineWindows))
+		}
+		if c.Anomaly.Threshold <= 0 {
+			errs = append(errs, fmt.Sprintf("ANOMALY_THRESHOLD must be greater than 0, got %g", c.Anomaly.Threshold))
+		}
+		if c.Anomaly.MinSamples < 1 {
+			errs = append(errs, fmt.Sprintf("ANOMALY_MIN_SAMPLES must be at least 1, got %d", c.Anomaly.MinSamples))
+		}
+	}
+
+	// 验证发件箱中继
+	if c.Outbox.Enabled {
+		if c.Outbox.PollInterval <= 0 {
+			errs = append(errs, fmt.Sprintf("OUTBOX_POLL_INTERVAL must be greater than 0, got %d", c.Outbox.PollInterval))
+		}
+		if c.Outbox.BatchSize <= 0 {
+			errs = append(errs, fmt.Sprintf("OUTBOX_BATCH_SIZE must be greater than 0, got %d", c.Outbox.BatchSize))
+		}
+		if c.Outbox.Retention < 0 {
+			errs = append(errs, fmt.Sprintf("OUTBOX_RETENTION must be non-negative, got %d", c.Outbox.Retention))
+		}
+		for i, u := range c.Outbox.WebhookURLs {
+			if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+				errs = append(errs, fmt.Sprintf("outbox.webhook_urls[%d] must be an http(s) URL, got %s", i, u))
+			}
+		}
+		if len(c.Outbox.Kafka.Brokers) > 0 {
+			if c.Outbox.Kafka.Topic == "" {
+				errs = append(errs, "KAFKA_TOPIC is required when KAFKA_BROKERS is set")
+			}
+			if c.Outbox.Kafka.Format != "json" && c.Outbox.Kafka.Format != "proto" {
+				errs = append(errs, fmt.Sprintf("KAFKA_FORMAT must be one of [json, proto], got %s", c.Outbox.Kafka.Format))
+			}
+		}
+	}
+
+	// 验证任务事件保留
+	if c.Events.Retention < 0 {
+		errs = append(errs, fmt.Sprintf("EVENT_RETENTION must be non-negative, got %d", c.Events.Retention))
+	}
+	if c.Events.Retention > 0 {
+		if c.Events.KeepLatest < 0 {
+			errs = append(errs, fmt.Sprintf("EVENT_KEEP_LATEST must be non-negative, got %d", c.Events.KeepLatest))
+		}
+		if c.Events.CompactInterval <= 0 {
+			errs = append(errs, fmt.Sprintf("EVENT_COMPACT_INTERVAL must be greater than 0, got %d", c.Events.CompactInterval))
+		}
+	}
+
+	// 验证任务缓存
+	if c.Cache.Enabled {
+		switch c.Cache.Backend {
+		case "memory":
+			if c.Cache.Size <= 0 {
+				errs = append(errs, fmt.Sprintf("TASK_CACHE_SIZE must be greater than 0, got %d", c.Cache.Size))
+			}
+		case "redis":
+			if c.Cache.RedisAddr == "" {
+				errs = append(errs, "TASK_CACHE_REDIS_ADDR is required when TASK_CACHE_BACKEND is redis")
+			}
+			// 缓存的是解密后的任务，共享缓存会绕过列加密
+			if c.Database.FieldEncryptionKey != "" {
+				errs = append(errs, "TASK_CACHE_BACKEND redis cannot be used with DB_FIELD_ENCRYPTION_KEY")
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("TASK_CACHE_BACKEND must be one of [memory, redis], got %s", c.Cache.Backend))
+		}
+		if c.Cache.TTL < 0 {
+			errs = append(errs, fmt.Sprintf("TASK_CACHE_TTL must be non-negative, got %d", c.Cache.TTL))
+		}
+		if c.Cache.EventPollInterval < 0 {
+			errs = append(errs, fmt.Sprintf("TASK_CACHE_EVENT_POLL_INTERVAL must be non-negative, got %d", c.Cache.EventPollInterval))
+		}
+	}
+
+	// 验证任务分片
+	if len(c.Sharding.Shards) > 0 && c.Server.Storage != "sqlite" {
+		errs = append(errs, "sharding.shards requires TASKFLOW_STORAGE sqlite")
+	}
+	namespaces := make(map[string]bool)
+	dbPaths := map[string]bool{c.Server.DBPath: true}
+	for i, shard := range c.Sharding.Shards {
+		if shard.Namespace == "" {
+			errs = append(errs, fmt.Sprintf("sharding.shards[%d].namespace is required", i))
+		} else if namespaces[shard.Namespace] {
+			errs = append(errs, fmt.Sprintf("sharding.shards[%d].namespace is duplicated: %s", i, shard.Namespace))
+		}
+		namespaces[shard.Namespace] = true
+		if shard.DBPath == "" {
+			errs = append(errs, fmt.Sprintf("sharding.shards[%d].db_path is required", i))
+		} else if dbPaths[shard.DBPath] {
+			errs = append(errs, fmt.Sprintf("sharding.shards[%d].db_path must differ from the main and other shard databases: %s", i, shard.DBPath))
+		}
+		dbPaths[shard.DBPath] = true
+	}
+
+	// 验证只读副本
+	if len(c.Database.ReadReplicas) > 0 {
+		if c.Server.Storage != "sqlite" {
+			errs = append(errs, "DB_READ_REPLICAS requires TASKFLOW_STORAGE sqlite")
+		}
+		if len(c.Sharding.Shards) > 0 {
+			errs = append(errs, "DB_READ_REPLICAS cannot be used with sharding.shards")
+		}
+		if c.Database.ReplicaCheckInterval <= 0 {
+			errs = append(errs, fmt.Sprintf("DB_REPLICA_CHECK_INTERVAL must be greater than 0, got %d", c.Database.ReplicaCheckInterval))
+		}
+		for i, replica := range c.Database.ReadReplicas {
+			if replica == "" || replica == c.Server.DBPath {
+				errs = append(errs, fmt.Sprintf("DB_READ_REPLICAS[%d] must be a path other than the primary database", i))
+			}
+		}
+	}
+	if c.Database.ReplicaMaxStaleness < 0 {
+		errs = append(errs, fmt.Sprintf("DB_REPLICA_MAX_STALENESS must be non-negative, got %d", c.Database.ReplicaMaxStaleness))
+	}
+	if c.Database.ReplicaStatsMaxStaleness < 0 {
+		errs = append(errs, fmt.Sprintf("DB_REPLICA_STATS_MAX_STALENESS must be non-negative, got %d", c.Database.ReplicaStatsMaxStaleness))
+	}
+
+	// 验证脱敏模式
+	for i, p := range c.Redaction.SensitiveKeys {
+		if _, err := path.Match(strings.ToLower(p), ""); err != nil {
+			errs = append(errs, fmt.Sprintf("redaction.sensitive_keys[%d] is not a valid pattern: %s", i, p))
+		}
+	}
+
+	// 验证 API 版本弃用计划
+	for _, field := range [][2]string{{"API_V1_DEPRECATED_AT", c.API.V1DeprecatedAt}, {"API_V1_SUNSET_AT", c.API.V1SunsetAt}} {
+		if field[1] == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, field[1]); err != nil {
+			errs = append(errs, fmt.Sprintf("%s must be an RFC3339 timestamp, got %s", field[0], field[1]))
+		}
+	}
+	if deprecatedAt, sunsetAt := c.API.V1Schedule(); !deprecatedAt.IsZero() && !sunsetAt.IsZero() && sunsetAt.Before(deprecatedAt) {
+		errs = append(errs, "API_V1_SUNSET_AT must not be before API_V1_DEPRECATED_AT")
+	}
+	if c.API.ResponseNaming != "snake" && c.API.ResponseNaming != "camel" {
+		errs = append(errs, fmt.Sprintf("API_RESPONSE_NAMING must be snake or camel, got %s", c.API.ResponseNaming))
+	}
+	if c.API.ResponseEnvelope != "" && c.API.ResponseEnvelope != "enveloped" && c.API.ResponseEnvelope != "bare" {
+		errs = append(errs, fmt.Sprintf("API_RESPONSE_ENVELOPE must be enveloped or bare, got %s", c.API.ResponseEnvelope))
+	}
+
+	// 验证响应压缩
+	if c.Compression.MinSize < 0 {
+		errs = append(errs, fmt.Sprintf("HTTP_COMPRESS_MIN_SIZE must be >= 0, got %d", c.Compression.MinSize))
+	}
+	if c.Compression.Level < -1 || c.Compression.Level > 9 {
+		errs = append(errs, fmt.Sprintf("HTTP_COMPRESS_LEVEL must be between -1 and 9, got %d", c.Compression.Level))
+	}
+
+	// 验证密钥主密钥
+	if c.Secrets.MasterKey != "" {
+		key, err := base64.StdEncoding.DecodeString(c.Secrets.MasterKey)
+		if err != nil || len(key) != 32 {
+			errs = append(errs, "SECRETS_MASTER_KEY must be a base64-encoded 32-byte key")
+		}
+	}
 
 	if len(errs) > 0 {
 		return fmt.Errorf("configuration validation failed: %s", strings.Join(errs, "; "))
@@ -428,6 +1279,18 @@ func (c *Config) GetTimeout() time.Duration {
 	return time.Duration(c.Server.Timeout) * time.Second
 }
 
+// ExpandPath 把以 ~ 开头的路径展开到用户主目录
+func ExpandPath(p string) string {
+	if !strings.HasPrefix(p, "~") {
+		return p
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return path.Join(homeDir, strings.TrimPrefix(p, "~/"))
+}
+
 // Helper functions
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -456,6 +1319,30 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // ValidateWorker 验证Worker配置（独立方法）
 func (c *Config) ValidateWorker() error {
 	return c.Worker.Validate()
@@ -594,12 +1481,46 @@ func (q *QueueConfig) Validate() error {
 		errs = append(errs, "QUEUE_DLX and QUEUE_DLQ must both be set or both be empty")
 	}
 
+	switch q.Backend {
+	case "", "none", "memory":
+	case "redis":
+		if q.RedisAddr == "" {
+			errs = append(errs, "QUEUE_REDIS_ADDR is required when QUEUE_BACKEND is redis")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("QUEUE_BACKEND must be one of [none, memory, redis], got %s", q.Backend))
+	}
+	if q.RedisDB < 0 {
+		errs = append(errs, fmt.Sprintf("QUEUE_REDIS_DB must be non-negative, got %d", q.RedisDB))
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
 	return nil
 }
 
+// validateFieldKeys 验证列加密主密钥格式 key_id:base64(32字节)
+func (d *DatabaseConfig) validateFieldKeys() []string {
+	var errs []string
+	check := func(name, value string) {
+		id, encoded, ok := strings.Cut(value, ":")
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if !ok || id == "" || err != nil || len(key) != 32 {
+			errs = append(errs, fmt.Sprintf("%s must be key_id:base64 of a 32-byte key", name))
+		}
+	}
+	if d.FieldEncryptionKey != "" {
+		check("DB_FIELD_ENCRYPTION_KEY", d.FieldEncryptionKey)
+	} else if len(d.FieldDecryptionKeys) > 0 {
+		errs = append(errs, "DB_FIELD_DECRYPTION_KEYS requires DB_FIELD_ENCRYPTION_KEY")
+	}
+	for i, k := range d.FieldDecryptionKeys {
+		check(fmt.Sprintf("DB_FIELD_DECRYPTION_KEYS[%d]", i), k)
+	}
+	return errs
+}
+
 // ValidateDatabase 验证Database配置（独立方法）
 func (c *Config) ValidateDatabase() error {
 	return c.Database.Validate()
@@ -686,6 +1607,11 @@ func (d *DatabaseConfig) Validate() error {
 		errs = append(errs, fmt.Sprintf("DB_RETRY_DELAY should not exceed 60000 ms (1m), got %d", d.RetryDelay))
 	}
 
+	if d.SlowQueryThreshold < 0 {
+		errs = append(errs, fmt.Sprintf("DB_SLOW_QUERY_THRESHOLD must be non-negative, got %d", d.SlowQueryThreshold))
+	}
+	errs = append(errs, d.validateFieldKeys()...)
+
 	if d.TablePrefix != "" && len(d.TablePrefix) > 16 {
 		errs = append(errs, fmt.Sprintf("DB_TABLE_PREFIX should not exceed 16 characters, got %d", len(d.TablePrefix)))
 	}
@@ -15,6 +15,13 @@ const (
 	ErrCodeInvalidState    ErrorCode = 1006  // 状态无效
 	ErrCodeTimeout         ErrorCode = 1007  // 超时
 	ErrCodeRateLimit       ErrorCode = 1008  // 限流
+	ErrCodeServerBusy      ErrorCode = 1009  // 服务繁忙（并发超限）
+	ErrCodePayloadTooLarge ErrorCode = 1010  // 请求内容过大
+	ErrCodeUnsupportedType ErrorCode = 1011  // 不支持的内容类型
+	ErrCodeConflict        ErrorCode = 1012  // 并发修改冲突
+	ErrCodeQuotaExceeded   ErrorCode = 1013  // 超出配额
+	ErrCodeResumeTokenExpired ErrorCode = 1014 // 需要补发的事件已被清理，无法续传
+	ErrCodePreconditionFailed ErrorCode = 1015 // 资源版本与请求的前提条件（If-Match / expected_version）不符
 
 	// 任务相关错误 (2xxx)
 	ErrCodeTaskNotFound       ErrorCode = 2000 // 任务不存在
@@ -24,16 +31,26 @@ const (
 	ErrCodeTaskTimeout         ErrorCode = 2004 // 任务执行超时
 	ErrCodeTaskDependency      ErrorCode = 2005 // 任务依赖未满足
 	ErrCodeTaskRetryExhausted  ErrorCode = 2006 // 重试次数耗尽
+	ErrCodeTaskInvalidTransition ErrorCode = 2007 // 不允许的状态转换
+	ErrCodeTaskDependencyCycle   ErrorCode = 2008 // 任务依赖存在环
+	ErrCodeTaskFieldNotEditable  ErrorCode = 2009 // 任务当前状态不允许修改该字段
+	ErrCodeTaskHasDependents     ErrorCode = 2010 // 任务仍被未结束的任务依赖
 
 	// 存储相关错误 (3xxx)
-	ErrCodeDBError        ErrorCode = 3000 // 数据库错误
-	ErrCodeDBNotConnected ErrorCode = 3001 // 数据库未连接
-	ErrCodeDBTransaction  ErrorCode = 3002 // 事务错误
+	ErrCodeDBError         ErrorCode = 3000 // 数据库错误
+	ErrCodeDBNotConnected  ErrorCode = 3001 // 数据库未连接
+	ErrCodeDBTransaction   ErrorCode = 3002 // 事务错误
+	ErrCodeBlobStore       ErrorCode = 3003 // 对象存储错误
+	ErrCodeBlobDisabled    ErrorCode = 3004 // 对象存储未启用
+	ErrCodeSecretsDisabled ErrorCode = 3005 // 密钥功能未启用
 
 	// gRPC 相关错误 (4xxx)
 	ErrCodeGRPCNotReady   ErrorCode = 4000 // gRPC 服务未就绪
 	ErrCodeGRPCConnection ErrorCode = 4001 // gRPC 连接错误
 	ErrCodeGRPCDeadline   ErrorCode = 4002 // gRPC 超时
+
+	// 调度器相关错误 (5xxx)
+	ErrCodeSchedulerUnavailable ErrorCode = 5000 // 调度器不可用
 )
 
 // ErrorCodeMap 错误码到错误消息的映射
@@ -49,6 +66,13 @@ var ErrorCodeMap = map[ErrorCode]string{
 	ErrCodeInvalidState:   "invalid state",
 	ErrCodeTimeout:        "timeout",
 	ErrCodeRateLimit:      "rate limit exceeded",
+	ErrCodeServerBusy:     "server busy",
+	ErrCodePayloadTooLarge: "payload too large",
+	ErrCodeUnsupportedType: "unsupported content type",
+	ErrCodeConflict:        "conflict",
+	ErrCodeQuotaExceeded:   "quota exceeded",
+	ErrCodeResumeTokenExpired: "resume token expired",
+	ErrCodePreconditionFailed: "precondition failed",
 
 	// 任务相关
 	ErrCodeTaskNotFound:       "task not found",
@@ -58,16 +82,80 @@ var ErrorCodeMap = map[ErrorCode]string{
 	ErrCodeTaskTimeout:        "task timeout",
 	ErrCodeTaskDependency:    "task dependency not satisfied",
 	ErrCodeTaskRetryExhausted: "task retry exhausted",
+	ErrCodeTaskInvalidTransition: "invalid task status transition",
+	ErrCodeTaskDependencyCycle:   "task dependency cycle",
+	ErrCodeTaskFieldNotEditable:  "task field not editable in current status",
+	ErrCodeTaskHasDependents:     "task has active dependents",
 
 	// 存储相关
-	ErrCodeDBError:        "database error",
-	ErrCodeDBNotConnected: "database not connected",
-	ErrCodeDBTransaction:  "database transaction error",
+	ErrCodeDBError:         "database error",
+	ErrCodeDBNotConnected:  "database not connected",
+	ErrCodeDBTransaction:   "database transaction error",
+	ErrCodeBlobStore:       "blob storage error",
+	ErrCodeBlobDisabled:    "blob storage disabled",
+	ErrCodeSecretsDisabled: "secrets disabled",
 
 	// gRPC 相关
 	ErrCodeGRPCNotReady:   "gRPC service not ready",
 	ErrCodeGRPCConnection: "gRPC connection error",
 	ErrCodeGRPCDeadline:  "gRPC deadline exceeded",
+
+	// 调度器相关
+	ErrCodeSchedulerUnavailable: "scheduler not available",
+}
+
+// ErrorReasonMap 错误码到机器可读原因的映射，随 HTTP 响应的 reason 字段和 gRPC ErrorInfo 返回，
+// 客户端应按 reason 而不是 message 判断错误类型
+var ErrorReasonMap = map[ErrorCode]string{
+	ErrCodeUnknown:         "UNKNOWN",
+	ErrCodeInvalidParam:    "INVALID_ARGUMENT",
+	ErrCodeUnauthorized:    "UNAUTHENTICATED",
+	ErrCodeForbidden:       "PERMISSION_DENIED",
+	ErrCodeNotFound:        "NOT_FOUND",
+	ErrCodeAlreadyExists:   "ALREADY_EXISTS",
+	ErrCodeInvalidState:    "INVALID_STATE",
+	ErrCodeTimeout:         "TIMEOUT",
+	ErrCodeRateLimit:       "RATE_LIMITED",
+	ErrCodeServerBusy:      "SERVER_BUSY",
+	ErrCodePayloadTooLarge: "PAYLOAD_TOO_LARGE",
+	ErrCodeUnsupportedType: "UNSUPPORTED_MEDIA_TYPE",
+	ErrCodeConflict:        "CONFLICT",
+	ErrCodeQuotaExceeded:   "QUOTA_EXCEEDED",
+	ErrCodeResumeTokenExpired: "RESUME_TOKEN_EXPIRED",
+	ErrCodePreconditionFailed: "PRECONDITION_FAILED",
+
+	ErrCodeTaskNotFound:          "TASK_NOT_FOUND",
+	ErrCodeTaskAlreadyRunning:    "TASK_ALREADY_RUNNING",
+	ErrCodeTaskTerminated:        "TASK_TERMINATED",
+	ErrCodeTaskCancelled:         "TASK_CANCELLED",
+	ErrCodeTaskTimeout:           "TASK_TIMEOUT",
+	ErrCodeTaskDependency:        "TASK_DEPENDENCY_UNSATISFIED",
+	ErrCodeTaskRetryExhausted:    "TASK_RETRY_EXHAUSTED",
+	ErrCodeTaskInvalidTransition: "TASK_INVALID_TRANSITION",
+	ErrCodeTaskDependencyCycle:   "TASK_DEPENDENCY_CYCLE",
+	ErrCodeTaskFieldNotEditable:  "TASK_FIELD_NOT_EDITABLE",
+	ErrCodeTaskHasDependents:     "TASK_HAS_DEPENDENTS",
+
+	ErrCodeDBError:         "DATABASE_ERROR",
+	ErrCodeDBNotConnected:  "DATABASE_NOT_CONNECTED",
+	ErrCodeDBTransaction:   "DATABASE_TRANSACTION_ERROR",
+	ErrCodeBlobStore:       "BLOB_STORE_ERROR",
+	ErrCodeBlobDisabled:    "BLOB_STORE_DISABLED",
+	ErrCodeSecretsDisabled: "SECRETS_DISABLED",
+
+	ErrCodeGRPCNotReady:   "GRPC_NOT_READY",
+	ErrCodeGRPCConnection: "GRPC_CONNECTION_ERROR",
+	ErrCodeGRPCDeadline:   "GRPC_DEADLINE_EXCEEDED",
+
+	ErrCodeSchedulerUnavailable: "SCHEDULER_UNAVAILABLE",
+}
+
+// GetCodeReason 获取错误码对应的机器可读原因
+func GetCodeReason(code ErrorCode) string {
+	if reason, ok := ErrorReasonMap[code]; ok {
+		return reason
+	}
+	return "UNKNOWN"
 }
 
 // GetCodeMsg 获取错误码对应的消息
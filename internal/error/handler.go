@@ -4,18 +4,32 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
 )
 
+// ErrorDomain gRPC ErrorInfo 中的错误域
+const ErrorDomain = "taskflow"
+
+// Coder 自带错误码的错误，例如 service 包的类型化错误；
+// FromError 和 HandleGinError 据此映射 HTTP 状态码和 gRPC 状态码
+type Coder interface {
+	error
+	ErrorCode() ErrorCode
+}
+
 // TaskError 任务服务错误结构
 type TaskError struct {
-	Code       ErrorCode `json:"code"`
-	Message    string    `json:"message"`
-	Detail     string    `json:"detail,omitempty"`
-	HTTPStatus int       `json:"-"`
+	Code       ErrorCode        `json:"code"`
+	Message    string           `json:"message"`
+	Detail     string           `json:"detail,omitempty"`
+	Violations []FieldViolation `json:"errors,omitempty"` // 不满足前置条件的字段，如当前状态下不可修改的字段
+	HTTPStatus int              `json:"-"`
 }
 
 // Error 实现 error 接口
@@ -51,6 +65,12 @@ func NewTaskErrorWithMsg(code ErrorCode, msg, detail string) *TaskError {
 	}
 }
 
+// WithViolations 附加字段级的前置条件错误，gRPC 中放入 PreconditionFailure details
+func (e *TaskError) WithViolations(violations ...FieldViolation) *TaskError {
+	e.Violations = append(e.Violations, violations...)
+	return e
+}
+
 // HTTPStatusFromCode 将错误码转换为 HTTP 状态码
 func HTTPStatusFromCode(code ErrorCode) int {
 	switch code {
@@ -64,51 +84,123 @@ func HTTPStatusFromCode(code ErrorCode) int {
 		return http.StatusForbidden
 	case ErrCodeNotFound, ErrCodeTaskNotFound:
 		return http.StatusNotFound
-	case ErrCodeAlreadyExists:
+	case ErrCodeAlreadyExists, ErrCodeConflict, ErrCodeTaskInvalidTransition, ErrCodeTaskFieldNotEditable, ErrCodeTaskHasDependents:
 		return http.StatusConflict
-	case ErrCodeInvalidState, ErrCodeTaskAlreadyRunning, ErrCodeTaskTerminated, ErrCodeTaskCancelled:
+	case ErrCodeInvalidState, ErrCodeTaskAlreadyRunning, ErrCodeTaskTerminated, ErrCodeTaskCancelled,
+		ErrCodeTaskDependency, ErrCodeTaskRetryExhausted, ErrCodeTaskDependencyCycle:
 		return http.StatusBadRequest
 	case ErrCodeTimeout, ErrCodeTaskTimeout, ErrCodeGRPCDeadline:
 		return http.StatusGatewayTimeout
-	case ErrCodeRateLimit:
+	case ErrCodeRateLimit, ErrCodeQuotaExceeded:
 		return http.StatusTooManyRequests
-	case ErrCodeDBError, ErrCodeDBNotConnected, ErrCodeDBTransaction, ErrCodeUnknown:
+	case ErrCodeServerBusy, ErrCodeBlobDisabled, ErrCodeSecretsDisabled, ErrCodeSchedulerUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrCodePayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrCodeUnsupportedType:
+		return http.StatusUnsupportedMediaType
+	case ErrCodeResumeTokenExpired:
+		return http.StatusGone
+	case ErrCodePreconditionFailed:
+		return http.StatusPreconditionFailed
+	case ErrCodeDBError, ErrCodeDBNotConnected, ErrCodeDBTransaction, ErrCodeBlobStore, ErrCodeUnknown:
 		return http.StatusInternalServerError
 	default:
 		return http.StatusInternalServerError
 	}
 }
 
-// ToGRPCStatus 将 TaskError 转换为 gRPC status
-func (e *TaskError) ToGRPCStatus() *status.Status {
-	switch e.Code {
+// GRPCCodeFromCode 将错误码转换为 gRPC 状态码
+func GRPCCodeFromCode(code ErrorCode) codes.Code {
+	switch code {
 	case ErrCodeSuccess:
-		return status.New(codes.OK, e.Message)
-	case ErrCodeInvalidParam:
-		return status.New(codes.InvalidArgument, e.Message)
+		return codes.OK
+	case ErrCodeInvalidParam, ErrCodePayloadTooLarge, ErrCodeUnsupportedType, ErrCodeTaskDependencyCycle:
+		return codes.InvalidArgument
 	case ErrCodeUnauthorized:
-		return status.New(codes.Unauthenticated, e.Message)
+		return codes.Unauthenticated
 	case ErrCodeForbidden:
-		return status.New(codes.PermissionDenied, e.Message)
+		return codes.PermissionDenied
 	case ErrCodeNotFound, ErrCodeTaskNotFound:
-		return status.New(codes.NotFound, e.Message)
+		return codes.NotFound
 	case ErrCodeAlreadyExists:
-		return status.New(codes.AlreadyExists, e.Message)
-	case ErrCodeTimeout, ErrCodeTaskTimeout:
-		return status.New(codes.DeadlineExceeded, e.Message)
-	case ErrCodeRateLimit:
-		return status.New(codes.ResourceExhausted, e.Message)
-	case ErrCodeDBError, ErrCodeDBNotConnected, ErrCodeDBTransaction:
-		return status.New(codes.Internal, e.Message)
-	case ErrCodeGRPCNotReady, ErrCodeGRPCConnection:
-		return status.New(codes.Unavailable, e.Message)
+		return codes.AlreadyExists
+	case ErrCodeConflict:
+		return codes.Aborted
+	case ErrCodeInvalidState, ErrCodeTaskAlreadyRunning, ErrCodeTaskTerminated, ErrCodeTaskCancelled,
+		ErrCodeTaskDependency, ErrCodeTaskRetryExhausted, ErrCodeTaskInvalidTransition, ErrCodeTaskFieldNotEditable,
+		ErrCodeTaskHasDependents, ErrCodePreconditionFailed:
+		return codes.FailedPrecondition
+	case ErrCodeTimeout, ErrCodeTaskTimeout, ErrCodeGRPCDeadline:
+		return codes.DeadlineExceeded
+	case ErrCodeRateLimit, ErrCodeServerBusy, ErrCodeQuotaExceeded:
+		return codes.ResourceExhausted
+	case ErrCodeResumeTokenExpired:
+		return codes.OutOfRange
+	case ErrCodeDBError, ErrCodeDBNotConnected, ErrCodeDBTransaction, ErrCodeBlobStore:
+		return codes.Internal
+	case ErrCodeGRPCNotReady, ErrCodeGRPCConnection, ErrCodeBlobDisabled, ErrCodeSecretsDisabled, ErrCodeSchedulerUnavailable:
+		return codes.Unavailable
 	default:
-		return status.New(codes.Unknown, e.Message)
+		return codes.Unknown
 	}
 }
 
-// FromGRPCStatus 从 gRPC status 创建 TaskError
+// ToGRPCStatus 将 TaskError 转换为 gRPC status，错误码、原因和详情放入 ErrorInfo details，
+// 字段级错误放入 PreconditionFailure details，经 FromGRPCStatus 可以还原
+func (e *TaskError) ToGRPCStatus() *status.Status {
+	st := status.New(GRPCCodeFromCode(e.Code), e.Message)
+	if e.Code == ErrCodeSuccess {
+		return st
+	}
+
+	info := &errdetails.ErrorInfo{
+		Reason:   GetCodeReason(e.Code),
+		Domain:   ErrorDomain,
+		Metadata: map[string]string{"code": strconv.Itoa(int(e.Code))},
+	}
+	if e.Detail != "" {
+		info.Metadata["detail"] = e.Detail
+	}
+	details := []protoadapt.MessageV1{info}
+	if len(e.Violations) > 0 {
+		pf := &errdetails.PreconditionFailure{}
+		for _, v := range e.Violations {
+			pf.Violations = append(pf.Violations, &errdetails.PreconditionFailure_Violation{
+				Type:        v.Rule,
+				Subject:     v.Field,
+				Description: v.Message,
+			})
+		}
+		details = append(details, pf)
+	}
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// FromGRPCStatus 从 gRPC status 创建 TaskError，带有本服务 ErrorInfo 时还原原始错误码、详情和字段级错误
 func FromGRPCStatus(s *status.Status) *TaskError {
+	for _, d := range s.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != ErrorDomain {
+			continue
+		}
+		if n, err := strconv.Atoi(info.Metadata["code"]); err == nil {
+			taskErr := NewTaskErrorWithMsg(ErrorCode(n), s.Message(), info.Metadata["detail"])
+			for _, d := range s.Details() {
+				if pf, ok := d.(*errdetails.PreconditionFailure); ok {
+					for _, v := range pf.Violations {
+						taskErr.Violations = append(taskErr.Violations, NewFieldViolation(v.Subject, v.Type, v.Description))
+					}
+				}
+			}
+			return taskErr
+		}
+	}
+
 	code := ErrCodeUnknown
 	httpStatus := http.StatusInternalServerError
 
@@ -131,6 +223,12 @@ func FromGRPCStatus(s *status.Status) *TaskError {
 	case codes.AlreadyExists:
 		code = ErrCodeAlreadyExists
 		httpStatus = http.StatusConflict
+	case codes.Aborted:
+		code = ErrCodeConflict
+		httpStatus = http.StatusConflict
+	case codes.FailedPrecondition:
+		code = ErrCodeInvalidState
+		httpStatus = http.StatusBadRequest
 	case codes.DeadlineExceeded:
 		code = ErrCodeTimeout
 		httpStatus = http.StatusGatewayTimeout
@@ -154,34 +252,85 @@ func FromGRPCStatus(s *status.Status) *TaskError {
 
 // GinErrorResponse Gin 错误响应结构
 type GinErrorResponse struct {
-	Code    ErrorCode `json:"code"`
-	Message string    `json:"message"`
-	Detail  string    `json:"detail,omitempty"`
+	Code    ErrorCode        `json:"code"`
+	Reason  string           `json:"reason"` // 机器可读的错误原因，如 TASK_NOT_FOUND
+	Message string           `json:"message"`
+	Detail  string           `json:"detail,omitempty"`
+	Errors  []FieldViolation `json:"errors,omitempty"`
 }
 
 // ToGinResponse 转换为 Gin JSON 响应
 func (e *TaskError) ToGinResponse() GinErrorResponse {
 	return GinErrorResponse{
 		Code:    e.Code,
+		Reason:  GetCodeReason(e.Code),
 		Message: e.Message,
 		Detail:  e.Detail,
+		Errors:  e.Violations,
 	}
 }
 
-// HandleGinError 处理 Gin 错误响应
-func HandleGinError(c *gin.Context, err error) {
+// FromError 将任意错误转换为 TaskError：TaskError 原样返回，Coder 按自带的错误码转换，
+// gRPC status 错误经 FromGRPCStatus 还原，其余视为未知错误
+func FromError(err error) *TaskError {
 	if err == nil {
-		return
+		return nil
 	}
 
 	var taskErr *TaskError
 	if errors.As(err, &taskErr) {
-		c.JSON(taskErr.HTTPStatus, taskErr.ToGinResponse())
+		return taskErr
+	}
+	var coder Coder
+	if errors.As(err, &coder) {
+		return NewTaskError(coder.ErrorCode(), err.Error())
+	}
+	if st, ok := status.FromError(err); ok {
+		return FromGRPCStatus(st)
+	}
+	return NewTaskError(ErrCodeUnknown, err.Error())
+}
+
+// ToGRPCError 将错误转换为 gRPC status 错误，供 gRPC handler 直接返回；
+// 已经是 gRPC status 的错误原样返回
+func ToGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.ToGRPCStatus().Err()
+	}
+	var taskErr *TaskError
+	var coder Coder
+	if !errors.As(err, &taskErr) && !errors.As(err, &coder) {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+	}
+	return FromError(err).ToGRPCStatus().Err()
+}
+
+// HandleGinError 处理 Gin 错误响应，gRPC handler 返回的 status 错误按其中的错误码和字段错误输出
+func HandleGinError(c *gin.Context, err error) {
+	if err == nil {
 		return
 	}
 
-	// 未知错误
-	taskErr = NewTaskError(ErrCodeUnknown, err.Error())
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		c.JSON(http.StatusBadRequest, verr.ToGinResponse())
+		return
+	}
+	if st, ok := status.FromError(err); ok {
+		if verr := FromGRPCBadRequest(st); verr != nil {
+			c.JSON(http.StatusBadRequest, verr.ToGinResponse())
+			return
+		}
+	}
+
+	taskErr := FromError(err)
 	c.JSON(taskErr.HTTPStatus, taskErr.ToGinResponse())
 }
 
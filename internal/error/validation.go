@@ -0,0 +1,213 @@
+package errorcode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FieldViolation 单个字段的校验错误
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError 请求校验错误，包含所有字段级错误
+type ValidationError struct {
+	Violations []FieldViolation `json:"errors"`
+}
+
+// NewValidationError 创建校验错误
+func NewValidationError(violations ...FieldViolation) *ValidationError {
+	return &ValidationError{Violations: violations}
+}
+
+// NewFieldViolation 创建字段校验错误
+func NewFieldViolation(field, rule, message string) FieldViolation {
+	return FieldViolation{Field: field, Rule: rule, Message: message}
+}
+
+// Add 追加字段错误
+func (e *ValidationError) Add(field, rule, message string) {
+	e.Violations = append(e.Violations, NewFieldViolation(field, rule, message))
+}
+
+// HasErrors 是否存在字段错误
+func (e *ValidationError) HasErrors() bool {
+	return len(e.Violations) > 0
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, fmt.Sprintf("%s: %s", v.Field, v.Message))
+	}
+	return fmt.Sprintf("[%d] %s: %s", ErrCodeInvalidParam, GetCodeMsg(ErrCodeInvalidParam), strings.Join(parts, "; "))
+}
+
+// ToGinResponse 转换为 Gin JSON 响应
+func (e *ValidationError) ToGinResponse() GinErrorResponse {
+	return GinErrorResponse{
+		Code:    ErrCodeInvalidParam,
+		Reason:  GetCodeReason(ErrCodeInvalidParam),
+		Message: GetCodeMsg(ErrCodeInvalidParam),
+		Errors:  e.Violations,
+	}
+}
+
+// ToGRPCStatus 转换为 gRPC status，字段错误放入 BadRequest details
+func (e *ValidationError) ToGRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, GetCodeMsg(ErrCodeInvalidParam))
+
+	br := &errdetails.BadRequest{}
+	for _, v := range e.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Message,
+			Reason:      v.Rule,
+		})
+	}
+
+	detailed, err := st.WithDetails(br)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// FromGRPCBadRequest 从带 BadRequest details 的 gRPC status 还原校验错误，没有字段错误时返回 nil
+func FromGRPCBadRequest(s *status.Status) *ValidationError {
+	if s.Code() != codes.InvalidArgument {
+		return nil
+	}
+	for _, d := range s.Details() {
+		br, ok := d.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		ve := NewValidationError()
+		for _, v := range br.FieldViolations {
+			ve.Add(v.Field, v.Reason, v.Description)
+		}
+		if ve.HasErrors() {
+			return ve
+		}
+	}
+	return nil
+}
+
+// FromBindingError 将 Gin 绑定错误转换为校验错误
+func FromBindingError(err error) *ValidationError {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		ve := NewValidationError()
+		for _, fe := range verrs {
+			ve.Add(fieldPath(fe), fe.Tag(), ruleMessage(fe))
+		}
+		return ve
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return NewValidationError(NewFieldViolation(typeErr.Field, "type",
+			fmt.Sprintf("must be of type %s", typeErr.Type.String())))
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return NewValidationError(NewFieldViolation("", "json",
+			fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)))
+	}
+
+	return NewValidationError(NewFieldViolation("", "body", err.Error()))
+}
+
+// BindJSON 绑定请求体，失败时写入结构化 400 响应并返回 false
+func BindJSON(c *gin.Context, obj interface{}) bool {
+	registerJSONTagNames()
+
+	if err := c.ShouldBindJSON(obj); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, FromBindingError(err).ToGinResponse())
+		return false
+	}
+	return true
+}
+
+// ValidateJSON Gin 中间件：请求体绑定到 newObj() 返回的结构，
+// 校验通过后存入上下文 key "request_body"，失败时直接返回结构化错误
+func ValidateJSON(newObj func() interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		obj := newObj()
+		if !BindJSON(c, obj) {
+			return
+		}
+		c.Set("request_body", obj)
+		c.Next()
+	}
+}
+
+var registerOnce sync.Once
+
+// registerJSONTagNames 让校验错误中的字段名使用 json tag
+func registerJSONTagNames() {
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	})
+}
+
+// fieldPath 去掉顶层结构名，例如 "req.input_params" -> "input_params"
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+// ruleMessage 生成可读的规则描述
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of [%s]", fe.Param())
+	case "len":
+		return fmt.Sprintf("must have length %s", fe.Param())
+	default:
+		return fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+	}
+}
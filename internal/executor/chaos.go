@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// ChaosFaults 故障注入参数，零值表示不注入故障
+type ChaosFaults struct {
+	FailureRate float64          `json:"failure_rate"`          // 执行失败的概率，0 到 1
+	ErrorClass  model.ErrorClass `json:"error_class,omitempty"` // 注入失败的分类：retryable、fatal、rate_limited；为空时按任务的重试策略处理
+	PanicRate   float64          `json:"panic_rate"`            // 执行器 panic 的概率，0 到 1，先于失败判定
+	LatencyMs   int64            `json:"latency_ms"`            // 每次执行前的固定延迟
+	JitterMs    int64            `json:"jitter_ms"`             // 在固定延迟之上附加 0 到 JitterMs 的随机延迟
+}
+
+// Validate 检查参数范围
+func (f ChaosFaults) Validate() error {
+	if f.FailureRate < 0 || f.FailureRate > 1 {
+		return fmt.Errorf("failure_rate must be between 0 and 1, got %v", f.FailureRate)
+	}
+	if f.PanicRate < 0 || f.PanicRate > 1 {
+		return fmt.Errorf("panic_rate must be between 0 and 1, got %v", f.PanicRate)
+	}
+	if f.LatencyMs < 0 || f.JitterMs < 0 {
+		return errors.New("latency_ms and jitter_ms must not be negative")
+	}
+	switch f.ErrorClass {
+	case model.ErrorClassUnknown, model.ErrorClassRetryable, model.ErrorClassFatal, model.ErrorClassRateLimited:
+	default:
+		return fmt.Errorf("error_class must be one of retryable, fatal, rate_limited, got %q", f.ErrorClass)
+	}
+	return nil
+}
+
+// Chaos 故障注入执行器，用于端到端验证重试、失败处理和告警：按当前的故障参数注入延迟、panic 或失败，
+// 未注入故障时调用 Inner（为空时直接成功）。故障参数可在运行中通过 SetFaults 调整
+type Chaos struct {
+	// Inner 未注入故障时实际执行任务的执行器，为空时返回固定输出
+	Inner service.Executor
+
+	mu     sync.RWMutex
+	faults ChaosFaults
+	random func() float64 // [0, 1) 随机数，测试中替换
+}
+
+// NewChaos 创建故障注入执行器，inner 可以为 nil
+func NewChaos(inner service.Executor) *Chaos {
+	return &Chaos{Inner: inner, random: rand.Float64}
+}
+
+// Faults 当前的故障参数
+func (c *Chaos) Faults() ChaosFaults {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.faults
+}
+
+// SetFaults 替换故障参数，对之后开始的执行生效
+func (c *Chaos) SetFaults(f ChaosFaults) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = f
+	return nil
+}
+
+// Execute 实现 service.Executor 接口
+func (c *Chaos) Execute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	c.mu.RLock()
+	f := c.faults
+	delay := time.Duration(f.LatencyMs) * time.Millisecond
+	if f.JitterMs > 0 {
+		delay += time.Duration(c.random() * float64(f.JitterMs) * float64(time.Millisecond))
+	}
+	panics := f.PanicRate > 0 && c.random() < f.PanicRate
+	fails := f.FailureRate > 0 && c.random() < f.FailureRate
+	c.mu.RUnlock()
+
+	logs := service.ExecutionContextFrom(ctx)
+	if delay > 0 {
+		logs.Logf("chaos: injecting %s latency", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if panics {
+		panic(fmt.Sprintf("chaos: injected panic in task %s", task.ID))
+	}
+	if fails {
+		logs.Logf("chaos: injecting failure")
+		err := fmt.Errorf("chaos: injected failure in task %s", task.ID)
+		switch f.ErrorClass {
+		case model.ErrorClassRetryable:
+			return nil, service.Retryable(err)
+		case model.ErrorClassFatal:
+			return nil, service.Fatal(err)
+		case model.ErrorClassRateLimited:
+			return nil, service.RateLimited(err, 0)
+		}
+		return nil, err
+	}
+
+	if c.Inner != nil {
+		return c.Inner.Execute(ctx, task)
+	}
+	return map[string]string{"chaos": "ok"}, nil
+}
@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+func TestChaos_InjectsFaults(t *testing.T) {
+	inner := service.ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		return map[string]string{"inner": "true"}, nil
+	})
+	c := NewChaos(inner)
+	task := &model.Task{ID: "t1"}
+
+	// 未设置故障时交给 Inner 执行
+	if out, err := c.Execute(context.Background(), task); err != nil || out["inner"] != "true" {
+		t.Fatalf("expected inner executor to run, got %v, %v", out, err)
+	}
+
+	roll := 0.5
+	c.random = func() float64 { return roll }
+	if err := c.SetFaults(ChaosFaults{FailureRate: 0.6, ErrorClass: model.ErrorClassFatal}); err != nil {
+		t.Fatalf("SetFaults: %v", err)
+	}
+	_, err := c.Execute(context.Background(), task)
+	if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal {
+		t.Errorf("expected injected fatal failure, got %v (%s)", err, class)
+	}
+	roll = 0.7
+	if _, err := c.Execute(context.Background(), task); err != nil {
+		t.Errorf("expected roll above failure rate to succeed, got %v", err)
+	}
+
+	c.SetFaults(ChaosFaults{PanicRate: 1})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected injected panic")
+			}
+		}()
+		c.Execute(context.Background(), task)
+	}()
+
+	// 延迟可被 ctx 取消
+	c.SetFaults(ChaosFaults{LatencyMs: time.Hour.Milliseconds()})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Execute(ctx, task); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected latency to honour ctx, got %v", err)
+	}
+}
+
+func TestChaosFaults_Validate(t *testing.T) {
+	for _, f := range []ChaosFaults{
+		{FailureRate: 1.5},
+		{PanicRate: -0.1},
+		{LatencyMs: -1},
+		{FailureRate: 0.5, ErrorClass: "flaky"},
+	} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", f)
+		}
+	}
+	if err := (ChaosFaults{FailureRate: 0.2, ErrorClass: model.ErrorClassRateLimited, LatencyMs: 50, JitterMs: 10}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
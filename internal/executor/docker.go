@@ -0,0 +1,388 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+const (
+	// DefaultDockerHost 默认的 Docker 守护进程地址
+	DefaultDockerHost = "unix:///var/run/docker.sock"
+	// dockerAPIVersion 使用的 Docker Engine API 版本（Docker 20.10 起支持）
+	dockerAPIVersion = "v1.41"
+	// dockerCleanupTimeout 任务结束后删除容器的超时，不受任务 ctx 约束
+	dockerCleanupTimeout = 30 * time.Second
+)
+
+// 容器任务的输入参数
+const (
+	DockerParamImage   = "image"   // 镜像，必填
+	DockerParamCommand = "command" // 命令，JSON 数组或以空白分隔的字符串，为空时使用镜像默认命令
+	DockerParamEnv     = "env"     // 环境变量，JSON 对象或逗号分隔的 KEY=VALUE
+	DockerParamMounts  = "mounts"  // 挂载，JSON 数组或逗号分隔的 宿主路径:容器路径[:ro]
+	DockerParamTimeout = "timeout" // 执行超时，如 10m，超时后容器被强制结束
+)
+
+// Docker 容器执行器：按任务参数通过 Docker Engine API 创建并启动容器，容器输出实时写入任务日志，
+// 退出码 0 视为成功；ctx 取消或超时时结束容器，无论结果如何容器都会被删除
+type Docker struct {
+	// Host 守护进程地址，unix:///path 或 tcp://host:port；为空时使用 DOCKER_HOST 环境变量或默认套接字
+	Host string
+	// AllowedMounts 允许挂载的宿主路径前缀，为空时禁止任务挂载宿主目录
+	AllowedMounts []string
+	// Timeout 默认执行超时，任务参数 timeout 可覆盖；0 表示不限制
+	Timeout time.Duration
+
+	client  *http.Client
+	baseURL string
+}
+
+// NewDocker 创建容器执行器
+func NewDocker(host string) (*Docker, error) {
+	d := &Docker{Host: host}
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = DefaultDockerHost
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		d.client = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}}
+		d.baseURL = "http://docker"
+	case "tcp", "http":
+		d.client = &http.Client{}
+		d.baseURL = "http://" + u.Host
+	case "https":
+		d.client = &http.Client{}
+		d.baseURL = "https://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q", u.Scheme)
+	}
+	return d, nil
+}
+
+// containerSpec 由任务参数解析出的容器配置
+type containerSpec struct {
+	Image   string
+	Cmd     []string
+	Env     []string
+	Binds   []string
+	Timeout time.Duration
+}
+
+// Execute 实现 service.Executor 接口
+func (d *Docker) Execute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	spec, err := d.parseSpec(task.InputParams)
+	if err != nil {
+		return nil, service.Fatal(err)
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+	ec := service.ExecutionContextFrom(ctx)
+
+	id, err := d.createContainer(ctx, task, spec)
+	if err != nil {
+		return nil, err
+	}
+	defer d.removeContainer(id)
+
+	if err := d.do(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil); err != nil {
+		return nil, service.Retryable(fmt.Errorf("failed to start container: %w", err))
+	}
+	ec.Logf("container %s started from %s", shortID(id), spec.Image)
+
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		d.streamLogs(ctx, id, ec)
+	}()
+
+	var result struct {
+		StatusCode int64
+		Error      *struct{ Message string }
+	}
+	waitErr := d.do(ctx, http.MethodPost, "/containers/"+id+"/wait", nil, &result)
+	if ctx.Err() != nil {
+		d.killContainer(id)
+		<-logsDone
+		ec.Logf("container %s killed: %v", shortID(id), ctx.Err())
+		return nil, ctx.Err()
+	}
+	<-logsDone
+	if waitErr != nil {
+		return nil, service.Retryable(fmt.Errorf("failed to wait for container: %w", waitErr))
+	}
+	if result.Error != nil && result.Error.Message != "" {
+		return nil, fmt.Errorf("container %s: %s", shortID(id), result.Error.Message)
+	}
+
+	if result.StatusCode != 0 {
+		return nil, fmt.Errorf("container %s exited with code %d", shortID(id), result.StatusCode)
+	}
+	return map[string]string{
+		"container_id": id,
+		"exit_code":    strconv.FormatInt(result.StatusCode, 10),
+	}, nil
+}
+
+// parseSpec 解析并校验任务参数
+func (d *Docker) parseSpec(params map[string]string) (*containerSpec, error) {
+	spec := &containerSpec{Image: strings.TrimSpace(params[DockerParamImage]), Timeout: d.Timeout}
+	if spec.Image == "" {
+		return nil, fmt.Errorf("param %q is required", DockerParamImage)
+	}
+
+	cmd, err := parseCommand(params[DockerParamCommand])
+	if err != nil {
+		return nil, fmt.Errorf("invalid param %q: %w", DockerParamCommand, err)
+	}
+	spec.Cmd = cmd
+	env, err := parseEnv(params[DockerParamEnv])
+	if err != nil {
+		return nil, fmt.Errorf("invalid param %q: %w", DockerParamEnv, err)
+	}
+	for _, kv := range env {
+		spec.Env = append(spec.Env, kv.Name+"="+kv.Value)
+	}
+
+	var mounts []string
+	if m := strings.TrimSpace(params[DockerParamMounts]); strings.HasPrefix(m, "[") {
+		if err := json.Unmarshal([]byte(m), &mounts); err != nil {
+			return nil, fmt.Errorf("invalid param %q: %w", DockerParamMounts, err)
+		}
+	} else {
+		mounts = splitList(m)
+	}
+	for _, m := range mounts {
+		bind, err := d.checkMount(m)
+		if err != nil {
+			return nil, err
+		}
+		spec.Binds = append(spec.Binds, bind)
+	}
+
+	if t := params[DockerParamTimeout]; t != "" {
+		timeout, err := time.ParseDuration(t)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid param %q: %q", DockerParamTimeout, t)
+		}
+		spec.Timeout = timeout
+	}
+	return spec, nil
+}
+
+// checkMount 校验挂载格式，宿主路径须位于 AllowedMounts 之下
+func (d *Docker) checkMount(mount string) (string, error) {
+	parts := strings.Split(mount, ":")
+	if len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw") {
+		return "", fmt.Errorf("invalid mount %q, want host:container[:ro]", mount)
+	}
+	src := filepath.Clean(parts[0])
+	if !filepath.IsAbs(src) || !filepath.IsAbs(parts[1]) {
+		return "", fmt.Errorf("invalid mount %q, paths must be absolute", mount)
+	}
+	for _, prefix := range d.AllowedMounts {
+		prefix = filepath.Clean(prefix)
+		if src == prefix || strings.HasPrefix(src, prefix+string(filepath.Separator)) {
+			parts[0] = src
+			return strings.Join(parts, ":"), nil
+		}
+	}
+	return "", fmt.Errorf("mount source %s is not allowed", src)
+}
+
+// createContainer 创建容器，本地没有镜像时先拉取
+func (d *Docker) createContainer(ctx context.Context, task *model.Task, spec *containerSpec) (string, error) {
+	body := map[string]interface{}{
+		"Image":  spec.Image,
+		"Cmd":    spec.Cmd,
+		"Env":    spec.Env,
+		"Labels": map[string]string{"taskflow.task_id": task.ID, "taskflow.task_type": task.TaskType},
+		"HostConfig": map[string]interface{}{
+			"Binds": spec.Binds,
+		},
+	}
+	if len(spec.Cmd) == 0 {
+		delete(body, "Cmd")
+	}
+
+	var created struct{ Id string }
+	err := d.do(ctx, http.MethodPost, "/containers/create", body, &created)
+	var apiErr *dockerError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+		service.ExecutionContextFrom(ctx).Logf("pulling image %s", spec.Image)
+		if err := d.pullImage(ctx, spec.Image); err != nil {
+			return "", err
+		}
+		err = d.do(ctx, http.MethodPost, "/containers/create", body, &created)
+	}
+	if err != nil {
+		if errors.As(err, &apiErr) && apiErr.status < http.StatusInternalServerError {
+			return "", service.Fatal(fmt.Errorf("failed to create container: %w", err))
+		}
+		return "", service.Retryable(fmt.Errorf("failed to create container: %w", err))
+	}
+	return created.Id, nil
+}
+
+// pullImage 拉取镜像，进度流中的错误消息视为失败
+func (d *Docker) pullImage(ctx context.Context, image string) error {
+	resp, err := d.request(ctx, http.MethodPost, "/images/create?fromImage="+url.QueryEscape(image), nil)
+	if err != nil {
+		var apiErr *dockerError
+		if errors.As(err, &apiErr) && apiErr.status < http.StatusInternalServerError {
+			return service.Fatal(fmt.Errorf("failed to pull image %s: %w", image, err))
+		}
+		return service.Retryable(fmt.Errorf("failed to pull image %s: %w", image, err))
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var progress struct{ Error string }
+		if err := dec.Decode(&progress); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return service.Retryable(fmt.Errorf("failed to pull image %s: %w", image, err))
+		}
+		if progress.Error != "" {
+			return service.Fatal(fmt.Errorf("failed to pull image %s: %s", image, progress.Error))
+		}
+	}
+}
+
+// streamLogs 跟随容器输出直到容器退出，按行写入任务日志
+func (d *Docker) streamLogs(ctx context.Context, id string, ec *service.ExecutionContext) {
+	resp, err := d.request(ctx, http.MethodGet, "/containers/"+id+"/logs?follow=1&stdout=1&stderr=1", nil)
+	if err != nil {
+		ec.Logf("failed to stream container logs: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	w := &lineWriter{log: ec.Log}
+	defer w.Flush()
+
+	// 未分配 TTY 的容器输出为多路复用流：8 字节帧头（流类型、3 字节填充、4 字节大端长度）+ 数据
+	r := bufio.NewReader(resp.Body)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return
+		}
+		if _, err := io.CopyN(w, r, int64(binary.BigEndian.Uint32(header[4:]))); err != nil {
+			return
+		}
+	}
+}
+
+// killContainer 强制结束容器，任务 ctx 已结束，使用独立的超时
+func (d *Docker) killContainer(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerCleanupTimeout)
+	defer cancel()
+	d.do(ctx, http.MethodPost, "/containers/"+id+"/kill", nil, nil)
+}
+
+// removeContainer 删除容器（包括仍在运行的）
+func (d *Docker) removeContainer(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerCleanupTimeout)
+	defer cancel()
+	d.do(ctx, http.MethodDelete, "/containers/"+id+"?force=1", nil, nil)
+}
+
+// dockerError Docker API 返回的错误
+type dockerError struct {
+	status  int
+	message string
+}
+
+func (e *dockerError) Error() string {
+	return fmt.Sprintf("docker API %d: %s", e.status, e.message)
+}
+
+// do 发送请求并解码 JSON 响应，out 为 nil 时丢弃响应体
+func (d *Docker) do(ctx context.Context, method, path string, in, out interface{}) error {
+	resp, err := d.request(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// request 发送请求，非 2xx 响应转换为 dockerError
+func (d *Docker) request(ctx context.Context, method, path string, in interface{}) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.baseURL+"/"+dockerAPIVersion+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var msg struct{ Message string }
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &msg) != nil || msg.Message == "" {
+			msg.Message = strings.TrimSpace(string(data))
+		}
+		return nil, &dockerError{status: resp.StatusCode, message: msg.Message}
+	}
+	return resp, nil
+}
+
+// shortID 容器 ID 的前 12 位，与 docker ps 一致
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
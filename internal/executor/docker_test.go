@@ -0,0 +1,203 @@
+package executor
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// fakeDocker 模拟 Docker Engine API 的最小子集
+type fakeDocker struct {
+	mu       sync.Mutex
+	images   map[string]bool
+	created  map[string]interface{}
+	calls    []string
+	exitCode int
+	block    chan struct{} // 非 nil 时 wait 阻塞到被 kill，创建后不再修改
+	killOnce sync.Once
+}
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+	f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/"+dockerAPIVersion)
+	switch {
+	case path == "/containers/create":
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		found := f.images[body["Image"].(string)]
+		if found {
+			f.created = body
+		}
+		f.mu.Unlock()
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No such image"})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"Id": "0123456789abcdef"})
+	case path == "/images/create":
+		image := r.URL.Query().Get("fromImage")
+		if image == "missing:latest" {
+			json.NewEncoder(w).Encode(map[string]string{"error": "manifest unknown"})
+			return
+		}
+		f.mu.Lock()
+		f.images[image] = true
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"status": "Downloaded"})
+	case strings.HasSuffix(path, "/start"):
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasSuffix(path, "/logs"):
+		for _, frame := range []struct {
+			stream byte
+			data   string
+		}{{1, "hello "}, {1, "world\npartial"}, {2, " line\n"}} {
+			header := make([]byte, 8)
+			header[0] = frame.stream
+			binary.BigEndian.PutUint32(header[4:], uint32(len(frame.data)))
+			w.Write(append(header, frame.data...))
+		}
+	case strings.HasSuffix(path, "/wait"):
+		if f.block != nil {
+			select {
+			case <-f.block:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]int{"StatusCode": f.exitCode})
+	case strings.HasSuffix(path, "/kill"):
+		if f.block != nil {
+			f.killOnce.Do(func() { close(f.block) })
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeDocker) called(call string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if c == call {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestDocker(t *testing.T, fake *fakeDocker) *Docker {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	d, err := NewDocker("tcp://" + strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("NewDocker: %v", err)
+	}
+	return d
+}
+
+func TestDocker_RunPullsImageAndRemovesContainer(t *testing.T) {
+	fake := &fakeDocker{images: map[string]bool{}}
+	d := newTestDocker(t, fake)
+	d.AllowedMounts = []string{"/srv/data"}
+
+	out, err := d.Execute(context.Background(), &model.Task{ID: "t1", InputParams: map[string]string{
+		DockerParamImage:   "alpine:latest",
+		DockerParamCommand: `["sh", "-c", "echo hi"]`,
+		DockerParamEnv:     "A=1, B=2",
+		DockerParamMounts:  "/srv/data/in:/in:ro",
+	}})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if out["exit_code"] != "0" || out["container_id"] != "0123456789abcdef" {
+		t.Errorf("output = %v", out)
+	}
+	if !fake.called("POST /" + dockerAPIVersion + "/images/create") {
+		t.Error("image was not pulled")
+	}
+	if !fake.called("DELETE /" + dockerAPIVersion + "/containers/0123456789abcdef") {
+		t.Error("container was not removed")
+	}
+
+	binds := fake.created["HostConfig"].(map[string]interface{})["Binds"].([]interface{})
+	if len(binds) != 1 || binds[0] != "/srv/data/in:/in:ro" {
+		t.Errorf("binds = %v", binds)
+	}
+	if env := fake.created["Env"].([]interface{}); len(env) != 2 || env[0] != "A=1" {
+		t.Errorf("env = %v", env)
+	}
+}
+
+func TestDocker_NonZeroExit(t *testing.T) {
+	fake := &fakeDocker{images: map[string]bool{"busybox": true}, exitCode: 2}
+	_, err := newTestDocker(t, fake).Execute(context.Background(), &model.Task{ID: "t1", InputParams: map[string]string{DockerParamImage: "busybox"}})
+	if err == nil || !strings.Contains(err.Error(), "exited with code 2") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestDocker_TimeoutKillsContainer(t *testing.T) {
+	fake := &fakeDocker{images: map[string]bool{"busybox": true}, block: make(chan struct{})}
+	d := newTestDocker(t, fake)
+
+	_, err := d.Execute(context.Background(), &model.Task{ID: "t1", InputParams: map[string]string{
+		DockerParamImage: "busybox", DockerParamTimeout: "100ms",
+	}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+	if !fake.called("POST /" + dockerAPIVersion + "/containers/0123456789abcdef/kill") {
+		t.Error("container was not killed")
+	}
+}
+
+func TestDocker_InvalidParamsAreFatal(t *testing.T) {
+	fake := &fakeDocker{images: map[string]bool{}}
+	d := newTestDocker(t, fake)
+	d.AllowedMounts = []string{"/srv/data"}
+
+	for name, params := range map[string]map[string]string{
+		"no image":          {},
+		"mount not allowed": {DockerParamImage: "busybox", DockerParamMounts: "/etc:/host-etc"},
+		"mount escape":      {DockerParamImage: "busybox", DockerParamMounts: "/srv/data/../../etc:/x"},
+		"relative mount":    {DockerParamImage: "busybox", DockerParamMounts: "data:/x"},
+		"bad env":           {DockerParamImage: "busybox", DockerParamEnv: "NOVALUE"},
+		"bad timeout":       {DockerParamImage: "busybox", DockerParamTimeout: "soon"},
+		"unknown image":     {DockerParamImage: "missing:latest"},
+	} {
+		_, err := d.Execute(context.Background(), &model.Task{ID: "t1", InputParams: params})
+		if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal {
+			t.Errorf("%s: err = %v, class = %q", name, err, class)
+		}
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{log: func(s string) { lines = append(lines, s) }}
+	w.Write([]byte("a\nb"))
+	w.Write([]byte("c\n\nd"))
+	w.Flush()
+	if got := strings.Join(lines, "|"); got != "a|bc||d" {
+		t.Errorf("lines = %q", got)
+	}
+}
@@ -0,0 +1,349 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// gRPC 调用任务的输入参数
+const (
+	GRPCParamTarget   = "target"   // 服务地址，如 billing:9090，须在 AllowedTargets 中
+	GRPCParamMethod   = "method"   // 完整方法名，如 /billing.v1.Invoices/Create
+	GRPCParamRequest  = "request"  // JSON 请求体（protobuf JSON 映射），为空表示空消息
+	GRPCParamMetadata = "metadata" // 请求元数据，JSON 对象
+	GRPCParamTimeout  = "timeout"  // 调用超时，如 30s
+)
+
+// GRPC gRPC 调用执行器：通过目标服务的 server reflection 获取方法描述，
+// 把 JSON 请求体转换为 protobuf 调用一元方法，响应以 JSON 写入任务输出的 response 字段。
+// 连接和方法描述按目标地址缓存
+type GRPC struct {
+	// AllowedTargets 允许调用的服务地址，"*" 表示不限制；为空时拒绝所有调用
+	AllowedTargets []string
+	// DialOptions 建立连接的选项，为空时使用明文连接
+	DialOptions []grpc.DialOption
+	// Timeout 默认调用超时，任务参数 timeout 可覆盖；0 表示不限制
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	conns   map[string]*grpc.ClientConn
+	methods map[string]protoreflect.MethodDescriptor // target + 方法名 -> 描述
+}
+
+// NewGRPC 创建 gRPC 调用执行器
+func NewGRPC(allowedTargets ...string) *GRPC {
+	return &GRPC{
+		AllowedTargets: allowedTargets,
+		conns:          make(map[string]*grpc.ClientConn),
+		methods:        make(map[string]protoreflect.MethodDescriptor),
+	}
+}
+
+// Close 关闭缓存的连接
+func (g *GRPC) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var errs []error
+	for target, conn := range g.conns {
+		errs = append(errs, conn.Close())
+		delete(g.conns, target)
+	}
+	g.methods = make(map[string]protoreflect.MethodDescriptor)
+	return errors.Join(errs...)
+}
+
+// Execute 实现 service.Executor 接口
+func (g *GRPC) Execute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	params := task.InputParams
+	target := strings.TrimSpace(params[GRPCParamTarget])
+	if target == "" {
+		return nil, service.Fatal(fmt.Errorf("param %q is required", GRPCParamTarget))
+	}
+	if !g.targetAllowed(target) {
+		return nil, service.Fatal(fmt.Errorf("grpc target %s is not allowed", target))
+	}
+	method := strings.TrimSpace(params[GRPCParamMethod])
+	if !strings.HasPrefix(method, "/") {
+		method = "/" + method
+	}
+	serviceName, methodName, ok := strings.Cut(method[1:], "/")
+	if !ok || serviceName == "" || methodName == "" {
+		return nil, service.Fatal(fmt.Errorf("invalid param %q: want /package.Service/Method", GRPCParamMethod))
+	}
+	md := metadata.MD{}
+	if v := strings.TrimSpace(params[GRPCParamMetadata]); v != "" {
+		var pairs map[string]string
+		if err := json.Unmarshal([]byte(v), &pairs); err != nil {
+			return nil, service.Fatal(fmt.Errorf("invalid param %q: %w", GRPCParamMetadata, err))
+		}
+		for k, v := range pairs {
+			md.Append(k, v)
+		}
+	}
+	timeout := g.Timeout
+	if v := params[GRPCParamTimeout]; v != "" {
+		var err error
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+			return nil, service.Fatal(fmt.Errorf("invalid param %q: %q", GRPCParamTimeout, v))
+		}
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	conn, err := g.conn(target)
+	if err != nil {
+		return nil, service.Fatal(err)
+	}
+	desc, err := g.method(ctx, conn, target, serviceName, methodName)
+	if err != nil {
+		return nil, err
+	}
+	if desc.IsStreamingClient() || desc.IsStreamingServer() {
+		return nil, service.Fatal(fmt.Errorf("method %s is streaming, only unary methods are supported", method))
+	}
+
+	req := dynamicpb.NewMessage(desc.Input())
+	if body := strings.TrimSpace(params[GRPCParamRequest]); body != "" {
+		if err := protojson.Unmarshal([]byte(body), req); err != nil {
+			return nil, service.Fatal(fmt.Errorf("invalid param %q: %w", GRPCParamRequest, err))
+		}
+	}
+	resp := dynamicpb.NewMessage(desc.Output())
+
+	start := time.Now()
+	if err := conn.Invoke(metadata.NewOutgoingContext(ctx, md), method, req, resp); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, classifyGRPCError(method, err)
+	}
+	service.ExecutionContextFrom(ctx).Logf("grpc %s on %s finished in %s", method, target, time.Since(start).Round(time.Millisecond))
+
+	out, err := protojson.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+	return map[string]string{
+		"response": string(out),
+		"code":     codes.OK.String(),
+	}, nil
+}
+
+func (g *GRPC) targetAllowed(target string) bool {
+	for _, allowed := range g.AllowedTargets {
+		if allowed == "*" || allowed == target {
+			return true
+		}
+	}
+	return false
+}
+
+// conn 获取或建立到 target 的连接，连接按需建立，不会在此阻塞
+func (g *GRPC) conn(target string) (*grpc.ClientConn, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if conn, ok := g.conns[target]; ok {
+		return conn, nil
+	}
+
+	opts := g.DialOptions
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grpc target %s: %w", target, err)
+	}
+	g.conns[target] = conn
+	return conn, nil
+}
+
+// method 通过 server reflection 解析方法描述并缓存
+func (g *GRPC) method(ctx context.Context, conn *grpc.ClientConn, target, serviceName, methodName string) (protoreflect.MethodDescriptor, error) {
+	key := target + "/" + serviceName + "/" + methodName
+	g.mu.Lock()
+	desc, ok := g.methods[key]
+	g.mu.Unlock()
+	if ok {
+		return desc, nil
+	}
+
+	files, err := fetchServiceFiles(ctx, conn, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, service.Fatal(fmt.Errorf("service %s not found on %s", serviceName, target))
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, service.Fatal(fmt.Errorf("%s is not a service", serviceName))
+	}
+	desc = sd.Methods().ByName(protoreflect.Name(methodName))
+	if desc == nil {
+		return nil, service.Fatal(fmt.Errorf("method %s not found in service %s", methodName, serviceName))
+	}
+
+	g.mu.Lock()
+	g.methods[key] = desc
+	g.mu.Unlock()
+	return desc, nil
+}
+
+// fetchServiceFiles 通过 reflection 获取定义服务的文件及其全部依赖，本地已注册的文件（如 well-known types）直接复用
+func fetchServiceFiles(ctx context.Context, conn *grpc.ClientConn, serviceName string) (*protoregistry.Files, error) {
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, reflectionError(err)
+	}
+	defer stream.CloseSend()
+
+	protos := make(map[string]*descriptorpb.FileDescriptorProto)
+	var order []string
+	request := func(req *rpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return reflectionError(err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return reflectionError(err)
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return service.Fatal(fmt.Errorf("server reflection: %s", e.GetErrorMessage()))
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return service.Fatal(fmt.Errorf("invalid descriptor from server reflection: %w", err))
+			}
+			if _, ok := protos[fd.GetName()]; !ok {
+				protos[fd.GetName()] = fd
+				order = append(order, fd.GetName())
+			}
+		}
+		return nil
+	}
+
+	if err := request(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: serviceName},
+	}); err != nil {
+		return nil, err
+	}
+	// 服务端可能只返回部分依赖，缺失的依赖按文件名补齐
+	for i := 0; i < len(order); i++ {
+		for _, dep := range protos[order[i]].GetDependency() {
+			if _, ok := protos[dep]; ok {
+				continue
+			}
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+				continue
+			}
+			if err := request(&rpb.ServerReflectionRequest{
+				MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	files := &protoregistry.Files{}
+	var register func(name string) error
+	register = func(name string) error {
+		if _, err := files.FindFileByPath(name); err == nil {
+			return nil
+		}
+		fd, ok := protos[name]
+		if !ok {
+			// 本地已注册
+			return nil
+		}
+		for _, dep := range fd.GetDependency() {
+			if err := register(dep); err != nil {
+				return err
+			}
+		}
+		file, err := protodesc.NewFile(fd, chainResolver{files, protoregistry.GlobalFiles})
+		if err != nil {
+			return err
+		}
+		return files.RegisterFile(file)
+	}
+	for _, name := range order {
+		if err := register(name); err != nil {
+			return nil, service.Fatal(fmt.Errorf("invalid descriptor from server reflection: %w", err))
+		}
+	}
+	return files, nil
+}
+
+// chainResolver 依次在多个文件注册表中查找
+type chainResolver []*protoregistry.Files
+
+func (c chainResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	for _, files := range c {
+		if fd, err := files.FindFileByPath(path); err == nil {
+			return fd, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (c chainResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	for _, files := range c {
+		if d, err := files.FindDescriptorByName(name); err == nil {
+			return d, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+// reflectionError 目标服务未开启 reflection 时不重试，连接问题可重试
+func reflectionError(err error) error {
+	if status.Code(err) == codes.Unimplemented {
+		return service.Fatal(errors.New("target does not support server reflection"))
+	}
+	return classifyGRPCError("server reflection", err)
+}
+
+// classifyGRPCError 按状态码为调用错误分类
+func classifyGRPCError(method string, err error) error {
+	st := status.Convert(err)
+	wrapped := fmt.Errorf("grpc %s: %s: %s", method, st.Code(), st.Message())
+	switch st.Code() {
+	case codes.Unavailable, codes.Aborted:
+		return service.Retryable(wrapped)
+	case codes.ResourceExhausted:
+		return service.RateLimited(wrapped, 0)
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.Unimplemented, codes.FailedPrecondition, codes.OutOfRange:
+		return service.Fatal(wrapped)
+	}
+	return wrapped
+}
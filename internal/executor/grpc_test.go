@@ -0,0 +1,87 @@
+package executor
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// startHealthServer 启动带 reflection 的健康检查服务，作为被调用的目标
+func startHealthServer(t *testing.T, withReflection bool) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	hs.SetServingStatus("billing", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	if withReflection {
+		reflection.Register(srv)
+	}
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestGRPC_InvokeViaReflection(t *testing.T) {
+	target := startHealthServer(t, true)
+	g := NewGRPC(target)
+	defer g.Close()
+
+	out, err := g.Execute(context.Background(), &model.Task{ID: "t1", InputParams: map[string]string{
+		GRPCParamTarget:   target,
+		GRPCParamMethod:   "grpc.health.v1.Health/Check",
+		GRPCParamRequest:  `{"service": "billing"}`,
+		GRPCParamMetadata: `{"x-request-id": "abc"}`,
+		GRPCParamTimeout:  "5s",
+	}})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(out["response"], "NOT_SERVING") || out["code"] != "OK" {
+		t.Errorf("output = %v", out)
+	}
+
+	// 服务端返回 NotFound：不重试
+	_, err = g.Execute(context.Background(), &model.Task{ID: "t2", InputParams: map[string]string{
+		GRPCParamTarget:  target,
+		GRPCParamMethod:  "/grpc.health.v1.Health/Check",
+		GRPCParamRequest: `{"service": "unknown"}`,
+	}})
+	if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal || !strings.Contains(err.Error(), "NotFound") {
+		t.Errorf("err = %v, class = %q", err, class)
+	}
+}
+
+func TestGRPC_FatalErrors(t *testing.T) {
+	target := startHealthServer(t, true)
+	plain := startHealthServer(t, false)
+	g := NewGRPC(target, plain)
+	defer g.Close()
+
+	for name, params := range map[string]map[string]string{
+		"target not allowed": {GRPCParamTarget: "10.0.0.1:443", GRPCParamMethod: "/grpc.health.v1.Health/Check"},
+		"bad method":         {GRPCParamTarget: target, GRPCParamMethod: "Check"},
+		"unknown service":    {GRPCParamTarget: target, GRPCParamMethod: "/nope.Service/Call"},
+		"unknown method":     {GRPCParamTarget: target, GRPCParamMethod: "/grpc.health.v1.Health/Nope"},
+		"streaming":          {GRPCParamTarget: target, GRPCParamMethod: "/grpc.health.v1.Health/Watch"},
+		"bad request":        {GRPCParamTarget: target, GRPCParamMethod: "/grpc.health.v1.Health/Check", GRPCParamRequest: `{"nope": 1}`},
+		"no reflection":      {GRPCParamTarget: plain, GRPCParamMethod: "/grpc.health.v1.Health/Check"},
+	} {
+		_, err := g.Execute(context.Background(), &model.Task{ID: "t1", InputParams: params})
+		if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal {
+			t.Errorf("%s: err = %v, class = %q", name, err, class)
+		}
+	}
+}
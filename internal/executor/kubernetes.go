@@ -0,0 +1,468 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// Kubernetes 执行器的默认值和限制，serviceAccountDir 为集群内 ServiceAccount 凭据的挂载位置
+const (
+	serviceAccountDir      = "/var/run/secrets/kubernetes.io/serviceaccount"
+	defaultK8sNamespace    = "default"
+	defaultK8sPollInterval = 2 * time.Second
+	defaultK8sJobTTL       = time.Hour
+	k8sCleanupTimeout      = 30 * time.Second
+	maxK8sOutputLogBytes   = 64 * 1024 // 写入任务输出的 Pod 日志上限，超出保留末尾
+	k8sJobNamePrefix       = "taskflow-"
+	maxK8sJobNameLen       = 63
+	k8sTaskIDLabel         = "taskflow.io/task-id"
+	k8sContainerName       = "task"
+)
+
+// Kubernetes Job 任务的输入参数
+const (
+	K8sParamNamespace = "namespace" // 命名空间，须在 AllowedNamespaces 中，为空时使用执行器的默认命名空间
+	K8sParamImage     = "image"     // 镜像，必填
+	K8sParamCommand   = "command"   // 命令，JSON 数组或以空白分隔的字符串，为空时使用镜像默认命令
+	K8sParamEnv       = "env"       // 环境变量，JSON 对象或逗号分隔的 KEY=VALUE
+	K8sParamCPU       = "cpu"       // CPU 请求和上限，如 500m
+	K8sParamMemory    = "memory"    // 内存请求和上限，如 512Mi
+	K8sParamTimeout   = "timeout"   // 执行超时，如 30m，写入 Job 的 activeDeadlineSeconds
+)
+
+// Kubernetes Job 执行器：每个任务（每次尝试）创建一个 Job，轮询直到完成或失败，
+// Pod 日志写入任务日志并附在任务输出中。Job 设置 ttlSecondsAfterFinished，
+// 由集群的 TTL 控制器按 TTL 清理；任务取消或超时时 Job 立即删除。
+// 重试由 taskflow 的重试策略负责，Job 的 backoffLimit 为 0
+type Kubernetes struct {
+	Host      string // API Server 地址，如 https://10.0.0.1:443
+	Token     string // Bearer Token
+	Namespace string // 默认命名空间
+	// AllowedNamespaces 任务可以指定的命名空间，为空时只允许默认命名空间
+	AllowedNamespaces []string
+	// TTL Job 结束后保留的时间，供排查问题；0 表示日志收集后立即删除
+	TTL time.Duration
+	// PollInterval 查询 Job 状态的间隔，默认 2s
+	PollInterval time.Duration
+	// ServiceAccount Pod 使用的 ServiceAccount，为空时使用命名空间的默认账号
+	ServiceAccount string
+
+	client *http.Client
+}
+
+// NewKubernetes 使用指定的 API Server 地址和凭据创建执行器，client 为 nil 时使用 http.DefaultClient
+func NewKubernetes(host, token, namespace string, client *http.Client) *Kubernetes {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if namespace == "" {
+		namespace = defaultK8sNamespace
+	}
+	return &Kubernetes{
+		Host:      strings.TrimRight(host, "/"),
+		Token:     token,
+		Namespace: namespace,
+		TTL:       defaultK8sJobTTL,
+		client:    client,
+	}
+}
+
+// NewKubernetesInCluster 在 Pod 内运行时使用挂载的 ServiceAccount 凭据创建执行器，默认命名空间为 Pod 所在的命名空间
+func NewKubernetesInCluster() (*Kubernetes, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a kubernetes cluster: KUBERNETES_SERVICE_HOST is not set")
+	}
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid cluster CA certificate")
+	}
+	namespace, _ := os.ReadFile(serviceAccountDir + "/namespace")
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	return NewKubernetes("https://"+net.JoinHostPort(host, port), strings.TrimSpace(string(token)), strings.TrimSpace(string(namespace)), client), nil
+}
+
+// jobSpec 由任务参数解析出的 Job 配置
+type jobSpec struct {
+	Namespace string
+	Image     string
+	Command   []string
+	Env       []envVar
+	Resources map[string]string
+	Timeout   time.Duration
+}
+
+// k8sJob Job 对象中用到的字段
+type k8sJob struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Status struct {
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// Execute 实现 service.Executor 接口
+func (k *Kubernetes) Execute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	spec, err := k.parseSpec(task.InputParams)
+	if err != nil {
+		return nil, service.Fatal(err)
+	}
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		// 比 activeDeadlineSeconds 稍长，优先由集群判定超时并给出原因
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout+k.pollInterval())
+		defer cancel()
+	}
+	ec := service.ExecutionContextFrom(ctx)
+
+	name := jobName(task)
+	if err := k.createJob(ctx, task, name, spec); err != nil {
+		return nil, err
+	}
+	ec.Logf("job %s/%s created from %s", spec.Namespace, name, spec.Image)
+
+	failure, err := k.waitJob(ctx, spec.Namespace, name)
+	if err != nil {
+		k.deleteJob(spec.Namespace, name)
+		if ctx.Err() != nil {
+			ec.Logf("job %s/%s deleted: %v", spec.Namespace, name, ctx.Err())
+			return nil, ctx.Err()
+		}
+		return nil, service.Retryable(fmt.Errorf("failed to watch job %s: %w", name, err))
+	}
+
+	logs := k.collectLogs(ctx, spec.Namespace, name, ec)
+	if k.TTL <= 0 {
+		k.deleteJob(spec.Namespace, name)
+	}
+
+	if failure != nil {
+		if failure.Reason == "DeadlineExceeded" {
+			return nil, fmt.Errorf("job %s: %s: %w", name, failure.Message, context.DeadlineExceeded)
+		}
+		return nil, fmt.Errorf("job %s failed: %s: %s", name, failure.Reason, failure.Message)
+	}
+	return map[string]string{
+		"job_name":  name,
+		"namespace": spec.Namespace,
+		"logs":      logs,
+	}, nil
+}
+
+// parseSpec 解析并校验任务参数
+func (k *Kubernetes) parseSpec(params map[string]string) (*jobSpec, error) {
+	spec := &jobSpec{
+		Namespace: strings.TrimSpace(params[K8sParamNamespace]),
+		Image:     strings.TrimSpace(params[K8sParamImage]),
+		Resources: make(map[string]string),
+	}
+	if spec.Image == "" {
+		return nil, fmt.Errorf("param %q is required", K8sParamImage)
+	}
+	if spec.Namespace == "" {
+		spec.Namespace = k.Namespace
+	}
+	if !k.namespaceAllowed(spec.Namespace) {
+		return nil, fmt.Errorf("namespace %q is not allowed", spec.Namespace)
+	}
+
+	var err error
+	if spec.Command, err = parseCommand(params[K8sParamCommand]); err != nil {
+		return nil, fmt.Errorf("invalid param %q: %w", K8sParamCommand, err)
+	}
+	if spec.Env, err = parseEnv(params[K8sParamEnv]); err != nil {
+		return nil, fmt.Errorf("invalid param %q: %w", K8sParamEnv, err)
+	}
+	for param, resource := range map[string]string{K8sParamCPU: "cpu", K8sParamMemory: "memory"} {
+		if v := strings.TrimSpace(params[param]); v != "" {
+			if !quantityPattern.MatchString(v) {
+				return nil, fmt.Errorf("invalid param %q: %q", param, v)
+			}
+			spec.Resources[resource] = v
+		}
+	}
+	if t := params[K8sParamTimeout]; t != "" {
+		if spec.Timeout, err = time.ParseDuration(t); err != nil || spec.Timeout < time.Second {
+			return nil, fmt.Errorf("invalid param %q: %q", K8sParamTimeout, t)
+		}
+	}
+	return spec, nil
+}
+
+// quantityPattern Kubernetes 资源数量，如 500m、1.5、512Mi、1G
+var quantityPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|k|M|G|T|P|E|Ki|Mi|Gi|Ti|Pi|Ei)?$`)
+
+func (k *Kubernetes) namespaceAllowed(ns string) bool {
+	if ns == k.Namespace {
+		return true
+	}
+	for _, allowed := range k.AllowedNamespaces {
+		if ns == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *Kubernetes) pollInterval() time.Duration {
+	if k.PollInterval > 0 {
+		return k.PollInterval
+	}
+	return defaultK8sPollInterval
+}
+
+// jobNameInvalid Job 名称中不允许的字符（DNS-1123 标签）
+var jobNameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// jobName 按任务 ID 和尝试次数生成 Job 名称，同一次尝试重复创建时命中已有的 Job
+func jobName(task *model.Task) string {
+	suffix := "-" + strconv.Itoa(int(task.RetryCount+1))
+	id := jobNameInvalid.ReplaceAllString(strings.ToLower(task.ID), "-")
+	if limit := maxK8sJobNameLen - len(k8sJobNamePrefix) - len(suffix); len(id) > limit {
+		id = id[:limit]
+	}
+	return k8sJobNamePrefix + strings.Trim(id, "-") + suffix
+}
+
+// createJob 创建 Job，已存在（同一次尝试在重启后重新执行）时沿用
+func (k *Kubernetes) createJob(ctx context.Context, task *model.Task, name string, spec *jobSpec) error {
+	container := map[string]interface{}{
+		"name":  k8sContainerName,
+		"image": spec.Image,
+	}
+	if len(spec.Command) > 0 {
+		container["command"] = spec.Command
+	}
+	if len(spec.Env) > 0 {
+		container["env"] = spec.Env
+	}
+	if len(spec.Resources) > 0 {
+		container["resources"] = map[string]interface{}{"requests": spec.Resources, "limits": spec.Resources}
+	}
+
+	podSpec := map[string]interface{}{
+		"restartPolicy": "Never",
+		"containers":    []interface{}{container},
+	}
+	if k.ServiceAccount != "" {
+		podSpec["serviceAccountName"] = k.ServiceAccount
+	}
+	jobSpec := map[string]interface{}{
+		"backoffLimit": 0,
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": map[string]string{k8sTaskIDLabel: labelValue(task.ID)}},
+			"spec":     podSpec,
+		},
+	}
+	if k.TTL > 0 {
+		jobSpec["ttlSecondsAfterFinished"] = int64(k.TTL / time.Second)
+	}
+	if spec.Timeout > 0 {
+		jobSpec["activeDeadlineSeconds"] = int64(spec.Timeout / time.Second)
+	}
+	job := map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata": map[string]interface{}{
+			"name":   name,
+			"labels": map[string]string{k8sTaskIDLabel: labelValue(task.ID)},
+		},
+		"spec": jobSpec,
+	}
+
+	err := k.do(ctx, http.MethodPost, "/apis/batch/v1/namespaces/"+spec.Namespace+"/jobs", job, nil)
+	var apiErr *k8sError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &apiErr) && apiErr.status == http.StatusConflict:
+		return nil
+	case errors.As(err, &apiErr) && apiErr.status < http.StatusInternalServerError && apiErr.status != http.StatusTooManyRequests:
+		return service.Fatal(fmt.Errorf("failed to create job: %w", err))
+	default:
+		return service.Retryable(fmt.Errorf("failed to create job: %w", err))
+	}
+}
+
+// jobFailure Job 失败的原因
+type jobFailure struct {
+	Reason  string
+	Message string
+}
+
+// waitJob 轮询 Job 直到 Complete 或 Failed，失败时返回原因；API 暂时不可用时继续轮询
+func (k *Kubernetes) waitJob(ctx context.Context, namespace, name string) (*jobFailure, error) {
+	ticker := time.NewTicker(k.pollInterval())
+	defer ticker.Stop()
+
+	for {
+		var job k8sJob
+		err := k.do(ctx, http.MethodGet, "/apis/batch/v1/namespaces/"+namespace+"/jobs/"+name, nil, &job)
+		var apiErr *k8sError
+		if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+			return nil, fmt.Errorf("job %s was deleted", name)
+		}
+		if err == nil {
+			for _, c := range job.Status.Conditions {
+				if c.Status != "True" {
+					continue
+				}
+				switch c.Type {
+				case "Complete":
+					return nil, nil
+				case "Failed":
+					return &jobFailure{Reason: c.Reason, Message: c.Message}, nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectLogs 读取 Job 的 Pod 日志写入任务日志，返回末尾部分作为任务输出
+func (k *Kubernetes) collectLogs(ctx context.Context, namespace, name string, ec *service.ExecutionContext) string {
+	var pods struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	selector := url.QueryEscape("job-name=" + name)
+	if err := k.do(ctx, http.MethodGet, "/api/v1/namespaces/"+namespace+"/pods?labelSelector="+selector, nil, &pods); err != nil {
+		ec.Logf("failed to list pods of job %s: %v", name, err)
+		return ""
+	}
+
+	var out bytes.Buffer
+	for _, pod := range pods.Items {
+		resp, err := k.request(ctx, http.MethodGet, "/api/v1/namespaces/"+namespace+"/pods/"+pod.Metadata.Name+"/log?container="+k8sContainerName, nil)
+		if err != nil {
+			ec.Logf("failed to read logs of pod %s: %v", pod.Metadata.Name, err)
+			continue
+		}
+		w := &lineWriter{log: ec.Log}
+		io.Copy(io.MultiWriter(w, &out), resp.Body)
+		w.Flush()
+		resp.Body.Close()
+	}
+
+	logs := out.Bytes()
+	if len(logs) > maxK8sOutputLogBytes {
+		logs = logs[len(logs)-maxK8sOutputLogBytes:]
+	}
+	return string(logs)
+}
+
+// deleteJob 删除 Job 及其 Pod，任务 ctx 可能已结束，使用独立的超时
+func (k *Kubernetes) deleteJob(namespace, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), k8sCleanupTimeout)
+	defer cancel()
+	k.do(ctx, http.MethodDelete, "/apis/batch/v1/namespaces/"+namespace+"/jobs/"+name, map[string]string{"propagationPolicy": "Background"}, nil)
+}
+
+// labelValue 标签值最长 63 个字符
+func labelValue(v string) string {
+	if len(v) > 63 {
+		return v[:63]
+	}
+	return v
+}
+
+// k8sError API Server 返回的错误
+type k8sError struct {
+	status  int
+	message string
+}
+
+func (e *k8sError) Error() string {
+	return fmt.Sprintf("kubernetes API %d: %s", e.status, e.message)
+}
+
+// do 发送请求并解码 JSON 响应，out 为 nil 时丢弃响应体
+func (k *Kubernetes) do(ctx context.Context, method, path string, in, out interface{}) error {
+	resp, err := k.request(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// request 发送请求，非 2xx 响应转换为 k8sError
+func (k *Kubernetes) request(ctx context.Context, method, path string, in interface{}) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, k.Host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if k.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.Token)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var status struct{ Message string }
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &status) != nil || status.Message == "" {
+			status.Message = strings.TrimSpace(string(data))
+		}
+		return nil, &k8sError{status: resp.StatusCode, message: status.Message}
+	}
+	return resp, nil
+}
@@ -0,0 +1,163 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// fakeKubernetes 模拟 API Server 中 Job、Pod 相关的接口
+type fakeKubernetes struct {
+	mu        sync.Mutex
+	job       map[string]interface{}
+	polls     int
+	condition string // 第二次查询时 Job 进入的状态：Complete、Failed 或空（一直运行）
+	reason    string
+	deleted   bool
+}
+
+func (f *fakeKubernetes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/apis/batch/v1/namespaces/jobs/jobs":
+		json.NewDecoder(r.Body).Decode(&f.job)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("{}"))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/apis/batch/v1/namespaces/jobs/jobs/"):
+		f.polls++
+		status := map[string]interface{}{}
+		if f.polls >= 2 && f.condition != "" {
+			status["conditions"] = []map[string]string{{"type": f.condition, "status": "True", "reason": f.reason, "message": "done"}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": status})
+	case r.Method == http.MethodDelete:
+		f.deleted = true
+		w.Write([]byte("{}"))
+	case r.URL.Path == "/api/v1/namespaces/jobs/pods":
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": []map[string]interface{}{{"metadata": map[string]string{"name": "pod-1"}}}})
+	case r.URL.Path == "/api/v1/namespaces/jobs/pods/pod-1/log":
+		w.Write([]byte("step 1\nstep 2\n"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestKubernetes(t *testing.T, fake *fakeKubernetes) *Kubernetes {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	k := NewKubernetes(srv.URL, "secret", "jobs", nil)
+	k.PollInterval = 10 * time.Millisecond
+	return k
+}
+
+func TestKubernetes_JobCompletes(t *testing.T) {
+	fake := &fakeKubernetes{condition: "Complete"}
+	k := newTestKubernetes(t, fake)
+
+	task := &model.Task{ID: "Task_42", TaskType: "k8s", InputParams: map[string]string{
+		K8sParamImage:   "busybox",
+		K8sParamCommand: "sh -c true",
+		K8sParamEnv:     `{"B":"2","A":"1"}`,
+		K8sParamCPU:     "500m",
+		K8sParamMemory:  "256Mi",
+		K8sParamTimeout: "10m",
+	}}
+	out, err := k.Execute(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if out["job_name"] != "taskflow-task-42-1" || out["namespace"] != "jobs" || out["logs"] != "step 1\nstep 2\n" {
+		t.Errorf("output = %v", out)
+	}
+	if fake.deleted {
+		t.Error("job should be left for the TTL controller")
+	}
+
+	spec := fake.job["spec"].(map[string]interface{})
+	if spec["ttlSecondsAfterFinished"] != float64(3600) || spec["activeDeadlineSeconds"] != float64(600) || spec["backoffLimit"] != float64(0) {
+		t.Errorf("job spec = %v", spec)
+	}
+	container := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})
+	env := container["env"].([]interface{})
+	if env[0].(map[string]interface{})["name"] != "A" {
+		t.Errorf("env = %v", env)
+	}
+	if limits := container["resources"].(map[string]interface{})["limits"].(map[string]interface{}); limits["cpu"] != "500m" || limits["memory"] != "256Mi" {
+		t.Errorf("resources = %v", container["resources"])
+	}
+}
+
+func TestKubernetes_JobFailed(t *testing.T) {
+	k := newTestKubernetes(t, &fakeKubernetes{condition: "Failed", reason: "BackoffLimitExceeded"})
+	_, err := k.Execute(context.Background(), &model.Task{ID: "t1", InputParams: map[string]string{K8sParamImage: "busybox"}})
+	if err == nil || !strings.Contains(err.Error(), "BackoffLimitExceeded") {
+		t.Errorf("err = %v", err)
+	}
+
+	k = newTestKubernetes(t, &fakeKubernetes{condition: "Failed", reason: "DeadlineExceeded"})
+	_, err = k.Execute(context.Background(), &model.Task{ID: "t1", InputParams: map[string]string{K8sParamImage: "busybox"}})
+	if class, _ := service.ClassifyError(err); class != model.ErrorClassTimeout {
+		t.Errorf("deadline exceeded: err = %v, class = %q", err, class)
+	}
+}
+
+func TestKubernetes_CancelDeletesJob(t *testing.T) {
+	fake := &fakeKubernetes{}
+	k := newTestKubernetes(t, fake)
+	k.TTL = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := k.Execute(ctx, &model.Task{ID: "t1", InputParams: map[string]string{K8sParamImage: "busybox"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v", err)
+	}
+	if !fake.deleted {
+		t.Error("job was not deleted")
+	}
+	if _, ok := fake.job["spec"].(map[string]interface{})["ttlSecondsAfterFinished"]; ok {
+		t.Error("ttl should not be set when TTL is 0")
+	}
+}
+
+func TestKubernetes_InvalidParamsAreFatal(t *testing.T) {
+	k := newTestKubernetes(t, &fakeKubernetes{})
+	k.AllowedNamespaces = []string{"batch"}
+
+	for name, params := range map[string]map[string]string{
+		"no image":         {},
+		"namespace denied": {K8sParamImage: "busybox", K8sParamNamespace: "kube-system"},
+		"bad cpu":          {K8sParamImage: "busybox", K8sParamCPU: "lots"},
+		"bad timeout":      {K8sParamImage: "busybox", K8sParamTimeout: "10ms"},
+		"bad command":      {K8sParamImage: "busybox", K8sParamCommand: "[sh"},
+	} {
+		_, err := k.Execute(context.Background(), &model.Task{ID: "t1", InputParams: params})
+		if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal {
+			t.Errorf("%s: err = %v, class = %q", name, err, class)
+		}
+	}
+}
+
+func TestJobName(t *testing.T) {
+	long := strings.Repeat("a", 80)
+	name := jobName(&model.Task{ID: long, RetryCount: 2})
+	if len(name) > maxK8sJobNameLen || !strings.HasSuffix(name, "-3") {
+		t.Errorf("jobName = %q", name)
+	}
+}
@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// envVar 环境变量
+type envVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// parseCommand 解析命令参数：JSON 数组，或以空白分隔的字符串
+func parseCommand(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "[") {
+		return strings.Fields(value), nil
+	}
+	var cmd []string
+	if err := json.Unmarshal([]byte(value), &cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// parseEnv 解析环境变量参数：JSON 对象（按变量名排序），或逗号分隔的 KEY=VALUE
+func parseEnv(value string) ([]envVar, error) {
+	value = strings.TrimSpace(value)
+	var env []envVar
+	if strings.HasPrefix(value, "{") {
+		var vars map[string]string
+		if err := json.Unmarshal([]byte(value), &vars); err != nil {
+			return nil, err
+		}
+		for k, v := range vars {
+			env = append(env, envVar{Name: k, Value: v})
+		}
+		sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+		return env, nil
+	}
+	for _, kv := range splitList(value) {
+		name, val, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q is not KEY=VALUE", kv)
+		}
+		env = append(env, envVar{Name: name, Value: val})
+	}
+	return env, nil
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// lineWriter 把字节流按行写入日志，Flush 输出最后不完整的一行
+type lineWriter struct {
+	log func(string)
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush 输出缓冲中剩余的内容
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.log(string(w.buf))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"taskflow/internal/service"
+)
+
+// Plugin 插件目录中发现的一个插件
+type Plugin struct {
+	TaskType string
+	Executor service.Executor
+}
+
+// LoadOptions 插件目录的加载选项
+type LoadOptions struct {
+	Timeout time.Duration // 每个插件的单次执行超时，0 表示不限制
+
+	// .wasm 模块的资源限制，含义同 WASM 的同名字段
+	WASMMaxMemory int64
+	WASMFuel      uint64
+}
+
+// LoadDir 扫描插件目录：每个可执行文件和 .wasm 模块是一个插件，去掉扩展名后的文件名即任务类型，
+// 如 resize-image.py 处理 resize-image 类型的任务，.wasm 模块在 WASM 沙箱中执行。
+// 隐藏文件、子目录和其他不可执行文件被忽略
+func LoadDir(dir string, opts LoadOptions) ([]Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin dir: %w", err)
+	}
+
+	var plugins []Plugin
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		// 跟随符号链接判断是否为可执行文件
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		wasm := filepath.Ext(name) == ".wasm"
+		if !wasm && info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+
+		taskType := strings.TrimSuffix(name, filepath.Ext(name))
+		if taskType == "" {
+			continue
+		}
+		if other, ok := seen[taskType]; ok {
+			return nil, fmt.Errorf("plugins %s and %s both handle task type %q", other, name, taskType)
+		}
+		seen[taskType] = name
+
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if wasm {
+			plugins = append(plugins, Plugin{TaskType: taskType, Executor: &WASM{
+				Module:    abs,
+				MaxMemory: opts.WASMMaxMemory,
+				Fuel:      opts.WASMFuel,
+				Timeout:   opts.Timeout,
+			}})
+			continue
+		}
+		exec := NewSubprocess(abs)
+		exec.Dir = dir
+		exec.Timeout = opts.Timeout
+		plugins = append(plugins, Plugin{TaskType: taskType, Executor: exec})
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].TaskType < plugins[j].TaskType })
+	return plugins, nil
+}
@@ -0,0 +1,345 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// SQL 任务的输入参数
+const (
+	SQLParamConnection = "connection" // 连接名，必填
+	SQLParamQuery      = "query"      // SQL 语句，必填，参数使用驱动的占位符（? 或 $1）
+	SQLParamArgs       = "args"       // 占位符参数，JSON 数组
+	SQLParamMaxRows    = "max_rows"   // 输出中保留的最大行数，默认 100
+	SQLParamMode       = "mode"       // query 返回结果集，exec 返回影响行数；为空时按语句的首个关键字判断
+)
+
+const (
+	defaultSQLMaxRows = 100
+	// maxSQLMaxRows max_rows 的上限，避免大结果集写入任务输出
+	maxSQLMaxRows = 1000
+)
+
+// SecretResolver 按名称解析密钥明文，secrets.Manager 实现该接口
+type SecretResolver interface {
+	Resolve(name string) (string, error)
+}
+
+// SQLConnection 外部数据库连接配置
+type SQLConnection struct {
+	Name      string
+	Driver    string // database/sql 驱动名，驱动须已在程序中导入，如 sqlite3
+	DSNSecret string // 保存 DSN 的密钥名，首次使用时解析
+	DSN       string // 明文 DSN，仅在 DSNSecret 为空时使用（本地开发）
+	ReadOnly  bool   // 只允许 query 模式
+
+	MaxConcurrency   int     // 同时执行的语句数，0 表示不限制
+	QueriesPerSecond float64 // 每秒最多开始的语句数，超出时排队等待；0 表示不限制
+}
+
+// SQL SQL 执行器：在配置的外部数据库连接上执行参数化语句，
+// 查询语句输出总行数和前 max_rows 行，其他语句输出影响行数。
+// 每个连接独立限制并发数和速率，数据库连接在首次使用时打开并复用
+type SQL struct {
+	secrets SecretResolver
+
+	mu    sync.Mutex
+	conns map[string]*sqlConn
+}
+
+// sqlConn 一个连接的运行时状态
+type sqlConn struct {
+	cfg SQLConnection
+	sem chan struct{} // 并发槽，nil 表示不限制
+
+	mu     sync.Mutex
+	db     *sql.DB
+	nextAt time.Time // 速率限制：下一条语句最早的开始时间
+}
+
+// NewSQL 创建 SQL 执行器，secrets 用于解析 DSNSecret，所有连接都使用明文 DSN 时可以为 nil
+func NewSQL(secrets SecretResolver, connections ...SQLConnection) (*SQL, error) {
+	s := &SQL{secrets: secrets, conns: make(map[string]*sqlConn)}
+	for _, cfg := range connections {
+		if cfg.Name == "" || cfg.Driver == "" {
+			return nil, errors.New("sql connection requires name and driver")
+		}
+		if cfg.DSNSecret == "" && cfg.DSN == "" {
+			return nil, fmt.Errorf("sql connection %s requires a DSN secret", cfg.Name)
+		}
+		if cfg.DSNSecret != "" && secrets == nil {
+			return nil, fmt.Errorf("sql connection %s uses a DSN secret but secrets are not enabled", cfg.Name)
+		}
+		if _, ok := s.conns[cfg.Name]; ok {
+			return nil, fmt.Errorf("duplicate sql connection %s", cfg.Name)
+		}
+		c := &sqlConn{cfg: cfg}
+		if cfg.MaxConcurrency > 0 {
+			c.sem = make(chan struct{}, cfg.MaxConcurrency)
+		}
+		s.conns[cfg.Name] = c
+	}
+	return s, nil
+}
+
+// Close 关闭已打开的数据库连接
+func (s *SQL) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, c := range s.conns {
+		c.mu.Lock()
+		if c.db != nil {
+			errs = append(errs, c.db.Close())
+			c.db = nil
+		}
+		c.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Execute 实现 service.Executor 接口
+func (s *SQL) Execute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	params := task.InputParams
+	s.mu.Lock()
+	conn, ok := s.conns[params[SQLParamConnection]]
+	s.mu.Unlock()
+	if !ok {
+		return nil, service.Fatal(fmt.Errorf("unknown sql connection %q", params[SQLParamConnection]))
+	}
+
+	query := strings.TrimSpace(params[SQLParamQuery])
+	if query == "" {
+		return nil, service.Fatal(fmt.Errorf("param %q is required", SQLParamQuery))
+	}
+	args, err := parseSQLArgs(params[SQLParamArgs])
+	if err != nil {
+		return nil, service.Fatal(fmt.Errorf("invalid param %q: %w", SQLParamArgs, err))
+	}
+	maxRows := defaultSQLMaxRows
+	if v := params[SQLParamMaxRows]; v != "" {
+		if maxRows, err = strconv.Atoi(v); err != nil || maxRows < 0 || maxRows > maxSQLMaxRows {
+			return nil, service.Fatal(fmt.Errorf("invalid param %q: must be between 0 and %d", SQLParamMaxRows, maxSQLMaxRows))
+		}
+	}
+	mode := params[SQLParamMode]
+	if mode == "" {
+		mode = detectSQLMode(query)
+	}
+	if mode != "query" && mode != "exec" {
+		return nil, service.Fatal(fmt.Errorf("invalid param %q: %q", SQLParamMode, mode))
+	}
+	if mode == "exec" && conn.cfg.ReadOnly {
+		return nil, service.Fatal(fmt.Errorf("sql connection %s is read-only", conn.cfg.Name))
+	}
+
+	db, err := conn.open(s.secrets)
+	if err != nil {
+		return nil, err
+	}
+	release, err := conn.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ec := service.ExecutionContextFrom(ctx)
+	start := time.Now()
+	var output map[string]string
+	if mode == "exec" {
+		output, err = execStatement(ctx, db, query, args)
+	} else {
+		output, err = runQuery(ctx, db, query, args, maxRows)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("sql %s on %s: %w", mode, conn.cfg.Name, err)
+	}
+	ec.Logf("sql %s on %s finished in %s", mode, conn.cfg.Name, time.Since(start).Round(time.Millisecond))
+	return output, nil
+}
+
+// open 首次使用时解析 DSN 并打开数据库，密钥不存在或驱动未注册时返回 Fatal 错误
+func (c *sqlConn) open(secrets SecretResolver) (*sql.DB, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.db != nil {
+		return c.db, nil
+	}
+
+	dsn := c.cfg.DSN
+	if c.cfg.DSNSecret != "" {
+		var err error
+		if dsn, err = secrets.Resolve(c.cfg.DSNSecret); err != nil {
+			return nil, service.Fatal(fmt.Errorf("failed to resolve DSN of sql connection %s: %w", c.cfg.Name, err))
+		}
+	}
+	db, err := sql.Open(c.cfg.Driver, dsn)
+	if err != nil {
+		// 不输出 DSN，其中可能包含密码
+		return nil, service.Fatal(fmt.Errorf("failed to open sql connection %s: %w", c.cfg.Name, err))
+	}
+	if c.cfg.MaxConcurrency > 0 {
+		db.SetMaxOpenConns(c.cfg.MaxConcurrency)
+	}
+	c.db = db
+	return db, nil
+}
+
+// acquire 等待速率限制和并发槽，返回释放函数
+func (c *sqlConn) acquire(ctx context.Context) (func(), error) {
+	if qps := c.cfg.QueriesPerSecond; qps > 0 {
+		c.mu.Lock()
+		now := time.Now()
+		at := c.nextAt
+		if at.Before(now) {
+			at = now
+		}
+		c.nextAt = at.Add(time.Duration(float64(time.Second) / qps))
+		c.mu.Unlock()
+
+		if wait := time.Until(at); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+
+	if c.sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case c.sem <- struct{}{}:
+		return func() { <-c.sem }, nil
+	}
+}
+
+// execStatement 执行非查询语句，输出影响行数
+func execStatement(ctx context.Context, db *sql.DB, query string, args []interface{}) (map[string]string, error) {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	output := map[string]string{}
+	if n, err := res.RowsAffected(); err == nil {
+		output["rows_affected"] = strconv.FormatInt(n, 10)
+	}
+	return output, nil
+}
+
+// runQuery 执行查询，读取全部结果统计行数，保留前 maxRows 行
+func runQuery(ctx context.Context, db *sql.DB, query string, args []interface{}, maxRows int) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]map[string]interface{}, 0)
+	count := 0
+	for rows.Next() {
+		count++
+		if len(kept) >= maxRows {
+			continue
+		}
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[col] = values[i]
+		}
+		kept = append(kept, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	columnsJSON, _ := json.Marshal(columns)
+	rowsJSON, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"row_count": strconv.Itoa(count),
+		"columns":   string(columnsJSON),
+		"rows":      string(rowsJSON),
+		"truncated": strconv.FormatBool(count > len(kept)),
+	}, nil
+}
+
+// detectSQLMode 按语句的首个关键字判断是否返回结果集
+func detectSQLMode(query string) string {
+	fields := strings.Fields(strings.TrimLeft(strings.TrimSpace(query), "("))
+	if len(fields) == 0 {
+		return "exec"
+	}
+	switch strings.ToLower(fields[0]) {
+	case "select", "with", "show", "explain", "describe", "values", "pragma", "table":
+		return "query"
+	}
+	return "exec"
+}
+
+// parseSQLArgs 解析 JSON 数组形式的占位符参数，整数保持为 int64
+func parseSQLArgs(value string) ([]interface{}, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(value)))
+	dec.UseNumber()
+	var raw []interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(raw))
+	for i, v := range raw {
+		switch v := v.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				args[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				args[i] = f
+			} else {
+				return nil, fmt.Errorf("invalid number %s", v)
+			}
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("argument %d must be a scalar", i+1)
+		default:
+			args[i] = v
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,166 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// staticSecrets 测试用的密钥表
+type staticSecrets map[string]string
+
+func (s staticSecrets) Resolve(name string) (string, error) {
+	if v, ok := s[name]; ok {
+		return v, nil
+	}
+	return "", errors.New("secret not found")
+}
+
+func newTestSQL(t *testing.T, conns ...SQLConnection) *SQL {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "warehouse.db")
+	secrets := staticSecrets{"warehouse-dsn": dsn}
+	for i := range conns {
+		conns[i].Driver = "sqlite3"
+		conns[i].DSNSecret = "warehouse-dsn"
+	}
+	s, err := NewSQL(secrets, conns...)
+	if err != nil {
+		t.Fatalf("NewSQL: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func runSQL(s *SQL, params map[string]string) (map[string]string, error) {
+	return s.Execute(context.Background(), &model.Task{ID: "t1", InputParams: params})
+}
+
+func TestSQL_ExecAndQuery(t *testing.T) {
+	s := newTestSQL(t, SQLConnection{Name: "warehouse"})
+
+	if _, err := runSQL(s, map[string]string{SQLParamConnection: "warehouse", SQLParamQuery: "CREATE TABLE orders (id INTEGER, customer TEXT, total REAL)"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	out, err := runSQL(s, map[string]string{
+		SQLParamConnection: "warehouse",
+		SQLParamQuery:      "INSERT INTO orders VALUES (?, ?, ?), (?, ?, ?), (?, ?, ?)",
+		SQLParamArgs:       `[1, "ada", 9.5, 2, "bob", 20, 3, "ada", 1.25]`,
+	})
+	if err != nil || out["rows_affected"] != "3" {
+		t.Fatalf("insert = %v, %v", out, err)
+	}
+
+	out, err = runSQL(s, map[string]string{
+		SQLParamConnection: "warehouse",
+		SQLParamQuery:      "SELECT id, customer FROM orders WHERE customer = ? ORDER BY id",
+		SQLParamArgs:       `["ada"]`,
+		SQLParamMaxRows:    "1",
+	})
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if out["row_count"] != "2" || out["truncated"] != "true" || out["columns"] != `["id","customer"]` {
+		t.Errorf("output = %v", out)
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(out["rows"]), &rows); err != nil || len(rows) != 1 || rows[0]["customer"] != "ada" || rows[0]["id"] != float64(1) {
+		t.Errorf("rows = %v, %v", rows, err)
+	}
+}
+
+func TestSQL_ReadOnlyAndInvalidParams(t *testing.T) {
+	s := newTestSQL(t, SQLConnection{Name: "ro", ReadOnly: true})
+
+	for name, params := range map[string]map[string]string{
+		"unknown connection": {SQLParamConnection: "nope", SQLParamQuery: "SELECT 1"},
+		"read-only":          {SQLParamConnection: "ro", SQLParamQuery: "DELETE FROM orders"},
+		"no query":           {SQLParamConnection: "ro"},
+		"bad args":           {SQLParamConnection: "ro", SQLParamQuery: "SELECT ?", SQLParamArgs: `[{"a":1}]`},
+		"bad max_rows":       {SQLParamConnection: "ro", SQLParamQuery: "SELECT 1", SQLParamMaxRows: "5000"},
+		"bad mode":           {SQLParamConnection: "ro", SQLParamQuery: "SELECT 1", SQLParamMode: "stream"},
+	} {
+		_, err := runSQL(s, params)
+		if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal {
+			t.Errorf("%s: err = %v, class = %q", name, err, class)
+		}
+	}
+
+	if out, err := runSQL(s, map[string]string{SQLParamConnection: "ro", SQLParamQuery: "\n  select 1 AS one"}); err != nil || out["row_count"] != "1" {
+		t.Errorf("read-only query = %v, %v", out, err)
+	}
+}
+
+func TestSQL_MissingSecretIsFatal(t *testing.T) {
+	s, err := NewSQL(staticSecrets{}, SQLConnection{Name: "db", Driver: "sqlite3", DSNSecret: "missing"})
+	if err != nil {
+		t.Fatalf("NewSQL: %v", err)
+	}
+	_, err = runSQL(s, map[string]string{SQLParamConnection: "db", SQLParamQuery: "SELECT 1"})
+	if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal {
+		t.Errorf("err = %v, class = %q", err, class)
+	}
+
+	if _, err := NewSQL(nil, SQLConnection{Name: "db", Driver: "sqlite3", DSNSecret: "dsn"}); err == nil {
+		t.Error("DSN secret without resolver should fail")
+	}
+}
+
+func TestSQL_ConcurrencyAndRateLimit(t *testing.T) {
+	s := newTestSQL(t, SQLConnection{Name: "limited", MaxConcurrency: 1, QueriesPerSecond: 20})
+	conn := s.conns["limited"]
+
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := conn.acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if n := atomic.AddInt32(&running, 1); n > atomic.LoadInt32(&maxRunning) {
+				atomic.StoreInt32(&maxRunning, n)
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning != 1 {
+		t.Errorf("max concurrent = %d, want 1", maxRunning)
+	}
+	// 4 条语句按 20/s 间隔 50ms 开始
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("rate limit not applied, elapsed %v", elapsed)
+	}
+}
+
+func TestDetectSQLMode(t *testing.T) {
+	for query, want := range map[string]string{
+		"SELECT 1":                             "query",
+		"(select 1) union select 2":            "query",
+		"WITH x AS (SELECT 1) SELECT * FROM x": "query",
+		"UPDATE t SET a = 1":                   "exec",
+		"insert into t values (1)":             "exec",
+	} {
+		if got := detectSQLMode(query); got != want {
+			t.Errorf("detectSQLMode(%q) = %s, want %s", query, got, want)
+		}
+	}
+}
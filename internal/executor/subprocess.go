@@ -0,0 +1,267 @@
+// Package executor 提供内置的任务执行器实现，第三方执行器无需重新编译即可接入：
+// 子进程执行器按 JSON over stdio 协议调用任意语言编写的插件程序。
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// Protocol 子进程插件协议版本，写入请求的 protocol 字段，插件可据此拒绝不兼容的版本
+const Protocol = "taskflow.executor/v1"
+
+// 插件输出的消息类型
+const (
+	MessageLog    = "log"    // 写入任务日志
+	MessageResult = "result" // 执行成功，output 为任务输出
+	MessageError  = "error"  // 执行失败，class 决定是否重试
+)
+
+const (
+	// defaultKillGrace 取消或超时后先发送 SIGTERM，超过该时间仍未退出则强制结束
+	defaultKillGrace = 5 * time.Second
+	// maxMessageSize 单条 stdout 消息的最大字节数
+	maxMessageSize = 4 << 20
+	// stderrTailLines 插件异常退出时错误信息附带的 stderr 末尾行数
+	stderrTailLines = 5
+)
+
+// Request 写入插件 stdin 的请求，写入后关闭 stdin
+type Request struct {
+	Protocol string      `json:"protocol"`
+	Task     RequestTask `json:"task"`
+}
+
+// RequestTask 请求中的任务信息
+type RequestTask struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	Params  map[string]string `json:"params"`
+	Attempt int32             `json:"attempt"`
+}
+
+// Message 插件 stdout 输出的一行 JSON
+type Message struct {
+	Type         string            `json:"type"`
+	Message      string            `json:"message,omitempty"`        // log 的日志文本、error 的错误信息
+	Output       map[string]string `json:"output,omitempty"`         // result 的任务输出
+	Class        model.ErrorClass  `json:"class,omitempty"`          // error 的分类：retryable、fatal、rate_limited，为空按重试策略处理
+	RetryAfterMs int64             `json:"retry_after_ms,omitempty"` // rate_limited 时建议的最短等待时间
+}
+
+// Subprocess 子进程执行器：每次执行启动一个插件进程，stdin 写入 Request，
+// stdout 逐行读取 Message。非 JSON 的 stdout 行和全部 stderr 输出写入任务日志；
+// 进程以 0 退出且未输出 result 视为成功、输出为空。ctx 取消时先发送 SIGTERM，宽限期后强制结束
+type Subprocess struct {
+	Command   string
+	Args      []string
+	Env       []string      // 追加的环境变量（KEY=VALUE），插件继承 taskflow 进程的环境
+	Dir       string        // 工作目录，为空时使用当前目录
+	Timeout   time.Duration // 单次执行超时，0 表示不限制（仍受任务自身超时约束）
+	KillGrace time.Duration // SIGTERM 后等待退出的时间，默认 5s
+}
+
+// NewSubprocess 创建子进程执行器
+func NewSubprocess(command string, args ...string) *Subprocess {
+	return &Subprocess{Command: command, Args: args}
+}
+
+// Execute 实现 service.Executor 接口
+func (p *Subprocess) Execute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	ec := service.ExecutionContextFrom(ctx)
+
+	req, err := newRequest(task)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Dir = p.Dir
+	cmd.Env = append(os.Environ(), p.Env...)
+	cmd.Env = append(cmd.Env,
+		"TASKFLOW_TASK_ID="+task.ID,
+		"TASKFLOW_TASK_TYPE="+task.TaskType,
+		"TASKFLOW_ATTEMPT="+strconv.Itoa(int(task.RetryCount+1)),
+	)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = p.KillGrace
+	if cmd.WaitDelay <= 0 {
+		cmd.WaitDelay = defaultKillGrace
+	}
+
+	// 通过 io.Pipe 而非 StdoutPipe 读取输出：插件的子进程继承管道时，Wait 仍能在 WaitDelay 后返回
+	stdout, stdoutW := io.Pipe()
+	stderr, stderrW := io.Pipe()
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+	if err := cmd.Start(); err != nil {
+		// 插件不存在或不可执行，重试无意义
+		return nil, service.Fatal(fmt.Errorf("failed to start plugin %s: %w", p.Command, err))
+	}
+
+	var (
+		wg      sync.WaitGroup
+		final   *Message
+		readErr error
+	)
+	tail := &lineTail{max: stderrTailLines}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		final, readErr = readMessages(stdout, ec)
+		// 协议错误时剩余输出不再解析，但仍需读完，避免插件阻塞在写入上
+		io.Copy(io.Discard, stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		logLines(stderr, ec, tail)
+	}()
+
+	waitErr := cmd.Wait()
+	stdoutW.Close()
+	stderrW.Close()
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.Command, readErr)
+	}
+	if final != nil && final.Type == MessageError {
+		return nil, final.err()
+	}
+	if waitErr != nil {
+		msg := fmt.Sprintf("plugin %s: %v", p.Command, waitErr)
+		if lines := tail.String(); lines != "" {
+			msg += ": " + lines
+		}
+		return nil, errors.New(msg)
+	}
+	if final == nil {
+		return nil, nil
+	}
+	return final.Output, nil
+}
+
+// newRequest 编码写入插件 stdin 的请求
+func newRequest(task *model.Task) ([]byte, error) {
+	req, err := json.Marshal(Request{
+		Protocol: Protocol,
+		Task: RequestTask{
+			ID:      task.ID,
+			Name:    task.Name,
+			Type:    task.TaskType,
+			Params:  task.InputParams,
+			Attempt: task.RetryCount + 1,
+		},
+	})
+	if err != nil {
+		return nil, service.Fatal(fmt.Errorf("failed to encode plugin request: %w", err))
+	}
+	return req, nil
+}
+
+// logLines 读取 stderr 直到 EOF，每行写入任务日志并保留末尾几行用于错误信息
+func logLines(r io.Reader, ec *service.ExecutionContext, tail *lineTail) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		tail.add(scanner.Text())
+		ec.Log(scanner.Text())
+	}
+	io.Copy(io.Discard, r)
+}
+
+// readMessages 读取 stdout 直到 EOF，日志消息写入执行上下文，返回最后一条 result 或 error 消息
+func readMessages(r io.Reader, ec *service.ExecutionContext) (*Message, error) {
+	var final *Message
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var msg Message
+		if line[0] != '{' || json.Unmarshal(line, &msg) != nil {
+			// 插件直接打印的文本按日志处理
+			ec.Log(string(line))
+			continue
+		}
+		switch msg.Type {
+		case MessageLog:
+			ec.Log(msg.Message)
+		case MessageResult, MessageError:
+			m := msg
+			final = &m
+		default:
+			return nil, fmt.Errorf("unknown message type %q", msg.Type)
+		}
+	}
+	return final, scanner.Err()
+}
+
+// err 将 error 消息转换为带分类的执行错误
+func (m *Message) err() error {
+	msg := m.Message
+	if msg == "" {
+		msg = "plugin reported failure"
+	}
+	err := errors.New(msg)
+	switch m.Class {
+	case model.ErrorClassRetryable:
+		return service.Retryable(err)
+	case model.ErrorClassFatal:
+		return service.Fatal(err)
+	case model.ErrorClassRateLimited:
+		return service.RateLimited(err, time.Duration(m.RetryAfterMs)*time.Millisecond)
+	}
+	return err
+}
+
+// lineTail 保留最后若干行
+type lineTail struct {
+	mu    sync.Mutex
+	max   int
+	lines []string
+}
+
+func (t *lineTail) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[1:]
+	}
+}
+
+func (t *lineTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.lines, "; ")
+}
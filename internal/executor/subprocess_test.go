@@ -0,0 +1,137 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+// writePlugin 在 dir 中写入可执行的 shell 脚本插件
+func writePlugin(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("write plugin: %v", err)
+	}
+	return path
+}
+
+func TestSubprocess_Result(t *testing.T) {
+	// 回显请求中的参数和环境变量
+	path := writePlugin(t, t.TempDir(), "echo.sh", `
+req=$(cat)
+echo "plain text line"
+echo '{"type":"log","message":"working"}'
+case "$req" in
+  *'"protocol":"taskflow.executor/v1"'*'"name":"ada"'*) ;;
+  *) echo '{"type":"error","message":"bad request","class":"fatal"}'; exit 0 ;;
+esac
+echo "{\"type\":\"result\",\"output\":{\"task\":\"$TASKFLOW_TASK_ID\",\"attempt\":\"$TASKFLOW_ATTEMPT\"}}"
+`)
+
+	task := &model.Task{ID: "t1", TaskType: "echo", InputParams: map[string]string{"name": "ada"}, RetryCount: 1}
+	out, err := NewSubprocess(path).Execute(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if out["task"] != "t1" || out["attempt"] != "2" {
+		t.Errorf("output = %v", out)
+	}
+}
+
+func TestSubprocess_ErrorClasses(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		script string
+		class  model.ErrorClass
+		retry  time.Duration
+		substr string
+	}{
+		{"fatal", `echo '{"type":"error","message":"invalid input","class":"fatal"}'`, model.ErrorClassFatal, 0, "invalid input"},
+		{"rate_limited", `echo '{"type":"error","message":"slow down","class":"rate_limited","retry_after_ms":1500}'`, model.ErrorClassRateLimited, 1500 * time.Millisecond, "slow down"},
+		{"unclassified", `echo '{"type":"error","message":"oops"}'`, model.ErrorClassUnknown, 0, "oops"},
+		{"exit_code", `echo "disk full" >&2; exit 3`, model.ErrorClassUnknown, 0, "disk full"},
+		{"unknown_message", `echo '{"type":"bogus"}'`, model.ErrorClassUnknown, 0, "unknown message type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePlugin(t, dir, tt.name+".sh", tt.script)
+			_, err := NewSubprocess(path).Execute(context.Background(), &model.Task{ID: "t1"})
+			if err == nil || !strings.Contains(err.Error(), tt.substr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.substr)
+			}
+			class, retryAfter := service.ClassifyError(err)
+			if class != tt.class || retryAfter != tt.retry {
+				t.Errorf("class = %q %v, want %q %v", class, retryAfter, tt.class, tt.retry)
+			}
+		})
+	}
+}
+
+func TestSubprocess_NoResultIsSuccess(t *testing.T) {
+	path := writePlugin(t, t.TempDir(), "quiet.sh", "cat >/dev/null\n")
+	out, err := NewSubprocess(path).Execute(context.Background(), &model.Task{ID: "t1"})
+	if err != nil || len(out) != 0 {
+		t.Errorf("Execute = %v, %v", out, err)
+	}
+}
+
+func TestSubprocess_MissingCommandIsFatal(t *testing.T) {
+	_, err := NewSubprocess(filepath.Join(t.TempDir(), "missing")).Execute(context.Background(), &model.Task{ID: "t1"})
+	if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal {
+		t.Errorf("err = %v, class = %q", err, class)
+	}
+}
+
+func TestSubprocess_Timeout(t *testing.T) {
+	path := writePlugin(t, t.TempDir(), "slow.sh", "sleep 10\n")
+	exec := NewSubprocess(path)
+	exec.Timeout = 100 * time.Millisecond
+	exec.KillGrace = 100 * time.Millisecond
+
+	start := time.Now()
+	_, err := exec.Execute(context.Background(), &model.Task{ID: "t1"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("plugin not killed, took %v", elapsed)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "resize-image.py", "")
+	writePlugin(t, dir, "send-email", "")
+	writePlugin(t, dir, ".hidden", "")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("docs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins, err := LoadDir(dir, LoadOptions{Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	if len(plugins) != 2 || plugins[0].TaskType != "resize-image" || plugins[1].TaskType != "send-email" {
+		t.Fatalf("plugins = %+v", plugins)
+	}
+	if exec := plugins[0].Executor.(*Subprocess); exec.Timeout != time.Minute || !filepath.IsAbs(exec.Command) {
+		t.Errorf("executor = %+v", exec)
+	}
+
+	writePlugin(t, dir, "send-email.sh", "")
+	if _, err := LoadDir(dir, LoadOptions{}); err == nil {
+		t.Error("duplicate task type should fail")
+	}
+}
@@ -0,0 +1,66 @@
+// wasmplugin 测试用的 WASM 插件，编译为 GOOS=wasip1 GOARCH=wasm，按任务类型执行不同的行为
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type request struct {
+	Task struct {
+		ID     string            `json:"id"`
+		Type   string            `json:"type"`
+		Params map[string]string `json:"params"`
+	} `json:"task"`
+}
+
+func main() {
+	var req request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, "bad request:", err)
+		os.Exit(2)
+	}
+
+	switch req.Task.Type {
+	case "echo":
+		// 只能看到 TASKFLOW_* 环境变量，没有文件系统
+		_, fsErr := os.ReadFile("/etc/hostname")
+		fmt.Println("plain text line")
+		emit(map[string]any{"type": "log", "message": "working"})
+		emit(map[string]any{"type": "result", "output": map[string]string{
+			"id":      req.Task.ID,
+			"param":   req.Task.Params["x"],
+			"env_id":  os.Getenv("TASKFLOW_TASK_ID"),
+			"attempt": os.Getenv("TASKFLOW_ATTEMPT"),
+			"home":    os.Getenv("HOME"),
+			"fs":      fmt.Sprint(fsErr != nil),
+		}})
+	case "error":
+		emit(map[string]any{"type": "error", "message": "bad input", "class": "fatal"})
+	case "exit":
+		fmt.Fprintln(os.Stderr, "boom")
+		os.Exit(3)
+	case "alloc":
+		buf := make([]byte, 64<<20)
+		for i := 0; i < len(buf); i += 4096 {
+			buf[i] = 1
+		}
+		emit(map[string]any{"type": "result", "output": map[string]string{"len": fmt.Sprint(len(buf))}})
+	case "spin":
+		n := 0
+		for {
+			n = step(n)
+		}
+	}
+}
+
+//go:noinline
+func step(n int) int {
+	return n + 1
+}
+
+func emit(msg map[string]any) {
+	b, _ := json.Marshal(msg)
+	fmt.Println(string(b))
+}
@@ -0,0 +1,202 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+const (
+	// DefaultWASMMaxMemory 模块默认可用的线性内存上限
+	DefaultWASMMaxMemory = 64 << 20
+	// wasmPageSize WASM 线性内存的页大小
+	wasmPageSize = 64 << 10
+)
+
+// errFuelExhausted 模块的函数调用次数超过预算
+var errFuelExhausted = errors.New("fuel exhausted")
+
+// wasmCache 进程内共享的编译缓存，同一模块只编译一次
+var wasmCache = wazero.NewCompilationCache()
+
+// WASM WASM 沙箱执行器：在内嵌的 WASI 运行时（wazero，纯 Go 实现，无需安装外部运行时）中执行用户提供的模块，
+// 模块通过 stdin/stdout 按子进程插件协议通信。模块没有文件系统、网络和宿主环境变量的访问权限，
+// 内存和函数调用次数（fuel）受限，适合多租户的自定义任务逻辑
+type WASM struct {
+	Module    string        // .wasm 模块路径
+	MaxMemory int64         // 线性内存上限（字节），默认 64MiB
+	Fuel      uint64        // 可执行的函数调用预算，耗尽后模块被终止；0 表示不限制
+	Timeout   time.Duration // 单次执行超时，0 表示不限制（仍受任务自身超时约束）
+}
+
+// NewWASM 创建 WASM 执行器，使用默认内存上限
+func NewWASM(module string) *WASM {
+	return &WASM{Module: module}
+}
+
+// Execute 实现 service.Executor 接口
+func (w *WASM) Execute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	binary, err := os.ReadFile(w.Module)
+	if err != nil {
+		return nil, service.Fatal(fmt.Errorf("wasm module unavailable: %w", err))
+	}
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.Timeout)
+		defer cancel()
+	}
+	ec := service.ExecutionContextFrom(ctx)
+
+	req, err := newRequest(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		final   *Message
+		readErr error
+	)
+	stdout, stdoutW := io.Pipe()
+	stderr, stderrW := io.Pipe()
+	tail := &lineTail{max: stderrTailLines}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		final, readErr = readMessages(stdout, ec)
+		io.Copy(io.Discard, stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		logLines(stderr, ec, tail)
+	}()
+
+	runErr := w.run(ctx, binary, task, req, stdoutW, stderrW)
+	stdoutW.Close()
+	stderrW.Close()
+	wg.Wait()
+
+	// 模块无效或 fuel 耗尽时重试无意义
+	if class, _ := service.ClassifyError(runErr); class == model.ErrorClassFatal {
+		return nil, runErr
+	}
+	name := filepath.Base(w.Module)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("wasm module %s: %w", name, readErr)
+	}
+	if final != nil && final.Type == MessageError {
+		return nil, final.err()
+	}
+	if runErr != nil {
+		msg := fmt.Sprintf("wasm module %s: %v", name, runErr)
+		if lines := tail.String(); lines != "" {
+			msg += ": " + lines
+		}
+		return nil, errors.New(msg)
+	}
+	if final == nil {
+		return nil, nil
+	}
+	return final.Output, nil
+}
+
+// run 在独立的运行时中实例化并执行模块：不挂载文件系统、不提供网络，只向模块传递任务相关的环境变量。
+// ctx 取消或 fuel 耗尽时模块被终止
+func (w *WASM) run(ctx context.Context, binary []byte, task *model.Task, req []byte, stdout, stderr io.Writer) error {
+	maxMemory := w.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = DefaultWASMMaxMemory
+	}
+	pages := uint32(min(maxMemory/wasmPageSize, 1<<16))
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if w.Fuel > 0 {
+		runCtx = withFuel(runCtx, w.Fuel, cancel)
+	}
+
+	r := wazero.NewRuntimeWithConfig(runCtx, wazero.NewRuntimeConfig().
+		WithCompilationCache(wasmCache).
+		WithMemoryLimitPages(pages).
+		WithCloseOnContextDone(true))
+	defer r.Close(context.Background())
+
+	wasi_snapshot_preview1.MustInstantiate(runCtx, r)
+	compiled, err := r.CompileModule(runCtx, binary)
+	if err != nil {
+		return service.Fatal(fmt.Errorf("invalid wasm module: %w", err))
+	}
+
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(filepath.Base(w.Module)).
+		WithEnv("TASKFLOW_TASK_ID", task.ID).
+		WithEnv("TASKFLOW_TASK_TYPE", task.TaskType).
+		WithEnv("TASKFLOW_ATTEMPT", strconv.Itoa(int(task.RetryCount+1))).
+		WithStdin(bytes.NewReader(req)).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader)
+	_, err = r.InstantiateModule(runCtx, compiled, config)
+	if cause := context.Cause(runCtx); errors.Is(cause, errFuelExhausted) {
+		return service.Fatal(fmt.Errorf("wasm module %s: %w", filepath.Base(w.Module), cause))
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("exit status %d", exitErr.ExitCode())
+	}
+	return err
+}
+
+// fuelKey fuel 计数在 context 中的键
+type fuelKey struct{}
+
+// fuel 一次执行剩余的函数调用预算
+type fuel struct {
+	remaining atomic.Int64
+	exhausted context.CancelCauseFunc
+}
+
+// withFuel 为执行设置函数调用预算：模块编译时插入的监听器在每次函数调用前扣减，
+// 耗尽后以 errFuelExhausted 取消 ctx，运行时在下一个检查点终止模块
+func withFuel(ctx context.Context, budget uint64, cancel context.CancelCauseFunc) context.Context {
+	f := &fuel{exhausted: cancel}
+	f.remaining.Store(int64(min(budget, 1<<62)))
+	ctx = context.WithValue(ctx, fuelKey{}, f)
+	return experimental.WithFunctionListenerFactory(ctx, fuelListenerFactory)
+}
+
+// fuelListenerFactory 为模块中的每个函数返回扣减 fuel 的监听器
+var fuelListenerFactory = experimental.FunctionListenerFactoryFunc(func(api.FunctionDefinition) experimental.FunctionListener {
+	return fuelListener
+})
+
+var fuelListener = experimental.FunctionListenerFunc(func(ctx context.Context, _ api.Module, _ api.FunctionDefinition, _ []uint64, _ experimental.StackIterator) {
+	if f, ok := ctx.Value(fuelKey{}).(*fuel); ok && f.remaining.Add(-1) == 0 {
+		f.exhausted(errFuelExhausted)
+	}
+})
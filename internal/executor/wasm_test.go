@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+)
+
+var (
+	wasmPluginOnce sync.Once
+	wasmPluginPath string
+	wasmPluginErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if wasmPluginPath != "" {
+		os.RemoveAll(filepath.Dir(wasmPluginPath))
+	}
+	os.Exit(code)
+}
+
+// buildWASMPlugin 把 testdata/wasmplugin 编译为 WASI 模块，同一次测试只编译一次
+func buildWASMPlugin(t *testing.T) string {
+	t.Helper()
+	wasmPluginOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "wasmplugin")
+		if err != nil {
+			wasmPluginErr = err
+			return
+		}
+		wasmPluginPath = filepath.Join(dir, "plugin.wasm")
+		cmd := exec.Command("go", "build", "-o", wasmPluginPath, "./testdata/wasmplugin")
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			wasmPluginErr = errors.New(string(out))
+		}
+	})
+	if wasmPluginErr != nil {
+		t.Fatalf("build wasm plugin: %v", wasmPluginErr)
+	}
+	return wasmPluginPath
+}
+
+func TestWASM_Result(t *testing.T) {
+	t.Setenv("HOME", "/home/secret")
+	task := &model.Task{ID: "t1", TaskType: "echo", InputParams: map[string]string{"x": "42"}, RetryCount: 1}
+	out, err := NewWASM(buildWASMPlugin(t)).Execute(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	want := map[string]string{"id": "t1", "param": "42", "env_id": "t1", "attempt": "2", "home": "", "fs": "true"}
+	for k, v := range want {
+		if out[k] != v {
+			t.Errorf("output[%s] = %q, want %q (output %v)", k, out[k], v, out)
+		}
+	}
+}
+
+func TestWASM_Failures(t *testing.T) {
+	module := buildWASMPlugin(t)
+
+	// 模块上报的错误按分类返回
+	_, err := NewWASM(module).Execute(context.Background(), &model.Task{ID: "t1", TaskType: "error"})
+	if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal || !strings.Contains(err.Error(), "bad input") {
+		t.Errorf("error: err = %v, class = %q", err, class)
+	}
+
+	// 非 0 退出带上 stderr 末尾
+	_, err = NewWASM(module).Execute(context.Background(), &model.Task{ID: "t1", TaskType: "exit"})
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("exit: err = %v", err)
+	}
+
+	// 超过内存上限时模块无法分配内存而退出
+	alloc := &model.Task{ID: "t1", TaskType: "alloc"}
+	if _, err := (&WASM{Module: module, MaxMemory: 32 << 20}).Execute(context.Background(), alloc); err == nil || !strings.Contains(err.Error(), "exit status 2") {
+		t.Errorf("alloc: expected the memory limit to stop the module, got %v", err)
+	}
+	if out, err := (&WASM{Module: module, MaxMemory: 256 << 20}).Execute(context.Background(), alloc); err != nil || out["len"] != "67108864" {
+		t.Errorf("alloc within the limit: out = %v, err = %v", out, err)
+	}
+}
+
+func TestWASM_Limits(t *testing.T) {
+	module := buildWASMPlugin(t)
+
+	// fuel 耗尽后模块被终止，重试无意义
+	_, err := (&WASM{Module: module, Fuel: 1_000_000}).Execute(context.Background(), &model.Task{ID: "t1", TaskType: "spin"})
+	if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal || !errors.Is(err, errFuelExhausted) {
+		t.Errorf("fuel: err = %v, class = %q", err, class)
+	}
+
+	// 超时和取消终止执行中的模块
+	start := time.Now()
+	_, err = (&WASM{Module: module, Timeout: 200 * time.Millisecond}).Execute(context.Background(), &model.Task{ID: "t1", TaskType: "spin"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout: err = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("timeout took %s", elapsed)
+	}
+}
+
+func TestWASM_InvalidModuleIsFatal(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.wasm")
+	if err := os.WriteFile(invalid, []byte("\x00asm"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, module := range []string{invalid, filepath.Join(dir, "missing.wasm")} {
+		_, err := NewWASM(module).Execute(context.Background(), &model.Task{ID: "t1"})
+		if class, _ := service.ClassifyError(err); class != model.ErrorClassFatal {
+			t.Errorf("%s: err = %v, class = %q", filepath.Base(module), err, class)
+		}
+	}
+}
+
+func TestLoadDir_WASM(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "score.wasm"), []byte("\x00asm"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plugins, err := LoadDir(dir, LoadOptions{WASMFuel: 500})
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	if len(plugins) != 1 || plugins[0].TaskType != "score" {
+		t.Fatalf("plugins = %+v", plugins)
+	}
+	if w, ok := plugins[0].Executor.(*WASM); !ok || w.Fuel != 500 {
+		t.Errorf("executor = %+v", plugins[0].Executor)
+	}
+}
@@ -17,7 +17,12 @@ type ContextKeys struct {
 	Token    string
 }
 
-var contextKeys = &ContextKeys{}
+// 各字段须互不相同，否则写入 context 时互相覆盖
+var contextKeys = &ContextKeys{
+	UserID:   "taskflow.user_id",
+	UserName: "taskflow.user_name",
+	Token:    "taskflow.token",
+}
 
 // PublicMethods public methods that don't require authentication
 var PublicMethods = map[string]bool{
@@ -0,0 +1,102 @@
+package grpc_middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestInFlightLimits_StreamsDoNotStarveUnary(t *testing.T) {
+	opts, err := GetUnaryServerOptions(WithMaxInFlight(1), WithMaxStreams(1))
+	if err != nil {
+		t.Fatalf("GetUnaryServerOptions: %v", err)
+	}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	watch := func(ctx context.Context) error {
+		stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+
+	// Watch 保持打开，占用流的名额但不占用一元调用的名额
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	if err := watch(watchCtx); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatalf("Check while a stream is open: %v", err)
+		}
+	}
+	if err := watch(ctx); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the second stream to be shed, got %v", err)
+	}
+
+	// 流结束后释放名额
+	stopWatch()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		err := watch(ctx)
+		if err == nil {
+			break
+		}
+		if status.Code(err) != codes.ResourceExhausted || time.Now().After(deadline) {
+			t.Fatalf("expected the stream slot to be released, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestInFlightTapHandle(t *testing.T) {
+	unary, streams := NewInFlightLimiter(1), NewInFlightLimiter(1)
+	tap := InFlightTapHandle(unary, streams)
+	shed := func() bool {
+		_, err := tap(context.Background(), nil)
+		return status.Code(err) == codes.ResourceExhausted
+	}
+
+	// 调用类型未知，只在两类名额都用完时提前拒绝
+	unary.TryAcquire()
+	if shed() {
+		t.Error("a full unary limiter alone must not shed streams")
+	}
+	streams.TryAcquire()
+	if !shed() {
+		t.Error("expected shedding when both limiters are full")
+	}
+	unary.Release()
+	if shed() {
+		t.Error("a full stream limiter alone must not shed unary RPCs")
+	}
+	if _, err := InFlightTapHandle(nil, streams)(context.Background(), nil); err != nil {
+		t.Errorf("unary RPCs are unlimited and must never be shed, got %v", err)
+	}
+}
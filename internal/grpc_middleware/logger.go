@@ -3,30 +3,40 @@ package grpc_middleware
 import (
 	"context"
 	"io"
-	"log"
+	"runtime/debug"
 	"time"
 
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+
+	"taskflow/internal/logger"
 )
 
 // LoggerConfig logger config
 type LoggerConfig struct {
-	InfoLogger  *log.Logger
-	ErrorLogger *log.Logger
+	// Logger structured logger, falls back to the global logger when nil
+	Logger *zap.SugaredLogger
+	// LogMetadata logs incoming metadata at debug level
+	LogMetadata bool
 }
 
 // defaultLoggerConfig default config
-var defaultLoggerConfig = &LoggerConfig{
-	InfoLogger:  log.Default(),
-	ErrorLogger: log.Default(),
-}
+var defaultLoggerConfig = &LoggerConfig{}
 
 // RequestIDHeader request ID header name
 const RequestIDHeader = "x-request-id"
 
+// log returns the configured logger or the global one
+func (c *LoggerConfig) log() *zap.SugaredLogger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return logger.Logger
+}
+
 // UnaryLoggerInterceptor creates unary logger interceptor
 func UnaryLoggerInterceptor(cfg *LoggerConfig) grpc.UnaryServerInterceptor {
 	if cfg == nil {
@@ -39,27 +49,20 @@ func UnaryLoggerInterceptor(cfg *LoggerConfig) grpc.UnaryServerInterceptor {
 		// Generate or extract request ID
 		requestID := generateRequestID(ctx)
 		ctx = context.WithValue(ctx, "request_id", requestID)
+		// Echo the request ID so clients can correlate responses with tasks and events
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
 		
-		// Log request
-		cfg.InfoLogger.Printf("[%s] RPC started: %s", requestID, info.FullMethod)
-		
-		// Get metadata
-		if md, ok := metadata.FromIncomingContext(ctx); ok {
-			cfg.InfoLogger.Printf("[%s] Metadata: %v", requestID, md)
+		log := cfg.log().With("request_id", requestID, "method", info.FullMethod, "kind", "unary")
+		if cfg.LogMetadata {
+			if md, ok := metadata.FromIncomingContext(ctx); ok {
+				log.Debugw("RPC metadata", "metadata", md)
+			}
 		}
 		
 		// Call handler
 		resp, err := handler(ctx, req)
 		
-		// Log response
-		duration := time.Since(startTime)
-		if err != nil {
-			cfg.InfoLogger.Printf("[%s] RPC failed: %s, duration: %v, error: %v", 
-				requestID, info.FullMethod, duration, err)
-		} else {
-			cfg.InfoLogger.Printf("[%s] RPC completed: %s, duration: %v", 
-				requestID, info.FullMethod, duration)
-		}
+		logRPCResult(log, err, time.Since(startTime))
 		
 		return resp, err
 	}
@@ -77,45 +80,46 @@ func StreamLoggerInterceptor(cfg *LoggerConfig) grpc.StreamServerInterceptor {
 		// Generate or extract request ID
 		requestID := generateRequestID(ss.Context())
 		ctx := context.WithValue(ss.Context(), "request_id", requestID)
+		ss.SetHeader(metadata.Pairs(RequestIDHeader, requestID))
 		
-		// Log stream start
-		cfg.InfoLogger.Printf("[%s] Stream started: %s", requestID, info.FullMethod)
+		log := cfg.log().With("request_id", requestID, "method", info.FullMethod, "kind", "stream")
+		log.Debugw("Stream started")
 		
 		// Create wrapped stream
 		wrappedStream := &loggingStream{
 			ServerStream: ss,
 			ctx:          ctx,
-			requestID:    requestID,
-			config:       cfg,
-			method:      info.FullMethod,
-			startTime:   startTime,
+			log:          log,
 		}
 		
 		// Call handler
 		err := handler(srv, wrappedStream)
 		
-		// Log stream end
-		duration := time.Since(startTime)
-		if err != nil {
-			cfg.InfoLogger.Printf("[%s] Stream failed: %s, duration: %v, error: %v", 
-				requestID, info.FullMethod, duration, err)
-		} else {
-			cfg.InfoLogger.Printf("[%s] Stream completed: %s, duration: %v", 
-				requestID, info.FullMethod, duration)
-		}
+		logRPCResult(log, err, time.Since(startTime))
 		
 		return err
 	}
 }
 
+// logRPCResult logs the final outcome of an RPC with its status code and latency
+func logRPCResult(log *zap.SugaredLogger, err error, duration time.Duration) {
+	code := status.Code(err)
+	fields := []interface{}{"code", code.String(), "duration", duration}
+	switch code {
+	case codes.OK:
+		log.Infow("RPC completed", fields...)
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		log.Errorw("RPC failed", append(fields, "error", err)...)
+	default:
+		log.Warnw("RPC failed", append(fields, "error", err)...)
+	}
+}
+
 // loggingStream wraps grpc.ServerStream for logging
 type loggingStream struct {
 	grpc.ServerStream
-	ctx        context.Context
-	requestID  string
-	config     *LoggerConfig
-	method     string
-	startTime  time.Time
+	ctx context.Context
+	log *zap.SugaredLogger
 }
 
 func (s *loggingStream) Context() context.Context {
@@ -125,7 +129,7 @@ func (s *loggingStream) Context() context.Context {
 func (s *loggingStream) SendMsg(m interface{}) error {
 	err := s.ServerStream.SendMsg(m)
 	if err != nil {
-		s.config.InfoLogger.Printf("[%s] Stream send error: %v", s.requestID, err)
+		s.log.Debugw("Stream send error", "error", err)
 	}
 	return err
 }
@@ -133,7 +137,7 @@ func (s *loggingStream) SendMsg(m interface{}) error {
 func (s *loggingStream) RecvMsg(m interface{}) error {
 	err := s.ServerStream.RecvMsg(m)
 	if err != nil && err != io.EOF {
-		s.config.InfoLogger.Printf("[%s] Stream receive error: %v", s.requestID, err)
+		s.log.Debugw("Stream receive error", "error", err)
 	}
 	return err
 }
@@ -149,8 +153,8 @@ func UnaryRecoveryInterceptor(cfg *LoggerConfig) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 		defer func() {
 			if r := recover(); r != nil {
-				cfg.ErrorLogger.Printf("[PANIC] Recovered in unary RPC: %s, error: %v", 
-					info.FullMethod, r)
+				cfg.log().Errorw("Recovered from panic in unary RPC",
+					"method", info.FullMethod, "request_id", GetRequestID(ctx), "panic", r, "stack", string(debug.Stack()))
 				err = status.Errorf(codes.Internal, "internal server error")
 			}
 		}()
@@ -168,8 +172,8 @@ func StreamRecoveryInterceptor(cfg *LoggerConfig) grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
 		defer func() {
 			if r := recover(); r != nil {
-				cfg.ErrorLogger.Printf("[PANIC] Recovered in stream RPC: %s, error: %v", 
-					info.FullMethod, r)
+				cfg.log().Errorw("Recovered from panic in stream RPC",
+					"method", info.FullMethod, "request_id", GetRequestID(ss.Context()), "panic", r, "stack", string(debug.Stack()))
 				err = status.Errorf(codes.Internal, "internal server error")
 			}
 		}()
@@ -205,3 +209,9 @@ func GetRequestID(ctx context.Context) string {
 	}
 	return ""
 }
+
+// ContextWithRequestID stores a request ID in the context under the same key the
+// gRPC interceptors use, so HTTP handlers calling TaskService see it via GetRequestID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, "request_id", requestID)
+}
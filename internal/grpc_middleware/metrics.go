@@ -0,0 +1,39 @@
+package grpc_middleware
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/metrics"
+)
+
+// UnaryMetricsInterceptor records per-RPC request counts and latency
+func UnaryMetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		startTime := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		metrics.RecordGRPCLatency(info.FullMethod, time.Since(startTime).Seconds())
+		metrics.RecordGRPCRequest(info.FullMethod, status.Code(err).String())
+
+		return resp, err
+	}
+}
+
+// StreamMetricsInterceptor records per-stream request counts and total stream duration
+func StreamMetricsInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		startTime := time.Now()
+
+		err := handler(srv, ss)
+
+		metrics.RecordGRPCLatency(info.FullMethod, time.Since(startTime).Seconds())
+		metrics.RecordGRPCRequest(info.FullMethod, status.Code(err).String())
+
+		return err
+	}
+}
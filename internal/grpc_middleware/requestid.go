@@ -0,0 +1,37 @@
+package grpc_middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryRequestIDInterceptor extracts or generates a request ID, stores it in the
+// context and echoes it back to the client in the response header metadata
+func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := generateRequestID(ctx)
+		ctx = context.WithValue(ctx, "request_id", requestID)
+
+		// Best effort: the header may already have been sent by another interceptor
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRequestIDInterceptor is the streaming counterpart of UnaryRequestIDInterceptor
+func StreamRequestIDInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		requestID := generateRequestID(ss.Context())
+		ctx := context.WithValue(ss.Context(), "request_id", requestID)
+
+		_ = ss.SetHeader(metadata.Pairs(RequestIDHeader, requestID))
+
+		return handler(srv, &serverStream{
+			ServerStream: ss,
+			ctx:          ctx,
+		})
+	}
+}
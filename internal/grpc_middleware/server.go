@@ -2,6 +2,8 @@ package grpc_middleware
 
 import (
 	"google.golang.org/grpc"
+
+	"taskflow/internal/apiversion"
 )
 
 // ========== Server Options ==========
@@ -14,10 +16,15 @@ type serverOptions struct {
 	rateLimitEnabled bool
 	loggerEnabled    bool
 	recoveryEnabled  bool
+	requestIDEnabled bool
+	metricsEnabled   bool
+	inFlightLimiter  *InFlightLimiter
+	streamLimiter    *InFlightLimiter
 	authConfig       *AuthConfig
 	tokenLimiter     *TokenBucketLimiter
 	slidingLimiter   *SlidingWindowLimiter
 	loggerConfig     *LoggerConfig
+	apiVersions      apiversion.Set
 }
 
 // WithAuth enables authentication
@@ -59,6 +66,46 @@ func WithRecovery() ServerOption {
 	}
 }
 
+// WithRequestID enables request ID propagation (echoed back in response metadata)
+func WithRequestID() ServerOption {
+	return func(o *serverOptions) {
+		o.requestIDEnabled = true
+	}
+}
+
+// WithMetrics enables per-RPC Prometheus metrics
+func WithMetrics() ServerOption {
+	return func(o *serverOptions) {
+		o.metricsEnabled = true
+	}
+}
+
+// WithMaxInFlight caps concurrently served unary RPCs; excess requests get RESOURCE_EXHAUSTED
+func WithMaxInFlight(limit int) ServerOption {
+	return func(o *serverOptions) {
+		if limit > 0 {
+			o.inFlightLimiter = NewInFlightLimiter(limit)
+		}
+	}
+}
+
+// WithMaxStreams caps concurrently open streams; excess streams get RESOURCE_EXHAUSTED.
+// Streams such as WatchTask stay open for minutes, so they are counted apart from unary RPCs
+func WithMaxStreams(limit int) ServerOption {
+	return func(o *serverOptions) {
+		if limit > 0 {
+			o.streamLimiter = NewInFlightLimiter(limit)
+		}
+	}
+}
+
+// WithAPIVersions reports the serving API version and deprecation schedule in response metadata
+func WithAPIVersions(versions apiversion.Set) ServerOption {
+	return func(o *serverOptions) {
+		o.apiVersions = versions
+	}
+}
+
 // DefaultServerOptions returns default server options
 func DefaultServerOptions() *serverOptions {
 	return &serverOptions{
@@ -66,6 +113,8 @@ func DefaultServerOptions() *serverOptions {
 		rateLimitEnabled: false,
 		loggerEnabled:    false,
 		recoveryEnabled:  false,
+		requestIDEnabled: false,
+		metricsEnabled:   false,
 	}
 }
 
@@ -89,6 +138,18 @@ func GetUnaryServerOptions(options ...ServerOption) ([]grpc.ServerOption, error)
 		streamInterceptors = append(streamInterceptors, StreamRecoveryInterceptor(recoveryCfg))
 	}
 
+	// Add request ID interceptor so every later interceptor sees the same ID
+	if opts.requestIDEnabled {
+		unaryInterceptors = append(unaryInterceptors, UnaryRequestIDInterceptor())
+		streamInterceptors = append(streamInterceptors, StreamRequestIDInterceptor())
+	}
+
+	// Add version interceptor so deprecation headers are sent even when a later interceptor rejects the call
+	if len(opts.apiVersions) > 0 {
+		unaryInterceptors = append(unaryInterceptors, UnaryVersionInterceptor(opts.apiVersions))
+		streamInterceptors = append(streamInterceptors, StreamVersionInterceptor(opts.apiVersions))
+	}
+
 	// Add logger interceptor
 	if opts.loggerEnabled {
 		loggerCfg := opts.loggerConfig
@@ -99,6 +160,20 @@ func GetUnaryServerOptions(options ...ServerOption) ([]grpc.ServerOption, error)
 		streamInterceptors = append(streamInterceptors, StreamLoggerInterceptor(loggerCfg))
 	}
 
+	// Add metrics interceptor
+	if opts.metricsEnabled {
+		unaryInterceptors = append(unaryInterceptors, UnaryMetricsInterceptor())
+		streamInterceptors = append(streamInterceptors, StreamMetricsInterceptor())
+	}
+
+	// Add in-flight limiter after metrics so shed requests are still counted
+	if opts.inFlightLimiter != nil {
+		unaryInterceptors = append(unaryInterceptors, UnaryInFlightInterceptor(opts.inFlightLimiter))
+	}
+	if opts.streamLimiter != nil {
+		streamInterceptors = append(streamInterceptors, StreamInFlightInterceptor(opts.streamLimiter))
+	}
+
 	// Add rate limiter
 	if opts.rateLimitEnabled {
 		if opts.tokenLimiter != nil {
@@ -122,17 +197,21 @@ func GetUnaryServerOptions(options ...ServerOption) ([]grpc.ServerOption, error)
 
 	var serverOpts []grpc.ServerOption
 
-	if len(unaryInterceptors) == 1 {
-		serverOpts = append(serverOpts, grpc.UnaryInterceptor(unaryInterceptors[0]))
-	} else if len(unaryInterceptors) > 1 {
-		// Use the last one for simplicity
-		serverOpts = append(serverOpts, grpc.UnaryInterceptor(unaryInterceptors[len(unaryInterceptors)-1]))
+	// Chain in declaration order: the first interceptor is the outermost
+	if len(unaryInterceptors) > 0 {
+		serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(unaryInterceptors...))
+	}
+	if len(streamInterceptors) > 0 {
+		serverOpts = append(serverOpts, grpc.ChainStreamInterceptor(streamInterceptors...))
 	}
 
-	if len(streamInterceptors) == 1 {
-		serverOpts = append(serverOpts, grpc.StreamInterceptor(streamInterceptors[0]))
-	} else if len(streamInterceptors) > 1 {
-		serverOpts = append(serverOpts, grpc.StreamInterceptor(streamInterceptors[len(streamInterceptors)-1]))
+	// Reject early at the transport when saturated, and bound streams per connection.
+	// Only possible when both kinds of RPC are limited; otherwise some RPCs are always admitted
+	if opts.inFlightLimiter != nil && opts.streamLimiter != nil {
+		serverOpts = append(serverOpts,
+			grpc.InTapHandle(InFlightTapHandle(opts.inFlightLimiter, opts.streamLimiter)),
+			grpc.MaxConcurrentStreams(uint32(opts.inFlightLimiter.Limit()+opts.streamLimiter.Limit())),
+		)
 	}
 
 	return serverOpts, nil
@@ -0,0 +1,46 @@
+package grpc_middleware
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"taskflow/internal/apiversion"
+)
+
+// versionMetadata builds the response header metadata for the API version of fullMethod,
+// which is derived from the proto package (taskflow.TaskService is v1, taskflow.v2.TaskService is v2)
+func versionMetadata(versions apiversion.Set, fullMethod string) metadata.MD {
+	name := apiversion.FromGRPCMethod(fullMethod)
+	v, ok := versions.Lookup(name)
+	if !ok {
+		v = apiversion.Version{Name: name}
+	}
+
+	md := metadata.MD{}
+	for k, val := range v.Headers() {
+		md.Set(strings.ToLower(k), val)
+	}
+	md.Set(strings.ToLower(apiversion.HeaderSupported), versions.Names())
+	return md
+}
+
+// UnaryVersionInterceptor reports the API version serving each call, plus deprecation and
+// sunset dates for deprecated versions, in the response header metadata
+func UnaryVersionInterceptor(versions apiversion.Set) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		// Best effort: the header may already have been sent by another interceptor
+		_ = grpc.SetHeader(ctx, versionMetadata(versions, info.FullMethod))
+		return handler(ctx, req)
+	}
+}
+
+// StreamVersionInterceptor is the streaming counterpart of UnaryVersionInterceptor
+func StreamVersionInterceptor(versions apiversion.Set) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		_ = ss.SetHeader(versionMetadata(versions, info.FullMethod))
+		return handler(srv, ss)
+	}
+}
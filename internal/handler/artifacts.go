@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/logger"
+	"taskflow/internal/storage"
+	pb "taskflow/proto"
+)
+
+// SetArtifactStore 设置产物存储（转存的任务输出、任务归档），store 为 nil 时归档不可用
+func (h *TaskHandler) SetArtifactStore(store storage.BlobStore) {
+	h.artifacts = store
+}
+
+// OpenTaskOutput 打开任务输出（JSON），输出已转存时从产物存储读取，调用者负责关闭返回的 ReadCloser
+func (h *TaskHandler) OpenTaskOutput(ctx context.Context, taskID string) (io.ReadCloser, error) {
+	if taskID == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id is required").ToGRPCStatus().Err()
+	}
+	task, err := h.getAccessibleTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.OutputRef == "" {
+		data, err := json.Marshal(task.OutputResult)
+		if err != nil {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeUnknown, err.Error()).ToGRPCStatus().Err()
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	if h.artifacts == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeBlobDisabled, "artifact storage is not enabled").ToGRPCStatus().Err()
+	}
+	rc, err := h.artifacts.Get(ctx, task.OutputRef)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeNotFound, "task output not found").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeBlobStore, err.Error()).ToGRPCStatus().Err()
+	}
+	return rc, nil
+}
+
+// ArchiveTask 把任务导出（含事件和评论）写入产物存储，返回对象 key 和大小
+func (h *TaskHandler) ArchiveTask(ctx context.Context, taskID string) (string, int64, error) {
+	if h.artifacts == nil {
+		return "", 0, errorcode.NewTaskError(errorcode.ErrCodeBlobDisabled, "artifact storage is not enabled").ToGRPCStatus().Err()
+	}
+
+	task, err := h.GetTask(ctx, &pb.GetTaskRequest{Id: taskID, IncludeEvents: true})
+	if err != nil {
+		return "", 0, err
+	}
+	data, err := json.Marshal(task)
+	if err != nil {
+		return "", 0, errorcode.NewTaskError(errorcode.ErrCodeUnknown, err.Error()).ToGRPCStatus().Err()
+	}
+
+	key := fmt.Sprintf("exports/%s/%d.json", task.Id, time.Now().Unix())
+	if err := h.artifacts.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "application/json"); err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return "", 0, errorcode.NewTaskError(errorcode.ErrCodeBlobStore, err.Error()).ToGRPCStatus().Err()
+	}
+	return key, int64(len(data)), nil
+}
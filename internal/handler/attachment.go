@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"taskflow/internal/config"
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/storage"
+	pb "taskflow/proto"
+)
+
+// maxAttachmentFilename 附件文件名最大长度
+const maxAttachmentFilename = 255
+
+// AttachmentPolicy 附件大小与类型限制
+type AttachmentPolicy struct {
+	MaxSize      int64    // 单个附件最大字节数
+	AllowedTypes []string // 允许的 MIME 类型，支持 text/* 通配；为空表示不限制
+}
+
+// allows 检查内容类型是否允许
+func (p AttachmentPolicy) allows(contentType string) bool {
+	if len(p.AllowedTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range p.AllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAttachmentStore 设置附件存储后端与限制，store 为 nil 时附件接口不可用
+func (h *TaskHandler) SetAttachmentStore(store storage.BlobStore, policy AttachmentPolicy) {
+	if policy.MaxSize <= 0 {
+		policy.MaxSize = config.DefaultAttachmentMaxSize
+	}
+	h.blobs = store
+	h.attachments = policy
+}
+
+// UploadAttachment 上传附件（gRPC）
+func (h *TaskHandler) UploadAttachment(ctx context.Context, req *pb.UploadAttachmentRequest) (*pb.TaskAttachment, error) {
+	a, err := h.StoreAttachment(ctx, req.TaskId, req.Filename, req.ContentType, bytes.NewReader(req.Content), req.UploadedBy)
+	if err != nil {
+		return nil, err
+	}
+	return toPBTaskAttachment(a), nil
+}
+
+// StoreAttachment 校验并保存附件内容和元数据，超出大小限制时不会读取全部内容
+func (h *TaskHandler) StoreAttachment(ctx context.Context, taskID, filename, contentType string, r io.Reader, uploadedBy string) (*model.TaskAttachment, error) {
+	if h.blobs == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeBlobDisabled, "attachments are not enabled").ToGRPCStatus().Err()
+	}
+
+	filename = filepath.Base(strings.ReplaceAll(strings.TrimSpace(filename), "\\", "/"))
+	if taskID == "" || filename == "" || filename == "." || filename == "/" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id and filename are required").ToGRPCStatus().Err()
+	}
+	if len(filename) > maxAttachmentFilename {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam,
+			fmt.Sprintf("filename must be at most %d bytes", maxAttachmentFilename)).ToGRPCStatus().Err()
+	}
+
+	if _, err := h.getAccessibleTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+
+	// 多读一个字节用于判断是否超限
+	content, err := io.ReadAll(io.LimitReader(r, h.attachments.MaxSize+1))
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
+	if int64(len(content)) > h.attachments.MaxSize {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodePayloadTooLarge,
+			fmt.Sprintf("attachment exceeds %d bytes", h.attachments.MaxSize)).ToGRPCStatus().Err()
+	}
+
+	// 未声明类型时根据内容探测
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(content)
+	}
+	if !h.attachments.allows(contentType) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeUnsupportedType,
+			fmt.Sprintf("content type %s is not allowed", contentType)).ToGRPCStatus().Err()
+	}
+
+	author := uploadedBy
+	if userID := grpc_middleware.GetUserID(ctx); userID != "" {
+		author = userID
+	}
+
+	sum := sha256.Sum256(content)
+	id := uuid.New().String()
+	a := &model.TaskAttachment{
+		ID:          id,
+		TaskID:      taskID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		Checksum:    hex.EncodeToString(sum[:]),
+		StorageKey:  "tasks/" + taskID + "/attachments/" + id,
+		UploadedBy:  author,
+		CreatedAt:   model.Now(),
+	}
+
+	if err := h.blobs.Put(ctx, a.StorageKey, bytes.NewReader(content), a.Size, a.ContentType); err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeBlobStore, err.Error()).ToGRPCStatus().Err()
+	}
+	if err := h.repo.AddAttachment(a); err != nil {
+		logger.Errorf("Handler error: %v", err)
+		// 元数据写入失败时清理已上传的内容
+		if derr := h.blobs.Delete(context.Background(), a.StorageKey); derr != nil {
+			logger.Warnf("Failed to clean up blob %s: %v", a.StorageKey, derr)
+		}
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	return a, nil
+}
+
+// ListAttachments 列出任务附件
+func (h *TaskHandler) ListAttachments(ctx context.Context, req *pb.ListAttachmentsRequest) (*pb.ListAttachmentsResponse, error) {
+	if req.TaskId == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id is required").ToGRPCStatus().Err()
+	}
+	if _, err := h.getAccessibleTask(ctx, req.TaskId); err != nil {
+		return nil, err
+	}
+
+	attachments, err := h.repo.ListAttachments(req.TaskId)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	resp := &pb.ListAttachmentsResponse{}
+	for _, a := range attachments {
+		resp.Attachments = append(resp.Attachments, toPBTaskAttachment(a))
+	}
+	return resp, nil
+}
+
+// DownloadAttachment 下载附件内容（gRPC）
+func (h *TaskHandler) DownloadAttachment(ctx context.Context, req *pb.DownloadAttachmentRequest) (*pb.DownloadAttachmentResponse, error) {
+	a, rc, err := h.OpenAttachment(ctx, req.TaskId, req.AttachmentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeBlobStore, err.Error()).ToGRPCStatus().Err()
+	}
+
+	return &pb.DownloadAttachmentResponse{
+		Attachment: toPBTaskAttachment(a),
+		Content:    content,
+	}, nil
+}
+
+// OpenAttachment 打开附件内容，调用者负责关闭返回的 ReadCloser
+func (h *TaskHandler) OpenAttachment(ctx context.Context, taskID, attachmentID string) (*model.TaskAttachment, io.ReadCloser, error) {
+	a, err := h.getAttachment(ctx, taskID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rc, err := h.blobs.Get(ctx, a.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, errorcode.NewTaskError(errorcode.ErrCodeNotFound, "attachment content not found").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, nil, errorcode.NewTaskError(errorcode.ErrCodeBlobStore, err.Error()).ToGRPCStatus().Err()
+	}
+	return a, rc, nil
+}
+
+// DeleteAttachment 删除附件元数据及内容
+func (h *TaskHandler) DeleteAttachment(ctx context.Context, req *pb.DeleteAttachmentRequest) (*pb.DeleteAttachmentResponse, error) {
+	a, err := h.getAttachment(ctx, req.TaskId, req.AttachmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.repo.DeleteAttachment(a.TaskID, a.ID); err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	// 元数据已删除，内容清理失败只记录日志
+	if err := h.blobs.Delete(ctx, a.StorageKey); err != nil {
+		logger.Warnf("Failed to delete blob %s: %v", a.StorageKey, err)
+	}
+
+	return &pb.DeleteAttachmentResponse{}, nil
+}
+
+// getAttachment 检查权限并加载附件元数据
+func (h *TaskHandler) getAttachment(ctx context.Context, taskID, attachmentID string) (*model.TaskAttachment, error) {
+	if h.blobs == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeBlobDisabled, "attachments are not enabled").ToGRPCStatus().Err()
+	}
+	if taskID == "" || attachmentID == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id and attachment_id are required").ToGRPCStatus().Err()
+	}
+	if _, err := h.getAccessibleTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+
+	a, err := h.repo.GetAttachment(taskID, attachmentID)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if a == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeNotFound, "attachment not found").ToGRPCStatus().Err()
+	}
+	return a, nil
+}
+
+// toPBTaskAttachment 转换为 Protobuf 附件
+func toPBTaskAttachment(a *model.TaskAttachment) *pb.TaskAttachment {
+	return &pb.TaskAttachment{
+		Id:          a.ID,
+		TaskId:      a.TaskID,
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		Checksum:    a.Checksum,
+		UploadedBy:  a.UploadedBy,
+		CreatedAt:   a.CreatedAt.Unix(),
+	}
+}
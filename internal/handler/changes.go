@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/model"
+	pb "taskflow/proto"
+)
+
+// 变更流分页限制
+const (
+	defaultChangeFeedLimit = 1000
+	maxChangeFeedLimit     = 10000
+)
+
+// 任务快照分页限制
+const (
+	defaultSnapshotPageSize = 500
+	maxSnapshotPageSize     = 5000
+)
+
+// changeStreamBatch StreamChanges 每次读取的事件数
+const changeStreamBatch = 100
+
+// GetChangeFeed 按序号返回所有任务中 since_seq 之后的事件，供外部系统增量同步任务状态
+func (h *TaskHandler) GetChangeFeed(ctx context.Context, req *pb.GetChangeFeedRequest) (*pb.GetChangeFeedResponse, error) {
+	canAccess, err := h.taskAccessFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultChangeFeedLimit
+	}
+	if limit > maxChangeFeedLimit {
+		limit = maxChangeFeedLimit
+	}
+
+	// 多取一条用于判断是否还有下一页
+	events, err := h.repo.ListEventsAfter(req.SinceSeq, limit+1)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	resp := &pb.GetChangeFeedResponse{LastSeq: req.SinceSeq}
+	if len(events) > limit {
+		events = events[:limit]
+		resp.HasMore = true
+	}
+	visible, err := h.visibleTasks(events, canAccess)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		// 不可见任务的事件也推进游标，调用者不会反复读到同一段
+		resp.LastSeq = e.Seq
+		if visible[e.TaskID] != nil {
+			resp.Events = append(resp.Events, toPBTaskEvent(e))
+		}
+	}
+	return resp, nil
+}
+
+// SnapshotTasks 按 ID 分页返回调用者可见的任务。游标在第一页读取前取得，之后的修改都会出现在
+// StreamChanges 中，因此快照加上游标之后的变更即可得到完整的任务表，镜像端按任务 ID 覆盖写入
+func (h *TaskHandler) SnapshotTasks(ctx context.Context, req *pb.SnapshotTasksRequest) (*pb.SnapshotTasksResponse, error) {
+	if req.Cursor < 0 {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "cursor must be greater than or equal to 0").ToGRPCStatus().Err()
+	}
+	canAccess, err := h.taskAccessFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultSnapshotPageSize
+	}
+	if pageSize > maxSnapshotPageSize {
+		pageSize = maxSnapshotPageSize
+	}
+
+	resp := &pb.SnapshotTasksResponse{Cursor: req.Cursor, LastId: req.AfterId}
+	if req.Cursor == 0 {
+		if resp.Cursor, err = h.repo.LatestEventSeq(); err != nil {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+	}
+
+	// 多取一个用于判断是否还有下一页
+	tasks, err := h.repo.ListAfterID(req.AfterId, pageSize+1)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if len(tasks) > pageSize {
+		tasks = tasks[:pageSize]
+		resp.HasMore = true
+	}
+	for _, task := range tasks {
+		resp.LastId = task.ID
+		if canAccess(task) {
+			resp.Tasks = append(resp.Tasks, h.toPBTask(task, false))
+		}
+	}
+	return resp, nil
+}
+
+// StreamChanges 服务端流式 - 回放 since_seq 之后的事件并持续推送新事件，每条附带任务的最新状态
+func (h *TaskHandler) StreamChanges(req *pb.StreamChangesRequest, stream pb.TaskService_StreamChangesServer) error {
+	return h.FollowChanges(stream.Context(), req.SinceSeq, stream.Send)
+}
+
+// FollowChanges 跟踪变更直到 ctx 取消，供 gRPC 流和 HTTP SSE 共用
+func (h *TaskHandler) FollowChanges(ctx context.Context, sinceSeq int64, send func(*pb.TaskChange) error) error {
+	canAccess, err := h.taskAccessFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 先订阅再读取，避免读取与订阅之间的变更只能等到下一次轮询
+	ch := h.subscribe("")
+	defer h.unsubscribe("", ch)
+
+	latest, err := h.repo.LatestEventSeq()
+	if err != nil {
+		return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if sinceSeq < 0 || sinceSeq > latest {
+		return errorcode.NewTaskError(errorcode.ErrCodeInvalidParam,
+			fmt.Sprintf("since_seq %d is not a known event sequence", sinceSeq)).ToGRPCStatus().Err()
+	}
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	last := sinceSeq
+	for {
+		for {
+			events, err := h.repo.ListEventsAfter(last, changeStreamBatch)
+			if err != nil {
+				return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+			}
+			visible, err := h.visibleTasks(events, canAccess)
+			if err != nil {
+				return err
+			}
+			for _, e := range events {
+				last = e.Seq
+				task := visible[e.TaskID]
+				if task == nil {
+					continue
+				}
+				if err := send(&pb.TaskChange{Event: toPBTaskEvent(e), Task: h.toPBTask(task, false)}); err != nil {
+					return err
+				}
+			}
+			if len(events) < changeStreamBatch {
+				break
+			}
+		}
+
+		// 本实例的变更通知只用于唤醒，其他实例写入的事件由轮询发现
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-ch:
+		}
+	}
+}
+
+// visibleTasks 返回事件涉及的任务中调用者可见的任务，按任务 ID 索引
+func (h *TaskHandler) visibleTasks(events []model.TaskEvent, canAccess func(*model.Task) bool) (map[string]*model.Task, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, e := range events {
+		if !seen[e.TaskID] {
+			seen[e.TaskID] = true
+			ids = append(ids, e.TaskID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	tasks, err := h.repo.GetByIDs(ids)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	visible := make(map[string]*model.Task, len(tasks))
+	for _, task := range tasks {
+		if canAccess(task) {
+			visible[task.ID] = task
+		}
+	}
+	return visible, nil
+}
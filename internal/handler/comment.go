@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// maxCommentLength 单条评论最大字符数
+const maxCommentLength = 4096
+
+// AddTaskComment 为任务添加评论（如故障排查注解）
+func (h *TaskHandler) AddTaskComment(ctx context.Context, req *pb.AddTaskCommentRequest) (*pb.TaskComment, error) {
+	body := strings.TrimSpace(req.Body)
+	if req.TaskId == "" || body == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id and body are required").ToGRPCStatus().Err()
+	}
+	if n := len([]rune(body)); n > maxCommentLength {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam,
+			fmt.Sprintf("body must be at most %d characters, got %d", maxCommentLength, n)).ToGRPCStatus().Err()
+	}
+
+	if _, err := h.getAccessibleTask(ctx, req.TaskId); err != nil {
+		return nil, err
+	}
+
+	author := req.Author
+	if userID := grpc_middleware.GetUserID(ctx); userID != "" {
+		author = userID
+	}
+
+	comment := &model.TaskComment{
+		ID:        uuid.New().String(),
+		TaskID:    req.TaskId,
+		Author:    author,
+		Body:      body,
+		CreatedAt: model.Now(),
+	}
+	if err := h.repo.AddComment(comment); err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	return toPBTaskComment(comment), nil
+}
+
+// ListTaskComments 按时间顺序列出任务评论
+func (h *TaskHandler) ListTaskComments(ctx context.Context, req *pb.ListTaskCommentsRequest) (*pb.ListTaskCommentsResponse, error) {
+	if req.TaskId == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id is required").ToGRPCStatus().Err()
+	}
+
+	task, err := h.getAccessibleTask(ctx, req.TaskId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListTaskCommentsResponse{}
+	for i := range task.Comments {
+		resp.Comments = append(resp.Comments, toPBTaskComment(&task.Comments[i]))
+	}
+	return resp, nil
+}
+
+// getAccessibleTask 加载任务并检查调用者的访问权限
+func (h *TaskHandler) getAccessibleTask(ctx context.Context, id string) (*model.Task, error) {
+	task, err := h.repo.GetByID(id)
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if err := h.checkTaskAccess(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// toPBTaskComment 转换为 Protobuf 评论
+func toPBTaskComment(comment *model.TaskComment) *pb.TaskComment {
+	return &pb.TaskComment{
+		Id:        comment.ID,
+		TaskId:    comment.TaskID,
+		Author:    comment.Author,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt.Unix(),
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_CorrelationID(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+
+	created, err := h.CreateTask(grpc_middleware.ContextWithRequestID(context.Background(), "req-create"), &pb.CreateTaskRequest{Name: "traced"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if created.CorrelationId != "req-create" {
+		t.Errorf("correlation_id = %q, want req-create", created.CorrelationId)
+	}
+
+	ctx := grpc_middleware.ContextWithRequestID(context.Background(), "req-cancel")
+	if _, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_CANCELLED}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	got, err := h.GetTask(context.Background(), &pb.GetTaskRequest{Id: created.Id, IncludeEvents: true})
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if got.CorrelationId != "req-create" || len(got.Events) != 2 ||
+		got.Events[0].CorrelationId != "req-create" || got.Events[1].CorrelationId != "req-cancel" {
+		t.Errorf("unexpected correlation IDs: task=%q events=%+v", got.CorrelationId, got.Events)
+	}
+
+	list, err := h.ListTasks(context.Background(), &pb.ListTasksRequest{CorrelationId: "req-create"})
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	if len(list.Tasks) != 1 || list.Tasks[0].Id != created.Id {
+		t.Errorf("expected the traced task, got %+v", list.Tasks)
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// DeleteTask 删除未在运行的任务及其事件、评论、附件、日志和历次运行。仍被未结束的任务依赖时须指定 force，
+// 强制删除时从这些任务中移除对它的依赖，并重新评估待调度任务。附件内容和转存的输出在删除成功后从存储中清理
+func (h *TaskHandler) DeleteTask(ctx context.Context, req *pb.DeleteTaskRequest) (*pb.DeleteTaskResponse, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+	task, err := h.getAccessibleTask(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExpectedVersion(task, req.ExpectedVersion); err != nil {
+		return nil, err
+	}
+	// 执行器仍会上报运行中任务的进度和结果，须先取消
+	if task.Status == model.TaskStatusRunning {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskInvalidTransition,
+			fmt.Sprintf("task is %s, cancel it before deleting", task.Status)).ToGRPCStatus().Err()
+	}
+
+	operator := grpc_middleware.GetUserID(ctx)
+	if operator == "" {
+		operator = "system"
+	}
+	requestID := grpc_middleware.GetRequestID(ctx)
+
+	deleted, err := h.repo.Delete(task.ID, req.Force, operator, requestID)
+	var dependentsErr *repository.DependentsError
+	if errors.As(err, &dependentsErr) {
+		return nil, hasDependentsError(dependentsErr).ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	logger.Infof("Task %s deleted by %s", task.ID, operator)
+
+	// 数据库中的记录已删除，存储中的对象清理失败只记录日志；请求结束不应中断清理
+	h.deleteTaskBlobs(context.WithoutCancel(ctx), deleted)
+
+	for _, id := range deleted.Dependents {
+		dependent, err := h.repo.GetByID(id)
+		if err != nil {
+			logger.Warnf("Failed to load dependent task %s: %v", id, err)
+			continue
+		}
+		h.broadcastCorrelatedTaskChange(dependent.ID, dependent, dependent.Status, dependent.Status, "dependency_removed", requestID)
+	}
+	// 下游任务的依赖可能已全部满足
+	if len(deleted.Dependents) > 0 {
+		h.wakeScheduler()
+	}
+	return &pb.DeleteTaskResponse{Dependents: deleted.Dependents}, nil
+}
+
+// hasDependentsError 任务仍被未结束的任务依赖的错误，每个下游任务一条字段错误，字段为下游任务 ID
+func hasDependentsError(err *repository.DependentsError) *errorcode.TaskError {
+	taskErr := errorcode.NewTaskError(errorcode.ErrCodeTaskHasDependents,
+		fmt.Sprintf("task %s is required by %d unfinished tasks, delete with force to remove the dependencies", err.TaskID, len(err.Dependents)))
+	for _, d := range err.Dependents {
+		taskErr.WithViolations(errorcode.NewFieldViolation(d.ID, "active_dependent", fmt.Sprintf("%s is %s", d.Name, d.Status)))
+	}
+	return taskErr
+}
+
+// deleteTaskBlobs 从附件存储和产物存储中删除已删除任务的附件内容和转存的输出
+func (h *TaskHandler) deleteTaskBlobs(ctx context.Context, deleted *repository.DeletedTask) {
+	for _, key := range deleted.AttachmentKeys {
+		if h.blobs == nil {
+			logger.Warnf("Attachment storage is not enabled, blob %s left behind", key)
+			continue
+		}
+		if err := h.blobs.Delete(ctx, key); err != nil {
+			logger.Warnf("Failed to delete blob %s: %v", key, err)
+		}
+	}
+	for _, key := range deleted.OutputRefs {
+		if h.artifacts == nil {
+			logger.Warnf("Artifact storage is not enabled, artifact %s left behind", key)
+			continue
+		}
+		if err := h.artifacts.Delete(ctx, key); err != nil {
+			logger.Warnf("Failed to delete artifact %s: %v", key, err)
+		}
+	}
+}
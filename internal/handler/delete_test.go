@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	"taskflow/internal/storage"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_DeleteTask(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	blobs, artifacts := storage.NewMemoryStore(), storage.NewMemoryStore()
+	h.SetAttachmentStore(blobs, AttachmentPolicy{})
+	h.SetArtifactStore(artifacts)
+	var wakeups int
+	h.SetSchedulerWakeup(func() { wakeups++ })
+	ctx := context.Background()
+
+	// upstream 已完成，输出转存到产物存储，并有一个附件；downstream 仍在等待它
+	now := time.Now()
+	for _, task := range []*model.Task{
+		{ID: "upstream", Name: "upstream", Status: model.TaskStatusRunning, CreatedAt: now, UpdatedAt: now},
+		{ID: "downstream", Name: "downstream", Status: model.TaskStatusPending, Dependencies: []string{"upstream"}, CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("Create(%s): %v", task.ID, err)
+		}
+	}
+	for store, key := range map[storage.BlobStore]string{blobs: "attachments/upstream/a1", artifacts: "tasks/upstream/output.json"} {
+		if err := store.Put(ctx, key, strings.NewReader("{}"), 2, "application/json"); err != nil {
+			t.Fatalf("Put(%s): %v", key, err)
+		}
+	}
+	if err := repo.AddAttachment(&model.TaskAttachment{ID: "a1", TaskID: "upstream", Filename: "a.json", StorageKey: "attachments/upstream/a1", CreatedAt: now}); err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+
+	// 运行中的任务须先取消
+	if _, err := h.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: "upstream"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition deleting a running task, got %v", err)
+	}
+	if err := repo.CompleteTask("upstream", model.TaskStatusRunning, nil, "tasks/upstream/output.json", "scheduler", "done", "", nil); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.DeleteTaskRequest
+		want codes.Code
+	}{
+		{"missing id", &pb.DeleteTaskRequest{}, codes.InvalidArgument},
+		{"missing task", &pb.DeleteTaskRequest{Id: "missing"}, codes.NotFound},
+		{"stale version", &pb.DeleteTaskRequest{Id: "upstream", Force: true, ExpectedVersion: 99}, codes.FailedPrecondition},
+	}
+	for _, tc := range cases {
+		if _, err := h.DeleteTask(ctx, tc.req); status.Code(err) != tc.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tc.name, status.Code(err), tc.want, err)
+		}
+	}
+	// 仍被未结束的任务依赖时列出这些任务
+	_, err := h.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: "upstream"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition deleting a task with active dependents, got %v", err)
+	}
+	if taskErr := errorcode.FromGRPCStatus(status.Convert(err)); taskErr.Code != errorcode.ErrCodeTaskHasDependents ||
+		!slices.Equal(taskErr.Violations, []errorcode.FieldViolation{{Field: "downstream", Rule: "active_dependent", Message: "downstream is PENDING"}}) {
+		t.Errorf("unexpected dependents error: %+v", taskErr)
+	}
+	if len(blobs.Keys()) != 1 || len(artifacts.Keys()) != 1 || wakeups != 0 {
+		t.Fatalf("expected rejected deletes to leave the blobs alone, got %v, %v", blobs.Keys(), artifacts.Keys())
+	}
+
+	changes := h.subscribe("downstream")
+	resp, err := h.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: "upstream", Force: true})
+	if err != nil {
+		t.Fatalf("forced DeleteTask: %v", err)
+	}
+	if !slices.Equal(resp.Dependents, []string{"downstream"}) {
+		t.Errorf("unexpected dependents %v", resp.Dependents)
+	}
+	if _, err := h.GetTask(ctx, &pb.GetTaskRequest{Id: "upstream"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound after delete, got %v", err)
+	}
+
+	// 附件内容和转存的输出随任务删除
+	if len(blobs.Keys()) != 0 || len(artifacts.Keys()) != 0 {
+		t.Errorf("expected the blobs deleted, got %v, %v", blobs.Keys(), artifacts.Keys())
+	}
+
+	// 下游任务收到 dependency_removed 变更，调度器被唤醒以调度不再被阻塞的任务
+	select {
+	case change := <-changes:
+		if change.ChangeType != "dependency_removed" || len(change.Task.GetDependencies()) != 0 {
+			t.Errorf("unexpected change event: %+v", change)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected a change event for downstream")
+	}
+	if wakeups != 1 {
+		t.Errorf("expected the scheduler woken once, got %d", wakeups)
+	}
+	events, _ := repo.GetEventsByTaskID("downstream")
+	if last := events[len(events)-1]; last.Operator != "system" || last.Message != "dependency upstream removed: upstream task deleted" {
+		t.Errorf("unexpected dependency removal event: %+v", last)
+	}
+}
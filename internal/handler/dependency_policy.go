@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"fmt"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/model"
+)
+
+// fromPBDependencyPolicies 转换依赖失败处理方式，未设置时返回 nil
+func fromPBDependencyPolicies(p map[string]string) map[string]model.DependencyFailurePolicy {
+	if len(p) == 0 {
+		return nil
+	}
+	policies := make(map[string]model.DependencyFailurePolicy, len(p))
+	for depID, policy := range p {
+		policies[depID] = model.DependencyFailurePolicy(policy)
+	}
+	return policies
+}
+
+// toPBDependencyPolicies 转换为 Protobuf 依赖失败处理方式
+func toPBDependencyPolicies(p map[string]model.DependencyFailurePolicy) map[string]string {
+	if len(p) == 0 {
+		return nil
+	}
+	policies := make(map[string]string, len(p))
+	for depID, policy := range p {
+		policies[depID] = string(policy)
+	}
+	return policies
+}
+
+// validateDependencyPolicies 校验依赖失败处理方式：只能为已声明的依赖配置，取值须合法
+func validateDependencyPolicies(verr *errorcode.ValidationError, policies map[string]string, dependencies []string) {
+	declared := make(map[string]bool, len(dependencies))
+	for _, dep := range dependencies {
+		declared[dep] = true
+	}
+	for depID, policy := range policies {
+		field := fmt.Sprintf("dependency_policies[%s]", depID)
+		if !declared[depID] {
+			verr.Add(field, "unknown", "must refer to a declared dependency")
+			continue
+		}
+		if err := model.DependencyFailurePolicy(policy).Validate(); err != nil {
+			verr.Add(field, "enum", err.Error())
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// maxRepairReasonLength 修复依赖原因的最大字符数
+const maxRepairReasonLength = 1024
+
+// RepairTaskDependencies 修复 PENDING 任务的依赖：改为依赖其他任务或移除依赖，用于上游任务被删除或最终失败、
+// 下游任务被阻塞时不必重建任务即可解除阻塞。仅 SetAccessControl 配置的管理员可用
+func (h *TaskHandler) RepairTaskDependencies(ctx context.Context, req *pb.RepairTaskDependenciesRequest) (*pb.Task, error) {
+	userID := grpc_middleware.GetUserID(ctx)
+	if userID == "" || !h.accessAdmins[userID] {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeForbidden, "repairing dependencies requires an admin").ToGRPCStatus().Err()
+	}
+	return h.RepairDependencies(ctx, req, userID)
+}
+
+// RepairDependencies 以 operator 的身份修复任务的依赖，不检查调用者是否为管理员，供已通过管理员认证的调用方
+// （/admin 端点）使用。修复记录为任务事件，包含操作者、修复内容、原因和请求 ID
+func (h *TaskHandler) RepairDependencies(ctx context.Context, req *pb.RepairTaskDependenciesRequest, operator string) (*pb.Task, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+	if n := utf8.RuneCountInString(req.Reason); n > maxRepairReasonLength {
+		return nil, errorcode.NewValidationError(errorcode.NewFieldViolation("reason", "max",
+			fmt.Sprintf("has %d characters, at most %d allowed", n, maxRepairReasonLength))).ToGRPCStatus().Err()
+	}
+
+	task, err := h.getAccessibleTask(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExpectedVersion(task, req.ExpectedVersion); err != nil {
+		return nil, err
+	}
+	if task.Status != model.TaskStatusPending {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskInvalidTransition,
+			fmt.Sprintf("task is %s, only PENDING tasks can have their dependencies repaired", task.Status)).ToGRPCStatus().Err()
+	}
+
+	repairs := make([]model.DependencyRepair, len(req.Repairs))
+	for i, r := range req.Repairs {
+		repairs[i] = model.DependencyRepair{DependencyID: r.DependencyId, ReplaceWith: r.ReplaceWith}
+	}
+	if err := h.checkDependencyRepairs(task, repairs); err != nil {
+		return nil, err
+	}
+
+	err = h.repo.RepairDependencies(task.ID, repairs, operator, req.Reason, grpc_middleware.GetRequestID(ctx))
+	if errors.Is(err, repository.ErrStatusMismatch) || errors.Is(err, repository.ErrDependenciesChanged) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeConflict, "task changed concurrently, retry with the latest dependencies").ToGRPCStatus().Err()
+	}
+	if errors.Is(err, repository.ErrCrossShard) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	logger.Infof("Task %s %s by %s", task.ID, model.DependencyRepairMessage(repairs, req.Reason), operator)
+
+	if task, err = h.repo.GetByID(task.ID); err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	h.broadcastCorrelatedTaskChange(task.ID, task, task.Status, task.Status, "dependencies_repaired", grpc_middleware.GetRequestID(ctx))
+	// 依赖可能已全部满足，重新评估待调度任务
+	h.wakeScheduler()
+	return h.toPBTask(task, false), nil
+}
+
+// checkDependencyRepairs 校验依赖修复：修复须适用于任务当前的依赖，改为依赖的任务须存在，且不能依赖任务自身的下游（形成环）
+func (h *TaskHandler) checkDependencyRepairs(task *model.Task, repairs []model.DependencyRepair) error {
+	repaired := *task
+	if errs := repaired.RepairDependencies(repairs); len(errs) > 0 {
+		verr := errorcode.NewValidationError()
+		for _, e := range errs {
+			verr.Add(e.Field, e.Rule, e.Message)
+		}
+		return verr.ToGRPCStatus().Err()
+	}
+
+	var targets []string
+	fields := make(map[string]string)
+	for i, r := range repairs {
+		if r.ReplaceWith != "" {
+			targets = append(targets, r.ReplaceWith)
+			fields[r.ReplaceWith] = fmt.Sprintf("repairs[%d].replace_with", i)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	found, err := h.repo.GetByIDs(targets)
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	verr := errorcode.NewValidationError()
+	for i, t := range found {
+		if t == nil {
+			verr.Add(fields[targets[i]], "not_found", "must refer to an existing task")
+		}
+	}
+	if verr.HasErrors() {
+		return verr.ToGRPCStatus().Err()
+	}
+
+	// 沿改为依赖的任务的上游逐层查找，遇到任务自身说明修复后形成环
+	visited := make(map[string]bool)
+	for level := found; len(level) > 0; {
+		var next []string
+		for _, t := range level {
+			if t == nil || visited[t.ID] {
+				continue
+			}
+			if t.ID == task.ID {
+				return errorcode.NewTaskError(errorcode.ErrCodeTaskDependencyCycle,
+					fmt.Sprintf("repaired dependencies would make task %s depend on itself", task.ID)).ToGRPCStatus().Err()
+			}
+			visited[t.ID] = true
+			next = append(next, t.Dependencies...)
+		}
+		if len(next) == 0 {
+			break
+		}
+		if level, err = h.repo.GetByIDs(next); err != nil {
+			logger.Errorf("Handler error: %v", err)
+			return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+	}
+	return nil
+}
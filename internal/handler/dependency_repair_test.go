@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_RepairTaskDependencies(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	h.SetAccessControl([]string{"user-admin-to"}, true)
+	ctx := context.Background()
+
+	// 简化的令牌校验把 Bearer 令牌前 8 个字符作为用户 ID
+	repair := func(token string, req *pb.RepairTaskDependenciesRequest) (*pb.Task, error) {
+		authCtx := ctx
+		if token != "" {
+			authCtx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+		resp, err := grpc_middleware.UnaryAuthInterceptor(nil)(authCtx, req,
+			&grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/RepairTaskDependencies"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return h.RepairTaskDependencies(ctx, req.(*pb.RepairTaskDependenciesRequest))
+			})
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*pb.Task), nil
+	}
+
+	// blocked 依赖一个已删除的任务和一个按 wait 处理、最终失败的任务，永远不会被调度
+	now := time.Now()
+	for _, task := range []*model.Task{
+		{ID: "failed", Name: "failed", Status: model.TaskStatusFailed, CreatedAt: now, UpdatedAt: now},
+		{ID: "replacement", Name: "replacement", Status: model.TaskStatusPending, CreatedAt: now, UpdatedAt: now},
+		{ID: "blocked", Name: "blocked", Status: model.TaskStatusPending, Dependencies: []string{"deleted", "failed"},
+			DependencyPolicies: map[string]model.DependencyFailurePolicy{"failed": model.DependencyFailureWait}, CreatedAt: now, UpdatedAt: now},
+		{ID: "downstream", Name: "downstream", Status: model.TaskStatusPending, Dependencies: []string{"blocked"}, CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("Create(%s): %v", task.ID, err)
+		}
+	}
+	_, lastSeq, _ := repo.OutboxSeqRange()
+
+	relink := &pb.RepairTaskDependenciesRequest{
+		Id:      "blocked",
+		Repairs: []*pb.DependencyRepair{{DependencyId: "deleted"}, {DependencyId: "failed", ReplaceWith: "replacement"}},
+		Reason:  "upstream was re-created",
+	}
+	for _, token := range []string{"", "bob-token"} {
+		if _, err := repair(token, relink); status.Code(err) != codes.PermissionDenied && status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected a non-admin caller %q rejected, got %v", token, err)
+		}
+	}
+
+	// field 为校验错误中的字段
+	cases := []struct {
+		name  string
+		req   *pb.RepairTaskDependenciesRequest
+		want  codes.Code
+		field string
+	}{
+		{"missing task", &pb.RepairTaskDependenciesRequest{Id: "missing", Repairs: relink.Repairs}, codes.NotFound, ""},
+		{"no repairs", &pb.RepairTaskDependenciesRequest{Id: "blocked"}, codes.InvalidArgument, "repairs"},
+		{"not a dependency", &pb.RepairTaskDependenciesRequest{Id: "blocked", Repairs: []*pb.DependencyRepair{{DependencyId: "replacement"}}},
+			codes.InvalidArgument, "repairs[0].dependency_id"},
+		{"missing replacement", &pb.RepairTaskDependenciesRequest{Id: "blocked", Repairs: []*pb.DependencyRepair{{DependencyId: "deleted", ReplaceWith: "gone"}}},
+			codes.InvalidArgument, "repairs[0].replace_with"},
+		{"stale version", &pb.RepairTaskDependenciesRequest{Id: "blocked", Repairs: relink.Repairs, ExpectedVersion: 99}, codes.FailedPrecondition, ""},
+		{"finished task", &pb.RepairTaskDependenciesRequest{Id: "failed", Repairs: relink.Repairs}, codes.FailedPrecondition, ""},
+	}
+	for _, tc := range cases {
+		_, err := repair("admin-token", tc.req)
+		if status.Code(err) != tc.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tc.name, status.Code(err), tc.want, err)
+			continue
+		}
+		if tc.field == "" {
+			continue
+		}
+		if verr := errorcode.FromGRPCBadRequest(status.Convert(err)); verr == nil || verr.Violations[0].Field != tc.field {
+			t.Errorf("%s: expected a violation of %s, got %v", tc.name, tc.field, verr)
+		}
+	}
+	// 改为依赖自身的下游会形成环
+	_, err := repair("admin-token", &pb.RepairTaskDependenciesRequest{Id: "blocked", Repairs: []*pb.DependencyRepair{{DependencyId: "deleted", ReplaceWith: "downstream"}}})
+	if status.Code(err) != codes.InvalidArgument || !strings.Contains(status.Convert(err).Message(), "dependency cycle") {
+		t.Errorf("expected a dependency cycle error, got %v", err)
+	}
+	if events, _ := repo.ListOutboxEventsAfter(lastSeq, 10); len(events) != 0 {
+		t.Fatalf("expected rejected repairs to leave no events, got %d", len(events))
+	}
+
+	task, err := repair("admin-token", relink)
+	if err != nil {
+		t.Fatalf("RepairTaskDependencies: %v", err)
+	}
+	if len(task.Dependencies) != 1 || task.Dependencies[0] != "replacement" || task.DependencyPolicies["replacement"] != "wait" {
+		t.Errorf("unexpected repaired dependencies %v with policies %v", task.Dependencies, task.DependencyPolicies)
+	}
+
+	// 修复记录在任务事件中，订阅者收到 dependencies_repaired 变更
+	events, _ := repo.ListOutboxEventsAfter(lastSeq, 10)
+	if len(events) != 1 || events[0].Operator != "user-admin-to" ||
+		events[0].Message != "dependencies repaired (deleted removed, failed -> replacement): upstream was re-created" {
+		t.Fatalf("unexpected repair events: %+v", events)
+	}
+	if change := h.outboxChangeEvent(events[0]); change.ChangeType != "dependencies_repaired" || len(change.Task.GetDependencies()) != 1 {
+		t.Errorf("unexpected watch event: %+v", change)
+	}
+
+	// 管理端点以固定身份调用，不经过用户认证
+	task, err = h.RepairDependencies(ctx, &pb.RepairTaskDependenciesRequest{
+		Id: "blocked", Repairs: []*pb.DependencyRepair{{DependencyId: "replacement"}}, ExpectedVersion: task.Version,
+	}, "admin")
+	if err != nil || len(task.Dependencies) != 0 {
+		t.Fatalf("RepairDependencies as admin endpoint: %v, %v", task, err)
+	}
+	if events, _ := repo.GetEventsByTaskID("blocked"); events[len(events)-1].Operator != "admin" {
+		t.Errorf("expected the admin endpoint recorded as the operator, got %+v", events[len(events)-1])
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/model"
+	pb "taskflow/proto"
+)
+
+const (
+	// durationWindow 估算耗时时参考的最近成功任务数
+	durationWindow = 100
+	// maxDurationWindow 耗时统计接口允许的最大窗口
+	maxDurationWindow = 1000
+	// durationCacheTTL 各任务类型耗时统计的缓存时间，列表接口中的 ETA 不必每次查询历史
+	durationCacheTTL = 30 * time.Second
+)
+
+// durationStats 某一任务类型最近成功任务的耗时统计
+type durationStats struct {
+	samples []time.Duration // 按完成时间倒序
+	avg     time.Duration
+	min     time.Duration
+	max     time.Duration
+	p50     time.Duration
+	p90     time.Duration
+	p95     time.Duration
+	p99     time.Duration
+}
+
+// newDurationStats 计算均值、极值和百分位数（最近秩法）
+func newDurationStats(samples []time.Duration) durationStats {
+	stats := durationStats{samples: samples}
+	if len(samples) == 0 {
+		return stats
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	percentile := func(p float64) time.Duration {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		return sorted[rank-1]
+	}
+	stats.avg = sum / time.Duration(len(sorted))
+	stats.min = sorted[0]
+	stats.max = sorted[len(sorted)-1]
+	stats.p50 = percentile(50)
+	stats.p90 = percentile(90)
+	stats.p95 = percentile(95)
+	stats.p99 = percentile(99)
+	return stats
+}
+
+// durationCache 缓存各任务类型的耗时统计，零值可用
+type durationCache struct {
+	mu      sync.Mutex
+	entries map[string]durationCacheEntry
+}
+
+type durationCacheEntry struct {
+	stats     durationStats
+	fetchedAt time.Time
+}
+
+// typeDurationStats 返回任务类型最近 durationWindow 个成功任务的耗时统计，结果缓存 durationCacheTTL
+func (h *TaskHandler) typeDurationStats(taskType string) (durationStats, error) {
+	c := &h.durations
+	c.mu.Lock()
+	entry, ok := c.entries[taskType]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < durationCacheTTL {
+		return entry.stats, nil
+	}
+
+	samples, err := h.statsReads.RecentDurations(taskType, durationWindow)
+	if err != nil {
+		return durationStats{}, err
+	}
+	stats := newDurationStats(samples)
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]durationCacheEntry)
+	}
+	c.entries[taskType] = durationCacheEntry{stats: stats, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return stats, nil
+}
+
+// setETA 为 PENDING 和 RUNNING 任务填充预计耗时（同类型历史耗时的中位数）和预计完成时间。
+// PENDING 任务的预计完成时间不含排队等待；运行时间已超过预计耗时的任务按当前时间计
+func (h *TaskHandler) setETA(pbTask *pb.Task, task *model.Task) {
+	if h.repo == nil || task.TaskType == "" {
+		return
+	}
+	if task.Status != model.TaskStatusPending && task.Status != model.TaskStatusRunning {
+		return
+	}
+	stats, err := h.typeDurationStats(task.TaskType)
+	if err != nil || len(stats.samples) == 0 {
+		return
+	}
+
+	now := time.Now()
+	start := now
+	if task.Status == model.TaskStatusRunning && task.StartedAt != nil {
+		start = *task.StartedAt
+	} else if task.NextRunAt != nil && task.NextRunAt.After(now) {
+		start = *task.NextRunAt
+	}
+	eta := start.Add(stats.p50)
+	if eta.Before(now) {
+		eta = now
+	}
+	pbTask.EstimatedDurationMs = stats.p50.Milliseconds()
+	pbTask.Eta = eta.Unix()
+}
+
+// GetDurationStats 按任务类型统计最近成功任务的执行耗时，未指定类型时返回所有有成功记录的类型
+func (h *TaskHandler) GetDurationStats(ctx context.Context, req *pb.GetDurationStatsRequest) (*pb.GetDurationStatsResponse, error) {
+	window := int(req.Window)
+	if window == 0 {
+		window = durationWindow
+	}
+	if window < 1 || window > maxDurationWindow {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "window must be between 1 and 1000").ToGRPCStatus().Err()
+	}
+
+	taskTypes := req.TaskTypes
+	listAll := len(taskTypes) == 0
+	if listAll {
+		var err error
+		if taskTypes, err = h.statsReads.ListTaskTypes(); err != nil {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+	}
+
+	resp := &pb.GetDurationStatsResponse{}
+	for _, taskType := range taskTypes {
+		samples, err := h.statsReads.RecentDurations(taskType, window)
+		if err != nil {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+		if listAll && len(samples) == 0 {
+			continue
+		}
+		resp.Stats = append(resp.Stats, toPBDurationStats(taskType, newDurationStats(samples), req.IncludeSamples))
+	}
+	return resp, nil
+}
+
+// toPBDurationStats 转换为 Protobuf 耗时统计
+func toPBDurationStats(taskType string, stats durationStats, includeSamples bool) *pb.TaskTypeDurationStats {
+	s := &pb.TaskTypeDurationStats{
+		TaskType: taskType,
+		Samples:  int32(len(stats.samples)),
+		AvgMs:    stats.avg.Milliseconds(),
+		MinMs:    stats.min.Milliseconds(),
+		MaxMs:    stats.max.Milliseconds(),
+		P50Ms:    stats.p50.Milliseconds(),
+		P90Ms:    stats.p90.Milliseconds(),
+		P95Ms:    stats.p95.Milliseconds(),
+		P99Ms:    stats.p99.Milliseconds(),
+	}
+	if includeSamples {
+		for _, d := range stats.samples {
+			s.RecentMs = append(s.RecentMs, d.Milliseconds())
+		}
+	}
+	return s
+}
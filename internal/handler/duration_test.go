@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestNewDurationStats(t *testing.T) {
+	var samples []time.Duration
+	for i := 10; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Second)
+	}
+	stats := newDurationStats(samples)
+
+	if stats.avg != 5500*time.Millisecond || stats.min != time.Second || stats.max != 10*time.Second {
+		t.Errorf("unexpected avg/min/max: %s %s %s", stats.avg, stats.min, stats.max)
+	}
+	if stats.p50 != 5*time.Second || stats.p90 != 9*time.Second || stats.p99 != 10*time.Second {
+		t.Errorf("unexpected percentiles: p50=%s p90=%s p99=%s", stats.p50, stats.p90, stats.p99)
+	}
+	if samples[0] != 10*time.Second {
+		t.Error("samples should not be reordered")
+	}
+}
+
+func TestTaskHandler_ETAAndDurationStats(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+
+	now := time.Now()
+	for i, d := range []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second} {
+		task := model.NewTask("done", "", model.TaskPriorityNormal, "etl", nil, nil, 0, "alice")
+		task.ID = "done-" + string(rune('a'+i))
+		started, completed := now.Add(-time.Hour), now.Add(-time.Hour+d)
+		task.Status, task.StartedAt, task.CompletedAt = model.TaskStatusSucceeded, &started, &completed
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	running := model.NewTask("running", "", model.TaskPriorityNormal, "etl", nil, nil, 0, "alice")
+	running.ID = "running"
+	running.Status = model.TaskStatusRunning
+	started := now.Add(-5 * time.Second)
+	running.StartedAt = &started
+	got := h.toPBTask(running, false)
+	if got.EstimatedDurationMs != 20000 {
+		t.Errorf("estimated duration = %dms, want 20000", got.EstimatedDurationMs)
+	}
+	if want := started.Add(20 * time.Second).Unix(); got.Eta != want {
+		t.Errorf("eta = %d, want %d", got.Eta, want)
+	}
+
+	// 终态任务和没有历史记录的类型不返回估算
+	running.Status = model.TaskStatusSucceeded
+	if got := h.toPBTask(running, false); got.Eta != 0 || got.EstimatedDurationMs != 0 {
+		t.Errorf("terminal task should have no ETA, got %+v", got)
+	}
+	other := model.NewTask("other", "", model.TaskPriorityNormal, "report", nil, nil, 0, "alice")
+	if got := h.toPBTask(other, false); got.Eta != 0 {
+		t.Errorf("task type without history should have no ETA, got %d", got.Eta)
+	}
+
+	resp, err := h.GetDurationStats(context.Background(), &pb.GetDurationStatsRequest{IncludeSamples: true})
+	if err != nil {
+		t.Fatalf("GetDurationStats: %v", err)
+	}
+	if len(resp.Stats) != 1 || resp.Stats[0].TaskType != "etl" || resp.Stats[0].Samples != 3 || resp.Stats[0].P50Ms != 20000 {
+		t.Fatalf("unexpected stats: %+v", resp.Stats)
+	}
+	if len(resp.Stats[0].RecentMs) != 3 {
+		t.Errorf("expected 3 samples, got %v", resp.Stats[0].RecentMs)
+	}
+
+	if _, err := h.GetDurationStats(context.Background(), &pb.GetDurationStatsRequest{Window: 5000}); err == nil {
+		t.Error("expected error for oversized window")
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// 任务事件分页限制
+const (
+	defaultTaskEventsLimit = 100
+	maxTaskEventsLimit     = 1000
+)
+
+// ListTaskEvents 按序号分页获取任务事件，可按事件时间过滤
+func (h *TaskHandler) ListTaskEvents(ctx context.Context, req *pb.ListTaskEventsRequest) (*pb.ListTaskEventsResponse, error) {
+	if req.TaskId == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id is required").ToGRPCStatus().Err()
+	}
+	verr := errorcode.NewValidationError()
+	if req.AfterSeq < 0 {
+		verr.Add("after_seq", "gte", "must be greater than or equal to 0")
+	}
+	if req.TimestampAfter < 0 {
+		verr.Add("timestamp_after", "gte", "must be greater than or equal to 0")
+	}
+	if req.TimestampBefore < 0 {
+		verr.Add("timestamp_before", "gte", "must be greater than or equal to 0")
+	}
+	if req.TimestampAfter > 0 && req.TimestampBefore > 0 && req.TimestampAfter >= req.TimestampBefore {
+		verr.Add("timestamp_before", "gtfield", "must be after timestamp_after")
+	}
+	if verr.HasErrors() {
+		return nil, verr.ToGRPCStatus().Err()
+	}
+	if _, err := h.getAccessibleTask(ctx, req.TaskId); err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultTaskEventsLimit
+	}
+	if limit > maxTaskEventsLimit {
+		limit = maxTaskEventsLimit
+	}
+
+	// 多取一条用于判断是否还有下一页
+	events, err := h.repo.ListTaskEvents(req.TaskId, repository.EventFilter{
+		AfterSeq: req.AfterSeq,
+		After:    unixTime(req.TimestampAfter),
+		Before:   unixTime(req.TimestampBefore),
+		Limit:    limit + 1,
+	})
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	resp := &pb.ListTaskEventsResponse{LastSeq: req.AfterSeq}
+	if len(events) > limit {
+		events = events[:limit]
+		resp.HasMore = true
+	}
+	for _, e := range events {
+		resp.Events = append(resp.Events, toPBTaskEvent(e))
+		resp.LastSeq = e.Seq
+	}
+	return resp, nil
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_ListTaskEvents(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "long-lived"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	now := time.Now()
+	for i := 4; i > 0; i-- {
+		if err := repo.AddEvent(&model.TaskEvent{TaskID: created.Id, Message: "retry", Timestamp: now.Add(-time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatalf("AddEvent: %v", err)
+		}
+	}
+
+	// 逐页读取，last_seq 作为下一页的 after_seq
+	var seqs []int64
+	req := &pb.ListTaskEventsRequest{TaskId: created.Id, Limit: 2}
+	for pages := 0; ; pages++ {
+		resp, err := h.ListTaskEvents(ctx, req)
+		if err != nil {
+			t.Fatalf("ListTaskEvents: %v", err)
+		}
+		for _, e := range resp.Events {
+			seqs = append(seqs, e.Seq)
+		}
+		if !resp.HasMore {
+			if pages != 2 {
+				t.Errorf("expected 3 pages, got %d", pages+1)
+			}
+			break
+		}
+		req.AfterSeq = resp.LastSeq
+	}
+	if len(seqs) != 5 {
+		t.Fatalf("expected 5 events, got %v", seqs)
+	}
+
+	ranged, err := h.ListTaskEvents(ctx, &pb.ListTaskEventsRequest{TaskId: created.Id,
+		TimestampAfter: now.Add(-150 * time.Minute).Unix(), TimestampBefore: now.Add(-30 * time.Minute).Unix()})
+	if err != nil {
+		t.Fatalf("ListTaskEvents with time range: %v", err)
+	}
+	if len(ranged.Events) != 2 || ranged.HasMore {
+		t.Errorf("expected the 2 events in range, got %+v", ranged)
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.ListTaskEventsRequest
+		want codes.Code
+	}{
+		{"missing task id", &pb.ListTaskEventsRequest{}, codes.InvalidArgument},
+		{"inverted range", &pb.ListTaskEventsRequest{TaskId: created.Id, TimestampAfter: now.Unix(), TimestampBefore: now.Add(-time.Hour).Unix()}, codes.InvalidArgument},
+		{"missing task", &pb.ListTaskEventsRequest{TaskId: "missing"}, codes.NotFound},
+	}
+	for _, tc := range cases {
+		if _, err := h.ListTaskEvents(ctx, tc.req); status.Code(err) != tc.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tc.name, status.Code(err), tc.want, err)
+		}
+	}
+}
@@ -2,45 +2,85 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
 
 	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/idgen"
 	"taskflow/internal/logger"
 	"taskflow/internal/model"
+	"taskflow/internal/notify"
+	"taskflow/internal/outbox"
+	"taskflow/internal/redact"
 	"taskflow/internal/repository"
+	"taskflow/internal/secrets"
+	"taskflow/internal/storage"
 	pb "taskflow/proto"
 )
 
 // TaskHandler 任务处理器
 type TaskHandler struct {
-	repo         *repository.TaskRepository
+	repo         repository.TaskStore
+	listReads    repository.TaskStore // 任务列表查询，可容忍只读副本延迟
+	statsReads   repository.TaskStore // 统计查询，可容忍只读副本延迟
+	teamRepo     repository.TeamStore
+	notifier     *notify.Notifier
+	wakeup       func() // 唤醒调度器，未设置时依赖调度器轮询
+	scheduler    SchedulerControl
+	blobs        storage.BlobStore
+	artifacts    storage.BlobStore
+	secrets      *secrets.Manager
+	durations    durationCache
+
+	redactor        *redact.Redactor
+	redactionAdmins map[string]bool
+
+	accessAdmins     map[string]bool
+	publicUnassigned bool
+
+	attachments  AttachmentPolicy
 	watchers     map[string][]chan *pb.TaskChangeEvent
 	watchersMu   sync.RWMutex
 	taskUpdateCh chan *pb.TaskChangeEvent
+
+	logWatchersMu sync.Mutex
+	logWatchers   map[string][]*logWatcher
 	pb.UnimplementedTaskServiceServer
 }
 
 // NewTaskHandler 创建任务处理器
-func NewTaskHandler(repo *repository.TaskRepository) *TaskHandler {
+func NewTaskHandler(repo repository.TaskStore, teamRepo repository.TeamStore) *TaskHandler {
 	h := &TaskHandler{
 		repo:         repo,
+		listReads:    repo,
+		statsReads:   repo,
+		teamRepo:     teamRepo,
 		watchers:     make(map[string][]chan *pb.TaskChangeEvent),
 		taskUpdateCh: make(chan *pb.TaskChangeEvent, 100),
+		logWatchers:  make(map[string][]*logWatcher),
 	}
 	// 启动任务变更通知循环
 	go h.taskUpdateNotifier()
 	return h
 }
 
+// SetStaleReads 设置任务列表和统计查询可容忍的只读副本延迟，仓储未配置副本或容忍度为 0 时读主库
+func (h *TaskHandler) SetStaleReads(lists, stats time.Duration) {
+	h.listReads = repository.StaleReads(h.repo, lists)
+	h.statsReads = repository.StaleReads(h.repo, stats)
+}
+
 // CreateTask 创建任务
 func (h *TaskHandler) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.Task, error) {
 	// 参数验证
-	if req.Name == "" {
-		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "name is required").ToGRPCStatus().Err()
+	if verr := validateCreateTaskRequest(req); verr.HasErrors() {
+		return nil, verr.ToGRPCStatus().Err()
 	}
 
 	// 创建任务模型
@@ -54,31 +94,159 @@ func (h *TaskHandler) CreateTask(ctx context.Context, req *pb.CreateTaskRequest)
 		req.MaxRetries,
 		req.CreatedBy,
 	)
-	task.ID = uuid.New().String()
+	task.ID = idgen.NewID()
+	if userID := grpc_middleware.GetUserID(ctx); userID != "" {
+		task.CreatedBy = userID
+	}
+	task.TeamID = req.TeamId
+	task.RetryPolicy = fromPBRetryPolicy(req.RetryPolicy)
+	task.DependencyPolicies = fromPBDependencyPolicies(req.DependencyPolicies)
+	task.ResourceSlots = req.ResourceSlots
+	task.GroupKey = req.GroupKey
+	task.Labels = req.Labels
+	task.NodeSelector = req.NodeSelector
+	task.TargetWorker = req.TargetWorker
+	task.SLADeadline = slaDeadline(task.CreatedAt, req.SlaDeadline, req.SlaSeconds)
+	task.WorkflowDeadline = workflowDeadline(task.CreatedAt, req.WorkflowDeadline, req.WorkflowTimeoutSeconds)
+	task.CorrelationID = grpc_middleware.GetRequestID(ctx)
+	if err := h.inheritWorkflowDeadline(task); err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	// 归属团队需存在
+	if task.TeamID != "" {
+		if err := h.checkTeamExists(task.TeamID); err != nil {
+			return nil, err
+		}
+	}
 
-	// 保存到数据库
-	if err := h.repo.Create(task); err != nil {
+	// 保存到数据库，分片存储不允许依赖其他分片的任务
+	if err := h.repo.Create(task); errors.Is(err, repository.ErrCrossShard) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	} else if err != nil {
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
+	h.wakeScheduler()
 
 	return h.toPBTask(task, false), nil
 }
 
-// GetTask 获取任务
+// validateCreateTaskRequest 校验创建任务请求，返回所有字段错误
+func validateCreateTaskRequest(req *pb.CreateTaskRequest) *errorcode.ValidationError {
+	verr := errorcode.NewValidationError()
+	if req.Name == "" {
+		verr.Add("name", "required", "is required")
+	}
+	if _, ok := pb.TaskPriority_name[int32(req.Priority)]; !ok {
+		verr.Add("priority", "enum", fmt.Sprintf("unknown priority %d", req.Priority))
+	}
+	if req.MaxRetries < 0 {
+		verr.Add("max_retries", "gte", "must be greater than or equal to 0")
+	}
+	if req.ResourceSlots < 0 {
+		verr.Add("resource_slots", "gte", "must be greater than or equal to 0")
+	}
+	if policy := fromPBRetryPolicy(req.RetryPolicy); policy != nil {
+		if err := policy.Validate(); err != nil {
+			verr.Add("retry_policy", "invalid", err.Error())
+		}
+	}
+	for i, dep := range req.Dependencies {
+		if dep == "" {
+			verr.Add(fmt.Sprintf("dependencies[%d]", i), "required", "must not be empty")
+		}
+	}
+	validateDependencyPolicies(verr, req.DependencyPolicies, req.Dependencies)
+	validateSLA(verr, req.SlaDeadline, req.SlaSeconds)
+	validateWorkflowDeadline(verr, req.WorkflowDeadline, req.WorkflowTimeoutSeconds)
+	if err := model.ValidateLabels(req.Labels); err != nil {
+		verr.Add("labels", "invalid", err.Error())
+	}
+	if err := model.ValidateLabels(req.NodeSelector); err != nil {
+		verr.Add("node_selector", "invalid", err.Error())
+	}
+	if len(req.TargetWorker) > model.MaxTargetWorkerLength {
+		verr.Add("target_worker", "max", fmt.Sprintf("must be at most %d characters", model.MaxTargetWorkerLength))
+	}
+	return verr
+}
+
+// GetTask 获取任务。设置 wait_for 时为长轮询：等到任务进入其中任一状态或等待超时后返回任务
 func (h *TaskHandler) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.Task, error) {
 	if req.Id == "" {
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
 	}
 
-	task, err := h.repo.GetByID(req.Id)
-	if err != nil { logger.Errorf("Handler error: %v", err)
+	waitFor, timeout, err := taskWaitParams(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Unredacted {
+		if err := h.checkUnredactedAccess(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var task *model.Task
+	if len(waitFor) > 0 {
+		task, err = h.waitForTaskStatus(ctx, req.Id, waitFor, timeout)
+	} else {
+		task, err = h.getAccessibleTask(ctx, req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pbTask := h.toPBTask(task, req.IncludeEvents)
+	if req.Unredacted {
+		pbTask.InputParams = task.InputParams
+		pbTask.SignalInput = task.SignalInput
+	}
+	return pbTask, nil
+}
+
+// maxGetTasksIDs 批量获取任务的最大 ID 数量
+const maxGetTasksIDs = 1000
+
+// GetTasks 批量获取任务，按请求顺序返回并标记不存在或无权访问的 ID
+func (h *TaskHandler) GetTasks(ctx context.Context, req *pb.GetTasksRequest) (*pb.GetTasksResponse, error) {
+	if len(req.Ids) == 0 {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "ids is required").ToGRPCStatus().Err()
+	}
+	if len(req.Ids) > maxGetTasksIDs {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam,
+			fmt.Sprintf("at most %d ids per request, got %d", maxGetTasksIDs, len(req.Ids))).ToGRPCStatus().Err()
+	}
+
+	tasks, err := h.repo.GetByIDs(req.Ids)
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
-	if task == nil {
-		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+
+	canAccess, err := h.taskAccessFilter(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return h.toPBTask(task, req.IncludeEvents), nil
+	resp := &pb.GetTasksResponse{Results: make([]*pb.GetTasksResult, 0, len(req.Ids))}
+	for i, id := range req.Ids {
+		task := tasks[i]
+		// 无权访问的任务与不存在的任务同样处理，避免泄露
+		if task != nil && !canAccess(task) {
+			task = nil
+		}
+
+		result := &pb.GetTasksResult{Id: id, Found: task != nil}
+		if task != nil {
+			result.Task = h.toPBTask(task, false)
+		} else {
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	return resp, nil
 }
 
 // ListTasks 列出任务
@@ -102,17 +270,40 @@ func (h *TaskHandler) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (
 		TaskType:  req.TaskType,
 	}
 
-	if len(req.StatusFilter) > 0 {
-		status := model.TaskStatus(req.StatusFilter[0])
-		filter.Status = &status
+	for _, status := range req.StatusFilter {
+		filter.Statuses = append(filter.Statuses, model.TaskStatus(status))
 	}
 	if req.Priority != 0 {
 		priority := model.TaskPriority(req.Priority)
 		filter.Priority = &priority
 	}
+	for _, priority := range req.Priorities {
+		filter.Priorities = append(filter.Priorities, model.TaskPriority(priority))
+	}
+	filter.TaskTypes = req.TaskTypes
+	filter.TeamID = req.TeamId
+	filter.CorrelationID = req.CorrelationId
+	filter.SortBy = repository.TaskSortField(req.SortBy)
+	filter.SortDesc = req.SortDesc
+	filter.CreatedAfter = unixTime(req.CreatedAfter)
+	filter.CreatedBefore = unixTime(req.CreatedBefore)
+	filter.CompletedAfter = unixTime(req.CompletedAfter)
+	filter.CompletedBefore = unixTime(req.CompletedBefore)
+	filter.UpdatedSince = unixTime(req.UpdatedSince)
+	if verr := validateListTasksRequest(req); verr.HasErrors() {
+		return nil, verr.ToGRPCStatus().Err()
+	}
+	if req.MyTeams {
+		userID := grpc_middleware.GetUserID(ctx)
+		if userID == "" {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeUnauthorized, "my_teams requires an authenticated caller").ToGRPCStatus().Err()
+		}
+		filter.MemberOf = userID
+	}
+	h.applyVisibility(ctx, &filter)
 
 	// 查询
-	tasks, total, err := h.repo.ListByFilter(filter)
+	tasks, total, err := h.listReads.ListByFilter(filter)
 	if err != nil { logger.Errorf("Handler error: %v", err)
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
@@ -131,64 +322,227 @@ func (h *TaskHandler) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (
 	}, nil
 }
 
+// validateListTasksRequest 校验过滤条件、排序字段和时间范围
+func validateListTasksRequest(req *pb.ListTasksRequest) *errorcode.ValidationError {
+	verr := errorcode.NewValidationError()
+	for i, status := range req.StatusFilter {
+		if _, ok := pb.TaskStatus_name[int32(status)]; !ok || status == pb.TaskStatus_TASK_STATUS_UNSPECIFIED {
+			verr.Add(fmt.Sprintf("status_filter[%d]", i), "enum", fmt.Sprintf("unknown status %d", status))
+		}
+	}
+	for i, priority := range req.Priorities {
+		if _, ok := pb.TaskPriority_name[int32(priority)]; !ok || priority == pb.TaskPriority_TASK_PRIORITY_UNSPECIFIED {
+			verr.Add(fmt.Sprintf("priorities[%d]", i), "enum", fmt.Sprintf("unknown priority %d", priority))
+		}
+	}
+	if !repository.TaskSortField(req.SortBy).Valid() {
+		verr.Add("sort_by", "oneof", fmt.Sprintf("must be one of %v", repository.TaskSortFields))
+	}
+	for _, r := range []struct {
+		after, before       string
+		afterVal, beforeVal int64
+	}{
+		{"created_after", "created_before", req.CreatedAfter, req.CreatedBefore},
+		{"completed_after", "completed_before", req.CompletedAfter, req.CompletedBefore},
+	} {
+		if r.afterVal < 0 {
+			verr.Add(r.after, "gte", "must be greater than or equal to 0")
+		}
+		if r.beforeVal < 0 {
+			verr.Add(r.before, "gte", "must be greater than or equal to 0")
+		}
+		if r.afterVal > 0 && r.beforeVal > 0 && r.afterVal >= r.beforeVal {
+			verr.Add(r.before, "gtfield", "must be after "+r.after)
+		}
+	}
+	if req.UpdatedSince < 0 {
+		verr.Add("updated_since", "gte", "must be greater than or equal to 0")
+	}
+	return verr
+}
+
+// unixTime Unix 秒转换为时间，0 表示未设置
+func unixTime(sec int64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
 // UpdateTask 更新任务
 func (h *TaskHandler) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest) (*pb.Task, error) {
 	if req.Id == "" {
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
 	}
+	if len(req.GetUpdateMask().GetPaths()) > 0 {
+		return h.updateTaskFields(ctx, req)
+	}
 
 	// 获取现有任务
 	task, err := h.repo.GetByID(req.Id)
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+	}
 	if err != nil { logger.Errorf("Handler error: %v", err)
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
-	if task == nil {
-		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+	if err := checkExpectedVersion(task, req.ExpectedVersion); err != nil {
+		return nil, err
+	}
+
+	// 执行结果只能随执行器上报结果的状态转换写入，不允许直接改写执行中或已结束任务的结果
+	result := model.ResultUpdate{
+		Status:       model.TaskStatus(req.Status),
+		OutputResult: req.OutputResult,
+		ErrorMessage: req.ErrorMessage,
+		RetryCount:   req.RetryCount,
+	}
+	if errs := result.CheckStatus(task.Status); len(errs) > 0 {
+		return nil, notEditableError(task.Status, errs).ToGRPCStatus().Err()
 	}
 
 	// 更新字段
+	oldStatus := task.Status
 	if req.Status != 0 {
-		oldStatus := task.Status
 		newStatus := model.TaskStatus(req.Status)
 
 		// 状态转换验证
 		if !isValidStatusTransition(oldStatus, newStatus) {
-			return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidState,
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskInvalidTransition,
 				fmt.Sprintf("invalid status transition from %s to %s", oldStatus, newStatus)).ToGRPCStatus().Err()
 		}
 
-		// 原子更新状态
-		err := h.repo.UpdateStatusWithEvent(req.Id, oldStatus, newStatus, "system", "status updated")
+		// 运行中的任务先通知执行器取消，等其清理完再记录 CANCELLED
+		if newStatus == model.TaskStatusCancelled && oldStatus == model.TaskStatusRunning && h.scheduler != nil {
+			h.scheduler.CancelExecution(req.Id)
+		}
+
+		// 原子更新状态，事件记录本次请求的 ID
+		err := h.repo.UpdateStatusWithCorrelatedEvent(req.Id, oldStatus, newStatus, "system", "status updated", grpc_middleware.GetRequestID(ctx))
+		if errors.Is(err, repository.ErrStatusMismatch) {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeConflict, "task status changed concurrently, retry with the latest status").ToGRPCStatus().Err()
+		}
 		if err != nil { logger.Errorf("Handler error: %v", err)
 			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 		}
 		task.Status = newStatus
+		task.Version++
+	}
+
+	// 状态转换已成功，此时任务已结束，执行器迟到的结果会因状态不匹配被丢弃
+	task.UpdatedAt = model.Now()
+	if req.OutputResult != nil || req.ErrorMessage != "" {
+		if req.OutputResult != nil {
+			task.OutputResult = req.OutputResult
+		}
+		if req.ErrorMessage != "" {
+			task.ErrorMessage = req.ErrorMessage
+		}
+
+		// 保存
+		if err := h.repo.Update(task); err != nil {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+		task.Version++
+	}
+
+	if task.Status != oldStatus {
+		h.broadcastCorrelatedTaskChange(task.ID, task, oldStatus, task.Status, "status_changed", grpc_middleware.GetRequestID(ctx))
+		h.notifier.NotifyTaskChange(task, oldStatus, task.Status)
+
+		// 下游任务可能因此满足依赖，或因上游未成功而被跳过
+		if task.Status.IsTerminal() {
+			h.wakeScheduler()
+		}
 	}
 
-	if req.OutputResult != nil {
-		task.OutputResult = req.OutputResult
+	return h.toPBTask(task, false), nil
+}
+
+// updateTaskFields 按 update_mask 更新任务定义。掩码和取值的错误一并以字段错误返回，
+// 任务当前状态不允许修改其中的字段时返回 ErrCodeTaskFieldNotEditable，并列出全部不可修改的字段
+func (h *TaskHandler) updateTaskFields(ctx context.Context, req *pb.UpdateTaskRequest) (*pb.Task, error) {
+	if req.Status != 0 || req.OutputResult != nil || req.ErrorMessage != "" || req.RetryCount != 0 {
+		return nil, errorcode.NewValidationError(errorcode.NewFieldViolation("update_mask", "exclusive",
+			"cannot be combined with status, output_result, error_message or retry_count")).ToGRPCStatus().Err()
+	}
+
+	update := model.TaskUpdate{
+		Mask:        req.UpdateMask.Paths,
+		Name:        req.Name,
+		Description: req.Description,
+		Priority:    model.TaskPriority(req.Priority),
+		Params:      req.InputParams,
+		MaxRetries:  req.MaxRetries,
+		Labels:      req.Labels,
+	}
+	if errs := update.Validate(); len(errs) > 0 {
+		verr := errorcode.NewValidationError()
+		for _, e := range errs {
+			verr.Add(e.Field, e.Rule, e.Message)
+		}
+		return nil, verr.ToGRPCStatus().Err()
+	}
+
+	task, err := h.repo.GetByID(req.Id)
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
-	if req.ErrorMessage != "" {
-		task.ErrorMessage = req.ErrorMessage
+	if err := checkExpectedVersion(task, req.ExpectedVersion); err != nil {
+		return nil, err
 	}
-	if req.RetryCount != 0 {
-		task.RetryCount = req.RetryCount
+	if errs := update.CheckStatus(task.Status); len(errs) > 0 {
+		return nil, notEditableError(task.Status, errs).ToGRPCStatus().Err()
 	}
-	task.UpdatedAt = time.Now()
 
-	// 保存
-	if err := h.repo.Update(task); err != nil {
+	update.Apply(task)
+	task.UpdatedAt = model.Now()
+	err = h.repo.UpdateDefinition(task, task.Status)
+	if errors.Is(err, repository.ErrStatusMismatch) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeConflict, "task status changed concurrently, retry with the latest status").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
 		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
 	}
+	task.Version++
 
+	h.broadcastCorrelatedTaskChange(task.ID, task, task.Status, task.Status, "updated", grpc_middleware.GetRequestID(ctx))
+	if task.Status == model.TaskStatusPending {
+		// 优先级可能变化，重新评估待调度任务
+		h.wakeScheduler()
+	}
 	return h.toPBTask(task, false), nil
 }
 
+// checkExpectedVersion 请求带有 expected_version 时任务的当前版本号须与之相同，否则返回 ErrCodePreconditionFailed。
+// 版本号在写入前的读取中比较，状态变更另由条件更新防止并发覆盖
+func checkExpectedVersion(task *model.Task, expected int64) error {
+	if expected != 0 && task.Version != expected {
+		return errorcode.NewTaskError(errorcode.ErrCodePreconditionFailed,
+			fmt.Sprintf("task version is %d, expected %d", task.Version, expected)).ToGRPCStatus().Err()
+	}
+	return nil
+}
+
+// notEditableError 任务当前状态不允许修改的字段，逐个放入错误的字段列表
+func notEditableError(status model.TaskStatus, errs []model.FieldError) *errorcode.TaskError {
+	taskErr := errorcode.NewTaskError(errorcode.ErrCodeTaskFieldNotEditable, fmt.Sprintf("task is %s", status))
+	for _, e := range errs {
+		taskErr.WithViolations(errorcode.NewFieldViolation(e.Field, e.Rule, e.Message))
+	}
+	return taskErr
+}
+
 // 状态转换验证
 func isValidStatusTransition(from, to model.TaskStatus) bool {
-	// PENDING 可以转到 RUNNING, CANCELLED
+	// PENDING 可以转到 RUNNING, CANCELLED, SKIPPED
 	if from == model.TaskStatusPending {
-		return to == model.TaskStatusRunning || to == model.TaskStatusCancelled
+		return to == model.TaskStatusRunning || to == model.TaskStatusCancelled || to == model.TaskStatusSkipped
 	}
 	// RUNNING 可以转到 SUCCEEDED, FAILED, TIMEOUT, CANCELLED
 	if from == model.TaskStatusRunning {
@@ -197,6 +551,10 @@ func isValidStatusTransition(from, to model.TaskStatus) bool {
 			to == model.TaskStatusTimeout ||
 			to == model.TaskStatusCancelled
 	}
+	// WAITING_INPUT 可以转到 CANCELLED，收到输入通过 SignalTask 重新进入 PENDING
+	if from == model.TaskStatusWaitingInput {
+		return to == model.TaskStatusCancelled
+	}
 	// 终态不能转换
 	return false
 }
@@ -204,21 +562,46 @@ func isValidStatusTransition(from, to model.TaskStatus) bool {
 // toPBTask 转换为 Protobuf 任务
 func (h *TaskHandler) toPBTask(task *model.Task, includeEvents bool) *pb.Task {
 	pbTask := &pb.Task{
-		Id:           task.ID,
-		Name:         task.Name,
-		Description:  task.Description,
-		Status:       pb.TaskStatus(task.Status),
-		Priority:     pb.TaskPriority(task.Priority),
-		TaskType:     task.TaskType,
-		InputParams:  task.InputParams,
-		OutputResult: task.OutputResult,
-		Dependencies: task.Dependencies,
-		RetryCount:   task.RetryCount,
-		MaxRetries:   task.MaxRetries,
-		ErrorMessage: task.ErrorMessage,
-		CreatedAt:    task.CreatedAt.Unix(),
-		UpdatedAt:    task.UpdatedAt.Unix(),
-		CreatedBy:    task.CreatedBy,
+		Id:              task.ID,
+		Name:            task.Name,
+		Description:     task.Description,
+		Status:          pb.TaskStatus(task.Status),
+		Priority:        pb.TaskPriority(task.Priority),
+		TaskType:        task.TaskType,
+		InputParams:     h.redactor.Params(task.InputParams),
+		OutputResult:    task.OutputResult,
+		Dependencies:    task.Dependencies,
+		RetryCount:      task.RetryCount,
+		MaxRetries:      task.MaxRetries,
+		ErrorMessage:    task.ErrorMessage,
+		ErrorClass:      string(task.ErrorClass),
+		BlockedReason:   task.BlockedReason,
+		ResourceSlots:   task.ResourceSlots,
+		CreatedAt:       task.CreatedAt.Unix(),
+		UpdatedAt:       task.UpdatedAt.Unix(),
+		CreatedBy:       task.CreatedBy,
+		TeamId:          task.TeamID,
+		GroupKey:        task.GroupKey,
+		Labels:          task.Labels,
+		CorrelationId:   task.CorrelationID,
+		ExecutedBy:      task.ExecutedBy,
+		OutputRef:       task.OutputRef,
+		RetryPolicy:     toPBRetryPolicy(task.RetryPolicy),
+		RerunOf:         task.RerunOf,
+		ParentId:        task.ParentID,
+		Progress:        task.Progress,
+		ProgressMessage: task.ProgressMessage,
+		Version:         task.Version,
+		InputRequest:    task.InputRequest,
+		SignalInput:     h.redactor.Params(task.SignalInput),
+		NodeSelector:    task.NodeSelector,
+		TargetWorker:    task.TargetWorker,
+		BasePriority:    pb.TaskPriority(task.BasePriority),
+		SubStatus:       task.SubStatus,
+	}
+	pbTask.DependencyPolicies = toPBDependencyPolicies(task.DependencyPolicies)
+	for i := range task.Phases {
+		pbTask.Phases = append(pbTask.Phases, toPBTaskPhase(&task.Phases[i]))
 	}
 
 	if task.StartedAt != nil {
@@ -227,26 +610,81 @@ func (h *TaskHandler) toPBTask(task *model.Task, includeEvents bool) *pb.Task {
 	if task.CompletedAt != nil {
 		pbTask.CompletedAt = task.CompletedAt.Unix()
 	}
+	if task.NextRunAt != nil {
+		pbTask.NextRunAt = task.NextRunAt.Unix()
+	}
+	if task.SLADeadline != nil {
+		pbTask.SlaDeadline = task.SLADeadline.Unix()
+	}
+	if task.WorkflowDeadline != nil {
+		pbTask.WorkflowDeadline = task.WorkflowDeadline.Unix()
+	}
+	if task.SLABreachedAt != nil {
+		pbTask.SlaBreachedAt = task.SLABreachedAt.Unix()
+	}
+	if task.QueuedAt != nil {
+		pbTask.QueuedAt = task.QueuedAt.Unix()
+	}
+	if task.QueueAlertedAt != nil {
+		pbTask.QueueAlertedAt = task.QueueAlertedAt.Unix()
+	}
+	if task.HeartbeatAt != nil {
+		pbTask.HeartbeatAt = task.HeartbeatAt.Unix()
+	}
+	h.setETA(pbTask, task)
 
 	if includeEvents {
 		for _, e := range task.Events {
-			pbTask.Events = append(pbTask.Events, &pb.TaskEvent{
-				Id:         e.ID,
-				FromStatus: pb.TaskStatus(e.FromStatus),
-				ToStatus:   pb.TaskStatus(e.ToStatus),
-				Message:    e.Message,
-				Timestamp:  e.Timestamp.Unix(),
-				Operator:   e.Operator,
-			})
+			pbTask.Events = append(pbTask.Events, toPBTaskEvent(e))
+		}
+		for i := range task.Comments {
+			pbTask.Comments = append(pbTask.Comments, toPBTaskComment(&task.Comments[i]))
+		}
+		for i := range task.Runs {
+			pbTask.Runs = append(pbTask.Runs, toPBTaskRun(&task.Runs[i]))
 		}
 	}
 
 	return pbTask
 }
 
+// toPBTaskEvent 转换为 Protobuf 任务事件
+func toPBTaskEvent(e model.TaskEvent) *pb.TaskEvent {
+	return &pb.TaskEvent{
+		Id:            e.ID,
+		FromStatus:    pb.TaskStatus(e.FromStatus),
+		ToStatus:      pb.TaskStatus(e.ToStatus),
+		Message:       e.Message,
+		Timestamp:     e.Timestamp.Unix(),
+		Operator:      e.Operator,
+		InstanceId:    e.InstanceID,
+		CorrelationId: e.CorrelationID,
+		Seq:           e.Seq,
+		TaskId:        e.TaskID,
+		Metadata:      e.Metadata,
+	}
+}
+
+// SetNotifier 设置任务状态变更通知器
+func (h *TaskHandler) SetNotifier(n *notify.Notifier) {
+	h.notifier = n
+}
+
+// SetSchedulerWakeup 设置调度器唤醒函数，任务创建或完成时立即触发调度
+func (h *TaskHandler) SetSchedulerWakeup(wake func()) {
+	h.wakeup = wake
+}
+
+// wakeScheduler 唤醒调度器
+func (h *TaskHandler) wakeScheduler() {
+	if h.wakeup != nil {
+		h.wakeup()
+	}
+}
+
 // RegisterTaskHandlers 注册任务服务句柄
-func RegisterTaskHandlers(repo *repository.TaskRepository) *TaskHandler {
-	return NewTaskHandler(repo)
+func RegisterTaskHandlers(repo repository.TaskStore, teamRepo repository.TeamStore) *TaskHandler {
+	return NewTaskHandler(repo, teamRepo)
 }
 
 // ========== 流式 RPC 实现 ==========
@@ -282,95 +720,225 @@ func (h *TaskHandler) notifyWatchers(event *pb.TaskChangeEvent) {
 	}
 }
 
-// broadcastTaskChange 广播任务变更
+// subscribe 注册变更通知，key 为任务 ID，空字符串接收所有任务的通知
+func (h *TaskHandler) subscribe(key string) chan *pb.TaskChangeEvent {
+	ch := make(chan *pb.TaskChangeEvent, 10)
+	h.watchersMu.Lock()
+	h.watchers[key] = append(h.watchers[key], ch)
+	h.watchersMu.Unlock()
+	return ch
+}
+
+// unsubscribe 取消变更通知并关闭通道
+func (h *TaskHandler) unsubscribe(key string, ch chan *pb.TaskChangeEvent) {
+	h.watchersMu.Lock()
+	defer h.watchersMu.Unlock()
+
+	chs := h.watchers[key]
+	for i, c := range chs {
+		if c == ch {
+			h.watchers[key] = append(chs[:i], chs[i+1:]...)
+			break
+		}
+	}
+	close(ch)
+}
+
+// broadcastTaskChange 广播任务变更，关联 ID 沿用任务的 correlation_id
 func (h *TaskHandler) broadcastTaskChange(taskId string, task *model.Task, fromStatus, toStatus model.TaskStatus, changeType string) {
+	h.broadcastCorrelatedTaskChange(taskId, task, fromStatus, toStatus, changeType, task.CorrelationID)
+}
+
+// broadcastCorrelatedTaskChange 广播由指定请求引起的任务变更
+func (h *TaskHandler) broadcastCorrelatedTaskChange(taskId string, task *model.Task, fromStatus, toStatus model.TaskStatus, changeType, correlationID string) {
+	if correlationID == "" {
+		correlationID = task.CorrelationID
+	}
 	event := &pb.TaskChangeEvent{
-		TaskId:     taskId,
-		Task:       h.toPBTask(task, false),
-		FromStatus: pb.TaskStatus(fromStatus),
-		ToStatus:   pb.TaskStatus(toStatus),
-		ChangedAt:  time.Now().Unix(),
-		ChangeType: changeType,
+		TaskId:        taskId,
+		Task:          h.toPBTask(task, false),
+		FromStatus:    pb.TaskStatus(fromStatus),
+		ToStatus:      pb.TaskStatus(toStatus),
+		ChangedAt:     time.Now().Unix(),
+		ChangeType:    changeType,
+		CorrelationId: correlationID,
 	}
 	h.taskUpdateCh <- event
+
+	if toStatus.IsTerminal() {
+		h.endLogWatchers(taskId)
+	}
+}
+
+// EncodeTaskChangeEvent 以 Protobuf 编码发件箱事件，供 Kafka 等外部接收端使用
+func (h *TaskHandler) EncodeTaskChangeEvent(event *model.OutboxEvent, task *model.Task) ([]byte, error) {
+	pbEvent := &pb.TaskChangeEvent{
+		TaskId:        event.TaskID,
+		FromStatus:    pb.TaskStatus(event.FromStatus),
+		ToStatus:      pb.TaskStatus(event.ToStatus),
+		ChangedAt:     event.CreatedAt.Unix(),
+		ChangeType:    outbox.ChangeType(event),
+		CorrelationId: event.CorrelationID,
+	}
+	if task != nil {
+		pbEvent.Task = h.toPBTask(task, false)
+	}
+	return proto.Marshal(pbEvent)
+}
+
+// PublishSLABreach 把 SLA 监控记录的违约推送给订阅者，状态不变，change_type 为 sla_breached
+func (h *TaskHandler) PublishSLABreach(task *model.Task) {
+	h.broadcastTaskChange(task.ID, task, task.Status, task.Status, "sla_breached")
 }
 
-// WatchTask 服务端流式 - 监听任务状态变化
+// PublishTaskChange 发布外部（如调度器）产生的任务状态变更给订阅者
+func (h *TaskHandler) PublishTaskChange(task *model.Task, fromStatus, toStatus model.TaskStatus) {
+	h.broadcastTaskChange(task.ID, task, fromStatus, toStatus, "status_changed")
+}
+
+// watchPollInterval WatchTask 在没有本实例变更通知时轮询发件箱的间隔，用于发现其他实例写入的事件
+const watchPollInterval = time.Second
+
+// watchBatchSize WatchTask 每次从发件箱读取的事件数
+const watchBatchSize = 100
+
+// WatchTask 服务端流式 - 监听任务状态变化。
+// 状态变更和 SLA 违约事件从发件箱按序号读取，携带 resume_token；断线后传回最后收到的令牌即可补发期间的事件。
+// 需要补发的事件已被清理时返回 OUT_OF_RANGE，客户端应以 include_initial 重新订阅
 func (h *TaskHandler) WatchTask(req *pb.WatchTaskRequest, stream pb.TaskService_WatchTaskServer) error {
-	ch := make(chan *pb.TaskChangeEvent, 10)
 	taskIDs := req.TaskIds
-
-	h.watchersMu.Lock()
 	watchKey := ""
 	if len(taskIDs) == 1 {
 		watchKey = taskIDs[0]
 	}
-	h.watchers[watchKey] = append(h.watchers[watchKey], ch)
-	h.watchersMu.Unlock()
+	ch := h.subscribe(watchKey)
+	defer h.unsubscribe(watchKey, ch)
+
+	// 只推送调用者可见的任务
+	canAccess, err := h.taskAccessFilter(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	// 先确定续传位置再发送初始快照，之后写入发件箱的事件都不会遗漏
+	first, last, err := h.repo.OutboxSeqRange()
+	if err != nil {
+		return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	// 令牌是客户端尚未收到的第一个事件序号，总是大于 0，因此发件箱为空时下发的令牌也能续传
+	lastSeq := last
+	if req.ResumeToken != 0 {
+		switch {
+		case req.ResumeToken < 0 || req.ResumeToken > last+1:
+			return errorcode.NewTaskError(errorcode.ErrCodeInvalidParam,
+				fmt.Sprintf("resume_token %d is not a known event sequence", req.ResumeToken)).ToGRPCStatus().Err()
+		case req.ResumeToken < first:
+			return errorcode.NewTaskError(errorcode.ErrCodeResumeTokenExpired,
+				fmt.Sprintf("events from resume_token %d have been purged, oldest retained is %d", req.ResumeToken, first)).ToGRPCStatus().Err()
+		}
+		lastSeq = req.ResumeToken - 1
+	}
 
 	if req.IncludeInitial {
 		var tasks []*model.Task
 		if len(taskIDs) > 0 {
 			for _, id := range taskIDs {
 				task, err := h.repo.GetByID(id)
-				if err == nil && task != nil {
+				if err == nil && canAccess(task) {
 					tasks = append(tasks, task)
 				}
 			}
 		} else {
-			tasks, _, _ = h.repo.ListByFilter(repository.TaskFilter{PageSize: 50, PageIndex: 0})
+			filter := repository.TaskFilter{PageSize: 50, PageIndex: 0}
+			h.applyVisibility(stream.Context(), &filter)
+			tasks, _, _ = h.repo.ListByFilter(filter)
 		}
 
 		for _, task := range tasks {
 			event := &pb.TaskChangeEvent{
-				TaskId:     task.ID,
-				Task:       h.toPBTask(task, false),
-				FromStatus: pb.TaskStatus(task.Status),
-				ToStatus:   pb.TaskStatus(task.Status),
-				ChangedAt:  task.UpdatedAt.Unix(),
-				ChangeType: "initial",
+				TaskId:      task.ID,
+				Task:        h.toPBTask(task, false),
+				FromStatus:  pb.TaskStatus(task.Status),
+				ToStatus:    pb.TaskStatus(task.Status),
+				ChangedAt:   task.UpdatedAt.Unix(),
+				ChangeType:  "initial",
+				ResumeToken: lastSeq + 1,
 			}
 			stream.Send(event)
 		}
 	}
 
+	wanted := func(event *pb.TaskChangeEvent) bool {
+		if len(taskIDs) > 0 && !slices.Contains(taskIDs, event.TaskId) {
+			return false
+		}
+		// 任务已删除时无法判断可见性，不推送
+		if event.Task == nil || !canAccess(&model.Task{CreatedBy: event.Task.CreatedBy, TeamID: event.Task.TeamId}) {
+			return false
+		}
+		return len(req.StatusFilter) == 0 || slices.Contains(req.StatusFilter, event.ToStatus)
+	}
+
 	ctx := stream.Context()
-	defer func() {
-		h.watchersMu.Lock()
-		if chs, ok := h.watchers[watchKey]; ok {
-			for i, c := range chs {
-				if c == ch {
-					h.watchers[watchKey] = append(chs[:i], chs[i+1:]...)
-					break
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// 补发并推送发件箱中 lastSeq 之后的事件
+		for {
+			events, err := h.repo.ListOutboxEventsAfter(lastSeq, watchBatchSize)
+			if err != nil {
+				return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+			}
+			for _, e := range events {
+				lastSeq = e.Seq
+				event := h.outboxChangeEvent(e)
+				if !wanted(event) {
+					continue
 				}
+				if err := stream.Send(event); err != nil {
+					return err
+				}
+			}
+			if len(events) < watchBatchSize {
+				break
 			}
 		}
-		h.watchersMu.Unlock()
-		close(ch)
-	}()
 
-	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-ticker.C:
 		case event := <-ch:
-			if len(req.StatusFilter) > 0 {
-				filtered := true
-				for _, s := range req.StatusFilter {
-					if event.ToStatus == s {
-						filtered = false
-						break
-					}
-				}
-				if filtered {
-					continue
-				}
+			// 状态变更和 SLA 违约已写入发件箱，通知只用于唤醒；创建事件不经过发件箱，直接推送
+			if event.ChangeType != "created" || !wanted(event) {
+				continue
+			}
+			event.ResumeToken = lastSeq + 1
+			if err := stream.Send(event); err != nil {
+				return err
 			}
-			stream.Send(event)
 		}
 	}
 }
 
+// outboxChangeEvent 把发件箱事件转换为 WatchTask 推送的变更事件，附带任务的当前快照
+func (h *TaskHandler) outboxChangeEvent(e *model.OutboxEvent) *pb.TaskChangeEvent {
+	event := &pb.TaskChangeEvent{
+		TaskId:        e.TaskID,
+		FromStatus:    pb.TaskStatus(e.FromStatus),
+		ToStatus:      pb.TaskStatus(e.ToStatus),
+		ChangedAt:     e.CreatedAt.Unix(),
+		ChangeType:    outbox.ChangeType(e),
+		CorrelationId: e.CorrelationID,
+		ResumeToken:   e.Seq + 1,
+	}
+	if task, err := h.repo.GetByID(e.TaskID); err == nil {
+		event.Task = h.toPBTask(task, false)
+	}
+	return event
+}
+
 // BatchCreateTasks 客户端流式 - 批量创建任务
 func (h *TaskHandler) BatchCreateTasks(stream pb.TaskService_BatchCreateTasksServer) error {
 	var tasks []*pb.Task
@@ -384,9 +952,9 @@ func (h *TaskHandler) BatchCreateTasks(stream pb.TaskService_BatchCreateTasksSer
 			break
 		}
 
-		if req.Name == "" {
+		if verr := validateCreateTaskRequest(req); verr.HasErrors() {
 			failedCount++
-			errors = append(errors, "name is required")
+			errors = append(errors, verr.Error())
 			tasks = append(tasks, nil)
 			continue
 		}
@@ -401,8 +969,27 @@ func (h *TaskHandler) BatchCreateTasks(stream pb.TaskService_BatchCreateTasksSer
 			req.MaxRetries,
 			req.CreatedBy,
 		)
-		task.ID = uuid.New().String()
+		task.ID = idgen.NewID()
+		if userID := grpc_middleware.GetUserID(stream.Context()); userID != "" {
+			task.CreatedBy = userID
+		}
+		task.TeamID = req.TeamId
+		task.RetryPolicy = fromPBRetryPolicy(req.RetryPolicy)
+		task.DependencyPolicies = fromPBDependencyPolicies(req.DependencyPolicies)
+		task.ResourceSlots = req.ResourceSlots
+		task.GroupKey = req.GroupKey
+		task.NodeSelector = req.NodeSelector
+		task.TargetWorker = req.TargetWorker
+		task.SLADeadline = slaDeadline(task.CreatedAt, req.SlaDeadline, req.SlaSeconds)
+		task.WorkflowDeadline = workflowDeadline(task.CreatedAt, req.WorkflowDeadline, req.WorkflowTimeoutSeconds)
+		task.CorrelationID = grpc_middleware.GetRequestID(stream.Context())
 
+		if err := h.inheritWorkflowDeadline(task); err != nil {
+			failedCount++
+			errors = append(errors, err.Error())
+			tasks = append(tasks, nil)
+			continue
+		}
 		if err := h.repo.Create(task); err != nil {
 			failedCount++
 			errors = append(errors, err.Error())
@@ -417,6 +1004,10 @@ func (h *TaskHandler) BatchCreateTasks(stream pb.TaskService_BatchCreateTasksSer
 		successCount++
 	}
 
+	if successCount > 0 {
+		h.wakeScheduler()
+	}
+
 	return stream.SendAndClose(&pb.BatchCreateTasksResponse{
 		Tasks:        tasks,
 		SuccessCount: int32(successCount),
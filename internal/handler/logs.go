@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// 任务日志分页限制
+const (
+	defaultTaskLogsLimit = 1000
+	maxTaskLogsLimit     = 10000
+)
+
+// logWatcherBuffer 日志订阅缓冲，溢出的行在发送时从存储回补
+const logWatcherBuffer = 256
+
+// logWatcher 单个日志跟踪订阅
+type logWatcher struct {
+	ch   chan model.TaskLogLine
+	done chan struct{} // 任务进入终态时关闭
+}
+
+// GetTaskLogs 分页获取任务执行日志
+func (h *TaskHandler) GetTaskLogs(ctx context.Context, req *pb.GetTaskLogsRequest) (*pb.GetTaskLogsResponse, error) {
+	if req.TaskId == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id is required").ToGRPCStatus().Err()
+	}
+	if _, err := h.getAccessibleTask(ctx, req.TaskId); err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultTaskLogsLimit
+	}
+	if limit > maxTaskLogsLimit {
+		limit = maxTaskLogsLimit
+	}
+
+	// 多取一行用于判断是否还有下一页
+	lines, err := h.repo.GetLogs(req.TaskId, req.AfterSeq, limit+1)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	resp := &pb.GetTaskLogsResponse{LastSeq: req.AfterSeq}
+	if len(lines) > limit {
+		lines = lines[:limit]
+		resp.HasMore = true
+	}
+	for _, l := range lines {
+		resp.Lines = append(resp.Lines, toPBTaskLogLine(l))
+		resp.LastSeq = l.Seq
+	}
+	return resp, nil
+}
+
+// TailTaskLogs 服务端流式 - 回放已有日志并推送新日志，任务结束后关闭流
+func (h *TaskHandler) TailTaskLogs(req *pb.TailTaskLogsRequest, stream pb.TaskService_TailTaskLogsServer) error {
+	return h.TailLogs(stream.Context(), req.TaskId, req.AfterSeq, stream.Send)
+}
+
+// TailLogs 跟踪任务日志，供 gRPC 流和 HTTP SSE 共用
+func (h *TaskHandler) TailLogs(ctx context.Context, taskID string, afterSeq int64, send func(*pb.TaskLogLine) error) error {
+	if taskID == "" {
+		return errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id is required").ToGRPCStatus().Err()
+	}
+	if _, err := h.getAccessibleTask(ctx, taskID); err != nil {
+		return err
+	}
+
+	// 先订阅再回放，避免回放与订阅之间的日志丢失
+	w := h.watchLogs(taskID)
+	defer h.unwatchLogs(taskID, w)
+
+	last := afterSeq
+	flush := func() error {
+		lines, err := h.repo.GetLogs(taskID, last, 0)
+		if err != nil {
+			return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+		for _, l := range lines {
+			if err := send(toPBTaskLogLine(l)); err != nil {
+				return err
+			}
+			last = l.Seq
+		}
+		return nil
+	}
+
+	task, err := repository.FindTask(h.repo, taskID)
+	if err != nil {
+		return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if task == nil || task.IsTerminal() {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.done:
+			// 任务已结束，补齐剩余日志
+			return flush()
+		case l := <-w.ch:
+			switch {
+			case l.Seq <= last:
+				continue
+			case l.Seq > last+1:
+				// 缓冲溢出导致缺行，从存储回补
+				if err := flush(); err != nil {
+					return err
+				}
+			default:
+				if err := send(toPBTaskLogLine(l)); err != nil {
+					return err
+				}
+				last = l.Seq
+			}
+		}
+	}
+}
+
+// PublishTaskLog 发布调度器产生的日志行给跟踪者
+func (h *TaskHandler) PublishTaskLog(line model.TaskLogLine) {
+	h.logWatchersMu.Lock()
+	defer h.logWatchersMu.Unlock()
+
+	for _, w := range h.logWatchers[line.TaskID] {
+		select {
+		case w.ch <- line:
+		default:
+		}
+	}
+}
+
+// watchLogs 注册日志跟踪
+func (h *TaskHandler) watchLogs(taskID string) *logWatcher {
+	w := &logWatcher{
+		ch:   make(chan model.TaskLogLine, logWatcherBuffer),
+		done: make(chan struct{}),
+	}
+
+	h.logWatchersMu.Lock()
+	defer h.logWatchersMu.Unlock()
+	h.logWatchers[taskID] = append(h.logWatchers[taskID], w)
+	return w
+}
+
+// unwatchLogs 取消日志跟踪
+func (h *TaskHandler) unwatchLogs(taskID string, w *logWatcher) {
+	h.logWatchersMu.Lock()
+	defer h.logWatchersMu.Unlock()
+
+	watchers := h.logWatchers[taskID]
+	for i, existing := range watchers {
+		if existing == w {
+			h.logWatchers[taskID] = append(watchers[:i], watchers[i+1:]...)
+			break
+		}
+	}
+	if len(h.logWatchers[taskID]) == 0 {
+		delete(h.logWatchers, taskID)
+	}
+}
+
+// endLogWatchers 任务进入终态时结束该任务的所有日志跟踪
+func (h *TaskHandler) endLogWatchers(taskID string) {
+	h.logWatchersMu.Lock()
+	defer h.logWatchersMu.Unlock()
+
+	for _, w := range h.logWatchers[taskID] {
+		close(w.done)
+	}
+	delete(h.logWatchers, taskID)
+}
+
+// toPBTaskLogLine 转换为 Protobuf 日志行
+func toPBTaskLogLine(l model.TaskLogLine) *pb.TaskLogLine {
+	return &pb.TaskLogLine{
+		TaskId:      l.TaskID,
+		Seq:         l.Seq,
+		Attempt:     l.Attempt,
+		TimestampMs: l.Timestamp.UnixMilli(),
+		Line:        l.Line,
+	}
+}
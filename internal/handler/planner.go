@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"taskflow/internal/config"
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/planner"
+	pb "taskflow/proto"
+)
+
+// defaultPlanDuration 没有历史记录和预计耗时的任务按该耗时模拟
+const defaultPlanDuration = time.Minute
+
+// 模拟耗时的来源
+const (
+	durationSourceHistory  = "history"
+	durationSourceEstimate = "estimate"
+	durationSourceDefault  = "default"
+)
+
+// PlanSchedule 模拟调度一组任务定义，不创建也不执行任务。
+// 每个任务的耗时取同类型最近成功任务耗时的中位数，没有历史记录时依次使用请求中的预计耗时和默认耗时
+func (h *TaskHandler) PlanSchedule(ctx context.Context, req *pb.PlanScheduleRequest) (*pb.PlanScheduleResponse, error) {
+	if len(req.Tasks) == 0 {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "tasks is required").ToGRPCStatus().Err()
+	}
+	if req.Workers < 0 || req.DefaultDurationMs < 0 {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "workers and default_duration_ms must not be negative").ToGRPCStatus().Err()
+	}
+	workers := int(req.Workers)
+	if workers == 0 {
+		workers = config.DefaultWorkerCount
+		if h.scheduler != nil {
+			workers, _, _, _ = h.scheduler.WorkerPoolStats()
+		}
+	}
+	fallback := defaultPlanDuration
+	if req.DefaultDurationMs > 0 {
+		fallback = time.Duration(req.DefaultDurationMs) * time.Millisecond
+	}
+
+	tasks := make([]planner.Task, len(req.Tasks))
+	sources := make([]string, len(req.Tasks))
+	samples := make([]int32, len(req.Tasks))
+	for i, t := range req.Tasks {
+		if t.EstimatedDurationMs < 0 {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "estimated_duration_ms must not be negative").ToGRPCStatus().Err()
+		}
+		var history durationStats
+		if t.TaskType != "" {
+			var err error
+			if history, err = h.typeDurationStats(t.TaskType); err != nil {
+				return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+			}
+		}
+
+		tasks[i] = planner.Task{
+			ID:           t.Id,
+			Priority:     int(t.Priority),
+			Dependencies: t.Dependencies,
+		}
+		switch {
+		case len(history.samples) > 0:
+			tasks[i].Duration, sources[i], samples[i] = history.p50, durationSourceHistory, int32(len(history.samples))
+		case t.EstimatedDurationMs > 0:
+			tasks[i].Duration, sources[i] = time.Duration(t.EstimatedDurationMs)*time.Millisecond, durationSourceEstimate
+		default:
+			tasks[i].Duration, sources[i] = fallback, durationSourceDefault
+		}
+	}
+
+	plan, err := planner.Simulate(tasks, workers)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
+
+	resp := &pb.PlanScheduleResponse{
+		CriticalPath:           plan.CriticalPath,
+		CriticalPathDurationMs: plan.CriticalPathDuration.Milliseconds(),
+		TotalDurationMs:        plan.TotalDuration.Milliseconds(),
+		Workers:                int32(plan.Workers),
+	}
+	for i, slot := range plan.Slots {
+		resp.Tasks = append(resp.Tasks, &pb.PlannedTask{
+			Id:             slot.ID,
+			Name:           req.Tasks[i].Name,
+			Order:          int32(slot.Order),
+			StartOffsetMs:  slot.Start.Milliseconds(),
+			EndOffsetMs:    slot.End.Milliseconds(),
+			DurationMs:     tasks[i].Duration.Milliseconds(),
+			DurationSource: sources[i],
+			HistorySamples: samples[i],
+			Critical:       slot.Critical,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"context"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/redact"
+)
+
+// SetRedaction 设置敏感参数脱敏：匹配的 input_params、signal_input 值在任务响应和变更事件中替换为掩码，
+// adminUsers 中的用户可通过 GetTask 的 unredacted 查看原值
+func (h *TaskHandler) SetRedaction(r *redact.Redactor, adminUsers []string) {
+	h.redactor = r
+	h.redactionAdmins = make(map[string]bool, len(adminUsers))
+	for _, u := range adminUsers {
+		h.redactionAdmins[u] = true
+	}
+}
+
+// checkUnredactedAccess 检查调用者能否查看未脱敏的参数，需要认证且在管理员列表中
+func (h *TaskHandler) checkUnredactedAccess(ctx context.Context) error {
+	userID := grpc_middleware.GetUserID(ctx)
+	if userID == "" || !h.redactionAdmins[userID] {
+		return errorcode.NewTaskError(errorcode.ErrCodeForbidden, "unredacted view requires an admin").ToGRPCStatus().Err()
+	}
+	return nil
+}
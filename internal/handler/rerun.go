@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/idgen"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// RerunTask 重新运行已结束（成功、取消或超时）的任务，返回将要执行的任务。
+// RESET（默认）保存本次运行的结果后把原任务重置为 PENDING；CLONE 按原任务定义创建 rerun_of 指向原任务的新任务
+func (h *TaskHandler) RerunTask(ctx context.Context, req *pb.RerunTaskRequest) (*pb.Task, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+	mode := model.RerunMode(req.Mode)
+	if req.Mode == pb.RerunMode_RERUN_MODE_UNSPECIFIED {
+		mode = model.RerunModeReset
+	}
+	if mode != model.RerunModeReset && mode != model.RerunModeClone {
+		return nil, errorcode.NewValidationError(errorcode.NewFieldViolation("mode", "enum",
+			fmt.Sprintf("unknown rerun mode %d", req.Mode))).ToGRPCStatus().Err()
+	}
+
+	task, err := h.repo.GetByID(req.Id)
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if err := h.checkTaskAccess(ctx, task); err != nil {
+		return nil, err
+	}
+	if err := checkExpectedVersion(task, req.ExpectedVersion); err != nil {
+		return nil, err
+	}
+	if !task.CanRerun() {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskInvalidTransition,
+			fmt.Sprintf("task is %s, only SUCCEEDED, CANCELLED or TIMEOUT tasks can be rerun", task.Status)).ToGRPCStatus().Err()
+	}
+
+	operator := grpc_middleware.GetUserID(ctx)
+	if operator == "" {
+		operator = "system"
+	}
+	requestID := grpc_middleware.GetRequestID(ctx)
+
+	if mode == model.RerunModeClone {
+		clone := task.CloneForRerun(task.CreatedBy)
+		clone.ID = idgen.NewID()
+		if userID := grpc_middleware.GetUserID(ctx); userID != "" {
+			clone.CreatedBy = userID
+		}
+		clone.CorrelationID = requestID
+		if err := h.repo.Create(clone); err != nil {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+		h.wakeScheduler()
+		return h.toPBTask(clone, false), nil
+	}
+
+	fromStatus := task.Status
+	_, err = h.repo.RerunTask(task.ID, fromStatus, operator, "task rerun", requestID)
+	if errors.Is(err, repository.ErrStatusMismatch) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeConflict, "task status changed concurrently, retry with the latest status").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if task, err = h.repo.GetByID(task.ID); err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	h.broadcastCorrelatedTaskChange(task.ID, task, fromStatus, task.Status, "status_changed", requestID)
+	h.notifier.NotifyTaskChange(task, fromStatus, task.Status)
+	h.wakeScheduler()
+	return h.toPBTask(task, false), nil
+}
+
+// toPBTaskRun 转换为 Protobuf 运行记录
+func toPBTaskRun(run *model.TaskRun) *pb.TaskRun {
+	pbRun := &pb.TaskRun{
+		Run:          run.Run,
+		Status:       pb.TaskStatus(run.Status),
+		OutputResult: run.OutputResult,
+		OutputRef:    run.OutputRef,
+		ErrorMessage: run.ErrorMessage,
+		ErrorClass:   string(run.ErrorClass),
+		RetryCount:   run.RetryCount,
+		ExecutedBy:   run.ExecutedBy,
+		ArchivedAt:   run.ArchivedAt.Unix(),
+	}
+	if run.StartedAt != nil {
+		pbRun.StartedAt = run.StartedAt.Unix()
+	}
+	if run.CompletedAt != nil {
+		pbRun.CompletedAt = run.CompletedAt.Unix()
+	}
+	return pbRun
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_RerunTask(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "nightly", Labels: map[string]string{"env": "prod"}})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// 未结束的任务不能重新运行
+	if _, err := h.RerunTask(ctx, &pb.RerunTaskRequest{Id: created.Id}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition rerunning a pending task, got %v", err)
+	}
+
+	for _, st := range []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_RUNNING, pb.TaskStatus_TASK_STATUS_SUCCEEDED} {
+		req := &pb.UpdateTaskRequest{Id: created.Id, Status: st}
+		if st == pb.TaskStatus_TASK_STATUS_SUCCEEDED {
+			req.OutputResult = map[string]string{"rows": "10"}
+		}
+		if _, err := h.UpdateTask(ctx, req); err != nil {
+			t.Fatalf("UpdateTask(%s): %v", st, err)
+		}
+	}
+
+	// 克隆：新任务关联原任务，原任务不变
+	clone, err := h.RerunTask(ctx, &pb.RerunTaskRequest{Id: created.Id, Mode: pb.RerunMode_RERUN_MODE_CLONE})
+	if err != nil {
+		t.Fatalf("clone rerun: %v", err)
+	}
+	if clone.Id == created.Id || clone.RerunOf != created.Id || clone.Status != pb.TaskStatus_TASK_STATUS_PENDING || clone.Labels["env"] != "prod" {
+		t.Errorf("unexpected clone: %+v", clone)
+	}
+
+	// 默认原地重置，上次运行的输出保存在运行记录中
+	reset, err := h.RerunTask(ctx, &pb.RerunTaskRequest{Id: created.Id})
+	if err != nil {
+		t.Fatalf("reset rerun: %v", err)
+	}
+	if reset.Id != created.Id || reset.Status != pb.TaskStatus_TASK_STATUS_PENDING || len(reset.OutputResult) != 0 {
+		t.Errorf("unexpected reset task: %+v", reset)
+	}
+	got, err := h.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id, IncludeEvents: true})
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if len(got.Runs) != 1 || got.Runs[0].Status != pb.TaskStatus_TASK_STATUS_SUCCEEDED || got.Runs[0].OutputResult["rows"] != "10" {
+		t.Errorf("unexpected runs: %+v", got.Runs)
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.RerunTaskRequest
+		want codes.Code
+	}{
+		{"missing id", &pb.RerunTaskRequest{}, codes.InvalidArgument},
+		{"unknown mode", &pb.RerunTaskRequest{Id: created.Id, Mode: 9}, codes.InvalidArgument},
+		{"missing task", &pb.RerunTaskRequest{Id: "missing"}, codes.NotFound},
+	}
+	for _, tc := range cases {
+		if _, err := h.RerunTask(ctx, tc.req); status.Code(err) != tc.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tc.name, status.Code(err), tc.want, err)
+		}
+	}
+}
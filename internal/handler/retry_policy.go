@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"taskflow/internal/model"
+	pb "taskflow/proto"
+)
+
+// fromPBRetryPolicy 转换 Protobuf 重试策略，未设置时返回 nil
+func fromPBRetryPolicy(p *pb.RetryPolicy) *model.RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	return &model.RetryPolicy{
+		MaxAttempts:     p.MaxAttempts,
+		Backoff:         model.BackoffStrategy(p.Backoff),
+		InitialDelayMs:  p.InitialDelayMs,
+		MaxDelayMs:      p.MaxDelayMs,
+		RetryableErrors: p.RetryableErrors,
+		RetryOnTimeout:  p.RetryOnTimeout,
+	}
+}
+
+// toPBRetryPolicy 转换为 Protobuf 重试策略
+func toPBRetryPolicy(p *model.RetryPolicy) *pb.RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	return &pb.RetryPolicy{
+		MaxAttempts:     p.MaxAttempts,
+		Backoff:         string(p.Backoff),
+		InitialDelayMs:  p.InitialDelayMs,
+		MaxDelayMs:      p.MaxDelayMs,
+		RetryableErrors: p.RetryableErrors,
+		RetryOnTimeout:  p.RetryOnTimeout,
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_CreateTaskRouting(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "train",
+		NodeSelector: map[string]string{"gpu": "true"}, TargetWorker: "gpu-host-1"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	got, err := h.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id})
+	if err != nil || got.NodeSelector["gpu"] != "true" || got.TargetWorker != "gpu-host-1" {
+		t.Errorf("unexpected routed task: %v, %v", got, err)
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.CreateTaskRequest
+	}{
+		{"invalid selector key", &pb.CreateTaskRequest{Name: "train", NodeSelector: map[string]string{"-gpu": "true"}}},
+		{"target worker too long", &pb.CreateTaskRequest{Name: "train", TargetWorker: strings.Repeat("w", 256)}},
+	}
+	for _, tc := range cases {
+		if _, err := h.CreateTask(ctx, tc.req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: code = %s, want InvalidArgument (%v)", tc.name, status.Code(err), err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// schedulerInstanceStaleAfter 心跳超过该时长未更新的实例视为失联（默认心跳间隔的 3 倍）
+const schedulerInstanceStaleAfter = 30 * time.Second
+
+// SchedulerControl 调度器控制接口，由 service.Scheduler 实现
+type SchedulerControl interface {
+	ResizeWorkerPool(size int) error
+	WorkerPoolStats() (size, busy, queueDepth int, autoscaled bool)
+	// CancelExecution 取消运行中任务的执行并等待执行器清理，任务未在本进程执行时返回 false
+	CancelExecution(taskID string) bool
+	// GetSchedulingDecision 说明任务当前为什么没有被调度
+	GetSchedulingDecision(taskID string) (*model.SchedulingDecision, error)
+}
+
+// SetSchedulerControl 设置调度器控制，未设置时调度器相关接口返回不可用
+func (h *TaskHandler) SetSchedulerControl(sc SchedulerControl) {
+	h.scheduler = sc
+}
+
+// GetWorkerPool 获取工作池状态
+func (h *TaskHandler) GetWorkerPool(ctx context.Context, req *pb.GetWorkerPoolRequest) (*pb.WorkerPoolStatus, error) {
+	if h.scheduler == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeSchedulerUnavailable, "scheduler is not running in this process").ToGRPCStatus().Err()
+	}
+	return h.workerPoolStatus(), nil
+}
+
+// ResizeWorkerPool 调整工作池大小，立即生效且不丢弃已排队的任务
+func (h *TaskHandler) ResizeWorkerPool(ctx context.Context, req *pb.ResizeWorkerPoolRequest) (*pb.WorkerPoolStatus, error) {
+	if req.Size < 1 {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "size must be positive").ToGRPCStatus().Err()
+	}
+	if h.scheduler == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeSchedulerUnavailable, "scheduler is not running in this process").ToGRPCStatus().Err()
+	}
+	if err := h.scheduler.ResizeWorkerPool(int(req.Size)); err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidState, err.Error()).ToGRPCStatus().Err()
+	}
+	return h.workerPoolStatus(), nil
+}
+
+// workerPoolStatus 转换为 Protobuf 工作池状态
+func (h *TaskHandler) workerPoolStatus() *pb.WorkerPoolStatus {
+	size, busy, queueDepth, autoscaled := h.scheduler.WorkerPoolStats()
+	return &pb.WorkerPoolStatus{
+		Size:       int32(size),
+		Busy:       int32(busy),
+		QueueDepth: int32(queueDepth),
+		Autoscaled: autoscaled,
+	}
+}
+
+// GetSchedulingDecision 按本进程调度器的调度路径说明任务当前为什么没有运行
+func (h *TaskHandler) GetSchedulingDecision(ctx context.Context, req *pb.GetSchedulingDecisionRequest) (*pb.SchedulingDecision, error) {
+	if req.TaskId == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id is required").ToGRPCStatus().Err()
+	}
+	if _, err := h.getAccessibleTask(ctx, req.TaskId); err != nil {
+		return nil, err
+	}
+	if h.scheduler == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeSchedulerUnavailable, "scheduler is not running in this process").ToGRPCStatus().Err()
+	}
+
+	decision, err := h.scheduler.GetSchedulingDecision(req.TaskId)
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskNotFound, "task not found").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	return toPBSchedulingDecision(decision), nil
+}
+
+// toPBSchedulingDecision 转换为 Protobuf 调度决策
+func toPBSchedulingDecision(d *model.SchedulingDecision) *pb.SchedulingDecision {
+	resp := &pb.SchedulingDecision{
+		TaskId:       d.TaskID,
+		Status:       pb.TaskStatus(d.Status),
+		Dispatchable: d.Dispatchable,
+		InstanceId:   d.InstanceID,
+		EvaluatedAt:  d.EvaluatedAt.Unix(),
+	}
+	for _, r := range d.Reasons {
+		reason := &pb.SchedulingReason{Code: string(r.Code), Message: r.Message}
+		if r.Until != nil {
+			reason.Until = r.Until.Unix()
+		}
+		for _, dep := range r.Dependencies {
+			reason.Dependencies = append(reason.Dependencies, &pb.DependencyState{
+				TaskId: dep.TaskID,
+				Status: pb.TaskStatus(dep.Status),
+				Policy: string(dep.Policy),
+			})
+		}
+		resp.Reasons = append(resp.Reasons, reason)
+	}
+	return resp
+}
+
+// ListSchedulerInstances 列出调度器实例，默认只返回心跳未超时的实例
+func (h *TaskHandler) ListSchedulerInstances(ctx context.Context, req *pb.ListSchedulerInstancesRequest) (*pb.ListSchedulerInstancesResponse, error) {
+	instances, err := h.repo.ListSchedulerInstances()
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	resp := &pb.ListSchedulerInstancesResponse{}
+	cutoff := time.Now().Add(-schedulerInstanceStaleAfter)
+	for _, inst := range instances {
+		alive := inst.HeartbeatAt.After(cutoff)
+		if !alive && !req.IncludeStale {
+			continue
+		}
+		resp.Instances = append(resp.Instances, toPBSchedulerInstance(inst, alive))
+	}
+	return resp, nil
+}
+
+// toPBSchedulerInstance 转换为 Protobuf 调度器实例
+func toPBSchedulerInstance(inst *model.SchedulerInstance, alive bool) *pb.SchedulerInstance {
+	return &pb.SchedulerInstance{
+		Id:            inst.ID,
+		Hostname:      inst.Hostname,
+		StartedAt:     inst.StartedAt.Unix(),
+		HeartbeatAt:   inst.HeartbeatAt.Unix(),
+		WorkerCount:   int32(inst.WorkerCount),
+		BusyWorkers:   int32(inst.BusyWorkers),
+		QueueDepth:    int32(inst.QueueDepth),
+		InFlightTasks: inst.InFlightTasks,
+		Alive:         alive,
+		Labels:        inst.Labels,
+	}
+}
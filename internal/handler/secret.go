@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"errors"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/secrets"
+	pb "taskflow/proto"
+)
+
+// SetSecretManager 设置密钥管理器，为 nil 时密钥接口不可用
+func (h *TaskHandler) SetSecretManager(m *secrets.Manager) {
+	h.secrets = m
+}
+
+// PutSecret 创建或覆盖密钥，响应不包含值
+func (h *TaskHandler) PutSecret(ctx context.Context, req *pb.PutSecretRequest) (*pb.Secret, error) {
+	if h.secrets == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeSecretsDisabled, "secrets are not enabled").ToGRPCStatus().Err()
+	}
+	if err := secrets.ValidateName(req.Name); err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, err.Error()).ToGRPCStatus().Err()
+	}
+	if req.Value == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "value is required").ToGRPCStatus().Err()
+	}
+
+	author := req.CreatedBy
+	if userID := grpc_middleware.GetUserID(ctx); userID != "" {
+		author = userID
+	}
+
+	secret, err := h.secrets.Put(req.Name, req.Value, author)
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	return toPBSecret(secret), nil
+}
+
+// ListSecrets 列出密钥元数据
+func (h *TaskHandler) ListSecrets(ctx context.Context, req *pb.ListSecretsRequest) (*pb.ListSecretsResponse, error) {
+	if h.secrets == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeSecretsDisabled, "secrets are not enabled").ToGRPCStatus().Err()
+	}
+
+	list, err := h.secrets.List()
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	resp := &pb.ListSecretsResponse{Secrets: make([]*pb.Secret, 0, len(list))}
+	for _, secret := range list {
+		resp.Secrets = append(resp.Secrets, toPBSecret(secret))
+	}
+	return resp, nil
+}
+
+// DeleteSecret 删除密钥，仍引用它的任务执行时失败
+func (h *TaskHandler) DeleteSecret(ctx context.Context, req *pb.DeleteSecretRequest) (*pb.DeleteSecretResponse, error) {
+	if h.secrets == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeSecretsDisabled, "secrets are not enabled").ToGRPCStatus().Err()
+	}
+	if req.Name == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "name is required").ToGRPCStatus().Err()
+	}
+
+	err := h.secrets.Delete(req.Name)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeNotFound, "secret not found").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	return &pb.DeleteSecretResponse{}, nil
+}
+
+// toPBSecret 转换为 Protobuf 密钥元数据
+func toPBSecret(s *model.Secret) *pb.Secret {
+	return &pb.Secret{
+		Name:      s.Name,
+		CreatedBy: s.CreatedBy,
+		CreatedAt: s.CreatedAt.Unix(),
+		UpdatedAt: s.UpdatedAt.Unix(),
+	}
+}
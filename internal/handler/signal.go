@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// SignalTask 向等待输入（WAITING_INPUT）的任务送达输入，返回重新进入 PENDING 的任务。
+// payload 与之前送达的输入按键合并，任务再次执行时执行器通过执行上下文读取
+func (h *TaskHandler) SignalTask(ctx context.Context, req *pb.SignalTaskRequest) (*pb.Task, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+
+	task, err := h.getAccessibleTask(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExpectedVersion(task, req.ExpectedVersion); err != nil {
+		return nil, err
+	}
+	if task.Status != model.TaskStatusWaitingInput {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskInvalidTransition,
+			fmt.Sprintf("task is %s, only WAITING_INPUT tasks can be signalled", task.Status)).ToGRPCStatus().Err()
+	}
+	if err := model.ValidateSignalPayload(task.SignalInput, req.Payload); err != nil {
+		return nil, errorcode.NewValidationError(errorcode.NewFieldViolation("payload", "invalid", err.Error())).ToGRPCStatus().Err()
+	}
+
+	operator := grpc_middleware.GetUserID(ctx)
+	if operator == "" {
+		operator = "system"
+	}
+	requestID := grpc_middleware.GetRequestID(ctx)
+
+	err = h.repo.SignalTask(task.ID, req.Payload, operator, "input received", requestID)
+	if errors.Is(err, repository.ErrStatusMismatch) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeConflict, "task status changed concurrently, retry with the latest status").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if task, err = h.repo.GetByID(task.ID); err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	h.broadcastCorrelatedTaskChange(task.ID, task, model.TaskStatusWaitingInput, task.Status, "status_changed", requestID)
+	h.notifier.NotifyTaskChange(task, model.TaskStatusWaitingInput, task.Status)
+	h.wakeScheduler()
+	return h.toPBTask(task, false), nil
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_SignalTask(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "approval"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// 未等待输入的任务不能接收信号
+	if _, err := h.SignalTask(ctx, &pb.SignalTaskRequest{Id: created.Id, Payload: map[string]string{"ok": "1"}}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition signalling a pending task, got %v", err)
+	}
+
+	// 模拟执行器请求输入
+	if _, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_RUNNING}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if err := repo.SuspendForInput(created.Id, "approve the deployment", "scheduler", "waiting for input", "", nil); err != nil {
+		t.Fatalf("SuspendForInput: %v", err)
+	}
+	waiting, err := h.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id})
+	if err != nil || waiting.Status != pb.TaskStatus_TASK_STATUS_WAITING_INPUT || waiting.InputRequest != "approve the deployment" {
+		t.Fatalf("unexpected waiting task: %v, %v", waiting, err)
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.SignalTaskRequest
+		want codes.Code
+	}{
+		{"missing id", &pb.SignalTaskRequest{Payload: map[string]string{"ok": "1"}}, codes.InvalidArgument},
+		{"missing task", &pb.SignalTaskRequest{Id: "missing", Payload: map[string]string{"ok": "1"}}, codes.NotFound},
+		{"empty payload", &pb.SignalTaskRequest{Id: created.Id}, codes.InvalidArgument},
+		{"empty key", &pb.SignalTaskRequest{Id: created.Id, Payload: map[string]string{"": "1"}}, codes.InvalidArgument},
+		{"stale version", &pb.SignalTaskRequest{Id: created.Id, Payload: map[string]string{"ok": "1"}, ExpectedVersion: waiting.Version - 1}, codes.FailedPrecondition},
+	}
+	for _, tc := range cases {
+		if _, err := h.SignalTask(ctx, tc.req); status.Code(err) != tc.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tc.name, status.Code(err), tc.want, err)
+		}
+	}
+
+	signalled, err := h.SignalTask(ctx, &pb.SignalTaskRequest{Id: created.Id,
+		Payload: map[string]string{"approved": "true"}, ExpectedVersion: waiting.Version})
+	if err != nil {
+		t.Fatalf("SignalTask: %v", err)
+	}
+	if signalled.Status != pb.TaskStatus_TASK_STATUS_PENDING || signalled.InputRequest != "" ||
+		signalled.SignalInput["approved"] != "true" || signalled.Version <= waiting.Version {
+		t.Errorf("unexpected signalled task: %+v", signalled)
+	}
+
+	// 再次挂起的任务可以取消
+	if _, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_RUNNING}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if err := repo.SuspendForInput(created.Id, "second approval", "scheduler", "waiting for input", "", nil); err != nil {
+		t.Fatalf("SuspendForInput: %v", err)
+	}
+	cancelled, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_CANCELLED})
+	if err != nil || cancelled.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Errorf("cancelling a waiting task: %v, %v", cancelled, err)
+	}
+}
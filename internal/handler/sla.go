@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/model"
+	pb "taskflow/proto"
+)
+
+const (
+	// slaReportWindow SLA 报告默认统计的时间窗口
+	slaReportWindow = 24 * time.Hour
+	// maxSLAReportTasks SLA 报告最多统计的任务数
+	maxSLAReportTasks = 10000
+	// maxSLAReportBreaches SLA 报告中最多列出的违约任务数
+	maxSLAReportBreaches = 100
+)
+
+// slaDeadline 计算任务的 SLA 截止时间：sla_deadline 为绝对时间，sla_seconds 相对创建时间，都未设置时返回 nil
+func slaDeadline(createdAt time.Time, deadline, seconds int64) *time.Time {
+	var t time.Time
+	switch {
+	case deadline > 0:
+		t = time.Unix(deadline, 0)
+	case seconds > 0:
+		t = createdAt.Add(time.Duration(seconds) * time.Second)
+	default:
+		return nil
+	}
+	return &t
+}
+
+// validateSLA 校验 SLA 声明：两种写法二选一，取值不能为负
+func validateSLA(verr *errorcode.ValidationError, deadline, seconds int64) {
+	if deadline < 0 {
+		verr.Add("sla_deadline", "gte", "must be greater than or equal to 0")
+	}
+	if seconds < 0 {
+		verr.Add("sla_seconds", "gte", "must be greater than or equal to 0")
+	}
+	if deadline > 0 && seconds > 0 {
+		verr.Add("sla_seconds", "excluded_with", "must not be set together with sla_deadline")
+	}
+}
+
+// GetSLAReport 统计截止时间在 [from, to] 内、调用者可见的任务的 SLA 达成情况
+func (h *TaskHandler) GetSLAReport(ctx context.Context, req *pb.GetSLAReportRequest) (*pb.SLAReport, error) {
+	now := time.Now()
+	to := now
+	if req.To > 0 {
+		to = time.Unix(req.To, 0)
+	}
+	from := to.Add(-slaReportWindow)
+	if req.From > 0 {
+		from = time.Unix(req.From, 0)
+	}
+	if from.After(to) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "from must not be after to").ToGRPCStatus().Err()
+	}
+
+	canAccess, err := h.taskAccessFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := h.repo.ListSLATasks(from, to, maxSLAReportTasks+1)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	report := &pb.SLAReport{From: from.Unix(), To: to.Unix(), Summary: &pb.SLASummary{}}
+	if len(tasks) > maxSLAReportTasks {
+		tasks = tasks[:maxSLAReportTasks]
+		report.Truncated = true
+	}
+
+	byType := make(map[string]*pb.SLASummary)
+	for _, task := range tasks {
+		if !canAccess(task) || req.TaskType != "" && task.TaskType != req.TaskType {
+			continue
+		}
+		summary, ok := byType[task.TaskType]
+		if !ok {
+			summary = &pb.SLASummary{TaskType: task.TaskType}
+			byType[task.TaskType] = summary
+		}
+
+		breached := task.SLABreached(now)
+		for _, s := range []*pb.SLASummary{report.Summary, summary} {
+			s.Total++
+			switch {
+			case breached:
+				s.Breached++
+			case task.IsTerminal():
+				s.Met++
+			default:
+				s.Open++
+			}
+		}
+		if breached && len(report.Breaches) < maxSLAReportBreaches {
+			report.Breaches = append(report.Breaches, toPBSLABreach(task, now))
+		}
+	}
+
+	setBreachRate(report.Summary)
+	for _, summary := range byType {
+		setBreachRate(summary)
+		report.ByType = append(report.ByType, summary)
+	}
+	sort.Slice(report.ByType, func(i, j int) bool { return report.ByType[i].TaskType < report.ByType[j].TaskType })
+	return report, nil
+}
+
+// setBreachRate 违约率只统计已有结果的任务
+func setBreachRate(s *pb.SLASummary) {
+	if decided := s.Met + s.Breached; decided > 0 {
+		s.BreachRate = float64(s.Breached) / float64(decided)
+	}
+}
+
+// toPBSLABreach 转换为 Protobuf 违约记录
+func toPBSLABreach(task *model.Task, now time.Time) *pb.SLABreach {
+	end := now
+	if task.IsTerminal() {
+		end = task.UpdatedAt
+		if task.CompletedAt != nil {
+			end = *task.CompletedAt
+		}
+	}
+	b := &pb.SLABreach{
+		TaskId:      task.ID,
+		Name:        task.Name,
+		TaskType:    task.TaskType,
+		Status:      pb.TaskStatus(task.Status),
+		SlaDeadline: task.SLADeadline.Unix(),
+	}
+	if overdue := end.Sub(*task.SLADeadline); overdue > 0 {
+		b.OverdueMs = overdue.Milliseconds()
+	}
+	if task.SLABreachedAt != nil {
+		b.BreachedAt = task.SLABreachedAt.Unix()
+	}
+	return b
+}
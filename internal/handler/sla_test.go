@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_SLA(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "nightly", TaskType: "report", SlaSeconds: 3600})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if created.SlaDeadline < time.Now().Add(59*time.Minute).Unix() || created.SlaBreachedAt != 0 {
+		t.Errorf("unexpected SLA fields: deadline=%d breached_at=%d", created.SlaDeadline, created.SlaBreachedAt)
+	}
+	if _, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "both", SlaSeconds: 60, SlaDeadline: time.Now().Unix()}); err == nil {
+		t.Error("expected error when both sla_deadline and sla_seconds are set")
+	}
+
+	now := time.Now()
+	add := func(id, taskType string, status model.TaskStatus, deadline, completed time.Duration) {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, taskType, nil, nil, 0, "alice")
+		task.ID = id
+		d := now.Add(deadline)
+		task.SLADeadline = &d
+		task.Status = status
+		if task.IsTerminal() {
+			c := now.Add(completed)
+			task.CompletedAt = &c
+		}
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	add("met", "etl", model.TaskStatusSucceeded, -time.Hour, -2*time.Hour)
+	add("late", "etl", model.TaskStatusSucceeded, -time.Hour, -30*time.Minute)
+	add("running-late", "report", model.TaskStatusRunning, -10*time.Minute, 0)
+	add("out-of-window", "etl", model.TaskStatusFailed, -48*time.Hour, -47*time.Hour)
+
+	report, err := h.GetSLAReport(ctx, &pb.GetSLAReportRequest{})
+	if err != nil {
+		t.Fatalf("GetSLAReport: %v", err)
+	}
+	if s := report.Summary; s.Total != 3 || s.Met != 1 || s.Breached != 2 || s.Open != 0 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+	if len(report.ByType) != 2 || report.ByType[0].TaskType != "etl" || report.ByType[0].BreachRate != 0.5 {
+		t.Errorf("unexpected per-type summary: %+v", report.ByType)
+	}
+	if len(report.Breaches) != 2 || report.Breaches[0].TaskId != "late" || report.Breaches[0].OverdueMs != (30*time.Minute).Milliseconds() {
+		t.Errorf("unexpected breaches: %+v", report.Breaches)
+	}
+
+	report, err = h.GetSLAReport(ctx, &pb.GetSLAReportRequest{TaskType: "report"})
+	if err != nil {
+		t.Fatalf("GetSLAReport: %v", err)
+	}
+	if report.Summary.Total != 1 || report.Summary.Breached != 1 {
+		t.Errorf("unexpected filtered summary: %+v", report.Summary)
+	}
+
+	if _, err := h.GetSLAReport(ctx, &pb.GetSLAReportRequest{From: now.Unix(), To: now.Add(-time.Hour).Unix()}); err == nil {
+		t.Error("expected error when from is after to")
+	}
+}
+
+func TestTaskHandler_SLAReportVisibility(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	h.SetAccessControl(nil, false)
+	ctx := context.Background()
+
+	// 简化的令牌校验把 Bearer 令牌前 8 个字符作为用户 ID
+	report := func(token string) *pb.SLAReport {
+		t.Helper()
+		authCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		resp, err := grpc_middleware.UnaryAuthInterceptor(nil)(authCtx, &pb.GetSLAReportRequest{},
+			&grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/GetSLAReport"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return h.GetSLAReport(ctx, req.(*pb.GetSLAReportRequest))
+			})
+		if err != nil {
+			t.Fatalf("GetSLAReport as %s: %v", token, err)
+		}
+		return resp.(*pb.SLAReport)
+	}
+
+	team, err := h.CreateTeam(ctx, &pb.CreateTeamRequest{Name: "ops", CreatedBy: "user-alice-to", Members: []string{"user-carol-to"}})
+	if err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	deadline := time.Now().Add(-time.Hour)
+	for _, task := range []*model.Task{
+		{ID: "ops-late", Name: "ops-late", TeamID: team.Id, CreatedBy: "user-alice-to"},
+		{ID: "bob-late", Name: "bob-late", CreatedBy: "user-bob-toke"},
+	} {
+		task.Status, task.SLADeadline = model.TaskStatusRunning, &deadline
+		task.CreatedAt, task.UpdatedAt = deadline.Add(-time.Hour), deadline.Add(-time.Hour)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("Create(%s): %v", task.ID, err)
+		}
+	}
+
+	// 团队成员看到团队的违约任务，非成员只看到自己的
+	if r := report("carol-token"); r.Summary.Total != 1 || len(r.Breaches) != 1 || r.Breaches[0].TaskId != "ops-late" {
+		t.Errorf("team member: summary %+v, breaches %+v", r.Summary, r.Breaches)
+	}
+	if r := report("bob-token"); r.Summary.Total != 1 || len(r.Breaches) != 1 || r.Breaches[0].TaskId != "bob-late" {
+		t.Errorf("non-member: summary %+v, breaches %+v", r.Summary, r.Breaches)
+	}
+}
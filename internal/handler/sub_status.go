@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// SetTaskSubStatus 外部执行器上报运行中任务进入的自定义阶段（子状态），同时作为一次心跳，返回更新后的任务。
+// 子状态只用于展示，任务状态不变；与当前子状态相同时只更新阶段说明
+func (h *TaskHandler) SetTaskSubStatus(ctx context.Context, req *pb.SetTaskSubStatusRequest) (*pb.Task, error) {
+	if req.Id == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "id is required").ToGRPCStatus().Err()
+	}
+	if err := model.ValidateSubStatus(req.SubStatus, req.Message); err != nil {
+		return nil, errorcode.NewValidationError(errorcode.NewFieldViolation("sub_status", "invalid", err.Error())).ToGRPCStatus().Err()
+	}
+
+	task, err := h.getAccessibleTask(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != model.TaskStatusRunning {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeTaskInvalidTransition,
+			fmt.Sprintf("task is %s, only RUNNING tasks can report a sub-status", task.Status)).ToGRPCStatus().Err()
+	}
+
+	operator := grpc_middleware.GetUserID(ctx)
+	if operator == "" {
+		operator = "system"
+	}
+	err = h.repo.UpdateSubStatus(task.ID, req.SubStatus, req.Message, model.Now(), operator, "")
+	if errors.Is(err, repository.ErrStatusMismatch) {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeConflict, "task status changed concurrently, retry with the latest status").ToGRPCStatus().Err()
+	}
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if task, err = h.repo.GetByID(task.ID); err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	h.broadcastCorrelatedTaskChange(task.ID, task, task.Status, task.Status, "sub_status_changed", grpc_middleware.GetRequestID(ctx))
+	return h.toPBTask(task, false), nil
+}
+
+// toPBTaskPhase 转换为 Protobuf 任务阶段
+func toPBTaskPhase(p *model.TaskPhase) *pb.TaskPhase {
+	return &pb.TaskPhase{
+		Name:      p.Name,
+		Message:   p.Message,
+		Attempt:   p.Attempt,
+		EnteredAt: p.EnteredAt.Unix(),
+	}
+}
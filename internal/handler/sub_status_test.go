@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_SetTaskSubStatus(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "ingest"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// 未运行的任务不能上报子状态
+	if _, err := h.SetTaskSubStatus(ctx, &pb.SetTaskSubStatusRequest{Id: created.Id, SubStatus: "UPLOADING"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition for a pending task, got %v", err)
+	}
+	if _, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_RUNNING}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	_, lastSeq, _ := repo.OutboxSeqRange()
+
+	cases := []struct {
+		name string
+		req  *pb.SetTaskSubStatusRequest
+		want codes.Code
+	}{
+		{"missing id", &pb.SetTaskSubStatusRequest{SubStatus: "UPLOADING"}, codes.InvalidArgument},
+		{"missing task", &pb.SetTaskSubStatusRequest{Id: "missing", SubStatus: "UPLOADING"}, codes.NotFound},
+		{"empty sub-status", &pb.SetTaskSubStatusRequest{Id: created.Id}, codes.InvalidArgument},
+		{"control characters", &pb.SetTaskSubStatusRequest{Id: created.Id, SubStatus: "UP\tLOADING"}, codes.InvalidArgument},
+	}
+	for _, tc := range cases {
+		if _, err := h.SetTaskSubStatus(ctx, tc.req); status.Code(err) != tc.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tc.name, status.Code(err), tc.want, err)
+		}
+	}
+
+	if _, err := h.SetTaskSubStatus(ctx, &pb.SetTaskSubStatusRequest{Id: created.Id, SubStatus: "UPLOADING", Message: "3 files"}); err != nil {
+		t.Fatalf("SetTaskSubStatus: %v", err)
+	}
+	updated, err := h.SetTaskSubStatus(ctx, &pb.SetTaskSubStatusRequest{Id: created.Id, SubStatus: "VALIDATING"})
+	if err != nil {
+		t.Fatalf("SetTaskSubStatus: %v", err)
+	}
+	if updated.Status != pb.TaskStatus_TASK_STATUS_RUNNING || updated.SubStatus != "VALIDATING" || len(updated.Phases) != 2 ||
+		updated.Phases[0].Name != "UPLOADING" || updated.Phases[0].Message != "3 files" || updated.Phases[0].Attempt != 1 || updated.HeartbeatAt == 0 {
+		t.Errorf("unexpected task after sub-status updates: %+v", updated)
+	}
+
+	// 列表和订阅推送的任务快照都带有子状态
+	list, err := h.ListTasks(ctx, &pb.ListTasksRequest{PageSize: 10})
+	if err != nil || len(list.Tasks) != 1 || list.Tasks[0].SubStatus != "VALIDATING" || len(list.Tasks[0].Phases) != 2 {
+		t.Errorf("expected sub-status in list results, got %v, %v", list, err)
+	}
+	events, _ := repo.ListOutboxEventsAfter(lastSeq, 10)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 sub-status outbox events, got %d", len(events))
+	}
+	change := h.outboxChangeEvent(events[1])
+	if change.ChangeType != "sub_status_changed" || change.FromStatus != change.ToStatus || change.Task.GetSubStatus() != "VALIDATING" {
+		t.Errorf("unexpected watch event: %+v", change)
+	}
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// CreateTeam 创建团队，创建者自动成为成员
+func (h *TaskHandler) CreateTeam(ctx context.Context, req *pb.CreateTeamRequest) (*pb.Team, error) {
+	if req.Name == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "name is required").ToGRPCStatus().Err()
+	}
+
+	createdBy := req.CreatedBy
+	if userID := grpc_middleware.GetUserID(ctx); userID != "" {
+		createdBy = userID
+	}
+
+	members := req.Members
+	if createdBy != "" {
+		members = append([]string{createdBy}, members...)
+	}
+
+	team := &model.Team{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		Members:   members,
+		CreatedBy: createdBy,
+		CreatedAt: model.Now(),
+	}
+	if err := h.teamRepo.Create(team); err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	return h.getPBTeam(team.ID)
+}
+
+// AddTeamMember 添加团队成员
+func (h *TaskHandler) AddTeamMember(ctx context.Context, req *pb.TeamMemberRequest) (*pb.Team, error) {
+	if req.TeamId == "" || req.UserId == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "team_id and user_id are required").ToGRPCStatus().Err()
+	}
+	if err := h.checkTeamExists(req.TeamId); err != nil {
+		return nil, err
+	}
+
+	if err := h.teamRepo.AddMember(req.TeamId, req.UserId); err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	return h.getPBTeam(req.TeamId)
+}
+
+// RemoveTeamMember 移除团队成员
+func (h *TaskHandler) RemoveTeamMember(ctx context.Context, req *pb.TeamMemberRequest) (*pb.Team, error) {
+	if req.TeamId == "" || req.UserId == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "team_id and user_id are required").ToGRPCStatus().Err()
+	}
+	if err := h.checkTeamExists(req.TeamId); err != nil {
+		return nil, err
+	}
+
+	if err := h.teamRepo.RemoveMember(req.TeamId, req.UserId); err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	return h.getPBTeam(req.TeamId)
+}
+
+// ListTeams 列出用户所在团队
+func (h *TaskHandler) ListTeams(ctx context.Context, req *pb.ListTeamsRequest) (*pb.ListTeamsResponse, error) {
+	userID := req.UserId
+	if userID == "" {
+		userID = grpc_middleware.GetUserID(ctx)
+	}
+	if userID == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "user_id is required").ToGRPCStatus().Err()
+	}
+
+	teams, err := h.teamRepo.ListByUser(userID)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+
+	resp := &pb.ListTeamsResponse{}
+	for _, team := range teams {
+		resp.Teams = append(resp.Teams, toPBTeam(team))
+	}
+	return resp, nil
+}
+
+// checkTeamExists 检查团队是否存在
+func (h *TaskHandler) checkTeamExists(teamID string) error {
+	team, err := h.teamRepo.GetByID(teamID)
+	if err != nil {
+		return errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if team == nil {
+		return errorcode.NewTaskError(errorcode.ErrCodeNotFound, "team not found").ToGRPCStatus().Err()
+	}
+	return nil
+}
+
+// SetAccessControl 设置任务可见性：adminUsers 中的用户可以访问所有任务；
+// publicUnassigned 为 true 时未归属团队的任务对所有认证用户可见
+func (h *TaskHandler) SetAccessControl(adminUsers []string, publicUnassigned bool) {
+	h.accessAdmins = make(map[string]bool, len(adminUsers))
+	for _, u := range adminUsers {
+		h.accessAdmins[u] = true
+	}
+	h.publicUnassigned = publicUnassigned
+}
+
+// unrestricted 调用者是否不受任务可见性限制：未认证调用和管理员
+func (h *TaskHandler) unrestricted(userID string) bool {
+	return userID == "" || h.accessAdmins[userID]
+}
+
+// checkTaskAccess 检查调用者是否可以访问任务（未认证调用不做限制）
+func (h *TaskHandler) checkTaskAccess(ctx context.Context, task *model.Task) error {
+	canAccess, err := h.taskAccessFilter(ctx)
+	if err != nil {
+		return err
+	}
+	if !canAccess(task) {
+		return errorcode.NewTaskError(errorcode.ErrCodeForbidden, "task belongs to another user or team").ToGRPCStatus().Err()
+	}
+	return nil
+}
+
+// taskAccessFilter 返回调用者的任务可见性判断函数，团队关系只加载一次，用于批量场景；
+// 传入 nil 时返回 false
+func (h *TaskHandler) taskAccessFilter(ctx context.Context) (func(*model.Task) bool, error) {
+	userID := grpc_middleware.GetUserID(ctx)
+	if h.unrestricted(userID) {
+		return func(task *model.Task) bool { return task != nil }, nil
+	}
+
+	var teamIDs []string
+	if h.teamRepo != nil {
+		var err error
+		if teamIDs, err = h.teamRepo.ListTeamIDsByUser(userID); err != nil {
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+	}
+	return func(task *model.Task) bool {
+		if task == nil {
+			return false
+		}
+		return (h.publicUnassigned && task.TeamID == "") || task.CanAccess(userID, teamIDs)
+	}, nil
+}
+
+// applyVisibility 把调用者的可见范围加入列表过滤条件，以认证身份为准而不是客户端传入的过滤参数
+func (h *TaskHandler) applyVisibility(ctx context.Context, filter *repository.TaskFilter) {
+	userID := grpc_middleware.GetUserID(ctx)
+	if h.unrestricted(userID) {
+		return
+	}
+	filter.VisibleTo = userID
+	filter.IncludeUnassigned = h.publicUnassigned
+}
+
+// getPBTeam 重新加载团队并转换为 Protobuf
+func (h *TaskHandler) getPBTeam(teamID string) (*pb.Team, error) {
+	team, err := h.teamRepo.GetByID(teamID)
+	if err != nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	if team == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeNotFound, "team not found").ToGRPCStatus().Err()
+	}
+	return toPBTeam(team), nil
+}
+
+// toPBTeam 转换为 Protobuf 团队
+func toPBTeam(team *model.Team) *pb.Team {
+	return &pb.Team{
+		Id:        team.ID,
+		Name:      team.Name,
+		Members:   team.Members,
+		CreatedBy: team.CreatedBy,
+		CreatedAt: team.CreatedAt.Unix(),
+	}
+}
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// maxTimelineTasks 时间线最多包含的任务数，超过时只返回先找到的任务并标记 truncated
+const maxTimelineTasks = 1000
+
+// GetTaskTimeline 返回一个工作流的时间线，供仪表盘和命令行绘制甘特图。指定 task_id 时包含与该任务通过依赖
+// 直接或间接相连的全部任务，指定 correlation_id 时包含同一请求创建的全部任务；调用者无权访问的任务不返回
+func (h *TaskHandler) GetTaskTimeline(ctx context.Context, req *pb.GetTaskTimelineRequest) (*pb.TaskTimeline, error) {
+	if (req.TaskId == "") == (req.CorrelationId == "") {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "exactly one of task_id and correlation_id is required").ToGRPCStatus().Err()
+	}
+
+	tasks, truncated, err := h.workflowTasks(ctx, req.TaskId, req.CorrelationId, maxTimelineTasks)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := buildTimeline(tasks, time.Now())
+	timeline.Truncated = truncated
+	return timeline, nil
+}
+
+// workflowTasks 工作流中调用者可访问的任务：指定 taskID 时为与该任务通过依赖直接或间接相连的任务，
+// 否则为 correlationID 请求创建的任务。超过 limit 个时只返回先找到的任务并返回 true
+func (h *TaskHandler) workflowTasks(ctx context.Context, taskID, correlationID string, limit int) ([]*model.Task, bool, error) {
+	if taskID == "" {
+		filter := repository.TaskFilter{CorrelationID: correlationID, SortBy: repository.SortByCreatedAt, PageSize: limit + 1}
+		h.applyVisibility(ctx, &filter)
+		tasks, _, err := h.repo.ListByFilter(filter)
+		if err != nil {
+			logger.Errorf("Handler error: %v", err)
+			return nil, false, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+		if len(tasks) > limit {
+			return tasks[:limit], true, nil
+		}
+		return tasks, false, nil
+	}
+
+	root, err := h.getAccessibleTask(ctx, taskID)
+	if err != nil {
+		return nil, false, err
+	}
+	tasks, truncated, err := h.dependencyClosure(root, limit)
+	if err != nil {
+		logger.Errorf("Handler error: %v", err)
+		return nil, false, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+	}
+	canAccess, err := h.taskAccessFilter(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	visible := tasks[:0]
+	for _, task := range tasks {
+		if canAccess(task) {
+			visible = append(visible, task)
+		}
+	}
+	return visible, truncated, nil
+}
+
+// dependencyClosure 从 root 出发沿依赖向上游和下游逐层查找相连的任务，超过 limit 个时截断
+func (h *TaskHandler) dependencyClosure(root *model.Task, limit int) ([]*model.Task, bool, error) {
+	found := map[string]bool{root.ID: true}
+	closure := []*model.Task{root}
+	frontier := []*model.Task{root}
+	for len(frontier) > 0 {
+		var ids, upstreamIDs []string
+		for _, task := range frontier {
+			ids = append(ids, task.ID)
+			for _, dep := range task.Dependencies {
+				if !found[dep] {
+					upstreamIDs = append(upstreamIDs, dep)
+				}
+			}
+		}
+		upstream, err := h.repo.GetByIDs(upstreamIDs)
+		if err != nil {
+			return nil, false, err
+		}
+		downstream, err := h.repo.ListDependents(ids, nil)
+		if err != nil {
+			return nil, false, err
+		}
+
+		frontier = nil
+		for _, task := range append(upstream, downstream...) {
+			if task == nil || found[task.ID] {
+				continue
+			}
+			if len(closure) == limit {
+				return closure, true, nil
+			}
+			found[task.ID] = true
+			closure = append(closure, task)
+			frontier = append(frontier, task)
+		}
+	}
+	return closure, false, nil
+}
+
+// buildTimeline 按创建时间排列任务并计算关键路径。依赖只保留时间线内的上游；
+// 运行中的任务按 now 计算耗时，关键路径从结束（或运行到 now）最晚的已开始任务出发，逐级回溯到结束最晚的上游，
+// 即决定工作流当前进度的依赖链
+func buildTimeline(tasks []*model.Task, now time.Time) *pb.TaskTimeline {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	byID := make(map[string]*model.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	// end 已开始任务的结束时间，运行中为 now；未开始的任务没有执行区间
+	end := func(task *model.Task) (time.Time, bool) {
+		switch {
+		case task.StartedAt == nil:
+			return time.Time{}, false
+		case task.CompletedAt != nil:
+			return *task.CompletedAt, true
+		case task.Status == model.TaskStatusRunning:
+			return now, true
+		}
+		return time.Time{}, false
+	}
+
+	timeline := &pb.TaskTimeline{}
+	var sink *model.Task
+	var sinkEnd time.Time
+	allDone := len(tasks) > 0
+	for _, task := range tasks {
+		entry := &pb.TimelineTask{
+			Id:         task.ID,
+			Name:       task.Name,
+			TaskType:   task.TaskType,
+			Status:     pb.TaskStatus(task.Status),
+			QueuedAtMs: task.CreatedAt.UnixMilli(),
+		}
+		readyAt, ready := task.CreatedAt, true
+		for _, dep := range task.Dependencies {
+			upstream := byID[dep]
+			if upstream == nil {
+				continue
+			}
+			entry.Dependencies = append(entry.Dependencies, dep)
+			if upstream.CompletedAt == nil {
+				ready = false
+			} else if upstream.CompletedAt.After(readyAt) {
+				readyAt = *upstream.CompletedAt
+			}
+		}
+		if ready {
+			entry.ReadyAtMs = readyAt.UnixMilli()
+		}
+		if task.StartedAt != nil {
+			entry.StartedAtMs = task.StartedAt.UnixMilli()
+		}
+		if task.CompletedAt != nil {
+			entry.EndedAtMs = task.CompletedAt.UnixMilli()
+		}
+		if at, ok := end(task); ok {
+			entry.DurationMs = at.Sub(*task.StartedAt).Milliseconds()
+			if sink == nil || !at.Before(sinkEnd) {
+				sink, sinkEnd = task, at
+			}
+		}
+		if !task.Status.IsTerminal() {
+			allDone = false
+		}
+		timeline.Tasks = append(timeline.Tasks, entry)
+	}
+
+	if len(tasks) > 0 {
+		timeline.StartedAtMs = tasks[0].CreatedAt.UnixMilli()
+	}
+	if allDone {
+		for _, entry := range timeline.Tasks {
+			timeline.EndedAtMs = max(timeline.EndedAtMs, entry.EndedAtMs)
+		}
+	}
+	if sink == nil {
+		return timeline
+	}
+
+	critical := make(map[string]bool)
+	head := sink
+	for task := sink; task != nil; {
+		critical[task.ID] = true
+		timeline.CriticalPath = append([]string{task.ID}, timeline.CriticalPath...)
+		head = task
+
+		var prev *model.Task
+		var prevEnd time.Time
+		for _, dep := range task.Dependencies {
+			upstream := byID[dep]
+			if upstream == nil || critical[dep] {
+				continue
+			}
+			if at, ok := end(upstream); ok && (prev == nil || at.After(prevEnd)) {
+				prev, prevEnd = upstream, at
+			}
+		}
+		task = prev
+	}
+	timeline.CriticalPathDurationMs = sinkEnd.Sub(head.CreatedAt).Milliseconds()
+	for _, entry := range timeline.Tasks {
+		entry.Critical = critical[entry.Id]
+	}
+	return timeline
+}
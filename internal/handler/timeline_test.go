@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_GetTaskTimeline(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	at := func(sec int) *time.Time {
+		v := base.Add(time.Duration(sec) * time.Second)
+		return &v
+	}
+	create := func(id string, deps []string, s model.TaskStatus, created int, started, completed *time.Time) {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "etl", nil, deps, 0, "alice")
+		task.ID, task.Status, task.CorrelationID = id, s, "req-1"
+		task.CreatedAt, task.StartedAt, task.CompletedAt = *at(created), started, completed
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	create("extract", nil, model.TaskStatusSucceeded, 0, at(1), at(11))
+	create("transform", []string{"extract"}, model.TaskStatusSucceeded, 2, at(12), at(42))
+	create("audit", []string{"extract"}, model.TaskStatusSucceeded, 3, at(12), at(20))
+	create("load", []string{"transform", "audit"}, model.TaskStatusRunning, 4, at(43), nil)
+	create("report", []string{"load"}, model.TaskStatusPending, 5, nil, nil)
+	create("unrelated", nil, model.TaskStatusSucceeded, 6, at(7), at(8))
+
+	ids := func(timeline *pb.TaskTimeline) []string {
+		var got []string
+		for _, task := range timeline.Tasks {
+			got = append(got, task.Id)
+		}
+		return got
+	}
+
+	// 从任一任务出发都能找到整个工作流，按创建时间排序
+	timeline, err := h.GetTaskTimeline(ctx, &pb.GetTaskTimelineRequest{TaskId: "audit"})
+	if err != nil {
+		t.Fatalf("GetTaskTimeline: %v", err)
+	}
+	if want := []string{"extract", "transform", "audit", "load", "report"}; !slices.Equal(ids(timeline), want) {
+		t.Fatalf("expected closure %v, got %v", want, ids(timeline))
+	}
+	if timeline.StartedAtMs != base.UnixMilli() || timeline.EndedAtMs != 0 || timeline.Truncated {
+		t.Errorf("unexpected timeline bounds: %v", timeline)
+	}
+	transform := timeline.Tasks[1]
+	if transform.QueuedAtMs != at(2).UnixMilli() || transform.ReadyAtMs != at(11).UnixMilli() ||
+		transform.StartedAtMs != at(12).UnixMilli() || transform.EndedAtMs != at(42).UnixMilli() || transform.DurationMs != 30000 {
+		t.Errorf("unexpected transform entry: %v", transform)
+	}
+	if report := timeline.Tasks[4]; report.ReadyAtMs != 0 || report.StartedAtMs != 0 || report.DurationMs != 0 {
+		t.Errorf("expected report to be waiting for load, got %v", report)
+	}
+
+	// 同一请求创建的任务
+	timeline, err = h.GetTaskTimeline(ctx, &pb.GetTaskTimelineRequest{CorrelationId: "req-1"})
+	if err != nil {
+		t.Fatalf("GetTaskTimeline: %v", err)
+	}
+	if len(timeline.Tasks) != 6 {
+		t.Errorf("expected all 6 tasks of the request, got %v", ids(timeline))
+	}
+
+	for _, req := range []*pb.GetTaskTimelineRequest{{}, {TaskId: "extract", CorrelationId: "req-1"}} {
+		if _, err := h.GetTaskTimeline(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for %v, got %v", req, err)
+		}
+	}
+	if _, err := h.GetTaskTimeline(ctx, &pb.GetTaskTimelineRequest{TaskId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
+
+func TestBuildTimeline_CriticalPath(t *testing.T) {
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(sec int) *time.Time {
+		v := base.Add(time.Duration(sec) * time.Second)
+		return &v
+	}
+	task := func(id string, deps []string, s model.TaskStatus, started, completed *time.Time) *model.Task {
+		return &model.Task{ID: id, Dependencies: deps, Status: s, CreatedAt: base, StartedAt: started, CompletedAt: completed}
+	}
+
+	// 关键路径沿结束最晚的上游回溯：load 等待的是 transform 而不是更早结束的 audit
+	now := *at(60)
+	timeline := buildTimeline([]*model.Task{
+		task("load", []string{"transform", "audit", "outside"}, model.TaskStatusRunning, at(43), nil),
+		task("audit", []string{"extract"}, model.TaskStatusSucceeded, at(12), at(20)),
+		task("transform", []string{"extract"}, model.TaskStatusSucceeded, at(12), at(42)),
+		task("extract", nil, model.TaskStatusSucceeded, at(1), at(11)),
+	}, now)
+	if want := []string{"extract", "transform", "load"}; !slices.Equal(timeline.CriticalPath, want) {
+		t.Errorf("expected critical path %v, got %v", want, timeline.CriticalPath)
+	}
+	if timeline.CriticalPathDurationMs != 60000 {
+		t.Errorf("expected critical path duration 60s, got %dms", timeline.CriticalPathDurationMs)
+	}
+	for _, entry := range timeline.Tasks {
+		if entry.Critical != slices.Contains(timeline.CriticalPath, entry.Id) {
+			t.Errorf("unexpected critical flag on %s", entry.Id)
+		}
+		if entry.Id == "load" && (entry.DurationMs != 17000 || !slices.Equal(entry.Dependencies, []string{"transform", "audit"})) {
+			t.Errorf("unexpected load entry: %v", entry)
+		}
+	}
+
+	// 全部结束后报告结束时间；没有开始过的任务不在关键路径上
+	timeline = buildTimeline([]*model.Task{
+		task("extract", nil, model.TaskStatusSucceeded, at(1), at(11)),
+		task("skipped", []string{"extract"}, model.TaskStatusCancelled, nil, at(30)),
+	}, now)
+	if timeline.EndedAtMs != at(30).UnixMilli() || !slices.Equal(timeline.CriticalPath, []string{"extract"}) {
+		t.Errorf("unexpected finished timeline: %v", timeline)
+	}
+	if timeline := buildTimeline(nil, now); len(timeline.CriticalPath) != 0 || timeline.StartedAtMs != 0 {
+		t.Errorf("expected empty timeline, got %v", timeline)
+	}
+}
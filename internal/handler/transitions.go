@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/model"
+	"taskflow/internal/service"
+	pb "taskflow/proto"
+)
+
+// 触发状态转换的接口
+const (
+	transitionActionUpdate = "update" // UpdateTask（PUT /tasks/:id）
+	transitionActionSignal = "signal" // SignalTask（POST /tasks/:id/signal）
+	transitionActionRerun  = "rerun"  // RerunTask（POST /tasks/:id/rerun）
+	transitionActionSystem = "system" // 只由调度器或执行器进行
+)
+
+// 调用者相对任务的角色
+const (
+	callerRoleUnauthenticated = "unauthenticated"
+	callerRoleAdmin           = "admin"
+	callerRoleOwner           = "owner"
+	callerRoleTeamMember      = "team_member"
+	callerRolePublic          = "public"
+)
+
+// stateMachine 状态转换表，与调度器使用的状态机相同
+var stateMachine = service.NewStateMachine()
+
+// GetAllowedTransitions 返回任务当前可以转换到的状态、触发每个转换的接口，以及调用者可以触发其中哪些，
+// 只读，不改变任务状态
+func (h *TaskHandler) GetAllowedTransitions(ctx context.Context, req *pb.GetAllowedTransitionsRequest) (*pb.AllowedTransitions, error) {
+	if req.TaskId == "" {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task_id is required").ToGRPCStatus().Err()
+	}
+	task, err := h.getAccessibleTask(ctx, req.TaskId)
+	if err != nil {
+		return nil, err
+	}
+	role, err := h.callerRole(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.AllowedTransitions{
+		TaskId:  task.ID,
+		Status:  pb.TaskStatus(task.Status),
+		Version: task.Version,
+		Role:    role,
+	}
+	for _, to := range stateMachine.GetAllowedTransitions(task.Status) {
+		action, reason := transitionAction(task.Status, to)
+		resp.Transitions = append(resp.Transitions, &pb.AllowedTransition{
+			ToStatus:      pb.TaskStatus(to),
+			Action:        action,
+			CallerAllowed: reason == "",
+			Reason:        reason,
+		})
+	}
+	// 重新运行不在状态机中：已结束的任务通过 RerunTask 重新进入 PENDING（CLONE 模式为创建新任务）
+	if task.CanRerun() {
+		resp.Transitions = append(resp.Transitions, &pb.AllowedTransition{
+			ToStatus:      pb.TaskStatus_TASK_STATUS_PENDING,
+			Action:        transitionActionRerun,
+			CallerAllowed: true,
+		})
+	}
+	return resp, nil
+}
+
+// transitionAction 触发 from 到 to 的转换的接口；调用者不能触发时返回原因
+func transitionAction(from, to model.TaskStatus) (action, reason string) {
+	switch {
+	case from == model.TaskStatusWaitingInput && to == model.TaskStatusPending:
+		return transitionActionSignal, ""
+	case isValidStatusTransition(from, to):
+		return transitionActionUpdate, ""
+	case from == model.TaskStatusRunning && to == model.TaskStatusWaitingInput:
+		return transitionActionSystem, "requested by the executor while the task is running"
+	case from == model.TaskStatusFailed && to == model.TaskStatusPending:
+		return transitionActionSystem, "manual retry is only available through the embedded library"
+	default:
+		return transitionActionSystem, "not available through the API"
+	}
+}
+
+// callerRole 调用者相对任务的角色，调用者须已通过 checkTaskAccess
+func (h *TaskHandler) callerRole(ctx context.Context, task *model.Task) (string, error) {
+	userID := grpc_middleware.GetUserID(ctx)
+	switch {
+	case userID == "":
+		return callerRoleUnauthenticated, nil
+	case h.accessAdmins[userID]:
+		return callerRoleAdmin, nil
+	case task.CreatedBy == userID:
+		return callerRoleOwner, nil
+	}
+
+	if task.TeamID != "" && h.teamRepo != nil {
+		teamIDs, err := h.teamRepo.ListTeamIDsByUser(userID)
+		if err != nil {
+			return "", errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+		if task.CanAccess(userID, teamIDs) {
+			return callerRoleTeamMember, nil
+		}
+	}
+	return callerRolePublic, nil
+}
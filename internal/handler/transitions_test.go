@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_GetAllowedTransitions(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	if _, err := h.GetAllowedTransitions(ctx, &pb.GetAllowedTransitionsRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without task_id, got %v", err)
+	}
+	if _, err := h.GetAllowedTransitions(ctx, &pb.GetAllowedTransitionsRequest{TaskId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for a missing task, got %v", err)
+	}
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "approval"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	transitions := func() map[pb.TaskStatus]*pb.AllowedTransition {
+		t.Helper()
+		resp, err := h.GetAllowedTransitions(ctx, &pb.GetAllowedTransitionsRequest{TaskId: created.Id})
+		if err != nil {
+			t.Fatalf("GetAllowedTransitions: %v", err)
+		}
+		task, _ := repo.GetByID(created.Id)
+		if resp.Status != pb.TaskStatus(task.Status) || resp.Version != task.Version || resp.Role != callerRoleUnauthenticated {
+			t.Errorf("unexpected transitions header: %+v", resp)
+		}
+		byStatus := make(map[pb.TaskStatus]*pb.AllowedTransition, len(resp.Transitions))
+		for _, tr := range resp.Transitions {
+			byStatus[tr.ToStatus] = tr
+		}
+		return byStatus
+	}
+	expect := func(got map[pb.TaskStatus]*pb.AllowedTransition, want map[pb.TaskStatus]string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("expected transitions to %v, got %v", want, got)
+		}
+		for to, action := range want {
+			tr := got[to]
+			if tr == nil || tr.Action != action || tr.CallerAllowed != (action != transitionActionSystem) {
+				t.Errorf("transition to %s: got %+v, want action %s", to, tr, action)
+			}
+			if tr != nil && !tr.CallerAllowed && tr.Reason == "" {
+				t.Errorf("transition to %s: expected a reason when the caller cannot trigger it", to)
+			}
+		}
+	}
+
+	expect(transitions(), map[pb.TaskStatus]string{
+		pb.TaskStatus_TASK_STATUS_RUNNING:   transitionActionUpdate,
+		pb.TaskStatus_TASK_STATUS_CANCELLED: transitionActionUpdate,
+		pb.TaskStatus_TASK_STATUS_SKIPPED:   transitionActionUpdate,
+	})
+
+	if _, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_RUNNING}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	expect(transitions(), map[pb.TaskStatus]string{
+		pb.TaskStatus_TASK_STATUS_SUCCEEDED:     transitionActionUpdate,
+		pb.TaskStatus_TASK_STATUS_FAILED:        transitionActionUpdate,
+		pb.TaskStatus_TASK_STATUS_TIMEOUT:       transitionActionUpdate,
+		pb.TaskStatus_TASK_STATUS_CANCELLED:     transitionActionUpdate,
+		pb.TaskStatus_TASK_STATUS_WAITING_INPUT: transitionActionSystem,
+	})
+
+	if err := repo.SuspendForInput(created.Id, "approve the deployment", "scheduler", "waiting for input", "", nil); err != nil {
+		t.Fatalf("SuspendForInput: %v", err)
+	}
+	expect(transitions(), map[pb.TaskStatus]string{
+		pb.TaskStatus_TASK_STATUS_PENDING:   transitionActionSignal,
+		pb.TaskStatus_TASK_STATUS_CANCELLED: transitionActionUpdate,
+	})
+
+	// 状态机中的终态只能通过重新运行回到 PENDING
+	if _, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_CANCELLED}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	expect(transitions(), map[pb.TaskStatus]string{
+		pb.TaskStatus_TASK_STATUS_PENDING: transitionActionRerun,
+	})
+}
+
+func TestTaskHandler_GetAllowedTransitionsRole(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	h.SetAccessControl([]string{"user-admin-to"}, true)
+	ctx := context.Background()
+
+	// 简化的令牌校验把 Bearer 令牌前 8 个字符作为用户 ID
+	call := func(token string, req interface{}, fn grpc.UnaryHandler) (interface{}, error) {
+		authCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		return grpc_middleware.UnaryAuthInterceptor(nil)(authCtx, req,
+			&grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/GetAllowedTransitions"}, fn)
+	}
+	create := func(token, teamID string) string {
+		resp, err := call(token, &pb.CreateTaskRequest{Name: "deploy", TeamId: teamID},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return h.CreateTask(ctx, req.(*pb.CreateTaskRequest))
+			})
+		if err != nil {
+			t.Fatalf("CreateTask as %s: %v", token, err)
+		}
+		return resp.(*pb.Task).Id
+	}
+	roleOf := func(token, taskID string) (string, error) {
+		resp, err := call(token, &pb.GetAllowedTransitionsRequest{TaskId: taskID},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return h.GetAllowedTransitions(ctx, req.(*pb.GetAllowedTransitionsRequest))
+			})
+		if err != nil {
+			return "", err
+		}
+		return resp.(*pb.AllowedTransitions).Role, nil
+	}
+
+	team, err := h.CreateTeam(ctx, &pb.CreateTeamRequest{Name: "ops", CreatedBy: "user-alice-to", Members: []string{"user-carol-to"}})
+	if err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	teamTask := create("alice-token", team.Id)
+	publicTask := create("alice-token", "")
+
+	cases := []struct {
+		token, taskID, want string
+	}{
+		{"alice-token", teamTask, callerRoleOwner},
+		{"carol-token", teamTask, callerRoleTeamMember},
+		{"admin-token", teamTask, callerRoleAdmin},
+		{"bob-token", publicTask, callerRolePublic},
+	}
+	for _, tc := range cases {
+		if role, err := roleOf(tc.token, tc.taskID); err != nil || role != tc.want {
+			t.Errorf("%s: role = %q, %v, want %q", tc.token, role, err, tc.want)
+		}
+	}
+	if _, err := roleOf("bob-token", teamTask); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for another team's task, got %v", err)
+	}
+}
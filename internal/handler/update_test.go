@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_UpdateTaskFieldMask(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{
+		Name: "original", Description: "keep", Priority: pb.TaskPriority_TASK_PRIORITY_LOW,
+		Labels: map[string]string{"env": "dev"},
+	})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// 只有掩码中的字段生效
+	updated, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{
+		Id:          created.Id,
+		UpdateMask:  &fieldmaskpb.FieldMask{Paths: []string{"name", "priority", "labels"}},
+		Name:        "renamed",
+		Description: "ignored",
+		Priority:    pb.TaskPriority_TASK_PRIORITY_HIGH,
+		Labels:      map[string]string{"env": "prod", "team": "ops"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if updated.Name != "renamed" || updated.Description != "keep" || updated.Priority != pb.TaskPriority_TASK_PRIORITY_HIGH ||
+		len(updated.Labels) != 2 || updated.Labels["env"] != "prod" {
+		t.Errorf("unexpected task after update: %+v", updated)
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.UpdateTaskRequest
+		want codes.Code
+	}{
+		{"unknown field", &pb.UpdateTaskRequest{Id: created.Id, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"retry_count"}}}, codes.InvalidArgument},
+		{"invalid value", &pb.UpdateTaskRequest{Id: created.Id, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}}}, codes.InvalidArgument},
+		{"combined with status", &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_CANCELLED,
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"description"}}}, codes.InvalidArgument},
+		{"missing task", &pb.UpdateTaskRequest{Id: "missing", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"description"}}}, codes.NotFound},
+	}
+	for _, tc := range cases {
+		if _, err := h.UpdateTask(ctx, tc.req); status.Code(err) != tc.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tc.name, status.Code(err), tc.want, err)
+		}
+	}
+
+	// 任务结束后不能再修改参数
+	if _, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_CANCELLED}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	_, err = h.UpdateTask(ctx, &pb.UpdateTaskRequest{
+		Id:          created.Id,
+		UpdateMask:  &fieldmaskpb.FieldMask{Paths: []string{"params"}},
+		InputParams: map[string]string{"k": "v"},
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition updating params of a cancelled task, got %v", err)
+	}
+	taskErr := errorcode.FromGRPCStatus(status.Convert(err))
+	if taskErr.Code != errorcode.ErrCodeTaskFieldNotEditable || len(taskErr.Violations) != 1 || taskErr.Violations[0].Field != "params" {
+		t.Errorf("unexpected error details: %+v", taskErr)
+	}
+}
+
+func TestTaskHandler_UpdateTaskResultFields(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "result"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// 结果字段只能随执行器上报的状态转换写入，被拒绝的请求不改变任务
+	rejected := []struct {
+		name   string
+		req    *pb.UpdateTaskRequest
+		fields []string
+	}{
+		{"output on pending task", &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_RUNNING,
+			OutputResult: map[string]string{"k": "v"}}, []string{"output_result"}},
+		{"retry count", &pb.UpdateTaskRequest{Id: created.Id, RetryCount: 3}, []string{"retry_count"}},
+	}
+	for _, tc := range rejected {
+		_, err := h.UpdateTask(ctx, tc.req)
+		taskErr := errorcode.FromGRPCStatus(status.Convert(err))
+		if status.Code(err) != codes.FailedPrecondition || taskErr.Code != errorcode.ErrCodeTaskFieldNotEditable ||
+			len(taskErr.Violations) != len(tc.fields) || taskErr.Violations[0].Field != tc.fields[0] {
+			t.Errorf("%s: unexpected error %v (%+v)", tc.name, err, taskErr)
+		}
+	}
+	if got, _ := h.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id}); got.Status != pb.TaskStatus_TASK_STATUS_PENDING || got.RetryCount != 0 {
+		t.Fatalf("rejected update changed the task: %+v", got)
+	}
+
+	if _, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_RUNNING}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	done, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_SUCCEEDED,
+		OutputResult: map[string]string{"result": "ok"}})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if done.OutputResult["result"] != "ok" {
+		t.Errorf("output not stored: %+v", done)
+	}
+
+	// 结束后的结果不能再改写
+	_, err = h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, OutputResult: map[string]string{"result": "forged"}})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition rewriting output of a finished task, got %v", err)
+	}
+}
+
+func TestTaskHandler_ExpectedVersion(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "versioned"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if created.Version != 1 {
+		t.Fatalf("expected version 1 on create, got %d", created.Version)
+	}
+
+	// 返回的版本号与之后读取的一致，可以直接用于下一次更新
+	renamed, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, ExpectedVersion: created.Version,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}}, Name: "renamed"})
+	if err != nil {
+		t.Fatalf("UpdateTask with the current version: %v", err)
+	}
+	started, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, ExpectedVersion: renamed.Version,
+		Status: pb.TaskStatus_TASK_STATUS_RUNNING})
+	if err != nil {
+		t.Fatalf("UpdateTask status with the current version: %v", err)
+	}
+	if got, _ := h.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id}); got.Version != started.Version || started.Version != 3 {
+		t.Errorf("returned version %d, stored version %d, want 3", started.Version, got.Version)
+	}
+
+	// 过期的版本号被拒绝，任务不变
+	_, err = h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, ExpectedVersion: renamed.Version,
+		Status: pb.TaskStatus_TASK_STATUS_CANCELLED})
+	if taskErr := errorcode.FromGRPCStatus(status.Convert(err)); status.Code(err) != codes.FailedPrecondition ||
+		taskErr.Code != errorcode.ErrCodePreconditionFailed {
+		t.Errorf("expected PRECONDITION_FAILED for a stale version, got %v", err)
+	}
+	if got, _ := h.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id}); got.Status != pb.TaskStatus_TASK_STATUS_RUNNING {
+		t.Errorf("rejected update changed the task: %s", got.Status)
+	}
+
+	done, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_SUCCEEDED,
+		OutputResult: map[string]string{"result": "ok"}})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if _, err := h.RerunTask(ctx, &pb.RerunTaskRequest{Id: created.Id, ExpectedVersion: started.Version}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition rerunning with a stale version, got %v", err)
+	}
+	rerun, err := h.RerunTask(ctx, &pb.RerunTaskRequest{Id: created.Id, ExpectedVersion: done.Version})
+	if err != nil {
+		t.Fatalf("RerunTask with the current version: %v", err)
+	}
+	if rerun.Version <= done.Version {
+		t.Errorf("expected the rerun to advance the version past %d, got %d", done.Version, rerun.Version)
+	}
+}
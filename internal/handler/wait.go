@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/model"
+	pb "taskflow/proto"
+)
+
+// GetTask 长轮询的等待时长
+const (
+	defaultTaskWait = 30 * time.Second
+	maxTaskWait     = 5 * time.Minute
+)
+
+// taskWaitMargin 请求带截止时间（gRPC deadline、HTTP 请求超时）时预留的时间，保证等待超时后仍来得及返回任务
+const taskWaitMargin = 500 * time.Millisecond
+
+// taskWaitParams 校验 GetTask 的 wait_for 和 timeout_ms，返回要等待的状态和最长等待时长
+func taskWaitParams(req *pb.GetTaskRequest) ([]model.TaskStatus, time.Duration, error) {
+	verr := errorcode.NewValidationError()
+	var statuses []model.TaskStatus
+	for _, s := range req.WaitFor {
+		status := model.TaskStatus(s)
+		if status <= model.TaskStatusUnspecified || status > model.TaskStatusWaitingInput {
+			verr.Add("wait_for", "enum", fmt.Sprintf("unknown task status %d", s))
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	switch {
+	case req.TimeoutMs < 0:
+		verr.Add("timeout_ms", "min", "must be greater than or equal to 0")
+	case req.TimeoutMs > 0 && len(req.WaitFor) == 0:
+		verr.Add("timeout_ms", "requires", "only applies together with wait_for")
+	}
+	if verr.HasErrors() {
+		return nil, 0, verr.ToGRPCStatus().Err()
+	}
+
+	return statuses, taskWaitTimeout(req.TimeoutMs), nil
+}
+
+// taskWaitTimeout 把请求的等待毫秒数转换为等待时长，未设置时为默认值，超过上限时按上限
+func taskWaitTimeout(timeoutMs int64) time.Duration {
+	if timeoutMs <= 0 {
+		return defaultTaskWait
+	}
+	return min(time.Duration(timeoutMs)*time.Millisecond, maxTaskWait)
+}
+
+// syncExecutionEndStatuses ExecuteTaskSync 停止等待的状态：任务的全部终态，以及需要调用方送达输入的 WAITING_INPUT
+var syncExecutionEndStatuses = []model.TaskStatus{
+	model.TaskStatusSucceeded, model.TaskStatusFailed, model.TaskStatusCancelled, model.TaskStatusTimeout, model.TaskStatusSkipped,
+	model.TaskStatusWaitingInput,
+}
+
+// ExecuteTaskSync 创建任务并等待其进入终态，任务的输出随响应返回。等待超时或任务进入 WAITING_INPUT 时
+// 任务继续保留，响应的 completed 为 false，调用方可送达输入或用 GetTask 的 wait_for 继续等待
+func (h *TaskHandler) ExecuteTaskSync(ctx context.Context, req *pb.ExecuteTaskSyncRequest) (*pb.ExecuteTaskSyncResponse, error) {
+	if req.Task == nil {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "task is required").ToGRPCStatus().Err()
+	}
+	if req.TimeoutMs < 0 {
+		return nil, errorcode.NewValidationError(errorcode.NewFieldViolation("timeout_ms", "min",
+			"must be greater than or equal to 0")).ToGRPCStatus().Err()
+	}
+
+	created, err := h.CreateTask(ctx, req.Task)
+	if err != nil {
+		return nil, err
+	}
+	task, err := h.waitForTaskStatus(ctx, created.Id, syncExecutionEndStatuses, taskWaitTimeout(req.TimeoutMs))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ExecuteTaskSyncResponse{
+		Task:      h.toPBTask(task, false),
+		Completed: task.Status.IsTerminal(),
+	}, nil
+}
+
+// waitForTaskStatus 等待任务进入 statuses 中的任一状态，超时后返回任务的当前状态。
+// 本实例的变更通知用于及时唤醒，其他实例（如独立部署的调度器）写入的变更由轮询发现
+func (h *TaskHandler) waitForTaskStatus(ctx context.Context, id string, statuses []model.TaskStatus, timeout time.Duration) (*model.Task, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline)-taskWaitMargin)
+	}
+
+	// 先订阅再读取，避免读取与订阅之间的变更只能等到下一次轮询
+	ch := h.subscribe(id)
+	defer h.unsubscribe(id, ch)
+
+	task, err := h.getAccessibleTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	for !slices.Contains(statuses, task.Status) {
+		select {
+		case <-ctx.Done():
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeTimeout, ctx.Err().Error()).ToGRPCStatus().Err()
+		case <-timer.C:
+			return task, nil
+		case <-ticker.C:
+		case <-ch:
+		}
+		if task, err = h.getAccessibleTask(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
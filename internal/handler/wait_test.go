@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestTaskHandler_GetTaskWaitFor(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	created, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "wait"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// 已处于目标状态时立即返回
+	task, err := h.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id, WaitFor: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_PENDING}})
+	if err != nil || task.Status != pb.TaskStatus_TASK_STATUS_PENDING {
+		t.Fatalf("GetTask already in status: %v, %v", task, err)
+	}
+
+	// 超时后返回当前状态
+	start := time.Now()
+	task, err = h.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id,
+		WaitFor: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_SUCCEEDED}, TimeoutMs: 50})
+	if err != nil || task.Status != pb.TaskStatus_TASK_STATUS_PENDING {
+		t.Fatalf("GetTask after timeout: %v, %v", task, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("GetTask returned after %v, before the timeout", elapsed)
+	}
+
+	// 请求的截止时间早于 timeout_ms 时按截止时间提前返回
+	deadlineCtx, cancel := context.WithTimeout(ctx, taskWaitMargin+100*time.Millisecond)
+	defer cancel()
+	task, err = h.GetTask(deadlineCtx, &pb.GetTaskRequest{Id: created.Id,
+		WaitFor: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_SUCCEEDED}, TimeoutMs: 60000})
+	if err != nil || task.Status != pb.TaskStatus_TASK_STATUS_PENDING {
+		t.Fatalf("GetTask with a request deadline: %v, %v", task, err)
+	}
+
+	// 状态变更后返回
+	done := make(chan *pb.Task, 1)
+	go func() {
+		task, err := h.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id,
+			WaitFor: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_SUCCEEDED, pb.TaskStatus_TASK_STATUS_FAILED}, TimeoutMs: 10000})
+		if err != nil {
+			t.Errorf("GetTask waiting: %v", err)
+		}
+		done <- task
+	}()
+	for _, s := range []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_RUNNING, pb.TaskStatus_TASK_STATUS_FAILED} {
+		if _, err := h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: s}); err != nil {
+			t.Fatalf("UpdateTask %s: %v", s, err)
+		}
+	}
+	select {
+	case task := <-done:
+		if task == nil || task.Status != pb.TaskStatus_TASK_STATUS_FAILED {
+			t.Errorf("GetTask waiting returned %v", task)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetTask did not return after the task failed")
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.GetTaskRequest
+		want codes.Code
+	}{
+		{"unknown status", &pb.GetTaskRequest{Id: created.Id, WaitFor: []pb.TaskStatus{99}}, codes.InvalidArgument},
+		{"negative timeout", &pb.GetTaskRequest{Id: created.Id, WaitFor: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_SUCCEEDED}, TimeoutMs: -1}, codes.InvalidArgument},
+		{"timeout without wait_for", &pb.GetTaskRequest{Id: created.Id, TimeoutMs: 1000}, codes.InvalidArgument},
+		{"missing task", &pb.GetTaskRequest{Id: "missing", WaitFor: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_SUCCEEDED}}, codes.NotFound},
+	}
+	for _, tc := range cases {
+		if _, err := h.GetTask(ctx, tc.req); status.Code(err) != tc.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tc.name, status.Code(err), tc.want, err)
+		}
+	}
+}
+
+func TestTaskHandler_ExecuteTaskSync(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	ctx := context.Background()
+
+	// 模拟调度器：执行新创建的任务并写入输出
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			tasks, _, _ := repo.ListByFilter(repository.TaskFilter{PageSize: 10, Statuses: []model.TaskStatus{model.TaskStatusPending}})
+			for _, task := range tasks {
+				h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: task.ID, Status: pb.TaskStatus_TASK_STATUS_RUNNING})
+				h.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: task.ID, Status: pb.TaskStatus_TASK_STATUS_SUCCEEDED,
+					OutputResult: map[string]string{"answer": "42"}})
+			}
+		}
+	}()
+
+	resp, err := h.ExecuteTaskSync(ctx, &pb.ExecuteTaskSyncRequest{Task: &pb.CreateTaskRequest{Name: "quick"}, TimeoutMs: 5000})
+	if err != nil {
+		t.Fatalf("ExecuteTaskSync: %v", err)
+	}
+	if !resp.Completed || resp.Task.Status != pb.TaskStatus_TASK_STATUS_SUCCEEDED || resp.Task.OutputResult["answer"] != "42" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.ExecuteTaskSyncRequest
+		want codes.Code
+	}{
+		{"missing task", &pb.ExecuteTaskSyncRequest{}, codes.InvalidArgument},
+		{"negative timeout", &pb.ExecuteTaskSyncRequest{Task: &pb.CreateTaskRequest{Name: "quick"}, TimeoutMs: -1}, codes.InvalidArgument},
+		{"invalid task", &pb.ExecuteTaskSyncRequest{Task: &pb.CreateTaskRequest{}}, codes.InvalidArgument},
+	}
+	for _, tc := range cases {
+		if _, err := h.ExecuteTaskSync(ctx, tc.req); status.Code(err) != tc.want {
+			t.Errorf("%s: code = %s, want %s (%v)", tc.name, status.Code(err), tc.want, err)
+		}
+	}
+}
+
+func TestTaskHandler_ExecuteTaskSyncTimeout(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+
+	// 没有调度器执行任务，等待超时后返回仍在等待的任务
+	resp, err := h.ExecuteTaskSync(context.Background(), &pb.ExecuteTaskSyncRequest{Task: &pb.CreateTaskRequest{Name: "slow"}, TimeoutMs: 50})
+	if err != nil {
+		t.Fatalf("ExecuteTaskSync: %v", err)
+	}
+	if resp.Completed || resp.Task.Status != pb.TaskStatus_TASK_STATUS_PENDING {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if _, err := repo.GetByID(resp.Task.Id); err != nil {
+		t.Errorf("task was not kept after the timeout: %v", err)
+	}
+}
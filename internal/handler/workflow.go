@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+const (
+	// maxCancelWorkflowTasks 取消工作流时最多处理的任务数，超过时只取消先找到的任务并标记 truncated
+	maxCancelWorkflowTasks = 10000
+	// maxCancelReasonLength 取消工作流原因的最大字符数
+	maxCancelReasonLength = 1024
+)
+
+// workflowDeadline 计算任务的工作流截止时间：workflow_deadline 为绝对时间，workflow_timeout_seconds 相对创建时间，
+// 规则同 slaDeadline
+func workflowDeadline(createdAt time.Time, deadline, seconds int64) *time.Time {
+	return slaDeadline(createdAt, deadline, seconds)
+}
+
+// validateWorkflowDeadline 校验工作流截止时间：两种写法二选一，取值不能为负
+func validateWorkflowDeadline(verr *errorcode.ValidationError, deadline, seconds int64) {
+	if deadline < 0 {
+		verr.Add("workflow_deadline", "gte", "must be greater than or equal to 0")
+	}
+	if seconds < 0 {
+		verr.Add("workflow_timeout_seconds", "gte", "must be greater than or equal to 0")
+	}
+	if deadline > 0 && seconds > 0 {
+		verr.Add("workflow_timeout_seconds", "excluded_with", "must not be set together with workflow_deadline")
+	}
+}
+
+// inheritWorkflowDeadline 依赖的上游所属工作流尚未到截止时间时，任务加入该工作流，截止时间取其中最早的一个
+func (h *TaskHandler) inheritWorkflowDeadline(task *model.Task) error {
+	if len(task.Dependencies) == 0 {
+		return nil
+	}
+	upstream, err := h.repo.GetByIDs(task.Dependencies)
+	if err != nil {
+		return err
+	}
+	for _, dep := range upstream {
+		if dep != nil && dep.WorkflowDeadline != nil && dep.WorkflowDeadline.After(task.CreatedAt) {
+			task.InheritWorkflowDeadline(dep.WorkflowDeadline)
+		}
+	}
+	return nil
+}
+
+// CancelWorkflow 取消工作流中全部未结束（PENDING、RUNNING、WAITING_INPUT）的任务，工作流的范围同 GetTaskTimeline，
+// 调用者无权访问的任务不取消。运行中的任务先取消执行，每个任务的取消记录为事件，包含操作者、原因和请求 ID
+func (h *TaskHandler) CancelWorkflow(ctx context.Context, req *pb.CancelWorkflowRequest) (*pb.CancelWorkflowResponse, error) {
+	if (req.TaskId == "") == (req.CorrelationId == "") {
+		return nil, errorcode.NewTaskError(errorcode.ErrCodeInvalidParam, "exactly one of task_id and correlation_id is required").ToGRPCStatus().Err()
+	}
+	if n := utf8.RuneCountInString(req.Reason); n > maxCancelReasonLength {
+		return nil, errorcode.NewValidationError(errorcode.NewFieldViolation("reason", "max",
+			fmt.Sprintf("has %d characters, at most %d allowed", n, maxCancelReasonLength))).ToGRPCStatus().Err()
+	}
+
+	tasks, truncated, err := h.workflowTasks(ctx, req.TaskId, req.CorrelationId, maxCancelWorkflowTasks)
+	if err != nil {
+		return nil, err
+	}
+
+	operator := grpc_middleware.GetUserID(ctx)
+	if operator == "" {
+		operator = "system"
+	}
+	requestID := grpc_middleware.GetRequestID(ctx)
+	message := model.WorkflowCancelledMessage(req.Reason)
+
+	resp := &pb.CancelWorkflowResponse{Truncated: truncated}
+	for _, task := range tasks {
+		from, cancelled, err := h.cancelWorkflowTask(task, operator, message, requestID)
+		if err != nil {
+			logger.Errorf("Handler error: %v", err)
+			return nil, errorcode.NewTaskError(errorcode.ErrCodeDBError, err.Error()).ToGRPCStatus().Err()
+		}
+		if !cancelled {
+			continue
+		}
+		resp.Cancelled = append(resp.Cancelled, task.ID)
+		h.broadcastCorrelatedTaskChange(task.ID, task, from, task.Status, "status_changed", requestID)
+		h.notifier.NotifyTaskChange(task, from, task.Status)
+	}
+
+	if len(resp.Cancelled) > 0 {
+		logger.Infof("Workflow of %s%s cancelled by %s: %d tasks", req.TaskId, req.CorrelationId, operator, len(resp.Cancelled))
+		// 其他工作流中依赖被取消任务的下游需要重新评估
+		h.wakeScheduler()
+	}
+	return resp, nil
+}
+
+// cancelWorkflowTask 取消工作流中的一个任务，返回取消前的状态。状态被并发修改（如调度器开始执行）时重新读取后再试一次，
+// 任务已结束或仍然冲突时不取消
+func (h *TaskHandler) cancelWorkflowTask(task *model.Task, operator, message, requestID string) (model.TaskStatus, bool, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if task.IsTerminal() {
+			return task.Status, false, nil
+		}
+		from := task.Status
+		// 运行中的任务先通知执行器取消，等其清理完再记录 CANCELLED
+		if from == model.TaskStatusRunning && h.scheduler != nil {
+			h.scheduler.CancelExecution(task.ID)
+		}
+		err := h.repo.UpdateStatusWithCorrelatedEvent(task.ID, from, model.TaskStatusCancelled, operator, message, requestID)
+		if err == nil {
+			task.Status = model.TaskStatusCancelled
+			task.UpdatedAt = model.Now()
+			task.Version++
+			return from, true, nil
+		}
+		if !errors.Is(err, repository.ErrStatusMismatch) {
+			return from, false, err
+		}
+
+		latest, err := h.repo.GetByID(task.ID)
+		if errors.Is(err, repository.ErrTaskNotFound) {
+			return from, false, nil
+		}
+		if err != nil {
+			return from, false, err
+		}
+		*task = *latest
+	}
+	return task.Status, false, nil
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+// cancellingScheduler 记录被取消执行的任务
+type cancellingScheduler struct {
+	SchedulerControl
+	cancelled []string
+}
+
+func (s *cancellingScheduler) CancelExecution(taskID string) bool {
+	s.cancelled = append(s.cancelled, taskID)
+	return true
+}
+
+func TestTaskHandler_WorkflowDeadline(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+
+	create := func(requestID string, req *pb.CreateTaskRequest) *pb.Task {
+		t.Helper()
+		task, err := h.CreateTask(grpc_middleware.ContextWithRequestID(context.Background(), requestID), req)
+		if err != nil {
+			t.Fatalf("CreateTask(%s): %v", req.Name, err)
+		}
+		return task
+	}
+
+	extract := create("req-1", &pb.CreateTaskRequest{Name: "extract", WorkflowTimeoutSeconds: 3600})
+	if want := extract.CreatedAt + 3600; extract.WorkflowDeadline != want {
+		t.Fatalf("workflow_deadline = %d, want %d", extract.WorkflowDeadline, want)
+	}
+
+	// 依赖工作流中任务的下游加入该工作流，已声明更早截止时间的保留自己的
+	report := create("req-2", &pb.CreateTaskRequest{Name: "report", Dependencies: []string{extract.Id}})
+	if report.WorkflowDeadline != extract.WorkflowDeadline {
+		t.Errorf("expected report to inherit %d, got %d", extract.WorkflowDeadline, report.WorkflowDeadline)
+	}
+	earlier := time.Now().Add(time.Minute).Unix()
+	urgent := create("req-2", &pb.CreateTaskRequest{Name: "urgent", Dependencies: []string{extract.Id}, WorkflowDeadline: earlier})
+	if urgent.WorkflowDeadline != earlier {
+		t.Errorf("expected urgent to keep %d, got %d", earlier, urgent.WorkflowDeadline)
+	}
+	if plain := create("req-3", &pb.CreateTaskRequest{Name: "plain"}); plain.WorkflowDeadline != 0 {
+		t.Errorf("expected no workflow deadline, got %d", plain.WorkflowDeadline)
+	}
+
+	for _, req := range []*pb.CreateTaskRequest{
+		{Name: "both", WorkflowDeadline: earlier, WorkflowTimeoutSeconds: 60},
+		{Name: "negative", WorkflowTimeoutSeconds: -1},
+	} {
+		if _, err := h.CreateTask(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("CreateTask(%s): expected InvalidArgument, got %v", req.Name, err)
+		}
+	}
+}
+
+func TestTaskHandler_CancelWorkflow(t *testing.T) {
+	repo, teams := repository.NewMemoryRepositories()
+	h := NewTaskHandler(repo, teams)
+	sched := &cancellingScheduler{}
+	h.SetSchedulerControl(sched)
+
+	base := time.Now().Add(-time.Hour)
+	create := func(id, correlationID string, deps []string, s model.TaskStatus, created int) {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "etl", nil, deps, 0, "alice")
+		task.ID, task.Status, task.CorrelationID = id, s, correlationID
+		task.CreatedAt = base.Add(time.Duration(created) * time.Second)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	create("extract", "req-1", nil, model.TaskStatusRunning, 0)
+	create("audit", "req-1", []string{"extract"}, model.TaskStatusSucceeded, 1)
+	create("transform", "req-1", []string{"extract"}, model.TaskStatusWaitingInput, 2)
+	create("report", "req-2", []string{"transform"}, model.TaskStatusPending, 3)
+	create("cleanup", "req-1", nil, model.TaskStatusPending, 4)
+
+	events := make(chan *pb.TaskChangeEvent, 10)
+	h.watchersMu.Lock()
+	h.watchers[""] = append(h.watchers[""], events)
+	h.watchersMu.Unlock()
+
+	// 依赖闭包：取消相连的全部未结束任务，已结束的和只属于同一请求的任务不受影响
+	ctx := grpc_middleware.ContextWithRequestID(context.Background(), "req-cancel")
+	resp, err := h.CancelWorkflow(ctx, &pb.CancelWorkflowRequest{TaskId: "report", Reason: "bad input"})
+	if err != nil {
+		t.Fatalf("CancelWorkflow: %v", err)
+	}
+	slices.Sort(resp.Cancelled)
+	if !slices.Equal(resp.Cancelled, []string{"extract", "report", "transform"}) || resp.Truncated {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+	if strings.Join(sched.cancelled, ",") != "extract" {
+		t.Errorf("expected only the running task's execution cancelled, got %v", sched.cancelled)
+	}
+	for id, want := range map[string]model.TaskStatus{
+		"extract":   model.TaskStatusCancelled,
+		"audit":     model.TaskStatusSucceeded,
+		"transform": model.TaskStatusCancelled,
+		"report":    model.TaskStatusCancelled,
+		"cleanup":   model.TaskStatusPending,
+	} {
+		if task, _ := repo.GetByID(id); task.Status != want {
+			t.Errorf("%s: status = %s, want %s", id, task.Status, want)
+		}
+	}
+	task, _ := repo.GetByID("transform")
+	last := task.Events[len(task.Events)-1]
+	if last.FromStatus != model.TaskStatusWaitingInput || last.Message != "workflow cancelled: bad input" ||
+		last.Operator != "system" || last.CorrelationID != "req-cancel" {
+		t.Errorf("unexpected cancel event: %+v", last)
+	}
+	for range resp.Cancelled {
+		select {
+		case event := <-events:
+			if event.ToStatus != pb.TaskStatus_TASK_STATUS_CANCELLED || event.CorrelationId != "req-cancel" {
+				t.Errorf("unexpected change event: %v", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for change events")
+		}
+	}
+
+	// 同一请求创建的任务
+	resp, err = h.CancelWorkflow(context.Background(), &pb.CancelWorkflowRequest{CorrelationId: "req-1"})
+	if err != nil {
+		t.Fatalf("CancelWorkflow: %v", err)
+	}
+	if strings.Join(resp.Cancelled, ",") != "cleanup" {
+		t.Errorf("expected only cleanup left to cancel, got %v", resp.Cancelled)
+	}
+	if task, _ := repo.GetByID("cleanup"); task.Events[len(task.Events)-1].Message != "workflow cancelled" {
+		t.Errorf("unexpected cancel event: %+v", task.Events[len(task.Events)-1])
+	}
+
+	for _, req := range []*pb.CancelWorkflowRequest{
+		{},
+		{TaskId: "extract", CorrelationId: "req-1"},
+		{TaskId: "extract", Reason: strings.Repeat("x", maxCancelReasonLength+1)},
+	} {
+		if _, err := h.CancelWorkflow(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("CancelWorkflow(%v): expected InvalidArgument, got %v", req, err)
+		}
+	}
+	if _, err := h.CancelWorkflow(context.Background(), &pb.CancelWorkflowRequest{TaskId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package idgen 任务和事件的 ID 生成器。默认生成 UUIDv4；ULID 和 snowflake 的 ID 按生成时间递增，
+// 写入时主键索引局部性更好，按 ID 排序即按创建顺序排序。已有的 UUID 仍然是合法 ID，切换生成器不需要迁移数据
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 支持的生成器
+const (
+	KindUUID      = "uuid"
+	KindULID      = "ulid"
+	KindSnowflake = "snowflake"
+)
+
+// Generator ID 生成器，实现需并发安全
+type Generator interface {
+	NewID() string
+}
+
+// New 按名称创建生成器，nodeID 只用于 snowflake，取值 0 到 MaxNodeID，多个实例需各不相同
+func New(kind string, nodeID int64) (Generator, error) {
+	switch kind {
+	case "", KindUUID:
+		return UUID{}, nil
+	case KindULID:
+		return NewULID(), nil
+	case KindSnowflake:
+		return NewSnowflake(nodeID)
+	default:
+		return nil, fmt.Errorf("unknown id generator %q, must be one of uuid, ulid, snowflake", kind)
+	}
+}
+
+var (
+	defaultMu  sync.RWMutex
+	defaultGen Generator = UUID{}
+)
+
+// SetDefault 设置 NewID 使用的生成器，服务启动时按配置调用一次
+func SetDefault(g Generator) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultGen = g
+}
+
+// NewID 使用默认生成器生成 ID
+func NewID() string {
+	defaultMu.RLock()
+	g := defaultGen
+	defaultMu.RUnlock()
+	return g.NewID()
+}
+
+// UUID 随机 UUIDv4，不按时间排序
+type UUID struct{}
+
+// NewID 生成 UUIDv4
+func (UUID) NewID() string {
+	return uuid.New().String()
+}
+
+// crockford ULID 使用的 Crockford Base32 字母表，字典序与数值顺序一致
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID 26 位的 ULID：48 位毫秒时间戳加 80 位随机数。同一毫秒内随机部分递增，
+// 同一生成器生成的 ID 严格递增；时钟回拨时沿用上一个时间戳
+type ULID struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	rand    io.Reader
+	lastMs  uint64
+	lastHi  uint16 // 随机部分高 16 位
+	lastLow uint64 // 随机部分低 64 位
+}
+
+// NewULID 创建 ULID 生成器
+func NewULID() *ULID {
+	return &ULID{now: time.Now, rand: rand.Reader}
+}
+
+// NewID 生成 ULID
+func (g *ULID) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms <= g.lastMs {
+		// 同一毫秒（或时钟回拨）内随机部分加一，溢出时进位到时间戳
+		ms = g.lastMs
+		g.lastLow++
+		if g.lastLow == 0 {
+			g.lastHi++
+			if g.lastHi == 0 {
+				ms++
+			}
+		}
+	} else {
+		var entropy [10]byte
+		if _, err := io.ReadFull(g.rand, entropy[:]); err != nil {
+			panic(fmt.Sprintf("idgen: read random: %v", err))
+		}
+		g.lastHi = binary.BigEndian.Uint16(entropy[:2])
+		g.lastLow = binary.BigEndian.Uint64(entropy[2:])
+	}
+	g.lastMs = ms
+
+	// 128 位：高 64 位为 48 位时间戳和随机部分高 16 位，低 64 位为随机部分其余位
+	hi := ms<<16 | uint64(g.lastHi)
+	lo := g.lastLow
+	var out [26]byte
+	// 从低位起每 5 位编码一个字符，最高位字符只有 3 位
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// snowflake 位布局：41 位毫秒时间戳（相对 snowflakeEpoch）、10 位节点、12 位序号
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+
+	// MaxNodeID snowflake 节点 ID 上限
+	MaxNodeID   = 1<<nodeBits - 1
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// snowflakeEpoch snowflake 时间戳的起点，41 位毫秒可用到 2089 年
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake 带节点 ID 的 snowflake 生成器，ID 以 19 位补零的十进制字符串表示，字典序与数值顺序一致。
+// 每个节点每毫秒最多生成 4096 个 ID，超过时等待下一毫秒；时钟回拨时沿用上一个时间戳
+type Snowflake struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	node     int64
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflake 创建节点 ID 为 node 的 snowflake 生成器
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxNodeID {
+		return nil, fmt.Errorf("snowflake node id must be between 0 and %d, got %d", MaxNodeID, node)
+	}
+	return &Snowflake{now: time.Now, node: node}, nil
+}
+
+// NewID 生成 snowflake ID
+func (g *Snowflake) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.now().Sub(snowflakeEpoch).Milliseconds()
+	if ms <= g.lastMs {
+		ms = g.lastMs
+		g.sequence = (g.sequence + 1) & maxSequence
+		if g.sequence == 0 {
+			// 本毫秒序号用完，等到下一毫秒
+			for ms <= g.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				ms = g.now().Sub(snowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = ms
+
+	id := ms<<(nodeBits+sequenceBits) | g.node<<sequenceBits | g.sequence
+	s := strconv.FormatInt(id, 10)
+	return "0000000000000000000"[len(s):] + s
+}
@@ -0,0 +1,116 @@
+package idgen
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestULID_SortsByTime(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	g := NewULID()
+	g.now = func() time.Time { return now }
+	g.rand = bytes.NewReader(bytes.Repeat([]byte{0xff, 0x00, 0x7f}, 100))
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		ids = append(ids, g.NewID()) // 同一毫秒内随机部分递增
+	}
+	now = now.Add(time.Millisecond)
+	ids = append(ids, g.NewID())
+	now = now.Add(-time.Second)
+	ids = append(ids, g.NewID()) // 时钟回拨
+
+	for i, id := range ids {
+		if len(id) != 26 {
+			t.Fatalf("expected 26-char ULID, got %q", id)
+		}
+		if i > 0 && id <= ids[i-1] {
+			t.Errorf("expected ids to increase, got %q after %q", id, ids[i-1])
+		}
+	}
+	// 前 10 个字符是毫秒时间戳
+	if ids[0][:10] != "01HF7YAT00" {
+		t.Errorf("unexpected timestamp prefix %q", ids[0][:10])
+	}
+}
+
+func TestULID_CarriesRandomOverflow(t *testing.T) {
+	g := NewULID()
+	g.now = func() time.Time { return time.UnixMilli(1700000000000) }
+	g.rand = bytes.NewReader(bytes.Repeat([]byte{0xff}, 10))
+
+	first := g.NewID()
+	second := g.NewID()
+	if first[:10] != "01HF7YAT00" || second[:10] != "01HF7YAT01" || second <= first {
+		t.Errorf("expected overflow to carry into the timestamp, got %q then %q", first, second)
+	}
+}
+
+func TestSnowflake_SortsByTimeAndEncodesNode(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	g, err := NewSnowflake(42)
+	if err != nil {
+		t.Fatalf("NewSnowflake: %v", err)
+	}
+	g.now = func() time.Time { return now }
+
+	var ids []string
+	for i := 0; i < maxSequence+2; i++ {
+		if i == maxSequence+1 {
+			// 本毫秒序号用完后等到下一毫秒
+			now = now.Add(time.Millisecond)
+		}
+		ids = append(ids, g.NewID())
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Fatal("expected snowflake ids to sort by generation order")
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if len(id) != 19 {
+			t.Fatalf("expected 19-digit id, got %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id %q", id)
+		}
+		seen[id] = true
+	}
+
+	n, _ := strconv.ParseInt(ids[0], 10, 64)
+	if node := n >> sequenceBits & MaxNodeID; node != 42 {
+		t.Errorf("expected node 42, got %d", node)
+	}
+	if ms := n >> (nodeBits + sequenceBits); ms != now.Add(-time.Millisecond).Sub(snowflakeEpoch).Milliseconds() {
+		t.Errorf("unexpected timestamp %d", ms)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, kind := range []string{"", KindUUID, KindULID, KindSnowflake} {
+		if _, err := New(kind, 1); err != nil {
+			t.Errorf("New(%q): %v", kind, err)
+		}
+	}
+	if _, err := New("sequence", 0); err == nil {
+		t.Error("expected error for unknown generator")
+	}
+	if _, err := New(KindSnowflake, MaxNodeID+1); err == nil {
+		t.Error("expected error for out-of-range node id")
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	if _, err := uuid.Parse(NewID()); err != nil {
+		t.Fatalf("expected UUID by default: %v", err)
+	}
+	SetDefault(NewULID())
+	defer SetDefault(UUID{})
+	if id := NewID(); len(id) != 26 {
+		t.Errorf("expected ULID after SetDefault, got %q", id)
+	}
+}
@@ -0,0 +1,774 @@
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/redact"
+	pb "taskflow/proto"
+)
+
+// TestGetTasks_PreservesOrderAndMarksMissing 批量获取保持请求顺序并标记不存在的 ID
+func TestGetTasks_PreservesOrderAndMarksMissing(t *testing.T) {
+	stack := newTestStack(t)
+
+	first := stack.createTask(t, &pb.CreateTaskRequest{Name: "first", TaskType: taskTypeGated})
+	second := stack.createTask(t, &pb.CreateTaskRequest{Name: "second", TaskType: taskTypeGated})
+
+	resp, err := stack.client.GetTasks(context.Background(), &pb.GetTasksRequest{
+		Ids: []string{second.Id, "missing-id", first.Id},
+	})
+	if err != nil {
+		t.Fatalf("GetTasks failed: %v", err)
+	}
+
+	if len(resp.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(resp.Results))
+	}
+	if r := resp.Results[0]; !r.Found || r.Task.GetName() != "second" {
+		t.Errorf("Expected second task first, got %v", r)
+	}
+	if r := resp.Results[1]; r.Found || r.Id != "missing-id" || r.Task != nil {
+		t.Errorf("Expected missing marker, got %v", r)
+	}
+	if r := resp.Results[2]; !r.Found || r.Task.GetName() != "first" {
+		t.Errorf("Expected first task last, got %v", r)
+	}
+	if len(resp.MissingIds) != 1 || resp.MissingIds[0] != "missing-id" {
+		t.Errorf("Expected missing_ids [missing-id], got %v", resp.MissingIds)
+	}
+
+	// 空请求返回参数错误
+	_, err = stack.client.GetTasks(context.Background(), &pb.GetTasksRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for empty ids, got %v", err)
+	}
+}
+
+// TestTaskComments_AddAndList 评论按时间顺序返回，并随 include_events 一起导出
+func TestTaskComments_AddAndList(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{
+		Name:        "annotated",
+		TaskType:    taskTypeEcho,
+		InputParams: map[string]string{"fail_times": "1"},
+	})
+	stack.waitForStatus(t, created.Id, pb.TaskStatus_TASK_STATUS_FAILED)
+
+	for _, body := range []string{"paged oncall", "  caused by upstream outage  "} {
+		if _, err := stack.client.AddTaskComment(ctx, &pb.AddTaskCommentRequest{
+			TaskId: created.Id,
+			Author: "oncall",
+			Body:   body,
+		}); err != nil {
+			t.Fatalf("AddTaskComment failed: %v", err)
+		}
+	}
+
+	resp, err := stack.client.ListTaskComments(ctx, &pb.ListTaskCommentsRequest{TaskId: created.Id})
+	if err != nil {
+		t.Fatalf("ListTaskComments failed: %v", err)
+	}
+	if len(resp.Comments) != 2 {
+		t.Fatalf("Expected 2 comments, got %d", len(resp.Comments))
+	}
+	if resp.Comments[0].Body != "paged oncall" || resp.Comments[1].Body != "caused by upstream outage" {
+		t.Errorf("Unexpected comment order or body: %v", resp.Comments)
+	}
+
+	// include_events 时评论与事件一起返回
+	task, err := stack.client.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id, IncludeEvents: true})
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	if len(task.Comments) != 2 || len(task.Events) == 0 {
+		t.Errorf("Expected comments and events, got %d comments, %d events", len(task.Comments), len(task.Events))
+	}
+
+	// 空评论与不存在的任务
+	if _, err := stack.client.AddTaskComment(ctx, &pb.AddTaskCommentRequest{TaskId: created.Id, Body: "   "}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for empty body, got %v", err)
+	}
+	if _, err := stack.client.AddTaskComment(ctx, &pb.AddTaskCommentRequest{TaskId: "missing-id", Body: "x"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for missing task, got %v", err)
+	}
+}
+
+// TestAttachments_Lifecycle 附件上传、列出、下载、删除及大小/类型限制
+func TestAttachments_Lifecycle(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{Name: "with-artifacts", TaskType: taskTypeGated})
+
+	content := []byte("line 1\nline 2\n")
+	uploaded, err := stack.client.UploadAttachment(ctx, &pb.UploadAttachmentRequest{
+		TaskId:     created.Id,
+		Filename:   "../logs/run.log",
+		Content:    content,
+		UploadedBy: "oncall",
+	})
+	if err != nil {
+		t.Fatalf("UploadAttachment failed: %v", err)
+	}
+	// 文件名去掉路径，未声明类型时根据内容探测
+	if uploaded.Filename != "run.log" || !strings.HasPrefix(uploaded.ContentType, "text/plain") {
+		t.Errorf("Unexpected attachment metadata: %v", uploaded)
+	}
+	sum := sha256.Sum256(content)
+	if uploaded.Size != int64(len(content)) || uploaded.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected size/checksum: %v", uploaded)
+	}
+
+	list, err := stack.client.ListAttachments(ctx, &pb.ListAttachmentsRequest{TaskId: created.Id})
+	if err != nil {
+		t.Fatalf("ListAttachments failed: %v", err)
+	}
+	if len(list.Attachments) != 1 || list.Attachments[0].Id != uploaded.Id {
+		t.Fatalf("Expected uploaded attachment in list, got %v", list.Attachments)
+	}
+
+	download, err := stack.client.DownloadAttachment(ctx, &pb.DownloadAttachmentRequest{TaskId: created.Id, AttachmentId: uploaded.Id})
+	if err != nil {
+		t.Fatalf("DownloadAttachment failed: %v", err)
+	}
+	if string(download.Content) != string(content) {
+		t.Errorf("Downloaded content mismatch: %q", download.Content)
+	}
+
+	// 超出大小限制
+	_, err = stack.client.UploadAttachment(ctx, &pb.UploadAttachmentRequest{
+		TaskId:   created.Id,
+		Filename: "big.txt",
+		Content:  []byte(strings.Repeat("x", testAttachmentMaxSize+1)),
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for oversized attachment, got %v", err)
+	}
+
+	// 不允许的类型（PNG 文件头）
+	_, err = stack.client.UploadAttachment(ctx, &pb.UploadAttachmentRequest{
+		TaskId:   created.Id,
+		Filename: "image.png",
+		Content:  []byte("\x89PNG\r\n\x1a\n0000"),
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for disallowed type, got %v", err)
+	}
+
+	if _, err := stack.client.DeleteAttachment(ctx, &pb.DeleteAttachmentRequest{TaskId: created.Id, AttachmentId: uploaded.Id}); err != nil {
+		t.Fatalf("DeleteAttachment failed: %v", err)
+	}
+	_, err = stack.client.DownloadAttachment(ctx, &pb.DownloadAttachmentRequest{TaskId: created.Id, AttachmentId: uploaded.Id})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound after delete, got %v", err)
+	}
+}
+
+// TestTaskLogs_TailAndPage 跟踪运行中任务的日志，任务结束后流关闭，并可分页读取
+func TestTaskLogs_TailAndPage(t *testing.T) {
+	stack := newTestStack(t)
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{Name: "logged", TaskType: taskTypeGated})
+	stack.waitForStatus(t, created.Id, pb.TaskStatus_TASK_STATUS_RUNNING)
+
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancel()
+
+	stream, err := stack.client.TailTaskLogs(ctx, &pb.TailTaskLogsRequest{TaskId: created.Id})
+	if err != nil {
+		t.Fatalf("TailTaskLogs failed: %v", err)
+	}
+
+	// 回放已写入的日志
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if first.Seq != 1 || first.Line != "waiting for release" || first.Attempt != 1 {
+		t.Fatalf("Unexpected first line: %v", first)
+	}
+
+	stack.releaseGated()
+
+	var lines []string
+	for {
+		line, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		lines = append(lines, line.Line)
+	}
+	if strings.Join(lines, "|") != "released|attempt 1" {
+		t.Errorf("Unexpected tailed lines: %v", lines)
+	}
+
+	// 分页读取
+	page, err := stack.client.GetTaskLogs(context.Background(), &pb.GetTaskLogsRequest{TaskId: created.Id, Limit: 2})
+	if err != nil {
+		t.Fatalf("GetTaskLogs failed: %v", err)
+	}
+	if len(page.Lines) != 2 || !page.HasMore || page.LastSeq != 2 {
+		t.Fatalf("Unexpected first page: %v", page)
+	}
+	page, err = stack.client.GetTaskLogs(context.Background(), &pb.GetTaskLogsRequest{TaskId: created.Id, AfterSeq: page.LastSeq})
+	if err != nil {
+		t.Fatalf("GetTaskLogs failed: %v", err)
+	}
+	if len(page.Lines) != 1 || page.HasMore || page.Lines[0].Line != "attempt 1" {
+		t.Errorf("Unexpected second page: %v", page)
+	}
+
+	// 已结束的任务：回放后立即关闭
+	stream, err = stack.client.TailTaskLogs(context.Background(), &pb.TailTaskLogsRequest{TaskId: created.Id, AfterSeq: 2})
+	if err != nil {
+		t.Fatalf("TailTaskLogs failed: %v", err)
+	}
+	if line, err := stream.Recv(); err != nil || line.Seq != 3 {
+		t.Fatalf("Expected replay of seq 3, got %v, %v", line, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected EOF for finished task, got %v", err)
+	}
+}
+
+// TestWorkerPool_ResizeWhileRunning 运行中调整工作池大小，执行中的任务不受影响
+func TestWorkerPool_ResizeWhileRunning(t *testing.T) {
+	stack := newTestStack(t)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		created := stack.createTask(t, &pb.CreateTaskRequest{Name: "gated", TaskType: taskTypeGated})
+		stack.waitForStatus(t, created.Id, pb.TaskStatus_TASK_STATUS_RUNNING)
+		ids = append(ids, created.Id)
+	}
+
+	resp, err := stack.client.ResizeWorkerPool(context.Background(), &pb.ResizeWorkerPoolRequest{Size: 1})
+	if err != nil {
+		t.Fatalf("ResizeWorkerPool failed: %v", err)
+	}
+	if resp.Size != 1 || resp.Busy != 3 || resp.Autoscaled {
+		t.Errorf("Unexpected pool status after shrink: %v", resp)
+	}
+
+	// 缩容后已在执行的任务照常完成
+	stack.releaseGated()
+	for _, id := range ids {
+		stack.waitForStatus(t, id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+	}
+
+	pool, err := stack.client.GetWorkerPool(context.Background(), &pb.GetWorkerPoolRequest{})
+	if err != nil {
+		t.Fatalf("GetWorkerPool failed: %v", err)
+	}
+	if pool.Size != 1 {
+		t.Errorf("Expected pool size 1, got %d", pool.Size)
+	}
+
+	_, err = stack.client.ResizeWorkerPool(context.Background(), &pb.ResizeWorkerPoolRequest{Size: 0})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for size 0, got %v", err)
+	}
+}
+
+// TestCancelRunningTask 取消运行中的任务会取消执行器上下文，执行器清理后才记录 CANCELLED
+func TestCancelRunningTask(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{Name: "cancel-me", TaskType: taskTypeGated})
+	stack.waitForStatus(t, created.Id, pb.TaskStatus_TASK_STATUS_RUNNING)
+
+	updated, err := stack.client.UpdateTask(ctx, &pb.UpdateTaskRequest{Id: created.Id, Status: pb.TaskStatus_TASK_STATUS_CANCELLED})
+	if err != nil {
+		t.Fatalf("UpdateTask failed: %v", err)
+	}
+	if updated.Status != pb.TaskStatus_TASK_STATUS_CANCELLED {
+		t.Fatalf("Expected CANCELLED, got %s", updated.Status)
+	}
+
+	// UpdateTask 返回前执行器已完成清理
+	logs, err := stack.client.GetTaskLogs(ctx, &pb.GetTaskLogsRequest{TaskId: created.Id})
+	if err != nil {
+		t.Fatalf("GetTaskLogs failed: %v", err)
+	}
+	if n := len(logs.Lines); n != 2 || logs.Lines[n-1].Line != "cleanup: task cancelled" {
+		t.Fatalf("Expected cleanup log line, got %v", logs.Lines)
+	}
+	if stack.executor.Attempts(created.Id) != 0 {
+		t.Errorf("Executor should not have completed the cancelled task")
+	}
+}
+
+// TestListSchedulerInstances 调度器实例心跳上报执行中的任务，任务记录执行实例
+func TestListSchedulerInstances(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{Name: "in-flight", TaskType: taskTypeGated})
+	running := stack.waitForStatus(t, created.Id, pb.TaskStatus_TASK_STATUS_RUNNING)
+	instanceID := stack.svc.Scheduler().InstanceID()
+	if running.ExecutedBy != instanceID {
+		t.Errorf("Expected executed_by %s, got %q", instanceID, running.ExecutedBy)
+	}
+
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		resp, err := stack.client.ListSchedulerInstances(ctx, &pb.ListSchedulerInstancesRequest{})
+		if err != nil {
+			t.Fatalf("ListSchedulerInstances failed: %v", err)
+		}
+		if len(resp.Instances) != 1 {
+			t.Fatalf("Expected 1 instance, got %v", resp.Instances)
+		}
+		inst := resp.Instances[0]
+		if inst.Id != instanceID || !inst.Alive || inst.WorkerCount != 10 {
+			t.Fatalf("Unexpected instance: %v", inst)
+		}
+		if len(inst.InFlightTasks) == 1 && inst.InFlightTasks[0] == created.Id && inst.BusyWorkers == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Heartbeat never reported in-flight task: %v", inst)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	stack.releaseGated()
+	done, err := stack.client.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id, IncludeEvents: true})
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	for _, e := range done.Events {
+		if e.Operator == "scheduler" && e.InstanceId != instanceID {
+			t.Errorf("Scheduler event missing instance ID: %v", e)
+		}
+	}
+}
+
+// TestGetSchedulingDecision 下游任务在上游运行期间报告 dependencies_unmet，上游完成后被调度
+func TestGetSchedulingDecision(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+
+	upstream := stack.createTask(t, &pb.CreateTaskRequest{Name: "upstream", TaskType: taskTypeGated})
+	stack.waitForStatus(t, upstream.Id, pb.TaskStatus_TASK_STATUS_RUNNING)
+	downstream := stack.createTask(t, &pb.CreateTaskRequest{Name: "downstream", Dependencies: []string{upstream.Id}})
+
+	decision, err := stack.client.GetSchedulingDecision(ctx, &pb.GetSchedulingDecisionRequest{TaskId: downstream.Id})
+	if err != nil {
+		t.Fatalf("GetSchedulingDecision failed: %v", err)
+	}
+	if decision.Dispatchable || len(decision.Reasons) != 1 || decision.Reasons[0].Code != "dependencies_unmet" {
+		t.Fatalf("Expected dependencies_unmet, got %v", decision)
+	}
+	deps := decision.Reasons[0].Dependencies
+	if len(deps) != 1 || deps[0].TaskId != upstream.Id || deps[0].Status != pb.TaskStatus_TASK_STATUS_RUNNING {
+		t.Errorf("Expected running upstream dependency, got %v", deps)
+	}
+	if decision.InstanceId != stack.svc.Scheduler().InstanceID() {
+		t.Errorf("Expected instance %s, got %q", stack.svc.Scheduler().InstanceID(), decision.InstanceId)
+	}
+
+	stack.releaseGated()
+	stack.waitForStatus(t, downstream.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+	decision, err = stack.client.GetSchedulingDecision(ctx, &pb.GetSchedulingDecisionRequest{TaskId: downstream.Id})
+	if err != nil {
+		t.Fatalf("GetSchedulingDecision failed: %v", err)
+	}
+	if len(decision.Reasons) != 1 || decision.Reasons[0].Code != "not_pending" {
+		t.Errorf("Expected not_pending after completion, got %v", decision)
+	}
+
+	_, err = stack.client.GetSchedulingDecision(ctx, &pb.GetSchedulingDecisionRequest{TaskId: "nonexistent"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+// TestRedaction_MasksSensitiveParams 敏感参数在响应中脱敏，只有管理员能查看原值
+func TestRedaction_MasksSensitiveParams(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+
+	redactor, err := redact.New([]string{"*password*"})
+	if err != nil {
+		t.Fatalf("redact.New failed: %v", err)
+	}
+	// 简化的令牌校验把 Bearer 令牌前 8 个字符作为用户 ID
+	stack.handler.SetRedaction(redactor, []string{"user-admin-to"})
+	stack.handler.SetAccessControl([]string{"user-admin-to"}, false)
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{
+		Name:        "db-migrate",
+		TaskType:    taskTypeGated,
+		InputParams: map[string]string{"db_password": "hunter2", "host": "db"},
+	})
+	if created.InputParams["db_password"] != redact.Mask || created.InputParams["host"] != "db" {
+		t.Errorf("CreateTask response not redacted: %v", created.InputParams)
+	}
+
+	task, err := stack.client.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id})
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	if task.InputParams["db_password"] != redact.Mask {
+		t.Errorf("GetTask response not redacted: %v", task.InputParams)
+	}
+	list, err := stack.client.ListTasks(ctx, &pb.ListTasksRequest{PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	for _, task := range list.Tasks {
+		if task.InputParams["db_password"] == "hunter2" {
+			t.Errorf("ListTasks response not redacted: %v", task.InputParams)
+		}
+	}
+
+	// 未认证调用不能查看原值
+	if _, err := stack.client.GetTask(ctx, &pb.GetTaskRequest{Id: created.Id, Unredacted: true}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for unredacted view, got %v", err)
+	}
+
+	// 经认证拦截器的调用按用户 ID 判断
+	getAs := func(token string) (*pb.Task, error) {
+		authCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		resp, err := grpc_middleware.UnaryAuthInterceptor(nil)(authCtx, &pb.GetTaskRequest{Id: created.Id, Unredacted: true},
+			&grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/GetTask"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return stack.handler.GetTask(ctx, req.(*pb.GetTaskRequest))
+			})
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*pb.Task), nil
+	}
+	if _, err := getAs("someone-else"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for non-admin, got %v", err)
+	}
+	task, err = getAs("admin-token")
+	if err != nil {
+		t.Fatalf("GetTask as admin failed: %v", err)
+	}
+	if task.InputParams["db_password"] != "hunter2" {
+		t.Errorf("Expected unredacted params for admin, got %v", task.InputParams)
+	}
+}
+
+// TestTaskVisibility 认证用户只能看到本人创建或所在团队的任务，创建者以认证身份为准，管理员不受限制
+func TestTaskVisibility(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+	stack.handler.SetAccessControl([]string{"user-admin-to"}, false)
+
+	// 简化的令牌校验把 Bearer 令牌前 8 个字符作为用户 ID
+	call := func(token, method string, req interface{}, fn func(ctx context.Context, req interface{}) (interface{}, error)) (interface{}, error) {
+		authCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		return grpc_middleware.UnaryAuthInterceptor(nil)(authCtx, req,
+			&grpc.UnaryServerInfo{FullMethod: "/taskflow.TaskService/" + method}, fn)
+	}
+	create := func(token, name string) *pb.Task {
+		resp, err := call(token, "CreateTask", &pb.CreateTaskRequest{Name: name, CreatedBy: "spoofed"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return stack.handler.CreateTask(ctx, req.(*pb.CreateTaskRequest))
+			})
+		if err != nil {
+			t.Fatalf("CreateTask as %s failed: %v", token, err)
+		}
+		return resp.(*pb.Task)
+	}
+	list := func(token string) []string {
+		resp, err := call(token, "ListTasks", &pb.ListTasksRequest{PageSize: 100},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return stack.handler.ListTasks(ctx, req.(*pb.ListTasksRequest))
+			})
+		if err != nil {
+			t.Fatalf("ListTasks as %s failed: %v", token, err)
+		}
+		var names []string
+		for _, task := range resp.(*pb.ListTasksResponse).Tasks {
+			names = append(names, task.Name)
+		}
+		return names
+	}
+
+	alice := create("alice-token", "alice-task")
+	create("bob-token", "bob-task")
+	if alice.CreatedBy != "user-alice-to" {
+		t.Errorf("Expected created_by from the auth principal, got %q", alice.CreatedBy)
+	}
+
+	if names := list("alice-token"); len(names) != 1 || names[0] != "alice-task" {
+		t.Errorf("Expected alice to see only her task, got %v", names)
+	}
+	if names := list("admin-token"); len(names) != 2 {
+		t.Errorf("Expected admin to see all tasks, got %v", names)
+	}
+
+	_, err := call("bob-token", "GetTask", &pb.GetTaskRequest{Id: alice.Id},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return stack.handler.GetTask(ctx, req.(*pb.GetTaskRequest))
+		})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for another user's task, got %v", err)
+	}
+
+	// 变更流只返回可见任务的事件
+	resp, err := call("alice-token", "GetChangeFeed", &pb.GetChangeFeedRequest{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return stack.handler.GetChangeFeed(ctx, req.(*pb.GetChangeFeedRequest))
+		})
+	if err != nil {
+		t.Fatalf("GetChangeFeed as alice failed: %v", err)
+	}
+	feed := resp.(*pb.GetChangeFeedResponse)
+	if len(feed.Events) == 0 {
+		t.Error("Expected alice to see the events of her task")
+	}
+	for _, e := range feed.Events {
+		if e.TaskId != alice.Id {
+			t.Errorf("Expected only events of alice's task, got %v", e)
+		}
+	}
+}
+
+// TestListTasks_Filters 按完成时间和状态、类型列表过滤，过滤条件不合法时返回字段错误
+func TestListTasks_Filters(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour).Unix()
+
+	done := stack.createTask(t, &pb.CreateTaskRequest{Name: "done", TaskType: taskTypeEcho})
+	stack.createTask(t, &pb.CreateTaskRequest{Name: "running", TaskType: taskTypeGated})
+	stack.waitForStatus(t, done.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+
+	list, err := stack.client.ListTasks(ctx, &pb.ListTasksRequest{
+		PageSize: 10, CompletedAfter: start, SortBy: "completed_at", SortDesc: true,
+	})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(list.Tasks) != 1 || list.Tasks[0].Id != done.Id {
+		t.Errorf("Expected only the completed task, got %v", list.Tasks)
+	}
+
+	list, err = stack.client.ListTasks(ctx, &pb.ListTasksRequest{
+		PageSize:     10,
+		StatusFilter: []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_SUCCEEDED, pb.TaskStatus_TASK_STATUS_RUNNING},
+		TaskTypes:    []string{taskTypeEcho, "unknown"},
+	})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(list.Tasks) != 1 || list.Tasks[0].Id != done.Id {
+		t.Errorf("Expected status and type lists to match only the echo task, got %v", list.Tasks)
+	}
+
+	for _, tc := range []struct {
+		req   *pb.ListTasksRequest
+		field string
+	}{
+		{&pb.ListTasksRequest{SortBy: "name"}, "sort_by"},
+		{&pb.ListTasksRequest{CreatedAfter: start + 10, CreatedBefore: start}, "created_before"},
+		{&pb.ListTasksRequest{UpdatedSince: -1}, "updated_since"},
+		{&pb.ListTasksRequest{StatusFilter: []pb.TaskStatus{99}}, "status_filter[0]"},
+	} {
+		_, err := stack.client.ListTasks(ctx, tc.req)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for %s, got %v", tc.field, err)
+			continue
+		}
+		verr := errorcode.FromGRPCBadRequest(status.Convert(err))
+		if verr == nil || len(verr.Violations) != 1 || verr.Violations[0].Field != tc.field {
+			t.Errorf("Expected a violation on %s, got %+v", tc.field, verr)
+		}
+	}
+}
+
+// TestChangeFeed_Paging 分页读取变更流，得到所有任务按序号排列的创建和状态变更事件
+func TestChangeFeed_Paging(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+
+	first := stack.createTask(t, &pb.CreateTaskRequest{Name: "first", TaskType: taskTypeEcho})
+	second := stack.createTask(t, &pb.CreateTaskRequest{Name: "second", TaskType: taskTypeEcho})
+	stack.waitForStatus(t, first.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+	stack.waitForStatus(t, second.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+
+	var events []*pb.TaskEvent
+	var since int64
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatalf("Change feed did not end, read %d events", len(events))
+		}
+		resp, err := stack.client.GetChangeFeed(ctx, &pb.GetChangeFeedRequest{SinceSeq: since, Limit: 2})
+		if err != nil {
+			t.Fatalf("GetChangeFeed failed: %v", err)
+		}
+		for _, e := range resp.Events {
+			if e.Seq <= since {
+				t.Errorf("Expected seq after %d, got %d", since, e.Seq)
+			}
+			since = e.Seq
+		}
+		events = append(events, resp.Events...)
+		if resp.LastSeq != since {
+			t.Errorf("Expected last_seq %d, got %d", since, resp.LastSeq)
+		}
+		if !resp.HasMore {
+			break
+		}
+	}
+
+	// 每个任务：创建、开始执行、成功
+	transitions := map[string][]pb.TaskStatus{}
+	for _, e := range events {
+		transitions[e.TaskId] = append(transitions[e.TaskId], e.ToStatus)
+	}
+	want := []pb.TaskStatus{pb.TaskStatus_TASK_STATUS_PENDING, pb.TaskStatus_TASK_STATUS_RUNNING, pb.TaskStatus_TASK_STATUS_SUCCEEDED}
+	for _, id := range []string{first.Id, second.Id} {
+		if !slices.Equal(transitions[id], want) {
+			t.Errorf("Expected transitions %v for task %s, got %v", want, id, transitions[id])
+		}
+	}
+
+	// 从末尾读取没有新事件，游标保持不变
+	resp, err := stack.client.GetChangeFeed(ctx, &pb.GetChangeFeedRequest{SinceSeq: since})
+	if err != nil || len(resp.Events) != 0 || resp.HasMore || resp.LastSeq != since {
+		t.Errorf("Expected an empty page at seq %d, got %v: %v", since, resp, err)
+	}
+}
+
+// TestMirrorSync 读取任务快照后从游标订阅变更，镜像最终与服务端一致
+func TestMirrorSync(t *testing.T) {
+	stack := newTestStack(t)
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancel()
+
+	for _, name := range []string{"a", "b", "c"} {
+		task := stack.createTask(t, &pb.CreateTaskRequest{Name: name, TaskType: taskTypeEcho})
+		stack.waitForStatus(t, task.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+	}
+
+	// 分页读取快照，游标取第一页的值
+	mirror := map[string]*pb.Task{}
+	var cursor int64
+	var afterID string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatalf("Snapshot did not end, mirrored %d tasks", len(mirror))
+		}
+		resp, err := stack.client.SnapshotTasks(ctx, &pb.SnapshotTasksRequest{Cursor: cursor, AfterId: afterID, PageSize: 1})
+		if err != nil {
+			t.Fatalf("SnapshotTasks failed: %v", err)
+		}
+		if cursor == 0 {
+			cursor = resp.Cursor
+		} else if resp.Cursor != cursor {
+			t.Errorf("Expected the cursor %d to be carried across pages, got %d", cursor, resp.Cursor)
+		}
+		for _, task := range resp.Tasks {
+			mirror[task.Id] = task
+		}
+		afterID = resp.LastId
+		if !resp.HasMore {
+			break
+		}
+	}
+	if len(mirror) != 3 || cursor == 0 {
+		t.Fatalf("Expected 3 tasks and a cursor, got %d tasks at cursor %d", len(mirror), cursor)
+	}
+
+	// 快照之后的变更由变更流补齐
+	stream, err := stack.client.StreamChanges(ctx, &pb.StreamChangesRequest{SinceSeq: cursor})
+	if err != nil {
+		t.Fatalf("StreamChanges failed: %v", err)
+	}
+	later := stack.createTask(t, &pb.CreateTaskRequest{Name: "later", TaskType: taskTypeEcho})
+	for mirror[later.Id].GetStatus() != pb.TaskStatus_TASK_STATUS_SUCCEEDED {
+		change, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed before the later task succeeded: %v", err)
+		}
+		if change.Event.Seq <= cursor || change.Event.TaskId != change.Task.Id {
+			t.Errorf("Unexpected change after cursor %d: %v", cursor, change)
+		}
+		mirror[change.Task.Id] = change.Task
+	}
+	if len(mirror) != 4 {
+		t.Errorf("Expected 4 mirrored tasks, got %d", len(mirror))
+	}
+
+	ahead, err := stack.client.StreamChanges(ctx, &pb.StreamChangesRequest{SinceSeq: cursor + 1000})
+	if err == nil {
+		_, err = ahead.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected INVALID_ARGUMENT for a cursor ahead of the latest event, got %v", err)
+	}
+}
+
+// TestDeleteTask_Dependents 仍被依赖的任务须强制删除，强制删除后被阻塞的下游任务立即被调度
+func TestDeleteTask_Dependents(t *testing.T) {
+	stack := newTestStack(t)
+	ctx := context.Background()
+
+	upstream := stack.createTask(t, &pb.CreateTaskRequest{
+		Name:        "upstream",
+		TaskType:    taskTypeEcho,
+		InputParams: map[string]string{"fail_times": "1"},
+	})
+	downstream := stack.createTask(t, &pb.CreateTaskRequest{
+		Name:               "downstream",
+		TaskType:           taskTypeEcho,
+		Dependencies:       []string{upstream.Id},
+		DependencyPolicies: map[string]string{upstream.Id: "wait"},
+	})
+	stack.waitForStatus(t, upstream.Id, pb.TaskStatus_TASK_STATUS_FAILED)
+
+	// 下游按 wait 等待失败的上游，删除上游返回 TASK_HAS_DEPENDENTS 并列出下游
+	_, err := stack.client.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: upstream.Id})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition, got %v", err)
+	}
+	taskErr := errorcode.FromGRPCStatus(status.Convert(err))
+	if taskErr.Code != errorcode.ErrCodeTaskHasDependents || len(taskErr.Violations) != 1 ||
+		taskErr.Violations[0].Field != downstream.Id || taskErr.Violations[0].Message != "downstream is PENDING" {
+		t.Fatalf("Expected downstream listed as the blocking dependent, got %+v", taskErr)
+	}
+
+	resp, err := stack.client.DeleteTask(ctx, &pb.DeleteTaskRequest{Id: upstream.Id, Force: true})
+	if err != nil {
+		t.Fatalf("DeleteTask failed: %v", err)
+	}
+	if len(resp.Dependents) != 1 || resp.Dependents[0] != downstream.Id {
+		t.Errorf("Expected downstream in dependents, got %v", resp.Dependents)
+	}
+	if _, err := stack.client.GetTask(ctx, &pb.GetTaskRequest{Id: upstream.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound after delete, got %v", err)
+	}
+
+	// 兜底轮询间隔为 1 小时，下游任务只能由删除后的唤醒调度
+	done := stack.waitForStatus(t, downstream.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+	if len(done.Dependencies) != 0 {
+		t.Errorf("Expected the deleted dependency removed, got %v", done.Dependencies)
+	}
+}
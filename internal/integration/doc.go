@@ -0,0 +1,4 @@
+// Package integration 黄金路径集成测试：在临时 SQLite 上启动真实调度器、
+// 任务处理器和 bufconn gRPC 服务，通过 gRPC 客户端驱动完整的
+// 创建 → 依赖 → 重试 → 监听流程。测试代码位于 *_test.go 中。
+package integration
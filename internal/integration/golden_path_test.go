@@ -0,0 +1,248 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "taskflow/proto"
+)
+
+// TestGoldenPath_CreateAndExecute 创建任务后由调度器执行成功
+func TestGoldenPath_CreateAndExecute(t *testing.T) {
+	stack := newTestStack(t)
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{
+		Name:     "echo-task",
+		TaskType: taskTypeEcho,
+		Priority: pb.TaskPriority_TASK_PRIORITY_NORMAL,
+	})
+	if created.Status != pb.TaskStatus_TASK_STATUS_PENDING {
+		t.Errorf("Expected PENDING on create, got %s", created.Status)
+	}
+
+	done := stack.waitForStatus(t, created.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+	if done.OutputResult["attempt"] != "1" {
+		t.Errorf("Expected output from test executor, got %v", done.OutputResult)
+	}
+	if n := stack.executor.Attempts(created.Id); n != 1 {
+		t.Errorf("Expected 1 attempt, got %d", n)
+	}
+}
+
+// TestGoldenPath_Dependency 下游任务在上游成功后才执行
+func TestGoldenPath_Dependency(t *testing.T) {
+	stack := newTestStack(t)
+
+	upstream := stack.createTask(t, &pb.CreateTaskRequest{Name: "upstream", TaskType: taskTypeGated})
+	downstream := stack.createTask(t, &pb.CreateTaskRequest{
+		Name:         "downstream",
+		TaskType:     taskTypeEcho,
+		Dependencies: []string{upstream.Id},
+	})
+
+	// 上游被阻塞时，下游保持 PENDING
+	stack.waitForStatus(t, upstream.Id, pb.TaskStatus_TASK_STATUS_RUNNING)
+	time.Sleep(200 * time.Millisecond)
+	if task, err := stack.client.GetTask(context.Background(), &pb.GetTaskRequest{Id: downstream.Id}); err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	} else if task.Status != pb.TaskStatus_TASK_STATUS_PENDING {
+		t.Fatalf("Expected downstream PENDING while upstream runs, got %s", task.Status)
+	}
+
+	stack.releaseGated()
+	stack.waitForStatus(t, upstream.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+	stack.waitForStatus(t, downstream.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+
+	order := stack.executor.Order()
+	if len(order) != 2 || order[0] != upstream.Id || order[1] != downstream.Id {
+		t.Errorf("Expected upstream then downstream, got %v", order)
+	}
+}
+
+// TestGoldenPath_Retry 执行失败后重试成功
+func TestGoldenPath_Retry(t *testing.T) {
+	stack := newTestStack(t)
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{
+		Name:        "flaky",
+		TaskType:    taskTypeEcho,
+		InputParams: map[string]string{"fail_times": "1"},
+		MaxRetries:  1,
+	})
+
+	stack.waitForStatus(t, created.Id, pb.TaskStatus_TASK_STATUS_FAILED)
+
+	if err := stack.svc.RetryTask(context.Background(), created.Id, "tester"); err != nil {
+		t.Fatalf("RetryTask failed: %v", err)
+	}
+
+	done := stack.waitForStatus(t, created.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+	if done.OutputResult["attempt"] != "2" {
+		t.Errorf("Expected success on attempt 2, got %v", done.OutputResult)
+	}
+	if n := stack.executor.Attempts(created.Id); n != 2 {
+		t.Errorf("Expected 2 attempts, got %d", n)
+	}
+}
+
+// TestGoldenPath_Watch 通过 WatchTask 观察调度器产生的状态变更
+func TestGoldenPath_Watch(t *testing.T) {
+	stack := newTestStack(t)
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{Name: "watched", TaskType: taskTypeGated})
+
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancel()
+
+	stream, err := stack.client.WatchTask(ctx, &pb.WatchTaskRequest{
+		TaskIds:        []string{created.Id},
+		IncludeInitial: true,
+	})
+	if err != nil {
+		t.Fatalf("WatchTask failed: %v", err)
+	}
+
+	// 收到初始事件说明订阅已注册
+	initial, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv initial failed: %v", err)
+	}
+	if initial.ChangeType != "initial" || initial.TaskId != created.Id {
+		t.Fatalf("Unexpected initial event: %v", initial)
+	}
+
+	stack.releaseGated()
+
+	seen := map[pb.TaskStatus]bool{initial.ToStatus: true}
+	for !seen[pb.TaskStatus_TASK_STATUS_SUCCEEDED] {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed before SUCCEEDED, seen %v: %v", seen, err)
+		}
+		if event.TaskId != created.Id {
+			t.Errorf("Unexpected event for task %s", event.TaskId)
+		}
+		seen[event.ToStatus] = true
+	}
+
+	if !seen[pb.TaskStatus_TASK_STATUS_RUNNING] {
+		t.Errorf("Expected RUNNING to be observed, seen %v", seen)
+	}
+}
+
+// TestGoldenPath_WatchResume 断线期间的状态变更在重连时凭 resume_token 补发，事件被清理后令牌过期
+func TestGoldenPath_WatchResume(t *testing.T) {
+	stack := newTestStack(t)
+
+	created := stack.createTask(t, &pb.CreateTaskRequest{Name: "resumed", TaskType: taskTypeGated})
+
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+	stream, err := stack.client.WatchTask(ctx, &pb.WatchTaskRequest{
+		TaskIds:        []string{created.Id},
+		IncludeInitial: true,
+	})
+	if err != nil {
+		cancel()
+		t.Fatalf("WatchTask failed: %v", err)
+	}
+	initial, err := stream.Recv()
+	cancel()
+	if err != nil {
+		t.Fatalf("Recv initial failed: %v", err)
+	}
+	if initial.ResumeToken <= 0 {
+		t.Fatalf("Expected a positive resume token on the initial event, got %d", initial.ResumeToken)
+	}
+
+	// 断线期间任务执行完成
+	stack.releaseGated()
+	stack.waitForStatus(t, created.Id, pb.TaskStatus_TASK_STATUS_SUCCEEDED)
+
+	ctx, cancel = context.WithTimeout(context.Background(), waitTimeout)
+	defer cancel()
+	resumed, err := stack.client.WatchTask(ctx, &pb.WatchTaskRequest{
+		TaskIds:     []string{created.Id},
+		ResumeToken: initial.ResumeToken,
+	})
+	if err != nil {
+		t.Fatalf("WatchTask resume failed: %v", err)
+	}
+	token := initial.ResumeToken
+	for {
+		event, err := resumed.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed before SUCCEEDED was replayed: %v", err)
+		}
+		if event.TaskId != created.Id || event.ChangeType != "status_changed" {
+			t.Errorf("Unexpected replayed event: %v", event)
+		}
+		if event.ResumeToken <= token {
+			t.Errorf("Expected resume tokens to increase, got %d after %d", event.ResumeToken, token)
+		}
+		token = event.ResumeToken
+		if event.ToStatus == pb.TaskStatus_TASK_STATUS_SUCCEEDED {
+			if event.Task.GetStatus() != pb.TaskStatus_TASK_STATUS_SUCCEEDED {
+				t.Errorf("Expected the replayed event to carry the task snapshot, got %v", event.Task)
+			}
+			break
+		}
+	}
+
+	// 令牌超出已分配的序号
+	ahead, err := stack.client.WatchTask(ctx, &pb.WatchTaskRequest{ResumeToken: token + 100})
+	if err == nil {
+		_, err = ahead.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected INVALID_ARGUMENT for a resume token ahead of the outbox, got %v", err)
+	}
+
+	// 清理已投递的事件后，初始令牌需要补发的事件不复存在
+	events, err := stack.repo.ListOutboxEventsAfter(0, 100)
+	if err != nil {
+		t.Fatalf("ListOutboxEventsAfter failed: %v", err)
+	}
+	for _, e := range events {
+		if err := stack.repo.MarkOutboxEventDelivered(e.ID, time.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("MarkOutboxEventDelivered failed: %v", err)
+		}
+	}
+	if _, err := stack.repo.PurgeDeliveredOutboxEvents(time.Now()); err != nil {
+		t.Fatalf("PurgeDeliveredOutboxEvents failed: %v", err)
+	}
+
+	expired, err := stack.client.WatchTask(ctx, &pb.WatchTaskRequest{ResumeToken: initial.ResumeToken})
+	if err == nil {
+		_, err = expired.Recv()
+	}
+	if status.Code(err) != codes.OutOfRange {
+		t.Errorf("Expected OUT_OF_RANGE for an expired resume token, got %v", err)
+	}
+
+	// 最新的令牌仍然有效，之后的变更照常推送
+	latest, err := stack.client.WatchTask(ctx, &pb.WatchTaskRequest{ResumeToken: token, IncludeInitial: true})
+	if err != nil {
+		t.Fatalf("WatchTask with the latest token failed: %v", err)
+	}
+	if initial, err := latest.Recv(); err != nil || initial.ResumeToken != token {
+		t.Fatalf("Expected an initial event with resume token %d, got %v: %v", token, initial, err)
+	}
+	next := stack.createTask(t, &pb.CreateTaskRequest{Name: "after-purge", TaskType: taskTypeEcho})
+	for {
+		event, err := latest.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed before the new task succeeded: %v", err)
+		}
+		if event.TaskId != next.Id || event.ResumeToken <= token {
+			t.Errorf("Unexpected event after the latest token %d: %v", token, event)
+		}
+		token = event.ResumeToken
+		if event.ToStatus == pb.TaskStatus_TASK_STATUS_SUCCEEDED {
+			break
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"taskflow/internal/grpc_middleware"
+	"taskflow/internal/handler"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	"taskflow/internal/service"
+	"taskflow/internal/storage"
+	pb "taskflow/proto"
+)
+
+// 测试执行器注册的任务类型
+const (
+	taskTypeEcho  = "echo"  // 立即成功；input_params.fail_times 指定前 N 次失败
+	taskTypeGated = "gated" // 阻塞直到 stack.release 被关闭
+)
+
+const waitTimeout = 5 * time.Second
+
+// 测试用附件限制
+const testAttachmentMaxSize = 1024
+
+// testStack 完整服务栈
+type testStack struct {
+	repo     repository.TaskStore
+	svc      *service.TaskService
+	handler  *handler.TaskHandler
+	client   pb.TaskServiceClient
+	executor *testExecutor
+	release  chan struct{}
+}
+
+// newTestStack 启动临时 SQLite + 调度器 + bufconn gRPC 服务
+func newTestStack(t *testing.T) *testStack {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "taskflow.db") + "?_busy_timeout=5000&_journal_mode=WAL"
+	db, err := repository.NewSQLite(dsn)
+	if err != nil {
+		t.Fatalf("failed to create SQLite: %v", err)
+	}
+	if err := db.InitSchema(); err != nil {
+		db.Close()
+		t.Fatalf("failed to init schema: %v", err)
+	}
+
+	taskRepo := repository.NewTaskRepository(db)
+	teamRepo := repository.NewTeamRepository(db)
+
+	stack := &testStack{
+		repo:    taskRepo,
+		svc:     service.NewTaskService(taskRepo),
+		handler: handler.NewTaskHandler(taskRepo, teamRepo),
+		release: make(chan struct{}),
+	}
+	stack.executor = newTestExecutor(stack.release)
+
+	blobs, err := storage.NewLocalStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+	stack.handler.SetAttachmentStore(blobs, handler.AttachmentPolicy{
+		MaxSize:      testAttachmentMaxSize,
+		AllowedTypes: []string{"text/*", "application/json"},
+	})
+
+	// 调度器执行测试执行器，并把状态变更推送给 WatchTask 订阅者
+	stack.svc.RegisterExecutor(taskTypeEcho, stack.executor)
+	stack.svc.RegisterExecutor(taskTypeGated, stack.executor)
+	stack.svc.Scheduler().OnTaskChange(stack.handler.PublishTaskChange)
+	stack.svc.Scheduler().OnTaskLog(stack.handler.PublishTaskLog)
+
+	// 调度完全由事件唤醒驱动，兜底轮询设得足够长，确保测试覆盖唤醒路径
+	stack.handler.SetSchedulerWakeup(stack.svc.Scheduler().Wake)
+	stack.handler.SetSchedulerControl(stack.svc.Scheduler())
+	stack.svc.Scheduler().SetPollingInterval(time.Hour)
+	stack.svc.Scheduler().SetHeartbeatInterval(20 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stack.svc.StartScheduler(ctx)
+
+	// gRPC 服务（与线上一致的拦截器链）
+	lis := bufconn.Listen(1024 * 1024)
+	opts, err := grpc_middleware.GetUnaryServerOptions(
+		grpc_middleware.WithRecovery(),
+		grpc_middleware.WithRequestID(),
+	)
+	if err != nil {
+		t.Fatalf("failed to build server options: %v", err)
+	}
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterTaskServiceServer(grpcServer, stack.handler)
+	go grpcServer.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufconn: %v", err)
+	}
+	stack.client = pb.NewTaskServiceClient(conn)
+
+	t.Cleanup(func() {
+		stack.releaseGated()
+		conn.Close()
+		grpcServer.Stop()
+		cancel()
+		stack.svc.StopScheduler()
+		db.Close()
+	})
+
+	return stack
+}
+
+// releaseGated 放行所有 gated 任务（可重复调用）
+func (s *testStack) releaseGated() {
+	select {
+	case <-s.release:
+	default:
+		close(s.release)
+	}
+}
+
+// createTask 通过 gRPC 创建任务
+func (s *testStack) createTask(t *testing.T, req *pb.CreateTaskRequest) *pb.Task {
+	t.Helper()
+
+	task, err := s.client.CreateTask(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	return task
+}
+
+// waitForStatus 轮询直到任务进入指定状态
+func (s *testStack) waitForStatus(t *testing.T, id string, want pb.TaskStatus) *pb.Task {
+	t.Helper()
+
+	deadline := time.Now().Add(waitTimeout)
+	var last *pb.Task
+	for time.Now().Before(deadline) {
+		task, err := s.client.GetTask(context.Background(), &pb.GetTaskRequest{Id: id})
+		if err != nil {
+			t.Fatalf("GetTask failed: %v", err)
+		}
+		if task.Status == want {
+			return task
+		}
+		last = task
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("task %s did not reach %s, last status %s", id, want, last.GetStatus())
+	return nil
+}
+
+// testExecutor 记录执行次数和顺序的测试执行器
+type testExecutor struct {
+	release <-chan struct{}
+
+	mu       sync.Mutex
+	attempts map[string]int
+	order    []string
+}
+
+func newTestExecutor(release <-chan struct{}) *testExecutor {
+	return &testExecutor{
+		release:  release,
+		attempts: make(map[string]int),
+	}
+}
+
+// Execute 实现 service.Executor
+func (e *testExecutor) Execute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	ec := service.ExecutionContextFrom(ctx)
+
+	if task.TaskType == taskTypeGated {
+		ec.Log("waiting for release")
+		select {
+		case <-e.release:
+		case <-ctx.Done():
+			ec.Logf("cleanup: %v", context.Cause(ctx))
+			return nil, ctx.Err()
+		}
+		ec.Log("released")
+	}
+
+	e.mu.Lock()
+	e.attempts[task.ID]++
+	attempt := e.attempts[task.ID]
+	e.order = append(e.order, task.ID)
+	e.mu.Unlock()
+
+	ec.Logf("attempt %d", attempt)
+
+	failTimes, _ := strconv.Atoi(task.InputParams["fail_times"])
+	if attempt <= failTimes {
+		return nil, fmt.Errorf("attempt %d failed", attempt)
+	}
+
+	return map[string]string{"attempt": strconv.Itoa(attempt)}, nil
+}
+
+// Attempts 任务执行次数
+func (e *testExecutor) Attempts(taskID string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.attempts[taskID]
+}
+
+// Order 任务执行顺序
+func (e *testExecutor) Order() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.order...)
+}
@@ -0,0 +1,375 @@
+// Package integrity 数据完整性检查：扫描任务数据中的不一致（依赖的任务不存在的待调度任务、没有执行租约的运行中任务、
+// 已删除任务残留的事件、缺少完成时间的终态任务），生成报告并可选修复。可按需运行，也可定期运行
+package integrity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// Operator 修复产生的状态事件的操作者
+const Operator = "integrity-checker"
+
+// IssueKind 不一致的类别
+type IssueKind string
+
+const (
+	// IssueMissingDependency PENDING 任务依赖的任务不存在，调度器每次评估都报错，任务永远不会被调度；修复时标记为 SKIPPED
+	IssueMissingDependency IssueKind = "missing_dependency"
+	// IssueOrphanedRunning RUNNING 任务没有租约：未记录执行实例、执行实例已注销或心跳超过 LeaseTimeout；修复时标记为 FAILED
+	IssueOrphanedRunning IssueKind = "orphaned_running"
+	// IssueOrphanedEvents 任务已删除但事件仍在；修复时删除这些事件
+	IssueOrphanedEvents IssueKind = "orphaned_events"
+	// IssueMissingCompletedAt 终态任务没有完成时间；修复时按进入该状态的事件时间补记，没有该事件时取更新时间
+	IssueMissingCompletedAt IssueKind = "missing_completed_at"
+)
+
+// IssueKinds 全部不一致类别
+var IssueKinds = []IssueKind{IssueMissingDependency, IssueOrphanedRunning, IssueOrphanedEvents, IssueMissingCompletedAt}
+
+// Issue 一处不一致
+type Issue struct {
+	Kind        IssueKind `json:"kind"`
+	TaskID      string    `json:"task_id"`
+	Detail      string    `json:"detail"`
+	Repaired    bool      `json:"repaired"`
+	RepairError string    `json:"repair_error,omitempty"` // 修复失败的原因；任务在检查后已被并发修改时也不修复
+}
+
+// Report 一轮检查的结果
+type Report struct {
+	StartedAt    time.Time         `json:"started_at"`
+	FinishedAt   time.Time         `json:"finished_at"`
+	Repair       bool              `json:"repair"`
+	TasksScanned int               `json:"tasks_scanned"`
+	Counts       map[IssueKind]int `json:"counts"`    // 各类不一致的数量，包括超出 MaxIssues 未列出的
+	Issues       []Issue           `json:"issues"`    // 最多 MaxIssues 条
+	Truncated    bool              `json:"truncated"` // 不一致超过 MaxIssues，Issues 不完整
+}
+
+// Options 检查参数
+type Options struct {
+	CheckInterval time.Duration // 定期检查的间隔，默认 1 小时
+	Repair        bool          // 定期检查时是否修复，按需检查由调用方指定
+	LeaseTimeout  time.Duration // 执行实例心跳超过该时长视为下线，最近该时长内认领、更新或上报过心跳的任务不检查，默认 1 分钟；须大于实例心跳间隔
+	BatchSize     int           // 每次读取的任务数，默认 500
+	MaxIssues     int           // 报告中列出的不一致数上限，默认 1000
+	InstanceID    string        // 记录在修复事件中的实例 ID
+	Clock         clock.Clock   // 判断租约和检查间隔的时钟，默认 clock.Real
+}
+
+// Checker 数据完整性检查器。同一检查器同时只运行一轮；修复使用条件更新，任务在检查后被并发修改时不修复，
+// 多个实例同时检查不会重复修复
+type Checker struct {
+	repo repository.TaskStore
+	opts Options
+
+	running sync.Mutex // 同时只运行一轮检查
+
+	mu   sync.Mutex
+	last *Report
+}
+
+// NewChecker 创建检查器
+func NewChecker(repo repository.TaskStore, opts Options) *Checker {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = time.Hour
+	}
+	if opts.LeaseTimeout <= 0 {
+		opts.LeaseTimeout = time.Minute
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.MaxIssues <= 0 {
+		opts.MaxIssues = 1000
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+	return &Checker{repo: repo, opts: opts}
+}
+
+// Run 定期检查，直到 ctx 取消
+func (c *Checker) Run(ctx context.Context) {
+	ticker := c.opts.Clock.NewTicker(c.opts.CheckInterval)
+	defer ticker.Stop()
+
+	logger.Infof("Integrity checker started, checking every %s (repair: %t)", c.opts.CheckInterval, c.opts.Repair)
+	for {
+		if _, err := c.Check(c.opts.Repair); err != nil {
+			logger.Errorf("Integrity check failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("Integrity checker stopped")
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// LastReport 最近一轮完成的检查的报告，尚未完成检查时为 nil
+func (c *Checker) LastReport() *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// Check 检查一轮，repair 为 true 时同时修复。读取失败时中止并返回错误，单个任务修复失败记录在报告中
+func (c *Checker) Check(repair bool) (*Report, error) {
+	c.running.Lock()
+	defer c.running.Unlock()
+
+	report := &Report{StartedAt: c.opts.Clock.Now(), Repair: repair, Counts: make(map[IssueKind]int), Issues: []Issue{}}
+	var running []*model.Task
+	for afterID := ""; ; {
+		tasks, err := c.repo.ListAfterID(afterID, c.opts.BatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tasks: %w", err)
+		}
+		report.TasksScanned += len(tasks)
+		if err := c.checkDependencies(report, tasks, repair); err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			switch {
+			case task.Status == model.TaskStatusRunning:
+				running = append(running, task)
+			case task.Status.IsTerminal() && task.CompletedAt == nil:
+				c.checkCompletedAt(report, task, repair)
+			}
+		}
+		if len(tasks) < c.opts.BatchSize {
+			break
+		}
+		afterID = tasks[len(tasks)-1].ID
+	}
+	// 扫描完成后再读取实例心跳，扫描期间开始运行的任务的实例已经注册
+	if err := c.checkLeases(report, running, repair); err != nil {
+		return nil, err
+	}
+	if err := c.checkOrphanedEvents(report, repair); err != nil {
+		return nil, err
+	}
+	report.FinishedAt = c.opts.Clock.Now()
+
+	for _, kind := range IssueKinds {
+		metrics.SetIntegrityIssues(string(kind), report.Counts[kind])
+	}
+	if len(report.Counts) > 0 {
+		logger.Warnf("Integrity check found inconsistencies in %d task(s): %v (repair: %t)", report.TasksScanned, report.Counts, repair)
+	}
+	c.mu.Lock()
+	c.last = report
+	c.mu.Unlock()
+	return report, nil
+}
+
+// add 记录一处不一致，fix 不为 nil 时执行修复
+func (c *Checker) add(report *Report, kind IssueKind, taskID, detail string, fix func() error) {
+	report.Counts[kind]++
+	issue := Issue{Kind: kind, TaskID: taskID, Detail: detail}
+	if fix != nil {
+		switch err := fix(); {
+		case errors.Is(err, repository.ErrStatusMismatch):
+			issue.RepairError = "task changed since it was checked"
+		case err != nil:
+			issue.RepairError = err.Error()
+			logger.Errorf("Failed to repair %s of task %s: %v", kind, taskID, err)
+		default:
+			issue.Repaired = true
+			metrics.RecordIntegrityRepair(string(kind))
+			logger.Infof("Repaired %s of task %s: %s", kind, taskID, detail)
+		}
+	}
+	if len(report.Issues) >= c.opts.MaxIssues {
+		report.Truncated = true
+		return
+	}
+	report.Issues = append(report.Issues, issue)
+}
+
+// checkDependencies 检查一批任务中 PENDING 任务的依赖是否存在；已开始或已结束的任务不再受依赖影响，不检查
+func (c *Checker) checkDependencies(report *Report, tasks []*model.Task, repair bool) error {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, task := range tasks {
+		if task.Status != model.TaskStatusPending {
+			continue
+		}
+		for _, id := range task.Dependencies {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	deps, err := c.repo.GetByIDs(ids)
+	if err != nil {
+		return fmt.Errorf("failed to load dependencies: %w", err)
+	}
+	exists := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		if dep != nil {
+			exists[dep.ID] = true
+		}
+	}
+
+	for _, task := range tasks {
+		if task.Status != model.TaskStatusPending {
+			continue
+		}
+		var missing []string
+		for _, id := range task.Dependencies {
+			if !exists[id] {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		detail := "dependency not found: " + strings.Join(missing, ", ")
+		var fix func() error
+		if repair {
+			fix = func() error {
+				return c.repo.UpdateStatusWithInstanceEvent(task.ID, model.TaskStatusPending, model.TaskStatusSkipped,
+					Operator, "skipped: "+detail, c.opts.InstanceID, nil)
+			}
+		}
+		c.add(report, IssueMissingDependency, task.ID, detail, fix)
+	}
+	return nil
+}
+
+// checkLeases 检查 RUNNING 任务的执行实例是否仍在心跳。工作池队列中尚未开始执行的任务也是 RUNNING，
+// 只要实例在心跳就视为持有租约
+func (c *Checker) checkLeases(report *Report, running []*model.Task, repair bool) error {
+	if len(running) == 0 {
+		return nil
+	}
+	list, err := c.repo.ListSchedulerInstances()
+	if err != nil {
+		return fmt.Errorf("failed to list scheduler instances: %w", err)
+	}
+	instances := make(map[string]*model.SchedulerInstance, len(list))
+	for _, inst := range list {
+		instances[inst.ID] = inst
+	}
+
+	now := c.opts.Clock.Now()
+	for _, task := range running {
+		if now.Sub(lastActive(task)) < c.opts.LeaseTimeout {
+			continue
+		}
+		var detail string
+		switch inst := instances[task.ExecutedBy]; {
+		case task.ExecutedBy == "":
+			detail = "no executing instance recorded"
+		case inst == nil:
+			detail = fmt.Sprintf("executing instance %s is not registered", task.ExecutedBy)
+		case now.Sub(inst.HeartbeatAt) > c.opts.LeaseTimeout:
+			detail = fmt.Sprintf("executing instance %s last heartbeat %s ago", task.ExecutedBy, now.Sub(inst.HeartbeatAt).Round(time.Second))
+		default:
+			continue
+		}
+		var fix func() error
+		if repair {
+			fix = func() error { return c.failOrphaned(task, detail) }
+		}
+		c.add(report, IssueOrphanedRunning, task.ID, detail, fix)
+	}
+	return nil
+}
+
+// failOrphaned 把没有租约的 RUNNING 任务标记为 FAILED。先重新读取任务，扫描后被重新调度或有更新（执行实例或更新时间变化）时不修复
+func (c *Checker) failOrphaned(task *model.Task, detail string) error {
+	current, err := c.repo.GetByID(task.ID)
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		return repository.ErrStatusMismatch
+	}
+	if err != nil {
+		return err
+	}
+	if current.Status != model.TaskStatusRunning || current.ExecutedBy != task.ExecutedBy || !current.UpdatedAt.Equal(task.UpdatedAt) {
+		return repository.ErrStatusMismatch
+	}
+	msg := "lost lease: " + detail
+	return c.repo.FailTask(task.ID, msg, model.ErrorClassRetryable, Operator, msg, c.opts.InstanceID, nil)
+}
+
+// checkCompletedAt 记录缺少完成时间的终态任务，修复时补记完成时间
+func (c *Checker) checkCompletedAt(report *Report, task *model.Task, repair bool) {
+	var fix func() error
+	if repair {
+		fix = func() error {
+			events, err := c.repo.GetEventsByTaskID(task.ID)
+			if err != nil {
+				return err
+			}
+			at := task.UpdatedAt
+			for i := len(events) - 1; i >= 0; i-- {
+				if events[i].ToStatus == task.Status && events[i].FromStatus != task.Status {
+					at = events[i].Timestamp
+					break
+				}
+			}
+			return c.repo.SetCompletedAt(task.ID, task.Status, at)
+		}
+	}
+	c.add(report, IssueMissingCompletedAt, task.ID, fmt.Sprintf("%s task has no completed_at", task.Status), fix)
+}
+
+// checkOrphanedEvents 记录已删除任务残留的事件，修复时删除
+func (c *Checker) checkOrphanedEvents(report *Report, repair bool) error {
+	for afterID := ""; ; {
+		ids, err := c.repo.ListOrphanedEventTaskIDs(afterID, c.opts.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list orphaned events: %w", err)
+		}
+		var deleted int64
+		var deleteErr error
+		if repair && len(ids) > 0 {
+			deleted, deleteErr = c.repo.DeleteOrphanedEvents(ids)
+		}
+		for _, id := range ids {
+			var fix func() error
+			if repair {
+				fix = func() error { return deleteErr }
+			}
+			c.add(report, IssueOrphanedEvents, id, "events remain for a deleted task", fix)
+		}
+		if deleted > 0 {
+			logger.Infof("Deleted %d orphaned event(s) of %d deleted task(s)", deleted, len(ids))
+		}
+		if len(ids) < c.opts.BatchSize {
+			return nil
+		}
+		afterID = ids[len(ids)-1]
+	}
+}
+
+// lastActive 任务最近的活动时间：认领（更新时间）、开始执行和执行器上报心跳中最晚的
+func lastActive(task *model.Task) time.Time {
+	last := task.UpdatedAt
+	for _, t := range []*time.Time{task.StartedAt, task.HeartbeatAt} {
+		if t != nil && t.After(last) {
+			last = *t
+		}
+	}
+	return last
+}
@@ -0,0 +1,136 @@
+package integrity
+
+import (
+	"testing"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+func TestChecker_Check(t *testing.T) {
+	repo, _ := repository.NewMemoryRepositories()
+	finished := time.Now().UTC().Add(-5 * time.Minute).Truncate(time.Second)
+
+	create := func(id string, status model.TaskStatus, mutate func(*model.Task)) {
+		t.Helper()
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "shell", nil, nil, 0, "alice")
+		task.ID = id
+		task.Status = status
+		if mutate != nil {
+			mutate(task)
+		}
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	create("upstream", model.TaskStatusSucceeded, func(task *model.Task) { task.CompletedAt = &finished })
+	create("waiting", model.TaskStatusPending, func(task *model.Task) { task.Dependencies = []string{"upstream", "deleted"} })
+	create("healthy", model.TaskStatusPending, func(task *model.Task) { task.Dependencies = []string{"upstream"} })
+	for id, instance := range map[string]string{"leased": "live", "orphaned": "crashed"} {
+		create(id, model.TaskStatusPending, nil)
+		if err := repo.UpdateStatusWithInstanceEvent(id, model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "task scheduled", instance, nil); err != nil {
+			t.Fatalf("claim %s: %v", id, err)
+		}
+	}
+	create("unfinished", model.TaskStatusFailed, nil)
+	if err := repo.AddEvent(&model.TaskEvent{TaskID: "unfinished", FromStatus: model.TaskStatusRunning, ToStatus: model.TaskStatusFailed, Timestamp: finished}); err != nil {
+		t.Fatalf("add event: %v", err)
+	}
+	// 任务已删除，事件仍在
+	if err := repo.AddEvent(&model.TaskEvent{TaskID: "deleted", ToStatus: model.TaskStatusSucceeded, Timestamp: finished}); err != nil {
+		t.Fatalf("add event: %v", err)
+	}
+
+	// 刚开始运行的任务不检查租约
+	if report, _ := NewChecker(repo, Options{}).Check(false); report.Counts[IssueOrphanedRunning] != 0 {
+		t.Errorf("expected recently started tasks to be skipped, got %+v", report.Issues)
+	}
+
+	now := time.Now().Add(10 * time.Minute)
+	if err := repo.UpsertSchedulerInstance(&model.SchedulerInstance{ID: "live", HeartbeatAt: now.Add(-5 * time.Second)}); err != nil {
+		t.Fatalf("upsert instance: %v", err)
+	}
+	c := NewChecker(repo, Options{BatchSize: 2, Clock: clock.NewFake(now)})
+	issues := func(report *Report) map[IssueKind][]string {
+		found := make(map[IssueKind][]string)
+		for _, issue := range report.Issues {
+			found[issue.Kind] = append(found[issue.Kind], issue.TaskID)
+		}
+		return found
+	}
+
+	// 只报告不修复
+	report, err := c.Check(false)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if report.TasksScanned != 6 {
+		t.Errorf("expected 6 tasks scanned, got %d", report.TasksScanned)
+	}
+	found := issues(report)
+	for kind, want := range map[IssueKind]string{
+		IssueMissingDependency:  "waiting",
+		IssueOrphanedRunning:    "orphaned",
+		IssueOrphanedEvents:     "deleted",
+		IssueMissingCompletedAt: "unfinished",
+	} {
+		if got := found[kind]; len(got) != 1 || got[0] != want {
+			t.Errorf("expected %s issue for %s, got %v", kind, want, got)
+		}
+	}
+	if task, _ := repo.GetByID("orphaned"); task.Status != model.TaskStatusRunning {
+		t.Errorf("expected a report-only check to leave tasks unchanged, got %s", task.Status)
+	}
+	if c.LastReport() != report {
+		t.Errorf("expected the last report to be kept")
+	}
+
+	// 修复
+	report, err = c.Check(true)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	for _, issue := range report.Issues {
+		if !issue.Repaired {
+			t.Errorf("expected %s of %s to be repaired: %s", issue.Kind, issue.TaskID, issue.RepairError)
+		}
+	}
+	if task, _ := repo.GetByID("waiting"); task.Status != model.TaskStatusSkipped {
+		t.Errorf("expected the task with a missing dependency skipped, got %s", task.Status)
+	}
+	if task, _ := repo.GetByID("orphaned"); task.Status != model.TaskStatusFailed || task.ErrorClass != model.ErrorClassRetryable {
+		t.Errorf("expected the orphaned task failed as retryable, got %s (%q)", task.Status, task.ErrorClass)
+	}
+	if task, _ := repo.GetByID("leased"); task.Status != model.TaskStatusRunning {
+		t.Errorf("expected the leased task still running, got %s", task.Status)
+	}
+	if task, _ := repo.GetByID("unfinished"); task.CompletedAt == nil || !task.CompletedAt.Equal(finished) {
+		t.Errorf("expected completed_at restored from the failure event, got %v", task.CompletedAt)
+	}
+	if events, _ := repo.GetEventsByTaskID("deleted"); len(events) != 0 {
+		t.Errorf("expected orphaned events deleted, got %d", len(events))
+	}
+
+	// 修复后再次检查没有不一致
+	if report, _ := c.Check(false); len(report.Issues) != 0 {
+		t.Errorf("expected no issues after repair, got %+v", report.Issues)
+	}
+}
+
+func TestChecker_MaxIssues(t *testing.T) {
+	repo, _ := repository.NewMemoryRepositories()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := repo.AddEvent(&model.TaskEvent{TaskID: id, Timestamp: time.Now()}); err != nil {
+			t.Fatalf("add event: %v", err)
+		}
+	}
+	report, err := NewChecker(repo, Options{MaxIssues: 2}).Check(false)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(report.Issues) != 2 || !report.Truncated || report.Counts[IssueOrphanedEvents] != 3 {
+		t.Errorf("expected 2 of 3 issues listed, got %d listed, counts %v, truncated %t", len(report.Issues), report.Counts, report.Truncated)
+	}
+}
@@ -5,8 +5,8 @@ import (
 )
 
 var (
-	// Logger 全局日志实例
-	Logger *zap.SugaredLogger
+	// Logger 全局日志实例（未初始化前为 no-op，避免测试或库调用时 panic）
+	Logger = zap.NewNop().Sugar()
 )
 
 // Init 初始化日志
@@ -1,6 +1,9 @@
 package metrics
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -44,6 +47,145 @@ var (
 		Help:    "gRPC request latency in seconds",
 		Buckets: prometheus.DefBuckets,
 	}, []string{"method"})
+
+	// RequestsShed - requests rejected by the in-flight limiter
+	RequestsShed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_requests_shed_total",
+		Help: "Total number of requests rejected because the in-flight limit was reached",
+	}, []string{"protocol"})
+
+	// WorkerPoolSize - current number of scheduler workers
+	WorkerPoolSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "taskflow_worker_pool_size",
+		Help: "Current number of scheduler workers",
+	})
+
+	// WorkerScaling - worker pool autoscaling events
+	WorkerScaling = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_worker_scaling_total",
+		Help: "Total number of worker pool scaling decisions",
+	}, []string{"direction"})
+
+	// SchedulerBackpressure - tasks deferred or requeued because the worker pool queue was full
+	SchedulerBackpressure = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_scheduler_backpressure_total",
+		Help: "Total number of tasks deferred or requeued because the worker pool was saturated",
+	}, []string{"action"})
+
+	// OutboxDeliveries - outbox event delivery attempts by sink and result
+	OutboxDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_outbox_deliveries_total",
+		Help: "Total number of outbox event delivery attempts",
+	}, []string{"sink", "result"})
+
+	// RepositoryQueryDuration - repository method duration by operation
+	RepositoryQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "taskflow_repository_query_duration_seconds",
+		Help:    "Repository query duration in seconds",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	// RepositorySlowQueries - repository queries slower than the slow query threshold
+	RepositorySlowQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_repository_slow_queries_total",
+		Help: "Total number of repository queries slower than the slow query threshold",
+	}, []string{"operation"})
+
+	// TaskCacheLookups - task cache lookups by result (hit, miss, error)
+	TaskCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_task_cache_lookups_total",
+		Help: "Total number of task cache lookups",
+	}, []string{"result"})
+
+	// TaskCacheInvalidations - task cache entries invalidated by writes or events
+	TaskCacheInvalidations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "taskflow_task_cache_invalidations_total",
+		Help: "Total number of task cache invalidations",
+	})
+
+	// TaskCacheEvictions - tasks evicted from the in-process cache because it was full
+	TaskCacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "taskflow_task_cache_evictions_total",
+		Help: "Total number of tasks evicted from the in-process task cache",
+	})
+
+	// ReplicaReads - stale-tolerant repository reads by target (replica, primary)
+	ReplicaReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_replica_reads_total",
+		Help: "Total number of stale-tolerant task reads by the database that served them",
+	}, []string{"target"})
+
+	// ReplicaLagSeconds - measured replication lag of each read replica, -1 when unavailable
+	ReplicaLagSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "taskflow_replica_lag_seconds",
+		Help: "Measured replication lag of each read replica, -1 when it could not be measured",
+	}, []string{"replica"})
+
+	// IntegrityIssues - inconsistencies found by the last data integrity check, by kind
+	IntegrityIssues = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "taskflow_integrity_issues",
+		Help: "Number of data inconsistencies found by the last integrity check, by kind",
+	}, []string{"kind"})
+
+	// IntegrityRepairs - inconsistencies repaired by the integrity checker, by kind
+	IntegrityRepairs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_integrity_repairs_total",
+		Help: "Total number of data inconsistencies repaired by the integrity checker, by kind",
+	}, []string{"kind"})
+
+	// SchedulerResourceSlotsInUse - resource slots held by running tasks
+	SchedulerResourceSlotsInUse = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "taskflow_scheduler_resource_slots_in_use",
+		Help: "Number of resource slots held by tasks running on this scheduler",
+	})
+
+	// SchedulerResourceCapacity - resource slot budget, 0 means unlimited
+	SchedulerResourceCapacity = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "taskflow_scheduler_resource_capacity",
+		Help: "Resource slot budget of this scheduler, 0 means unlimited",
+	})
+
+	// OutboxBacklog - undelivered outbox events
+	OutboxBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "taskflow_outbox_backlog",
+		Help: "Number of outbox events not yet delivered",
+	})
+
+	// SLABreaches - tasks detected past their SLA deadline by task type and status at detection
+	SLABreaches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_sla_breaches_total",
+		Help: "Total number of tasks that missed their SLA deadline",
+	}, []string{"task_type", "status"})
+
+	// QueueTimeExceeded - tasks detected pending longer than the max queue time of their type, and whether their priority was escalated
+	QueueTimeExceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_queue_time_exceeded_total",
+		Help: "Total number of tasks that waited longer than the max queue time before their first run",
+	}, []string{"task_type", "escalated"})
+
+	// WorkflowDeadlineCancelled - unfinished tasks cancelled because their workflow exceeded its deadline
+	WorkflowDeadlineCancelled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_workflow_deadline_cancelled_total",
+		Help: "Total number of unfinished tasks cancelled because their workflow exceeded its deadline",
+	}, []string{"task_type"})
+
+	// TaskFailureRate - failure rate of each task type over the latest anomaly detection window
+	TaskFailureRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "taskflow_task_failure_rate",
+		Help: "Ratio of failed to finished tasks over the latest anomaly detection window",
+	}, []string{"task_type"})
+
+	// FailureRateAnomaly - whether the failure rate of a task type is currently above its baseline threshold
+	FailureRateAnomaly = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "taskflow_task_failure_rate_anomaly",
+		Help: "1 while the failure rate of a task type is anomalously above its baseline, 0 otherwise",
+	}, []string{"task_type"})
+
+	// FailureRateAnomalies - failure spikes raised by the anomaly detector
+	FailureRateAnomalies = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "taskflow_task_failure_rate_anomalies_total",
+		Help: "Total number of failure rate spikes detected",
+	}, []string{"task_type"})
 )
 
 // RecordTaskStatus records task status count
@@ -75,3 +217,118 @@ func RecordGRPCRequest(method, status string) {
 func RecordGRPCLatency(method string, duration float64) {
 	GRPCLatency.WithLabelValues(method).Observe(duration)
 }
+
+// RecordRequestShed records a request rejected by the in-flight limiter
+func RecordRequestShed(protocol string) {
+	RequestsShed.WithLabelValues(protocol).Inc()
+}
+
+// RecordWorkerPoolSize records the current worker pool size
+func RecordWorkerPoolSize(size int) {
+	WorkerPoolSize.Set(float64(size))
+}
+
+// RecordWorkerScaling records a worker pool scaling decision
+func RecordWorkerScaling(direction string) {
+	WorkerScaling.WithLabelValues(direction).Inc()
+}
+
+// RecordSchedulerBackpressure records a task deferred or requeued due to a saturated worker pool
+func RecordSchedulerBackpressure(action string) {
+	SchedulerBackpressure.WithLabelValues(action).Inc()
+}
+
+// RecordOutboxDelivery records an outbox delivery attempt ("success" or "failure")
+func RecordOutboxDelivery(sink, result string) {
+	OutboxDeliveries.WithLabelValues(sink, result).Inc()
+}
+
+// RecordOutboxBacklog records the number of undelivered outbox events
+func RecordOutboxBacklog(count int) {
+	OutboxBacklog.Set(float64(count))
+}
+
+// RecordRepositoryQuery records a repository query duration
+func RecordRepositoryQuery(operation string, duration float64) {
+	RepositoryQueryDuration.WithLabelValues(operation).Observe(duration)
+}
+
+// RecordRepositorySlowQuery records a repository query slower than the threshold
+func RecordRepositorySlowQuery(operation string) {
+	RepositorySlowQueries.WithLabelValues(operation).Inc()
+}
+
+// RecordTaskCacheLookup records a task cache lookup ("hit", "miss" or "error")
+func RecordTaskCacheLookup(result string) {
+	TaskCacheLookups.WithLabelValues(result).Inc()
+}
+
+// RecordReplicaRead records a stale-tolerant read served by "replica" or "primary"
+func RecordReplicaRead(target string) {
+	ReplicaReads.WithLabelValues(target).Inc()
+}
+
+// SetReplicaLag records the measured lag of a read replica, a negative lag marks it unavailable
+func SetReplicaLag(replica string, lag time.Duration) {
+	if lag < 0 {
+		ReplicaLagSeconds.WithLabelValues(replica).Set(-1)
+		return
+	}
+	ReplicaLagSeconds.WithLabelValues(replica).Set(lag.Seconds())
+}
+
+// SetIntegrityIssues records the number of inconsistencies of a kind found by the last integrity check
+func SetIntegrityIssues(kind string, n int) {
+	IntegrityIssues.WithLabelValues(kind).Set(float64(n))
+}
+
+// RecordIntegrityRepair records a repaired inconsistency
+func RecordIntegrityRepair(kind string) {
+	IntegrityRepairs.WithLabelValues(kind).Inc()
+}
+
+// RecordTaskCacheInvalidation records a task cache invalidation
+func RecordTaskCacheInvalidation() {
+	TaskCacheInvalidations.Inc()
+}
+
+// RecordTaskCacheEviction records a task evicted from the in-process cache
+func RecordTaskCacheEviction() {
+	TaskCacheEvictions.Inc()
+}
+
+// RecordSchedulerResources records scheduler resource slot usage and capacity
+func RecordSchedulerResources(inUse, capacity int) {
+	SchedulerResourceSlotsInUse.Set(float64(inUse))
+	SchedulerResourceCapacity.Set(float64(capacity))
+}
+
+// RecordSLABreach records a task detected past its SLA deadline
+func RecordSLABreach(taskType, status string) {
+	SLABreaches.WithLabelValues(taskType, status).Inc()
+}
+
+// RecordQueueTimeExceeded records a task detected pending longer than the max queue time
+func RecordQueueTimeExceeded(taskType string, escalated bool) {
+	QueueTimeExceeded.WithLabelValues(taskType, strconv.FormatBool(escalated)).Inc()
+}
+
+// RecordWorkflowDeadlineCancelled records a task cancelled because its workflow exceeded its deadline
+func RecordWorkflowDeadlineCancelled(taskType string) {
+	WorkflowDeadlineCancelled.WithLabelValues(taskType).Inc()
+}
+
+// RecordFailureRate records the latest failure rate of a task type and whether it is anomalous
+func RecordFailureRate(taskType string, rate float64, anomalous bool) {
+	TaskFailureRate.WithLabelValues(taskType).Set(rate)
+	v := 0.0
+	if anomalous {
+		v = 1
+	}
+	FailureRateAnomaly.WithLabelValues(taskType).Set(v)
+}
+
+// RecordFailureRateAnomaly records a detected failure spike
+func RecordFailureRateAnomaly(taskType string) {
+	FailureRateAnomalies.WithLabelValues(taskType).Inc()
+}
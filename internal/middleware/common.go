@@ -3,16 +3,22 @@ package middleware
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
 	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
 )
 
-// RequestID 中间件 - 添加请求ID
+// RequestID 中间件 - 添加请求ID，同时写入请求 context，TaskService 据此记录任务和事件的 correlation_id
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
@@ -21,6 +27,7 @@ func RequestID() gin.HandlerFunc {
 		}
 		c.Set("trace_id", requestID)
 		c.Header("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(grpc_middleware.ContextWithRequestID(c.Request.Context(), requestID))
 		c.Next()
 	}
 }
@@ -43,7 +50,7 @@ func Logger() gin.HandlerFunc {
 		}
 
 		logger.Infof("[%s] %s %s %d %v | %s",
-			c.GetHeader("X-Request-ID"),
+			c.GetString("trace_id"),
 			method,
 			path,
 			status,
@@ -104,6 +111,55 @@ func RequestBodyLogger() gin.HandlerFunc {
 	}
 }
 
+// MaxInFlight 并发请求限制中间件，超过 limit 时直接返回 503
+// limit <= 0 表示不限制；skipPaths 中的路径（如健康检查）不受限制
+func MaxInFlight(limit int, skipPaths ...string) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	skip := make(map[string]bool, len(skipPaths))
+	for _, p := range skipPaths {
+		skip[p] = true
+	}
+	sem := make(chan struct{}, limit)
+
+	return func(c *gin.Context) {
+		if skip[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			c.Next()
+		default:
+			metrics.RecordRequestShed("http")
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"code":    errorcode.ErrCodeServerBusy,
+				"message": "server busy: too many in-flight requests",
+			})
+		}
+	}
+}
+
+// BearerToken 令牌校验中间件，请求须携带 Authorization: Bearer <token>，否则返回 401。
+// 用于保护调试等运维端点，token 为空时拒绝所有请求
+func BearerToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodeUnauthorized, "invalid or missing bearer token")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // Timeout 超时控制中间件（优化版 - 修复goroutine泄漏）
 func Timeout(timeout time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBearerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(token string) *gin.Engine {
+		router := gin.New()
+		router.GET("/debug", BearerToken(token), func(c *gin.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+		return router
+	}
+
+	cases := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"valid", "0123456789abcdef", "Bearer 0123456789abcdef", http.StatusOK},
+		{"missing", "0123456789abcdef", "", http.StatusUnauthorized},
+		{"wrong", "0123456789abcdef", "Bearer fedcba9876543210", http.StatusUnauthorized},
+		{"no scheme", "0123456789abcdef", "0123456789abcdef", http.StatusUnauthorized},
+		{"empty configured token", "", "Bearer ", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			newRouter(tc.token).ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tc.want, w.Body.String())
+			}
+			if tc.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
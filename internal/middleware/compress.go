@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的响应压缩编码，deflate 按 HTTP 的定义使用 zlib 格式
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// CompressOptions 响应压缩参数
+type CompressOptions struct {
+	MinSize      int      // 响应体达到该字节数才压缩，<= 0 时不压缩
+	Level        int      // 压缩级别 0~9，-1 为默认级别
+	ContentTypes []string // 允许压缩的内容类型，以 / 结尾的按前缀匹配（如 text/）
+}
+
+// Compress 按 Accept-Encoding 以 gzip 或 deflate 压缩响应：先缓冲响应体，达到 MinSize 且内容类型在允许列表中时才压缩，
+// 不足 MinSize 的响应原样输出。已设置 Content-Encoding 的响应（如 /metrics）、HEAD 请求、范围请求和无响应体的状态码不压缩；
+// 流式响应在第一次 Flush 时尚未达到 MinSize 的，之后不再压缩
+func Compress(opts CompressOptions) gin.HandlerFunc {
+	if opts.MinSize <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, opts: &opts, encoding: encoding}
+		c.Writer = w
+		// panic 时同样收尾，外层的 Recovery 直接写入原始的 ResponseWriter
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding 按 Accept-Encoding 的 q 值选择 gzip 或 deflate，q 相同时优先 gzip；都不接受时返回空
+func negotiateEncoding(header string) string {
+	var best string
+	var bestQ float64
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "*" {
+			name = EncodingGzip
+		}
+		if name != EncodingGzip && name != EncodingDeflate {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > bestQ || q == bestQ && q > 0 && name == EncodingGzip {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter 的输出状态
+const (
+	writeUndecided  = iota // 缓冲中，尚未决定是否压缩
+	writeRaw               // 原样输出
+	writeCompressed        // 压缩输出
+	writeClosed            // 压缩流已结束
+)
+
+var errCompressClosed = errors.New("compressed response already finished")
+
+// compressWriter 缓冲响应体直到可以决定是否压缩的 ResponseWriter
+type compressWriter struct {
+	gin.ResponseWriter
+	opts     *CompressOptions
+	encoding string
+
+	mu    sync.Mutex
+	state int
+	buf   []byte
+	enc   interface {
+		io.WriteCloser
+		Flush() error
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch w.state {
+	case writeRaw:
+		return w.ResponseWriter.Write(p)
+	case writeCompressed:
+		return w.enc.Write(p)
+	case writeClosed:
+		return 0, errCompressClosed
+	}
+
+	if !w.compressible() {
+		if err := w.commitRaw(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.opts.MinSize {
+		if err := w.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written 缓冲中的响应也视为已写入，避免后续中间件重复写入
+func (w *compressWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// WriteHeaderNow 立即写出响应头，之后不再压缩
+func (w *compressWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == writeUndecided {
+		w.commitRaw()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Unwrap 供 http.ResponseController 访问底层连接（如调整写超时）
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush 流式响应：已压缩时刷新压缩流，尚未决定时按原样输出
+func (w *compressWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch w.state {
+	case writeUndecided:
+		w.commitRaw()
+	case writeCompressed:
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close 请求处理结束：输出缓冲中不足 MinSize 的响应，或结束压缩流
+func (w *compressWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch w.state {
+	case writeUndecided:
+		w.commitRaw()
+	case writeCompressed:
+		w.enc.Close()
+		w.state = writeClosed
+	}
+}
+
+// compressible 按响应头判断是否可以压缩，在第一次写入响应体时调用
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	switch status := w.Status(); {
+	case status < http.StatusOK, status == http.StatusNoContent, status == http.StatusPartialContent, status == http.StatusNotModified:
+		return false
+	}
+	mediaType, _, _ := strings.Cut(h.Get("Content-Type"), ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" {
+		return false
+	}
+	for _, allowed := range w.opts.ContentTypes {
+		if mediaType == allowed || strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// commitRaw 决定原样输出，写出已缓冲的内容
+func (w *compressWriter) commitRaw() error {
+	w.state = writeRaw
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// startCompression 设置压缩响应头并把已缓冲的内容写入压缩流
+func (w *compressWriter) startCompression() error {
+	h := w.Header()
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+
+	var err error
+	if w.encoding == EncodingGzip {
+		w.enc, err = gzip.NewWriterLevel(w.ResponseWriter, w.opts.Level)
+	} else {
+		w.enc, err = zlib.NewWriterLevel(w.ResponseWriter, w.opts.Level)
+	}
+	if err != nil {
+		return err
+	}
+	w.state = writeCompressed
+	buf := w.buf
+	w.buf = nil
+	_, err = w.enc.Write(buf)
+	return err
+}
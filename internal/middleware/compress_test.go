@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCompressRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compress(CompressOptions{MinSize: 64, Level: -1, ContentTypes: []string{"application/json", "text/"}}))
+	large := strings.Repeat("x", 256)
+	router.GET("/large", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": large}) })
+	router.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": "x"}) })
+	router.GET("/binary", func(c *gin.Context) { c.Data(http.StatusOK, "application/octet-stream", []byte(large)) })
+	router.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "br")
+		c.Data(http.StatusOK, "text/plain", []byte(large))
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Writer.WriteString("data: first\n\n")
+		c.Writer.Flush()
+		c.Writer.WriteString("data: " + large + "\n\n")
+	})
+	return router
+}
+
+func compressRequest(router *gin.Engine, method, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompress(t *testing.T) {
+	router := newCompressRouter()
+	want := `{"data":"` + strings.Repeat("x", 256) + `"}`
+
+	// gzip
+	w := compressRequest(router, http.MethodGet, "/large", "deflate;q=0.5, gzip")
+	if w.Header().Get("Content-Encoding") != EncodingGzip || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected a gzip response varying on Accept-Encoding, got %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != want {
+		t.Errorf("gzip body = %s", body)
+	}
+
+	// deflate（zlib 格式）
+	w = compressRequest(router, http.MethodGet, "/large", "gzip;q=0.2, deflate")
+	if w.Header().Get("Content-Encoding") != EncodingDeflate {
+		t.Fatalf("expected a deflate response, got %v", w.Header())
+	}
+	zlr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("zlib.NewReader: %v", err)
+	}
+	if body, _ := io.ReadAll(zlr); string(body) != want {
+		t.Errorf("deflate body = %s", body)
+	}
+
+	// 原样输出的情况
+	for _, tt := range []struct {
+		name, method, path, accept string
+	}{
+		{"below the size threshold", http.MethodGet, "/small", "gzip"},
+		{"content type not allowed", http.MethodGet, "/binary", "gzip"},
+		{"already encoded", http.MethodGet, "/encoded", "gzip"},
+		{"no accepted encoding", http.MethodGet, "/large", "br, gzip;q=0"},
+		{"no Accept-Encoding", http.MethodGet, "/large", ""},
+		{"flushed before the threshold", http.MethodGet, "/stream", "gzip"},
+	} {
+		w := compressRequest(router, tt.method, tt.path, tt.accept)
+		if enc := w.Header().Get("Content-Encoding"); enc == EncodingGzip || enc == EncodingDeflate {
+			t.Errorf("%s: expected an uncompressed response, got %s", tt.name, enc)
+		}
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Errorf("%s: got %d with %d bytes", tt.name, w.Code, w.Body.Len())
+		}
+	}
+	if w := compressRequest(router, http.MethodGet, "/stream", "gzip"); !strings.HasPrefix(w.Body.String(), "data: first\n\ndata: xxx") {
+		t.Errorf("stream body = %q", w.Body.String())
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                         "",
+		"gzip":                     EncodingGzip,
+		"deflate, gzip":            EncodingGzip,
+		"gzip;q=0.5, deflate;q=1":  EncodingDeflate,
+		"*":                        EncodingGzip,
+		"br":                       "",
+		"gzip;q=0, deflate;q=0":    "",
+		"identity, deflate;q=0.1 ": EncodingDeflate,
+	} {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	errorcode "taskflow/internal/error"
+)
+
+// 条件请求的请求头
+const (
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// VersionETag 由资源版本号生成的强 ETag，如 "3"，可以原样放入 If-Match
+func VersionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// HashETag 由 parts 的摘要生成的强 ETag，用于没有单一版本号的响应（列表、带子资源的详情）
+func HashETag(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:12]) + `"`
+}
+
+// NotModified 为响应设置 ETag。请求的 If-None-Match 与之匹配（弱比较，* 匹配任意 ETag）时写入 304 并返回 true，
+// 调用方不再写入响应体
+func NotModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	header := c.GetHeader(HeaderIfNoneMatch)
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// IfMatchVersion 解析 If-Match 请求头中由 VersionETag 生成的版本号，未设置或为 * 时返回 0（不检查版本）。
+// 只支持单个 ETag；If-Match 按强比较匹配，弱 ETag 或不是版本号的 ETag 不会与任何版本匹配，返回 ErrCodePreconditionFailed
+func IfMatchVersion(c *gin.Context) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader(HeaderIfMatch))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errorcode.NewValidationError(errorcode.NewFieldViolation(HeaderIfMatch, "single",
+			"only a single entity tag is supported"))
+	}
+	unquoted, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if !ok || err != nil || version <= 0 {
+		return 0, errorcode.NewTaskError(errorcode.ErrCodePreconditionFailed,
+			fmt.Sprintf("%s %s does not match any version", HeaderIfMatch, header))
+	}
+	return version, nil
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	errorcode "taskflow/internal/error"
+)
+
+func TestNotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/task", func(c *gin.Context) {
+		if NotModified(c, VersionETag(3)) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"version": 3})
+	})
+
+	for _, tt := range []struct {
+		ifNoneMatch string
+		want        int
+	}{
+		{"", http.StatusOK},
+		{`"3"`, http.StatusNotModified},
+		{`W/"3"`, http.StatusNotModified},
+		{`"2", "3"`, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"2"`, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/task", nil)
+		if tt.ifNoneMatch != "" {
+			req.Header.Set(HeaderIfNoneMatch, tt.ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want || w.Header().Get("ETag") != `"3"` {
+			t.Errorf("If-None-Match %q: got %d with ETag %q", tt.ifNoneMatch, w.Code, w.Header().Get("ETag"))
+		}
+		if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %q: 304 with body %q", tt.ifNoneMatch, w.Body.String())
+		}
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	for _, tt := range []struct {
+		ifMatch string
+		want    int64
+		status  int // 0 表示无错误
+	}{
+		{"", 0, 0},
+		{"*", 0, 0},
+		{`"7"`, 7, 0},
+		{` "7" `, 7, 0},
+		{`W/"7"`, 0, http.StatusPreconditionFailed},
+		{`"3f2a9c"`, 0, http.StatusPreconditionFailed},
+		{"7", 0, http.StatusPreconditionFailed},
+		{`"6", "7"`, 0, http.StatusBadRequest},
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPut, "/task", nil)
+		c.Request.Header.Set(HeaderIfMatch, tt.ifMatch)
+
+		got, err := IfMatchVersion(c)
+		if got != tt.want {
+			t.Errorf("If-Match %q: version = %d, want %d", tt.ifMatch, got, tt.want)
+		}
+		switch {
+		case tt.status == 0 && err != nil:
+			t.Errorf("If-Match %q: unexpected error %v", tt.ifMatch, err)
+		case tt.status == http.StatusBadRequest:
+			if _, ok := err.(*errorcode.ValidationError); !ok {
+				t.Errorf("If-Match %q: expected a validation error, got %v", tt.ifMatch, err)
+			}
+		case tt.status != 0:
+			if taskErr := errorcode.FromError(err); taskErr == nil || taskErr.HTTPStatus != tt.status {
+				t.Errorf("If-Match %q: expected %d, got %v", tt.ifMatch, tt.status, err)
+			}
+		}
+	}
+}
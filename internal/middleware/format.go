@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	errorcode "taskflow/internal/error"
+)
+
+// 响应格式协商的请求头和响应头
+const (
+	HeaderAcceptProfile  = "Accept-Profile"
+	HeaderContentProfile = "Content-Profile"
+)
+
+// 响应字段命名
+const (
+	NamingSnake = "snake" // 默认，与 proto 字段名一致
+	NamingCamel = "camel"
+)
+
+// 响应信封模式，为空时按 API 版本决定（v1 裸响应，v2 起包裹）
+const (
+	EnvelopeEnveloped = "enveloped"
+	EnvelopeBare      = "bare"
+)
+
+// responseFormatKey gin 上下文中保存请求响应格式的键
+const responseFormatKey = "response_format"
+
+// ResponseFormat 成功响应的字段命名和信封模式
+type ResponseFormat struct {
+	Naming   string // snake 或 camel，为空视为 snake
+	Envelope string // enveloped 或 bare，为空时按 API 版本决定
+}
+
+// ParseResponseProfile 解析 Accept-Profile 请求头，逗号分隔的取值逐项覆盖 base：
+// snake/camel 选择字段命名，enveloped/bare 选择信封模式
+func ParseResponseProfile(header string, base ResponseFormat) (ResponseFormat, error) {
+	format := base
+	for _, token := range strings.Split(header, ",") {
+		switch token = strings.ToLower(strings.TrimSpace(token)); token {
+		case "":
+		case NamingSnake, NamingCamel:
+			format.Naming = token
+		case EnvelopeEnveloped, EnvelopeBare:
+			format.Envelope = token
+		default:
+			return base, fmt.Errorf("unknown response profile %q, expected snake, camel, enveloped or bare", token)
+		}
+	}
+	return format, nil
+}
+
+// ResponseFormatter 记录请求的响应格式：默认取 defaults（配置），Accept-Profile 请求头逐项覆盖，
+// 无法识别的取值返回 400。格式只作用于 Respond 写入的成功响应
+func ResponseFormatter(defaults ResponseFormat) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", HeaderAcceptProfile)
+		format, err := ParseResponseProfile(c.GetHeader(HeaderAcceptProfile), defaults)
+		if err != nil {
+			errorcode.HandleGinError(c, errorcode.NewValidationError(
+				errorcode.NewFieldViolation(HeaderAcceptProfile, "enum", err.Error())))
+			c.Abort()
+			return
+		}
+		c.Set(responseFormatKey, format)
+		c.Next()
+	}
+}
+
+// RequestResponseFormat 返回请求的响应格式，未经过 ResponseFormatter 中间件时返回零值（snake，按版本决定信封）
+func RequestResponseFormat(c *gin.Context) ResponseFormat {
+	format, _ := c.Value(responseFormatKey).(ResponseFormat)
+	return format
+}
+
+// CamelCase 把 v 序列化时的结构体字段名（json 标签）转换为 camelCase，map 的键属于业务数据，保持不变
+func CamelCase(v interface{}) interface{} {
+	return camelValue(reflect.ValueOf(v))
+}
+
+// camelName 把 snake_case 名称转换为 camelCase，如 input_params → inputParams
+func camelName(name string) string {
+	parts := strings.Split(name, "_")
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for _, p := range parts[1:] {
+		if p != "" {
+			b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+		}
+	}
+	return b.String()
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func camelValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	// 自定义序列化的类型（如 time.Time）原样交给 encoding/json
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return camelValue(v.Elem())
+	case reflect.Struct:
+		obj := make(orderedObject, 0, v.NumField())
+		for _, f := range structFields(v.Type()) {
+			fv, err := v.FieldByIndexErr(f.index)
+			if err != nil { // 经过 nil 的嵌入指针，与 encoding/json 一样忽略
+				continue
+			}
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			obj = append(obj, objectField{name: f.name, value: camelValue(fv)})
+		}
+		return obj
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = camelValue(iter.Value())
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		fallthrough
+	case reflect.Array:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = camelValue(v.Index(i))
+		}
+		return items
+	default:
+		return v.Interface()
+	}
+}
+
+// jsonField 结构体中参与序列化的字段
+type jsonField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// fieldCache 按类型缓存的字段列表
+var fieldCache sync.Map // reflect.Type -> []jsonField
+
+// structFields 按 encoding/json 的规则列出导出字段（json 标签名或字段名转为 camelCase），展开无标签的嵌入结构体
+func structFields(t reflect.Type) []jsonField {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]jsonField)
+	}
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, f := range structFields(ft) {
+					f.index = append([]int{i}, f.index...)
+					fields = append(fields, f)
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, jsonField{
+			name:      camelName(name),
+			index:     []int{i},
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// isEmptyValue 与 encoding/json 的 omitempty 判断一致
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// objectField 保持字段顺序的 JSON 对象成员
+type objectField struct {
+	name  string
+	value interface{}
+}
+
+// orderedObject 按结构体字段顺序输出的 JSON 对象，与 encoding/json 对结构体的输出顺序一致
+type orderedObject []objectField
+
+// MarshalJSON 实现 json.Marshaler
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
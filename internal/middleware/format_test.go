@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskflow/internal/apiversion"
+)
+
+type formatTask struct {
+	ID          string            `json:"id"`
+	TaskType    string            `json:"task_type"`
+	InputParams map[string]string `json:"input_params,omitempty"`
+	ErrorClass  string            `json:"error_class,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Events      []formatEvent     `json:"events"`
+	internal    string
+}
+
+type formatEvent struct {
+	ToStatus int32 `json:"to_status"`
+}
+
+func newFormatRouter(defaults ResponseFormat) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	versions := apiversion.Set{{Name: apiversion.V1}, {Name: apiversion.V2}}
+	for _, v := range versions {
+		router.Group("/api/"+v.Name, APIVersion(v, versions), ResponseFormatter(defaults)).GET("/task", func(c *gin.Context) {
+			Respond(c, http.StatusOK, formatTask{
+				ID:          "t1",
+				TaskType:    "shell",
+				InputParams: map[string]string{"retry_limit": "3"},
+				CreatedAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				Events:      []formatEvent{{ToStatus: 1}},
+				internal:    "hidden",
+			})
+		})
+	}
+	return router
+}
+
+func TestRespond_ResponseFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults ResponseFormat
+		path     string
+		profile  string
+		want     string
+		content  string
+	}{
+		{
+			name:    "default v1 snake bare",
+			path:    "/api/v1/task",
+			want:    `{"id":"t1","task_type":"shell","input_params":{"retry_limit":"3"},"created_at":"2026-01-02T03:04:05Z","events":[{"to_status":1}]}`,
+			content: "snake, bare",
+		},
+		{
+			name:    "camel keeps map keys",
+			path:    "/api/v1/task",
+			profile: "camel",
+			want:    `{"id":"t1","taskType":"shell","inputParams":{"retry_limit":"3"},"createdAt":"2026-01-02T03:04:05Z","events":[{"toStatus":1}]}`,
+			content: "camel, bare",
+		},
+		{
+			name:    "camel envelope on v2",
+			path:    "/api/v2/task",
+			profile: "CAMEL",
+			want:    `{"apiVersion":"v2","data":{"id":"t1","taskType":"shell","inputParams":{"retry_limit":"3"},"createdAt":"2026-01-02T03:04:05Z","events":[{"toStatus":1}]}}`,
+			content: "camel, enveloped",
+		},
+		{
+			name:    "bare overrides the v2 envelope",
+			path:    "/api/v2/task",
+			profile: "bare",
+			want:    `{"id":"t1","task_type":"shell","input_params":{"retry_limit":"3"},"created_at":"2026-01-02T03:04:05Z","events":[{"to_status":1}]}`,
+			content: "snake, bare",
+		},
+		{
+			name:     "configured defaults, header overrides one axis",
+			defaults: ResponseFormat{Naming: NamingCamel, Envelope: EnvelopeEnveloped},
+			path:     "/api/v1/task",
+			profile:  "snake",
+			want:     `{"api_version":"v1","data":{"id":"t1","task_type":"shell","input_params":{"retry_limit":"3"},"created_at":"2026-01-02T03:04:05Z","events":[{"to_status":1}]}}`,
+			content:  "snake, enveloped",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.profile != "" {
+				req.Header.Set(HeaderAcceptProfile, tt.profile)
+			}
+			w := httptest.NewRecorder()
+			newFormatRouter(tt.defaults).ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK || w.Body.String() != tt.want {
+				t.Errorf("got %d %s\nwant %s", w.Code, w.Body.String(), tt.want)
+			}
+			if got := w.Header().Get(HeaderContentProfile); got != tt.content {
+				t.Errorf("Content-Profile = %q, want %q", got, tt.content)
+			}
+			if got := w.Header().Get("Vary"); got != HeaderAcceptProfile {
+				t.Errorf("Vary = %q", got)
+			}
+		})
+	}
+}
+
+func TestResponseFormatter_RejectsUnknownProfile(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/task", nil)
+	req.Header.Set(HeaderAcceptProfile, "camel, kebab")
+	w := httptest.NewRecorder()
+	newFormatRouter(ResponseFormat{}).ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown profile, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskflow/internal/apiversion"
+)
+
+// apiVersionKey gin 上下文中保存请求 API 版本的键
+const apiVersionKey = "api_version"
+
+// Envelope v2 起成功响应的外层结构：api_version 为实际处理请求的版本，
+// 版本已弃用时同时给出 deprecated 和 sunset（与响应头一致），业务数据位于 data
+type Envelope struct {
+	APIVersion string      `json:"api_version"`
+	Deprecated bool        `json:"deprecated,omitempty"`
+	Sunset     string      `json:"sunset,omitempty"`
+	Data       interface{} `json:"data"`
+}
+
+// APIVersion 版本路由组中间件：记录请求的 API 版本，并写入 API-Version、API-Supported-Versions
+// 以及已弃用版本的 Deprecation/Sunset/Link 响应头
+func APIVersion(v apiversion.Version, supported apiversion.Set) gin.HandlerFunc {
+	headers := v.Headers()
+	names := supported.Names()
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, v)
+		for k, val := range headers {
+			c.Header(k, val)
+		}
+		c.Header(apiversion.HeaderSupported, names)
+		c.Next()
+	}
+}
+
+// RequestAPIVersion 返回请求的 API 版本，未经过 APIVersion 中间件时返回 false
+func RequestAPIVersion(c *gin.Context) (apiversion.Version, bool) {
+	v, ok := c.Get(apiVersionKey)
+	if !ok {
+		return apiversion.Version{}, false
+	}
+	version, ok := v.(apiversion.Version)
+	return version, ok
+}
+
+// Respond 按请求的 API 版本和响应格式写入成功响应：默认 v1 直接输出 data，v2 起包裹为 Envelope；
+// 响应格式（见 ResponseFormatter）可以强制包裹或不包裹，并把字段名转换为 camelCase
+func Respond(c *gin.Context, code int, data interface{}) {
+	format := RequestResponseFormat(c)
+	v, ok := RequestAPIVersion(c)
+	enveloped := ok && v.Enveloped()
+	switch format.Envelope {
+	case EnvelopeEnveloped:
+		enveloped = true
+	case EnvelopeBare:
+		enveloped = false
+	}
+
+	var body interface{} = data
+	if enveloped {
+		env := Envelope{APIVersion: v.Name, Deprecated: v.Deprecated(), Data: data}
+		if !v.SunsetAt.IsZero() {
+			env.Sunset = v.SunsetAt.UTC().Format(time.RFC3339)
+		}
+		body = env
+	}
+
+	naming, envelope := NamingSnake, EnvelopeBare
+	if format.Naming == NamingCamel {
+		naming = NamingCamel
+		body = CamelCase(body)
+	}
+	if enveloped {
+		envelope = EnvelopeEnveloped
+	}
+	c.Header(HeaderContentProfile, naming+", "+envelope)
+	c.JSON(code, body)
+}
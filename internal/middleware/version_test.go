@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskflow/internal/apiversion"
+)
+
+func newVersionedRouter(versions apiversion.Set) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	for _, v := range versions {
+		router.Group("/api/"+v.Name, APIVersion(v, versions)).GET("/ping", func(c *gin.Context) {
+			Respond(c, 200, gin.H{"pong": true})
+		})
+	}
+	return router
+}
+
+func TestAPIVersion_EnvelopeAndDeprecationHeaders(t *testing.T) {
+	deprecatedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunsetAt := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	router := newVersionedRouter(apiversion.Set{
+		{Name: apiversion.V1, DeprecatedAt: deprecatedAt, SunsetAt: sunsetAt, Link: "https://example.com/migrate"},
+		{Name: apiversion.V2},
+	})
+
+	// v1 保持裸响应并携带弃用头
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil))
+	if w.Body.String() != `{"pong":true}` {
+		t.Errorf("v1 body = %s", w.Body.String())
+	}
+	want := map[string]string{
+		"API-Version":            "v1",
+		"API-Supported-Versions": "v1, v2",
+		"Deprecation":            "@1767225600",
+		"Sunset":                 "Wed, 01 Jul 2026 00:00:00 GMT",
+		"Link":                   `<https://example.com/migrate>; rel="deprecation"`,
+	}
+	for k, v := range want {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("v1 header %s = %q, want %q", k, got, v)
+		}
+	}
+
+	// v2 包裹为信封，未弃用时不带弃用头
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/ping", nil))
+	var env struct {
+		APIVersion string          `json:"api_version"`
+		Deprecated bool            `json:"deprecated"`
+		Data       map[string]bool `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatalf("v2 body %s: %v", w.Body.String(), err)
+	}
+	if env.APIVersion != "v2" || env.Deprecated || !env.Data["pong"] {
+		t.Errorf("v2 envelope = %+v", env)
+	}
+	if w.Header().Get("API-Version") != "v2" || w.Header().Get("Deprecation") != "" || w.Header().Get("Sunset") != "" {
+		t.Errorf("v2 headers = %v", w.Header())
+	}
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+// Now 返回 UTC 当前时间。持久化和对外返回的时间统一为 UTC，
+// 避免不同时区的实例写入带不同偏移量的时间，导致按字符串比较的时间范围查询出错
+func Now() time.Time {
+	return time.Now().UTC()
+}
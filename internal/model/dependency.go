@@ -0,0 +1,112 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DependencyFailurePolicy 依赖边的上游任务最终未成功（FAILED、CANCELLED、TIMEOUT、SKIPPED）时下游任务的处理方式
+type DependencyFailurePolicy string
+
+const (
+	DependencyFailureSkip   DependencyFailurePolicy = "skip"   // 默认：下游任务标记为 SKIPPED，并继续向下游传播
+	DependencyFailureIgnore DependencyFailurePolicy = "ignore" // 视为依赖已满足，下游任务照常运行
+	DependencyFailureWait   DependencyFailurePolicy = "wait"   // 保持 PENDING，等待上游任务被手动重试
+)
+
+// Validate 校验依赖失败处理方式
+func (p DependencyFailurePolicy) Validate() error {
+	switch p {
+	case DependencyFailureSkip, DependencyFailureIgnore, DependencyFailureWait:
+		return nil
+	default:
+		return fmt.Errorf("dependency policy must be one of [skip, ignore, wait], got %s", p)
+	}
+}
+
+// DependencyPolicy 依赖 depID 的上游任务未成功时的处理方式，未配置时为 skip
+func (t *Task) DependencyPolicy(depID string) DependencyFailurePolicy {
+	if p, ok := t.DependencyPolicies[depID]; ok {
+		return p
+	}
+	return DependencyFailureSkip
+}
+
+// RemoveDependency 移除对 depID 的依赖及其失败处理方式，上游任务被删除时使用
+func (t *Task) RemoveDependency(depID string) {
+	t.Dependencies = slices.DeleteFunc(t.Dependencies, func(id string) bool { return id == depID })
+	delete(t.DependencyPolicies, depID)
+	if len(t.DependencyPolicies) == 0 {
+		t.DependencyPolicies = nil
+	}
+}
+
+// DependencyRepair 修复一条依赖：把对 DependencyID 的依赖改为依赖 ReplaceWith，ReplaceWith 为空时移除该依赖
+type DependencyRepair struct {
+	DependencyID string
+	ReplaceWith  string
+}
+
+// RepairDependencies 按顺序应用依赖修复，改为依赖的任务沿用原依赖的失败处理方式。修复的不是当前依赖、
+// 改为依赖任务自身或已依赖的任务时返回全部错误，任务不变；改为依赖的任务是否存在、是否形成环由调用方检查
+func (t *Task) RepairDependencies(repairs []DependencyRepair) []FieldError {
+	if len(repairs) == 0 {
+		return []FieldError{{"repairs", "required", "must list at least one repair"}}
+	}
+
+	dependencies := slices.Clone(t.Dependencies)
+	policies := maps.Clone(t.DependencyPolicies)
+	var errs []FieldError
+	for i, r := range repairs {
+		field := fmt.Sprintf("repairs[%d]", i)
+		idx := slices.Index(dependencies, r.DependencyID)
+		switch {
+		case r.DependencyID == "":
+			errs = append(errs, FieldError{field + ".dependency_id", "required", "must not be empty"})
+		case idx < 0:
+			errs = append(errs, FieldError{field + ".dependency_id", "unknown", "must refer to a current dependency"})
+		case r.ReplaceWith == "":
+			dependencies = slices.Delete(dependencies, idx, idx+1)
+			delete(policies, r.DependencyID)
+		case r.ReplaceWith == t.ID:
+			errs = append(errs, FieldError{field + ".replace_with", "self", "task cannot depend on itself"})
+		case slices.Contains(dependencies, r.ReplaceWith):
+			errs = append(errs, FieldError{field + ".replace_with", "duplicate", "task already depends on " + r.ReplaceWith})
+		default:
+			dependencies[idx] = r.ReplaceWith
+			if policy, ok := policies[r.DependencyID]; ok {
+				delete(policies, r.DependencyID)
+				policies[r.ReplaceWith] = policy
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	t.Dependencies = dependencies
+	t.DependencyPolicies = policies
+	if len(t.DependencyPolicies) == 0 {
+		t.DependencyPolicies = nil
+	}
+	return nil
+}
+
+// DependencyRepairMessage 修复依赖的任务事件说明，如 "dependencies repaired (a -> b, c removed): upstream deleted"
+func DependencyRepairMessage(repairs []DependencyRepair, reason string) string {
+	changes := make([]string, len(repairs))
+	for i, r := range repairs {
+		if r.ReplaceWith == "" {
+			changes[i] = r.DependencyID + " removed"
+		} else {
+			changes[i] = r.DependencyID + " -> " + r.ReplaceWith
+		}
+	}
+	msg := "dependencies repaired (" + strings.Join(changes, ", ") + ")"
+	if reason != "" {
+		msg += ": " + reason
+	}
+	return msg
+}
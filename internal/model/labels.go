@@ -0,0 +1,31 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	MaxLabels           = 64  // 单个任务最多的标签数
+	MaxLabelKeyLength   = 63  // 标签键最大长度
+	MaxLabelValueLength = 255 // 标签值最大长度
+)
+
+// labelKeyPattern 标签键由字母、数字和 . _ / - 组成，以字母或数字开头
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// ValidateLabels 校验任务标签的数量、键格式和值长度
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > MaxLabels {
+		return fmt.Errorf("at most %d labels are allowed, got %d", MaxLabels, len(labels))
+	}
+	for k, v := range labels {
+		if len(k) > MaxLabelKeyLength || !labelKeyPattern.MatchString(k) {
+			return fmt.Errorf("label key %q must be 1-%d characters of letters, digits, '.', '_', '/' or '-' starting with a letter or digit", k, MaxLabelKeyLength)
+		}
+		if len(v) > MaxLabelValueLength {
+			return fmt.Errorf("label %q value must be at most %d characters", k, MaxLabelValueLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 子状态限制
+const (
+	MaxSubStatusLength    = 64   // 子状态最大字符数
+	MaxPhaseMessageLength = 1024 // 阶段说明最大字符数
+	MaxTaskPhases         = 100  // 每个任务保留的阶段历史条数，超出时丢弃最早的阶段
+)
+
+// TaskPhase 执行器上报的一个自定义阶段（子状态），如 UPLOADING、VALIDATING。
+// 阶段持续到下一个阶段开始或本次执行结束，不影响任务状态
+type TaskPhase struct {
+	Name      string    `json:"name" bson:"name"`
+	Message   string    `json:"message,omitempty" bson:"message,omitempty"`
+	Attempt   int32     `json:"attempt" bson:"attempt"` // 上报阶段的执行次数，从 1 开始
+	EnteredAt time.Time `json:"entered_at" bson:"entered_at"`
+}
+
+// ValidateSubStatus 检查子状态和阶段说明：子状态不能为空、不超过 MaxSubStatusLength 个字符且不含控制字符，
+// 说明不超过 MaxPhaseMessageLength 个字符
+func ValidateSubStatus(subStatus, message string) error {
+	if strings.TrimSpace(subStatus) == "" {
+		return errors.New("sub-status must not be empty")
+	}
+	if n := utf8.RuneCountInString(subStatus); n > MaxSubStatusLength {
+		return fmt.Errorf("sub-status has %d characters, at most %d allowed", n, MaxSubStatusLength)
+	}
+	if strings.IndexFunc(subStatus, unicode.IsControl) >= 0 {
+		return errors.New("sub-status must not contain control characters")
+	}
+	if n := utf8.RuneCountInString(message); n > MaxPhaseMessageLength {
+		return fmt.Errorf("phase message has %d characters, at most %d allowed", n, MaxPhaseMessageLength)
+	}
+	return nil
+}
+
+// EnterPhase 把任务的子状态设为 phase.Name 并追加到阶段历史，历史超过 MaxTaskPhases 条时丢弃最早的阶段。
+// 本次执行已处于同名阶段时只更新该阶段的说明，不追加历史，返回 false
+func (t *Task) EnterPhase(phase TaskPhase) bool {
+	if n := len(t.Phases); n > 0 && t.SubStatus == phase.Name && t.Phases[n-1].Name == phase.Name && t.Phases[n-1].Attempt == phase.Attempt {
+		t.Phases[n-1].Message = phase.Message
+		return false
+	}
+	t.SubStatus = phase.Name
+	t.Phases = append(t.Phases, phase)
+	if len(t.Phases) > MaxTaskPhases {
+		t.Phases = append([]TaskPhase(nil), t.Phases[len(t.Phases)-MaxTaskPhases:]...)
+	}
+	return true
+}
+
+// PhaseEventMessage 进入阶段的任务事件说明
+func PhaseEventMessage(subStatus, message string) string {
+	if message == "" {
+		return "entered phase " + subStatus
+	}
+	return "entered phase " + subStatus + ": " + message
+}
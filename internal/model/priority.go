@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// OwnPriority 任务自身的优先级：通过依赖继承被临时提升时为提升前的优先级
+func (t *Task) OwnPriority() TaskPriority {
+	if t.BasePriority != TaskPriorityUnspecified {
+		return t.BasePriority
+	}
+	return t.Priority
+}
+
+// BoostPriority 把任务的优先级临时提升到 priority（下游高优先级任务在等待它），记录提升前的优先级；
+// 当前优先级已不低于 priority 时不改变任务并返回 false
+func (t *Task) BoostPriority(priority TaskPriority, now time.Time) bool {
+	if t.Priority >= priority {
+		return false
+	}
+	t.BasePriority = t.OwnPriority()
+	t.Priority = priority
+	t.UpdatedAt = now
+	return true
+}
+
+// RestorePriority 恢复被提升前的优先级，未被提升时返回 false
+func (t *Task) RestorePriority(now time.Time) bool {
+	if t.BasePriority == TaskPriorityUnspecified {
+		return false
+	}
+	t.Priority = t.BasePriority
+	t.BasePriority = TaskPriorityUnspecified
+	t.UpdatedAt = now
+	return true
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// QueuedSince 任务开始排队等待首次执行的时间：原地重新运行后为重新排队的时间，否则为创建时间
+func (t *Task) QueuedSince() time.Time {
+	if t.QueuedAt != nil {
+		return *t.QueuedAt
+	}
+	return t.CreatedAt
+}
+
+// QueueTime 尚未开始执行的 PENDING 任务到 now 为止的排队时长，其他任务返回 0
+func (t *Task) QueueTime(now time.Time) time.Duration {
+	if t.Status != TaskStatusPending || t.StartedAt != nil {
+		return 0
+	}
+	return max(now.Sub(t.QueuedSince()), 0)
+}
+
+// EscalatePriority 把任务自身的优先级提高到 priority：通过依赖继承临时提升的任务只提高提升前的优先级，
+// 恢复时不再低于 priority。自身优先级已不低于 priority 时不改变任务并返回 false
+func (t *Task) EscalatePriority(priority TaskPriority, now time.Time) bool {
+	if t.OwnPriority() >= priority {
+		return false
+	}
+	if t.Priority > priority {
+		t.BasePriority = priority
+	} else {
+		t.Priority, t.BasePriority = priority, TaskPriorityUnspecified
+	}
+	t.UpdatedAt = now
+	return true
+}
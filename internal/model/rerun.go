@@ -0,0 +1,107 @@
+package model
+
+import (
+	"maps"
+	"time"
+)
+
+// RerunMode 重新运行已结束任务的方式
+type RerunMode int32
+
+const (
+	RerunModeReset RerunMode = 1 // 保存本次运行的结果后把任务重置为 PENDING，在原任务上再次执行
+	RerunModeClone RerunMode = 2 // 按原任务定义创建一个新任务，通过 RerunOf 关联原任务，原任务不变
+)
+
+func (m RerunMode) String() string {
+	switch m {
+	case RerunModeReset:
+		return "RESET"
+	case RerunModeClone:
+		return "CLONE"
+	default:
+		return "UNSPECIFIED"
+	}
+}
+
+// TaskRun 任务原地重新运行前保存的一次运行结果
+type TaskRun struct {
+	TaskID       string            `json:"task_id" bson:"task_id"`
+	Run          int32             `json:"run" bson:"run"` // 第几次运行，从 1 开始
+	Status       TaskStatus        `json:"status" bson:"status"`
+	OutputResult map[string]string `json:"output_result,omitempty" bson:"output_result,omitempty"`
+	OutputRef    string            `json:"output_ref,omitempty" bson:"output_ref,omitempty"`
+	ErrorMessage string            `json:"error_message,omitempty" bson:"error_message,omitempty"`
+	ErrorClass   ErrorClass        `json:"error_class,omitempty" bson:"error_class,omitempty"`
+	RetryCount   int32             `json:"retry_count" bson:"retry_count"`
+	ExecutedBy   string            `json:"executed_by,omitempty" bson:"executed_by,omitempty"`
+	StartedAt    *time.Time        `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	CompletedAt  *time.Time        `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	ArchivedAt   time.Time         `json:"archived_at" bson:"archived_at"` // 重新运行的时间
+}
+
+// CanRerun 检查任务是否可以重新运行：成功、取消和超时的任务可以；
+// 失败的任务通过重试再次执行，跳过的任务随上游重新运行
+func (t *Task) CanRerun() bool {
+	return t.Status == TaskStatusSucceeded || t.Status == TaskStatusCancelled || t.Status == TaskStatusTimeout
+}
+
+// CloneForRerun 按任务定义创建待执行的新任务（未设置 ID），执行状态、结果、SLA 和工作流截止时间不复制
+func (t *Task) CloneForRerun(createdBy string) *Task {
+	clone := NewTask(t.Name, t.Description, t.OwnPriority(), t.TaskType, maps.Clone(t.InputParams),
+		append([]string(nil), t.Dependencies...), t.MaxRetries, createdBy)
+	clone.DependencyPolicies = maps.Clone(t.DependencyPolicies)
+	if t.RetryPolicy != nil {
+		policy := *t.RetryPolicy
+		policy.RetryableErrors = append([]string(nil), policy.RetryableErrors...)
+		clone.RetryPolicy = &policy
+	}
+	clone.ResourceSlots = t.ResourceSlots
+	clone.TeamID = t.TeamID
+	clone.GroupKey = t.GroupKey
+	clone.Labels = maps.Clone(t.Labels)
+	clone.NodeSelector = maps.Clone(t.NodeSelector)
+	clone.TargetWorker = t.TargetWorker
+	clone.RerunOf = t.ID
+	return clone
+}
+
+// ResetForRerun 把已结束的任务重置为待执行，返回保存本次运行结果的记录，run 为本次运行的序号。
+// 重新运行不再受原工作流截止时间的限制
+func (t *Task) ResetForRerun(run int32, now time.Time) TaskRun {
+	record := TaskRun{
+		TaskID:       t.ID,
+		Run:          run,
+		Status:       t.Status,
+		OutputResult: t.OutputResult,
+		OutputRef:    t.OutputRef,
+		ErrorMessage: t.ErrorMessage,
+		ErrorClass:   t.ErrorClass,
+		RetryCount:   t.RetryCount,
+		ExecutedBy:   t.ExecutedBy,
+		StartedAt:    t.StartedAt,
+		CompletedAt:  t.CompletedAt,
+		ArchivedAt:   now,
+	}
+
+	t.Status = TaskStatusPending
+	t.OutputResult = nil
+	t.OutputRef = ""
+	t.ErrorMessage = ""
+	t.ErrorClass = ""
+	t.RetryCount = 0
+	t.NextRunAt = nil
+	t.ExecutedBy = ""
+	t.StartedAt = nil
+	t.CompletedAt = nil
+	t.BlockedReason = ""
+	t.InputRequest = ""
+	t.SignalInput = nil
+	t.Priority, t.BasePriority = t.OwnPriority(), TaskPriorityUnspecified
+	t.QueuedAt = &now
+	t.QueueAlertedAt = nil
+	t.WorkflowDeadline = nil
+	t.SubStatus, t.Phases = "", nil
+	t.UpdatedAt = now
+	return record
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BackoffStrategy 重试退避策略
+type BackoffStrategy string
+
+const (
+	BackoffNone        BackoffStrategy = "none"        // 立即重试
+	BackoffFixed       BackoffStrategy = "fixed"       // 每次等待 InitialDelay
+	BackoffExponential BackoffStrategy = "exponential" // 从 InitialDelay 开始翻倍，不超过 MaxDelay
+)
+
+// ErrorClass 执行错误分类，决定失败后重试还是终止
+type ErrorClass string
+
+const (
+	ErrorClassUnknown     ErrorClass = ""             // 未分类，按重试策略的错误匹配规则处理
+	ErrorClassRetryable   ErrorClass = "retryable"    // 暂时性错误，按退避重试
+	ErrorClassFatal       ErrorClass = "fatal"        // 不可恢复，立即失败
+	ErrorClassRateLimited ErrorClass = "rate_limited" // 被限流，以更长的退避重试
+	ErrorClassTimeout     ErrorClass = "timeout"      // 执行超时，由 RetryOnTimeout 决定是否重试
+)
+
+// 重试策略默认值与上限
+const (
+	DefaultRetryInitialDelay = time.Second
+	DefaultRetryMaxDelay     = 5 * time.Minute
+	MaxRetryAttempts         = 100
+	MaxRetryDelay            = 24 * time.Hour
+)
+
+// RetryPolicy 任务重试策略。零值字段使用默认值：MaxAttempts 为 0 时取 MaxRetries+1，
+// 退避默认为 exponential（1 秒起，最长 5 分钟），RetryableErrors 为空表示所有错误都可重试
+type RetryPolicy struct {
+	MaxAttempts     int32           `json:"max_attempts,omitempty" bson:"max_attempts,omitempty"`         // 最多执行次数（含首次）
+	Backoff         BackoffStrategy `json:"backoff,omitempty" bson:"backoff,omitempty"`                   // none, fixed, exponential
+	InitialDelayMs  int64           `json:"initial_delay_ms,omitempty" bson:"initial_delay_ms,omitempty"` // 首次重试前的等待毫秒数
+	MaxDelayMs      int64           `json:"max_delay_ms,omitempty" bson:"max_delay_ms,omitempty"`         // 单次等待上限毫秒数
+	RetryableErrors []string        `json:"retryable_errors,omitempty" bson:"retryable_errors,omitempty"` // 错误信息包含其中任一子串时才重试（不区分大小写）
+	RetryOnTimeout  bool            `json:"retry_on_timeout,omitempty" bson:"retry_on_timeout,omitempty"` // 执行超时是否重试
+}
+
+// Validate 校验策略参数
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 || p.MaxAttempts > MaxRetryAttempts {
+		return fmt.Errorf("max_attempts must be between 0 and %d, got %d", MaxRetryAttempts, p.MaxAttempts)
+	}
+	switch p.Backoff {
+	case "", BackoffNone, BackoffFixed, BackoffExponential:
+	default:
+		return fmt.Errorf("backoff must be one of [none, fixed, exponential], got %s", p.Backoff)
+	}
+	if p.InitialDelayMs < 0 || p.MaxDelayMs < 0 {
+		return fmt.Errorf("retry delays must be non-negative")
+	}
+	if time.Duration(p.InitialDelayMs)*time.Millisecond > MaxRetryDelay || time.Duration(p.MaxDelayMs)*time.Millisecond > MaxRetryDelay {
+		return fmt.Errorf("retry delays must not exceed %s", MaxRetryDelay)
+	}
+	if p.MaxDelayMs > 0 && p.InitialDelayMs > p.MaxDelayMs {
+		return fmt.Errorf("initial_delay_ms (%d) must not exceed max_delay_ms (%d)", p.InitialDelayMs, p.MaxDelayMs)
+	}
+	for i, pattern := range p.RetryableErrors {
+		if strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("retryable_errors[%d] must not be empty", i)
+		}
+	}
+	return nil
+}
+
+// Attempts 最多执行次数，maxRetries 为任务的 MaxRetries
+func (p *RetryPolicy) Attempts(maxRetries int32) int32 {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return maxRetries + 1
+}
+
+// Delay 第 attempt 次执行失败后（attempt 从 1 开始）到下次执行的等待时间
+func (p *RetryPolicy) Delay(attempt int32) time.Duration {
+	initial := DefaultRetryInitialDelay
+	if p.InitialDelayMs > 0 {
+		initial = time.Duration(p.InitialDelayMs) * time.Millisecond
+	}
+	maxDelay := DefaultRetryMaxDelay
+	if p.MaxDelayMs > 0 {
+		maxDelay = time.Duration(p.MaxDelayMs) * time.Millisecond
+	}
+
+	switch p.Backoff {
+	case BackoffNone:
+		return 0
+	case BackoffFixed:
+		return min(initial, maxDelay)
+	default:
+		d := initial
+		for i := int32(1); i < attempt && d < maxDelay; i++ {
+			d *= 2
+		}
+		return min(d, maxDelay)
+	}
+}
+
+// Retryable 错误是否允许重试，timedOut 表示执行超时
+func (p *RetryPolicy) Retryable(errMsg string, timedOut bool) bool {
+	if timedOut {
+		return p.RetryOnTimeout
+	}
+	if len(p.RetryableErrors) == 0 {
+		return true
+	}
+	lower := strings.ToLower(errMsg)
+	for _, pattern := range p.RetryableErrors {
+		if strings.Contains(lower, strings.ToLower(pattern)) {
+			return true
+		}
+	}
+	return false
+}
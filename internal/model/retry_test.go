@@ -0,0 +1,85 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	tests := []struct {
+		policy   RetryPolicy
+		attempt  int32
+		expected time.Duration
+	}{
+		{RetryPolicy{}, 1, time.Second},
+		{RetryPolicy{}, 3, 4 * time.Second},
+		{RetryPolicy{}, 20, DefaultRetryMaxDelay},
+		{RetryPolicy{Backoff: BackoffNone}, 5, 0},
+		{RetryPolicy{Backoff: BackoffFixed, InitialDelayMs: 500}, 5, 500 * time.Millisecond},
+		{RetryPolicy{Backoff: BackoffExponential, InitialDelayMs: 100, MaxDelayMs: 250}, 3, 250 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.Delay(tt.attempt); got != tt.expected {
+			t.Errorf("Delay(%d) with %+v = %s, expected %s", tt.attempt, tt.policy, got, tt.expected)
+		}
+	}
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	all := &RetryPolicy{}
+	if !all.Retryable("anything", false) {
+		t.Error("expected all errors to be retryable without patterns")
+	}
+	if all.Retryable("deadline exceeded", true) {
+		t.Error("expected timeouts not to be retried without RetryOnTimeout")
+	}
+
+	filtered := &RetryPolicy{RetryableErrors: []string{"connection refused", "503"}, RetryOnTimeout: true}
+	if !filtered.Retryable("dial tcp: Connection Refused", false) {
+		t.Error("expected case-insensitive match to be retryable")
+	}
+	if filtered.Retryable("invalid input", false) {
+		t.Error("expected unmatched error not to be retryable")
+	}
+	if !filtered.Retryable("context deadline exceeded", true) {
+		t.Error("expected timeout to be retryable with RetryOnTimeout")
+	}
+}
+
+func TestRetryPolicy_Validate(t *testing.T) {
+	valid := []RetryPolicy{
+		{},
+		{MaxAttempts: 5, Backoff: BackoffFixed, InitialDelayMs: 1000},
+		{Backoff: BackoffExponential, InitialDelayMs: 100, MaxDelayMs: 1000, RetryableErrors: []string{"timeout"}},
+	}
+	for _, p := range valid {
+		if err := p.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", p, err)
+		}
+	}
+
+	invalid := []RetryPolicy{
+		{MaxAttempts: -1},
+		{MaxAttempts: MaxRetryAttempts + 1},
+		{Backoff: "linear"},
+		{InitialDelayMs: -1},
+		{InitialDelayMs: 2000, MaxDelayMs: 1000},
+		{MaxDelayMs: int64(MaxRetryDelay/time.Millisecond) + 1},
+		{RetryableErrors: []string{" "}},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", p)
+		}
+	}
+}
+
+func TestRetryPolicy_Attempts(t *testing.T) {
+	if got := (&RetryPolicy{}).Attempts(2); got != 3 {
+		t.Errorf("expected MaxRetries+1 attempts by default, got %d", got)
+	}
+	if got := (&RetryPolicy{MaxAttempts: 5}).Attempts(2); got != 5 {
+		t.Errorf("expected explicit MaxAttempts, got %d", got)
+	}
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MaxTargetWorkerLength 目标实例（实例 ID 或主机名）的最大长度
+const MaxTargetWorkerLength = 255
+
+// ValidateRouting 校验任务的路由约束：节点选择器的规则与任务标签相同，目标实例不超过 MaxTargetWorkerLength 个字符
+func ValidateRouting(nodeSelector map[string]string, targetWorker string) error {
+	if err := ValidateLabels(nodeSelector); err != nil {
+		return fmt.Errorf("node selector: %w", err)
+	}
+	if len(targetWorker) > MaxTargetWorkerLength {
+		return fmt.Errorf("target worker must be at most %d characters", MaxTargetWorkerLength)
+	}
+	return nil
+}
+
+// HasRouting 任务是否声明了路由约束（目标实例或节点选择器）
+func (t *Task) HasRouting() bool {
+	return t.TargetWorker != "" || len(t.NodeSelector) > 0
+}
+
+// RoutingMismatch 检查任务的路由约束是否允许由该调度器实例认领：目标实例须等于实例 ID 或主机名，
+// 节点选择器的每个键值都须出现在实例标签中。匹配时返回空字符串，否则返回不匹配的说明
+func (t *Task) RoutingMismatch(instanceID, hostname string, labels map[string]string) string {
+	if t.TargetWorker != "" && t.TargetWorker != instanceID && t.TargetWorker != hostname {
+		return fmt.Sprintf("task targets worker %s", t.TargetWorker)
+	}
+	var missing []string
+	for _, k := range slices.Sorted(maps.Keys(t.NodeSelector)) {
+		if v, ok := labels[k]; !ok || v != t.NodeSelector[k] {
+			missing = append(missing, k+"="+t.NodeSelector[k])
+		}
+	}
+	if len(missing) > 0 {
+		return "worker labels do not match node selector " + strings.Join(missing, ", ")
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// SchedulingReasonCode 任务暂不能调度的原因分类
+type SchedulingReasonCode string
+
+const (
+	ReasonNotPending        SchedulingReasonCode = "not_pending"        // 任务不是 PENDING，不再参与调度
+	ReasonSchedulerStopped  SchedulingReasonCode = "scheduler_stopped"  // 调度器未在运行
+	ReasonSchedulerPaused   SchedulingReasonCode = "scheduler_paused"   // 调度器暂停派发新任务
+	ReasonDependenciesUnmet SchedulingReasonCode = "dependencies_unmet" // 上游任务尚未成功
+	ReasonDependencyFailed  SchedulingReasonCode = "dependency_failed"  // 上游任务最终未成功，下次评估时任务被跳过
+	ReasonRetryBackoff      SchedulingReasonCode = "retry_backoff"      // 自动重试的退避期间
+	ReasonRateLimited       SchedulingReasonCode = "rate_limited"       // 上次执行被下游限流，等待其要求的时间
+	ReasonBlackoutWindow    SchedulingReasonCode = "blackout_window"    // 处于暂停调度的时间窗口
+	ReasonWorkerMismatch    SchedulingReasonCode = "worker_mismatch"    // 任务的目标实例或节点选择器与本实例不匹配
+	ReasonResourceSlots     SchedulingReasonCode = "resource_slots"     // 资源槽位预算不足
+	ReasonExclusion         SchedulingReasonCode = "exclusion"          // 互斥的任务（单例类型、反亲和类型、同一分组）正在运行
+	ReasonQueueFull         SchedulingReasonCode = "queue_full"         // 工作池队列已满
+	ReasonBacklog           SchedulingReasonCode = "backlog"            // 可调度任务过多，本任务不在一轮评估的范围内
+)
+
+// SchedulingReason 任务暂不能调度的一个原因
+type SchedulingReason struct {
+	Code         SchedulingReasonCode `json:"code"`
+	Message      string               `json:"message"`
+	Until        *time.Time           `json:"until,omitempty"`        // 原因预计解除的时间，无法预计时为空
+	Dependencies []DependencyState    `json:"dependencies,omitempty"` // dependencies_unmet、dependency_failed 涉及的上游任务
+}
+
+// DependencyState 阻塞下游任务的上游任务及其状态
+type DependencyState struct {
+	TaskID string                  `json:"task_id"`
+	Status TaskStatus              `json:"status"`
+	Policy DependencyFailurePolicy `json:"policy"` // 上游未成功时下游的处理方式
+}
+
+// SchedulingDecision 调度器对任务的一次只读评估：按调度路径逐项检查，列出当前阻止任务被认领的全部原因
+type SchedulingDecision struct {
+	TaskID       string             `json:"task_id"`
+	Status       TaskStatus         `json:"status"`
+	Dispatchable bool               `json:"dispatchable"` // 没有阻塞原因，调度器下一次评估时会认领
+	Reasons      []SchedulingReason `json:"reasons,omitempty"`
+	InstanceID   string             `json:"instance_id"` // 做出评估的调度器实例，资源、队列等状态是该实例本地的
+	EvaluatedAt  time.Time          `json:"evaluated_at"`
+}
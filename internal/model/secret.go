@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// Secret 命名密钥，值以 AES-GCM 加密后存储，只在任务执行时解密，任何接口都不返回明文
+type Secret struct {
+	Name       string    `json:"name" bson:"_id"`
+	Ciphertext []byte    `json:"-" bson:"ciphertext"`
+	CreatedBy  string    `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" bson:"updated_at"`
+}
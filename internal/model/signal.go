@@ -0,0 +1,50 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"time"
+)
+
+// MaxSignalInputKeys 任务累计的信号输入最多的键数
+const MaxSignalInputKeys = 64
+
+// ValidateSignalPayload 校验送达 WAITING_INPUT 任务的信号输入：至少包含一个键、键不能为空，
+// 与已送达的输入合并后不超过 MaxSignalInputKeys 个键
+func ValidateSignalPayload(existing, payload map[string]string) error {
+	if len(payload) == 0 {
+		return fmt.Errorf("payload must contain at least one key")
+	}
+	for k := range payload {
+		if k == "" {
+			return fmt.Errorf("payload keys must not be empty")
+		}
+	}
+	if merged := len(MergeSignalInput(existing, payload)); merged > MaxSignalInputKeys {
+		return fmt.Errorf("at most %d input keys are allowed, got %d after merging", MaxSignalInputKeys, merged)
+	}
+	return nil
+}
+
+// MergeSignalInput 把信号输入合并到已送达的输入，同名键以本次信号为准；返回新的映射，不修改参数
+func MergeSignalInput(existing, payload map[string]string) map[string]string {
+	merged := make(map[string]string, len(existing)+len(payload))
+	maps.Copy(merged, existing)
+	maps.Copy(merged, payload)
+	return merged
+}
+
+// SuspendForInput 把运行中的任务挂起为 WAITING_INPUT，prompt 说明执行器需要的输入
+func (t *Task) SuspendForInput(prompt string, now time.Time) {
+	t.Status = TaskStatusWaitingInput
+	t.InputRequest = prompt
+	t.UpdatedAt = now
+}
+
+// ApplySignal 合并信号输入，把 WAITING_INPUT 任务重新置为 PENDING 等待调度并清除输入说明
+func (t *Task) ApplySignal(payload map[string]string, now time.Time) {
+	t.Status = TaskStatusPending
+	t.InputRequest = ""
+	t.SignalInput = MergeSignalInput(t.SignalInput, payload)
+	t.UpdatedAt = now
+}
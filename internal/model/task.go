@@ -1,20 +1,27 @@
 package model
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"taskflow/internal/idgen"
 )
 
 // TaskStatus 任务状态枚举
 type TaskStatus int32
 
 const (
-	TaskStatusUnspecified TaskStatus = 0
-	TaskStatusPending     TaskStatus = 1
-	TaskStatusRunning     TaskStatus = 2
-	TaskStatusSucceeded   TaskStatus = 3
-	TaskStatusFailed      TaskStatus = 4
-	TaskStatusCancelled   TaskStatus = 5
-	TaskStatusTimeout     TaskStatus = 6
+	TaskStatusUnspecified  TaskStatus = 0
+	TaskStatusPending      TaskStatus = 1
+	TaskStatusRunning      TaskStatus = 2
+	TaskStatusSucceeded    TaskStatus = 3
+	TaskStatusFailed       TaskStatus = 4
+	TaskStatusCancelled    TaskStatus = 5
+	TaskStatusTimeout      TaskStatus = 6
+	TaskStatusSkipped      TaskStatus = 7 // 上游依赖最终未成功，任务不再执行
+	TaskStatusWaitingInput TaskStatus = 8 // 执行器请求外部输入后挂起，收到信号后重新进入 PENDING
 )
 
 func (s TaskStatus) String() string {
@@ -31,11 +38,42 @@ func (s TaskStatus) String() string {
 		return "CANCELLED"
 	case TaskStatusTimeout:
 		return "TIMEOUT"
+	case TaskStatusSkipped:
+		return "SKIPPED"
+	case TaskStatusWaitingInput:
+		return "WAITING_INPUT"
 	default:
 		return "UNSPECIFIED"
 	}
 }
 
+// ParseTaskStatus 按名称（如 SUCCEEDED，不区分大小写，可带 TASK_STATUS_ 前缀）或数值解析任务状态
+func ParseTaskStatus(s string) (TaskStatus, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+		if status := TaskStatus(n); status > TaskStatusUnspecified && status <= TaskStatusWaitingInput {
+			return status, nil
+		}
+		return TaskStatusUnspecified, fmt.Errorf("unknown task status %q", s)
+	}
+	name := strings.TrimPrefix(strings.ToUpper(s), "TASK_STATUS_")
+	for status := TaskStatusPending; status <= TaskStatusWaitingInput; status++ {
+		if status.String() == name {
+			return status, nil
+		}
+	}
+	return TaskStatusUnspecified, fmt.Errorf("unknown task status %q", s)
+}
+
+// IsTerminal 检查状态是否为终态
+func (s TaskStatus) IsTerminal() bool {
+	return s == TaskStatusSucceeded ||
+		s == TaskStatusFailed ||
+		s == TaskStatusCancelled ||
+		s == TaskStatusTimeout ||
+		s == TaskStatusSkipped
+}
+
 // TaskPriority 任务优先级枚举
 type TaskPriority int32
 
@@ -62,37 +100,190 @@ func (p TaskPriority) String() string {
 	}
 }
 
+// ParseTaskPriority 按名称（如 HIGH，不区分大小写，可带 TASK_PRIORITY_ 前缀）或数值解析任务优先级
+func ParseTaskPriority(s string) (TaskPriority, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+		if priority := TaskPriority(n); priority > TaskPriorityUnspecified && priority <= TaskPriorityUrgent {
+			return priority, nil
+		}
+		return TaskPriorityUnspecified, fmt.Errorf("unknown task priority %q", s)
+	}
+	name := strings.TrimPrefix(strings.ToUpper(s), "TASK_PRIORITY_")
+	for priority := TaskPriorityLow; priority <= TaskPriorityUrgent; priority++ {
+		if priority.String() == name {
+			return priority, nil
+		}
+	}
+	return TaskPriorityUnspecified, fmt.Errorf("unknown task priority %q", s)
+}
+
 // Task 任务实体
 type Task struct {
-	ID            string            `json:"id" bson:"_id"`
-	Name          string            `json:"name" bson:"name"`
-	Description   string            `json:"description" bson:"description"`
-	Status        TaskStatus        `json:"status" bson:"status"`
-	Priority      TaskPriority      `json:"priority" bson:"priority"`
-	TaskType      string            `json:"task_type" bson:"task_type"`
-	InputParams   map[string]string `json:"input_params" bson:"input_params"`
-	OutputResult  map[string]string `json:"output_result" bson:"output_result"`
-	Dependencies  []string          `json:"dependencies" bson:"dependencies"`
-	RetryCount    int32             `json:"retry_count" bson:"retry_count"`
-	MaxRetries    int32             `json:"max_retries" bson:"max_retries"`
-	ErrorMessage  string            `json:"error_message" bson:"error_message"`
-	CreatedAt     time.Time         `json:"created_at" bson:"created_at"`
-	UpdatedAt     time.Time         `json:"updated_at" bson:"updated_at"`
-	StartedAt     *time.Time        `json:"started_at,omitempty" bson:"started_at,omitempty"`
-	CompletedAt   *time.Time        `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
-	CreatedBy     string            `json:"created_by" bson:"created_by"`
-	Events        []TaskEvent       `json:"events" bson:"events"`
+	ID                 string                             `json:"id" bson:"_id"`
+	Name               string                             `json:"name" bson:"name"`
+	Description        string                             `json:"description" bson:"description"`
+	Status             TaskStatus                         `json:"status" bson:"status"`
+	Priority           TaskPriority                       `json:"priority" bson:"priority"`
+	BasePriority       TaskPriority                       `json:"base_priority,omitempty" bson:"base_priority,omitempty"` // 通过依赖继承临时提升优先级前任务自身的优先级，未提升时为 UNSPECIFIED
+	TaskType           string                             `json:"task_type" bson:"task_type"`
+	InputParams        map[string]string                  `json:"input_params" bson:"input_params"`
+	OutputResult       map[string]string                  `json:"output_result" bson:"output_result"`
+	Dependencies       []string                           `json:"dependencies" bson:"dependencies"`
+	DependencyPolicies map[string]DependencyFailurePolicy `json:"dependency_policies,omitempty" bson:"dependency_policies,omitempty"` // 按依赖 ID 配置上游未成功时的处理方式，未配置的依赖为 skip
+	RetryCount         int32                              `json:"retry_count" bson:"retry_count"`
+	MaxRetries         int32                              `json:"max_retries" bson:"max_retries"`
+	RetryPolicy        *RetryPolicy                       `json:"retry_policy,omitempty" bson:"retry_policy,omitempty"` // 为空时失败任务不自动重试
+	NextRunAt          *time.Time                         `json:"next_run_at,omitempty" bson:"next_run_at,omitempty"`   // 重试退避期间最早可调度的时间
+	ErrorMessage       string                             `json:"error_message" bson:"error_message"`
+	ErrorClass         ErrorClass                         `json:"error_class,omitempty" bson:"error_class,omitempty"`       // 最近一次执行错误的分类
+	BlockedReason      string                             `json:"blocked_reason,omitempty" bson:"blocked_reason,omitempty"` // PENDING 任务暂不能调度的原因
+	ResourceSlots      int32                              `json:"resource_slots,omitempty" bson:"resource_slots,omitempty"` // 运行时占用的资源槽位，0 表示 DefaultResourceSlots
+	CreatedAt          time.Time                          `json:"created_at" bson:"created_at"`
+	UpdatedAt          time.Time                          `json:"updated_at" bson:"updated_at"`
+	StartedAt          *time.Time                         `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	CompletedAt        *time.Time                         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	CreatedBy          string                             `json:"created_by" bson:"created_by"`
+	TeamID             string                             `json:"team_id,omitempty" bson:"team_id,omitempty"`
+	CorrelationID      string                             `json:"correlation_id,omitempty" bson:"correlation_id,omitempty"`       // 创建任务的请求 ID
+	GroupKey           string                             `json:"group_key,omitempty" bson:"group_key,omitempty"`                 // 分组键相同的任务串行执行
+	Labels             map[string]string                  `json:"labels,omitempty" bson:"labels,omitempty"`                       // 用户自定义的键值标签
+	ExecutedBy         string                             `json:"executed_by,omitempty" bson:"executed_by,omitempty"`             // 最近一次执行该任务的调度器实例 ID
+	OutputRef          string                             `json:"output_ref,omitempty" bson:"output_ref,omitempty"`               // 输出过大时转存到产物存储的对象 key
+	SLADeadline        *time.Time                         `json:"sla_deadline,omitempty" bson:"sla_deadline,omitempty"`           // 任务应在该时间前成功完成
+	SLABreachedAt      *time.Time                         `json:"sla_breached_at,omitempty" bson:"sla_breached_at,omitempty"`     // SLA 监控记录违约的时间
+	RerunOf            string                             `json:"rerun_of,omitempty" bson:"rerun_of,omitempty"`                   // 克隆重新运行时原任务的 ID
+	ParentID           string                             `json:"parent_id,omitempty" bson:"parent_id,omitempty"`                 // 执行中通过 ExecutionContext 提交子任务时父任务的 ID
+	Progress           int32                              `json:"progress" bson:"progress"`                                       // 执行器上报的本次执行进度，0 到 100
+	ProgressMessage    string                             `json:"progress_message,omitempty" bson:"progress_message,omitempty"`   // 执行器随进度上报的说明
+	HeartbeatAt        *time.Time                         `json:"heartbeat_at,omitempty" bson:"heartbeat_at,omitempty"`           // 执行器最近一次上报心跳或进度的时间
+	Version            int64                              `json:"version" bson:"version"`                                         // 每次写入任务时加一，作为 ETag 和乐观并发控制的依据
+	InputRequest       string                             `json:"input_request,omitempty" bson:"input_request,omitempty"`         // WAITING_INPUT 任务的执行器请求的输入说明
+	SignalInput        map[string]string                  `json:"signal_input,omitempty" bson:"signal_input,omitempty"`           // 信号送达的输入，多次信号按键合并
+	NodeSelector       map[string]string                  `json:"node_selector,omitempty" bson:"node_selector,omitempty"`         // 只由标签包含全部键值的调度器实例认领
+	TargetWorker       string                             `json:"target_worker,omitempty" bson:"target_worker,omitempty"`         // 只由实例 ID 或主机名与之相同的调度器实例认领
+	QueuedAt           *time.Time                         `json:"queued_at,omitempty" bson:"queued_at,omitempty"`                 // 原地重新运行后重新排队的时间，为空时排队时长从创建时间起算
+	QueueAlertedAt     *time.Time                         `json:"queue_alerted_at,omitempty" bson:"queue_alerted_at,omitempty"`   // 排队时长监控记录排队超时的时间
+	WorkflowDeadline   *time.Time                         `json:"workflow_deadline,omitempty" bson:"workflow_deadline,omitempty"` // 所属工作流的截止时间，过后工作流中未结束的任务被取消
+	SubStatus          string                             `json:"sub_status,omitempty" bson:"sub_status,omitempty"`               // 执行器上报的自定义阶段，每次开始执行时清空；任务状态以 Status 为准
+	Phases             []TaskPhase                        `json:"phases,omitempty" bson:"phases,omitempty"`                       // 执行器上报的阶段历史，按开始时间升序
+	Events             []TaskEvent                        `json:"events" bson:"events"`
+	Comments           []TaskComment                      `json:"comments,omitempty" bson:"comments,omitempty"`
+	Runs               []TaskRun                          `json:"runs,omitempty" bson:"runs,omitempty"` // 原地重新运行前保存的历次运行结果
 }
 
 // TaskEvent 任务状态变更事件
 type TaskEvent struct {
-	ID         string     `json:"id" bson:"_id"`
-	TaskID     string     `json:"task_id" bson:"task_id"`
-	FromStatus TaskStatus `json:"from_status" bson:"from_status"`
-	ToStatus   TaskStatus `json:"to_status" bson:"to_status"`
-	Message    string     `json:"message" bson:"message"`
-	Timestamp  time.Time  `json:"timestamp" bson:"timestamp"`
-	Operator   string     `json:"operator" bson:"operator"`
+	ID            string            `json:"id" bson:"_id"`
+	TaskID        string            `json:"task_id" bson:"task_id"`
+	FromStatus    TaskStatus        `json:"from_status" bson:"from_status"`
+	ToStatus      TaskStatus        `json:"to_status" bson:"to_status"`
+	Message       string            `json:"message" bson:"message"`
+	Timestamp     time.Time         `json:"timestamp" bson:"timestamp"`
+	Operator      string            `json:"operator" bson:"operator"`
+	InstanceID    string            `json:"instance_id,omitempty" bson:"instance_id,omitempty"`       // 产生事件的调度器实例 ID
+	CorrelationID string            `json:"correlation_id,omitempty" bson:"correlation_id,omitempty"` // 引起事件的请求 ID，后台产生的事件沿用任务的
+	Seq           int64             `json:"seq" bson:"seq"`                                           // 写入序号，全局单调递增，作为变更流的游标
+	Metadata      map[string]string `json:"metadata,omitempty" bson:"metadata,omitempty"`             // 结构化上下文，键见 EventMeta* 常量
+}
+
+// 任务事件元数据键，由调度器在执行相关的状态变更中填写
+const (
+	EventMetaAttempt      = "attempt"        // 事件所属的第几次执行，从 1 开始
+	EventMetaWorker       = "worker"         // 执行任务的调度器实例 ID
+	EventMetaDurationMs   = "duration_ms"    // 本次执行耗时（毫秒）
+	EventMetaErrorClass   = "error_class"    // 执行失败的错误分类
+	EventMetaRetryDelayMs = "retry_delay_ms" // 自动重试前的等待时间（毫秒）
+	EventMetaPanicStack   = "panic_stack"    // 执行 panic 时的调用栈
+)
+
+// TaskComment 任务评论/注解（如故障排查记录）
+type TaskComment struct {
+	ID        string    `json:"id" bson:"_id"`
+	TaskID    string    `json:"task_id" bson:"task_id"`
+	Author    string    `json:"author" bson:"author"`
+	Body      string    `json:"body" bson:"body"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// TaskAttachment 任务附件元数据，内容保存在对象存储中
+type TaskAttachment struct {
+	ID          string    `json:"id" bson:"_id"`
+	TaskID      string    `json:"task_id" bson:"task_id"`
+	Filename    string    `json:"filename" bson:"filename"`
+	ContentType string    `json:"content_type" bson:"content_type"`
+	Size        int64     `json:"size" bson:"size"`
+	Checksum    string    `json:"checksum" bson:"checksum"` // SHA-256 十六进制
+	StorageKey  string    `json:"-" bson:"storage_key"`
+	UploadedBy  string    `json:"uploaded_by" bson:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
+// TaskLogLine 任务执行日志行，Seq 在任务内单调递增（跨重试连续）
+type TaskLogLine struct {
+	TaskID    string    `json:"task_id" bson:"task_id"`
+	Seq       int64     `json:"seq" bson:"seq"`
+	Attempt   int32     `json:"attempt" bson:"attempt"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+	Line      string    `json:"line" bson:"line"`
+}
+
+// SchedulerInstance 调度器实例（集群节点）心跳记录
+type SchedulerInstance struct {
+	ID            string            `json:"id" bson:"_id"`
+	Hostname      string            `json:"hostname" bson:"hostname"`
+	StartedAt     time.Time         `json:"started_at" bson:"started_at"`
+	HeartbeatAt   time.Time         `json:"heartbeat_at" bson:"heartbeat_at"`
+	WorkerCount   int               `json:"worker_count" bson:"worker_count"`
+	BusyWorkers   int               `json:"busy_workers" bson:"busy_workers"`
+	QueueDepth    int               `json:"queue_depth" bson:"queue_depth"`
+	InFlightTasks []string          `json:"in_flight_tasks" bson:"in_flight_tasks"`
+	Labels        map[string]string `json:"labels,omitempty" bson:"labels,omitempty"` // 实例标签，与任务的节点选择器匹配
+}
+
+// SchedulerCheckpoint 调度器停止时保存的运行状态，以相同名称重新启动的调度器据此恢复，避免计数归零、工作池回到初始大小
+type SchedulerCheckpoint struct {
+	Name              string    `json:"name" bson:"_id"`
+	SavedAt           time.Time `json:"saved_at" bson:"saved_at"`
+	BaseWorkers       int       `json:"base_workers" bson:"base_workers"`               // 启动时按配置的工作池大小，配置变化后不再恢复 WorkerCount
+	WorkerCount       int       `json:"worker_count" bson:"worker_count"`               // 停止时的工作池大小（含运行中调整和自动伸缩的结果）
+	AutoscaleLowTicks int       `json:"autoscale_low_ticks" bson:"autoscale_low_ticks"` // 自动伸缩已累计的低利用率周期数
+	ScheduledCount    int       `json:"scheduled_count" bson:"scheduled_count"`
+	FinishedCount     int       `json:"finished_count" bson:"finished_count"`
+}
+
+// 发件箱事件类型
+const (
+	OutboxEventTaskStatusChanged        = "task.status_changed"        // 任务状态变更
+	OutboxEventTaskSLABreached          = "task.sla_breached"          // 任务超过 SLA 截止时间仍未成功完成
+	OutboxEventTaskPriorityBoosted      = "task.priority_boosted"      // 下游高优先级任务等待时临时提升上游任务的优先级
+	OutboxEventTaskPriorityRestored     = "task.priority_restored"     // 被提升的任务开始执行后恢复原优先级
+	OutboxEventTaskQueueTimeExceeded    = "task.queue_time_exceeded"   // 任务排队等待首次执行的时长超过上限
+	OutboxEventTaskSubStatusChanged     = "task.sub_status_changed"    // 执行器上报任务进入新的自定义阶段
+	OutboxEventTaskDependenciesRepaired = "task.dependencies_repaired" // 管理员修复待处理任务的依赖
+	OutboxEventTaskDependencyRemoved    = "task.dependency_removed"    // 上游任务被强制删除，下游任务移除了对它的依赖
+)
+
+// OutboxEvent 发件箱事件：与状态变更在同一事务中写入，由中继投递到外部系统。
+// 投递语义为至少一次，消费者应按 ID 去重
+type OutboxEvent struct {
+	ID            string     `json:"id" bson:"_id"`  // 与对应任务事件的 ID 相同
+	Seq           int64      `json:"seq" bson:"seq"` // 写入序号，单调递增，作为 WatchTask 的续传令牌
+	TaskID        string     `json:"task_id" bson:"task_id"`
+	EventType     string     `json:"event_type" bson:"event_type"`
+	FromStatus    TaskStatus `json:"from_status" bson:"from_status"`
+	ToStatus      TaskStatus `json:"to_status" bson:"to_status"`
+	Message       string     `json:"message,omitempty" bson:"message"`
+	Operator      string     `json:"operator,omitempty" bson:"operator"`
+	InstanceID    string     `json:"instance_id,omitempty" bson:"instance_id"`
+	CorrelationID string     `json:"correlation_id,omitempty" bson:"correlation_id"` // 同对应任务事件
+	CreatedAt     time.Time  `json:"created_at" bson:"created_at"`
+
+	// 投递状态
+	Attempts      int        `json:"-" bson:"attempts"`
+	NextAttemptAt time.Time  `json:"-" bson:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"-" bson:"delivered_at"`
+	LastError     string     `json:"-" bson:"last_error"`
 }
 
 // NewTask 创建新任务
@@ -113,17 +304,70 @@ func NewTask(name, description string, priority TaskPriority, taskType string, i
 	}
 }
 
+// NewCreatedEvent 创建任务时记录的事件：从 UNSPECIFIED 进入初始状态，使变更流也能反映新建的任务
+func NewCreatedEvent(task *Task) TaskEvent {
+	at := task.CreatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return TaskEvent{
+		ID:            idgen.NewID(),
+		TaskID:        task.ID,
+		FromStatus:    TaskStatusUnspecified,
+		ToStatus:      task.Status,
+		Message:       "task created",
+		Timestamp:     at,
+		Operator:      task.CreatedBy,
+		CorrelationID: task.CorrelationID,
+	}
+}
+
 // IsTerminal 检查任务是否处于终态
 func (t *Task) IsTerminal() bool {
-	return t.Status == TaskStatusSucceeded ||
-		t.Status == TaskStatusFailed ||
-		t.Status == TaskStatusCancelled ||
-		t.Status == TaskStatusTimeout
+	return t.Status.IsTerminal()
+}
+
+// SLABreached 判断任务是否违反 SLA：截止时间已过仍未结束，或在截止时间之后才结束，或结束时未成功。
+// 终态任务的结束时间取 CompletedAt，未记录时取 UpdatedAt
+func (t *Task) SLABreached(now time.Time) bool {
+	if t.SLADeadline == nil {
+		return false
+	}
+	if t.SLABreachedAt != nil {
+		return true
+	}
+	if !t.IsTerminal() {
+		return now.After(*t.SLADeadline)
+	}
+	finished := t.UpdatedAt
+	if t.CompletedAt != nil {
+		finished = *t.CompletedAt
+	}
+	return t.Status != TaskStatusSucceeded || finished.After(*t.SLADeadline)
+}
+
+// DefaultResourceSlots 未声明资源需求的任务占用的槽位
+const DefaultResourceSlots = 1
+
+// Slots 任务运行时占用的资源槽位
+func (t *Task) Slots() int {
+	if t.ResourceSlots <= 0 {
+		return DefaultResourceSlots
+	}
+	return int(t.ResourceSlots)
+}
+
+// MaxAttempts 任务最多执行的次数（含首次执行），设置了重试策略时以策略为准，否则为 MaxRetries+1
+func (t *Task) MaxAttempts() int32 {
+	if t.RetryPolicy != nil {
+		return t.RetryPolicy.Attempts(t.MaxRetries)
+	}
+	return t.MaxRetries + 1
 }
 
-// CanRetry 检查任务是否可重试
+// CanRetry 检查失败的任务是否还有重试次数
 func (t *Task) CanRetry() bool {
-	return t.Status == TaskStatusFailed && t.RetryCount < t.MaxRetries
+	return t.Status == TaskStatusFailed && t.RetryCount+1 < t.MaxAttempts()
 }
 
 // MarkRunning 标记任务为运行中
@@ -142,10 +386,9 @@ func (t *Task) MarkCompleted() {
 	t.UpdatedAt = now
 }
 
-// MarkFailed 标记任务失败
+// MarkFailed 标记任务失败，重试次数在重新排队时累加，这里不变
 func (t *Task) MarkFailed(errMsg string) {
 	t.Status = TaskStatusFailed
 	t.ErrorMessage = errMsg
-	t.RetryCount++
 	t.UpdatedAt = time.Now()
 }
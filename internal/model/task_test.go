@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -17,6 +19,8 @@ func TestTaskStatus_String(t *testing.T) {
 		{TaskStatusFailed, "FAILED"},
 		{TaskStatusCancelled, "CANCELLED"},
 		{TaskStatusTimeout, "TIMEOUT"},
+		{TaskStatusSkipped, "SKIPPED"},
+		{TaskStatusWaitingInput, "WAITING_INPUT"},
 	}
 
 	for _, tt := range tests {
@@ -27,6 +31,50 @@ func TestTaskStatus_String(t *testing.T) {
 	}
 }
 
+func TestParseTaskStatus(t *testing.T) {
+	for input, want := range map[string]TaskStatus{
+		"SUCCEEDED":              TaskStatusSucceeded,
+		"failed":                 TaskStatusFailed,
+		" Cancelled ":            TaskStatusCancelled,
+		"TASK_STATUS_SKIPPED":    TaskStatusSkipped,
+		"task_status_timeout":    TaskStatusTimeout,
+		"2":                      TaskStatusRunning,
+		"":                       TaskStatusUnspecified,
+		"UNSPECIFIED":            TaskStatusUnspecified,
+		"0":                      TaskStatusUnspecified,
+		"waiting_input":          TaskStatusWaitingInput,
+		"8":                      TaskStatusWaitingInput,
+		"9":                      TaskStatusUnspecified,
+		"DONE":                   TaskStatusUnspecified,
+		"TASK_STATUS_":           TaskStatusUnspecified,
+		"TASK_STATUS_SUCCEEDED ": TaskStatusSucceeded,
+	} {
+		got, err := ParseTaskStatus(input)
+		if got != want || (err == nil) != (want != TaskStatusUnspecified) {
+			t.Errorf("ParseTaskStatus(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+}
+
+func TestParseTaskPriority(t *testing.T) {
+	for input, want := range map[string]TaskPriority{
+		"HIGH":                 TaskPriorityHigh,
+		" urgent ":             TaskPriorityUrgent,
+		"TASK_PRIORITY_LOW":    TaskPriorityLow,
+		"2":                    TaskPriorityNormal,
+		"":                     TaskPriorityUnspecified,
+		"UNSPECIFIED":          TaskPriorityUnspecified,
+		"5":                    TaskPriorityUnspecified,
+		"CRITICAL":             TaskPriorityUnspecified,
+		"task_priority_normal": TaskPriorityNormal,
+	} {
+		got, err := ParseTaskPriority(input)
+		if got != want || (err == nil) != (want != TaskPriorityUnspecified) {
+			t.Errorf("ParseTaskPriority(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+}
+
 func TestTaskPriority_String(t *testing.T) {
 	tests := []struct {
 		priority TaskPriority
@@ -106,6 +154,8 @@ func TestTask_IsTerminal(t *testing.T) {
 		{TaskStatusFailed, true},
 		{TaskStatusCancelled, true},
 		{TaskStatusTimeout, true},
+		{TaskStatusSkipped, true},
+		{TaskStatusWaitingInput, false},
 	}
 
 	for _, tt := range tests {
@@ -188,8 +238,8 @@ func TestTask_MarkFailed(t *testing.T) {
 	if task.ErrorMessage != errMsg {
 		t.Errorf("expected ErrorMessage '%s', got '%s'", errMsg, task.ErrorMessage)
 	}
-	if task.RetryCount != 1 {
-		t.Errorf("expected RetryCount 1, got %d", task.RetryCount)
+	if task.RetryCount != 0 {
+		t.Errorf("expected RetryCount unchanged, got %d", task.RetryCount)
 	}
 }
 
@@ -224,3 +274,219 @@ func TestTaskEvent(t *testing.T) {
 		t.Errorf("expected Operator 'system', got '%s'", event.Operator)
 	}
 }
+
+func TestTask_CanAccess(t *testing.T) {
+	task := &Task{CreatedBy: "alice"}
+
+	// 未归属团队的任务只有创建者可见
+	if task.CanAccess("bob", nil) {
+		t.Error("task without team should only be visible to its creator")
+	}
+	if !task.CanAccess("alice", nil) {
+		t.Error("creator should see a task without team")
+	}
+
+	task.TeamID = "team-a"
+	if !task.CanAccess("alice", nil) {
+		t.Error("creator should always have access")
+	}
+	if task.CanAccess("bob", []string{"team-b"}) {
+		t.Error("non-member should not have access")
+	}
+	if !task.CanAccess("bob", []string{"team-b", "team-a"}) {
+		t.Error("team member should have access")
+	}
+}
+
+func TestTask_ApplySignal(t *testing.T) {
+	task := &Task{Status: TaskStatusRunning}
+	now := time.Now()
+	task.SuspendForInput("approval", now)
+	if task.Status != TaskStatusWaitingInput || task.InputRequest != "approval" {
+		t.Fatalf("unexpected suspended task: %+v", task)
+	}
+
+	task.ApplySignal(map[string]string{"approved": "yes", "by": "alice"}, now)
+	sent := map[string]string{"by": "bob"}
+	task.SuspendForInput("second approval", now)
+	task.ApplySignal(sent, now)
+	sent["by"] = "mallory"
+
+	if task.Status != TaskStatusPending || task.InputRequest != "" {
+		t.Errorf("signalled task should be PENDING without an input request: %+v", task)
+	}
+	if task.SignalInput["approved"] != "yes" || task.SignalInput["by"] != "bob" {
+		t.Errorf("signal input should be merged by key, got %v", task.SignalInput)
+	}
+}
+
+func TestValidateSignalPayload(t *testing.T) {
+	full := make(map[string]string, MaxSignalInputKeys)
+	for i := range MaxSignalInputKeys {
+		full[fmt.Sprintf("k%d", i)] = "v"
+	}
+
+	tests := []struct {
+		name     string
+		existing map[string]string
+		payload  map[string]string
+		wantErr  bool
+	}{
+		{"valid", nil, map[string]string{"answer": "42"}, false},
+		{"empty payload", nil, nil, true},
+		{"empty key", nil, map[string]string{"": "x"}, true},
+		{"overwrite within limit", full, map[string]string{"k0": "w"}, false},
+		{"too many keys after merge", full, map[string]string{"extra": "x"}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateSignalPayload(tt.existing, tt.payload); (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateSignalPayload() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTask_RoutingMismatch(t *testing.T) {
+	labels := map[string]string{"gpu": "true", "region": "eu"}
+
+	tests := []struct {
+		name  string
+		task  Task
+		match bool
+	}{
+		{"no routing", Task{}, true},
+		{"target by instance id", Task{TargetWorker: "host-a-1"}, true},
+		{"target by hostname", Task{TargetWorker: "host-a"}, true},
+		{"other target", Task{TargetWorker: "host-b"}, false},
+		{"selector subset", Task{NodeSelector: map[string]string{"gpu": "true"}}, true},
+		{"selector value differs", Task{NodeSelector: map[string]string{"region": "us"}}, false},
+		{"selector key missing", Task{NodeSelector: map[string]string{"zone": "a"}}, false},
+		{"target and selector", Task{TargetWorker: "host-a", NodeSelector: map[string]string{"gpu": "false"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.task.RoutingMismatch("host-a-1", "host-a", labels); (got == "") != tt.match {
+			t.Errorf("%s: RoutingMismatch() = %q, want match %v", tt.name, got, tt.match)
+		}
+		if tt.task.HasRouting() == (tt.name == "no routing") {
+			t.Errorf("%s: HasRouting() = %v", tt.name, tt.task.HasRouting())
+		}
+	}
+
+	if err := ValidateRouting(map[string]string{"-bad": "x"}, ""); err == nil {
+		t.Error("expected an error for an invalid selector key")
+	}
+	if err := ValidateRouting(nil, strings.Repeat("w", MaxTargetWorkerLength+1)); err == nil {
+		t.Error("expected an error for a too long target worker")
+	}
+}
+
+func TestTask_EscalatePriority(t *testing.T) {
+	now := time.Now()
+	task := &Task{Status: TaskStatusPending, Priority: TaskPriorityLow, CreatedAt: now.Add(-time.Hour)}
+	if got := task.QueueTime(now); got != time.Hour {
+		t.Errorf("QueueTime = %s, want 1h", got)
+	}
+	rerunAt := now.Add(-time.Minute)
+	task.QueuedAt = &rerunAt
+	if got := task.QueueTime(now); got != time.Minute {
+		t.Errorf("QueueTime after rerun = %s, want 1m", got)
+	}
+
+	if !task.EscalatePriority(TaskPriorityHigh, now) || task.Priority != TaskPriorityHigh {
+		t.Errorf("expected priority HIGH, got %s", task.Priority)
+	}
+	if task.EscalatePriority(TaskPriorityNormal, now) {
+		t.Error("escalating to a lower priority should not change the task")
+	}
+
+	// 被依赖继承提升的任务只提高自身优先级，恢复后不低于升级后的优先级
+	boosted := &Task{Status: TaskStatusPending, Priority: TaskPriorityLow}
+	boosted.BoostPriority(TaskPriorityUrgent, now)
+	if !boosted.EscalatePriority(TaskPriorityHigh, now) || boosted.Priority != TaskPriorityUrgent || boosted.BasePriority != TaskPriorityHigh {
+		t.Errorf("unexpected escalated boosted task: %s from %s", boosted.Priority, boosted.BasePriority)
+	}
+	boosted.RestorePriority(now)
+	if boosted.Priority != TaskPriorityHigh {
+		t.Errorf("restored priority = %s, want HIGH", boosted.Priority)
+	}
+}
+
+func TestTask_EnterPhase(t *testing.T) {
+	now := time.Now()
+	task := &Task{Status: TaskStatusRunning}
+	if !task.EnterPhase(TaskPhase{Name: "UPLOADING", Attempt: 1, EnteredAt: now}) || task.SubStatus != "UPLOADING" {
+		t.Fatalf("expected sub-status UPLOADING, got %q", task.SubStatus)
+	}
+	// 同一次执行重复上报同名阶段只更新说明
+	if task.EnterPhase(TaskPhase{Name: "UPLOADING", Message: "50%", Attempt: 1, EnteredAt: now.Add(time.Second)}) {
+		t.Error("re-entering the current phase should not append to the history")
+	}
+	if len(task.Phases) != 1 || task.Phases[0].Message != "50%" || !task.Phases[0].EnteredAt.Equal(now) {
+		t.Errorf("unexpected phases: %+v", task.Phases)
+	}
+	if !task.EnterPhase(TaskPhase{Name: "VALIDATING", Attempt: 1, EnteredAt: now}) ||
+		!task.EnterPhase(TaskPhase{Name: "UPLOADING", Attempt: 2, EnteredAt: now}) || len(task.Phases) != 3 {
+		t.Errorf("expected a new phase for each change and each attempt, got %+v", task.Phases)
+	}
+
+	for i := 0; i < MaxTaskPhases; i++ {
+		task.EnterPhase(TaskPhase{Name: fmt.Sprintf("STEP_%d", i), Attempt: 2, EnteredAt: now})
+	}
+	if len(task.Phases) != MaxTaskPhases || task.Phases[0].Name != "STEP_0" || task.SubStatus != fmt.Sprintf("STEP_%d", MaxTaskPhases-1) {
+		t.Errorf("expected the oldest phases dropped, got %d phases starting at %s", len(task.Phases), task.Phases[0].Name)
+	}
+}
+
+func TestValidateSubStatus(t *testing.T) {
+	tests := []struct {
+		subStatus, message string
+		valid              bool
+	}{
+		{"UPLOADING", "", true},
+		{"上传中", "chunk 3/10", true},
+		{"", "", false},
+		{"  ", "", false},
+		{"UPLOAD\nING", "", false},
+		{strings.Repeat("A", MaxSubStatusLength+1), "", false},
+		{"UPLOADING", strings.Repeat("m", MaxPhaseMessageLength+1), false},
+	}
+	for _, tt := range tests {
+		if err := ValidateSubStatus(tt.subStatus, tt.message); (err == nil) != tt.valid {
+			t.Errorf("ValidateSubStatus(%q, %d chars) = %v, want valid %v", tt.subStatus, len(tt.message), err, tt.valid)
+		}
+	}
+}
+
+func TestTask_RepairDependencies(t *testing.T) {
+	task := &Task{
+		ID:                 "t1",
+		Dependencies:       []string{"a", "b", "c"},
+		DependencyPolicies: map[string]DependencyFailurePolicy{"a": DependencyFailureWait, "c": DependencyFailureIgnore},
+	}
+
+	// 任一修复不合法时任务不变
+	errs := task.RepairDependencies([]DependencyRepair{
+		{DependencyID: "missing"}, {DependencyID: "a", ReplaceWith: "t1"}, {DependencyID: "b", ReplaceWith: "c"}, {DependencyID: "c"},
+	})
+	rules := make([]string, len(errs))
+	for i, e := range errs {
+		rules[i] = e.Field + ":" + e.Rule
+	}
+	if got := strings.Join(rules, ","); got != "repairs[0].dependency_id:unknown,repairs[1].replace_with:self,repairs[2].replace_with:duplicate" {
+		t.Errorf("unexpected errors: %s", got)
+	}
+	if len(task.Dependencies) != 3 || len(task.DependencyPolicies) != 2 {
+		t.Errorf("expected the task unchanged, got %v %v", task.Dependencies, task.DependencyPolicies)
+	}
+	if errs := task.RepairDependencies(nil); len(errs) != 1 || errs[0].Field != "repairs" {
+		t.Errorf("expected an error for no repairs, got %v", errs)
+	}
+
+	// 按顺序应用：移除 c 之后 b 可以改为依赖 c
+	if errs := task.RepairDependencies([]DependencyRepair{{DependencyID: "c"}, {DependencyID: "a", ReplaceWith: "x"}, {DependencyID: "b", ReplaceWith: "c"}}); len(errs) != 0 {
+		t.Fatalf("RepairDependencies: %v", errs)
+	}
+	if fmt.Sprint(task.Dependencies) != "[x c]" || task.DependencyPolicy("x") != DependencyFailureWait ||
+		task.DependencyPolicy("c") != DependencyFailureSkip || len(task.DependencyPolicies) != 1 {
+		t.Errorf("unexpected repaired dependencies %v with policies %v", task.Dependencies, task.DependencyPolicies)
+	}
+}
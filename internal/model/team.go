@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+)
+
+// Team 团队（任务归属组），成员自动获得团队任务的访问权限
+type Team struct {
+	ID        string    `json:"id" bson:"_id"`
+	Name      string    `json:"name" bson:"name"`
+	Members   []string  `json:"members" bson:"members"`
+	CreatedBy string    `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// HasMember 检查用户是否为团队成员
+func (t *Team) HasMember(userID string) bool {
+	for _, m := range t.Members {
+		if m == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccess 检查用户是否可以访问任务
+// 创建者始终可见；归属团队的任务对团队成员可见；未归属团队的任务只有创建者可见
+func (t *Task) CanAccess(userID string, teamIDs []string) bool {
+	if t.CreatedBy == userID {
+		return true
+	}
+	if t.TeamID == "" {
+		return false
+	}
+	for _, id := range teamIDs {
+		if id == t.TeamID {
+			return true
+		}
+	}
+	return false
+}
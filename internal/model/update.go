@@ -0,0 +1,163 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// 可通过字段掩码更新的任务字段
+const (
+	UpdateFieldName        = "name"
+	UpdateFieldDescription = "description"
+	UpdateFieldPriority    = "priority"
+	UpdateFieldParams      = "params"
+	UpdateFieldMaxRetries  = "max_retries"
+	UpdateFieldLabels      = "labels"
+)
+
+// updatableStatuses 各字段允许修改时任务所处的状态，nil 表示任何状态都可修改。
+// 优先级和参数在任务开始执行后不再生效；重试上限只对还会执行或可手动重试的任务有意义
+var updatableStatuses = map[string][]TaskStatus{
+	UpdateFieldName:        nil,
+	UpdateFieldDescription: nil,
+	UpdateFieldLabels:      nil,
+	UpdateFieldPriority:    {TaskStatusPending},
+	UpdateFieldParams:      {TaskStatusPending},
+	UpdateFieldMaxRetries:  {TaskStatusPending, TaskStatusRunning, TaskStatusFailed},
+}
+
+// FieldError 字段校验失败的原因
+type FieldError struct {
+	Field   string // 字段路径
+	Rule    string // 违反的规则，如 required、enum、gte
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// TaskUpdate 按字段掩码更新任务定义，只有 Mask 中列出的字段生效，未列出的字段即使有值也被忽略
+type TaskUpdate struct {
+	Mask        []string
+	Name        string
+	Description string
+	Priority    TaskPriority
+	Params      map[string]string
+	MaxRetries  int32
+	Labels      map[string]string // 整体替换，为空表示清除全部标签
+}
+
+// Validate 校验掩码和其中字段的取值，返回全部错误。掩码为空或包含未知、重复的字段时同样报错
+func (u *TaskUpdate) Validate() []FieldError {
+	if len(u.Mask) == 0 {
+		return []FieldError{{"update_mask", "required", "must list at least one field"}}
+	}
+
+	var errs []FieldError
+	seen := make(map[string]bool, len(u.Mask))
+	for _, field := range u.Mask {
+		if _, ok := updatableStatuses[field]; !ok {
+			errs = append(errs, FieldError{"update_mask", "enum",
+				fmt.Sprintf("unknown field %q, must be one of [name, description, priority, params, max_retries, labels]", field)})
+			continue
+		}
+		if seen[field] {
+			errs = append(errs, FieldError{"update_mask", "unique", fmt.Sprintf("duplicate field %q", field)})
+			continue
+		}
+		seen[field] = true
+
+		switch field {
+		case UpdateFieldName:
+			if strings.TrimSpace(u.Name) == "" {
+				errs = append(errs, FieldError{field, "required", "is required"})
+			}
+		case UpdateFieldPriority:
+			if u.Priority < TaskPriorityLow || u.Priority > TaskPriorityUrgent {
+				errs = append(errs, FieldError{field, "enum", fmt.Sprintf("unknown priority %d", u.Priority)})
+			}
+		case UpdateFieldMaxRetries:
+			if u.MaxRetries < 0 {
+				errs = append(errs, FieldError{field, "gte", "must be greater than or equal to 0"})
+			}
+		case UpdateFieldLabels:
+			if err := ValidateLabels(u.Labels); err != nil {
+				errs = append(errs, FieldError{field, "invalid", err.Error()})
+			}
+		}
+	}
+	return errs
+}
+
+// CheckStatus 检查处于 status 的任务是否允许修改掩码中的字段，返回全部不可修改的字段
+func (u *TaskUpdate) CheckStatus(status TaskStatus) []FieldError {
+	var errs []FieldError
+	for _, field := range u.Mask {
+		if allowed, ok := updatableStatuses[field]; ok && allowed != nil && !slices.Contains(allowed, status) {
+			errs = append(errs, FieldError{field, "status",
+				fmt.Sprintf("cannot be updated while task is %s, allowed in %v", status, allowed)})
+		}
+	}
+	return errs
+}
+
+// Apply 把掩码中的字段写入任务，调用前须通过 Validate
+func (u *TaskUpdate) Apply(task *Task) {
+	for _, field := range u.Mask {
+		switch field {
+		case UpdateFieldName:
+			task.Name = u.Name
+		case UpdateFieldDescription:
+			task.Description = u.Description
+		case UpdateFieldPriority:
+			// 显式设置的优先级取代继承的优先级，开始执行后不再恢复
+			task.Priority = u.Priority
+			task.BasePriority = TaskPriorityUnspecified
+		case UpdateFieldParams:
+			task.InputParams = u.Params
+		case UpdateFieldMaxRetries:
+			task.MaxRetries = u.MaxRetries
+		case UpdateFieldLabels:
+			task.Labels = u.Labels
+		}
+	}
+}
+
+// 执行结果相关的字段，只能随执行器上报结果的状态转换一起写入
+const (
+	ResultFieldOutput     = "output_result"
+	ResultFieldError      = "error_message"
+	ResultFieldRetryCount = "retry_count"
+)
+
+// ResultUpdate 不带字段掩码的旧式更新：状态转换以及随之上报的执行结果
+type ResultUpdate struct {
+	Status       TaskStatus // 目标状态，为 0 表示不改变状态
+	OutputResult map[string]string
+	ErrorMessage string
+	RetryCount   int32
+}
+
+// CheckStatus 检查处于 current 的任务是否允许写入请求中的结果字段，返回全部不可修改的字段。
+// 输出结果只能随 RUNNING -> SUCCEEDED 写入，错误信息只能随 RUNNING -> FAILED/TIMEOUT 写入，
+// 重试次数由重试流程维护，不能直接修改
+func (u *ResultUpdate) CheckStatus(current TaskStatus) []FieldError {
+	var errs []FieldError
+	reporting := func(to ...TaskStatus) bool {
+		return current == TaskStatusRunning && slices.Contains(to, u.Status)
+	}
+	if u.OutputResult != nil && !reporting(TaskStatusSucceeded) {
+		errs = append(errs, FieldError{ResultFieldOutput, "status",
+			fmt.Sprintf("can only be set when a RUNNING task transitions to SUCCEEDED, task is %s", current)})
+	}
+	if u.ErrorMessage != "" && !reporting(TaskStatusFailed, TaskStatusTimeout) {
+		errs = append(errs, FieldError{ResultFieldError, "status",
+			fmt.Sprintf("can only be set when a RUNNING task transitions to FAILED or TIMEOUT, task is %s", current)})
+	}
+	if u.RetryCount != 0 {
+		errs = append(errs, FieldError{ResultFieldRetryCount, "read_only", "is maintained by the retry flow and cannot be updated"})
+	}
+	return errs
+}
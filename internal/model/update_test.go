@@ -0,0 +1,115 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTaskUpdate_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		update TaskUpdate
+		want   []string // 期望出错的字段
+	}{
+		{"empty mask", TaskUpdate{}, []string{"update_mask"}},
+		{"valid", TaskUpdate{Mask: []string{UpdateFieldName, UpdateFieldPriority}, Name: "n", Priority: TaskPriorityHigh}, nil},
+		{"unknown field", TaskUpdate{Mask: []string{"status"}}, []string{"update_mask"}},
+		{"duplicate field", TaskUpdate{Mask: []string{UpdateFieldDescription, UpdateFieldDescription}}, []string{"update_mask"}},
+		{"empty name", TaskUpdate{Mask: []string{UpdateFieldName}, Name: " "}, []string{UpdateFieldName}},
+		{"unspecified priority", TaskUpdate{Mask: []string{UpdateFieldPriority}}, []string{UpdateFieldPriority}},
+		{"negative max retries", TaskUpdate{Mask: []string{UpdateFieldMaxRetries}, MaxRetries: -1}, []string{UpdateFieldMaxRetries}},
+		{"invalid label key", TaskUpdate{Mask: []string{UpdateFieldLabels}, Labels: map[string]string{"-bad": "v"}}, []string{UpdateFieldLabels}},
+		{"long label value", TaskUpdate{Mask: []string{UpdateFieldLabels}, Labels: map[string]string{"k": strings.Repeat("v", MaxLabelValueLength+1)}}, []string{UpdateFieldLabels}},
+		{"unmasked values ignored", TaskUpdate{Mask: []string{UpdateFieldDescription}, MaxRetries: -1}, nil},
+		{"all errors reported", TaskUpdate{Mask: []string{"status", UpdateFieldName}}, []string{"update_mask", UpdateFieldName}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.update.Validate()
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Field)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Validate() fields = %v, want %v (%v)", got, tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestTaskUpdate_CheckStatus(t *testing.T) {
+	tests := []struct {
+		field   string
+		status  TaskStatus
+		wantErr bool
+	}{
+		{UpdateFieldName, TaskStatusSucceeded, false},
+		{UpdateFieldLabels, TaskStatusRunning, false},
+		{UpdateFieldPriority, TaskStatusPending, false},
+		{UpdateFieldPriority, TaskStatusRunning, true},
+		{UpdateFieldParams, TaskStatusFailed, true},
+		{UpdateFieldMaxRetries, TaskStatusFailed, false},
+		{UpdateFieldMaxRetries, TaskStatusCancelled, true},
+	}
+	for _, tt := range tests {
+		update := TaskUpdate{Mask: []string{tt.field}}
+		if errs := update.CheckStatus(tt.status); (len(errs) > 0) != tt.wantErr {
+			t.Errorf("CheckStatus(%s) for %s: errs = %v, wantErr %v", tt.status, tt.field, errs, tt.wantErr)
+		}
+	}
+
+	// 所有不可修改的字段一并报告
+	update := TaskUpdate{Mask: []string{UpdateFieldPriority, UpdateFieldName, UpdateFieldParams}}
+	if errs := update.CheckStatus(TaskStatusRunning); len(errs) != 2 || errs[0].Field != UpdateFieldPriority || errs[1].Field != UpdateFieldParams {
+		t.Errorf("CheckStatus(RUNNING) = %v, want priority and params", errs)
+	}
+}
+
+func TestResultUpdate_CheckStatus(t *testing.T) {
+	output := map[string]string{"k": "v"}
+	tests := []struct {
+		name    string
+		current TaskStatus
+		update  ResultUpdate
+		want    []string // 期望不可修改的字段
+	}{
+		{"status only", TaskStatusPending, ResultUpdate{Status: TaskStatusCancelled}, nil},
+		{"output on success", TaskStatusRunning, ResultUpdate{Status: TaskStatusSucceeded, OutputResult: output}, nil},
+		{"error on failure", TaskStatusRunning, ResultUpdate{Status: TaskStatusFailed, ErrorMessage: "boom"}, nil},
+		{"error on timeout", TaskStatusRunning, ResultUpdate{Status: TaskStatusTimeout, ErrorMessage: "slow"}, nil},
+		{"output without transition", TaskStatusRunning, ResultUpdate{OutputResult: output}, []string{ResultFieldOutput}},
+		{"output on pending task", TaskStatusPending, ResultUpdate{Status: TaskStatusSucceeded, OutputResult: output}, []string{ResultFieldOutput}},
+		{"output on finished task", TaskStatusSucceeded, ResultUpdate{OutputResult: output}, []string{ResultFieldOutput}},
+		{"error on success", TaskStatusRunning, ResultUpdate{Status: TaskStatusSucceeded, ErrorMessage: "boom"}, []string{ResultFieldError}},
+		{"retry count", TaskStatusFailed, ResultUpdate{RetryCount: 1}, []string{ResultFieldRetryCount}},
+		{"all errors reported", TaskStatusCancelled, ResultUpdate{OutputResult: output, ErrorMessage: "x", RetryCount: 2},
+			[]string{ResultFieldOutput, ResultFieldError, ResultFieldRetryCount}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range tt.update.CheckStatus(tt.current) {
+				got = append(got, e.Field)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("CheckStatus(%s) fields = %v, want %v", tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskUpdate_Apply(t *testing.T) {
+	task := &Task{Name: "old", Description: "keep", Priority: TaskPriorityLow, MaxRetries: 1}
+	update := TaskUpdate{
+		Mask:        []string{UpdateFieldName, UpdateFieldMaxRetries, UpdateFieldLabels},
+		Name:        "new",
+		Description: "ignored",
+		MaxRetries:  3,
+		Labels:      map[string]string{"env": "prod"},
+	}
+	update.Apply(task)
+
+	if task.Name != "new" || task.Description != "keep" || task.Priority != TaskPriorityLow || task.MaxRetries != 3 || task.Labels["env"] != "prod" {
+		t.Errorf("unexpected task after Apply: %+v", task)
+	}
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// WorkflowActiveStatuses 工作流被取消或超过截止时间时会被取消的未结束状态
+var WorkflowActiveStatuses = []TaskStatus{TaskStatusPending, TaskStatusRunning, TaskStatusWaitingInput}
+
+// InheritWorkflowDeadline 任务加入截止时间为 deadline 的工作流（依赖其中的任务或由其中的任务提交）时，
+// 截止时间取二者中较早的一个；deadline 为 nil 时不变
+func (t *Task) InheritWorkflowDeadline(deadline *time.Time) {
+	if deadline == nil || (t.WorkflowDeadline != nil && !deadline.Before(*t.WorkflowDeadline)) {
+		return
+	}
+	d := *deadline
+	t.WorkflowDeadline = &d
+}
+
+// WorkflowExpired 判断任务所属工作流的截止时间已过而任务尚未结束
+func (t *Task) WorkflowExpired(now time.Time) bool {
+	return t.WorkflowDeadline != nil && !t.IsTerminal() && !now.Before(*t.WorkflowDeadline)
+}
+
+// WorkflowCancelledMessage 工作流被取消时成员任务的取消事件说明
+func WorkflowCancelledMessage(reason string) string {
+	if reason == "" {
+		return "workflow cancelled"
+	}
+	return "workflow cancelled: " + reason
+}
+
+// WorkflowDeadlineMessage 工作流超过截止时间时成员任务的取消事件说明
+func WorkflowDeadlineMessage(deadline time.Time) string {
+	return fmt.Sprintf("workflow deadline %s exceeded", deadline.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"taskflow/internal/config"
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+)
+
+// channel 已解析模板的通知渠道
+type channel struct {
+	cfg    config.NotificationChannel
+	tmpl   *template.Template
+	events map[string]bool
+	types  map[string]bool
+}
+
+// matches 判断任务变更是否需要通知该渠道
+func (c *channel) matches(data *TemplateData) bool {
+	// 失败率异常不对应单个任务，渠道模板多半引用了 .Task，只发送给显式订阅的渠道
+	if data.Spike != nil && !c.events[data.Event] {
+		return false
+	}
+	if len(c.events) > 0 && !c.events[data.Event] {
+		return false
+	}
+	if len(c.types) > 0 && !c.types[strings.ToLower(data.taskType())] {
+		return false
+	}
+	return true
+}
+
+// Notifier 任务通知器：按渠道渲染模板并投递
+type Notifier struct {
+	channels []*channel
+	client   *http.Client
+}
+
+// NewNotifier 创建通知器，启动时解析所有渠道模板
+func NewNotifier(cfg config.NotificationConfig) (*Notifier, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = time.Duration(config.DefaultNotifyTimeout) * time.Second
+	}
+
+	n := &Notifier{client: &http.Client{Timeout: timeout}}
+	for _, chCfg := range cfg.Channels {
+		tmpl, err := parseTemplate(chCfg.Name, chCfg.Type, chCfg.Template)
+		if err != nil {
+			return nil, err
+		}
+		n.channels = append(n.channels, &channel{
+			cfg:    chCfg,
+			tmpl:   tmpl,
+			events: toSet(chCfg.Events),
+			types:  toSet(chCfg.TaskTypes),
+		})
+	}
+	return n, nil
+}
+
+// Render 渲染指定渠道的通知内容
+func (n *Notifier) Render(channelName string, data *TemplateData) (string, error) {
+	for _, ch := range n.channels {
+		if ch.cfg.Name == channelName {
+			return render(ch, data)
+		}
+	}
+	return "", fmt.Errorf("notification channel not found: %s", channelName)
+}
+
+// NotifyTaskChange 任务状态变更时异步通知所有匹配渠道
+func (n *Notifier) NotifyTaskChange(task *model.Task, from, to model.TaskStatus) {
+	if n == nil || task == nil || len(n.channels) == 0 {
+		return
+	}
+	n.notify(NewTemplateData(task, from, to))
+}
+
+// NotifySLABreach 任务违反 SLA 时异步通知订阅了 sla_breached 事件（或未限定事件）的渠道
+func (n *Notifier) NotifySLABreach(task *model.Task, now time.Time) {
+	if n == nil || task == nil || len(n.channels) == 0 {
+		return
+	}
+	n.notify(NewSLABreachData(task, now))
+}
+
+// NotifyQueueTimeExceeded 任务排队超时时异步通知订阅了 queue_time_exceeded 事件（或未限定事件）的渠道
+func (n *Notifier) NotifyQueueTimeExceeded(task *model.Task, now time.Time) {
+	if n == nil || task == nil || len(n.channels) == 0 {
+		return
+	}
+	n.notify(NewQueueTimeExceededData(task, now))
+}
+
+// NotifyFailureSpike 任务类型失败率异常时异步通知订阅了 failure_spike 事件的渠道
+func (n *Notifier) NotifyFailureSpike(spike *FailureSpike, now time.Time) {
+	if n == nil || spike == nil || len(n.channels) == 0 {
+		return
+	}
+	n.notify(NewFailureSpikeData(spike, now))
+}
+
+// notify 渲染并异步投递到所有匹配渠道
+func (n *Notifier) notify(data *TemplateData) {
+	for _, ch := range n.channels {
+		if !ch.matches(data) {
+			continue
+		}
+
+		body, err := render(ch, data)
+		if err != nil {
+			logger.Errorf("Failed to render notification for channel %s: %v", ch.cfg.Name, err)
+			continue
+		}
+
+		go func(ch *channel, body string) {
+			if err := n.send(context.Background(), ch, body); err != nil {
+				logger.Errorf("Failed to send notification to channel %s: %v", ch.cfg.Name, err)
+			}
+		}(ch, body)
+	}
+}
+
+// send 投递通知
+func (n *Notifier) send(ctx context.Context, ch *channel, body string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ch.cfg.URL, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range ch.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// render 执行模板
+func render(ch *channel, data *TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := ch.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// toSet 转为小写集合
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
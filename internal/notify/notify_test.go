@@ -0,0 +1,273 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"taskflow/internal/config"
+	"taskflow/internal/model"
+)
+
+func newFailedTask() *model.Task {
+	task := model.NewTask("nightly-report", "test", model.TaskPriorityNormal, "report", map[string]string{"env": "prod"}, nil, 3, "tester")
+	task.ID = "task-1"
+	started := time.Now().Add(-90 * time.Second)
+	completed := started.Add(90 * time.Second)
+	task.StartedAt = &started
+	task.CompletedAt = &completed
+	task.ErrorMessage = strings.Repeat("x", 300)
+	return task
+}
+
+func TestNotifier_DefaultTemplates(t *testing.T) {
+	n, err := NewNotifier(config.NotificationConfig{Channels: []config.NotificationChannel{
+		{Name: "hook", Type: ChannelTypeWebhook, URL: "http://localhost"},
+		{Name: "slack", Type: ChannelTypeSlack, URL: "http://localhost"},
+	}})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	data := NewTemplateData(newFailedTask(), model.TaskStatusRunning, model.TaskStatusFailed)
+	if data.Duration != 90*time.Second {
+		t.Errorf("Expected duration 90s, got %v", data.Duration)
+	}
+
+	// 默认模板必须渲染出合法 JSON
+	for _, name := range []string{"hook", "slack"} {
+		out, err := n.Render(name, data)
+		if err != nil {
+			t.Fatalf("Render %s failed: %v", name, err)
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			t.Fatalf("Render %s produced invalid JSON: %v\n%s", name, err, out)
+		}
+	}
+
+	if _, err := n.Render("missing", data); err == nil {
+		t.Error("Expected error for unknown channel")
+	}
+}
+
+func TestNotifier_CustomTemplate(t *testing.T) {
+	n, err := NewNotifier(config.NotificationConfig{Channels: []config.NotificationChannel{
+		{Name: "custom", URL: "http://localhost", Template: `{{.Task.Name}}|{{.ToStatus}}|{{param .Task "env"}}|{{duration .Duration}}|{{truncate 5 .ErrorSnippet}}`},
+	}})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	out, err := n.Render("custom", NewTemplateData(newFailedTask(), model.TaskStatusRunning, model.TaskStatusFailed))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := "nightly-report|FAILED|prod|1m30s|xxxxx..."; out != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	// 非法模板在启动时报错
+	_, err = NewNotifier(config.NotificationConfig{Channels: []config.NotificationChannel{
+		{Name: "bad", URL: "http://localhost", Template: "{{.Task.Name"},
+	}})
+	if err == nil {
+		t.Error("Expected error for invalid template")
+	}
+}
+
+func TestNotifier_NotifyTaskChange(t *testing.T) {
+	received := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r.Header.Get("X-Token") + " " + string(body)
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{Channels: []config.NotificationChannel{
+		{Name: "failures", URL: srv.URL, Events: []string{"FAILED"}, Template: "failed {{.Task.ID}}", Headers: map[string]string{"X-Token": "secret"}},
+		{Name: "other-type", URL: srv.URL, TaskTypes: []string{"email"}, Template: "email {{.Task.ID}}"},
+	}})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	task := newFailedTask()
+
+	// 成功事件不匹配任何渠道
+	n.NotifyTaskChange(task, model.TaskStatusRunning, model.TaskStatusSucceeded)
+	// 失败事件只匹配 failures 渠道
+	n.NotifyTaskChange(task, model.TaskStatusRunning, model.TaskStatusFailed)
+
+	select {
+	case got := <-received:
+		if got != "secret failed task-1" {
+			t.Errorf("Unexpected notification: %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for notification")
+	}
+
+	select {
+	case got := <-received:
+		t.Errorf("Unexpected extra notification: %q", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// nil 通知器安全
+	var nilNotifier *Notifier
+	nilNotifier.NotifyTaskChange(task, model.TaskStatusRunning, model.TaskStatusFailed)
+}
+
+func TestNotifier_NotifySLABreach(t *testing.T) {
+	received := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{Channels: []config.NotificationChannel{
+		{Name: "sla", URL: srv.URL, Events: []string{EventSLABreached}, Template: "{{.Event}} {{.Task.ID}} {{.ToStatus}} {{duration .Overdue}}"},
+		{Name: "failures", URL: srv.URL, Events: []string{"FAILED"}, Template: "failed {{.Task.ID}}"},
+		{Name: "slack", Type: ChannelTypeSlack, URL: "http://localhost", Events: []string{"succeeded"}}, // 只用于渲染
+	}})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	task := newFailedTask()
+	task.Status = model.TaskStatusRunning
+	task.CompletedAt = nil
+	now := time.Now()
+	deadline := now.Add(-2 * time.Minute)
+	task.SLADeadline = &deadline
+
+	out, err := n.Render("slack", NewSLABreachData(task, now))
+	if err != nil {
+		t.Fatalf("Render slack failed: %v", err)
+	}
+	if !strings.Contains(out, "missed its SLA by 2m0s") {
+		t.Errorf("Unexpected slack message: %s", out)
+	}
+
+	n.NotifySLABreach(task, now)
+	select {
+	case got := <-received:
+		if got != "sla_breached task-1 RUNNING 2m0s" {
+			t.Errorf("Unexpected notification: %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for notification")
+	}
+	select {
+	case got := <-received:
+		t.Errorf("Unexpected extra notification: %q", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestNotifier_NotifyQueueTimeExceeded(t *testing.T) {
+	received := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{Channels: []config.NotificationChannel{
+		{Name: "queue", URL: srv.URL, Events: []string{EventQueueTimeExceeded}, Template: "{{.Event}} {{.Task.ID}} {{duration .QueueTime}}"},
+		{Name: "sla", URL: srv.URL, Events: []string{EventSLABreached}, Template: "sla {{.Task.ID}}"},
+		{Name: "slack", Type: ChannelTypeSlack, URL: "http://localhost", Events: []string{"succeeded"}}, // 只用于渲染
+	}})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	now := time.Now()
+	task := model.NewTask("nightly-report", "test", model.TaskPriorityNormal, "report", nil, nil, 0, "tester")
+	task.ID = "task-1"
+	task.CreatedAt = now.Add(-45 * time.Minute)
+
+	out, err := n.Render("slack", NewQueueTimeExceededData(task, now))
+	if err != nil {
+		t.Fatalf("Render slack failed: %v", err)
+	}
+	if !strings.Contains(out, "has been queued for 45m0s") {
+		t.Errorf("Unexpected slack message: %s", out)
+	}
+
+	n.NotifyQueueTimeExceeded(task, now)
+	select {
+	case got := <-received:
+		if got != "queue_time_exceeded task-1 45m0s" {
+			t.Errorf("Unexpected notification: %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for notification")
+	}
+	select {
+	case got := <-received:
+		t.Errorf("Unexpected extra notification: %q", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestNotifier_NotifyFailureSpike(t *testing.T) {
+	received := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{Channels: []config.NotificationChannel{
+		{Name: "spikes", URL: srv.URL, Events: []string{EventFailureSpike}, TaskTypes: []string{"etl"}},
+		{Name: "all", URL: srv.URL, Template: "{{.Task.ID}}"},
+		{Name: "slack", Type: ChannelTypeSlack, URL: "http://localhost", Events: []string{EventFailureSpike}}, // 只用于渲染
+	}})
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+
+	spike := &FailureSpike{TaskType: "etl", Window: 10 * time.Minute, Total: 20, Failed: 10, Rate: 0.5, Baseline: 0.05, StdDev: 0.02}
+	out, err := n.Render("slack", NewFailureSpikeData(spike, time.Now()))
+	if err != nil {
+		t.Fatalf("Render slack failed: %v", err)
+	}
+	if !strings.Contains(out, "Failure rate of etl is 50.0% (10 of 20 tasks in 10m0s), baseline 5.0%") {
+		t.Errorf("Unexpected slack message: %s", out)
+	}
+
+	// 未显式订阅 failure_spike 的渠道不接收
+	n.NotifyFailureSpike(spike, time.Now())
+	select {
+	case got := <-received:
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(got), &v); err != nil {
+			t.Fatalf("Default webhook template produced invalid JSON: %v\n%s", err, got)
+		}
+		if v["event"] != EventFailureSpike || v["task_type"] != "etl" || v["failure_rate"] != 0.5 || v["window_seconds"] != 600.0 {
+			t.Errorf("Unexpected notification: %s", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for notification")
+	}
+	select {
+	case got := <-received:
+		t.Errorf("Unexpected extra notification: %q", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	spike.TaskType = "report"
+	n.NotifyFailureSpike(spike, time.Now())
+	select {
+	case got := <-received:
+		t.Errorf("Channel filtered by task type should not receive: %q", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
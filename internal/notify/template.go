@@ -0,0 +1,217 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// 渠道类型
+const (
+	ChannelTypeWebhook = "webhook"
+	ChannelTypeSlack   = "slack"
+)
+
+// EventSLABreached SLA 违约通知的事件名，渠道的 events 中配置该值即可订阅
+const EventSLABreached = "sla_breached"
+
+// EventQueueTimeExceeded 任务排队超时通知的事件名，渠道的 events 中配置该值即可订阅
+const EventQueueTimeExceeded = "queue_time_exceeded"
+
+// EventFailureSpike 任务类型失败率异常通知的事件名。该通知不对应单个任务（Task 为 nil），只发送给 events 中显式配置了该值的渠道
+const EventFailureSpike = "failure_spike"
+
+// defaultErrorSnippetLen 错误摘要默认长度
+const defaultErrorSnippetLen = 200
+
+// DefaultWebhookTemplate webhook 默认模板：任务的 JSON 摘要
+const DefaultWebhookTemplate = `{{if .Spike}}{
+  "event": {{json .Event}},
+  "task_type": {{json .Spike.TaskType}},
+  "window_seconds": {{.Spike.Window.Seconds}},
+  "finished": {{.Spike.Total}},
+  "failed": {{.Spike.Failed}},
+  "failure_rate": {{.Spike.Rate}},
+  "baseline_rate": {{.Spike.Baseline}},
+  "baseline_stddev": {{.Spike.StdDev}}
+}{{else}}{
+  "event": {{json .Event}},
+  "task_id": {{json .Task.ID}},
+  "name": {{json .Task.Name}},
+  "task_type": {{json .Task.TaskType}},
+  "from_status": {{json .FromStatus}},
+  "to_status": {{json .ToStatus}},
+  "duration_seconds": {{.Duration.Seconds}},
+  "error": {{json .ErrorSnippet}}
+}{{end}}`
+
+// DefaultSlackTemplate Slack 默认模板：blocks 消息
+const DefaultSlackTemplate = `{{if .Spike}}{
+  "text": {{json (printf "Failure rate of %s is %.1f%% (%d of %d tasks in %s), baseline %.1f%%" .Spike.TaskType (percent .Spike.Rate) .Spike.Failed .Spike.Total (duration .Spike.Window) (percent .Spike.Baseline))}}
+}{{else}}{
+  "text": {{if eq .Event "sla_breached"}}{{json (printf "Task %s missed its SLA by %s" .Task.Name (duration .Overdue))}}{{else if eq .Event "queue_time_exceeded"}}{{json (printf "Task %s has been queued for %s" .Task.Name (duration .QueueTime))}}{{else}}{{json (printf "Task %s is %s" .Task.Name .ToStatus)}}{{end}},
+  "blocks": [
+    {
+      "type": "section",
+      "text": {
+        "type": "mrkdwn",
+        "text": {{json (printf "*%s* → ` + "`%s`" + `\nID: %s\nDuration: %s" .Task.Name .ToStatus .Task.ID (duration .Duration))}}
+      }
+    }{{if .ErrorSnippet}},
+    {
+      "type": "section",
+      "text": {
+        "type": "mrkdwn",
+        "text": {{json (printf "` + "```%s```" + `" .ErrorSnippet)}}
+      }
+    }{{end}}
+  ]
+}{{end}}`
+
+// TemplateData 模板渲染数据
+type TemplateData struct {
+	Event        string
+	Task         *model.Task
+	FromStatus   string
+	ToStatus     string
+	Duration     time.Duration
+	ErrorSnippet string
+	Timestamp    time.Time
+	Overdue      time.Duration // SLA 违约通知中超过截止时间的时长
+	QueueTime    time.Duration // 排队超时通知中已排队的时长
+	Spike        *FailureSpike // 失败率异常通知的内容，其他通知为 nil
+}
+
+// FailureSpike 某一任务类型最近窗口内的失败率显著高于基线
+type FailureSpike struct {
+	TaskType string
+	Window   time.Duration // 统计窗口
+	Total    int           // 窗口内结束（成功、失败或超时）的任务数
+	Failed   int
+	Rate     float64 // 窗口内失败率
+	Baseline float64 // 基线失败率（此前各窗口失败率的均值）
+	StdDev   float64 // 基线失败率的标准差
+}
+
+// taskType 通知对应的任务类型，用于渠道的任务类型过滤
+func (d *TemplateData) taskType() string {
+	if d.Spike != nil {
+		return d.Spike.TaskType
+	}
+	return d.Task.TaskType
+}
+
+// NewTemplateData 根据任务状态变更构造模板数据
+func NewTemplateData(task *model.Task, from, to model.TaskStatus) *TemplateData {
+	now := model.Now()
+	data := &TemplateData{
+		Event:        strings.ToLower(to.String()),
+		Task:         task,
+		FromStatus:   from.String(),
+		ToStatus:     to.String(),
+		ErrorSnippet: truncate(task.ErrorMessage, defaultErrorSnippetLen),
+		Timestamp:    now,
+	}
+
+	if task.StartedAt != nil {
+		end := now
+		if task.CompletedAt != nil {
+			end = *task.CompletedAt
+		}
+		data.Duration = end.Sub(*task.StartedAt)
+	}
+
+	return data
+}
+
+// NewSLABreachData 构造 SLA 违约通知的模板数据，FromStatus 与 ToStatus 均为任务当前状态
+func NewSLABreachData(task *model.Task, now time.Time) *TemplateData {
+	data := NewTemplateData(task, task.Status, task.Status)
+	data.Event = EventSLABreached
+	data.Timestamp = now
+	if task.SLADeadline != nil {
+		end := now
+		if task.IsTerminal() && task.CompletedAt != nil {
+			end = *task.CompletedAt
+		}
+		if d := end.Sub(*task.SLADeadline); d > 0 {
+			data.Overdue = d
+		}
+	}
+	return data
+}
+
+// NewQueueTimeExceededData 构造排队超时通知的模板数据，FromStatus 与 ToStatus 均为 PENDING
+func NewQueueTimeExceededData(task *model.Task, now time.Time) *TemplateData {
+	data := NewTemplateData(task, task.Status, task.Status)
+	data.Event = EventQueueTimeExceeded
+	data.Timestamp = now
+	data.QueueTime = task.QueueTime(now)
+	return data
+}
+
+// NewFailureSpikeData 构造失败率异常通知的模板数据
+func NewFailureSpikeData(spike *FailureSpike, now time.Time) *TemplateData {
+	return &TemplateData{Event: EventFailureSpike, Spike: spike, Timestamp: now}
+}
+
+// templateFuncs 模板可用函数
+var templateFuncs = template.FuncMap{
+	"json":     toJSON,
+	"truncate": func(n int, s string) string { return truncate(s, n) },
+	"duration": formatDuration,
+	"percent":  func(ratio float64) float64 { return ratio * 100 },
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"param": func(t *model.Task, key string) string {
+		if t == nil || t.InputParams == nil {
+			return ""
+		}
+		return t.InputParams[key]
+	},
+}
+
+// parseTemplate 解析渠道模板，未配置时使用渠道类型的默认模板
+func parseTemplate(name, channelType, text string) (*template.Template, error) {
+	if text == "" {
+		switch channelType {
+		case ChannelTypeSlack:
+			text = DefaultSlackTemplate
+		default:
+			text = DefaultWebhookTemplate
+		}
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template for channel %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// toJSON 将值编码为 JSON 字面量，便于在 JSON 模板中安全嵌入字符串
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// truncate 按字符截断并追加省略号
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
+
+// formatDuration 格式化时长（精确到毫秒）
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,259 @@
+// Package openapi 由路由表和请求/响应类型（反射 json、binding 标签）生成 OpenAPI 3.0 文档，
+// 路由注册和文档共用同一份描述，接口变更无需手工同步
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"taskflow/internal/apiversion"
+)
+
+// Version 生成的 OpenAPI 规范版本
+const Version = "3.0.3"
+
+// Document OpenAPI 文档（只包含本服务用到的字段）
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info 文档元信息
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem 一个路径下各 HTTP 方法的操作
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+}
+
+// Operation 一个接口操作
+type Operation struct {
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	OperationID string              `json:"operationId"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter 路径或查询参数
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response 响应
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType 某种内容类型的结构
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components 可复用的结构定义
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Route 一个 HTTP 接口的描述，Path 使用 gin 语法（/tasks/:id），路径参数自动生成
+type Route struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Query       []Param     // 查询参数
+	Header      []Param     // 请求头
+	Form        []Param     // multipart/form-data 字段，Type 为 file 表示上传文件
+	Body        interface{} // JSON 请求体类型的零值，nil 表示无请求体
+	Status      int         // 成功状态码，默认 200
+	Response    interface{} // 成功响应类型的零值，nil 表示无响应体
+	ContentType string      // 成功响应的内容类型，默认 application/json
+	Raw         bool        // 响应不包裹版本信封（文件下载、事件流等）
+	NotModified bool        // 带 If-None-Match 的条件请求在资源未变化时返回 304
+}
+
+// Param 查询参数、请求头或表单字段
+type Param struct {
+	Name        string
+	Type        string // string、integer、boolean、file
+	Description string
+	Required    bool
+}
+
+// pathParamPattern gin 路径参数
+var pathParamPattern = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// Build 为每个 API 版本生成 /api/<版本> 下的全部接口：已弃用版本的操作标记 deprecated，
+// 需要信封的版本（v2 起）成功响应包裹为 {api_version, data}
+func Build(info Info, versions apiversion.Set, routes []Route, errorType interface{}) *Document {
+	g := newGenerator()
+	errorSchema := g.schemaOf(errorType)
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+	for _, v := range versions {
+		for _, r := range routes {
+			path := "/api/" + v.Name + pathParamPattern.ReplaceAllString(r.Path, "{$1}")
+			item := doc.Paths[path]
+			if item == nil {
+				item = &PathItem{}
+				doc.Paths[path] = item
+			}
+			item.set(r.Method, g.operation(v, r, errorSchema))
+		}
+	}
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+// set 按方法放入操作
+func (p *PathItem) set(method string, op *Operation) {
+	switch method {
+	case http.MethodGet:
+		p.Get = op
+	case http.MethodPut:
+		p.Put = op
+	case http.MethodPost:
+		p.Post = op
+	case http.MethodDelete:
+		p.Delete = op
+	case http.MethodPatch:
+		p.Patch = op
+	}
+}
+
+// operation 生成一个版本下的接口操作
+func (g *generator) operation(v apiversion.Version, r Route, errorSchema *Schema) *Operation {
+	op := &Operation{
+		Summary:     r.Summary,
+		OperationID: operationID(v.Name, r.Method, r.Path),
+		Deprecated:  v.Deprecated(),
+		Responses:   make(map[string]Response),
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+
+	for _, m := range pathParamPattern.FindAllStringSubmatch(r.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, q := range r.Query {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: q.Name, In: "query", Description: q.Description, Required: q.Required, Schema: &Schema{Type: q.Type},
+		})
+	}
+	for _, h := range r.Header {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: h.Name, In: "header", Description: h.Description, Required: h.Required, Schema: &Schema{Type: h.Type},
+		})
+	}
+
+	switch {
+	case r.Body != nil:
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: g.schemaOf(r.Body)}},
+		}
+	case len(r.Form) > 0:
+		form := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for _, f := range r.Form {
+			field := &Schema{Type: f.Type, Description: f.Description}
+			if f.Type == "file" {
+				field = &Schema{Type: "string", Format: "binary", Description: f.Description}
+			}
+			form.Properties[f.Name] = field
+			if f.Required {
+				form.Required = append(form.Required, f.Name)
+			}
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"multipart/form-data": {Schema: form}},
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp := Response{Description: http.StatusText(status)}
+	contentType := r.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	if r.Response != nil || contentType != "application/json" {
+		schema := &Schema{Type: "string"}
+		if r.Response != nil {
+			schema = g.schemaOf(r.Response)
+		} else if !strings.HasPrefix(contentType, "text/") {
+			schema.Format = "binary"
+		}
+		if !r.Raw && v.Enveloped() {
+			schema = envelope(schema)
+		}
+		resp.Content = map[string]MediaType{contentType: {Schema: schema}}
+	}
+	op.Responses[strconv.Itoa(status)] = resp
+	if r.NotModified {
+		op.Responses[strconv.Itoa(http.StatusNotModified)] = Response{Description: http.StatusText(http.StatusNotModified)}
+	}
+	op.Responses["default"] = Response{
+		Description: "错误",
+		Content:     map[string]MediaType{"application/json": {Schema: errorSchema}},
+	}
+	return op
+}
+
+// envelope 版本信封结构，与 middleware.Envelope 一致
+func envelope(data *Schema) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"api_version": {Type: "string"},
+			"deprecated":  {Type: "boolean"},
+			"sunset":      {Type: "string", Format: "date-time"},
+			"data":        data,
+		},
+		Required: []string{"api_version", "data"},
+	}
+}
+
+// operationID 生成唯一操作 ID，如 v2_get_tasks_id_logs
+func operationID(version, method, path string) string {
+	parts := []string{version, strings.ToLower(method)}
+	for _, seg := range strings.Split(path, "/") {
+		seg = strings.TrimLeft(seg, ":*")
+		if seg != "" {
+			parts = append(parts, strings.ReplaceAll(seg, "-", "_"))
+		}
+	}
+	return strings.Join(parts, "_")
+}
@@ -0,0 +1,113 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"taskflow/internal/apiversion"
+)
+
+type testItem struct {
+	ID        string            `json:"id"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Children  []*testItem       `json:"children,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	internal  string
+	Ignored   string `json:"-"`
+}
+
+type testCreateBody struct {
+	Name     string   `json:"name" binding:"required,max=64"`
+	Priority int32    `json:"priority" binding:"gte=0,lte=4"`
+	Tags     []string `json:"tags" binding:"min=1"`
+}
+
+type testError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func TestBuild(t *testing.T) {
+	versions := apiversion.Set{
+		{Name: apiversion.V1, DeprecatedAt: time.Now()},
+		{Name: apiversion.V2},
+	}
+	doc := Build(Info{Title: "test", Version: "v2"}, versions, []Route{
+		{Method: http.MethodPost, Path: "/items", Body: testCreateBody{}, Status: http.StatusCreated, Response: &testItem{}},
+		{Method: http.MethodGet, Path: "/items/:id/raw", ContentType: "application/octet-stream", Raw: true},
+		{Method: http.MethodGet, Path: "/items/:id", Header: []Param{{Name: "If-None-Match", Type: "string"}},
+			Response: &testItem{}, NotModified: true},
+	}, testError{})
+
+	v1 := doc.Paths["/api/v1/items"]
+	v2 := doc.Paths["/api/v2/items"]
+	if v1 == nil || v1.Post == nil || v2 == nil || v2.Post == nil {
+		t.Fatalf("paths = %v", doc.Paths)
+	}
+	if !v1.Post.Deprecated || v2.Post.Deprecated {
+		t.Errorf("deprecated v1=%v v2=%v", v1.Post.Deprecated, v2.Post.Deprecated)
+	}
+	if v1.Post.OperationID != "v1_post_items" {
+		t.Errorf("operationId = %s", v1.Post.OperationID)
+	}
+
+	// v1 直接引用结构，v2 包裹在信封中
+	if ref := v1.Post.Responses["201"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/TestItem" {
+		t.Errorf("v1 response ref = %q", ref)
+	}
+	env := v2.Post.Responses["201"].Content["application/json"].Schema
+	if env.Properties["data"] == nil || env.Properties["data"].Ref != "#/components/schemas/TestItem" {
+		t.Errorf("v2 response = %+v", env)
+	}
+
+	if ref := v1.Post.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/TestCreateBody" {
+		t.Fatalf("request body ref = %q", ref)
+	}
+	body := doc.Components.Schemas["TestCreateBody"]
+	if len(body.Required) != 1 || body.Required[0] != "name" {
+		t.Errorf("required = %v", body.Required)
+	}
+	if p := body.Properties["priority"]; p.Minimum == nil || *p.Minimum != 0 || p.Maximum == nil || *p.Maximum != 4 {
+		t.Errorf("priority = %+v", p)
+	}
+	if n := body.Properties["name"].MaxLength; n == nil || *n != 64 {
+		t.Errorf("name maxLength = %v", n)
+	}
+	if n := body.Properties["tags"].MinItems; n == nil || *n != 1 {
+		t.Errorf("tags minItems = %v", n)
+	}
+
+	item := doc.Components.Schemas["TestItem"]
+	if item == nil {
+		t.Fatalf("schemas = %v", doc.Components.Schemas)
+	}
+	if _, ok := item.Properties["internal"]; ok {
+		t.Error("unexported field documented")
+	}
+	if _, ok := item.Properties["Ignored"]; ok {
+		t.Error(`json:"-" field documented`)
+	}
+	if item.Properties["created_at"].Format != "date-time" || item.Properties["children"].Items.Ref != "#/components/schemas/TestItem" {
+		t.Errorf("item = %+v", item.Properties)
+	}
+
+	raw := doc.Paths["/api/v2/items/{id}/raw"]
+	if raw == nil || raw.Get == nil || len(raw.Get.Parameters) != 1 || raw.Get.Parameters[0].In != "path" {
+		t.Fatalf("raw path = %+v", raw)
+	}
+	if s := raw.Get.Responses["200"].Content["application/octet-stream"].Schema; s.Format != "binary" {
+		t.Errorf("raw response = %+v", s)
+	}
+
+	get := doc.Paths["/api/v1/items/{id}"].Get
+	if len(get.Parameters) != 2 || get.Parameters[1].In != "header" || get.Parameters[1].Name != "If-None-Match" {
+		t.Errorf("parameters = %+v", get.Parameters)
+	}
+	if _, ok := get.Responses["304"]; !ok {
+		t.Errorf("responses = %v", get.Responses)
+	}
+	if _, ok := raw.Get.Responses["304"]; ok {
+		t.Error("304 documented for a route without conditional requests")
+	}
+}
@@ -0,0 +1,207 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Schema JSON Schema（OpenAPI 3.0 子集）
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	protoEnumType = reflect.TypeOf((*protoreflect.Enum)(nil)).Elem()
+	bytesType     = reflect.TypeOf([]byte(nil))
+)
+
+// generator 反射生成结构定义，具名结构放入 components/schemas 并以 $ref 引用
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// schemaOf 生成值 v 的类型对应的结构
+func (g *generator) schemaOf(v interface{}) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+// schema 与 encoding/json 的序列化结果保持一致
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if t.Implements(protoEnumType) {
+		return enumSchema(reflect.Zero(t).Interface().(protoreflect.Enum))
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t == bytesType {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.define(t)}
+	}
+	// interface{} 等任意值
+	return &Schema{}
+}
+
+// define 注册具名结构，返回在 components/schemas 中的名称，不同包的同名类型加包名前缀区分
+func (g *generator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	// 未导出的类型名首字母大写，便于 SDK 生成类名
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[t] = name
+	// 先占位再展开，支持递归引用
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+// structSchema 按 json 标签展开导出字段，binding 标签转换为必填和取值范围
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				embedded := g.structSchema(f.Type)
+				for k, v := range embedded.Properties {
+					s.Properties[k] = v
+				}
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+			name = f.Name
+		}
+
+		field := g.schema(f.Type)
+		if required := applyBinding(field, f.Tag.Get("binding")); required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = field
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// applyBinding 把 gin binding 规则（required、gte、lte、min、max）写入字段结构，返回是否必填
+func applyBinding(s *Schema, binding string) bool {
+	if binding == "" || s.Ref != "" {
+		return strings.Contains(","+binding+",", ",required,")
+	}
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		if key == "required" {
+			required = true
+			continue
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case s.Type == "integer" || s.Type == "number":
+			switch key {
+			case "gte", "min":
+				s.Minimum = &n
+			case "lte", "max":
+				s.Maximum = &n
+			}
+		case s.Type == "array":
+			if key == "min" {
+				s.MinItems = intPtr(int(n))
+			} else if key == "max" {
+				s.MaxItems = intPtr(int(n))
+			}
+		case s.Type == "string":
+			if key == "min" {
+				s.MinLength = intPtr(int(n))
+			} else if key == "max" {
+				s.MaxLength = intPtr(int(n))
+			}
+		}
+	}
+	return required
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// enumSchema protobuf 枚举序列化为整数，描述中列出各取值的名称
+func enumSchema(e protoreflect.Enum) *Schema {
+	values := e.Descriptor().Values()
+	s := &Schema{Type: "integer", Format: "int32"}
+	names := make([]string, 0, values.Len())
+	for i := 0; i < values.Len(); i++ {
+		v := values.Get(i)
+		s.Enum = append(s.Enum, int32(v.Number()))
+		names = append(names, strconv.Itoa(int(v.Number()))+"="+string(v.Name()))
+	}
+	s.Description = strings.Join(names, ", ")
+	return s
+}
@@ -0,0 +1,29 @@
+package openapi
+
+import (
+	"html/template"
+	"io"
+)
+
+// uiTemplate Swagger UI 页面，静态资源从 CDN 加载，离线环境可直接使用 openapi.json
+var uiTemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`))
+
+// WriteUI 输出加载 specURL 的 Swagger UI 页面
+func WriteUI(w io.Writer, title, specURL string) error {
+	return uiTemplate.Execute(w, struct{ Title, SpecURL string }{title, specURL})
+}
@@ -0,0 +1,373 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// Kafka 消息格式
+const (
+	KafkaFormatJSON  = "json"
+	KafkaFormatProto = "proto"
+)
+
+// TaskLookup 按 ID 获取任务快照，任务已删除时返回 nil
+type TaskLookup func(taskID string) (*model.Task, error)
+
+// Encoder 把发件箱事件和任务快照编码为消息体
+type Encoder func(event *model.OutboxEvent, task *model.Task) ([]byte, error)
+
+// TaskChangeEvent JSON 格式的任务变更事件，字段与 Protobuf TaskChangeEvent 对应
+type TaskChangeEvent struct {
+	EventID    string           `json:"event_id"`
+	TaskID     string           `json:"task_id"`
+	Task       *model.Task      `json:"task,omitempty"`
+	FromStatus model.TaskStatus `json:"from_status"`
+	ToStatus   model.TaskStatus `json:"to_status"`
+	ChangedAt  int64            `json:"changed_at"`
+	ChangeType string           `json:"change_type"`
+
+	CorrelationID string `json:"correlation_id,omitempty"` // 引起变更的请求 ID
+}
+
+// EncodeJSON 以 JSON 编码 TaskChangeEvent
+func EncodeJSON(event *model.OutboxEvent, task *model.Task) ([]byte, error) {
+	return json.Marshal(&TaskChangeEvent{
+		EventID:       event.ID,
+		TaskID:        event.TaskID,
+		Task:          task,
+		FromStatus:    event.FromStatus,
+		ToStatus:      event.ToStatus,
+		ChangedAt:     event.CreatedAt.Unix(),
+		ChangeType:    ChangeType(event),
+		CorrelationID: event.CorrelationID,
+	})
+}
+
+// ChangeType 发件箱事件对应的 TaskChangeEvent.change_type
+func ChangeType(event *model.OutboxEvent) string {
+	switch event.EventType {
+	case model.OutboxEventTaskSLABreached:
+		return "sla_breached"
+	case model.OutboxEventTaskSubStatusChanged:
+		return "sub_status_changed"
+	case model.OutboxEventTaskDependenciesRepaired:
+		return "dependencies_repaired"
+	case model.OutboxEventTaskDependencyRemoved:
+		return "dependency_removed"
+	}
+	return "status_changed"
+}
+
+// KafkaOptions Kafka 接收端配置
+type KafkaOptions struct {
+	Brokers  []string // 引导 broker 地址（host:port）
+	Topic    string
+	ClientID string
+	Format   string        // json 或 proto，仅用于 content-type 消息头
+	Timeout  time.Duration // 单次请求超时，默认 10 秒
+}
+
+// KafkaSink 把任务变更事件发布到 Kafka 主题，key 为任务 ID（同一任务的事件落在同一分区，保持顺序），
+// 消息头携带 event_id 供消费者去重。消息体中的任务为投递时的快照
+type KafkaSink struct {
+	producer    *kafkaProducer
+	tasks       TaskLookup
+	encode      Encoder
+	contentType string
+}
+
+// NewKafkaSink 创建 Kafka 接收端，encode 为空时使用 JSON
+func NewKafkaSink(opts KafkaOptions, tasks TaskLookup, encode Encoder) (*KafkaSink, error) {
+	if len(opts.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers are required")
+	}
+	if opts.Topic == "" {
+		return nil, fmt.Errorf("kafka topic is required")
+	}
+	if opts.ClientID == "" {
+		opts.ClientID = "taskflow"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if encode == nil {
+		encode = EncodeJSON
+	}
+
+	contentType := "application/json"
+	if opts.Format == KafkaFormatProto {
+		contentType = "application/x-protobuf"
+	}
+	return &KafkaSink{
+		producer:    &kafkaProducer{opts: opts, conns: make(map[string]*kafkaConn)},
+		tasks:       tasks,
+		encode:      encode,
+		contentType: contentType,
+	}, nil
+}
+
+// Name 接收端名称
+func (s *KafkaSink) Name() string {
+	return "kafka:" + s.producer.opts.Topic
+}
+
+// Publish 发布事件，等待所有同步副本确认（acks=all）
+func (s *KafkaSink) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	var task *model.Task
+	if s.tasks != nil {
+		var err error
+		if task, err = s.tasks(event.TaskID); err != nil {
+			return err
+		}
+	}
+
+	value, err := s.encode(event, task)
+	if err != nil {
+		return err
+	}
+	return s.producer.produce(ctx, kafkaMessage{
+		Key:   []byte(event.TaskID),
+		Value: value,
+		Headers: []kafkaHeader{
+			{Key: "event_id", Value: []byte(event.ID)},
+			{Key: "event_type", Value: []byte(event.EventType)},
+			{Key: "content-type", Value: []byte(s.contentType)},
+		},
+		Timestamp: event.CreatedAt,
+	})
+}
+
+// Close 关闭 broker 连接
+func (s *KafkaSink) Close() error {
+	s.producer.close()
+	return nil
+}
+
+// kafkaProducer 最小 Kafka 生产者：缓存主题元数据，按 key 哈希选择分区并发送到分区 leader。
+// 发送失败时丢弃连接和元数据，由发件箱中继按退避重试
+type kafkaProducer struct {
+	opts KafkaOptions
+
+	mu            sync.Mutex
+	correlationID int32
+	brokers       map[int32]string // node id -> host:port
+	leaders       []int32          // 按分区号索引的 leader node id，nil 表示需要刷新元数据
+	conns         map[string]*kafkaConn
+}
+
+// kafkaConn broker 连接
+type kafkaConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// produce 发送一条消息
+func (p *kafkaProducer) produce(ctx context.Context, msg kafkaMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.leaders == nil {
+		if err := p.refreshMetadata(ctx); err != nil {
+			return err
+		}
+	}
+
+	partition := partitionForKey(msg.Key, len(p.leaders))
+	addr, ok := p.brokers[p.leaders[partition]]
+	if !ok {
+		p.leaders = nil
+		return fmt.Errorf("kafka: no leader for %s/%d", p.opts.Topic, partition)
+	}
+
+	var body kafkaEncoder
+	body.nullableString(nil) // transactional id
+	body.int16(-1)           // acks=all
+	body.int32(int32(p.opts.Timeout / time.Millisecond))
+	body.int32(1)
+	body.string(p.opts.Topic)
+	body.int32(1)
+	body.int32(partition)
+	body.bytes(encodeRecordBatch([]kafkaMessage{msg}))
+
+	resp, err := p.roundTrip(ctx, addr, kafkaAPIProduce, kafkaProduceVersion, body.buf)
+	if err != nil {
+		p.leaders = nil
+		return err
+	}
+
+	d := kafkaDecoder{buf: resp}
+	for i := d.int32(); i > 0; i-- {
+		d.string() // topic
+		for j := d.int32(); j > 0; j-- {
+			d.int32() // partition
+			code := d.int16()
+			d.int64() // base offset
+			d.int64() // log append time
+			if code != 0 && d.err == nil {
+				kerr := kafkaError(code)
+				if kerr.staleMetadata() {
+					p.leaders = nil
+				}
+				return kerr
+			}
+		}
+	}
+	return d.err
+}
+
+// refreshMetadata 从任一可达 broker 获取主题分区的 leader
+func (p *kafkaProducer) refreshMetadata(ctx context.Context) error {
+	var body kafkaEncoder
+	body.int32(1)
+	body.string(p.opts.Topic)
+
+	var lastErr error
+	for _, addr := range p.opts.Brokers {
+		resp, err := p.roundTrip(ctx, addr, kafkaAPIMetadata, kafkaMetadataVersion, body.buf)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return p.parseMetadata(resp)
+	}
+	return fmt.Errorf("kafka: metadata unavailable: %w", lastErr)
+}
+
+// parseMetadata 解析 Metadata v1 响应
+func (p *kafkaProducer) parseMetadata(resp []byte) error {
+	d := kafkaDecoder{buf: resp}
+
+	brokers := make(map[int32]string)
+	for i := d.int32(); i > 0; i-- {
+		nodeID := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		brokers[nodeID] = net.JoinHostPort(host, fmt.Sprint(port))
+	}
+	d.int32() // controller id
+
+	var leaders []int32
+	for i := d.int32(); i > 0; i-- {
+		code := d.int16()
+		name := d.string()
+		d.int8() // is internal
+		partitions := make(map[int32]int32)
+		for j := d.int32(); j > 0; j-- {
+			d.int16() // partition error code
+			index := d.int32()
+			partitions[index] = d.int32()
+			for k := d.int32(); k > 0; k-- { // replicas
+				d.int32()
+			}
+			for k := d.int32(); k > 0; k-- { // isr
+				d.int32()
+			}
+		}
+		if name != p.opts.Topic {
+			continue
+		}
+		if code != 0 {
+			return kafkaError(code)
+		}
+		leaders = make([]int32, len(partitions))
+		for index, leader := range partitions {
+			if int(index) >= len(leaders) {
+				return fmt.Errorf("kafka: non-contiguous partitions for topic %s", name)
+			}
+			leaders[index] = leader
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if len(leaders) == 0 {
+		return fmt.Errorf("kafka: topic %s has no partitions", p.opts.Topic)
+	}
+
+	p.brokers = brokers
+	p.leaders = leaders
+	return nil
+}
+
+// roundTrip 发送请求并读取响应体（不含关联 ID），网络错误时断开连接
+func (p *kafkaProducer) roundTrip(ctx context.Context, addr string, apiKey, apiVersion int16, body []byte) ([]byte, error) {
+	c, err := p.conn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.exchange(ctx, c, apiKey, apiVersion, body)
+	if err != nil {
+		c.conn.Close()
+		delete(p.conns, addr)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// exchange 在连接上完成一次请求响应
+func (p *kafkaProducer) exchange(ctx context.Context, c *kafkaConn, apiKey, apiVersion int16, body []byte) ([]byte, error) {
+	deadline := time.Now().Add(p.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	p.correlationID++
+	id := p.correlationID
+	if _, err := c.conn.Write(encodeRequest(apiKey, apiVersion, id, p.opts.ClientID, body)); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(c.r, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, err
+	}
+	if len(resp) < 4 || int32(binary.BigEndian.Uint32(resp)) != id {
+		return nil, errors.New("kafka: correlation id mismatch")
+	}
+	return resp[4:], nil
+}
+
+// conn 获取或建立到 broker 的连接
+func (p *kafkaProducer) conn(ctx context.Context, addr string) (*kafkaConn, error) {
+	if c, ok := p.conns[addr]; ok {
+		return c, nil
+	}
+	dialer := net.Dialer{Timeout: p.opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: dial %s: %w", addr, err)
+	}
+	c := &kafkaConn{conn: conn, r: bufio.NewReader(conn)}
+	p.conns[addr] = c
+	return c, nil
+}
+
+// close 关闭所有连接
+func (p *kafkaProducer) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, c := range p.conns {
+		c.conn.Close()
+		delete(p.conns, addr)
+	}
+}
@@ -0,0 +1,283 @@
+package outbox
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+// Kafka API key 与版本（只实现生产所需的最小子集）
+const (
+	kafkaAPIProduce       = 0
+	kafkaAPIMetadata      = 3
+	kafkaProduceVersion   = 3 // 首个支持 RecordBatch v2（消息头）的版本
+	kafkaMetadataVersion  = 1
+	kafkaRecordBatchMagic = 2
+)
+
+// 需要刷新元数据的 Kafka 错误码
+const (
+	kafkaErrUnknownTopicOrPartition = 3
+	kafkaErrLeaderNotAvailable      = 5
+	kafkaErrNotLeaderForPartition   = 6
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaError Kafka 响应中的错误码
+type kafkaError int16
+
+func (e kafkaError) Error() string { return fmt.Sprintf("kafka: error code %d", int16(e)) }
+
+// staleMetadata 错误是否意味着分区 leader 已变化
+func (e kafkaError) staleMetadata() bool {
+	return e == kafkaErrUnknownTopicOrPartition || e == kafkaErrLeaderNotAvailable || e == kafkaErrNotLeaderForPartition
+}
+
+// kafkaHeader 消息头
+type kafkaHeader struct {
+	Key   string
+	Value []byte
+}
+
+// kafkaMessage 待发送的消息
+type kafkaMessage struct {
+	Key       []byte
+	Value     []byte
+	Headers   []kafkaHeader
+	Timestamp time.Time
+}
+
+// kafkaEncoder 大端编码缓冲
+type kafkaEncoder struct {
+	buf []byte
+}
+
+func (e *kafkaEncoder) int8(v int8)   { e.buf = append(e.buf, byte(v)) }
+func (e *kafkaEncoder) int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *kafkaEncoder) int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *kafkaEncoder) int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+
+// varint zigzag 变长整数（RecordBatch v2 记录内使用）
+func (e *kafkaEncoder) varint(v int64) { e.buf = binary.AppendVarint(e.buf, v) }
+
+func (e *kafkaEncoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *kafkaEncoder) nullableString(s *string) {
+	if s == nil {
+		e.int16(-1)
+		return
+	}
+	e.string(*s)
+}
+
+func (e *kafkaEncoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// varintBytes 变长长度前缀的字节串，nil 编码为 -1
+func (e *kafkaEncoder) varintBytes(b []byte) {
+	if b == nil {
+		e.varint(-1)
+		return
+	}
+	e.varint(int64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// kafkaDecoder 大端解码，遇到越界时记录错误并返回零值
+type kafkaDecoder struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.pos+n > len(d.buf) {
+		d.err = errors.New("kafka: truncated response")
+		return nil
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf[d.pos:])
+	if n <= 0 {
+		d.err = errors.New("kafka: malformed varint")
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+func (d *kafkaDecoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+func (d *kafkaDecoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+func (d *kafkaDecoder) varintBytes() []byte {
+	n := d.varint()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+// encodeRequest 编码带请求头（v1）的请求，含长度前缀
+func encodeRequest(apiKey, apiVersion int16, correlationID int32, clientID string, body []byte) []byte {
+	var e kafkaEncoder
+	e.int32(0) // 长度占位
+	e.int16(apiKey)
+	e.int16(apiVersion)
+	e.int32(correlationID)
+	e.string(clientID)
+	e.buf = append(e.buf, body...)
+	binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+	return e.buf
+}
+
+// encodeRecordBatch 编码 RecordBatch v2（不压缩、非幂等生产者）
+func encodeRecordBatch(msgs []kafkaMessage) []byte {
+	first := msgs[0].Timestamp.UnixMilli()
+	maxTS := first
+
+	var records kafkaEncoder
+	for i, msg := range msgs {
+		ts := msg.Timestamp.UnixMilli()
+		if ts > maxTS {
+			maxTS = ts
+		}
+
+		var r kafkaEncoder
+		r.int8(0) // attributes
+		r.varint(ts - first)
+		r.varint(int64(i))
+		r.varintBytes(msg.Key)
+		r.varintBytes(msg.Value)
+		r.varint(int64(len(msg.Headers)))
+		for _, h := range msg.Headers {
+			r.varintBytes([]byte(h.Key))
+			r.varintBytes(h.Value)
+		}
+
+		records.varint(int64(len(r.buf)))
+		records.buf = append(records.buf, r.buf...)
+	}
+
+	// CRC 覆盖 attributes 到批次末尾
+	var tail kafkaEncoder
+	tail.int16(0) // attributes
+	tail.int32(int32(len(msgs) - 1))
+	tail.int64(first)
+	tail.int64(maxTS)
+	tail.int64(-1) // producer id
+	tail.int16(-1) // producer epoch
+	tail.int32(-1) // base sequence
+	tail.int32(int32(len(msgs)))
+	tail.buf = append(tail.buf, records.buf...)
+
+	var batch kafkaEncoder
+	batch.int64(0)                                // base offset
+	batch.int32(int32(4 + 1 + 4 + len(tail.buf))) // batch length：leader epoch + magic + crc + 其余
+	batch.int32(-1)                               // partition leader epoch
+	batch.int8(kafkaRecordBatchMagic)
+	batch.int32(int32(crc32.Checksum(tail.buf, castagnoli)))
+	batch.buf = append(batch.buf, tail.buf...)
+	return batch.buf
+}
+
+// murmur2 与 Java 客户端默认分区器相同的哈希，保证同一 key 落在相同分区
+func murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	length := len(data)
+	h := seed ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length & 3 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// partitionForKey 按 key 选择分区
+func partitionForKey(key []byte, partitions int) int32 {
+	return (murmur2(key) & 0x7fffffff) % int32(partitions)
+}
@@ -0,0 +1,308 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"taskflow/internal/config"
+	"taskflow/internal/model"
+)
+
+func TestMurmur2MatchesJavaClient(t *testing.T) {
+	// 取自 Kafka Java 客户端 UtilsTest
+	cases := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for in, want := range cases {
+		if got := murmur2([]byte(in)); got != want {
+			t.Errorf("murmur2(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestKafkaSink_PublishesKeyedJSONEvents(t *testing.T) {
+	broker := newFakeKafka(t, 3)
+	repo := setupTestRepo(t)
+	task := createCompletedTask(t, repo, "kafka")
+
+	sinks, err := NewSinks(config.OutboxConfig{Kafka: config.KafkaConfig{
+		Brokers: []string{broker.addr},
+		Topic:   "task-events",
+		Format:  KafkaFormatJSON,
+	}}, repo.GetByID, nil)
+	if err != nil {
+		t.Fatalf("failed to create sinks: %v", err)
+	}
+	defer sinks[0].(*KafkaSink).Close()
+
+	relay := NewRelay(repo, sinks, Options{})
+	if n := relay.relayOnce(context.Background()); n != 2 {
+		t.Fatalf("expected 2 events delivered, got %d", n)
+	}
+
+	msgs := broker.messages()
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 kafka messages, got %d", len(msgs))
+	}
+	wantPartition := partitionForKey([]byte(task.ID), 3)
+	for _, msg := range msgs {
+		if msg.topic != "task-events" || msg.partition != wantPartition || string(msg.key) != task.ID {
+			t.Errorf("unexpected message routing: topic=%s partition=%d key=%s", msg.topic, msg.partition, msg.key)
+		}
+		if msg.headers["content-type"] != "application/json" || msg.headers["event_id"] == "" {
+			t.Errorf("unexpected headers: %v", msg.headers)
+		}
+	}
+
+	var event TaskChangeEvent
+	if err := json.Unmarshal(msgs[1].value, &event); err != nil {
+		t.Fatalf("invalid JSON payload: %v", err)
+	}
+	if event.TaskID != task.ID || event.ToStatus != model.TaskStatusSucceeded || event.Task == nil || event.Task.Name != "kafka" {
+		t.Errorf("unexpected payload: %+v", event)
+	}
+	if event.EventID != msgs[1].headers["event_id"] {
+		t.Errorf("payload event ID %s does not match header %s", event.EventID, msgs[1].headers["event_id"])
+	}
+}
+
+func TestKafkaSink_RefreshesMetadataOnLeaderChange(t *testing.T) {
+	broker := newFakeKafka(t, 1)
+	sink, err := NewKafkaSink(KafkaOptions{Brokers: []string{broker.addr}, Topic: "task-events"}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	event := &model.OutboxEvent{ID: "e1", TaskID: "t1", EventType: model.OutboxEventTaskStatusChanged, CreatedAt: time.Now()}
+	broker.failNext(kafkaErrNotLeaderForPartition)
+	if err := sink.Publish(context.Background(), event); err == nil {
+		t.Fatal("expected error when broker reports not leader")
+	}
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	if got := broker.metadataRequests(); got != 2 {
+		t.Errorf("expected metadata refresh after leader change, got %d metadata requests", got)
+	}
+}
+
+// fakeKafkaMessage 假 broker 收到的消息
+type fakeKafkaMessage struct {
+	topic     string
+	partition int32
+	key       []byte
+	value     []byte
+	headers   map[string]string
+}
+
+// fakeKafka 单节点假 broker，实现 Metadata v1 和 Produce v3
+type fakeKafka struct {
+	t          *testing.T
+	addr       string
+	host       string
+	port       int32
+	partitions int
+
+	mu       sync.Mutex
+	received []fakeKafkaMessage
+	metadata int
+	failCode int16
+}
+
+func newFakeKafka(t *testing.T, partitions int) *fakeKafka {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	f := &fakeKafka{t: t, addr: ln.Addr().String(), host: host, port: int32(port), partitions: partitions}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeKafka) messages() []fakeKafkaMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeKafkaMessage(nil), f.received...)
+}
+
+func (f *fakeKafka) metadataRequests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.metadata
+}
+
+func (f *fakeKafka) failNext(code int16) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failCode = code
+}
+
+func (f *fakeKafka) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, req); err != nil {
+			return
+		}
+
+		d := kafkaDecoder{buf: req}
+		apiKey := d.int16()
+		d.int16() // version
+		correlationID := d.int32()
+		d.string() // client id
+
+		var resp kafkaEncoder
+		resp.int32(0)
+		resp.int32(correlationID)
+		switch apiKey {
+		case kafkaAPIMetadata:
+			f.writeMetadata(&resp)
+		case kafkaAPIProduce:
+			f.handleProduce(&d, &resp)
+		default:
+			return
+		}
+		binary.BigEndian.PutUint32(resp.buf, uint32(len(resp.buf)-4))
+		if _, err := conn.Write(resp.buf); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeKafka) writeMetadata(resp *kafkaEncoder) {
+	f.mu.Lock()
+	f.metadata++
+	f.mu.Unlock()
+
+	resp.int32(1) // brokers
+	resp.int32(1)
+	resp.string(f.host)
+	resp.int32(f.port)
+	resp.nullableString(nil)
+	resp.int32(1) // controller id
+	resp.int32(1) // topics
+	resp.int16(0)
+	resp.string("task-events")
+	resp.int8(0)
+	resp.int32(int32(f.partitions))
+	for i := 0; i < f.partitions; i++ {
+		resp.int16(0)
+		resp.int32(int32(i))
+		resp.int32(1) // leader
+		resp.int32(1)
+		resp.int32(1)
+		resp.int32(1)
+		resp.int32(1)
+	}
+}
+
+func (f *fakeKafka) handleProduce(d *kafkaDecoder, resp *kafkaEncoder) {
+	if n := d.int16(); n >= 0 { // transactional id
+		d.next(int(n))
+	}
+	if acks := d.int16(); acks != -1 {
+		f.t.Errorf("expected acks=all, got %d", acks)
+	}
+	d.int32() // timeout
+	d.int32() // topics
+	topic := d.string()
+	d.int32() // partitions
+	partition := d.int32()
+	batch := d.bytes()
+
+	f.mu.Lock()
+	code := f.failCode
+	f.failCode = 0
+	f.mu.Unlock()
+	if code == 0 {
+		msgs := f.decodeBatch(topic, partition, batch)
+		f.mu.Lock()
+		f.received = append(f.received, msgs...)
+		f.mu.Unlock()
+	}
+
+	resp.int32(1)
+	resp.string(topic)
+	resp.int32(1)
+	resp.int32(partition)
+	resp.int16(code)
+	resp.int64(0)
+	resp.int64(-1)
+	resp.int32(0) // throttle time
+}
+
+func (f *fakeKafka) decodeBatch(topic string, partition int32, batch []byte) []fakeKafkaMessage {
+	d := kafkaDecoder{buf: batch}
+	d.int64() // base offset
+	if n := d.int32(); int(n) != len(batch)-12 {
+		f.t.Errorf("batch length %d does not match payload %d", n, len(batch)-12)
+	}
+	d.int32() // leader epoch
+	if magic := d.int8(); magic != kafkaRecordBatchMagic {
+		f.t.Errorf("unexpected magic %d", magic)
+	}
+	crc := uint32(d.int32())
+	if got := crc32.Checksum(batch[d.pos:], castagnoli); got != crc {
+		f.t.Errorf("crc mismatch: header %d, computed %d", crc, got)
+	}
+	d.int16() // attributes
+	d.int32() // last offset delta
+	d.int64() // first timestamp
+	d.int64() // max timestamp
+	d.int64() // producer id
+	d.int16() // producer epoch
+	d.int32() // base sequence
+
+	var msgs []fakeKafkaMessage
+	for n := d.int32(); n > 0; n-- {
+		d.varint() // length
+		d.int8()   // attributes
+		d.varint() // timestamp delta
+		d.varint() // offset delta
+		msg := fakeKafkaMessage{topic: topic, partition: partition, headers: make(map[string]string)}
+		msg.key = d.varintBytes()
+		msg.value = d.varintBytes()
+		for h := d.varint(); h > 0; h-- {
+			k := d.varintBytes()
+			msg.headers[string(k)] = string(d.varintBytes())
+		}
+		msgs = append(msgs, msg)
+	}
+	if d.err != nil {
+		f.t.Errorf("failed to decode record batch: %v", d.err)
+	}
+	return msgs
+}
@@ -0,0 +1,158 @@
+// Package outbox 发件箱中继：轮询与状态变更同事务写入的事件，并至少一次投递到 webhook、消息队列等接收端
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// 重试退避：首次失败后 1 秒，之后翻倍，最长 5 分钟
+const (
+	baseBackoff = time.Second
+	maxBackoff  = 5 * time.Minute
+)
+
+// purgeInterval 清理已投递事件的间隔
+const purgeInterval = time.Hour
+
+// Options 中继参数
+type Options struct {
+	PollInterval time.Duration // 轮询间隔，默认 1 秒
+	BatchSize    int           // 每轮最多投递的事件数，默认 100
+	Retention    time.Duration // 已投递事件保留时长，0 表示不清理
+}
+
+// Relay 发件箱中继。事件只有在所有接收端都投递成功后才标记为已投递，
+// 任一接收端失败时整条事件按退避重试（已成功的接收端会再次收到，消费者应按事件 ID 去重）。
+// 同一任务的事件按写入顺序投递：较早的事件失败后，该任务后续的事件等待其投递成功
+type Relay struct {
+	repo  repository.TaskStore
+	sinks []Sink
+	opts  Options
+	now   func() time.Time
+
+	lastPurge time.Time
+}
+
+// NewRelay 创建中继
+func NewRelay(repo repository.TaskStore, sinks []Sink, opts Options) *Relay {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	return &Relay{repo: repo, sinks: sinks, opts: opts, now: time.Now}
+}
+
+// Run 轮询并投递事件，直到 ctx 取消
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	logger.Infof("Outbox relay started with %d sink(s)", len(r.sinks))
+	for {
+		r.relayOnce(ctx)
+		r.purge()
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayOnce 投递一批到期事件，返回成功投递的事件数
+func (r *Relay) relayOnce(ctx context.Context) int {
+	events, err := r.repo.ListDueOutboxEvents(r.now(), r.opts.BatchSize)
+	if err != nil {
+		logger.Errorf("Failed to list outbox events: %v", err)
+		return 0
+	}
+
+	delivered := 0
+	blocked := make(map[string]bool) // 本轮已有事件失败的任务
+	for _, event := range events {
+		if ctx.Err() != nil {
+			break
+		}
+		if blocked[event.TaskID] {
+			continue
+		}
+
+		if err := r.deliver(ctx, event); err != nil {
+			blocked[event.TaskID] = true
+			next := r.now().Add(backoff(event.Attempts))
+			logger.Warnf("Failed to deliver outbox event %s (attempt %d), retrying at %s: %v",
+				event.ID, event.Attempts+1, next.Format(time.RFC3339), err)
+			if err := r.repo.MarkOutboxEventFailed(event.ID, err.Error(), next); err != nil {
+				logger.Errorf("Failed to record outbox delivery failure for %s: %v", event.ID, err)
+			}
+			continue
+		}
+
+		if err := r.repo.MarkOutboxEventDelivered(event.ID, r.now()); err != nil {
+			// 标记失败时事件会被再次投递，符合至少一次语义
+			logger.Errorf("Failed to mark outbox event %s delivered: %v", event.ID, err)
+			continue
+		}
+		delivered++
+	}
+
+	if count, err := r.repo.CountPendingOutboxEvents(); err == nil {
+		metrics.RecordOutboxBacklog(count)
+	}
+	return delivered
+}
+
+// deliver 投递到所有接收端，返回第一个错误
+func (r *Relay) deliver(ctx context.Context, event *model.OutboxEvent) error {
+	var firstErr error
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			metrics.RecordOutboxDelivery(sink.Name(), "failure")
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		metrics.RecordOutboxDelivery(sink.Name(), "success")
+	}
+	return firstErr
+}
+
+// purge 定期删除超过保留期的已投递事件
+func (r *Relay) purge() {
+	if r.opts.Retention <= 0 || r.now().Sub(r.lastPurge) < purgeInterval {
+		return
+	}
+	r.lastPurge = r.now()
+
+	n, err := r.repo.PurgeDeliveredOutboxEvents(r.now().Add(-r.opts.Retention))
+	if err != nil {
+		logger.Errorf("Failed to purge delivered outbox events: %v", err)
+		return
+	}
+	if n > 0 {
+		logger.Infof("Purged %d delivered outbox event(s)", n)
+	}
+}
+
+// backoff 第 attempts 次失败后的重试间隔
+func backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 0; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+func setupTestRepo(t *testing.T) *repository.TaskRepository {
+	t.Helper()
+	tmpFile, err := os.CreateTemp("", "taskflow_outbox_test_*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	db, err := repository.NewSQLite(tmpFile.Name())
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		t.Fatalf("failed to create SQLite: %v", err)
+	}
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to init schema: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+	return repository.NewTaskRepository(db)
+}
+
+// createCompletedTask 创建任务并经历 PENDING -> RUNNING -> SUCCEEDED，产生两条发件箱事件
+func createCompletedTask(t *testing.T, repo *repository.TaskRepository, name string) *model.Task {
+	t.Helper()
+	task := model.NewTask(name, "", model.TaskPriorityNormal, "test", nil, nil, 0, "tester")
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if err := repo.UpdateStatusWithEvent(task.ID, model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "task scheduled"); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	if err := repo.UpdateStatusWithEvent(task.ID, model.TaskStatusRunning, model.TaskStatusSucceeded, "scheduler", "task completed"); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	return task
+}
+
+func TestRelay_DeliversInOrderToAllSinks(t *testing.T) {
+	repo := setupTestRepo(t)
+	task := createCompletedTask(t, repo, "delivered")
+
+	var mu sync.Mutex
+	var received []*model.OutboxEvent
+	var eventIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event model.OutboxEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, &event)
+		eventIDs = append(eventIDs, r.Header.Get("X-Taskflow-Event-Id"))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	var bus []string
+	relay := NewRelay(repo, []Sink{
+		NewWebhookSink(srv.URL, nil),
+		NewFuncSink("bus", func(ctx context.Context, event *model.OutboxEvent) error {
+			bus = append(bus, event.ID)
+			return nil
+		}),
+	}, Options{})
+
+	if n := relay.relayOnce(context.Background()); n != 2 {
+		t.Fatalf("expected 2 events delivered, got %d", n)
+	}
+	if len(received) != 2 || len(bus) != 2 {
+		t.Fatalf("expected both sinks to receive 2 events, got webhook=%d bus=%d", len(received), len(bus))
+	}
+	if received[0].ToStatus != model.TaskStatusRunning || received[1].ToStatus != model.TaskStatusSucceeded {
+		t.Errorf("events out of order: %+v, %+v", received[0], received[1])
+	}
+	if received[0].TaskID != task.ID || received[0].EventType != model.OutboxEventTaskStatusChanged {
+		t.Errorf("unexpected event payload: %+v", received[0])
+	}
+	if eventIDs[0] != received[0].ID {
+		t.Errorf("expected event ID header %s, got %s", received[0].ID, eventIDs[0])
+	}
+
+	// 已投递的事件不会再次投递
+	if n := relay.relayOnce(context.Background()); n != 0 {
+		t.Errorf("expected no events on second pass, got %d", n)
+	}
+	if pending, _ := repo.CountPendingOutboxEvents(); pending != 0 {
+		t.Errorf("expected empty backlog, got %d", pending)
+	}
+}
+
+func TestRelay_RetriesFailedEventsWithBackoff(t *testing.T) {
+	repo := setupTestRepo(t)
+	createCompletedTask(t, repo, "flaky")
+
+	failing := true
+	var delivered []model.TaskStatus
+	relay := NewRelay(repo, []Sink{
+		NewFuncSink("flaky", func(ctx context.Context, event *model.OutboxEvent) error {
+			if failing {
+				return errors.New("sink unavailable")
+			}
+			delivered = append(delivered, event.ToStatus)
+			return nil
+		}),
+	}, Options{})
+
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+
+	// 第一条失败后，同一任务的后续事件本轮不投递，保持顺序
+	if n := relay.relayOnce(context.Background()); n != 0 {
+		t.Fatalf("expected no deliveries while sink is down, got %d", n)
+	}
+	events, _ := repo.ListDueOutboxEvents(now.Add(time.Hour), 10)
+	if len(events) != 2 || events[0].Attempts != 1 || events[0].LastError != "sink unavailable" || events[1].Attempts != 0 {
+		t.Fatalf("unexpected outbox state after failure: %+v", events)
+	}
+
+	// 退避期内不重试，也不越过失败的事件投递同一任务的后续事件
+	failing = false
+	if n := relay.relayOnce(context.Background()); n != 0 || len(delivered) != 0 {
+		t.Fatalf("expected nothing delivered during backoff, got %v", delivered)
+	}
+
+	// 退避到期后按顺序补投
+	later := now.Add(2 * time.Second)
+	relay.now = func() time.Time { return later }
+	if n := relay.relayOnce(context.Background()); n != 2 {
+		t.Fatalf("expected 2 deliveries after backoff, got %d", n)
+	}
+	if len(delivered) != 2 || delivered[0] != model.TaskStatusRunning || delivered[1] != model.TaskStatusSucceeded {
+		t.Errorf("expected in-order delivery, got %v", delivered)
+	}
+	if pending, _ := repo.CountPendingOutboxEvents(); pending != 0 {
+		t.Errorf("expected empty backlog, %d pending", pending)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{0: time.Second, 1: 2 * time.Second, 3: 8 * time.Second, 20: maxBackoff}
+	for attempts, want := range cases {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"taskflow/internal/config"
+	"taskflow/internal/model"
+)
+
+// Sink 事件接收端
+type Sink interface {
+	// Name 接收端名称，用于日志和指标
+	Name() string
+	// Publish 投递一条事件，返回 nil 表示接收端已确认
+	Publish(ctx context.Context, event *model.OutboxEvent) error
+}
+
+// NewSinks 按配置创建接收端。tasks 用于在 Kafka 消息中附带任务快照，
+// protoEncoder 在 Kafka 使用 proto 格式时必须提供
+func NewSinks(cfg config.OutboxConfig, tasks TaskLookup, protoEncoder Encoder) ([]Sink, error) {
+	var sinks []Sink
+	for _, u := range cfg.WebhookURLs {
+		sinks = append(sinks, NewWebhookSink(u, nil))
+	}
+
+	if kc := cfg.Kafka; len(kc.Brokers) > 0 {
+		encode := EncodeJSON
+		if kc.Format == KafkaFormatProto {
+			if protoEncoder == nil {
+				return nil, fmt.Errorf("kafka proto format requires a protobuf encoder")
+			}
+			encode = protoEncoder
+		}
+		sink, err := NewKafkaSink(KafkaOptions{
+			Brokers:  kc.Brokers,
+			Topic:    kc.Topic,
+			ClientID: kc.ClientID,
+			Format:   kc.Format,
+		}, tasks, encode)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("outbox relay enabled but no sinks configured")
+	}
+	return sinks, nil
+}
+
+// WebhookSink 以 JSON POST 投递事件，请求头 X-Taskflow-Event-Id 携带事件 ID 供接收方去重
+type WebhookSink struct {
+	url    string
+	name   string
+	client *http.Client
+}
+
+// NewWebhookSink 创建 webhook 接收端，client 为空时使用 10 秒超时的默认客户端
+func NewWebhookSink(rawURL string, client *http.Client) *WebhookSink {
+	if client == nil {
+		client = &http.Client{Timeout: time.Duration(config.DefaultNotifyTimeout) * time.Second}
+	}
+	// 名称只使用主机名，避免地址中的令牌出现在日志和指标中
+	name := "webhook"
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		name += ":" + u.Host
+	}
+	return &WebhookSink{url: rawURL, name: name, client: client}
+}
+
+// Name 接收端名称
+func (s *WebhookSink) Name() string {
+	return s.name
+}
+
+// Publish 投递事件，非 2xx 响应视为失败
+func (s *WebhookSink) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Taskflow-Event-Id", event.ID)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// FuncSink 进程内接收端，用于接入事件总线等
+type FuncSink struct {
+	name string
+	fn   func(ctx context.Context, event *model.OutboxEvent) error
+}
+
+// NewFuncSink 创建进程内接收端
+func NewFuncSink(name string, fn func(ctx context.Context, event *model.OutboxEvent) error) *FuncSink {
+	return &FuncSink{name: name, fn: fn}
+}
+
+// Name 接收端名称
+func (s *FuncSink) Name() string {
+	return s.name
+}
+
+// Publish 调用处理函数
+func (s *FuncSink) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	return s.fn(ctx, event)
+}
@@ -0,0 +1,258 @@
+// Package planner 在不执行任务的前提下模拟调度：按依赖关系、优先级和工作线程数推算
+// 每个任务的预计开始和结束时间、关键路径和总耗时
+package planner
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxTasks 单次模拟的任务数上限
+const MaxTasks = 10000
+
+// Task 待模拟的任务
+type Task struct {
+	ID           string
+	Priority     int // 数值越大越先调度，与 model.TaskPriority 一致
+	Dependencies []string
+	Duration     time.Duration
+}
+
+// Slot 一个任务的模拟结果
+type Slot struct {
+	ID       string
+	Order    int           // 开始顺序，从 1 开始
+	Start    time.Duration // 相对模拟开始的预计开始时间
+	End      time.Duration
+	Critical bool // 位于关键路径上
+}
+
+// Plan 模拟结果
+type Plan struct {
+	Slots                []Slot // 与输入顺序一致
+	CriticalPath         []string
+	CriticalPathDuration time.Duration // 不受工作线程数限制时的最短总耗时
+	TotalDuration        time.Duration // 按工作线程数调度的总耗时
+	Workers              int
+}
+
+// Simulate 按调度器的规则模拟执行：依赖全部完成的任务进入就绪队列，
+// 有空闲工作线程时按优先级（相同时先就绪、先提交的优先）开始执行
+func Simulate(tasks []Task, workers int) (*Plan, error) {
+	if workers < 1 {
+		return nil, errors.New("workers must be positive")
+	}
+	if len(tasks) > MaxTasks {
+		return nil, fmt.Errorf("too many tasks: %d > %d", len(tasks), MaxTasks)
+	}
+
+	index := make(map[string]int, len(tasks))
+	for i, t := range tasks {
+		if t.ID == "" {
+			return nil, fmt.Errorf("task %d has no id", i+1)
+		}
+		if t.Duration < 0 {
+			return nil, fmt.Errorf("task %s has negative duration", t.ID)
+		}
+		if _, dup := index[t.ID]; dup {
+			return nil, fmt.Errorf("duplicate task id %s", t.ID)
+		}
+		index[t.ID] = i
+	}
+
+	dependents := make([][]int, len(tasks))
+	waiting := make([]int, len(tasks))
+	for i, t := range tasks {
+		for _, dep := range t.Dependencies {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("task %s depends on unknown task %s", t.ID, dep)
+			}
+			dependents[j] = append(dependents[j], i)
+			waiting[i]++
+		}
+	}
+
+	order, err := topoOrder(tasks, dependents, waiting)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Slots: make([]Slot, len(tasks)), Workers: workers}
+	for i, t := range tasks {
+		plan.Slots[i].ID = t.ID
+	}
+	plan.CriticalPath, plan.CriticalPathDuration = criticalPath(tasks, index, order)
+	for _, id := range plan.CriticalPath {
+		plan.Slots[index[id]].Critical = true
+	}
+	plan.TotalDuration = listSchedule(tasks, dependents, waiting, workers, plan.Slots)
+	return plan, nil
+}
+
+// topoOrder 拓扑排序，存在循环依赖时返回错误
+func topoOrder(tasks []Task, dependents [][]int, waiting []int) ([]int, error) {
+	remaining := append([]int(nil), waiting...)
+	order := make([]int, 0, len(tasks))
+	for i := range tasks {
+		if remaining[i] == 0 {
+			order = append(order, i)
+		}
+	}
+	for k := 0; k < len(order); k++ {
+		for _, d := range dependents[order[k]] {
+			if remaining[d]--; remaining[d] == 0 {
+				order = append(order, d)
+			}
+		}
+	}
+	if len(order) != len(tasks) {
+		for i := range tasks {
+			if remaining[i] > 0 {
+				return nil, fmt.Errorf("circular dependency involving task %s", tasks[i].ID)
+			}
+		}
+	}
+	return order, nil
+}
+
+// criticalPath 按拓扑序求耗时最长的依赖链
+func criticalPath(tasks []Task, index map[string]int, order []int) ([]string, time.Duration) {
+	finish := make([]time.Duration, len(tasks))
+	prev := make([]int, len(tasks))
+	last := -1
+	for _, i := range order {
+		prev[i] = -1
+		var start time.Duration
+		for _, dep := range tasks[i].Dependencies {
+			if j := index[dep]; finish[j] > start || prev[i] == -1 && finish[j] == start {
+				start, prev[i] = finish[j], j
+			}
+		}
+		finish[i] = start + tasks[i].Duration
+		if last == -1 || finish[i] > finish[last] {
+			last = i
+		}
+	}
+	if last == -1 {
+		return nil, 0
+	}
+
+	var path []string
+	for i := last; i != -1; i = prev[i] {
+		path = append([]string{tasks[i].ID}, path...)
+	}
+	return path, finish[last]
+}
+
+// listSchedule 离散事件模拟，填充每个任务的开始顺序和时间，返回总耗时
+func listSchedule(tasks []Task, dependents [][]int, waiting []int, workers int, slots []Slot) time.Duration {
+	remaining := append([]int(nil), waiting...)
+	ready := &readyQueue{tasks: tasks}
+	for i := range tasks {
+		if remaining[i] == 0 {
+			heap.Push(ready, readyItem{index: i})
+		}
+	}
+
+	var (
+		now     time.Duration
+		running runningQueue
+		started int
+		seq     int
+	)
+	for started < len(tasks) || running.Len() > 0 {
+		for running.Len() < workers && ready.Len() > 0 {
+			item := heap.Pop(ready).(readyItem)
+			started++
+			slot := &slots[item.index]
+			slot.Order = started
+			slot.Start = now
+			slot.End = now + tasks[item.index].Duration
+			heap.Push(&running, runningItem{index: item.index, end: slot.End})
+		}
+		if running.Len() == 0 {
+			break
+		}
+
+		// 推进到最早结束的任务，同时结束的一并释放
+		now = running[0].end
+		for running.Len() > 0 && running[0].end == now {
+			done := heap.Pop(&running).(runningItem)
+			for _, d := range dependents[done.index] {
+				if remaining[d]--; remaining[d] == 0 {
+					seq++
+					heap.Push(ready, readyItem{index: d, readyAt: now, seq: seq})
+				}
+			}
+		}
+	}
+	return now
+}
+
+// readyItem 就绪任务
+type readyItem struct {
+	index   int
+	readyAt time.Duration
+	seq     int
+}
+
+// readyQueue 按优先级、就绪时间、提交顺序排序的就绪队列
+type readyQueue struct {
+	tasks []Task
+	items []readyItem
+}
+
+func (q *readyQueue) Len() int { return len(q.items) }
+
+func (q *readyQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if pa, pb := q.tasks[a.index].Priority, q.tasks[b.index].Priority; pa != pb {
+		return pa > pb
+	}
+	if a.readyAt != b.readyAt {
+		return a.readyAt < b.readyAt
+	}
+	return a.index < b.index
+}
+
+func (q *readyQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *readyQueue) Push(x interface{}) { q.items = append(q.items, x.(readyItem)) }
+
+func (q *readyQueue) Pop() interface{} {
+	item := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return item
+}
+
+// runningItem 执行中的任务
+type runningItem struct {
+	index int
+	end   time.Duration
+}
+
+// runningQueue 按结束时间排序
+type runningQueue []runningItem
+
+func (q runningQueue) Len() int { return len(q) }
+
+func (q runningQueue) Less(i, j int) bool {
+	if q[i].end != q[j].end {
+		return q[i].end < q[j].end
+	}
+	return q[i].index < q[j].index
+}
+
+func (q runningQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *runningQueue) Push(x interface{}) { *q = append(*q, x.(runningItem)) }
+
+func (q *runningQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,103 @@
+package planner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulate_DiamondWithLimitedWorkers(t *testing.T) {
+	// a -> b, c -> d；b 比 c 慢，关键路径为 a b d
+	tasks := []Task{
+		{ID: "a", Duration: 10 * time.Second},
+		{ID: "b", Duration: 30 * time.Second, Dependencies: []string{"a"}},
+		{ID: "c", Duration: 20 * time.Second, Dependencies: []string{"a"}},
+		{ID: "d", Duration: 5 * time.Second, Dependencies: []string{"b", "c"}},
+	}
+
+	plan, err := Simulate(tasks, 2)
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	if want := []string{"a", "b", "d"}; !reflect.DeepEqual(plan.CriticalPath, want) {
+		t.Errorf("critical path = %v, want %v", plan.CriticalPath, want)
+	}
+	if plan.CriticalPathDuration != 45*time.Second {
+		t.Errorf("critical path duration = %s, want 45s", plan.CriticalPathDuration)
+	}
+	if plan.TotalDuration != 45*time.Second {
+		t.Errorf("total duration = %s, want 45s", plan.TotalDuration)
+	}
+	wantStart := map[string]time.Duration{"a": 0, "b": 10 * time.Second, "c": 10 * time.Second, "d": 40 * time.Second}
+	for _, slot := range plan.Slots {
+		if slot.Start != wantStart[slot.ID] {
+			t.Errorf("%s starts at %s, want %s", slot.ID, slot.Start, wantStart[slot.ID])
+		}
+		if slot.Critical != (slot.ID != "c") {
+			t.Errorf("%s critical = %v", slot.ID, slot.Critical)
+		}
+	}
+
+	// 单个工作线程时 b、c 串行
+	plan, err = Simulate(tasks, 1)
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	if plan.TotalDuration != 65*time.Second {
+		t.Errorf("total duration with 1 worker = %s, want 65s", plan.TotalDuration)
+	}
+	if plan.CriticalPathDuration != 45*time.Second {
+		t.Errorf("critical path duration should not depend on workers, got %s", plan.CriticalPathDuration)
+	}
+}
+
+func TestSimulate_PriorityOrder(t *testing.T) {
+	tasks := []Task{
+		{ID: "low", Priority: 1, Duration: time.Second},
+		{ID: "urgent", Priority: 4, Duration: time.Second},
+		{ID: "normal", Priority: 2, Duration: time.Second},
+		{ID: "normal2", Priority: 2, Duration: time.Second},
+	}
+	plan, err := Simulate(tasks, 1)
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+
+	order := make([]string, len(tasks))
+	for _, slot := range plan.Slots {
+		order[slot.Order-1] = slot.ID
+	}
+	if want := []string{"urgent", "normal", "normal2", "low"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("start order = %v, want %v", order, want)
+	}
+	if plan.TotalDuration != 4*time.Second {
+		t.Errorf("total duration = %s, want 4s", plan.TotalDuration)
+	}
+}
+
+func TestSimulate_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks []Task
+		want  string
+	}{
+		{"empty id", []Task{{ID: ""}}, "no id"},
+		{"duplicate", []Task{{ID: "a"}, {ID: "a"}}, "duplicate"},
+		{"unknown dependency", []Task{{ID: "a", Dependencies: []string{"x"}}}, "unknown task x"},
+		{"cycle", []Task{{ID: "a", Dependencies: []string{"b"}}, {ID: "b", Dependencies: []string{"a"}}}, "circular"},
+		{"negative duration", []Task{{ID: "a", Duration: -time.Second}}, "negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Simulate(tt.tasks, 1)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Simulate([]Task{{ID: "a"}}, 0); err == nil {
+		t.Error("expected error for zero workers")
+	}
+}
@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryQueue 进程内队列，用于单节点部署和测试
+type MemoryQueue struct {
+	mu     sync.Mutex
+	items  []string
+	queued map[string]struct{}
+	notify chan struct{} // 有新任务时发送信号（容量 1）
+}
+
+// NewMemoryQueue 创建进程内队列
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		queued: make(map[string]struct{}),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Push 推入任务 ID
+func (q *MemoryQueue) Push(ctx context.Context, taskID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.queued[taskID]; ok {
+		return nil
+	}
+	q.queued[taskID] = struct{}{}
+	q.items = append(q.items, taskID)
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pop 弹出任务 ID
+func (q *MemoryQueue) Pop(ctx context.Context) (string, error) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			taskID := q.items[0]
+			q.items = q.items[1:]
+			delete(q.queued, taskID)
+			more := len(q.items) > 0
+			q.mu.Unlock()
+
+			// 还有剩余任务时把信号传给下一个消费者
+			if more {
+				select {
+				case q.notify <- struct{}{}:
+				default:
+				}
+			}
+			return taskID, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-q.notify:
+		}
+	}
+}
+
+// Len 队列长度
+func (q *MemoryQueue) Len(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items), nil
+}
+
+// Close 无需释放资源
+func (q *MemoryQueue) Close() error {
+	return nil
+}
@@ -0,0 +1,49 @@
+// Package queue 提供就绪任务队列的可插拔实现，调度器推入就绪任务 ID，各节点的消费者弹出后认领执行
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"taskflow/internal/config"
+)
+
+// 队列后端类型
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Queue 就绪任务队列。任务状态仍以仓储为准，队列只负责分发 ID，
+// 消费者弹出后须通过仓储的状态条件更新认领任务，重复投递是安全的
+type Queue interface {
+	// Push 推入就绪任务 ID，ID 已在队列中时不重复推入
+	Push(ctx context.Context, taskID string) error
+	// Pop 弹出一个任务 ID（先进先出），队列为空时阻塞直到 ctx 取消
+	Pop(ctx context.Context) (string, error)
+	// Len 队列中的任务数
+	Len(ctx context.Context) (int, error)
+	// Close 释放连接
+	Close() error
+}
+
+// New 按配置创建队列，backend 为空或 none 时返回 nil，表示由调度器直接轮询数据库
+func New(cfg config.QueueConfig) (Queue, error) {
+	switch cfg.Backend {
+	case "", "none":
+		return nil, nil
+	case BackendMemory:
+		return NewMemoryQueue(), nil
+	case BackendRedis:
+		return NewRedisQueue(RedisOptions{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+			Key:      "taskflow:ready:" + cfg.Name,
+			Timeout:  time.Duration(cfg.Timeout) * time.Second,
+		})
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", cfg.Backend)
+	}
+}
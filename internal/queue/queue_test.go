@@ -0,0 +1,245 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"taskflow/internal/config"
+	"taskflow/internal/redis"
+)
+
+func TestMemoryQueue_FIFOAndDedupe(t *testing.T) {
+	q := NewMemoryQueue()
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "a", "c"} {
+		if err := q.Push(ctx, id); err != nil {
+			t.Fatalf("push %s: %v", id, err)
+		}
+	}
+	if n, _ := q.Len(ctx); n != 3 {
+		t.Fatalf("expected 3 queued, got %d", n)
+	}
+
+	for _, want := range []string{"a", "b", "c"} {
+		got, err := q.Pop(ctx)
+		if err != nil || got != want {
+			t.Fatalf("expected %s, got %q (%v)", want, got, err)
+		}
+	}
+
+	// 弹出后可再次推入
+	q.Push(ctx, "a")
+	if got, _ := q.Pop(ctx); got != "a" {
+		t.Fatalf("expected a after re-push, got %q", got)
+	}
+}
+
+func TestMemoryQueue_PopBlocksUntilPushOrCancel(t *testing.T) {
+	q := NewMemoryQueue()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.Pop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	got := make(chan string, 1)
+	go func() {
+		id, _ := q.Pop(context.Background())
+		got <- id
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Push(context.Background(), "x")
+
+	select {
+	case id := <-got:
+		if id != "x" {
+			t.Fatalf("expected x, got %q", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pop did not wake after push")
+	}
+}
+
+func TestNew_Backends(t *testing.T) {
+	if q, err := New(config.QueueConfig{Backend: "none"}); err != nil || q != nil {
+		t.Fatalf("expected nil queue for none, got %v, %v", q, err)
+	}
+	if q, err := New(config.QueueConfig{Backend: BackendMemory}); err != nil || q == nil {
+		t.Fatalf("expected memory queue, got %v, %v", q, err)
+	}
+	if _, err := New(config.QueueConfig{Backend: BackendRedis}); err == nil {
+		t.Fatal("expected error for redis without address")
+	}
+	if _, err := New(config.QueueConfig{Backend: "kafka"}); err == nil {
+		t.Fatal("expected error for unknown backend")
+	}
+}
+
+func TestRedisQueue_PushPop(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	q, err := NewRedisQueue(RedisOptions{Addr: srv.addr, Password: "secret", DB: 2, Key: "taskflow:ready:test"})
+	if err != nil {
+		t.Fatalf("new redis queue: %v", err)
+	}
+	defer q.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "a"} {
+		if err := q.Push(ctx, id); err != nil {
+			t.Fatalf("push %s: %v", id, err)
+		}
+	}
+	if n, err := q.Len(ctx); err != nil || n != 2 {
+		t.Fatalf("expected 2 queued, got %d (%v)", n, err)
+	}
+	for _, want := range []string{"a", "b"} {
+		got, err := q.Pop(ctx)
+		if err != nil || got != want {
+			t.Fatalf("expected %s, got %q (%v)", want, got, err)
+		}
+	}
+
+	if got := srv.commands(); got["AUTH"] != 2 || got["SELECT"] != 2 {
+		t.Errorf("expected AUTH and SELECT on both connections, got %v", got)
+	}
+
+	// 空队列时 Pop 阻塞直到 ctx 取消
+	cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := q.Pop(cctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestRedisQueue_AuthError(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	q, _ := NewRedisQueue(RedisOptions{Addr: srv.addr, Password: "wrong", Key: "k"})
+	defer q.Close()
+
+	var redisErr redis.Error
+	if err := q.Push(context.Background(), "a"); !errors.As(err, &redisErr) {
+		t.Fatalf("expected redis error, got %v", err)
+	}
+}
+
+// fakeRedis 实现队列用到的 Redis 命令子集
+type fakeRedis struct {
+	addr     string
+	password string
+
+	mu   sync.Mutex
+	list []string // 头部为 LPUSH 端
+	seen map[string]int
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	srv := &fakeRedis{addr: ln.Addr().String(), password: password, seen: make(map[string]int)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+func (f *fakeRedis) commands() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]int, len(f.seen))
+	for k, v := range f.seen {
+		out[k] = v
+	}
+	return out
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := redis.ReadReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+		if _, err := conn.Write([]byte(f.handle(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) handle(args []string) string {
+	f.mu.Lock()
+	f.seen[args[0]]++
+	f.mu.Unlock()
+
+	switch args[0] {
+	case "AUTH":
+		if args[1] != f.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "EVAL":
+		id := args[4]
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, v := range f.list {
+			if v == id {
+				return ":0\r\n"
+			}
+		}
+		f.list = append([]string{id}, f.list...)
+		return fmt.Sprintf(":%d\r\n", len(f.list))
+	case "LLEN":
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return fmt.Sprintf(":%d\r\n", len(f.list))
+	case "BRPOP":
+		secs, _ := strconv.Atoi(args[2])
+		deadline := time.Now().Add(time.Duration(secs) * time.Second)
+		for {
+			f.mu.Lock()
+			if n := len(f.list); n > 0 {
+				v := f.list[n-1]
+				f.list = f.list[:n-1]
+				f.mu.Unlock()
+				return fmt.Sprintf("*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(v), v)
+			}
+			f.mu.Unlock()
+			if time.Now().After(deadline) {
+				return "*-1\r\n"
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
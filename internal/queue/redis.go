@@ -0,0 +1,105 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"taskflow/internal/redis"
+)
+
+// redisPopBlock BRPOP 单次阻塞时长（秒），到期后重新检查 ctx
+const redisPopBlock = 1
+
+// pushScript 仅在 ID 不在列表中时推入，保证 Push 幂等（LPOS 需要 Redis 6.0.6+）
+const pushScript = `if not redis.call('LPOS', KEYS[1], ARGV[1]) then return redis.call('LPUSH', KEYS[1], ARGV[1]) end return 0`
+
+// RedisOptions Redis 队列配置
+type RedisOptions struct {
+	Addr     string // host:port
+	Password string
+	DB       int
+	Key      string        // 就绪列表的 key
+	Timeout  time.Duration // 单条命令的网络超时，默认 5 秒
+}
+
+// RedisQueue 基于 Redis 列表的队列：LPUSH 推入、BRPOP 弹出。
+// Push 与 Pop 使用各自的连接，避免阻塞弹出占住推入
+type RedisQueue struct {
+	opts RedisOptions
+	push *redis.Conn
+	pop  *redis.Conn
+}
+
+// NewRedisQueue 创建 Redis 队列，连接在首次使用时建立
+func NewRedisQueue(opts RedisOptions) (*RedisQueue, error) {
+	if opts.Addr == "" {
+		return nil, fmt.Errorf("redis address is required")
+	}
+	if opts.Key == "" {
+		return nil, fmt.Errorf("redis queue key is required")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = redis.DefaultTimeout
+	}
+	conn := redis.Options{Addr: opts.Addr, Password: opts.Password, DB: opts.DB, Timeout: opts.Timeout}
+	return &RedisQueue{opts: opts, push: redis.NewConn(conn), pop: redis.NewConn(conn)}, nil
+}
+
+// Push 推入任务 ID
+func (q *RedisQueue) Push(ctx context.Context, taskID string) error {
+	_, err := q.push.Do(ctx, 0, "EVAL", pushScript, "1", q.opts.Key, taskID)
+	return err
+}
+
+// Pop 弹出任务 ID
+func (q *RedisQueue) Pop(ctx context.Context) (string, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+
+		reply, err := q.pop.Do(ctx, q.opts.Timeout+redisPopBlock*time.Second,
+			"BRPOP", q.opts.Key, strconv.Itoa(redisPopBlock))
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", err
+		}
+		if reply == nil {
+			continue // 超时无数据
+		}
+
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 2 {
+			return "", fmt.Errorf("redis: unexpected BRPOP reply %v", reply)
+		}
+		taskID, ok := items[1].(string)
+		if !ok {
+			return "", fmt.Errorf("redis: unexpected BRPOP value %v", items[1])
+		}
+		return taskID, nil
+	}
+}
+
+// Len 队列长度
+func (q *RedisQueue) Len(ctx context.Context) (int, error) {
+	reply, err := q.push.Do(ctx, 0, "LLEN", q.opts.Key)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected LLEN reply %v", reply)
+	}
+	return int(n), nil
+}
+
+// Close 关闭连接
+func (q *RedisQueue) Close() error {
+	q.push.Close()
+	q.pop.Close()
+	return nil
+}
@@ -0,0 +1,133 @@
+// Package queuetime 排队时长监控：定期检查尚未开始执行的 PENDING 任务，对排队超过所属任务类型上限的任务记录排队超时，
+// 并发出任务事件、发件箱事件、通知和指标，可选提高任务的优先级
+package queuetime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+	"taskflow/internal/notify"
+	"taskflow/internal/repository"
+)
+
+// Operator 排队超时事件的操作者
+const Operator = "queue-monitor"
+
+// Options 监控参数
+type Options struct {
+	CheckInterval time.Duration            // 检查间隔，默认 60 秒
+	BatchSize     int                      // 每个上限每轮最多检查的任务数，默认 500
+	MaxAge        time.Duration            // 排队时长上限，0 表示只检查 MaxAgeByType 中的任务类型
+	MaxAgeByType  map[string]time.Duration // 按任务类型（不区分大小写）覆盖 MaxAge，0 表示不检查该类型
+	EscalateTo    model.TaskPriority       // 排队超时后把任务自身的优先级提高到该优先级，TaskPriorityUnspecified 表示不提高
+	InstanceID    string                   // 记录在排队超时事件中的实例 ID
+	Clock         clock.Clock              // 计算排队时长和检查间隔的时钟，默认 clock.Real
+}
+
+// Monitor 排队时长监控。每个任务每次排队只记录一次：多个实例同时检查时由仓储的条件更新保证只有一个实例发出事件和通知
+type Monitor struct {
+	repo     repository.TaskStore
+	notifier *notify.Notifier
+	opts     Options
+
+	types []string // MaxAgeByType 中的任务类型（小写、排序），按 MaxAge 检查时排除
+}
+
+// NewMonitor 创建监控，notifier 可以为 nil
+func NewMonitor(repo repository.TaskStore, notifier *notify.Notifier, opts Options) *Monitor {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = 60 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+	byType := make(map[string]time.Duration, len(opts.MaxAgeByType))
+	for taskType, maxAge := range opts.MaxAgeByType {
+		byType[strings.ToLower(taskType)] = maxAge
+	}
+	opts.MaxAgeByType = byType
+	return &Monitor{repo: repo, notifier: notifier, opts: opts, types: slices.Sorted(maps.Keys(byType))}
+}
+
+// Run 定期检查，直到 ctx 取消
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := m.opts.Clock.NewTicker(m.opts.CheckInterval)
+	defer ticker.Stop()
+
+	logger.Infof("Queue time monitor started, checking every %s", m.opts.CheckInterval)
+	for {
+		m.Check()
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("Queue time monitor stopped")
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Check 检查一轮，返回本轮新记录的排队超时任务数
+func (m *Monitor) Check() int {
+	now := m.opts.Clock.Now()
+	exceeded := 0
+	for _, taskType := range m.types {
+		if maxAge := m.opts.MaxAgeByType[taskType]; maxAge > 0 {
+			exceeded += m.check(now, maxAge, []string{taskType}, nil)
+		}
+	}
+	if m.opts.MaxAge > 0 {
+		exceeded += m.check(now, m.opts.MaxAge, nil, m.types)
+	}
+	return exceeded
+}
+
+// check 记录排队超过 maxAge 的任务，taskTypes、excludeTypes 的含义同 ListQueueTimeCandidates
+func (m *Monitor) check(now time.Time, maxAge time.Duration, taskTypes, excludeTypes []string) int {
+	tasks, err := m.repo.ListQueueTimeCandidates(now.Add(-maxAge), taskTypes, excludeTypes, m.opts.BatchSize)
+	if err != nil {
+		logger.Errorf("Failed to list queue time candidates: %v", err)
+		return 0
+	}
+
+	exceeded := 0
+	for _, task := range tasks {
+		message := exceededMessage(task, now, maxAge, m.opts.EscalateTo)
+		if err := m.repo.MarkQueueTimeExceeded(task.ID, now, m.opts.EscalateTo, Operator, message, m.opts.InstanceID); err != nil {
+			if !errors.Is(err, repository.ErrStatusMismatch) {
+				logger.Errorf("Failed to record queue time of task %s: %v", task.ID, err)
+			}
+			continue
+		}
+		alertedAt := now
+		task.QueueAlertedAt = &alertedAt
+		escalated := task.EscalatePriority(m.opts.EscalateTo, now)
+		exceeded++
+
+		logger.Warnf("Task %s (%s) exceeded its max queue time: %s", task.ID, task.TaskType, message)
+		metrics.RecordQueueTimeExceeded(task.TaskType, escalated)
+		m.notifier.NotifyQueueTimeExceeded(task, now)
+	}
+	return exceeded
+}
+
+// exceededMessage 排队超时事件说明
+func exceededMessage(task *model.Task, now time.Time, maxAge time.Duration, escalateTo model.TaskPriority) string {
+	message := fmt.Sprintf("queued for %s, exceeding the max queue time %s", task.QueueTime(now).Round(time.Second), maxAge)
+	if own := task.OwnPriority(); own < escalateTo {
+		message += fmt.Sprintf("; priority escalated from %s to %s", own, escalateTo)
+	}
+	return message
+}
@@ -0,0 +1,82 @@
+package queuetime
+
+import (
+	"testing"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+func TestMonitor_Check(t *testing.T) {
+	repo, _ := repository.NewMemoryRepositories()
+	now := time.Now()
+
+	add := func(id, taskType string, priority model.TaskPriority) {
+		task := model.NewTask(id, "", priority, taskType, nil, nil, 0, "alice")
+		task.ID = id
+		task.CreatedAt = now
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	add("etl", "etl", model.TaskPriorityLow)
+	add("report", "report", model.TaskPriorityLow)
+	add("urgent", "report", model.TaskPriorityUrgent)
+	add("adhoc", "adhoc", model.TaskPriorityLow)
+
+	fake := clock.NewFake(now)
+	m := NewMonitor(repo, nil, Options{
+		MaxAge:       time.Hour,
+		MaxAgeByType: map[string]time.Duration{"ETL": 10 * time.Minute, "adhoc": 0},
+		EscalateTo:   model.TaskPriorityHigh,
+		InstanceID:   "inst-1",
+		Clock:        fake,
+	})
+
+	if n := m.Check(); n != 0 {
+		t.Fatalf("expected no tasks over their max queue time, got %d", n)
+	}
+
+	// 按任务类型覆盖的上限先到期，排队超时后提高优先级
+	fake.Advance(15 * time.Minute)
+	if n := m.Check(); n != 1 {
+		t.Fatalf("expected only the etl task to exceed its max queue time, got %d", n)
+	}
+	task, _ := repo.GetByID("etl")
+	if task.QueueAlertedAt == nil || task.Priority != model.TaskPriorityHigh {
+		t.Errorf("expected etl task alerted and escalated to HIGH, got %s alerted at %v", task.Priority, task.QueueAlertedAt)
+	}
+	last := task.Events[len(task.Events)-1]
+	if last.Operator != Operator || last.InstanceID != "inst-1" ||
+		last.Message != "queued for 15m0s, exceeding the max queue time 10m0s; priority escalated from LOW to HIGH" {
+		t.Errorf("unexpected queue time event: %+v", last)
+	}
+
+	// 其他类型按默认上限；上限为 0 的类型不检查；已不低于目标优先级的任务不改变优先级
+	fake.Advance(time.Hour)
+	if n := m.Check(); n != 2 {
+		t.Fatalf("expected the report tasks to exceed the default max queue time, got %d", n)
+	}
+	if task, _ := repo.GetByID("urgent"); task.Priority != model.TaskPriorityUrgent || task.QueueAlertedAt == nil {
+		t.Errorf("expected urgent task alerted without changing its priority, got %s alerted at %v", task.Priority, task.QueueAlertedAt)
+	}
+	if task, _ := repo.GetByID("adhoc"); task.QueueAlertedAt != nil {
+		t.Error("task type with a max queue time of 0 should not be checked")
+	}
+	if n := m.Check(); n != 0 {
+		t.Errorf("expected each task to be alerted once, got %d", n)
+	}
+
+	events, _ := repo.ListDueOutboxEvents(fake.Now().Add(time.Second), 20)
+	alerts := 0
+	for _, e := range events {
+		if e.EventType == model.OutboxEventTaskQueueTimeExceeded {
+			alerts++
+		}
+	}
+	if alerts != 3 {
+		t.Errorf("expected 3 queue time outbox events, got %d", alerts)
+	}
+}
@@ -0,0 +1,102 @@
+// Package redact 敏感字段脱敏：InputParams 中键名匹配配置模式的值在接口响应、事件和任务日志中被替换为掩码
+package redact
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Mask 替换敏感值的掩码
+const Mask = "[REDACTED]"
+
+// Redactor 按键名通配模式（不区分大小写，path.Match 语法）判断敏感参数。nil Redactor 不脱敏任何字段
+type Redactor struct {
+	patterns []string
+}
+
+// New 创建脱敏器，模式为空时返回 nil
+func New(patterns []string) (*Redactor, error) {
+	var compiled []string
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid sensitive key pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, p)
+	}
+	if len(compiled) == 0 {
+		return nil, nil
+	}
+	return &Redactor{patterns: compiled}, nil
+}
+
+// IsSensitive 键名是否匹配敏感模式
+func (r *Redactor) IsSensitive(key string) bool {
+	if r == nil {
+		return false
+	}
+	key = strings.ToLower(key)
+	for _, p := range r.patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Params 返回敏感值替换为掩码的参数副本，没有敏感键时原样返回 params
+func (r *Redactor) Params(params map[string]string) map[string]string {
+	if r == nil {
+		return params
+	}
+	var redacted map[string]string
+	for k := range params {
+		if !r.IsSensitive(k) {
+			continue
+		}
+		if redacted == nil {
+			redacted = make(map[string]string, len(params))
+			for k, v := range params {
+				redacted[k] = v
+			}
+		}
+		redacted[k] = Mask
+	}
+	if redacted == nil {
+		return params
+	}
+	return redacted
+}
+
+// SensitiveValues 返回 params 中敏感键的非空值，用于在自由文本（如日志）中掩盖
+func (r *Redactor) SensitiveValues(params map[string]string) []string {
+	var values []string
+	for k, v := range params {
+		if v != "" && r.IsSensitive(k) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Text 把文本中出现的 values 替换为掩码，较长的值优先替换
+func Text(text string, values []string) string {
+	if len(values) == 0 {
+		return text
+	}
+	sorted := append([]string(nil), values...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, v := range sorted {
+		if v != "" {
+			pairs = append(pairs, v, Mask)
+		}
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
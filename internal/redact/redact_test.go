@@ -0,0 +1,54 @@
+package redact
+
+import "testing"
+
+func TestRedactor_Params(t *testing.T) {
+	r, err := New([]string{"*password*", "API_KEY", " "})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	params := map[string]string{"db_password": "hunter2", "api_key": "k", "host": "db"}
+	got := r.Params(params)
+	if got["db_password"] != Mask || got["api_key"] != Mask || got["host"] != "db" {
+		t.Errorf("Params = %v", got)
+	}
+	if params["db_password"] != "hunter2" {
+		t.Error("input params modified")
+	}
+
+	// 没有敏感键时原样返回
+	plain := map[string]string{"host": "db"}
+	if got := r.Params(plain); len(got) != 1 || got["host"] != "db" {
+		t.Errorf("Params(plain) = %v", got)
+	}
+}
+
+func TestRedactor_Nil(t *testing.T) {
+	r, err := New(nil)
+	if err != nil || r != nil {
+		t.Fatalf("New(nil) = %v, %v", r, err)
+	}
+	if r.IsSensitive("password") {
+		t.Error("nil redactor should not mark keys sensitive")
+	}
+	if got := r.Params(map[string]string{"password": "x"}); got["password"] != "x" {
+		t.Errorf("nil redactor Params = %v", got)
+	}
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	if _, err := New([]string{"[abc"}); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestText(t *testing.T) {
+	got := Text("connecting with secret-long and secret", []string{"secret", "secret-long"})
+	if want := "connecting with " + Mask + " and " + Mask; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+	if got := Text("nothing", nil); got != "nothing" {
+		t.Errorf("Text = %q", got)
+	}
+}
@@ -0,0 +1,182 @@
+// Package redis 提供最小的 Redis 客户端：单条连接上按 RESP 协议收发命令，供就绪队列和任务缓存共用
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout 单条命令的默认网络超时
+const DefaultTimeout = 5 * time.Second
+
+// Options 连接配置
+type Options struct {
+	Addr     string // host:port
+	Password string
+	DB       int
+	Timeout  time.Duration // 单条命令的网络超时，默认 5 秒
+}
+
+// Error Redis 返回的错误回复，连接仍然可用
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// Conn 惰性建立、出错后重连的单条连接，命令串行执行
+type Conn struct {
+	opts Options
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewConn 创建连接，连接在首次执行命令时建立
+func NewConn(opts Options) *Conn {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	return &Conn{opts: opts}
+}
+
+// Do 执行一条命令，timeout <= 0 时使用连接的默认超时。网络错误时断开连接，下次调用重连
+func (c *Conn) Do(ctx context.Context, timeout time.Duration, args ...string) (interface{}, error) {
+	if timeout <= 0 {
+		timeout = c.opts.Timeout
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.roundTrip(timeout, args...)
+	var redisErr Error
+	if err != nil && !errors.As(err, &redisErr) {
+		c.reset()
+	}
+	return reply, err
+}
+
+// Close 断开连接
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reset()
+	return nil
+}
+
+// dial 建立连接并完成认证和选库
+func (c *Conn) dial(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: c.opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return fmt.Errorf("redis: dial %s: %w", c.opts.Addr, err)
+	}
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+
+	if c.opts.Password != "" {
+		if _, err := c.roundTrip(c.opts.Timeout, "AUTH", c.opts.Password); err != nil {
+			c.reset()
+			return err
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := c.roundTrip(c.opts.Timeout, "SELECT", strconv.Itoa(c.opts.DB)); err != nil {
+			c.reset()
+			return err
+		}
+	}
+	return nil
+}
+
+// roundTrip 写入命令并读取回复
+func (c *Conn) roundTrip(timeout time.Duration, args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return ReadReply(c.r)
+}
+
+// reset 断开连接
+func (c *Conn) reset() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = nil
+	c.r = nil
+}
+
+// ReadReply 读取一条 RESP 回复：简单字符串和批量字符串返回 string，
+// 整数返回 int64，数组返回 []interface{}，空批量字符串和空数组返回 nil
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	prefix, body := line[0], line[1:len(line)-2]
+
+	switch prefix {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = ReadReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", prefix)
+	}
+}
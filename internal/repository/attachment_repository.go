@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// attachmentColumns task_attachments 表查询列（顺序需与 scanAttachment 保持一致）
+const attachmentColumns = `id, task_id, filename, content_type, size, checksum, storage_key, uploaded_by, created_at`
+
+// AddAttachment 保存附件元数据
+func (r *TaskRepository) AddAttachment(a *model.TaskAttachment) error {
+	defer r.db.observe("tasks.AddAttachment", time.Now(), "task_id", a.TaskID)
+	query := `INSERT INTO task_attachments (` + attachmentColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.DB().Exec(query,
+		a.ID,
+		a.TaskID,
+		a.Filename,
+		a.ContentType,
+		a.Size,
+		a.Checksum,
+		a.StorageKey,
+		a.UploadedBy,
+		formatTime(a.CreatedAt),
+	)
+	return err
+}
+
+// GetAttachment 获取任务的单个附件，不存在时返回 nil
+func (r *TaskRepository) GetAttachment(taskID, id string) (*model.TaskAttachment, error) {
+	defer r.db.observe("tasks.GetAttachment", time.Now(), "task_id", taskID, "id", id)
+	query := `SELECT ` + attachmentColumns + ` FROM task_attachments WHERE id = ? AND task_id = ?`
+
+	a, err := scanAttachment(r.db.DB().QueryRow(query, id, taskID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// ListAttachments 按上传时间列出任务附件
+func (r *TaskRepository) ListAttachments(taskID string) ([]*model.TaskAttachment, error) {
+	defer r.db.observe("tasks.ListAttachments", time.Now(), "task_id", taskID)
+	query := `SELECT ` + attachmentColumns + ` FROM task_attachments WHERE task_id = ? ORDER BY created_at ASC, rowid ASC`
+
+	rows, err := r.db.DB().Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*model.TaskAttachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// DeleteAttachment 删除附件元数据
+func (r *TaskRepository) DeleteAttachment(taskID, id string) error {
+	defer r.db.observe("tasks.DeleteAttachment", time.Now(), "task_id", taskID, "id", id)
+	_, err := r.db.DB().Exec(`DELETE FROM task_attachments WHERE id = ? AND task_id = ?`, id, taskID)
+	return err
+}
+
+// scanAttachment 扫描一行附件记录
+func scanAttachment(row interface{ Scan(...interface{}) error }) (*model.TaskAttachment, error) {
+	var a model.TaskAttachment
+	var contentType, checksum, uploadedBy sql.NullString
+	var createdAt string
+
+	if err := row.Scan(&a.ID, &a.TaskID, &a.Filename, &contentType, &a.Size, &checksum, &a.StorageKey, &uploadedBy, &createdAt); err != nil {
+		return nil, err
+	}
+	a.ContentType = contentType.String
+	a.Checksum = checksum.String
+	a.UploadedBy = uploadedBy.String
+	a.CreatedAt = parseTimestamp(createdAt)
+	return &a, nil
+}
@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// BackupFormat 逻辑导出文件的格式标识
+const BackupFormat = "taskflow-backup/1"
+
+// sqliteFileHeader SQLite 数据库文件的前 16 个字节，用于区分文件快照和逻辑导出
+const sqliteFileHeader = "SQLite format 3\x00"
+
+var (
+	// ErrRestoreTargetExists 恢复只写入新的数据库，目标文件已存在时拒绝覆盖
+	ErrRestoreTargetExists = errors.New("restore target database already exists")
+	// ErrBackupSchemaTooNew 备份来自更新的版本，本版本不认识其表结构
+	ErrBackupSchemaTooNew = errors.New("backup schema version is newer than this build")
+)
+
+// BackupHeader 备份的元数据，逻辑导出的首行
+type BackupHeader struct {
+	Format        string    `json:"format"`         // BackupFormat，文件快照为 sqlite
+	Dialect       Dialect   `json:"dialect"`        // 导出数据库的方言
+	SchemaVersion int       `json:"schema_version"` // 导出时已执行的最高迁移版本
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// backupRecord 逻辑导出中的一行：每张表先输出一条只有 Columns 的记录声明列顺序，之后每行数据一条 Values 记录
+type backupRecord struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns,omitempty"`
+	Values  []any    `json:"values,omitempty"`
+}
+
+// Snapshot 把数据库的一致快照写入新文件 dest（VACUUM INTO），写入期间不阻塞其他连接的读写，dest 已存在时失败
+func (s *SQLite) Snapshot(dest string) error {
+	if _, err := s.db.Exec(`VACUUM INTO ?`, dest); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return nil
+}
+
+// Export 把全部业务表（任务、事件、评论、附件元数据、日志、运行记录、发件箱、团队、密钥、调度器实例和检查点等）
+// 按行导出为 JSON Lines。所有表在同一个读事务中读取，导出的是同一时刻的数据；加密的列原样导出，恢复的实例需使用相同的主密钥
+func (s *SQLite) Export(w io.Writer) (*BackupHeader, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	header := &BackupHeader{Format: BackupFormat, Dialect: DialectSQLite, CreatedAt: time.Now().UTC()}
+	var version sql.NullInt64
+	if err := tx.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	header.SchemaVersion = int(version.Int64)
+	tables, err := backupTables(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if err := exportTable(tx, enc, table); err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", table, err)
+		}
+	}
+	return header, buf.Flush()
+}
+
+// backupTables 需要备份的表：除 SQLite 内部表和迁移记录外的全部表
+func backupTables(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query(`SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// exportTable 导出一张表的列声明和全部行
+func exportTable(tx *sql.Tx, enc *json.Encoder, table string) error {
+	rows, err := tx.Query(`SELECT * FROM ` + quoteIdent(table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if err := enc.Encode(backupRecord{Table: table, Columns: columns}); err != nil {
+		return err
+	}
+
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if err := enc.Encode(backupRecord{Table: table, Values: values}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Restore 把文件快照或逻辑导出恢复为新的 SQLite 数据库 dbPath，返回备份的元数据。
+// 备份的表结构版本不能比本版本新；逻辑导出先写入迁移到备份版本的空库，再执行之后的迁移，
+// 使旧版本的备份也能经过数据迁移恢复。恢复先写入临时文件，成功后才改名为 dbPath
+func Restore(dbPath string, r io.Reader) (*BackupHeader, error) {
+	if info, err := os.Stat(dbPath); err == nil && info.Size() > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRestoreTargetExists, dbPath)
+	}
+	migrations, err := LoadMigrations(DialectSQLite)
+	if err != nil {
+		return nil, err
+	}
+	latest := migrations[len(migrations)-1].Version
+
+	tmpPath := dbPath + ".restore"
+	os.Remove(tmpPath)
+	in := bufio.NewReader(r)
+	var header *BackupHeader
+	if magic, _ := in.Peek(len(sqliteFileHeader)); bytes.Equal(magic, []byte(sqliteFileHeader)) {
+		header, err = restoreSnapshot(tmpPath, in, latest)
+	} else {
+		header, err = restoreExport(tmpPath, in, latest)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	return header, nil
+}
+
+// restoreSnapshot 把文件快照写入 path 并迁移到当前版本
+func restoreSnapshot(path string, r io.Reader, latest int) (*BackupHeader, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	db, err := NewSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	version, err := SchemaVersion(db.db)
+	if err != nil {
+		return nil, fmt.Errorf("not a taskflow database: %w", err)
+	}
+	if version > latest {
+		return nil, fmt.Errorf("%w: backup %d, supported %d", ErrBackupSchemaTooNew, version, latest)
+	}
+	if _, err := db.Migrate(); err != nil {
+		return nil, err
+	}
+	return &BackupHeader{Format: "sqlite", Dialect: DialectSQLite, SchemaVersion: version}, nil
+}
+
+// restoreExport 在 path 创建迁移到备份版本的空库，写入逻辑导出的全部行后迁移到当前版本
+func restoreExport(path string, r io.Reader, latest int) (*BackupHeader, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var header BackupHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("invalid backup header: %w", err)
+	}
+	switch {
+	case header.Format != BackupFormat:
+		return nil, fmt.Errorf("unsupported backup format %q", header.Format)
+	case header.Dialect != DialectSQLite:
+		return nil, fmt.Errorf("unsupported backup dialect %q", header.Dialect)
+	case header.SchemaVersion <= 0:
+		return nil, fmt.Errorf("invalid backup schema version %d", header.SchemaVersion)
+	case header.SchemaVersion > latest:
+		return nil, fmt.Errorf("%w: backup %d, supported %d", ErrBackupSchemaTooNew, header.SchemaVersion, latest)
+	}
+
+	db, err := NewSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if _, err := migrateTo(db.db, DialectSQLite, header.SchemaVersion); err != nil {
+		return nil, err
+	}
+	if err := db.ExecTx(func(tx *sql.Tx) error { return importRecords(tx, dec) }); err != nil {
+		return nil, err
+	}
+	if _, err := db.Migrate(); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// importRecords 按列声明逐行写入逻辑导出的数据，外键检查推迟到提交时
+func importRecords(tx *sql.Tx, dec *json.Decoder) error {
+	if _, err := tx.Exec(`PRAGMA defer_foreign_keys = ON`); err != nil {
+		return err
+	}
+	stmts := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}()
+
+	for line := 2; ; line++ {
+		var rec backupRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("backup line %d: %w", line, err)
+		}
+		if rec.Columns != nil {
+			if _, dup := stmts[rec.Table]; dup {
+				return fmt.Errorf("backup line %d: table %s declared twice", line, rec.Table)
+			}
+			stmt, err := prepareRestoreInsert(tx, rec.Table, rec.Columns)
+			if err != nil {
+				return fmt.Errorf("backup line %d: %w", line, err)
+			}
+			stmts[rec.Table] = stmt
+			continue
+		}
+		stmt, ok := stmts[rec.Table]
+		if !ok {
+			return fmt.Errorf("backup line %d: rows of %s before its columns", line, rec.Table)
+		}
+		for i, v := range rec.Values {
+			rec.Values[i] = restoreValue(v)
+		}
+		if _, err := stmt.Exec(rec.Values...); err != nil {
+			return fmt.Errorf("backup line %d: failed to restore %s: %w", line, rec.Table, err)
+		}
+	}
+}
+
+// prepareRestoreInsert 检查目标表为空后准备插入语句，表在备份的版本中应已由迁移建出
+func prepareRestoreInsert(tx *sql.Tx, table string, columns []string) (*sql.Stmt, error) {
+	var nonEmpty bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM ` + quoteIdent(table) + `)`).Scan(&nonEmpty); err != nil {
+		return nil, fmt.Errorf("table %s: %w", table, err)
+	}
+	if nonEmpty {
+		return nil, fmt.Errorf("table %s is not empty", table)
+	}
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return tx.Prepare(`INSERT INTO ` + quoteIdent(table) + ` (` + strings.Join(quoted, ", ") + `) VALUES (` + placeholders + `)`)
+}
+
+// restoreValue 把 JSON 解码出的数字还原为整数或浮点数
+func restoreValue(v any) any {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+// quoteIdent 引用 SQL 标识符
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+func TestBackupRestore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewTaskRepository(db)
+	teams := NewTeamRepository(db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, id := range []string{"a", "b"} {
+		if err := repo.Create(newStoreTask(id, model.TaskPriorityNormal, now)); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	if err := repo.UpdateStatusWithEvent("a", model.TaskStatusPending, model.TaskStatusCancelled, "alice", "cancel"); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+	if err := teams.Create(&model.Team{ID: "t1", Name: "ops", CreatedBy: "alice", CreatedAt: now}); err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+	if err := teams.AddMember("t1", "bob"); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	latestSeq, _ := repo.LatestEventSeq()
+
+	// verify 检查恢复的库与原库数据一致，事件序号在原库之后继续分配
+	verify := func(t *testing.T, path string) {
+		restored, err := NewSQLite(path)
+		if err != nil {
+			t.Fatalf("failed to open restored database: %v", err)
+		}
+		defer restored.Close()
+		got := NewTaskRepository(restored)
+		task, err := got.GetByID("a")
+		if err != nil || task.Status != model.TaskStatusCancelled || task.InputParams["cmd"] != "echo" {
+			t.Fatalf("unexpected restored task: %+v (%v)", task, err)
+		}
+		if events, _ := got.GetEventsByTaskID("a"); len(events) != 2 {
+			t.Errorf("expected 2 restored events, got %d", len(events))
+		}
+		if team, _ := NewTeamRepository(restored).GetByID("t1"); team == nil || len(team.Members) != 1 {
+			t.Errorf("expected the restored team with its member, got %+v", team)
+		}
+		if err := got.UpdateStatusWithEvent("b", model.TaskStatusPending, model.TaskStatusCancelled, "alice", "cancel"); err != nil {
+			t.Fatalf("failed to write restored database: %v", err)
+		}
+		if seq, _ := got.LatestEventSeq(); seq != latestSeq+1 {
+			t.Errorf("expected event seq to continue from %d, got %d", latestSeq, seq)
+		}
+	}
+
+	t.Run("export", func(t *testing.T) {
+		var buf bytes.Buffer
+		header, err := db.Export(&buf)
+		if err != nil {
+			t.Fatalf("Export: %v", err)
+		}
+		if header.Format != BackupFormat || header.SchemaVersion == 0 {
+			t.Fatalf("unexpected header: %+v", header)
+		}
+
+		path := filepath.Join(t.TempDir(), "restored.db")
+		restoredHeader, err := Restore(path, bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if restoredHeader.SchemaVersion != header.SchemaVersion {
+			t.Errorf("expected schema version %d, got %d", header.SchemaVersion, restoredHeader.SchemaVersion)
+		}
+		verify(t, path)
+
+		// 只恢复到新库
+		if _, err := Restore(path, bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrRestoreTargetExists) {
+			t.Errorf("expected ErrRestoreTargetExists, got %v", err)
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		snapshot := filepath.Join(t.TempDir(), "snapshot.db")
+		if err := db.Snapshot(snapshot); err != nil {
+			t.Fatalf("Snapshot: %v", err)
+		}
+		f, err := os.Open(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		path := filepath.Join(t.TempDir(), "restored.db")
+		header, err := Restore(path, f)
+		if err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if header.Format != "sqlite" {
+			t.Errorf("expected a snapshot header, got %+v", header)
+		}
+		verify(t, path)
+	})
+
+	t.Run("newer schema", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := db.Export(&buf); err != nil {
+			t.Fatalf("Export: %v", err)
+		}
+		first, rest, _ := strings.Cut(buf.String(), "\n")
+		var header BackupHeader
+		json.Unmarshal([]byte(first), &header)
+		header.SchemaVersion = 9999
+		data, _ := json.Marshal(header)
+
+		path := filepath.Join(t.TempDir(), "restored.db")
+		if _, err := Restore(path, strings.NewReader(string(data)+"\n"+rest)); !errors.Is(err, ErrBackupSchemaTooNew) {
+			t.Fatalf("expected ErrBackupSchemaTooNew, got %v", err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected no database after a failed restore")
+		}
+	})
+}
+
+// TestRestore_OlderSchema 旧版本的备份恢复后经过之后的数据迁移
+func TestRestore_OlderSchema(t *testing.T) {
+	old, err := NewSQLite(filepath.Join(t.TempDir(), "old.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	if _, err := migrateTo(old.DB(), DialectSQLite, 16); err != nil {
+		t.Fatalf("failed to migrate to version 16: %v", err)
+	}
+	// 0017 之前按本地时区写入的时间
+	if _, err := old.DB().Exec(`INSERT INTO tasks (id, name, description, status, priority, task_type, input_params,
+		output_result, dependencies, error_message, created_at, updated_at, created_by)
+		VALUES ('legacy', 'legacy task', '', 1, 1, 'shell', '{}', '{}', '[]', '',
+		'2026-01-02T10:00:00+08:00', '2026-01-02T10:00:00+08:00', 'alice')`); err != nil {
+		t.Fatalf("failed to insert legacy task: %v", err)
+	}
+	var buf bytes.Buffer
+	if header, err := old.Export(&buf); err != nil || header.SchemaVersion != 16 {
+		t.Fatalf("Export: %+v (%v)", header, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "restored.db")
+	if _, err := Restore(path, &buf); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	restored, err := NewSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	task, err := NewTaskRepository(restored).GetByID("legacy")
+	if err != nil {
+		t.Fatalf("failed to read restored task: %v", err)
+	}
+	if want := time.Date(2026, 1, 2, 2, 0, 0, 0, time.UTC); !task.CreatedAt.Equal(want) {
+		t.Errorf("expected created_at migrated to %s, got %s", want, task.CreatedAt)
+	}
+}
@@ -0,0 +1,449 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+)
+
+// cacheEventBatch FollowEvents 每次读取的事件数
+const cacheEventBatch = 500
+
+// TaskCache GetByID 读穿缓存的存储后端，由进程内 LRU（LRUTaskCache）和 Redis（RedisTaskCache）实现。
+// Set 传入的任务归缓存所有，Get 返回的任务调用方不得修改
+type TaskCache interface {
+	Get(id string) (*model.Task, bool)
+	Set(task *model.Task)
+	Delete(id string)
+	// Purge 删除全部缓存的任务，用于影响大量任务的写入（如压缩事件）
+	Purge()
+}
+
+// CachedTaskStore 为 GetByID 加读穿缓存的任务仓储，其余查询直接转发。
+// 经由它写入任务（状态、字段、事件、评论）后立即失效对应缓存；
+// 其他实例的写入由 FollowEvents 按任务事件失效，字段修改没有事件，最迟在缓存过期后可见
+type CachedTaskStore struct {
+	TaskStore
+	cache TaskCache
+
+	mu      sync.Mutex
+	pending map[string]*pendingLoad
+}
+
+// pendingLoad 正在从仓储读取的任务，读取期间被失效时不写入缓存，避免旧值覆盖失效
+type pendingLoad struct {
+	loaders int
+	stale   bool
+}
+
+// NewCachedTaskStore 用 cache 缓存 store 的 GetByID 结果
+func NewCachedTaskStore(store TaskStore, cache TaskCache) *CachedTaskStore {
+	return &CachedTaskStore{TaskStore: store, cache: cache, pending: make(map[string]*pendingLoad)}
+}
+
+var _ TaskStore = (*CachedTaskStore)(nil)
+
+// GetByID 先查缓存，未命中时读取仓储并写入缓存
+func (s *CachedTaskStore) GetByID(id string) (*model.Task, error) {
+	if task, ok := s.cache.Get(id); ok {
+		metrics.RecordTaskCacheLookup("hit")
+		return cloneCachedTask(task), nil
+	}
+	metrics.RecordTaskCacheLookup("miss")
+
+	load := s.beginLoad(id)
+	task, err := s.TaskStore.GetByID(id)
+	stored := false
+	if err == nil && !s.isStale(load) {
+		s.cache.Set(cloneCachedTask(task))
+		stored = true
+	}
+	// 写入缓存前后被失效的，删除刚写入的值
+	if s.endLoad(id, load) && stored {
+		s.cache.Delete(id)
+	}
+	return task, err
+}
+
+// Invalidate 失效任务的缓存
+func (s *CachedTaskStore) Invalidate(id string) {
+	s.mu.Lock()
+	if load := s.pending[id]; load != nil {
+		load.stale = true
+	}
+	s.mu.Unlock()
+
+	s.cache.Delete(id)
+	metrics.RecordTaskCacheInvalidation()
+}
+
+// Purge 失效全部任务的缓存
+func (s *CachedTaskStore) Purge() {
+	s.mu.Lock()
+	for _, load := range s.pending {
+		load.stale = true
+	}
+	s.mu.Unlock()
+
+	s.cache.Purge()
+	metrics.RecordTaskCacheInvalidation()
+}
+
+// Stale 底层仓储读写分离时返回容忍 maxStaleness 延迟的读取视图，重查询分到副本，GetByID 和写入仍经过缓存
+func (s *CachedTaskStore) Stale(maxStaleness time.Duration) TaskStore {
+	view, ok := StaleReads(s.TaskStore, maxStaleness).(*staleTaskStore)
+	if !ok {
+		return s
+	}
+	return &staleTaskStore{TaskStore: s, replicated: view.replicated, maxStaleness: maxStaleness}
+}
+
+// FollowEvents 按 interval 读取任务事件并失效涉及的任务，直到 ctx 取消，
+// 用于多个实例共用数据库而各自使用进程内缓存时感知其他实例的状态变更
+func (s *CachedTaskStore) FollowEvents(ctx context.Context, interval time.Duration) {
+	last, err := s.LatestEventSeq()
+	if err != nil {
+		logger.Errorf("Task cache failed to read latest event seq: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			events, err := s.ListEventsAfter(last, cacheEventBatch)
+			if err != nil {
+				logger.Errorf("Task cache failed to read events after %d: %v", last, err)
+				break
+			}
+			for _, e := range events {
+				s.Invalidate(e.TaskID)
+				last = e.Seq
+			}
+			if len(events) < cacheEventBatch {
+				break
+			}
+		}
+	}
+}
+
+// beginLoad 登记一次仓储读取
+func (s *CachedTaskStore) beginLoad(id string) *pendingLoad {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	load := s.pending[id]
+	if load == nil {
+		load = &pendingLoad{}
+		s.pending[id] = load
+	}
+	load.loaders++
+	return load
+}
+
+// isStale 读取期间任务是否被失效
+func (s *CachedTaskStore) isStale(load *pendingLoad) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return load.stale
+}
+
+// endLoad 结束一次仓储读取，返回读取期间任务是否被失效
+func (s *CachedTaskStore) endLoad(id string, load *pendingLoad) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	load.loaders--
+	if load.loaders == 0 {
+		delete(s.pending, id)
+	}
+	return load.stale
+}
+
+// Create 创建任务
+func (s *CachedTaskStore) Create(task *model.Task) error {
+	defer s.Invalidate(task.ID)
+	return s.TaskStore.Create(task)
+}
+
+// Update 更新任务
+func (s *CachedTaskStore) Update(task *model.Task) error {
+	defer s.Invalidate(task.ID)
+	return s.TaskStore.Update(task)
+}
+
+// UpdateDefinition 更新任务定义
+func (s *CachedTaskStore) UpdateDefinition(task *model.Task, expectedStatus model.TaskStatus) error {
+	defer s.Invalidate(task.ID)
+	return s.TaskStore.UpdateDefinition(task, expectedStatus)
+}
+
+// Delete 删除任务，同时失效被移除依赖的下游任务
+func (s *CachedTaskStore) Delete(id string, force bool, operator, correlationID string) (*DeletedTask, error) {
+	defer s.Invalidate(id)
+	deleted, err := s.TaskStore.Delete(id, force, operator, correlationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, dependent := range deleted.Dependents {
+		s.Invalidate(dependent)
+	}
+	return deleted, nil
+}
+
+// RepairDependencies 修复待处理任务的依赖
+func (s *CachedTaskStore) RepairDependencies(taskID string, repairs []model.DependencyRepair, operator, reason, correlationID string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.RepairDependencies(taskID, repairs, operator, reason, correlationID)
+}
+
+// AddEvent 添加事件
+func (s *CachedTaskStore) AddEvent(event *model.TaskEvent) error {
+	defer s.Invalidate(event.TaskID)
+	return s.TaskStore.AddEvent(event)
+}
+
+// UpdateStatus 条件更新状态
+func (s *CachedTaskStore) UpdateStatus(id string, fromStatus, toStatus model.TaskStatus) error {
+	defer s.Invalidate(id)
+	return s.TaskStore.UpdateStatus(id, fromStatus, toStatus)
+}
+
+// UpdateStatusWithEvent 条件更新状态并记录事件
+func (s *CachedTaskStore) UpdateStatusWithEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.UpdateStatusWithEvent(taskID, fromStatus, toStatus, operator, message)
+}
+
+// UpdateStatusWithCorrelatedEvent 条件更新状态并记录带请求 ID 的事件
+func (s *CachedTaskStore) UpdateStatusWithCorrelatedEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, correlationID string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.UpdateStatusWithCorrelatedEvent(taskID, fromStatus, toStatus, operator, message, correlationID)
+}
+
+// UpdateStatusWithInstanceEvent 条件更新状态并记录带调度器实例 ID 的事件
+func (s *CachedTaskStore) UpdateStatusWithInstanceEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID string, meta map[string]string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.UpdateStatusWithInstanceEvent(taskID, fromStatus, toStatus, operator, message, instanceID, meta)
+}
+
+// ScheduleRetry 安排重试
+func (s *CachedTaskStore) ScheduleRetry(taskID string, fromStatus model.TaskStatus, retryCount int32, nextRunAt *time.Time, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.ScheduleRetry(taskID, fromStatus, retryCount, nextRunAt, errMsg, errClass, operator, message, instanceID, meta)
+}
+
+// FailTask 标记任务失败
+func (s *CachedTaskStore) FailTask(taskID, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.FailTask(taskID, errMsg, errClass, operator, message, instanceID, meta)
+}
+
+// CompleteTask 标记任务成功并写入输出
+func (s *CachedTaskStore) CompleteTask(taskID string, fromStatus model.TaskStatus, output map[string]string, outputRef, operator, message, instanceID string, meta map[string]string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.CompleteTask(taskID, fromStatus, output, outputRef, operator, message, instanceID, meta)
+}
+
+// ClaimExclusive 互斥认领任务
+func (s *CachedTaskStore) ClaimExclusive(taskID string, excl Exclusion, operator, message, instanceID string, meta map[string]string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.ClaimExclusive(taskID, excl, operator, message, instanceID, meta)
+}
+
+// SetBlockedReason 记录任务暂不能调度的原因
+func (s *CachedTaskStore) SetBlockedReason(taskID, reason string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.SetBlockedReason(taskID, reason)
+}
+
+// UpdateProgress 记录执行进度
+func (s *CachedTaskStore) UpdateProgress(taskID string, progress int32, message string, at time.Time) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.UpdateProgress(taskID, progress, message, at)
+}
+
+// UpdateSubStatus 记录执行器上报的自定义阶段
+func (s *CachedTaskStore) UpdateSubStatus(taskID, subStatus, message string, at time.Time, operator, instanceID string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.UpdateSubStatus(taskID, subStatus, message, at, operator, instanceID)
+}
+
+// Heartbeat 记录执行器心跳
+func (s *CachedTaskStore) Heartbeat(taskID string, at time.Time) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.Heartbeat(taskID, at)
+}
+
+// SuspendForInput 挂起任务等待外部输入
+func (s *CachedTaskStore) SuspendForInput(taskID, prompt, operator, message, instanceID string, meta map[string]string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.SuspendForInput(taskID, prompt, operator, message, instanceID, meta)
+}
+
+// SignalTask 向等待输入的任务送达输入
+func (s *CachedTaskStore) SignalTask(taskID string, payload map[string]string, operator, message, correlationID string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.SignalTask(taskID, payload, operator, message, correlationID)
+}
+
+// RerunTask 原地重新运行已结束的任务
+func (s *CachedTaskStore) RerunTask(taskID string, fromStatus model.TaskStatus, operator, message, correlationID string) (int32, error) {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.RerunTask(taskID, fromStatus, operator, message, correlationID)
+}
+
+// BoostPriority 临时提升任务的优先级
+func (s *CachedTaskStore) BoostPriority(taskID string, priority model.TaskPriority, operator, message, instanceID string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.BoostPriority(taskID, priority, operator, message, instanceID)
+}
+
+// RestorePriority 恢复任务被提升前的优先级
+func (s *CachedTaskStore) RestorePriority(taskID, operator, message, instanceID string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.RestorePriority(taskID, operator, message, instanceID)
+}
+
+// MarkQueueTimeExceeded 记录排队超时
+func (s *CachedTaskStore) MarkQueueTimeExceeded(taskID string, at time.Time, escalateTo model.TaskPriority, operator, message, instanceID string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.MarkQueueTimeExceeded(taskID, at, escalateTo, operator, message, instanceID)
+}
+
+// MarkSLABreached 记录 SLA 违约
+func (s *CachedTaskStore) MarkSLABreached(taskID string, at time.Time, operator, message, instanceID string) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.MarkSLABreached(taskID, at, operator, message, instanceID)
+}
+
+// SetCompletedAt 补记完成时间
+func (s *CachedTaskStore) SetCompletedAt(taskID string, status model.TaskStatus, at time.Time) error {
+	defer s.Invalidate(taskID)
+	return s.TaskStore.SetCompletedAt(taskID, status, at)
+}
+
+// CompactEvents 压缩任务事件。缓存的任务带有事件且无法得知涉及哪些任务，删除了事件时失效全部缓存
+func (s *CachedTaskStore) CompactEvents(before time.Time, keepLatest int) (int64, error) {
+	n, err := s.TaskStore.CompactEvents(before, keepLatest)
+	if n > 0 {
+		s.Purge()
+	}
+	return n, err
+}
+
+// AddComment 添加评论
+func (s *CachedTaskStore) AddComment(comment *model.TaskComment) error {
+	defer s.Invalidate(comment.TaskID)
+	return s.TaskStore.AddComment(comment)
+}
+
+// cloneCachedTask 复制任务及其事件和评论
+func cloneCachedTask(t *model.Task) *model.Task {
+	c := cloneTask(t)
+	c.Events = append([]model.TaskEvent(nil), t.Events...)
+	c.Comments = append([]model.TaskComment(nil), t.Comments...)
+	return c
+}
+
+// LRUTaskCache 进程内 LRU 任务缓存，超过容量时淘汰最久未访问的任务，条目在 ttl 后过期
+type LRUTaskCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // 头部为最近访问
+	entries map[string]*list.Element
+}
+
+// lruEntry LRU 缓存条目
+type lruEntry struct {
+	task      *model.Task
+	expiresAt time.Time
+}
+
+// NewLRUTaskCache 创建进程内缓存，size 为最多缓存的任务数，ttl <= 0 表示不过期
+func NewLRUTaskCache(size int, ttl time.Duration) *LRUTaskCache {
+	return &LRUTaskCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get 查询缓存，过期条目视为未命中
+func (c *LRUTaskCache) Get(id string) (*model.Task, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*lruEntry)
+	if c.ttl > 0 && !c.now().Before(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, id)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.task, true
+}
+
+// Set 写入缓存，超过容量时淘汰最久未访问的任务
+func (c *LRUTaskCache) Set(task *model.Task) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{task: task, expiresAt: c.now().Add(c.ttl)}
+	if el, ok := c.entries[task.ID]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[task.ID] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).task.ID)
+		metrics.RecordTaskCacheEviction()
+	}
+}
+
+// Delete 删除缓存
+func (c *LRUTaskCache) Delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[id]; ok {
+		c.order.Remove(el)
+		delete(c.entries, id)
+	}
+}
+
+// Purge 删除全部缓存
+func (c *LRUTaskCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.entries)
+}
+
+// Len 缓存的任务数
+func (c *LRUTaskCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
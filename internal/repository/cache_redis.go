@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+	"taskflow/internal/redis"
+)
+
+// RedisTaskCache 多实例共享的 Redis 任务缓存，任务以 JSON 保存在 prefix+ID 下。
+// 各实例写入任务时删除共享的缓存，Redis 不可用时按未命中处理，缓存不影响读写结果
+type RedisTaskCache struct {
+	conn   *redis.Conn
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisTaskCache 创建 Redis 缓存，ttl <= 0 表示不过期
+func NewRedisTaskCache(opts redis.Options, prefix string, ttl time.Duration) *RedisTaskCache {
+	return &RedisTaskCache{conn: redis.NewConn(opts), prefix: prefix, ttl: ttl}
+}
+
+// Get 查询缓存
+func (c *RedisTaskCache) Get(id string) (*model.Task, bool) {
+	reply, err := c.conn.Do(context.Background(), 0, "GET", c.prefix+id)
+	if err != nil {
+		metrics.RecordTaskCacheLookup("error")
+		logger.Warnf("Task cache GET %s failed: %v", id, err)
+		return nil, false
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, false
+	}
+	var task model.Task
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		logger.Warnf("Task cache entry %s is corrupt: %v", id, err)
+		return nil, false
+	}
+	return &task, true
+}
+
+// Set 写入缓存
+func (c *RedisTaskCache) Set(task *model.Task) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return
+	}
+	args := []string{"SET", c.prefix + task.ID, string(data)}
+	if c.ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(c.ttl.Milliseconds(), 10))
+	}
+	if _, err := c.conn.Do(context.Background(), 0, args...); err != nil {
+		logger.Warnf("Task cache SET %s failed: %v", task.ID, err)
+	}
+}
+
+// Delete 删除缓存。删除失败时其他实例最迟在缓存过期后读到新值
+func (c *RedisTaskCache) Delete(id string) {
+	if _, err := c.conn.Do(context.Background(), 0, "DEL", c.prefix+id); err != nil {
+		logger.Warnf("Task cache DEL %s failed: %v", id, err)
+	}
+}
+
+// Purge 按前缀扫描并删除全部缓存的任务，失败时其他实例最迟在缓存过期后读到新值
+func (c *RedisTaskCache) Purge() {
+	match := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(c.prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := c.conn.Do(context.Background(), 0, "SCAN", cursor, "MATCH", match, "COUNT", strconv.Itoa(cacheEventBatch))
+		if err != nil {
+			logger.Warnf("Task cache SCAN %s failed: %v", match, err)
+			return
+		}
+		page, _ := reply.([]interface{})
+		if len(page) != 2 {
+			logger.Warnf("Task cache SCAN %s returned an unexpected reply", match)
+			return
+		}
+		cursor, _ = page[0].(string)
+		keys, _ := page[1].([]interface{})
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, key := range keys {
+				if k, ok := key.(string); ok {
+					args = append(args, k)
+				}
+			}
+			if _, err := c.conn.Do(context.Background(), 0, args...); err != nil {
+				logger.Warnf("Task cache DEL during purge failed: %v", err)
+				return
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			return
+		}
+	}
+}
+
+// Close 断开连接
+func (c *RedisTaskCache) Close() error {
+	return c.conn.Close()
+}
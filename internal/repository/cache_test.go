@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/redis"
+)
+
+// countingStore 统计 GetByID 的调用次数，onGet 在读取仓储前调用
+type countingStore struct {
+	TaskStore
+	mu    sync.Mutex
+	gets  int
+	onGet func(id string)
+}
+
+func (s *countingStore) GetByID(id string) (*model.Task, error) {
+	s.mu.Lock()
+	s.gets++
+	onGet := s.onGet
+	s.mu.Unlock()
+	if onGet != nil {
+		onGet(id)
+	}
+	return s.TaskStore.GetByID(id)
+}
+
+func (s *countingStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gets
+}
+
+func TestCachedTaskStore_ReadThroughAndInvalidate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, _ TeamStore) {
+		backing := &countingStore{TaskStore: tasks}
+		store := NewCachedTaskStore(backing, NewLRUTaskCache(10, time.Minute))
+
+		if err := store.Create(newStoreTask("t1", model.TaskPriorityNormal, time.Now())); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		first, err := store.GetByID("t1")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		// 修改返回值不影响缓存
+		first.Name = "changed"
+		first.InputParams["cmd"] = "rm"
+
+		second, err := store.GetByID("t1")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if backing.count() != 1 {
+			t.Fatalf("expected 1 store read, got %d", backing.count())
+		}
+		if second.Name != "task t1" || second.InputParams["cmd"] != "echo" || len(second.Events) != 1 {
+			t.Fatalf("cached task was mutated: %+v", second)
+		}
+
+		if err := store.UpdateStatusWithEvent("t1", model.TaskStatusPending, model.TaskStatusRunning, "alice", "start"); err != nil {
+			t.Fatalf("update status: %v", err)
+		}
+		third, err := store.GetByID("t1")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if backing.count() != 2 || third.Status != model.TaskStatusRunning || len(third.Events) != 2 {
+			t.Fatalf("expected reload after write, reads=%d task=%+v", backing.count(), third)
+		}
+
+		if _, err := store.Delete("t1", false, "alice", ""); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := store.GetByID("t1"); err != ErrTaskNotFound {
+			t.Fatalf("expected ErrTaskNotFound after delete, got %v", err)
+		}
+	})
+}
+
+func TestCachedTaskStore_ForcedDeleteInvalidatesDependents(t *testing.T) {
+	tasks, _ := NewMemoryRepositories()
+	store := NewCachedTaskStore(tasks, NewLRUTaskCache(10, time.Minute))
+	upstream := newStoreTask("upstream", model.TaskPriorityNormal, time.Now())
+	downstream := newStoreTask("downstream", model.TaskPriorityNormal, time.Now())
+	downstream.Dependencies = []string{"upstream"}
+	for _, task := range []*model.Task{upstream, downstream} {
+		if err := store.Create(task); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	if cached, _ := store.GetByID("downstream"); len(cached.Dependencies) != 1 {
+		t.Fatalf("expected the dependency cached, got %v", cached.Dependencies)
+	}
+
+	if _, err := store.Delete("upstream", true, "alice", ""); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got, _ := store.GetByID("downstream"); len(got.Dependencies) != 0 {
+		t.Errorf("expected the removed dependency reloaded, got %v", got.Dependencies)
+	}
+}
+
+func TestCachedTaskStore_CompactEventsPurgesCache(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, _ TeamStore) {
+		store := NewCachedTaskStore(tasks, NewLRUTaskCache(10, time.Minute))
+		task := newStoreTask("t1", model.TaskPriorityNormal, time.Now())
+		if err := store.Create(task); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if err := store.UpdateStatusWithEvent("t1", model.TaskStatusPending, model.TaskStatusRunning, "alice", "start"); err != nil {
+			t.Fatalf("update status: %v", err)
+		}
+		if cached, _ := store.GetByID("t1"); len(cached.Events) != 2 {
+			t.Fatalf("expected 2 cached events, got %d", len(cached.Events))
+		}
+
+		n, err := store.CompactEvents(time.Now().Add(time.Hour), 1)
+		if err != nil || n != 1 {
+			t.Fatalf("CompactEvents = %d, %v", n, err)
+		}
+		if got, _ := store.GetByID("t1"); len(got.Events) != 1 || got.Events[0].Message != "start" {
+			t.Errorf("expected compacted events reloaded, got %+v", got.Events)
+		}
+	})
+}
+
+func TestCachedTaskStore_InvalidatedLoadNotCached(t *testing.T) {
+	tasks, _ := NewMemoryRepositories()
+	backing := &countingStore{TaskStore: tasks}
+	store := NewCachedTaskStore(backing, NewLRUTaskCache(10, time.Minute))
+	if err := tasks.Create(newStoreTask("t1", model.TaskPriorityNormal, time.Now())); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// 读取仓储期间任务被修改，读到的旧值不应进入缓存
+	backing.onGet = func(id string) { store.Invalidate(id) }
+	if _, err := store.GetByID("t1"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	backing.onGet = nil
+	if _, err := store.GetByID("t1"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if _, err := store.GetByID("t1"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if backing.count() != 2 {
+		t.Fatalf("expected the invalidated load to be skipped, got %d store reads", backing.count())
+	}
+}
+
+func TestCachedTaskStore_FollowEvents(t *testing.T) {
+	tasks, _ := NewMemoryRepositories()
+	// 两个实例共用仓储，各自使用进程内缓存
+	a := NewCachedTaskStore(tasks, NewLRUTaskCache(10, 0))
+	b := NewCachedTaskStore(tasks, NewLRUTaskCache(10, 0))
+	if err := a.Create(newStoreTask("t1", model.TaskPriorityNormal, time.Now())); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := b.GetByID("t1"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.FollowEvents(ctx, 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+
+	if err := a.UpdateStatusWithEvent("t1", model.TaskStatusPending, model.TaskStatusRunning, "alice", "start"); err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		task, err := b.GetByID("t1")
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if task.Status == model.TaskStatusRunning {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("other instance's status change was not invalidated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLRUTaskCache_EvictionAndTTL(t *testing.T) {
+	now := time.Now()
+	cache := NewLRUTaskCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Set(&model.Task{ID: "a"})
+	cache.Set(&model.Task{ID: "b"})
+	cache.Get("a") // a 最近访问，b 被淘汰
+	cache.Set(&model.Task{ID: "c"})
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", cache.Len())
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Get("c"); ok {
+		t.Fatal("expected c to expire")
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("expected expired entry to be removed, got %d entries", cache.Len())
+	}
+}
+
+func TestRedisTaskCache(t *testing.T) {
+	srv := newFakeRedisCache(t)
+	cache := NewRedisTaskCache(redis.Options{Addr: srv.addr}, "taskflow:task:", time.Minute)
+	defer cache.Close()
+
+	if _, ok := cache.Get("t1"); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	task := newStoreTask("t1", model.TaskPriorityHigh, time.Now())
+	task.Events = []model.TaskEvent{{ID: "e1", TaskID: "t1", Seq: 7}}
+	cache.Set(task)
+	if ttl := srv.ttl("taskflow:task:t1"); ttl != "60000" {
+		t.Fatalf("expected PX 60000, got %q", ttl)
+	}
+
+	got, ok := cache.Get("t1")
+	if !ok {
+		t.Fatal("expected hit")
+	}
+	if got.Name != task.Name || got.Priority != task.Priority || got.InputParams["cmd"] != "echo" ||
+		len(got.Events) != 1 || got.Events[0].Seq != 7 || !got.CreatedAt.Equal(task.CreatedAt) {
+		t.Fatalf("unexpected cached task: %+v", got)
+	}
+
+	cache.Delete("t1")
+	if _, ok := cache.Get("t1"); ok {
+		t.Fatal("expected miss after delete")
+	}
+
+	// Purge 只删除本缓存前缀下的键
+	cache.Set(task)
+	cache.Set(newStoreTask("t2", model.TaskPriorityHigh, time.Now()))
+	srv.set("other:t1", "kept")
+	cache.Purge()
+	for _, id := range []string{"t1", "t2"} {
+		if _, ok := cache.Get(id); ok {
+			t.Errorf("expected %s purged", id)
+		}
+	}
+	if srv.get("other:t1") != "kept" {
+		t.Error("expected keys outside the prefix kept")
+	}
+}
+
+// fakeRedisCache 实现缓存用到的 GET、SET、DEL、SCAN，SCAN 一次返回全部匹配的键
+type fakeRedisCache struct {
+	addr string
+
+	mu     sync.Mutex
+	values map[string]string
+	ttls   map[string]string
+}
+
+func newFakeRedisCache(t *testing.T) *fakeRedisCache {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	srv := &fakeRedisCache{addr: ln.Addr().String(), values: make(map[string]string), ttls: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+func (f *fakeRedisCache) ttl(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ttls[key]
+}
+
+func (f *fakeRedisCache) get(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.values[key]
+}
+
+func (f *fakeRedisCache) set(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[key] = value
+}
+
+func (f *fakeRedisCache) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := redis.ReadReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		if len(args) < 2 {
+			return
+		}
+
+		f.mu.Lock()
+		var out string
+		switch args[0] {
+		case "GET":
+			if v, ok := f.values[args[1]]; ok {
+				out = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			} else {
+				out = "$-1\r\n"
+			}
+		case "SET":
+			f.values[args[1]] = args[2]
+			if len(args) == 5 && args[3] == "PX" {
+				f.ttls[args[1]] = args[4]
+			}
+			out = "+OK\r\n"
+		case "DEL":
+			for _, key := range args[1:] {
+				delete(f.values, key)
+			}
+			out = fmt.Sprintf(":%d\r\n", len(args)-1)
+		case "SCAN":
+			var keys []string
+			for key := range f.values {
+				if len(args) >= 4 && args[2] == "MATCH" && strings.HasPrefix(key, strings.TrimSuffix(args[3], "*")) {
+					keys = append(keys, key)
+				}
+			}
+			out = fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n", len(keys))
+			for _, key := range keys {
+				out += fmt.Sprintf("$%d\r\n%s\r\n", len(key), key)
+			}
+		default:
+			out = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
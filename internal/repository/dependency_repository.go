@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// ErrDependenciesChanged 修复依赖时任务的依赖已被并发修改，修复不再适用
+var ErrDependenciesChanged = errors.New("task dependencies changed concurrently")
+
+// RepairDependencies 按 model.Task.RepairDependencies 修复 PENDING 任务的依赖，同一事务中写入 task.dependencies_repaired 事件。
+// 任务已不是 PENDING 时返回 ErrStatusMismatch，依赖已被并发修改、修复不再适用时返回 ErrDependenciesChanged
+func (r *TaskRepository) RepairDependencies(taskID string, repairs []model.DependencyRepair, operator, reason, correlationID string) error {
+	defer r.db.observe("tasks.RepairDependencies", time.Now(), "task_id", taskID, "repairs", len(repairs))
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		task := model.Task{ID: taskID}
+		var dependencies string
+		var policies sql.NullString
+		err := tx.QueryRow(`SELECT dependencies, dependency_policies FROM tasks WHERE id = ? AND status = ?`,
+			taskID, model.TaskStatusPending).Scan(&dependencies, &policies)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrStatusMismatch
+		}
+		if err != nil {
+			return err
+		}
+		json.Unmarshal([]byte(dependencies), &task.Dependencies)
+		if policies.Valid {
+			json.Unmarshal([]byte(policies.String), &task.DependencyPolicies)
+		}
+		if errs := task.RepairDependencies(repairs); len(errs) > 0 {
+			return fmt.Errorf("%w: %s", ErrDependenciesChanged, errs[0])
+		}
+
+		encoded, _ := json.Marshal(task.Dependencies)
+		now := formatTime(model.Now())
+		result, err := tx.Exec(`UPDATE tasks SET dependencies = ?, dependency_policies = ?, updated_at = ?, version = version + 1
+			WHERE id = ? AND status = ?`,
+			string(encoded), nullableDependencyPolicies(task.DependencyPolicies), now, taskID, model.TaskStatusPending)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		return insertTaskEvent(tx, model.OutboxEventTaskDependenciesRepaired, taskID, model.TaskStatusPending, model.TaskStatusPending,
+			operator, model.DependencyRepairMessage(repairs, reason), "", correlationID, now, nil)
+	})
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// EventFilter 单个任务事件的分页与时间过滤条件
+type EventFilter struct {
+	AfterSeq int64     // 返回 seq 大于该值的事件
+	After    time.Time // 事件时间不早于该时间，零值表示不限
+	Before   time.Time // 事件时间早于该时间，零值表示不限
+	Limit    int       // <= 0 表示不限制
+}
+
+// ListTaskEvents 按 seq 升序分页获取任务事件
+func (r *TaskRepository) ListTaskEvents(taskID string, filter EventFilter) ([]model.TaskEvent, error) {
+	defer r.db.observe("tasks.ListTaskEvents", time.Now(), "task_id", taskID, "after_seq", filter.AfterSeq, "limit", filter.Limit)
+	query := `SELECT ` + eventColumns + ` FROM task_events WHERE task_id = ? AND seq > ?`
+	args := []interface{}{taskID, filter.AfterSeq}
+	if !filter.After.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, formatTime(filter.After))
+	}
+	if !filter.Before.IsZero() {
+		query += ` AND timestamp < ?`
+		args = append(args, formatTime(filter.Before))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	query += ` ORDER BY seq ASC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.DB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// CompactEvents 删除 before 之前的任务事件，每个任务最近的 keepLatest 条事件无论新旧都保留；返回删除的事件数
+func (r *TaskRepository) CompactEvents(before time.Time, keepLatest int) (int64, error) {
+	defer r.db.observe("tasks.CompactEvents", time.Now(), "before", before, "keep_latest", keepLatest)
+	result, err := r.db.DB().Exec(`DELETE FROM task_events WHERE seq IN (
+		SELECT seq FROM (
+			SELECT seq, timestamp, ROW_NUMBER() OVER (PARTITION BY task_id ORDER BY seq DESC) AS rank
+			FROM task_events
+		) WHERE rank > ? AND timestamp < ?
+	)`, keepLatest, formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
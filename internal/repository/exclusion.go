@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"taskflow/internal/model"
+)
+
+// ErrExclusionBusy 与待认领任务互斥的任务正在运行（单例类型、反亲和类型或同一分组），待认领任务保持 PENDING
+var ErrExclusionBusy = errors.New("conflicting task running")
+
+// Exclusion 认领任务时的互斥条件：任务类型属于 TaskTypes 或分组键等于 GroupKey 的其他任务 RUNNING 时不认领
+type Exclusion struct {
+	TaskTypes []string
+	GroupKey  string
+}
+
+// IsZero 是否没有互斥条件
+func (e Exclusion) IsZero() bool {
+	return len(e.TaskTypes) == 0 && e.GroupKey == ""
+}
+
+// matches 任务是否满足互斥条件
+func (e Exclusion) matches(t *model.Task) bool {
+	if e.GroupKey != "" && t.GroupKey == e.GroupKey {
+		return true
+	}
+	for _, taskType := range e.TaskTypes {
+		if t.TaskType == taskType {
+			return true
+		}
+	}
+	return false
+}
+
+// condition 互斥条件的 SQL 片段及参数，没有互斥条件时恒为假
+func (e Exclusion) condition() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if len(e.TaskTypes) > 0 {
+		conds = append(conds, `task_type IN (`+inPlaceholders(len(e.TaskTypes))+`)`)
+		for _, taskType := range e.TaskTypes {
+			args = append(args, taskType)
+		}
+	}
+	if e.GroupKey != "" {
+		conds = append(conds, `group_key = ?`)
+		args = append(args, e.GroupKey)
+	}
+	if len(conds) == 0 {
+		return `1 = 0`, nil
+	}
+	return strings.Join(conds, ` OR `), args
+}
+
+// exclusionBusyError 互斥的任务正在运行，优先说明同一分组
+func exclusionBusyError(e Exclusion, running *model.Task) error {
+	if e.GroupKey != "" && running.GroupKey == e.GroupKey {
+		return fmt.Errorf("%w: task %s in group %s is running", ErrExclusionBusy, running.ID, running.GroupKey)
+	}
+	return fmt.Errorf("%w: task %s of type %s is running", ErrExclusionBusy, running.ID, running.TaskType)
+}
@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 加密列的值格式：enc:v1:<主密钥 ID>:<base64 包装后的数据密钥>:<base64 nonce||密文>
+const encryptedFieldPrefix = "enc:v1:"
+
+// FieldKeySize 主密钥和数据密钥长度（AES-256）
+const FieldKeySize = 32
+
+// FieldKey 列加密主密钥（KEK），ID 写入密文用于轮换后查找解密密钥
+type FieldKey struct {
+	ID  string
+	Key []byte
+}
+
+// ParseFieldKey 解析 "key_id:base64(32 字节)" 格式的主密钥
+func ParseFieldKey(s string) (FieldKey, error) {
+	id, encoded, ok := strings.Cut(s, ":")
+	if !ok || id == "" {
+		return FieldKey{}, fmt.Errorf("field encryption key must be key_id:base64_key")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return FieldKey{}, fmt.Errorf("field encryption key %s: %w", id, err)
+	}
+	if len(key) != FieldKeySize {
+		return FieldKey{}, fmt.Errorf("field encryption key %s: expected %d bytes, got %d", id, FieldKeySize, len(key))
+	}
+	return FieldKey{ID: id, Key: key}, nil
+}
+
+// FieldEncryptor 任务敏感列（input_params、output_result、signal_input）的信封加密：每个值使用随机数据密钥加密，
+// 数据密钥再由主密钥包装。轮换主密钥只需重新包装数据密钥，见 TaskRepository.RotateFieldEncryption
+type FieldEncryptor struct {
+	primary string
+	keks    map[string]cipher.AEAD
+}
+
+// NewFieldEncryptor 创建列加密器，primary 用于加密，previous 为轮换前的旧主密钥，只用于解密
+func NewFieldEncryptor(primary FieldKey, previous ...FieldKey) (*FieldEncryptor, error) {
+	e := &FieldEncryptor{primary: primary.ID, keks: make(map[string]cipher.AEAD)}
+	for _, k := range append([]FieldKey{primary}, previous...) {
+		if strings.Contains(k.ID, ":") || k.ID == "" {
+			return nil, fmt.Errorf("invalid field encryption key id %q", k.ID)
+		}
+		if _, dup := e.keks[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate field encryption key id %s", k.ID)
+		}
+		aead, err := newGCM(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("field encryption key %s: %w", k.ID, err)
+		}
+		e.keks[k.ID] = aead
+	}
+	return e, nil
+}
+
+// PrimaryKeyID 当前用于加密的主密钥 ID
+func (e *FieldEncryptor) PrimaryKeyID() string {
+	return e.primary
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != FieldKeySize {
+		return nil, fmt.Errorf("expected %d-byte key, got %d", FieldKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealField 生成 nonce 并加密，返回 nonce||密文
+func sealField(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// openField 解密 sealField 的输出
+func openField(aead cipher.AEAD, data, aad []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], aad)
+}
+
+// fieldAAD 附加数据绑定任务 ID 和列名，密文不能被挪用到其他行或列
+func fieldAAD(taskID, column string) []byte {
+	return []byte("tasks." + column + ":" + taskID)
+}
+
+// encrypt 加密列值，e 为 nil 时原样返回
+func (e *FieldEncryptor) encrypt(taskID, column, plaintext string) (string, error) {
+	if e == nil {
+		return plaintext, nil
+	}
+
+	dek := make([]byte, FieldKeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	aead, err := newGCM(dek)
+	if err != nil {
+		return "", err
+	}
+	aad := fieldAAD(taskID, column)
+	ciphertext, err := sealField(aead, []byte(plaintext), aad)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := sealField(e.keks[e.primary], dek, aad)
+	if err != nil {
+		return "", err
+	}
+	return encryptedFieldPrefix + e.primary + ":" +
+		base64.StdEncoding.EncodeToString(wrapped) + ":" +
+		base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// parseEncryptedField 拆分加密列值，不是加密格式时返回 ok=false
+func parseEncryptedField(value string) (keyID, wrapped, ciphertext string, ok bool) {
+	rest, found := strings.CutPrefix(value, encryptedFieldPrefix)
+	if !found {
+		return "", "", "", false
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// unwrapKey 用主密钥解开数据密钥
+func (e *FieldEncryptor) unwrapKey(keyID, wrapped string, aad []byte) ([]byte, error) {
+	kek, ok := e.keks[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown field encryption key %s", keyID)
+	}
+	data, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return openField(kek, data, aad)
+}
+
+// decrypt 解密列值，未加密的值（启用加密前写入的行）原样返回
+func (e *FieldEncryptor) decrypt(taskID, column, value string) (string, error) {
+	keyID, wrapped, encoded, ok := parseEncryptedField(value)
+	if !ok {
+		return value, nil
+	}
+	if e == nil {
+		return "", fmt.Errorf("task %s %s is encrypted but field encryption is not configured", taskID, column)
+	}
+
+	aad := fieldAAD(taskID, column)
+	dek, err := e.unwrapKey(keyID, wrapped, aad)
+	if err != nil {
+		return "", fmt.Errorf("task %s %s: %w", taskID, column, err)
+	}
+	aead, err := newGCM(dek)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := openField(aead, data, aad)
+	if err != nil {
+		return "", fmt.Errorf("task %s %s: %w", taskID, column, err)
+	}
+	return string(plaintext), nil
+}
+
+// rewrap 把列值改为由当前主密钥保护：旧主密钥加密的值只重新包装数据密钥，未加密的值直接加密。
+// 已由当前主密钥保护时返回 changed=false
+func (e *FieldEncryptor) rewrap(taskID, column, value string) (string, bool, error) {
+	keyID, wrapped, ciphertext, ok := parseEncryptedField(value)
+	if !ok {
+		encrypted, err := e.encrypt(taskID, column, value)
+		return encrypted, err == nil, err
+	}
+	if keyID == e.primary {
+		return value, false, nil
+	}
+
+	aad := fieldAAD(taskID, column)
+	dek, err := e.unwrapKey(keyID, wrapped, aad)
+	if err != nil {
+		return "", false, fmt.Errorf("task %s %s: %w", taskID, column, err)
+	}
+	rewrapped, err := sealField(e.keks[e.primary], dek, aad)
+	if err != nil {
+		return "", false, err
+	}
+	return encryptedFieldPrefix + e.primary + ":" +
+		base64.StdEncoding.EncodeToString(rewrapped) + ":" + ciphertext, true, nil
+}
+
+// SetFieldEncryptor 启用任务敏感列加密，e 为 nil 时不加密（已加密的行仍需密钥才能读取）。须在使用仓储之前调用
+func (s *SQLite) SetFieldEncryptor(e *FieldEncryptor) {
+	s.fields = e
+}
+
+// RotateFieldEncryption 把不由当前主密钥保护的 input_params、output_result、signal_input（旧主密钥加密或未加密）
+// 改为由当前主密钥保护，每批最多处理 batchSize 行，返回更新的行数。并发修改的行跳过，下次轮换时处理
+func (r *TaskRepository) RotateFieldEncryption(batchSize int) (int, error) {
+	defer r.db.observe("tasks.RotateFieldEncryption", time.Now())
+	e := r.db.fields
+	if e == nil {
+		return 0, errors.New("field encryption is not configured")
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	current := escaper.Replace(encryptedFieldPrefix+e.primary+":") + "%"
+	rotated := 0
+	lastID := ""
+	for {
+		rows, err := r.db.DB().Query(`SELECT id, input_params, output_result, signal_input FROM tasks
+			WHERE id > ? AND (input_params NOT LIKE ? ESCAPE '\' OR output_result NOT LIKE ? ESCAPE '\'
+				OR signal_input NOT LIKE ? ESCAPE '\')
+			ORDER BY id LIMIT ?`, lastID, current, current, current, batchSize)
+		if err != nil {
+			return rotated, err
+		}
+		// signal_input 为 NULL 时（任务没有等待过输入）不加密
+		type row struct {
+			id, input, output string
+			signal            sql.NullString
+		}
+		var batch []row
+		for rows.Next() {
+			var rw row
+			var input, output sql.NullString
+			if err := rows.Scan(&rw.id, &input, &output, &rw.signal); err != nil {
+				rows.Close()
+				return rotated, err
+			}
+			rw.input, rw.output = input.String, output.String
+			batch = append(batch, rw)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return rotated, err
+		}
+		if len(batch) == 0 {
+			return rotated, nil
+		}
+
+		for _, rw := range batch {
+			lastID = rw.id
+			input, inputChanged, err := e.rewrap(rw.id, "input_params", rw.input)
+			if err != nil {
+				return rotated, err
+			}
+			output, outputChanged, err := e.rewrap(rw.id, "output_result", rw.output)
+			if err != nil {
+				return rotated, err
+			}
+			signal, signalChanged := rw.signal, false
+			if rw.signal.Valid {
+				if signal.String, signalChanged, err = e.rewrap(rw.id, "signal_input", rw.signal.String); err != nil {
+					return rotated, err
+				}
+			}
+			if !inputChanged && !outputChanged && !signalChanged {
+				continue
+			}
+
+			// 条件更新，避免覆盖轮换期间写入的新值
+			result, err := r.db.DB().Exec(`UPDATE tasks SET input_params = ?, output_result = ?, signal_input = ?
+				WHERE id = ? AND input_params = ? AND output_result = ? AND COALESCE(signal_input, '') = ?`,
+				input, output, signal, rw.id, rw.input, rw.output, rw.signal.String)
+			if err != nil {
+				return rotated, err
+			}
+			if n, _ := result.RowsAffected(); n > 0 {
+				rotated++
+			}
+		}
+	}
+}
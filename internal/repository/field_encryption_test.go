@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+func testFieldKey(id string, b byte) FieldKey {
+	return FieldKey{ID: id, Key: bytes.Repeat([]byte{b}, FieldKeySize)}
+}
+
+// rawFields 直接读取数据库中的 input_params 和 output_result
+func rawFields(t *testing.T, db *SQLite, id string) (string, string) {
+	t.Helper()
+	var input, output string
+	if err := db.DB().QueryRow(`SELECT input_params, output_result FROM tasks WHERE id = ?`, id).Scan(&input, &output); err != nil {
+		t.Fatalf("failed to read raw fields: %v", err)
+	}
+	return input, output
+}
+
+func TestTaskRepository_FieldEncryption(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewTaskRepository(db)
+
+	// 启用加密前写入的明文行
+	legacy := newStoreTask("legacy", model.TaskPriorityNormal, time.Now().Truncate(time.Second))
+	legacy.InputParams = map[string]string{"password": "legacy-secret"}
+	if err := repo.Create(legacy); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	v1, err := NewFieldEncryptor(testFieldKey("v1", 1))
+	if err != nil {
+		t.Fatalf("NewFieldEncryptor: %v", err)
+	}
+	db.SetFieldEncryptor(v1)
+
+	task := newStoreTask("enc", model.TaskPriorityNormal, time.Now().Truncate(time.Second))
+	task.InputParams = map[string]string{"password": "hunter2"}
+	task.OutputResult = map[string]string{"token": "abc"}
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	input, output := rawFields(t, db, "enc")
+	if !strings.HasPrefix(input, "enc:v1:v1:") || strings.Contains(input, "hunter2") || strings.Contains(output, "abc") {
+		t.Fatalf("expected encrypted columns, got input=%q output=%q", input, output)
+	}
+
+	got, err := repo.GetByID("enc")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.InputParams["password"] != "hunter2" || got.OutputResult["token"] != "abc" {
+		t.Errorf("decrypted fields = %v / %v", got.InputParams, got.OutputResult)
+	}
+	if got, err := repo.GetByID("legacy"); err != nil || got.InputParams["password"] != "legacy-secret" {
+		t.Errorf("legacy plaintext row = %v, %v", got, err)
+	}
+
+	// 密文挪到其他行无法解密
+	if _, err := db.DB().Exec(`UPDATE tasks SET input_params = ? WHERE id = 'legacy'`, input); err != nil {
+		t.Fatalf("failed to move ciphertext: %v", err)
+	}
+	if _, err := repo.GetByID("legacy"); err == nil {
+		t.Error("expected ciphertext bound to another task to fail")
+	}
+	legacy.InputParams = map[string]string{"password": "legacy-secret"}
+	if err := repo.Update(legacy); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	// 轮换：v2 为主密钥，v1 只用于解密
+	v2, err := NewFieldEncryptor(testFieldKey("key_v2", 2), testFieldKey("v1", 1))
+	if err != nil {
+		t.Fatalf("NewFieldEncryptor: %v", err)
+	}
+	db.SetFieldEncryptor(v2)
+
+	if got, err := repo.GetByID("enc"); err != nil || got.InputParams["password"] != "hunter2" {
+		t.Fatalf("read with previous key = %v, %v", got, err)
+	}
+
+	n, err := repo.RotateFieldEncryption(1)
+	if err != nil {
+		t.Fatalf("RotateFieldEncryption: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("rotated %d rows, want 2", n)
+	}
+	rotated, _ := rawFields(t, db, "enc")
+	_, _, oldCiphertext, _ := parseEncryptedField(input)
+	_, _, newCiphertext, _ := parseEncryptedField(rotated)
+	if !strings.HasPrefix(rotated, "enc:v1:key_v2:") || oldCiphertext != newCiphertext {
+		t.Errorf("expected data key to be rewrapped with v2 without re-encrypting data, got %q", rotated)
+	}
+	if n, _ := repo.RotateFieldEncryption(1); n != 0 {
+		t.Errorf("second rotation updated %d rows", n)
+	}
+
+	// 旧主密钥移除后仍可读取
+	v2only, _ := NewFieldEncryptor(testFieldKey("key_v2", 2))
+	db.SetFieldEncryptor(v2only)
+	for _, id := range []string{"enc", "legacy"} {
+		if got, err := repo.GetByID(id); err != nil || got.InputParams["password"] == "" {
+			t.Errorf("GetByID(%s) after rotation = %v, %v", id, got, err)
+		}
+	}
+
+	// 未配置密钥时不能读取加密行
+	db.SetFieldEncryptor(nil)
+	if _, err := repo.GetByID("enc"); err == nil {
+		t.Error("expected error reading encrypted row without keys")
+	}
+}
+
+func TestParseFieldKey(t *testing.T) {
+	if _, err := ParseFieldKey("v1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="); err != nil {
+		t.Errorf("ParseFieldKey: %v", err)
+	}
+	for _, s := range []string{"", "AAAA", ":AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "v1:c2hvcnQ=", "v1:***"} {
+		if _, err := ParseFieldKey(s); err == nil {
+			t.Errorf("ParseFieldKey(%q) = nil, want error", s)
+		}
+	}
+}
+
+func TestTaskRepository_SignalInputEncryption(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	repo := NewTaskRepository(db)
+
+	v1, _ := NewFieldEncryptor(testFieldKey("v1", 1))
+	db.SetFieldEncryptor(v1)
+
+	task := newStoreTask("wait", model.TaskPriorityNormal, time.Now().Truncate(time.Second))
+	task.Status = model.TaskStatusRunning
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	rawSignal := func() string {
+		t.Helper()
+		var signal string
+		if err := db.DB().QueryRow(`SELECT signal_input FROM tasks WHERE id = 'wait'`).Scan(&signal); err != nil {
+			t.Fatalf("failed to read raw signal_input: %v", err)
+		}
+		return signal
+	}
+
+	// 两次信号按键合并，合并时解密已送达的输入
+	for _, payload := range []map[string]string{{"approver": "bob", "otp": "123456"}, {"otp": "654321"}} {
+		if err := repo.SuspendForInput("wait", "need approval", "system", "waiting for input", "", nil); err != nil {
+			t.Fatalf("SuspendForInput: %v", err)
+		}
+		if err := repo.SignalTask("wait", payload, "bob", "input received", ""); err != nil {
+			t.Fatalf("SignalTask: %v", err)
+		}
+		if err := repo.UpdateStatus("wait", model.TaskStatusPending, model.TaskStatusRunning); err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+	}
+	signal := rawSignal()
+	if !strings.HasPrefix(signal, "enc:v1:v1:") || strings.Contains(signal, "654321") {
+		t.Fatalf("expected encrypted signal_input, got %q", signal)
+	}
+	got, err := repo.GetByID("wait")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.SignalInput["approver"] != "bob" || got.SignalInput["otp"] != "654321" {
+		t.Errorf("decrypted signal_input = %v", got.SignalInput)
+	}
+
+	// 轮换同样重新包装 signal_input
+	v2, _ := NewFieldEncryptor(testFieldKey("v2", 2), testFieldKey("v1", 1))
+	db.SetFieldEncryptor(v2)
+	if _, err := repo.RotateFieldEncryption(10); err != nil {
+		t.Fatalf("RotateFieldEncryption: %v", err)
+	}
+	if signal := rawSignal(); !strings.HasPrefix(signal, "enc:v1:v2:") {
+		t.Errorf("expected signal_input rewrapped with v2, got %q", signal)
+	}
+	v2only, _ := NewFieldEncryptor(testFieldKey("v2", 2))
+	db.SetFieldEncryptor(v2only)
+	if got, err := repo.GetByID("wait"); err != nil || got.SignalInput["otp"] != "654321" {
+		t.Errorf("GetByID after rotation = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// UpsertSchedulerInstance 写入或更新调度器实例心跳
+func (r *TaskRepository) UpsertSchedulerInstance(inst *model.SchedulerInstance) error {
+	defer r.db.observe("tasks.UpsertSchedulerInstance", time.Now(), "id", inst.ID)
+	inFlight, err := json.Marshal(inst.InFlightTasks)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.DB().Exec(`INSERT INTO scheduler_instances (
+		id, hostname, started_at, heartbeat_at, worker_count, busy_workers, queue_depth, in_flight_tasks, labels
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+		heartbeat_at = excluded.heartbeat_at,
+		worker_count = excluded.worker_count,
+		busy_workers = excluded.busy_workers,
+		queue_depth = excluded.queue_depth,
+		in_flight_tasks = excluded.in_flight_tasks,
+		labels = excluded.labels`,
+		inst.ID,
+		inst.Hostname,
+		formatTime(inst.StartedAt),
+		formatTime(inst.HeartbeatAt),
+		inst.WorkerCount,
+		inst.BusyWorkers,
+		inst.QueueDepth,
+		string(inFlight),
+		nullableLabels(inst.Labels),
+	)
+	return err
+}
+
+// ListSchedulerInstances 列出调度器实例，按启动时间升序
+func (r *TaskRepository) ListSchedulerInstances() ([]*model.SchedulerInstance, error) {
+	defer r.db.observe("tasks.ListSchedulerInstances", time.Now())
+	rows, err := r.db.DB().Query(`SELECT id, hostname, started_at, heartbeat_at, worker_count, busy_workers, queue_depth, in_flight_tasks, labels
+	FROM scheduler_instances ORDER BY started_at ASC, id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var instances []*model.SchedulerInstance
+	for rows.Next() {
+		var inst model.SchedulerInstance
+		var startedAt, heartbeatAt, inFlight string
+		var labels sql.NullString
+		if err := rows.Scan(&inst.ID, &inst.Hostname, &startedAt, &heartbeatAt,
+			&inst.WorkerCount, &inst.BusyWorkers, &inst.QueueDepth, &inFlight, &labels); err != nil {
+			return nil, err
+		}
+		inst.StartedAt = parseTimestamp(startedAt)
+		inst.HeartbeatAt = parseTimestamp(heartbeatAt)
+		json.Unmarshal([]byte(inFlight), &inst.InFlightTasks)
+		if labels.Valid {
+			json.Unmarshal([]byte(labels.String), &inst.Labels)
+		}
+		instances = append(instances, &inst)
+	}
+	return instances, rows.Err()
+}
+
+// DeleteSchedulerInstance 删除调度器实例（正常停止时调用）
+func (r *TaskRepository) DeleteSchedulerInstance(id string) error {
+	defer r.db.observe("tasks.DeleteSchedulerInstance", time.Now(), "id", id)
+	_, err := r.db.DB().Exec(`DELETE FROM scheduler_instances WHERE id = ?`, id)
+	return err
+}
+
+// SaveSchedulerCheckpoint 写入或覆盖调度器检查点
+func (r *TaskRepository) SaveSchedulerCheckpoint(cp *model.SchedulerCheckpoint) error {
+	defer r.db.observe("tasks.SaveSchedulerCheckpoint", time.Now(), "name", cp.Name)
+	_, err := r.db.DB().Exec(`INSERT INTO scheduler_checkpoints (
+		name, saved_at, base_workers, worker_count, autoscale_low_ticks, scheduled_count, finished_count
+	) VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(name) DO UPDATE SET
+		saved_at = excluded.saved_at,
+		base_workers = excluded.base_workers,
+		worker_count = excluded.worker_count,
+		autoscale_low_ticks = excluded.autoscale_low_ticks,
+		scheduled_count = excluded.scheduled_count,
+		finished_count = excluded.finished_count`,
+		cp.Name,
+		formatTime(cp.SavedAt),
+		cp.BaseWorkers,
+		cp.WorkerCount,
+		cp.AutoscaleLowTicks,
+		cp.ScheduledCount,
+		cp.FinishedCount,
+	)
+	return err
+}
+
+// GetSchedulerCheckpoint 读取调度器检查点，不存在时返回 nil
+func (r *TaskRepository) GetSchedulerCheckpoint(name string) (*model.SchedulerCheckpoint, error) {
+	defer r.db.observe("tasks.GetSchedulerCheckpoint", time.Now(), "name", name)
+	var cp model.SchedulerCheckpoint
+	var savedAt string
+	err := r.db.DB().QueryRow(`SELECT name, saved_at, base_workers, worker_count, autoscale_low_ticks, scheduled_count, finished_count
+	FROM scheduler_checkpoints WHERE name = ?`, name).Scan(&cp.Name, &savedAt,
+		&cp.BaseWorkers, &cp.WorkerCount, &cp.AutoscaleLowTicks, &cp.ScheduledCount, &cp.FinishedCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp.SavedAt = parseTimestamp(savedAt)
+	return &cp, nil
+}
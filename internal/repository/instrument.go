@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+)
+
+// DefaultSlowQueryThreshold 默认慢查询日志阈值
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// SetSlowQueryThreshold 设置慢查询日志阈值，耗时不低于阈值的仓储方法以 Warn 级别记录操作名和查询参数。
+// threshold <= 0 表示不记录，须在使用仓储之前调用
+func (s *SQLite) SetSlowQueryThreshold(threshold time.Duration) {
+	s.slowQueryThreshold = threshold
+}
+
+// observe 记录仓储方法耗时，params 为交替的参数名和值。用法：defer r.db.observe("tasks.GetByID", time.Now(), "id", id)
+func (s *SQLite) observe(operation string, start time.Time, params ...interface{}) {
+	elapsed := time.Since(start)
+	metrics.RecordRepositoryQuery(operation, elapsed.Seconds())
+
+	if s.slowQueryThreshold > 0 && elapsed >= s.slowQueryThreshold {
+		metrics.RecordRepositorySlowQuery(operation)
+		logger.Warnf("Slow query %s took %s: %s", operation, elapsed, formatParams(params))
+	}
+}
+
+// formatParams 把交替的参数名和值格式化为 key=value，指针参数取其指向的值
+func formatParams(params []interface{}) string {
+	parts := make([]string, 0, len(params)/2)
+	for i := 0; i+1 < len(params); i += 2 {
+		value := params[i+1]
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				value = "<nil>"
+			} else {
+				value = v.Elem().Interface()
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%v=%v", params[i], value))
+	}
+	return strings.Join(parts, " ")
+}
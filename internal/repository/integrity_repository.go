@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// ListOrphanedEventTaskIDs 列出有事件但任务已不存在、ID 大于 afterID 的任务 ID，按 ID 升序，最多 limit 个。
+// SQLite 连接未开启外键约束，旧版本的 Delete 只删除任务行，残留其事件
+func (r *TaskRepository) ListOrphanedEventTaskIDs(afterID string, limit int) ([]string, error) {
+	defer r.db.observe("tasks.ListOrphanedEventTaskIDs", time.Now(), "after_id", afterID, "limit", limit)
+	rows, err := r.db.DB().Query(`SELECT DISTINCT task_id FROM task_events
+		WHERE task_id > ? AND task_id NOT IN (SELECT id FROM tasks) ORDER BY task_id LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteOrphanedEvents 删除 taskIDs 中任务已不存在的事件，返回删除的事件数；任务存在的 ID 不受影响
+func (r *TaskRepository) DeleteOrphanedEvents(taskIDs []string) (int64, error) {
+	defer r.db.observe("tasks.DeleteOrphanedEvents", time.Now(), "task_ids", len(taskIDs))
+	if len(taskIDs) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, len(taskIDs))
+	for i, id := range taskIDs {
+		args[i] = id
+	}
+	result, err := r.db.DB().Exec(`DELETE FROM task_events WHERE task_id IN (`+inPlaceholders(len(taskIDs))+`)
+		AND task_id NOT IN (SELECT id FROM tasks)`, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SetCompletedAt 为缺少完成时间的终态任务补记完成时间，任务状态已不是 status 或已有完成时间时返回 ErrStatusMismatch
+func (r *TaskRepository) SetCompletedAt(taskID string, status model.TaskStatus, at time.Time) error {
+	defer r.db.observe("tasks.SetCompletedAt", time.Now(), "id", taskID)
+	result, err := r.db.DB().Exec(`UPDATE tasks SET completed_at = ?, version = version + 1 WHERE id = ? AND status = ? AND completed_at IS NULL`,
+		formatTime(at), taskID, status)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// AppendLogs 批量写入任务日志行
+func (r *TaskRepository) AppendLogs(lines []model.TaskLogLine) error {
+	defer r.db.observe("tasks.AppendLogs", time.Now(), "lines", len(lines))
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`INSERT INTO task_logs (task_id, seq, attempt, timestamp, line) VALUES (?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, l := range lines {
+			if _, err := stmt.Exec(l.TaskID, l.Seq, l.Attempt, l.Timestamp.UTC().Format(time.RFC3339Nano), l.Line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetLogs 获取 seq 大于 afterSeq 的日志行，按 seq 升序，limit <= 0 表示不限制
+func (r *TaskRepository) GetLogs(taskID string, afterSeq int64, limit int) ([]model.TaskLogLine, error) {
+	defer r.db.observe("tasks.GetLogs", time.Now(), "task_id", taskID, "after_seq", afterSeq, "limit", limit)
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := r.db.DB().Query(`SELECT task_id, seq, attempt, timestamp, line
+	FROM task_logs WHERE task_id = ? AND seq > ? ORDER BY seq ASC LIMIT ?`, taskID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []model.TaskLogLine
+	for rows.Next() {
+		var l model.TaskLogLine
+		var ts string
+		if err := rows.Scan(&l.TaskID, &l.Seq, &l.Attempt, &ts, &l.Line); err != nil {
+			return nil, err
+		}
+		t, _ := time.Parse(time.RFC3339Nano, ts)
+		l.Timestamp = t.UTC()
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// LastLogSeq 获取任务最新日志行的 seq，没有日志时返回 0
+func (r *TaskRepository) LastLogSeq(taskID string) (int64, error) {
+	defer r.db.observe("tasks.LastLogSeq", time.Now(), "task_id", taskID)
+	var seq sql.NullInt64
+	err := r.db.DB().QueryRow(`SELECT MAX(seq) FROM task_logs WHERE task_id = ?`, taskID).Scan(&seq)
+	return seq.Int64, err
+}
+
+// TrimLogs 只保留任务最近 keep 行日志（环形缓冲）
+func (r *TaskRepository) TrimLogs(taskID string, keep int64) error {
+	defer r.db.observe("tasks.TrimLogs", time.Now(), "task_id", taskID, "keep", keep)
+	_, err := r.db.DB().Exec(`DELETE FROM task_logs WHERE task_id = ? AND seq <= (SELECT MAX(seq) FROM task_logs WHERE task_id = ?) - ?`,
+		taskID, taskID, keep)
+	return err
+}
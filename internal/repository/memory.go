@@ -0,0 +1,1800 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"taskflow/internal/idgen"
+	"taskflow/internal/model"
+)
+
+// errDuplicateKey 内存仓储主键或唯一约束冲突
+var errDuplicateKey = errors.New("duplicate key")
+
+// memoryState 内存仓储共享的数据，任务与团队仓储共用一把锁（ListByFilter 的 MemberOf 需要查成员关系）
+type memoryState struct {
+	mu          sync.RWMutex
+	tasks       map[string]*model.Task
+	events      map[string][]model.TaskEvent
+	eventSeq    int64 // 已分配的最大事件序号
+	comments    map[string][]model.TaskComment
+	attachments map[string][]*model.TaskAttachment
+	logs        map[string][]model.TaskLogLine
+	runs        map[string][]model.TaskRun
+	instances   map[string]*model.SchedulerInstance
+	checkpoints map[string]*model.SchedulerCheckpoint
+	outbox      []*model.OutboxEvent
+	outboxSeq   int64 // 已分配的最大发件箱序号
+	teams       map[string]*model.Team
+}
+
+// MemoryTaskRepository 线程安全的内存任务仓储，不持久化，用于嵌入和测试
+type MemoryTaskRepository struct {
+	s *memoryState
+}
+
+// MemoryTeamRepository 与 MemoryTaskRepository 共享数据的内存团队仓储
+type MemoryTeamRepository struct {
+	s *memoryState
+}
+
+// NewMemoryRepositories 创建共享同一份数据的内存任务仓储和团队仓储
+func NewMemoryRepositories() (*MemoryTaskRepository, *MemoryTeamRepository) {
+	s := &memoryState{
+		tasks:       make(map[string]*model.Task),
+		events:      make(map[string][]model.TaskEvent),
+		comments:    make(map[string][]model.TaskComment),
+		attachments: make(map[string][]*model.TaskAttachment),
+		logs:        make(map[string][]model.TaskLogLine),
+		runs:        make(map[string][]model.TaskRun),
+		instances:   make(map[string]*model.SchedulerInstance),
+		checkpoints: make(map[string]*model.SchedulerCheckpoint),
+		teams:       make(map[string]*model.Team),
+	}
+	return &MemoryTaskRepository{s: s}, &MemoryTeamRepository{s: s}
+}
+
+var (
+	_ TaskStore = (*MemoryTaskRepository)(nil)
+	_ TeamStore = (*MemoryTeamRepository)(nil)
+)
+
+// cloneTask 复制任务，调用方修改返回值不影响仓储中的数据（不含事件、评论和运行记录）
+func cloneTask(t *model.Task) *model.Task {
+	c := *t
+	c.Events = nil
+	c.Comments = nil
+	c.Runs = nil
+	if t.InputParams != nil {
+		c.InputParams = make(map[string]string, len(t.InputParams))
+		for k, v := range t.InputParams {
+			c.InputParams[k] = v
+		}
+	}
+	if t.OutputResult != nil {
+		c.OutputResult = make(map[string]string, len(t.OutputResult))
+		for k, v := range t.OutputResult {
+			c.OutputResult[k] = v
+		}
+	}
+	c.Dependencies = append([]string(nil), t.Dependencies...)
+	c.Labels = maps.Clone(t.Labels)
+	c.SignalInput = maps.Clone(t.SignalInput)
+	c.NodeSelector = maps.Clone(t.NodeSelector)
+	c.Phases = slices.Clone(t.Phases)
+	if t.DependencyPolicies != nil {
+		c.DependencyPolicies = make(map[string]model.DependencyFailurePolicy, len(t.DependencyPolicies))
+		for k, v := range t.DependencyPolicies {
+			c.DependencyPolicies[k] = v
+		}
+	}
+	if t.StartedAt != nil {
+		v := *t.StartedAt
+		c.StartedAt = &v
+	}
+	if t.CompletedAt != nil {
+		v := *t.CompletedAt
+		c.CompletedAt = &v
+	}
+	if t.NextRunAt != nil {
+		v := *t.NextRunAt
+		c.NextRunAt = &v
+	}
+	if t.SLADeadline != nil {
+		v := *t.SLADeadline
+		c.SLADeadline = &v
+	}
+	if t.SLABreachedAt != nil {
+		v := *t.SLABreachedAt
+		c.SLABreachedAt = &v
+	}
+	if t.WorkflowDeadline != nil {
+		v := *t.WorkflowDeadline
+		c.WorkflowDeadline = &v
+	}
+	if t.HeartbeatAt != nil {
+		v := *t.HeartbeatAt
+		c.HeartbeatAt = &v
+	}
+	if t.RetryPolicy != nil {
+		p := *t.RetryPolicy
+		p.RetryableErrors = append([]string(nil), t.RetryPolicy.RetryableErrors...)
+		c.RetryPolicy = &p
+	}
+	return &c
+}
+
+// sortedTasks 按 less 排序的任务副本
+func (s *memoryState) sortedTasks(match func(*model.Task) bool, less func(a, b *model.Task) bool) []*model.Task {
+	var tasks []*model.Task
+	for _, t := range s.tasks {
+		if match(t) {
+			tasks = append(tasks, cloneTask(t))
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if less(tasks[i], tasks[j]) {
+			return true
+		}
+		if less(tasks[j], tasks[i]) {
+			return false
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks
+}
+
+// paginate 与 SQL 的 LIMIT/OFFSET 一致，limit < 0 表示不限制
+func paginate(tasks []*model.Task, limit, offset int) []*model.Task {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(tasks) {
+		return nil
+	}
+	tasks = tasks[offset:]
+	if limit >= 0 && limit < len(tasks) {
+		tasks = tasks[:limit]
+	}
+	return tasks
+}
+
+func newestFirst(a, b *model.Task) bool { return a.CreatedAt.After(b.CreatedAt) }
+
+// containsFold 与 SQLite 的 LIKE '%keyword%' 一致（ASCII 不区分大小写）
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// Create 创建任务，新任务的版本号为 1
+func (r *MemoryTaskRepository) Create(task *model.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.tasks[task.ID]; ok {
+		return fmt.Errorf("task %s: %w", task.ID, errDuplicateKey)
+	}
+	task.Version = 1
+	stored := cloneTask(task)
+	// 与 SQLite 实现一致，创建时不写入执行相关字段
+	stored.ExecutedBy = ""
+	stored.OutputRef = ""
+	stored.NextRunAt = nil
+	stored.ErrorClass = model.ErrorClassUnknown
+	stored.BlockedReason = ""
+	stored.SLABreachedAt = nil
+	resetTaskProgress(stored)
+	// 与 SQLite 实现一致，时间统一存为 UTC
+	stored.CreatedAt = stored.CreatedAt.UTC()
+	stored.UpdatedAt = stored.UpdatedAt.UTC()
+	r.s.tasks[task.ID] = stored
+	r.s.appendEvent(model.NewCreatedEvent(stored))
+	return nil
+}
+
+// GetByID 根据 ID 获取任务（含事件、评论和历次运行），不存在时返回 ErrTaskNotFound
+func (r *MemoryTaskRepository) GetByID(id string) (*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	t, ok := r.s.tasks[id]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	task := cloneTask(t)
+	task.Events = append([]model.TaskEvent(nil), r.s.events[id]...)
+	task.Comments = append([]model.TaskComment(nil), r.s.comments[id]...)
+	task.Runs = cloneRuns(r.s.runs[id])
+	return task, nil
+}
+
+// GetByIDs 批量获取任务（不含事件），结果与 ids 顺序一致，不存在的任务对应位置为 nil
+func (r *MemoryTaskRepository) GetByIDs(ids []string) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	result := make([]*model.Task, len(ids))
+	for i, id := range ids {
+		if t, ok := r.s.tasks[id]; ok {
+			result[i] = cloneTask(t)
+		}
+	}
+	return result, nil
+}
+
+// ListAfterID 按 ID 升序列出 ID 大于 afterID 的任务（不含事件）
+func (r *MemoryTaskRepository) ListAfterID(afterID string, limit int) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool { return t.ID > afterID }, byID)
+	return paginate(tasks, limit, 0), nil
+}
+
+func byID(a, b *model.Task) bool { return a.ID < b.ID }
+
+// Update 更新任务（不修改创建时间、执行实例、SLA 违约时间、父任务和执行进度）
+func (r *MemoryTaskRepository) Update(task *model.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	existing, ok := r.s.tasks[task.ID]
+	if !ok {
+		return nil
+	}
+	stored := cloneTask(task)
+	stored.CreatedAt = existing.CreatedAt
+	stored.ExecutedBy = existing.ExecutedBy
+	stored.SLABreachedAt = existing.SLABreachedAt
+	stored.QueuedAt, stored.QueueAlertedAt = existing.QueuedAt, existing.QueueAlertedAt
+	stored.WorkflowDeadline = existing.WorkflowDeadline
+	stored.CorrelationID = existing.CorrelationID
+	stored.ParentID = existing.ParentID
+	stored.Progress, stored.ProgressMessage, stored.HeartbeatAt = existing.Progress, existing.ProgressMessage, existing.HeartbeatAt
+	stored.InputRequest, stored.SignalInput = existing.InputRequest, maps.Clone(existing.SignalInput)
+	stored.SubStatus, stored.Phases = existing.SubStatus, slices.Clone(existing.Phases)
+	stored.Version = existing.Version + 1
+	r.s.tasks[task.ID] = stored
+	return nil
+}
+
+// UpdateDefinition 只写入任务定义中可由用户修改的字段，任务状态已不是 expectedStatus 时返回 ErrStatusMismatch
+func (r *MemoryTaskRepository) UpdateDefinition(task *model.Task, expectedStatus model.TaskStatus) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[task.ID]
+	if !ok || t.Status != expectedStatus {
+		return ErrStatusMismatch
+	}
+	t.Name = task.Name
+	t.Description = task.Description
+	t.Priority = task.Priority
+	t.InputParams = maps.Clone(task.InputParams)
+	t.MaxRetries = task.MaxRetries
+	t.Labels = maps.Clone(task.Labels)
+	t.BasePriority = task.BasePriority
+	t.UpdatedAt = task.UpdatedAt
+	t.Version++
+	return nil
+}
+
+// Delete 删除任务及其事件、评论、附件、日志和历次运行，依赖检查、force 的处理和下游任务的事件与 SQLite 实现一致
+func (r *MemoryTaskRepository) Delete(id string, force bool, operator, correlationID string) (*DeletedTask, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	dependents := r.s.sortedTasks(func(t *model.Task) bool {
+		return t.ID != id && slices.Contains(t.Dependencies, id)
+	}, func(a, b *model.Task) bool { return a.ID < b.ID })
+	if !force {
+		if err := activeDependentsError(id, dependents); err != nil {
+			return nil, err
+		}
+	}
+
+	deleted := &DeletedTask{}
+	for _, a := range r.s.attachments[id] {
+		deleted.AttachmentKeys = append(deleted.AttachmentKeys, a.StorageKey)
+	}
+	if t, ok := r.s.tasks[id]; ok && t.OutputRef != "" {
+		deleted.OutputRefs = append(deleted.OutputRefs, t.OutputRef)
+	}
+	for _, run := range r.s.runs[id] {
+		if run.OutputRef != "" && !slices.Contains(deleted.OutputRefs, run.OutputRef) {
+			deleted.OutputRefs = append(deleted.OutputRefs, run.OutputRef)
+		}
+	}
+
+	now := model.Now()
+	for _, dependent := range dependents {
+		t := r.s.tasks[dependent.ID]
+		t.RemoveDependency(id)
+		t.UpdatedAt = now
+		t.Version++
+		r.s.appendCorrelatedTaskEvent(t, model.OutboxEventTaskDependencyRemoved, operator, dependencyRemovedMessage(id), "", correlationID)
+		deleted.Dependents = append(deleted.Dependents, t.ID)
+	}
+	delete(r.s.tasks, id)
+	delete(r.s.events, id)
+	delete(r.s.comments, id)
+	delete(r.s.attachments, id)
+	delete(r.s.logs, id)
+	delete(r.s.runs, id)
+	return deleted, nil
+}
+
+// List 列出任务（分页）
+func (r *MemoryTaskRepository) List(limit, offset int, statusFilter *model.TaskStatus) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool {
+		return statusFilter == nil || t.Status == *statusFilter
+	}, newestFirst)
+	return paginate(tasks, limit, offset), nil
+}
+
+// ListByStatus 根据状态列出任务
+func (r *MemoryTaskRepository) ListByStatus(status model.TaskStatus, limit int) ([]*model.Task, error) {
+	return r.List(limit, 0, &status)
+}
+
+// ListByCreator 根据创建者列出任务
+func (r *MemoryTaskRepository) ListByCreator(createdBy string, limit, offset int) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool { return t.CreatedBy == createdBy }, newestFirst)
+	return paginate(tasks, limit, offset), nil
+}
+
+// ListPending 列出待处理任务（可被调度），跳过重试退避在 now 时尚未到期的任务，以及上游依赖尚未结束
+// （或未成功且按 wait 处理）的任务
+func (r *MemoryTaskRepository) ListPending(limit int, now time.Time) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool {
+		return t.Status == model.TaskStatusPending && (t.NextRunAt == nil || !t.NextRunAt.After(now)) && r.s.dependenciesSettled(t)
+	}, func(a, b *model.Task) bool {
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return paginate(tasks, limit, 0), nil
+}
+
+// ListDependents 列出直接依赖 taskIDs 中任一任务的任务，statusFilter 不为空时只返回该状态的任务，按创建时间排序
+func (r *MemoryTaskRepository) ListDependents(taskIDs []string, statusFilter *model.TaskStatus) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.s.sortedTasks(func(t *model.Task) bool {
+		if statusFilter != nil && t.Status != *statusFilter {
+			return false
+		}
+		return slices.ContainsFunc(t.Dependencies, func(dep string) bool { return slices.Contains(taskIDs, dep) })
+	}, func(a, b *model.Task) bool { return a.CreatedAt.Before(b.CreatedAt) }), nil
+}
+
+// ListByFilter 按条件过滤任务
+func (r *MemoryTaskRepository) ListByFilter(filter TaskFilter) ([]*model.Task, int, error) {
+	if !filter.SortBy.Valid() {
+		return nil, 0, fmt.Errorf("%w: %q", ErrInvalidSortField, filter.SortBy)
+	}
+
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool {
+		switch {
+		case filter.Status != nil && t.Status != *filter.Status,
+			filter.Priority != nil && t.Priority != *filter.Priority,
+			filter.TaskType != "" && t.TaskType != filter.TaskType,
+			len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, t.Status),
+			len(filter.Priorities) > 0 && !slices.Contains(filter.Priorities, t.Priority),
+			len(filter.TaskTypes) > 0 && !slices.Contains(filter.TaskTypes, t.TaskType),
+			filter.CreatedBy != "" && t.CreatedBy != filter.CreatedBy,
+			filter.TeamID != "" && t.TeamID != filter.TeamID,
+			filter.MemberOf != "" && !r.s.isMember(t.TeamID, filter.MemberOf),
+			filter.VisibleTo != "" && t.CreatedBy != filter.VisibleTo && !r.s.isMember(t.TeamID, filter.VisibleTo) &&
+				!(filter.IncludeUnassigned && t.TeamID == ""),
+			filter.CorrelationID != "" && t.CorrelationID != filter.CorrelationID,
+			filter.Keyword != "" && !containsFold(t.Name, filter.Keyword) && !containsFold(t.Description, filter.Keyword),
+			!filter.CreatedAfter.IsZero() && t.CreatedAt.Before(filter.CreatedAfter),
+			!filter.CreatedBefore.IsZero() && !t.CreatedAt.Before(filter.CreatedBefore),
+			!filter.CompletedAfter.IsZero() && (t.CompletedAt == nil || t.CompletedAt.Before(filter.CompletedAfter)),
+			!filter.CompletedBefore.IsZero() && (t.CompletedAt == nil || !t.CompletedAt.Before(filter.CompletedBefore)),
+			!filter.UpdatedSince.IsZero() && t.UpdatedAt.Before(filter.UpdatedSince):
+			return false
+		}
+		return true
+	}, filter.less)
+
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	if filter.PageIndex < 0 {
+		filter.PageIndex = 0
+	}
+	return paginate(tasks, filter.PageSize, filter.PageIndex*filter.PageSize), len(tasks), nil
+}
+
+// less 与 TaskFilter.orderBy 生成的 ORDER BY 一致，取值相同时由 sortedTasks 按 ID 升序
+func (f TaskFilter) less(a, b *model.Task) bool {
+	switch f.SortBy {
+	case "":
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	case SortByCompletedAt:
+		if (a.CompletedAt == nil) != (b.CompletedAt == nil) {
+			return b.CompletedAt == nil
+		}
+		if a.CompletedAt == nil {
+			return false
+		}
+		return timeLess(*a.CompletedAt, *b.CompletedAt, f.SortDesc)
+	case SortByCreatedAt:
+		return timeLess(a.CreatedAt, b.CreatedAt, f.SortDesc)
+	case SortByUpdatedAt:
+		return timeLess(a.UpdatedAt, b.UpdatedAt, f.SortDesc)
+	case SortByPriority:
+		if f.SortDesc {
+			return a.Priority > b.Priority
+		}
+		return a.Priority < b.Priority
+	case SortByStatus:
+		if f.SortDesc {
+			return a.Status > b.Status
+		}
+		return a.Status < b.Status
+	}
+	return false
+}
+
+// timeLess 按 desc 指定的方向比较时间
+func timeLess(a, b time.Time, desc bool) bool {
+	if desc {
+		return a.After(b)
+	}
+	return a.Before(b)
+}
+
+// Search 搜索任务
+func (r *MemoryTaskRepository) Search(keyword string, limit, offset int) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool {
+		return containsFold(t.Name, keyword) || containsFold(t.Description, keyword) || containsFold(t.TaskType, keyword)
+	}, newestFirst)
+	return paginate(tasks, limit, offset), nil
+}
+
+// Count 统计任务数量
+func (r *MemoryTaskRepository) Count(statusFilter *model.TaskStatus) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	count := 0
+	for _, t := range r.s.tasks {
+		if statusFilter == nil || t.Status == *statusFilter {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// RecentDurations 返回指定类型最近 limit 个成功任务的执行耗时（完成时间减开始时间），按完成时间倒序
+func (r *MemoryTaskRepository) RecentDurations(taskType string, limit int) ([]time.Duration, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool {
+		return t.TaskType == taskType && t.Status == model.TaskStatusSucceeded &&
+			t.StartedAt != nil && t.CompletedAt != nil && !t.CompletedAt.Before(*t.StartedAt)
+	}, func(a, b *model.Task) bool { return a.CompletedAt.After(*b.CompletedAt) })
+
+	var durations []time.Duration
+	for _, t := range paginate(tasks, limit, 0) {
+		durations = append(durations, t.CompletedAt.Sub(*t.StartedAt))
+	}
+	return durations, nil
+}
+
+// CountOutcomesByType 按任务类型统计完成时间在 [from, to) 内的成功与失败任务数，取消和跳过的任务不计入
+func (r *MemoryTaskRepository) CountOutcomesByType(from, to time.Time) (map[string]OutcomeCount, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	counts := make(map[string]OutcomeCount)
+	for _, t := range r.s.tasks {
+		if t.CompletedAt == nil || t.CompletedAt.Before(from) || !t.CompletedAt.Before(to) {
+			continue
+		}
+		c := counts[t.TaskType]
+		switch t.Status {
+		case model.TaskStatusSucceeded:
+			c.Succeeded++
+		case model.TaskStatusFailed, model.TaskStatusTimeout:
+			c.Failed++
+		default:
+			continue
+		}
+		counts[t.TaskType] = c
+	}
+	return counts, nil
+}
+
+// ListTaskTypes 列出已有任务使用过的任务类型（不含空类型），按名称排序
+func (r *MemoryTaskRepository) ListTaskTypes() ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var types []string
+	for _, t := range r.s.tasks {
+		if t.TaskType != "" && !seen[t.TaskType] {
+			seen[t.TaskType] = true
+			types = append(types, t.TaskType)
+		}
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// AddEvent 添加任务事件，未指定请求 ID 时沿用任务的
+func (r *MemoryTaskRepository) AddEvent(event *model.TaskEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored := *event
+	if t, ok := r.s.tasks[event.TaskID]; ok && stored.CorrelationID == "" {
+		stored.CorrelationID = t.CorrelationID
+	}
+	r.s.appendEvent(stored)
+	return nil
+}
+
+// GetEventsByTaskID 获取任务的所有事件
+func (r *MemoryTaskRepository) GetEventsByTaskID(taskID string) ([]model.TaskEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return append([]model.TaskEvent(nil), r.s.events[taskID]...), nil
+}
+
+// LatestEventSeq 返回已分配的最大事件序号，没有事件时为 0
+func (r *MemoryTaskRepository) LatestEventSeq() (int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.s.eventSeq, nil
+}
+
+// ListEventsAfter 按序号升序列出所有任务中序号大于 afterSeq 的事件
+func (r *MemoryTaskRepository) ListEventsAfter(afterSeq int64, limit int) ([]model.TaskEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var events []model.TaskEvent
+	for _, taskEvents := range r.s.events {
+		for _, e := range taskEvents {
+			if e.Seq > afterSeq {
+				events = append(events, e)
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// ListTaskEvents 按 seq 升序分页获取任务事件
+func (r *MemoryTaskRepository) ListTaskEvents(taskID string, filter EventFilter) ([]model.TaskEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var events []model.TaskEvent
+	for _, e := range r.s.events[taskID] {
+		if e.Seq <= filter.AfterSeq ||
+			(!filter.After.IsZero() && e.Timestamp.Before(filter.After)) ||
+			(!filter.Before.IsZero() && !e.Timestamp.Before(filter.Before)) {
+			continue
+		}
+		events = append(events, e)
+		if filter.Limit > 0 && len(events) >= filter.Limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// CompactEvents 删除 before 之前的任务事件，每个任务最近的 keepLatest 条事件无论新旧都保留；返回删除的事件数
+func (r *MemoryTaskRepository) CompactEvents(before time.Time, keepLatest int) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var removed int64
+	for taskID, events := range r.s.events {
+		if len(events) <= keepLatest {
+			continue
+		}
+		cut := len(events) - keepLatest
+		kept := make([]model.TaskEvent, 0, len(events))
+		for i, e := range events {
+			if i < cut && e.Timestamp.Before(before) {
+				removed++
+				continue
+			}
+			kept = append(kept, e)
+		}
+		r.s.events[taskID] = kept
+	}
+	return removed, nil
+}
+
+// ListOrphanedEventTaskIDs 列出有事件但任务已不存在、ID 大于 afterID 的任务 ID，按 ID 升序，最多 limit 个
+func (r *MemoryTaskRepository) ListOrphanedEventTaskIDs(afterID string, limit int) ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var ids []string
+	for taskID, events := range r.s.events {
+		if _, ok := r.s.tasks[taskID]; !ok && taskID > afterID && len(events) > 0 {
+			ids = append(ids, taskID)
+		}
+	}
+	sort.Strings(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+// DeleteOrphanedEvents 删除 taskIDs 中任务已不存在的事件，返回删除的事件数
+func (r *MemoryTaskRepository) DeleteOrphanedEvents(taskIDs []string) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var removed int64
+	for _, taskID := range taskIDs {
+		if _, ok := r.s.tasks[taskID]; ok {
+			continue
+		}
+		removed += int64(len(r.s.events[taskID]))
+		delete(r.s.events, taskID)
+	}
+	return removed, nil
+}
+
+// SetCompletedAt 为缺少完成时间的终态任务补记完成时间，任务状态已不是 status 或已有完成时间时返回 ErrStatusMismatch
+func (r *MemoryTaskRepository) SetCompletedAt(taskID string, status model.TaskStatus, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || t.Status != status || t.CompletedAt != nil {
+		return ErrStatusMismatch
+	}
+	at = at.UTC()
+	t.CompletedAt = &at
+	t.Version++
+	return nil
+}
+
+// UpdateStatus 原子更新任务状态，进入终态时记录完成时间
+func (r *MemoryTaskRepository) UpdateStatus(id string, fromStatus, toStatus model.TaskStatus) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[id]
+	if !ok || t.Status != fromStatus {
+		return ErrStatusMismatch
+	}
+	now := model.Now()
+	t.Status = toStatus
+	t.UpdatedAt = now
+	t.Version++
+	if toStatus.IsTerminal() {
+		t.CompletedAt = &now
+	}
+	return nil
+}
+
+// UpdateStatusWithEvent 原子更新任务状态并记录事件
+func (r *MemoryTaskRepository) UpdateStatusWithEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message string) error {
+	return r.UpdateStatusWithInstanceEvent(taskID, fromStatus, toStatus, operator, message, "", nil)
+}
+
+// UpdateStatusWithCorrelatedEvent 原子更新任务状态并记录事件，事件记录引起变更的请求 ID
+func (r *MemoryTaskRepository) UpdateStatusWithCorrelatedEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, correlationID string) error {
+	return r.s.transition(taskID, fromStatus, toStatus, operator, message, "", correlationID, nil, func(t *model.Task) {
+		if fromStatus == model.TaskStatusPending {
+			t.BlockedReason = ""
+		}
+	})
+}
+
+// UpdateStatusWithInstanceEvent 原子更新任务状态并记录带调度器实例 ID 和元数据的事件，
+// 转为 RUNNING 时同时记录执行实例，离开 PENDING 时清除阻塞原因
+func (r *MemoryTaskRepository) UpdateStatusWithInstanceEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID string, meta map[string]string) error {
+	return r.s.transition(taskID, fromStatus, toStatus, operator, message, instanceID, "", meta, func(t *model.Task) {
+		if toStatus == model.TaskStatusRunning && instanceID != "" {
+			t.ExecutedBy = instanceID
+		}
+		if toStatus == model.TaskStatusRunning {
+			resetTaskProgress(t)
+		}
+		if fromStatus == model.TaskStatusPending {
+			t.BlockedReason = ""
+		}
+	})
+}
+
+// ClaimExclusive 认领任务（PENDING -> RUNNING），有满足互斥条件的其他 RUNNING 任务时返回包装了 ErrExclusionBusy 的错误
+func (r *MemoryTaskRepository) ClaimExclusive(taskID string, excl Exclusion, operator, message, instanceID string, meta map[string]string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// 持有锁期间完成检查和认领
+	for _, t := range r.s.tasks {
+		if t.Status == model.TaskStatusRunning && t.ID != taskID && excl.matches(t) {
+			return exclusionBusyError(excl, t)
+		}
+	}
+	return r.s.transitionLocked(taskID, model.TaskStatusPending, model.TaskStatusRunning, operator, message, instanceID, "", meta, func(t *model.Task) {
+		t.ExecutedBy = instanceID
+		t.BlockedReason = ""
+		resetTaskProgress(t)
+	})
+}
+
+// RunningConflict 检查是否有满足互斥条件的其他任务正在运行，有时返回包装了 ErrExclusionBusy 的错误
+func (r *MemoryTaskRepository) RunningConflict(taskID string, excl Exclusion) error {
+	if excl.IsZero() {
+		return nil
+	}
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, t := range r.s.tasks {
+		if t.Status == model.TaskStatusRunning && t.ID != taskID && excl.matches(t) {
+			return exclusionBusyError(excl, t)
+		}
+	}
+	return nil
+}
+
+// resetTaskProgress 与 SQLite 实现一致，开始新一次执行时清空上次执行上报的进度、心跳和子状态，阶段历史保留
+func resetTaskProgress(t *model.Task) {
+	t.Progress, t.ProgressMessage, t.HeartbeatAt = 0, "", nil
+	t.SubStatus = ""
+}
+
+// UpdateProgress 记录 RUNNING 任务的执行进度和说明，同时作为一次心跳；任务已不是 RUNNING 时返回 ErrStatusMismatch
+func (r *MemoryTaskRepository) UpdateProgress(taskID string, progress int32, message string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || t.Status != model.TaskStatusRunning {
+		return ErrStatusMismatch
+	}
+	heartbeatAt := at.UTC()
+	t.Progress, t.ProgressMessage, t.HeartbeatAt = progress, message, &heartbeatAt
+	t.Version++
+	return nil
+}
+
+// UpdateSubStatus 记录 RUNNING 任务进入的自定义阶段，同时作为一次心跳；进入新阶段时写入 task.sub_status_changed 事件，
+// 同名阶段只更新说明。任务已不是 RUNNING 时返回 ErrStatusMismatch
+func (r *MemoryTaskRepository) UpdateSubStatus(taskID, subStatus, message string, at time.Time, operator, instanceID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || t.Status != model.TaskStatusRunning {
+		return ErrStatusMismatch
+	}
+	at = at.UTC()
+	entered := t.EnterPhase(model.TaskPhase{Name: subStatus, Message: message, Attempt: t.RetryCount + 1, EnteredAt: at})
+	t.HeartbeatAt, t.UpdatedAt = &at, at
+	t.Version++
+	if entered {
+		r.s.appendTaskEvent(t, model.OutboxEventTaskSubStatusChanged, operator, model.PhaseEventMessage(subStatus, message), instanceID)
+	}
+	return nil
+}
+
+// RepairDependencies 修复 PENDING 任务的依赖并写入 task.dependencies_repaired 事件，错误与 SQLite 实现一致
+func (r *MemoryTaskRepository) RepairDependencies(taskID string, repairs []model.DependencyRepair, operator, reason, correlationID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || t.Status != model.TaskStatusPending {
+		return ErrStatusMismatch
+	}
+	if errs := t.RepairDependencies(repairs); len(errs) > 0 {
+		return fmt.Errorf("%w: %s", ErrDependenciesChanged, errs[0])
+	}
+	t.UpdatedAt = model.Now()
+	t.Version++
+	r.s.appendCorrelatedTaskEvent(t, model.OutboxEventTaskDependenciesRepaired, operator, model.DependencyRepairMessage(repairs, reason), "", correlationID)
+	return nil
+}
+
+// Heartbeat 记录 RUNNING 任务的执行器心跳时间；任务已不是 RUNNING 时返回 ErrStatusMismatch
+func (r *MemoryTaskRepository) Heartbeat(taskID string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || t.Status != model.TaskStatusRunning {
+		return ErrStatusMismatch
+	}
+	heartbeatAt := at.UTC()
+	t.HeartbeatAt = &heartbeatAt
+	t.Version++
+	return nil
+}
+
+// SetBlockedReason 记录 PENDING 任务暂不能调度的原因，reason 为空表示清除；任务已不是 PENDING 或原因未变化时忽略
+func (r *MemoryTaskRepository) SetBlockedReason(taskID, reason string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if t, ok := r.s.tasks[taskID]; ok && t.Status == model.TaskStatusPending && t.BlockedReason != reason {
+		t.BlockedReason = reason
+		t.Version++
+	}
+	return nil
+}
+
+// ScheduleRetry 把失败的任务从 fromStatus 重置为 PENDING，重试次数由 retryCount 加一，
+// 同时写入最近错误及其分类和最早可调度时间；状态或重试次数已变化时返回 ErrStatusMismatch
+func (r *MemoryTaskRepository) ScheduleRetry(taskID string, fromStatus model.TaskStatus, retryCount int32, nextRunAt *time.Time, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if t, ok := r.s.tasks[taskID]; !ok || t.RetryCount != retryCount {
+		return ErrStatusMismatch
+	}
+	return r.s.transitionLocked(taskID, fromStatus, model.TaskStatusPending, operator, message, instanceID, "", meta, func(t *model.Task) {
+		t.RetryCount = retryCount + 1
+		t.CompletedAt = nil
+		t.ErrorMessage = errMsg
+		t.ErrorClass = errClass
+		t.NextRunAt = nil
+		if nextRunAt != nil {
+			v := *nextRunAt
+			t.NextRunAt = &v
+		}
+	})
+}
+
+// FailTask 把执行失败的任务从 RUNNING 标记为 FAILED，同时写入错误和错误分类
+func (r *MemoryTaskRepository) FailTask(taskID, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error {
+	return r.s.transition(taskID, model.TaskStatusRunning, model.TaskStatusFailed, operator, message, instanceID, "", meta, func(t *model.Task) {
+		t.ErrorMessage = errMsg
+		t.ErrorClass = errClass
+		t.NextRunAt = nil
+	})
+}
+
+// CompleteTask 把任务从 fromStatus 标记为 SUCCEEDED，同时写入输出并清除上次失败的错误
+func (r *MemoryTaskRepository) CompleteTask(taskID string, fromStatus model.TaskStatus, output map[string]string, outputRef, operator, message, instanceID string, meta map[string]string) error {
+	return r.s.transition(taskID, fromStatus, model.TaskStatusSucceeded, operator, message, instanceID, "", meta, func(t *model.Task) {
+		t.OutputResult = maps.Clone(output)
+		t.OutputRef = outputRef
+		t.ErrorMessage = ""
+		t.ErrorClass = ""
+		t.NextRunAt = nil
+	})
+}
+
+// SuspendForInput 把执行器请求外部输入的任务从 RUNNING 挂起为 WAITING_INPUT，同时写入输入说明
+func (r *MemoryTaskRepository) SuspendForInput(taskID, prompt, operator, message, instanceID string, meta map[string]string) error {
+	return r.s.transition(taskID, model.TaskStatusRunning, model.TaskStatusWaitingInput, operator, message, instanceID, "", meta, func(t *model.Task) {
+		t.InputRequest = prompt
+	})
+}
+
+// SignalTask 向 WAITING_INPUT 的任务送达输入，按键合并后把任务重新置为 PENDING；任务已不在等待输入时返回 ErrStatusMismatch
+func (r *MemoryTaskRepository) SignalTask(taskID string, payload map[string]string, operator, message, correlationID string) error {
+	return r.s.transition(taskID, model.TaskStatusWaitingInput, model.TaskStatusPending, operator, message, "", correlationID, nil, func(t *model.Task) {
+		t.ApplySignal(payload, t.UpdatedAt)
+	})
+}
+
+// RerunTask 把已结束的任务从 fromStatus 原地重置为 PENDING，同时保存本次运行的结果，返回保存的运行序号
+func (r *MemoryTaskRepository) RerunTask(taskID string, fromStatus model.TaskStatus, operator, message, correlationID string) (int32, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t, ok := r.s.tasks[taskID]
+	if !ok || t.Status != fromStatus {
+		return 0, ErrStatusMismatch
+	}
+
+	run := int32(len(r.s.runs[taskID]) + 1)
+	record := cloneTask(t).ResetForRerun(run, model.Now())
+	err := r.s.transitionLocked(taskID, fromStatus, model.TaskStatusPending, operator, message, "", correlationID, nil, func(t *model.Task) {
+		t.ResetForRerun(run, t.UpdatedAt)
+	})
+	if err != nil {
+		return 0, err
+	}
+	r.s.runs[taskID] = append(r.s.runs[taskID], record)
+	return run, nil
+}
+
+// ListRuns 获取任务原地重新运行前保存的历次运行记录，按运行序号升序
+func (r *MemoryTaskRepository) ListRuns(taskID string) ([]model.TaskRun, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return cloneRuns(r.s.runs[taskID]), nil
+}
+
+// cloneRuns 复制运行记录，调用方修改返回值不影响仓储中的数据
+func cloneRuns(runs []model.TaskRun) []model.TaskRun {
+	if len(runs) == 0 {
+		return nil
+	}
+	c := make([]model.TaskRun, len(runs))
+	for i, run := range runs {
+		c[i] = run
+		c[i].OutputResult = maps.Clone(run.OutputResult)
+	}
+	return c
+}
+
+// transition 条件状态变更，同时记录状态事件和发件箱事件；correlationID 为空时事件沿用任务的请求 ID
+func (s *memoryState) transition(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID, correlationID string, meta map[string]string, apply func(*model.Task)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transitionLocked(taskID, fromStatus, toStatus, operator, message, instanceID, correlationID, meta, apply)
+}
+
+// transitionLocked 同 transition，调用方须持有写锁
+func (s *memoryState) transitionLocked(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID, correlationID string, meta map[string]string, apply func(*model.Task)) error {
+	t, ok := s.tasks[taskID]
+	if !ok || t.Status != fromStatus {
+		return ErrStatusMismatch
+	}
+	now := model.Now()
+	t.Status = toStatus
+	t.UpdatedAt = now
+	t.Version++
+	if toStatus.IsTerminal() {
+		t.CompletedAt = &now
+	}
+	apply(t)
+	if correlationID == "" {
+		correlationID = t.CorrelationID
+	}
+
+	eventID := idgen.NewID()
+	s.appendEvent(model.TaskEvent{
+		ID:            eventID,
+		TaskID:        taskID,
+		FromStatus:    fromStatus,
+		ToStatus:      toStatus,
+		Message:       message,
+		Timestamp:     now,
+		Operator:      operator,
+		InstanceID:    instanceID,
+		CorrelationID: correlationID,
+		Metadata:      meta,
+	})
+	s.appendOutbox(&model.OutboxEvent{
+		ID:            eventID,
+		TaskID:        taskID,
+		EventType:     model.OutboxEventTaskStatusChanged,
+		FromStatus:    fromStatus,
+		ToStatus:      toStatus,
+		Message:       message,
+		Operator:      operator,
+		InstanceID:    instanceID,
+		CorrelationID: correlationID,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	})
+	return nil
+}
+
+// ListSLABreachCandidates 列出截止时间已过、尚未记录违约的任务，条件与 SQLite 实现一致
+func (r *MemoryTaskRepository) ListSLABreachCandidates(now, since time.Time, limit int) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool {
+		if t.SLABreachedAt != nil || t.SLADeadline == nil || t.SLADeadline.After(now) {
+			return false
+		}
+		return !t.IsTerminal() || t.SLADeadline.After(since)
+	}, bySLADeadline)
+	return paginate(tasks, limit, 0), nil
+}
+
+// MarkSLABreached 记录任务违约时间并写入事件和 task.sla_breached 发件箱事件
+func (r *MemoryTaskRepository) MarkSLABreached(taskID string, at time.Time, operator, message, instanceID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || t.SLABreachedAt != nil {
+		return ErrSLAAlreadyMarked
+	}
+	breachedAt := at
+	t.SLABreachedAt = &breachedAt
+	t.Version++
+
+	eventID := idgen.NewID()
+	r.s.appendEvent(model.TaskEvent{
+		ID:            eventID,
+		TaskID:        taskID,
+		FromStatus:    t.Status,
+		ToStatus:      t.Status,
+		Message:       message,
+		Timestamp:     at,
+		Operator:      operator,
+		InstanceID:    instanceID,
+		CorrelationID: t.CorrelationID,
+	})
+	r.s.appendOutbox(&model.OutboxEvent{
+		ID:            eventID,
+		TaskID:        taskID,
+		EventType:     model.OutboxEventTaskSLABreached,
+		FromStatus:    t.Status,
+		ToStatus:      t.Status,
+		Message:       message,
+		Operator:      operator,
+		InstanceID:    instanceID,
+		CorrelationID: t.CorrelationID,
+		CreatedAt:     at,
+		NextAttemptAt: at,
+	})
+	return nil
+}
+
+// ListWaitingOnDependencies 列出优先级不低于 minPriority、依赖尚未全部结束的 PENDING 任务，条件与 SQLite 实现一致
+func (r *MemoryTaskRepository) ListWaitingOnDependencies(minPriority model.TaskPriority, limit int) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool {
+		return t.Status == model.TaskStatusPending && t.Priority >= minPriority && len(t.Dependencies) > 0 && !r.s.dependenciesSettled(t)
+	}, func(a, b *model.Task) bool {
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return paginate(tasks, limit, 0), nil
+}
+
+// BoostPriority 临时提升 PENDING 任务的优先级并写入事件和 task.priority_boosted 发件箱事件
+func (r *MemoryTaskRepository) BoostPriority(taskID string, priority model.TaskPriority, operator, message, instanceID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || t.Status != model.TaskStatusPending || !t.BoostPriority(priority, model.Now()) {
+		return ErrStatusMismatch
+	}
+	t.Version++
+	r.s.appendTaskEvent(t, model.OutboxEventTaskPriorityBoosted, operator, message, instanceID)
+	return nil
+}
+
+// RestorePriority 恢复任务被提升前的优先级并写入事件和 task.priority_restored 发件箱事件
+func (r *MemoryTaskRepository) RestorePriority(taskID, operator, message, instanceID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || !t.RestorePriority(model.Now()) {
+		return ErrStatusMismatch
+	}
+	t.Version++
+	r.s.appendTaskEvent(t, model.OutboxEventTaskPriorityRestored, operator, message, instanceID)
+	return nil
+}
+
+// ListQueueTimeCandidates 列出排队时间不晚于 queuedBefore、尚未记录排队超时的 PENDING 任务，条件与 SQLite 实现一致
+func (r *MemoryTaskRepository) ListQueueTimeCandidates(queuedBefore time.Time, taskTypes, excludeTypes []string, limit int) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool {
+		if t.Status != model.TaskStatusPending || t.StartedAt != nil || t.QueueAlertedAt != nil || t.QueuedSince().After(queuedBefore) {
+			return false
+		}
+		sameType := func(taskType string) bool { return strings.EqualFold(taskType, t.TaskType) }
+		if len(taskTypes) > 0 && !slices.ContainsFunc(taskTypes, sameType) {
+			return false
+		}
+		return !slices.ContainsFunc(excludeTypes, sameType)
+	}, byQueuedSince)
+	return paginate(tasks, limit, 0), nil
+}
+
+// MarkQueueTimeExceeded 记录任务排队超时，escalateTo 高于任务自身优先级时提高优先级，并写入事件和 task.queue_time_exceeded 发件箱事件
+func (r *MemoryTaskRepository) MarkQueueTimeExceeded(taskID string, at time.Time, escalateTo model.TaskPriority, operator, message, instanceID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tasks[taskID]
+	if !ok || t.Status != model.TaskStatusPending || t.StartedAt != nil || t.QueueAlertedAt != nil {
+		return ErrStatusMismatch
+	}
+	alertedAt := at
+	t.QueueAlertedAt = &alertedAt
+	t.EscalatePriority(escalateTo, at)
+	t.UpdatedAt = at
+	t.Version++
+	r.s.appendTaskEvent(t, model.OutboxEventTaskQueueTimeExceeded, operator, message, instanceID)
+	return nil
+}
+
+// ListWorkflowDeadlineExceeded 列出所属工作流的截止时间不晚于 now、尚未结束的任务，按截止时间升序
+func (r *MemoryTaskRepository) ListWorkflowDeadlineExceeded(now time.Time, limit int) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool { return t.WorkflowExpired(now) }, byWorkflowDeadline)
+	return paginate(tasks, limit, 0), nil
+}
+
+// ListSLATasks 列出截止时间在 [from, to] 内的任务，按截止时间升序
+func (r *MemoryTaskRepository) ListSLATasks(from, to time.Time, limit int) ([]*model.Task, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tasks := r.s.sortedTasks(func(t *model.Task) bool {
+		return t.SLADeadline != nil && !t.SLADeadline.Before(from) && !t.SLADeadline.After(to)
+	}, bySLADeadline)
+	return paginate(tasks, limit, 0), nil
+}
+
+// bySLADeadline 按 SLA 截止时间升序
+func bySLADeadline(a, b *model.Task) bool { return a.SLADeadline.Before(*b.SLADeadline) }
+
+// byWorkflowDeadline 按工作流截止时间升序
+func byWorkflowDeadline(a, b *model.Task) bool { return a.WorkflowDeadline.Before(*b.WorkflowDeadline) }
+
+// byQueuedSince 按开始排队的时间升序
+func byQueuedSince(a, b *model.Task) bool { return a.QueuedSince().Before(b.QueuedSince()) }
+
+// AddComment 添加任务评论
+func (r *MemoryTaskRepository) AddComment(comment *model.TaskComment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.comments[comment.TaskID] = append(r.s.comments[comment.TaskID], *comment)
+	return nil
+}
+
+// GetCommentsByTaskID 获取任务的所有评论（按时间顺序）
+func (r *MemoryTaskRepository) GetCommentsByTaskID(taskID string) ([]model.TaskComment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return append([]model.TaskComment(nil), r.s.comments[taskID]...), nil
+}
+
+// AddAttachment 保存附件元数据
+func (r *MemoryTaskRepository) AddAttachment(a *model.TaskAttachment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored := *a
+	r.s.attachments[a.TaskID] = append(r.s.attachments[a.TaskID], &stored)
+	return nil
+}
+
+// GetAttachment 获取任务的单个附件，不存在时返回 nil
+func (r *MemoryTaskRepository) GetAttachment(taskID, id string) (*model.TaskAttachment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, a := range r.s.attachments[taskID] {
+		if a.ID == id {
+			c := *a
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListAttachments 按上传时间列出任务附件
+func (r *MemoryTaskRepository) ListAttachments(taskID string) ([]*model.TaskAttachment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var attachments []*model.TaskAttachment
+	for _, a := range r.s.attachments[taskID] {
+		c := *a
+		attachments = append(attachments, &c)
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment 删除附件元数据
+func (r *MemoryTaskRepository) DeleteAttachment(taskID, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	list := r.s.attachments[taskID]
+	for i, a := range list {
+		if a.ID == id {
+			r.s.attachments[taskID] = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// AppendLogs 批量写入任务日志行，(task_id, seq) 重复时整批失败
+func (r *MemoryTaskRepository) AppendLogs(lines []model.TaskLogLine) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	seen := make(map[string]map[int64]bool)
+	for _, l := range lines {
+		if seen[l.TaskID] == nil {
+			seen[l.TaskID] = make(map[int64]bool)
+			for _, existing := range r.s.logs[l.TaskID] {
+				seen[l.TaskID][existing.Seq] = true
+			}
+		}
+		if seen[l.TaskID][l.Seq] {
+			return fmt.Errorf("log line %s/%d: %w", l.TaskID, l.Seq, errDuplicateKey)
+		}
+		seen[l.TaskID][l.Seq] = true
+	}
+
+	for _, l := range lines {
+		r.s.logs[l.TaskID] = append(r.s.logs[l.TaskID], l)
+	}
+	for taskID := range seen {
+		logs := r.s.logs[taskID]
+		sort.SliceStable(logs, func(i, j int) bool { return logs[i].Seq < logs[j].Seq })
+	}
+	return nil
+}
+
+// GetLogs 获取 seq 大于 afterSeq 的日志行，按 seq 升序，limit <= 0 表示不限制
+func (r *MemoryTaskRepository) GetLogs(taskID string, afterSeq int64, limit int) ([]model.TaskLogLine, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var lines []model.TaskLogLine
+	for _, l := range r.s.logs[taskID] {
+		if l.Seq <= afterSeq {
+			continue
+		}
+		if limit > 0 && len(lines) >= limit {
+			break
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// LastLogSeq 获取任务最新日志行的 seq，没有日志时返回 0
+func (r *MemoryTaskRepository) LastLogSeq(taskID string) (int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	logs := r.s.logs[taskID]
+	if len(logs) == 0 {
+		return 0, nil
+	}
+	return logs[len(logs)-1].Seq, nil
+}
+
+// TrimLogs 只保留任务最近 keep 行日志（环形缓冲）
+func (r *MemoryTaskRepository) TrimLogs(taskID string, keep int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	logs := r.s.logs[taskID]
+	if len(logs) == 0 {
+		return nil
+	}
+	cutoff := logs[len(logs)-1].Seq - keep
+	i := sort.Search(len(logs), func(i int) bool { return logs[i].Seq > cutoff })
+	r.s.logs[taskID] = append([]model.TaskLogLine(nil), logs[i:]...)
+	return nil
+}
+
+// UpsertSchedulerInstance 写入或更新调度器实例心跳
+func (r *MemoryTaskRepository) UpsertSchedulerInstance(inst *model.SchedulerInstance) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored := *inst
+	stored.InFlightTasks = append([]string(nil), inst.InFlightTasks...)
+	stored.Labels = maps.Clone(inst.Labels)
+	if existing, ok := r.s.instances[inst.ID]; ok {
+		stored.Hostname = existing.Hostname
+		stored.StartedAt = existing.StartedAt
+	}
+	r.s.instances[inst.ID] = &stored
+	return nil
+}
+
+// ListSchedulerInstances 列出调度器实例，按启动时间升序
+func (r *MemoryTaskRepository) ListSchedulerInstances() ([]*model.SchedulerInstance, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	instances := make([]*model.SchedulerInstance, 0, len(r.s.instances))
+	for _, inst := range r.s.instances {
+		c := *inst
+		c.InFlightTasks = append([]string(nil), inst.InFlightTasks...)
+		c.Labels = maps.Clone(inst.Labels)
+		instances = append(instances, &c)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if !instances[i].StartedAt.Equal(instances[j].StartedAt) {
+			return instances[i].StartedAt.Before(instances[j].StartedAt)
+		}
+		return instances[i].ID < instances[j].ID
+	})
+	return instances, nil
+}
+
+// DeleteSchedulerInstance 删除调度器实例（正常停止时调用）
+func (r *MemoryTaskRepository) DeleteSchedulerInstance(id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.instances, id)
+	return nil
+}
+
+// SaveSchedulerCheckpoint 写入或覆盖调度器检查点
+func (r *MemoryTaskRepository) SaveSchedulerCheckpoint(cp *model.SchedulerCheckpoint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored := *cp
+	r.s.checkpoints[cp.Name] = &stored
+	return nil
+}
+
+// GetSchedulerCheckpoint 读取调度器检查点，不存在时返回 nil
+func (r *MemoryTaskRepository) GetSchedulerCheckpoint(name string) (*model.SchedulerCheckpoint, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	cp, ok := r.s.checkpoints[name]
+	if !ok {
+		return nil, nil
+	}
+	c := *cp
+	return &c, nil
+}
+
+// ListDueOutboxEvents 列出到期待投递的发件箱事件，按写入顺序。
+// 同一任务较早的事件仍在退避等待时，其后的事件不会列出，以保持任务内的投递顺序
+func (r *MemoryTaskRepository) ListDueOutboxEvents(now time.Time, limit int) ([]*model.OutboxEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var events []*model.OutboxEvent
+	blocked := make(map[string]bool)
+	for _, e := range r.s.outbox {
+		if e.DeliveredAt != nil {
+			continue
+		}
+		if e.NextAttemptAt.After(now) {
+			blocked[e.TaskID] = true
+			continue
+		}
+		if blocked[e.TaskID] {
+			continue
+		}
+		if len(events) >= limit {
+			break
+		}
+		c := *e
+		events = append(events, &c)
+	}
+	return events, nil
+}
+
+// MarkOutboxEventDelivered 标记发件箱事件已投递
+func (r *MemoryTaskRepository) MarkOutboxEventDelivered(id string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if e := r.s.findOutboxEvent(id); e != nil {
+		e.DeliveredAt = &at
+		e.Attempts++
+		e.LastError = ""
+	}
+	return nil
+}
+
+// MarkOutboxEventFailed 记录投递失败并安排下次重试
+func (r *MemoryTaskRepository) MarkOutboxEventFailed(id string, lastErr string, nextAttemptAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if e := r.s.findOutboxEvent(id); e != nil {
+		e.Attempts++
+		e.LastError = lastErr
+		e.NextAttemptAt = nextAttemptAt
+	}
+	return nil
+}
+
+// CountPendingOutboxEvents 未投递的发件箱事件数
+func (r *MemoryTaskRepository) CountPendingOutboxEvents() (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	count := 0
+	for _, e := range r.s.outbox {
+		if e.DeliveredAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// PurgeDeliveredOutboxEvents 删除在 before 之前已投递的发件箱事件
+func (r *MemoryTaskRepository) PurgeDeliveredOutboxEvents(before time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	kept := r.s.outbox[:0]
+	var purged int64
+	for _, e := range r.s.outbox {
+		if e.DeliveredAt != nil && e.DeliveredAt.Before(before) {
+			purged++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	r.s.outbox = kept
+	return purged, nil
+}
+
+// ListOutboxEventsAfter 按序号升序列出序号大于 afterSeq 的发件箱事件，不论是否已投递
+func (r *MemoryTaskRepository) ListOutboxEventsAfter(afterSeq int64, limit int) ([]*model.OutboxEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var events []*model.OutboxEvent
+	for _, e := range r.s.outbox {
+		if e.Seq <= afterSeq {
+			continue
+		}
+		if len(events) >= limit {
+			break
+		}
+		c := *e
+		events = append(events, &c)
+	}
+	return events, nil
+}
+
+// OutboxSeqRange 返回仍保留的最小序号和已分配的最大序号；事件都已清理时 first 为 last+1
+func (r *MemoryTaskRepository) OutboxSeqRange() (first, last int64, err error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if len(r.s.outbox) == 0 {
+		return r.s.outboxSeq + 1, r.s.outboxSeq, nil
+	}
+	return r.s.outbox[0].Seq, r.s.outboxSeq, nil
+}
+
+// appendEvent 分配序号并写入任务事件，调用方需持有写锁
+func (s *memoryState) appendEvent(event model.TaskEvent) {
+	s.eventSeq++
+	event.Seq = s.eventSeq
+	event.Timestamp = event.Timestamp.UTC()
+	event.Metadata = maps.Clone(event.Metadata)
+	s.events[event.TaskID] = append(s.events[event.TaskID], event)
+}
+
+// dependenciesSettled 与 SQLite 的 ready_tasks 视图一致：上游全部结束，且未成功的上游都不按 wait 处理。
+// 上游不存在时视为已结束，由调度器检查依赖时报错；调用方需持有读锁
+func (s *memoryState) dependenciesSettled(t *model.Task) bool {
+	for _, id := range t.Dependencies {
+		up, ok := s.tasks[id]
+		if !ok {
+			continue
+		}
+		if !up.Status.IsTerminal() {
+			return false
+		}
+		if up.Status != model.TaskStatusSucceeded && t.DependencyPolicy(id) == model.DependencyFailureWait {
+			return false
+		}
+	}
+	return true
+}
+
+// appendTaskEvent 为不改变状态的变更写入任务事件和 eventType 类型的发件箱事件，时间取任务的更新时间，调用方需持有写锁
+func (s *memoryState) appendTaskEvent(t *model.Task, eventType, operator, message, instanceID string) {
+	s.appendCorrelatedTaskEvent(t, eventType, operator, message, instanceID, "")
+}
+
+// appendCorrelatedTaskEvent 同 appendTaskEvent，事件记录引起变更的请求 ID，为空时沿用任务的
+func (s *memoryState) appendCorrelatedTaskEvent(t *model.Task, eventType, operator, message, instanceID, correlationID string) {
+	if correlationID == "" {
+		correlationID = t.CorrelationID
+	}
+	eventID := idgen.NewID()
+	s.appendEvent(model.TaskEvent{
+		ID:            eventID,
+		TaskID:        t.ID,
+		FromStatus:    t.Status,
+		ToStatus:      t.Status,
+		Message:       message,
+		Timestamp:     t.UpdatedAt,
+		Operator:      operator,
+		InstanceID:    instanceID,
+		CorrelationID: correlationID,
+	})
+	s.appendOutbox(&model.OutboxEvent{
+		ID:            eventID,
+		TaskID:        t.ID,
+		EventType:     eventType,
+		FromStatus:    t.Status,
+		ToStatus:      t.Status,
+		Message:       message,
+		Operator:      operator,
+		InstanceID:    instanceID,
+		CorrelationID: correlationID,
+		CreatedAt:     t.UpdatedAt,
+		NextAttemptAt: t.UpdatedAt,
+	})
+}
+
+// appendOutbox 分配序号并写入发件箱事件，调用方需持有写锁
+func (s *memoryState) appendOutbox(event *model.OutboxEvent) {
+	s.outboxSeq++
+	event.Seq = s.outboxSeq
+	s.outbox = append(s.outbox, event)
+}
+
+func (s *memoryState) findOutboxEvent(id string) *model.OutboxEvent {
+	for _, e := range s.outbox {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// isMember 用户是否是团队成员
+func (s *memoryState) isMember(teamID, userID string) bool {
+	team, ok := s.teams[teamID]
+	if !ok {
+		return false
+	}
+	for _, m := range team.Members {
+		if m == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Create 创建团队（连同初始成员），团队名称重复时返回错误
+func (r *MemoryTeamRepository) Create(team *model.Team) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.teams {
+		if existing.ID == team.ID || existing.Name == team.Name {
+			return fmt.Errorf("team %s: %w", team.Name, errDuplicateKey)
+		}
+	}
+	stored := *team
+	stored.Members = nil
+	for _, userID := range team.Members {
+		if !containsString(stored.Members, userID) {
+			stored.Members = append(stored.Members, userID)
+		}
+	}
+	r.s.teams[team.ID] = &stored
+	return nil
+}
+
+// GetByID 根据 ID 获取团队（含成员），不存在时返回 nil
+func (r *MemoryTeamRepository) GetByID(id string) (*model.Team, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	team, ok := r.s.teams[id]
+	if !ok {
+		return nil, nil
+	}
+	c := *team
+	c.Members = append([]string(nil), team.Members...)
+	return &c, nil
+}
+
+// Delete 删除团队及其成员关系
+func (r *MemoryTeamRepository) Delete(id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.teams, id)
+	return nil
+}
+
+// AddMember 添加团队成员（已存在时忽略）
+func (r *MemoryTeamRepository) AddMember(teamID, userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if team, ok := r.s.teams[teamID]; ok && !containsString(team.Members, userID) {
+		team.Members = append(team.Members, userID)
+	}
+	return nil
+}
+
+// RemoveMember 移除团队成员
+func (r *MemoryTeamRepository) RemoveMember(teamID, userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	team, ok := r.s.teams[teamID]
+	if !ok {
+		return nil
+	}
+	members := team.Members[:0:0]
+	for _, m := range team.Members {
+		if m != userID {
+			members = append(members, m)
+		}
+	}
+	team.Members = members
+	return nil
+}
+
+// ListMembers 列出团队成员
+func (r *MemoryTeamRepository) ListMembers(teamID string) ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if team, ok := r.s.teams[teamID]; ok {
+		return append([]string(nil), team.Members...), nil
+	}
+	return nil, nil
+}
+
+// ListTeamIDsByUser 列出用户所属的团队 ID
+func (r *MemoryTeamRepository) ListTeamIDsByUser(userID string) ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var ids []string
+	for id := range r.s.teams {
+		if r.s.isMember(id, userID) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// ListByUser 列出用户所属的团队
+func (r *MemoryTeamRepository) ListByUser(userID string) ([]*model.Team, error) {
+	ids, err := r.ListTeamIDsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := make([]*model.Team, 0, len(ids))
+	for _, id := range ids {
+		team, err := r.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if team != nil {
+			teams = append(teams, team)
+		}
+	}
+	return teams, nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MemorySecretRepository 线程安全的内存密钥仓储
+type MemorySecretRepository struct {
+	mu      sync.RWMutex
+	secrets map[string]*model.Secret
+}
+
+// NewMemorySecretRepository 创建内存密钥仓储
+func NewMemorySecretRepository() *MemorySecretRepository {
+	return &MemorySecretRepository{secrets: make(map[string]*model.Secret)}
+}
+
+var _ SecretStore = (*MemorySecretRepository)(nil)
+
+// cloneSecret 复制密钥，避免调用方修改密文
+func cloneSecret(s *model.Secret) *model.Secret {
+	c := *s
+	c.Ciphertext = append([]byte(nil), s.Ciphertext...)
+	return &c
+}
+
+// Put 创建或覆盖密钥，覆盖时保留创建者和创建时间
+func (r *MemorySecretRepository) Put(secret *model.Secret) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := cloneSecret(secret)
+	if existing, ok := r.secrets[secret.Name]; ok {
+		c.CreatedBy = existing.CreatedBy
+		c.CreatedAt = existing.CreatedAt
+	}
+	r.secrets[secret.Name] = c
+	return nil
+}
+
+// Get 根据名称获取密钥，不存在时返回 nil
+func (r *MemorySecretRepository) Get(name string) (*model.Secret, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if s, ok := r.secrets[name]; ok {
+		return cloneSecret(s), nil
+	}
+	return nil, nil
+}
+
+// List 按名称列出所有密钥
+func (r *MemorySecretRepository) List() ([]*model.Secret, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	secrets := make([]*model.Secret, 0, len(r.secrets))
+	for _, s := range r.secrets {
+		secrets = append(secrets, cloneSecret(s))
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets, nil
+}
+
+// Delete 删除密钥，不存在时返回 false
+func (r *MemorySecretRepository) Delete(name string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.secrets[name]; !ok {
+		return false, nil
+	}
+	delete(r.secrets, name)
+	return true, nil
+}
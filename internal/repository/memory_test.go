@@ -0,0 +1,1839 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// forEachStore 对 SQLite 和内存实现运行同一组用例
+func forEachStore(t *testing.T, fn func(t *testing.T, tasks TaskStore, teams TeamStore)) {
+	t.Run("sqlite", func(t *testing.T) {
+		db, cleanup := setupTestDB(t)
+		defer cleanup()
+		fn(t, NewTaskRepository(db), NewTeamRepository(db))
+	})
+	t.Run("memory", func(t *testing.T) {
+		tasks, teams := NewMemoryRepositories()
+		fn(t, tasks, teams)
+	})
+}
+
+func newStoreTask(id string, priority model.TaskPriority, createdAt time.Time) *model.Task {
+	return &model.Task{
+		ID:          id,
+		Name:        "task " + id,
+		Status:      model.TaskStatusPending,
+		Priority:    priority,
+		TaskType:    "shell",
+		InputParams: map[string]string{"cmd": "echo"},
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+		CreatedBy:   "alice",
+	}
+}
+
+func TestTaskStore_Contract(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		low := newStoreTask("low", model.TaskPriorityLow, base)
+		high := newStoreTask("high", model.TaskPriorityHigh, base.Add(time.Minute))
+		for _, task := range []*model.Task{low, high} {
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		if err := tasks.Create(newStoreTask("low", model.TaskPriorityLow, base)); err == nil {
+			t.Error("expected error creating duplicate task")
+		}
+
+		if got, err := tasks.GetByID("missing"); !errors.Is(err, ErrTaskNotFound) || got != nil {
+			t.Errorf("expected ErrTaskNotFound for missing task, got %v, %v", got, err)
+		}
+		if got, err := FindTask(tasks, "missing"); err != nil || got != nil {
+			t.Errorf("expected nil, nil from FindTask, got %v, %v", got, err)
+		}
+
+		// 返回值是副本，修改不影响仓储
+		got, _ := tasks.GetByID("low")
+		got.InputParams["cmd"] = "changed"
+		if again, _ := tasks.GetByID("low"); again.InputParams["cmd"] != "echo" {
+			t.Errorf("expected stored task to be unaffected, got %q", again.InputParams["cmd"])
+		}
+
+		pending, _ := tasks.ListPending(10, time.Now())
+		if len(pending) != 2 || pending[0].ID != "high" {
+			t.Fatalf("expected high priority task first, got %v", pending)
+		}
+
+		// 状态变更写入事件和发件箱
+		if err := tasks.UpdateStatusWithInstanceEvent("high", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("failed to update status: %v", err)
+		}
+		if err := tasks.UpdateStatusWithEvent("high", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start"); err == nil {
+			t.Error("expected status mismatch error")
+		}
+		nextRunAt := time.Now().Add(time.Hour)
+		if err := tasks.ScheduleRetry("high", model.TaskStatusRunning, 0, &nextRunAt, "boom", model.ErrorClassRetryable, "scheduler", "retry", "inst-1", nil); err != nil {
+			t.Fatalf("failed to schedule retry: %v", err)
+		}
+		got, _ = tasks.GetByID("high")
+		if got.ExecutedBy != "inst-1" || got.RetryCount != 1 || got.ErrorClass != model.ErrorClassRetryable || len(got.Events) != 3 {
+			t.Errorf("unexpected task after retry: %+v", got)
+		}
+		pending, _ = tasks.ListPending(10, time.Now())
+		if len(pending) != 1 || pending[0].ID != "low" {
+			t.Errorf("expected backed-off task to be skipped, got %v", pending)
+		}
+		if n, _ := tasks.CountPendingOutboxEvents(); n != 2 {
+			t.Errorf("expected 2 outbox events, got %d", n)
+		}
+
+		// 团队过滤
+		if err := teams.Create(&model.Team{ID: "team-1", Name: "ops", CreatedAt: base, Members: []string{"bob"}}); err != nil {
+			t.Fatalf("failed to create team: %v", err)
+		}
+		low.TeamID = "team-1"
+		low.UpdatedAt = time.Now()
+		if err := tasks.Update(low); err != nil {
+			t.Fatalf("failed to update task: %v", err)
+		}
+		list, total, err := tasks.ListByFilter(TaskFilter{MemberOf: "bob"})
+		if err != nil || total != 1 || len(list) != 1 || list[0].ID != "low" {
+			t.Errorf("expected only team task for member, got %v (total %d, err %v)", list, total, err)
+		}
+		list, total, _ = tasks.ListByFilter(TaskFilter{Keyword: "TASK", PageSize: 1, PageIndex: 1})
+		if total != 2 || len(list) != 1 || list[0].ID != "low" {
+			t.Errorf("unexpected keyword page: %v (total %d)", list, total)
+		}
+
+		// 日志环形缓冲
+		var lines []model.TaskLogLine
+		for i := int64(1); i <= 5; i++ {
+			lines = append(lines, model.TaskLogLine{TaskID: "low", Seq: i, Timestamp: time.Now(), Line: fmt.Sprint(i)})
+		}
+		if err := tasks.AppendLogs(lines); err != nil {
+			t.Fatalf("failed to append logs: %v", err)
+		}
+		if err := tasks.AppendLogs(lines[:1]); err == nil {
+			t.Error("expected error appending duplicate log line")
+		}
+		if err := tasks.TrimLogs("low", 2); err != nil {
+			t.Fatalf("failed to trim logs: %v", err)
+		}
+		logs, _ := tasks.GetLogs("low", 0, 0)
+		if last, _ := tasks.LastLogSeq("low"); len(logs) != 2 || logs[0].Seq != 4 || last != 5 {
+			t.Errorf("unexpected logs after trim: %v (last %d)", logs, last)
+		}
+	})
+}
+
+func TestMemoryTaskRepository_ConcurrentAccess(t *testing.T) {
+	tasks, _ := NewMemoryRepositories()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("task-%d", i)
+			if err := tasks.Create(newStoreTask(id, model.TaskPriorityNormal, time.Now())); err != nil {
+				t.Errorf("failed to create task: %v", err)
+				return
+			}
+			if err := tasks.UpdateStatusWithEvent(id, model.TaskStatusPending, model.TaskStatusRunning, "test", ""); err != nil {
+				t.Errorf("failed to update status: %v", err)
+			}
+			tasks.ListPending(10, time.Now())
+		}(i)
+	}
+	wg.Wait()
+
+	running := model.TaskStatusRunning
+	if n, _ := tasks.Count(&running); n != 20 {
+		t.Errorf("expected 20 running tasks, got %d", n)
+	}
+}
+
+func TestTaskStore_ClaimExclusive(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		now := time.Now()
+		for _, id := range []string{"backup-1", "backup-2"} {
+			task := newStoreTask(id, model.TaskPriorityNormal, now)
+			task.TaskType = "backup"
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		singleton := Exclusion{TaskTypes: []string{"backup"}}
+		if err := tasks.ClaimExclusive("backup-1", singleton, "scheduler", "task scheduled", "inst-1", nil); err != nil {
+			t.Fatalf("failed to claim first task: %v", err)
+		}
+		err := tasks.ClaimExclusive("backup-2", singleton, "scheduler", "task scheduled", "inst-1", nil)
+		if !errors.Is(err, ErrExclusionBusy) {
+			t.Fatalf("expected ErrExclusionBusy, got %v", err)
+		}
+		if err := tasks.SetBlockedReason("backup-2", err.Error()); err != nil {
+			t.Fatalf("failed to set blocked reason: %v", err)
+		}
+		got, _ := tasks.GetByID("backup-2")
+		if got.Status != model.TaskStatusPending || !strings.Contains(got.BlockedReason, "backup-1") {
+			t.Errorf("unexpected blocked task: status=%s reason=%q", got.Status, got.BlockedReason)
+		}
+
+		// 运行中的任务结束后可以认领，阻塞原因被清除
+		if err := tasks.UpdateStatusWithEvent("backup-1", model.TaskStatusRunning, model.TaskStatusSucceeded, "scheduler", "done"); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if err := tasks.ClaimExclusive("backup-2", singleton, "scheduler", "task scheduled", "inst-1", nil); err != nil {
+			t.Fatalf("failed to claim second task: %v", err)
+		}
+		got, _ = tasks.GetByID("backup-2")
+		if got.Status != model.TaskStatusRunning || got.BlockedReason != "" || got.ExecutedBy != "inst-1" || len(got.Events) != 2 {
+			t.Errorf("unexpected claimed task: %+v", got)
+		}
+
+		// 分组键相同的任务互斥，不同类型也不例外；无关任务不受影响
+		for _, id := range []string{"deploy-a", "migrate-a", "deploy-b"} {
+			task := newStoreTask(id, model.TaskPriorityNormal, now)
+			task.TaskType = strings.Split(id, "-")[0]
+			task.GroupKey = "env-" + strings.Split(id, "-")[1]
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		if err := tasks.ClaimExclusive("deploy-a", Exclusion{GroupKey: "env-a"}, "scheduler", "task scheduled", "inst-1", nil); err != nil {
+			t.Fatalf("failed to claim grouped task: %v", err)
+		}
+		err = tasks.ClaimExclusive("migrate-a", Exclusion{GroupKey: "env-a"}, "scheduler", "task scheduled", "inst-1", nil)
+		if !errors.Is(err, ErrExclusionBusy) || !strings.Contains(err.Error(), "deploy-a in group env-a") {
+			t.Fatalf("expected group conflict, got %v", err)
+		}
+		// 只读检查与认领失败时的说明一致
+		if conflict := tasks.RunningConflict("migrate-a", Exclusion{GroupKey: "env-a"}); conflict == nil || conflict.Error() != err.Error() {
+			t.Errorf("RunningConflict = %v, want %v", conflict, err)
+		}
+		if conflict := tasks.RunningConflict("deploy-a", Exclusion{GroupKey: "env-a"}); conflict != nil {
+			t.Errorf("expected a task not to conflict with itself, got %v", conflict)
+		}
+		if err := tasks.ClaimExclusive("deploy-b", Exclusion{GroupKey: "env-b", TaskTypes: []string{"migrate"}}, "scheduler", "task scheduled", "inst-1", nil); err != nil {
+			t.Fatalf("failed to claim task in another group: %v", err)
+		}
+		if got, _ := tasks.GetByID("deploy-a"); got.GroupKey != "env-a" {
+			t.Errorf("expected group key to be persisted, got %q", got.GroupKey)
+		}
+	})
+}
+
+func TestTaskStore_DurationHistory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		base := time.Now().Truncate(time.Second).Add(-time.Hour)
+		add := func(id, taskType string, status model.TaskStatus, offset, duration time.Duration) {
+			task := newStoreTask(id, model.TaskPriorityNormal, base)
+			task.TaskType = taskType
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+			started, completed := base.Add(offset), base.Add(offset+duration)
+			task.Status = status
+			task.StartedAt, task.CompletedAt = &started, &completed
+			if err := tasks.Update(task); err != nil {
+				t.Fatalf("failed to update task: %v", err)
+			}
+		}
+		add("etl-1", "etl", model.TaskStatusSucceeded, 0, 10*time.Second)
+		add("etl-2", "etl", model.TaskStatusSucceeded, time.Minute, 20*time.Second)
+		add("etl-3", "etl", model.TaskStatusSucceeded, 2*time.Minute, 30*time.Second)
+		add("etl-failed", "etl", model.TaskStatusFailed, 3*time.Minute, time.Second)
+		add("report-1", "report", model.TaskStatusSucceeded, 0, time.Minute)
+
+		got, err := tasks.RecentDurations("etl", 2)
+		if err != nil {
+			t.Fatalf("RecentDurations: %v", err)
+		}
+		want := []time.Duration{30 * time.Second, 20 * time.Second}
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("RecentDurations = %v, want %v", got, want)
+		}
+		if got, _ := tasks.RecentDurations("unknown", 10); len(got) != 0 {
+			t.Errorf("expected no durations for unknown type, got %v", got)
+		}
+
+		types, err := tasks.ListTaskTypes()
+		if err != nil {
+			t.Fatalf("ListTaskTypes: %v", err)
+		}
+		if len(types) != 2 || types[0] != "etl" || types[1] != "report" {
+			t.Errorf("ListTaskTypes = %v, want [etl report]", types)
+		}
+
+		counts, err := tasks.CountOutcomesByType(base.Add(2*time.Minute), base.Add(4*time.Minute))
+		if err != nil {
+			t.Fatalf("CountOutcomesByType: %v", err)
+		}
+		if len(counts) != 1 || counts["etl"] != (OutcomeCount{Succeeded: 1, Failed: 1}) {
+			t.Errorf("CountOutcomesByType = %v, want etl 1/1", counts)
+		}
+		if counts, _ := tasks.CountOutcomesByType(base, base.Add(time.Minute)); counts["etl"].Total() != 1 || counts["report"].Total() != 0 {
+			t.Errorf("completion at the end of the window should be excluded, got %v", counts)
+		}
+	})
+}
+
+func TestTaskStore_SLA(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		now := time.Now().Truncate(time.Millisecond)
+		add := func(id string, deadline time.Duration) *model.Task {
+			task := newStoreTask(id, model.TaskPriorityNormal, now)
+			d := now.Add(deadline)
+			task.SLADeadline = &d
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+			return task
+		}
+		add("late", -time.Minute)
+		add("future", time.Hour)
+		done := add("done-long-ago", -48*time.Hour)
+		done.Status = model.TaskStatusSucceeded
+		if err := tasks.Update(done); err != nil {
+			t.Fatalf("failed to update task: %v", err)
+		}
+
+		got, _ := tasks.GetByID("late")
+		if got.SLADeadline == nil || !got.SLADeadline.Equal(now.Add(-time.Minute)) {
+			t.Fatalf("expected SLA deadline to be persisted, got %v", got.SLADeadline)
+		}
+
+		// 已结束且截止时间早于 since 的任务不再列出
+		candidates, err := tasks.ListSLABreachCandidates(now, now.Add(-24*time.Hour), 10)
+		if err != nil {
+			t.Fatalf("ListSLABreachCandidates: %v", err)
+		}
+		if len(candidates) != 1 || candidates[0].ID != "late" {
+			t.Fatalf("unexpected candidates: %v", candidates)
+		}
+
+		if err := tasks.MarkSLABreached("late", now, "sla-monitor", "deadline exceeded", "inst-1"); err != nil {
+			t.Fatalf("MarkSLABreached: %v", err)
+		}
+		if err := tasks.MarkSLABreached("late", now, "sla-monitor", "deadline exceeded", "inst-1"); !errors.Is(err, ErrSLAAlreadyMarked) {
+			t.Fatalf("expected ErrSLAAlreadyMarked, got %v", err)
+		}
+		got, _ = tasks.GetByID("late")
+		if got.SLABreachedAt == nil || len(got.Events) != 2 || got.Events[1].ToStatus != model.TaskStatusPending {
+			t.Errorf("unexpected breached task: %+v", got)
+		}
+		// 整体更新不清除违约记录
+		if err := tasks.Update(got); err != nil {
+			t.Fatalf("failed to update task: %v", err)
+		}
+		if got, _ := tasks.GetByID("late"); got.SLABreachedAt == nil {
+			t.Error("Update should keep the SLA breach")
+		}
+		if candidates, _ := tasks.ListSLABreachCandidates(now, now.Add(-24*time.Hour), 10); len(candidates) != 0 {
+			t.Errorf("breached task should not be listed again: %v", candidates)
+		}
+
+		inWindow, err := tasks.ListSLATasks(now.Add(-time.Hour), now.Add(2*time.Hour), 10)
+		if err != nil {
+			t.Fatalf("ListSLATasks: %v", err)
+		}
+		if len(inWindow) != 2 || inWindow[0].ID != "late" || inWindow[1].ID != "future" {
+			t.Errorf("unexpected SLA tasks: %v", inWindow)
+		}
+	})
+}
+
+func TestSecretStore_Contract(t *testing.T) {
+	run := func(t *testing.T, store SecretStore) {
+		created := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := store.Put(&model.Secret{Name: "token", Ciphertext: []byte{1, 2, 3}, CreatedBy: "alice", CreatedAt: created, UpdatedAt: created}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+
+		// 覆盖只更新密文和更新时间
+		updated := created.Add(time.Minute)
+		if err := store.Put(&model.Secret{Name: "token", Ciphertext: []byte{4, 5}, CreatedBy: "bob", CreatedAt: updated, UpdatedAt: updated}); err != nil {
+			t.Fatalf("Put overwrite: %v", err)
+		}
+		got, err := store.Get("token")
+		if err != nil || got == nil {
+			t.Fatalf("Get = %v, %v", got, err)
+		}
+		if string(got.Ciphertext) != string([]byte{4, 5}) || got.CreatedBy != "alice" || !got.CreatedAt.Equal(created) || !got.UpdatedAt.Equal(updated) {
+			t.Errorf("unexpected secret after overwrite: %+v", got)
+		}
+
+		store.Put(&model.Secret{Name: "another", Ciphertext: []byte{6}, CreatedAt: created, UpdatedAt: created})
+		list, err := store.List()
+		if err != nil || len(list) != 2 || list[0].Name != "another" || list[1].Name != "token" {
+			t.Fatalf("List = %v, %v", list, err)
+		}
+
+		if ok, err := store.Delete("token"); err != nil || !ok {
+			t.Fatalf("Delete = %v, %v", ok, err)
+		}
+		if ok, _ := store.Delete("token"); ok {
+			t.Error("expected second delete to report missing")
+		}
+		if got, err := store.Get("token"); err != nil || got != nil {
+			t.Errorf("Get deleted = %v, %v", got, err)
+		}
+	}
+
+	t.Run("sqlite", func(t *testing.T) {
+		db, cleanup := setupTestDB(t)
+		defer cleanup()
+		run(t, NewSecretRepository(db))
+	})
+	t.Run("memory", func(t *testing.T) {
+		run(t, NewMemorySecretRepository())
+	})
+}
+
+func TestTaskStore_CorrelationID(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		task := newStoreTask("traced", model.TaskPriorityNormal, time.Now())
+		task.CorrelationID = "req-create"
+		if err := tasks.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		other := newStoreTask("untraced", model.TaskPriorityNormal, time.Now())
+		if err := tasks.Create(other); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+
+		// 后台产生的事件沿用任务的请求 ID，请求引起的事件记录该请求
+		if err := tasks.UpdateStatusWithInstanceEvent("traced", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("UpdateStatusWithInstanceEvent: %v", err)
+		}
+		if err := tasks.UpdateStatusWithCorrelatedEvent("traced", model.TaskStatusRunning, model.TaskStatusCancelled, "system", "cancel", "req-cancel"); err != nil {
+			t.Fatalf("UpdateStatusWithCorrelatedEvent: %v", err)
+		}
+		if err := tasks.AddEvent(&model.TaskEvent{ID: "manual", TaskID: "traced", Message: "note", Timestamp: time.Now()}); err != nil {
+			t.Fatalf("AddEvent: %v", err)
+		}
+
+		events, err := tasks.GetEventsByTaskID("traced")
+		if err != nil {
+			t.Fatalf("GetEventsByTaskID: %v", err)
+		}
+		want := map[string]string{"task created": "req-create", "start": "req-create", "cancel": "req-cancel", "note": "req-create"}
+		if len(events) != len(want) {
+			t.Fatalf("expected %d events, got %d", len(want), len(events))
+		}
+		for _, e := range events {
+			if e.CorrelationID != want[e.Message] {
+				t.Errorf("event %q correlation_id = %q, want %q", e.Message, e.CorrelationID, want[e.Message])
+			}
+		}
+
+		outbox, _ := tasks.ListDueOutboxEvents(time.Now().Add(time.Minute), 10)
+		if len(outbox) != 2 || outbox[0].CorrelationID != "req-create" || outbox[1].CorrelationID != "req-cancel" {
+			t.Errorf("unexpected outbox correlation IDs: %+v", outbox)
+		}
+
+		// 请求 ID 只在创建时记录，整体更新不覆盖
+		got, _ := tasks.GetByID("traced")
+		got.CorrelationID = ""
+		if err := tasks.Update(got); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, _ := tasks.GetByID("traced"); got.CorrelationID != "req-create" {
+			t.Errorf("correlation_id = %q, want req-create", got.CorrelationID)
+		}
+
+		list, total, err := tasks.ListByFilter(TaskFilter{CorrelationID: "req-create"})
+		if err != nil {
+			t.Fatalf("ListByFilter: %v", err)
+		}
+		if total != 1 || len(list) != 1 || list[0].ID != "traced" {
+			t.Errorf("expected only the traced task, got %d", total)
+		}
+	})
+}
+
+func TestTaskStore_VisibleTo(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		if err := teams.Create(&model.Team{ID: "team-1", Name: "ops", CreatedAt: time.Now(), Members: []string{"bob"}}); err != nil {
+			t.Fatalf("failed to create team: %v", err)
+		}
+		for _, spec := range []struct{ id, createdBy, teamID string }{
+			{"own", "bob", ""},
+			{"team", "alice", "team-1"},
+			{"other-team", "alice", "team-2"},
+			{"unassigned", "alice", ""},
+		} {
+			task := newStoreTask(spec.id, model.TaskPriorityNormal, time.Now())
+			task.CreatedBy = spec.createdBy
+			task.TeamID = spec.teamID
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		ids := func(filter TaskFilter) map[string]bool {
+			list, total, err := tasks.ListByFilter(filter)
+			if err != nil {
+				t.Fatalf("ListByFilter: %v", err)
+			}
+			got := make(map[string]bool, len(list))
+			for _, task := range list {
+				got[task.ID] = true
+			}
+			if total != len(list) {
+				t.Errorf("total %d does not match %d tasks", total, len(list))
+			}
+			return got
+		}
+		if got := ids(TaskFilter{VisibleTo: "bob"}); len(got) != 2 || !got["own"] || !got["team"] {
+			t.Errorf("expected own and team tasks, got %v", got)
+		}
+		if got := ids(TaskFilter{VisibleTo: "bob", IncludeUnassigned: true}); len(got) != 3 || !got["unassigned"] {
+			t.Errorf("expected unassigned tasks to be included, got %v", got)
+		}
+	})
+}
+
+func TestTaskStore_ListByFilterSort(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		at := func(minutes int) *time.Time {
+			ts := base.Add(time.Duration(minutes) * time.Minute)
+			return &ts
+		}
+		for _, spec := range []struct {
+			id        string
+			priority  model.TaskPriority
+			status    model.TaskStatus
+			created   int
+			updated   int
+			completed *time.Time
+		}{
+			{"a", model.TaskPriorityHigh, model.TaskStatusPending, 0, 3, nil},
+			{"b", model.TaskPriorityLow, model.TaskStatusSucceeded, 1, 1, at(5)},
+			{"c", model.TaskPriorityNormal, model.TaskStatusFailed, 2, 2, at(4)},
+			{"d", model.TaskPriorityNormal, model.TaskStatusRunning, 2, 0, nil},
+		} {
+			task := newStoreTask(spec.id, spec.priority, *at(spec.created))
+			task.Status = spec.status
+			task.UpdatedAt = *at(spec.updated)
+			task.CompletedAt = spec.completed
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		for _, tc := range []struct {
+			sortBy TaskSortField
+			desc   bool
+			want   string
+		}{
+			{"", false, "acdb"},
+			{SortByCreatedAt, false, "abcd"},
+			{SortByCreatedAt, true, "cdba"},
+			{SortByUpdatedAt, false, "dbca"},
+			{SortByPriority, false, "bcda"},
+			{SortByStatus, true, "cbda"},
+			{SortByCompletedAt, false, "cbad"},
+			{SortByCompletedAt, true, "bcad"},
+		} {
+			list, _, err := tasks.ListByFilter(TaskFilter{SortBy: tc.sortBy, SortDesc: tc.desc})
+			if err != nil {
+				t.Fatalf("ListByFilter(%q): %v", tc.sortBy, err)
+			}
+			got := ""
+			for _, task := range list {
+				got += task.ID
+			}
+			if got != tc.want {
+				t.Errorf("sort_by=%q desc=%v: got order %s, want %s", tc.sortBy, tc.desc, got, tc.want)
+			}
+		}
+
+		if _, _, err := tasks.ListByFilter(TaskFilter{SortBy: "name; DROP TABLE tasks"}); !errors.Is(err, ErrInvalidSortField) {
+			t.Errorf("expected ErrInvalidSortField, got %v", err)
+		}
+	})
+}
+
+func TestTaskStore_ListByFilterTimeRange(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		base := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+		at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+		for _, spec := range []struct {
+			id               string
+			created, updated int
+			completed        int // 小于 0 表示未完成
+		}{
+			{"old", 0, 2, 1},
+			{"mid", 5, 6, 6},
+			{"new", 10, 10, -1},
+			{"recent", 11, 12, 12},
+		} {
+			task := newStoreTask(spec.id, model.TaskPriorityNormal, at(spec.created))
+			task.UpdatedAt = at(spec.updated)
+			if spec.completed >= 0 {
+				completed := at(spec.completed)
+				task.CompletedAt = &completed
+			}
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		for _, tc := range []struct {
+			name   string
+			filter TaskFilter
+			want   string
+		}{
+			{"created range", TaskFilter{CreatedAfter: at(5), CreatedBefore: at(11)}, "mid,new"},
+			{"created before is exclusive", TaskFilter{CreatedBefore: at(5)}, "old"},
+			{"completed after", TaskFilter{CompletedAfter: at(6)}, "mid,recent"},
+			{"completed before", TaskFilter{CompletedBefore: at(6)}, "old"},
+			{"updated since", TaskFilter{UpdatedSince: at(10)}, "new,recent"},
+			{"combined", TaskFilter{CreatedAfter: at(1), CompletedAfter: at(1)}, "mid,recent"},
+		} {
+			tc.filter.SortBy = SortByCreatedAt
+			list, total, err := tasks.ListByFilter(tc.filter)
+			if err != nil {
+				t.Fatalf("%s: ListByFilter: %v", tc.name, err)
+			}
+			ids := make([]string, len(list))
+			for i, task := range list {
+				ids[i] = task.ID
+			}
+			if got := strings.Join(ids, ","); got != tc.want || total != len(list) {
+				t.Errorf("%s: got %s (total %d), want %s", tc.name, got, total, tc.want)
+			}
+		}
+	})
+}
+
+func TestTaskStore_TerminalTransitionRecordsCompletion(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		for _, id := range []string{"ok", "failed"} {
+			if err := tasks.Create(newStoreTask(id, model.TaskPriorityNormal, time.Now())); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+			if err := tasks.UpdateStatusWithInstanceEvent(id, model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+				t.Fatalf("failed to start task: %v", err)
+			}
+		}
+		if got, _ := tasks.GetByID("ok"); got.CompletedAt != nil {
+			t.Errorf("expected running task to have no completion time, got %v", got.CompletedAt)
+		}
+
+		if err := tasks.UpdateStatusWithInstanceEvent("ok", model.TaskStatusRunning, model.TaskStatusSucceeded, "scheduler", "done", "inst-1", nil); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if err := tasks.FailTask("failed", "boom", model.ErrorClassFatal, "scheduler", "failed", "inst-1", nil); err != nil {
+			t.Fatalf("failed to fail task: %v", err)
+		}
+		for _, id := range []string{"ok", "failed"} {
+			if got, _ := tasks.GetByID(id); got.CompletedAt == nil {
+				t.Errorf("expected %s task to record its completion time", id)
+			}
+		}
+	})
+}
+
+func TestTaskStore_CompleteTask(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		for _, id := range []string{"ok", "cancelled"} {
+			task := newStoreTask(id, model.TaskPriorityNormal, time.Now())
+			task.ErrorMessage = "previous attempt failed"
+			task.ErrorClass = model.ErrorClassRetryable
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+			if err := tasks.UpdateStatusWithInstanceEvent(id, model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+				t.Fatalf("failed to start task: %v", err)
+			}
+		}
+
+		if err := tasks.CompleteTask("ok", model.TaskStatusRunning, map[string]string{"result": "42"}, "", "scheduler", "done", "inst-1", nil); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		got, _ := tasks.GetByID("ok")
+		if got.Status != model.TaskStatusSucceeded || got.OutputResult["result"] != "42" || got.CompletedAt == nil {
+			t.Errorf("unexpected completed task: %+v", got)
+		}
+		if got.ErrorMessage != "" || got.ErrorClass != "" {
+			t.Errorf("expected previous error to be cleared, got %q (%s)", got.ErrorMessage, got.ErrorClass)
+		}
+		events, _ := tasks.GetEventsByTaskID("ok")
+		if last := events[len(events)-1]; last.ToStatus != model.TaskStatusSucceeded || last.InstanceID != "inst-1" {
+			t.Errorf("unexpected completion event: %+v", last)
+		}
+
+		// 任务在执行中被取消后，迟到的结果不覆盖取消
+		if err := tasks.UpdateStatusWithEvent("cancelled", model.TaskStatusRunning, model.TaskStatusCancelled, "alice", "cancel"); err != nil {
+			t.Fatalf("failed to cancel task: %v", err)
+		}
+		if err := tasks.CompleteTask("cancelled", model.TaskStatusRunning, map[string]string{"result": "late"}, "", "scheduler", "done", "inst-1", nil); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("expected ErrStatusMismatch completing a cancelled task, got %v", err)
+		}
+		got, _ = tasks.GetByID("cancelled")
+		if got.Status != model.TaskStatusCancelled || len(got.OutputResult) != 0 {
+			t.Errorf("expected cancelled task without output, got %s %v", got.Status, got.OutputResult)
+		}
+	})
+}
+
+func TestTaskStore_ProgressAndHeartbeat(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		task := newStoreTask("progress", model.TaskPriorityNormal, time.Now())
+		task.ParentID = "parent"
+		if err := tasks.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		at := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+		if err := tasks.UpdateProgress("progress", 10, "queued", at); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("expected ErrStatusMismatch for a PENDING task, got %v", err)
+		}
+		if err := tasks.UpdateStatusWithInstanceEvent("progress", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("failed to start task: %v", err)
+		}
+
+		if err := tasks.UpdateProgress("progress", 40, "copying", at); err != nil {
+			t.Fatalf("UpdateProgress: %v", err)
+		}
+		if err := tasks.Heartbeat("progress", at.Add(time.Minute)); err != nil {
+			t.Fatalf("Heartbeat: %v", err)
+		}
+		got, _ := tasks.GetByID("progress")
+		if got.ParentID != "parent" || got.Progress != 40 || got.ProgressMessage != "copying" {
+			t.Errorf("unexpected progress: parent=%q progress=%d message=%q", got.ParentID, got.Progress, got.ProgressMessage)
+		}
+		if got.HeartbeatAt == nil || !got.HeartbeatAt.Equal(at.Add(time.Minute)) {
+			t.Errorf("HeartbeatAt = %v, want %s", got.HeartbeatAt, at.Add(time.Minute))
+		}
+
+		// 重试开始新一次执行时清空上次的进度和心跳
+		if err := tasks.ScheduleRetry("progress", model.TaskStatusRunning, 0, nil, "boom", model.ErrorClassRetryable, "scheduler", "retry", "inst-1", nil); err != nil {
+			t.Fatalf("ScheduleRetry: %v", err)
+		}
+		if err := tasks.UpdateStatusWithInstanceEvent("progress", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("failed to restart task: %v", err)
+		}
+		got, _ = tasks.GetByID("progress")
+		if got.Progress != 0 || got.ProgressMessage != "" || got.HeartbeatAt != nil {
+			t.Errorf("expected progress reset on a new attempt, got %d %q %v", got.Progress, got.ProgressMessage, got.HeartbeatAt)
+		}
+	})
+}
+
+func TestTaskStore_SubStatus(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		task := newStoreTask("phased", model.TaskPriorityNormal, time.Now())
+		if err := tasks.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		at := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+		if err := tasks.UpdateSubStatus("phased", "UPLOADING", "", at, "scheduler", "inst-1"); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("expected ErrStatusMismatch for a PENDING task, got %v", err)
+		}
+		if err := tasks.UpdateStatusWithInstanceEvent("phased", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("failed to start task: %v", err)
+		}
+		_, lastSeq, _ := tasks.OutboxSeqRange()
+
+		// 同名阶段只更新说明，进入新阶段时才写入事件
+		for _, step := range []struct{ subStatus, message string }{
+			{"UPLOADING", "chunk 1/2"}, {"UPLOADING", "chunk 2/2"}, {"VALIDATING", ""},
+		} {
+			if err := tasks.UpdateSubStatus("phased", step.subStatus, step.message, at, "scheduler", "inst-1"); err != nil {
+				t.Fatalf("UpdateSubStatus(%s): %v", step.subStatus, err)
+			}
+			at = at.Add(time.Minute)
+		}
+		got, _ := tasks.GetByID("phased")
+		if got.Status != model.TaskStatusRunning || got.SubStatus != "VALIDATING" || len(got.Phases) != 2 ||
+			got.Phases[0].Message != "chunk 2/2" || got.Phases[0].Attempt != 1 || !got.Phases[1].EnteredAt.Equal(at.Add(-time.Minute)) {
+			t.Errorf("unexpected sub-status %q with phases %+v", got.SubStatus, got.Phases)
+		}
+		if got.HeartbeatAt == nil || !got.HeartbeatAt.Equal(at.Add(-time.Minute)) {
+			t.Errorf("HeartbeatAt = %v, want %s", got.HeartbeatAt, at.Add(-time.Minute))
+		}
+		if list, _, err := tasks.ListByFilter(TaskFilter{}); err != nil || len(list) != 1 || list[0].SubStatus != "VALIDATING" || len(list[0].Phases) != 2 {
+			t.Errorf("expected sub-status and phases in list results, got %v, %v", list, err)
+		}
+		events, _ := tasks.ListOutboxEventsAfter(lastSeq, 10)
+		if len(events) != 2 || events[0].EventType != model.OutboxEventTaskSubStatusChanged ||
+			events[0].Message != "entered phase UPLOADING: chunk 1/2" || events[1].Message != "entered phase VALIDATING" {
+			t.Errorf("unexpected sub-status events: %+v", events)
+		}
+
+		// 重试开始新一次执行时清空子状态，阶段历史保留
+		if err := tasks.ScheduleRetry("phased", model.TaskStatusRunning, 0, nil, "boom", model.ErrorClassRetryable, "scheduler", "retry", "inst-1", nil); err != nil {
+			t.Fatalf("ScheduleRetry: %v", err)
+		}
+		if err := tasks.UpdateStatusWithInstanceEvent("phased", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("failed to restart task: %v", err)
+		}
+		if err := tasks.UpdateSubStatus("phased", "VALIDATING", "", at, "scheduler", "inst-1"); err != nil {
+			t.Fatalf("UpdateSubStatus: %v", err)
+		}
+		got, _ = tasks.GetByID("phased")
+		if got.SubStatus != "VALIDATING" || len(got.Phases) != 3 || got.Phases[2].Attempt != 2 {
+			t.Errorf("expected a new phase for the second attempt, got %q %+v", got.SubStatus, got.Phases)
+		}
+
+		// 原地重新运行时清空子状态和阶段历史
+		if err := tasks.CompleteTask("phased", model.TaskStatusRunning, nil, "", "scheduler", "done", "inst-1", nil); err != nil {
+			t.Fatalf("CompleteTask: %v", err)
+		}
+		if got, _ = tasks.GetByID("phased"); got.SubStatus != "VALIDATING" {
+			t.Errorf("expected the last sub-status kept after completion, got %q", got.SubStatus)
+		}
+		if _, err := tasks.RerunTask("phased", model.TaskStatusSucceeded, "alice", "rerun", ""); err != nil {
+			t.Fatalf("RerunTask: %v", err)
+		}
+		if got, _ = tasks.GetByID("phased"); got.SubStatus != "" || len(got.Phases) != 0 {
+			t.Errorf("expected sub-status and phases reset on rerun, got %q %+v", got.SubStatus, got.Phases)
+		}
+	})
+}
+
+func TestTaskStore_Version(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		task := newStoreTask("versioned", model.TaskPriorityNormal, time.Now())
+		if err := tasks.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if task.Version != 1 {
+			t.Errorf("Create set version %d, want 1", task.Version)
+		}
+
+		version := func() int64 {
+			t.Helper()
+			got, err := tasks.GetByID("versioned")
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			return got.Version
+		}
+		want := int64(1)
+		steps := []struct {
+			name  string
+			write func() error
+			bumps bool
+		}{
+			{"block", func() error { return tasks.SetBlockedReason("versioned", "waiting") }, true},
+			{"same blocked reason", func() error { return tasks.SetBlockedReason("versioned", "waiting") }, false},
+			{"edit definition", func() error {
+				task.Description = "edited"
+				return tasks.UpdateDefinition(task, model.TaskStatusPending)
+			}, true},
+			{"start", func() error {
+				return tasks.UpdateStatusWithInstanceEvent("versioned", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil)
+			}, true},
+			{"progress", func() error { return tasks.UpdateProgress("versioned", 50, "half", time.Now()) }, true},
+			{"heartbeat", func() error { return tasks.Heartbeat("versioned", time.Now()) }, true},
+			{"sub-status", func() error {
+				return tasks.UpdateSubStatus("versioned", "UPLOADING", "", time.Now(), "scheduler", "inst-1")
+			}, true},
+			{"complete", func() error {
+				return tasks.CompleteTask("versioned", model.TaskStatusRunning, map[string]string{"ok": "1"}, "", "scheduler", "done", "inst-1", nil)
+			}, true},
+			{"mismatched transition", func() error {
+				if err := tasks.UpdateStatus("versioned", model.TaskStatusRunning, model.TaskStatusFailed); !errors.Is(err, ErrStatusMismatch) {
+					return fmt.Errorf("expected ErrStatusMismatch, got %v", err)
+				}
+				return nil
+			}, false},
+			{"rerun", func() error {
+				_, err := tasks.RerunTask("versioned", model.TaskStatusSucceeded, "alice", "rerun", "")
+				return err
+			}, true},
+		}
+		for _, step := range steps {
+			if err := step.write(); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if step.bumps {
+				want++
+			}
+			if got := version(); got != want {
+				t.Errorf("%s: version = %d, want %d", step.name, got, want)
+			}
+		}
+	})
+}
+
+func TestTaskStore_RerunTask(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		task := newStoreTask("rerun", model.TaskPriorityNormal, time.Now())
+		task.RetryCount = 1
+		if err := tasks.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if err := tasks.UpdateStatusWithInstanceEvent("rerun", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("failed to start task: %v", err)
+		}
+		if err := tasks.CompleteTask("rerun", model.TaskStatusRunning, map[string]string{"result": "42"}, "", "scheduler", "done", "inst-1", nil); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+
+		// 状态不符时不保存运行记录
+		if _, err := tasks.RerunTask("rerun", model.TaskStatusCancelled, "alice", "rerun", ""); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("expected ErrStatusMismatch, got %v", err)
+		}
+
+		run, err := tasks.RerunTask("rerun", model.TaskStatusSucceeded, "alice", "rerun", "req-1")
+		if err != nil || run != 1 {
+			t.Fatalf("RerunTask = %d, %v", run, err)
+		}
+		got, _ := tasks.GetByID("rerun")
+		if got.Status != model.TaskStatusPending || len(got.OutputResult) != 0 || got.RetryCount != 0 ||
+			got.CompletedAt != nil || got.ExecutedBy != "" {
+			t.Errorf("expected task reset to pending, got %+v", got)
+		}
+		if len(got.Runs) != 1 {
+			t.Fatalf("expected 1 saved run, got %d", len(got.Runs))
+		}
+		saved := got.Runs[0]
+		if saved.Run != 1 || saved.Status != model.TaskStatusSucceeded || saved.OutputResult["result"] != "42" ||
+			saved.RetryCount != 1 || saved.ExecutedBy != "inst-1" || saved.CompletedAt == nil {
+			t.Errorf("unexpected saved run: %+v", saved)
+		}
+		events, _ := tasks.GetEventsByTaskID("rerun")
+		if last := events[len(events)-1]; last.FromStatus != model.TaskStatusSucceeded || last.ToStatus != model.TaskStatusPending || last.CorrelationID != "req-1" {
+			t.Errorf("unexpected rerun event: %+v", last)
+		}
+
+		// 第二次运行被取消后再次重新运行，序号递增
+		if err := tasks.UpdateStatusWithEvent("rerun", model.TaskStatusPending, model.TaskStatusCancelled, "alice", "cancel"); err != nil {
+			t.Fatalf("failed to cancel task: %v", err)
+		}
+		if run, err := tasks.RerunTask("rerun", model.TaskStatusCancelled, "alice", "rerun", ""); err != nil || run != 2 {
+			t.Fatalf("second RerunTask = %d, %v", run, err)
+		}
+		runs, _ := tasks.ListRuns("rerun")
+		if len(runs) != 2 || runs[1].Status != model.TaskStatusCancelled || len(runs[1].OutputResult) != 0 {
+			t.Errorf("unexpected runs: %+v", runs)
+		}
+
+		// 克隆出的任务记录原任务
+		clone := got.CloneForRerun("bob")
+		clone.ID = "rerun-clone"
+		if err := tasks.Create(clone); err != nil {
+			t.Fatalf("failed to create clone: %v", err)
+		}
+		if got, _ := tasks.GetByID("rerun-clone"); got.RerunOf != "rerun" || got.Status != model.TaskStatusPending || len(got.Runs) != 0 {
+			t.Errorf("unexpected clone: %+v", got)
+		}
+	})
+}
+
+func TestTaskStore_SignalTask(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		task := newStoreTask("signal", model.TaskPriorityNormal, time.Now())
+		if err := tasks.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+
+		// 只有运行中的任务可以挂起，只有等待输入的任务可以接收信号
+		if err := tasks.SuspendForInput("signal", "approval", "scheduler", "waiting", "inst-1", nil); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("suspending a pending task: expected ErrStatusMismatch, got %v", err)
+		}
+		if err := tasks.SignalTask("signal", map[string]string{"approved": "yes"}, "alice", "signal", ""); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("signalling a pending task: expected ErrStatusMismatch, got %v", err)
+		}
+
+		for i, payload := range []map[string]string{{"approved": "yes", "by": "alice"}, {"by": "bob"}} {
+			if err := tasks.UpdateStatusWithInstanceEvent("signal", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+				t.Fatalf("failed to start task: %v", err)
+			}
+			if err := tasks.SuspendForInput("signal", "approval", "scheduler", "waiting", "inst-1", nil); err != nil {
+				t.Fatalf("SuspendForInput: %v", err)
+			}
+			got, _ := tasks.GetByID("signal")
+			if got.Status != model.TaskStatusWaitingInput || got.InputRequest != "approval" {
+				t.Errorf("round %d: unexpected suspended task: %+v", i, got)
+			}
+
+			if err := tasks.SignalTask("signal", payload, "alice", "signal", "req-1"); err != nil {
+				t.Fatalf("SignalTask: %v", err)
+			}
+			if err := tasks.SignalTask("signal", payload, "alice", "signal", ""); !errors.Is(err, ErrStatusMismatch) {
+				t.Errorf("round %d: second signal should not apply, got %v", i, err)
+			}
+		}
+
+		got, _ := tasks.GetByID("signal")
+		if got.Status != model.TaskStatusPending || got.InputRequest != "" {
+			t.Errorf("expected signalled task to be pending without an input request, got %+v", got)
+		}
+		if len(got.SignalInput) != 2 || got.SignalInput["approved"] != "yes" || got.SignalInput["by"] != "bob" {
+			t.Errorf("expected merged signal input, got %v", got.SignalInput)
+		}
+		events, _ := tasks.GetEventsByTaskID("signal")
+		if last := events[len(events)-1]; last.FromStatus != model.TaskStatusWaitingInput || last.ToStatus != model.TaskStatusPending || last.CorrelationID != "req-1" {
+			t.Errorf("unexpected signal event: %+v", last)
+		}
+	})
+}
+
+func TestTaskStore_Routing(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		task := newStoreTask("routed", model.TaskPriorityNormal, time.Now())
+		task.NodeSelector = map[string]string{"gpu": "true", "region": "eu"}
+		task.TargetWorker = "host-a"
+		if err := tasks.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		task.NodeSelector["region"] = "us"
+
+		got, err := tasks.GetByID("routed")
+		if err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+		if got.TargetWorker != "host-a" || len(got.NodeSelector) != 2 || got.NodeSelector["region"] != "eu" {
+			t.Errorf("expected routing to be persisted, got selector %v target %q", got.NodeSelector, got.TargetWorker)
+		}
+
+		// 就绪任务列表照常返回带路由约束的任务，由调度器按实例过滤
+		pending, _ := tasks.ListPending(10, time.Now())
+		if len(pending) != 1 || pending[0].TargetWorker != "host-a" {
+			t.Errorf("expected routed task in pending list, got %v", pending)
+		}
+	})
+}
+
+func TestTaskStore_PriorityInheritance(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		base := time.Now().Add(-time.Hour)
+		up := newStoreTask("up", model.TaskPriorityLow, base)
+		if err := tasks.Create(up); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		for i, p := range []model.TaskPriority{model.TaskPriorityUrgent, model.TaskPriorityNormal} {
+			down := newStoreTask(fmt.Sprintf("down-%d", i), p, base.Add(time.Minute))
+			down.Dependencies = []string{"up"}
+			if err := tasks.Create(down); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		// 只列出依赖未结束、优先级不低于阈值的任务
+		waiting, err := tasks.ListWaitingOnDependencies(model.TaskPriorityHigh, 10)
+		if err != nil {
+			t.Fatalf("ListWaitingOnDependencies: %v", err)
+		}
+		if len(waiting) != 1 || waiting[0].ID != "down-0" {
+			t.Fatalf("expected only down-0 to be waiting, got %v", waiting)
+		}
+
+		if err := tasks.BoostPriority("up", model.TaskPriorityHigh, "scheduler", "boost", "inst-1"); err != nil {
+			t.Fatalf("BoostPriority: %v", err)
+		}
+		if err := tasks.BoostPriority("up", model.TaskPriorityUrgent, "scheduler", "boost", "inst-1"); err != nil {
+			t.Fatalf("BoostPriority: %v", err)
+		}
+		if err := tasks.BoostPriority("up", model.TaskPriorityHigh, "scheduler", "boost", "inst-1"); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("boosting to a lower priority: expected ErrStatusMismatch, got %v", err)
+		}
+		got, _ := tasks.GetByID("up")
+		if got.Priority != model.TaskPriorityUrgent || got.BasePriority != model.TaskPriorityLow {
+			t.Errorf("expected URGENT boosted from LOW, got %s from %s", got.Priority, got.BasePriority)
+		}
+		if last := got.Events[len(got.Events)-1]; last.FromStatus != model.TaskStatusPending || last.ToStatus != model.TaskStatusPending || last.InstanceID != "inst-1" {
+			t.Errorf("unexpected boost event: %+v", last)
+		}
+
+		// 开始执行后恢复原优先级，只恢复一次
+		if err := tasks.UpdateStatusWithInstanceEvent("up", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("failed to start task: %v", err)
+		}
+		if err := tasks.BoostPriority("up", model.TaskPriorityUrgent, "scheduler", "boost", "inst-1"); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("boosting a running task: expected ErrStatusMismatch, got %v", err)
+		}
+		if err := tasks.RestorePriority("up", "scheduler", "restore", "inst-1"); err != nil {
+			t.Fatalf("RestorePriority: %v", err)
+		}
+		if err := tasks.RestorePriority("up", "scheduler", "restore", "inst-1"); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("restoring twice: expected ErrStatusMismatch, got %v", err)
+		}
+		got, _ = tasks.GetByID("up")
+		if got.Priority != model.TaskPriorityLow || got.BasePriority != model.TaskPriorityUnspecified {
+			t.Errorf("expected LOW after restore, got %s from %s", got.Priority, got.BasePriority)
+		}
+	})
+}
+
+func TestTaskStore_QueueTime(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		now := time.Now()
+		add := func(id, taskType string, createdAt time.Time, started bool) {
+			task := newStoreTask(id, model.TaskPriorityLow, createdAt)
+			task.TaskType = taskType
+			if started {
+				task.StartedAt = &createdAt // 已执行过、等待重试的任务
+			}
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		add("old-etl", "etl", now.Add(-3*time.Hour), false)
+		add("old-report", "report", now.Add(-2*time.Hour), false)
+		add("new-etl", "etl", now.Add(-30*time.Second), false)
+		add("retrying", "etl", now.Add(-3*time.Hour), true)
+
+		ids := func(taskTypes, excludeTypes []string) []string {
+			candidates, err := tasks.ListQueueTimeCandidates(now.Add(-time.Hour), taskTypes, excludeTypes, 10)
+			if err != nil {
+				t.Fatalf("ListQueueTimeCandidates: %v", err)
+			}
+			var ids []string
+			for _, c := range candidates {
+				ids = append(ids, c.ID)
+			}
+			return ids
+		}
+		if got := ids(nil, nil); !slices.Equal(got, []string{"old-etl", "old-report"}) {
+			t.Errorf("candidates = %v, want [old-etl old-report]", got)
+		}
+		if got := ids([]string{"etl"}, nil); !slices.Equal(got, []string{"old-etl"}) {
+			t.Errorf("etl candidates = %v, want [old-etl]", got)
+		}
+		if got := ids(nil, []string{"ETL"}); !slices.Equal(got, []string{"old-report"}) {
+			t.Errorf("non-etl candidates = %v, want [old-report]", got)
+		}
+
+		// 只记录一次；升级优先级与不升级
+		if err := tasks.MarkQueueTimeExceeded("old-etl", now, model.TaskPriorityHigh, "queue-monitor", "queued too long", "inst-1"); err != nil {
+			t.Fatalf("MarkQueueTimeExceeded: %v", err)
+		}
+		if err := tasks.MarkQueueTimeExceeded("old-etl", now, model.TaskPriorityHigh, "queue-monitor", "queued too long", "inst-1"); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("marking twice: expected ErrStatusMismatch, got %v", err)
+		}
+		if err := tasks.MarkQueueTimeExceeded("old-report", now, model.TaskPriorityUnspecified, "queue-monitor", "queued too long", "inst-1"); err != nil {
+			t.Fatalf("MarkQueueTimeExceeded: %v", err)
+		}
+		got, _ := tasks.GetByID("old-etl")
+		if got.QueueAlertedAt == nil || got.Priority != model.TaskPriorityHigh {
+			t.Errorf("expected alerted task escalated to HIGH, got %s alerted at %v", got.Priority, got.QueueAlertedAt)
+		}
+		if last := got.Events[len(got.Events)-1]; last.Message != "queued too long" || last.InstanceID != "inst-1" {
+			t.Errorf("unexpected queue time event: %+v", last)
+		}
+		if got, _ := tasks.GetByID("old-report"); got.QueueAlertedAt == nil || got.Priority != model.TaskPriorityLow {
+			t.Errorf("expected alerted task to keep priority LOW, got %s alerted at %v", got.Priority, got.QueueAlertedAt)
+		}
+		if got := ids(nil, nil); len(got) != 0 {
+			t.Errorf("alerted tasks should not be listed again, got %v", got)
+		}
+
+		// 原地重新运行后从重新排队的时间起算，可再次告警
+		if err := tasks.UpdateStatusWithInstanceEvent("old-etl", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("failed to start task: %v", err)
+		}
+		if err := tasks.CompleteTask("old-etl", model.TaskStatusRunning, nil, "", "scheduler", "done", "inst-1", nil); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if _, err := tasks.RerunTask("old-etl", model.TaskStatusSucceeded, "alice", "rerun", ""); err != nil {
+			t.Fatalf("RerunTask: %v", err)
+		}
+		got, _ = tasks.GetByID("old-etl")
+		if got.QueuedAt == nil || got.QueueAlertedAt != nil || got.QueueTime(time.Now()) > time.Minute {
+			t.Errorf("expected rerun task to be queued again, got queued at %v alerted at %v", got.QueuedAt, got.QueueAlertedAt)
+		}
+		candidates, _ := tasks.ListQueueTimeCandidates(time.Now().Add(time.Minute), []string{"etl"}, nil, 10)
+		if len(candidates) != 2 || candidates[1].ID != "old-etl" {
+			t.Errorf("expected rerun task to be listed after new-etl, got %v", candidates)
+		}
+	})
+}
+
+func TestTaskStore_WorkflowDeadline(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		now := time.Now().UTC().Truncate(time.Second)
+		add := func(id string, deadline time.Duration, status model.TaskStatus) {
+			task := newStoreTask(id, model.TaskPriorityNormal, now.Add(-time.Hour))
+			task.Status = status
+			if deadline != 0 {
+				d := now.Add(deadline)
+				task.WorkflowDeadline = &d
+			}
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		add("expired-later", -time.Minute, model.TaskStatusRunning)
+		add("expired-first", -time.Hour, model.TaskStatusPending)
+		add("waiting", -time.Minute, model.TaskStatusWaitingInput)
+		add("finished", -time.Hour, model.TaskStatusSucceeded)
+		add("not-yet", time.Hour, model.TaskStatusPending)
+		add("no-deadline", 0, model.TaskStatusPending)
+
+		got, _ := tasks.GetByID("not-yet")
+		if got.WorkflowDeadline == nil || !got.WorkflowDeadline.Equal(now.Add(time.Hour)) {
+			t.Errorf("expected the workflow deadline stored, got %v", got.WorkflowDeadline)
+		}
+		expired, err := tasks.ListWorkflowDeadlineExceeded(now, 10)
+		if err != nil {
+			t.Fatalf("ListWorkflowDeadlineExceeded: %v", err)
+		}
+		var ids []string
+		for _, task := range expired {
+			ids = append(ids, task.ID)
+		}
+		if ids[0] != "expired-first" || !slices.Equal(slices.Sorted(slices.Values(ids)), []string{"expired-first", "expired-later", "waiting"}) {
+			t.Errorf("expired = %v, want expired-first then expired-later and waiting", ids)
+		}
+
+		// 原地重新运行后不再受原工作流截止时间的限制
+		if err := tasks.UpdateStatusWithEvent("expired-later", model.TaskStatusRunning, model.TaskStatusCancelled, "workflow-monitor", "deadline exceeded"); err != nil {
+			t.Fatalf("failed to cancel task: %v", err)
+		}
+		if _, err := tasks.RerunTask("expired-later", model.TaskStatusCancelled, "alice", "rerun", ""); err != nil {
+			t.Fatalf("RerunTask: %v", err)
+		}
+		if got, _ := tasks.GetByID("expired-later"); got.WorkflowDeadline != nil {
+			t.Errorf("expected the rerun task to drop its workflow deadline, got %v", got.WorkflowDeadline)
+		}
+		if expired, _ := tasks.ListWorkflowDeadlineExceeded(now, 10); len(expired) != 2 {
+			t.Errorf("expected 2 expired tasks after the rerun, got %d", len(expired))
+		}
+	})
+}
+
+func TestTaskStore_UpdateDefinition(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		task := newStoreTask("def", model.TaskPriorityNormal, time.Now())
+		task.Labels = map[string]string{"env": "dev"}
+		if err := tasks.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if got, _ := tasks.GetByID("def"); got.Labels["env"] != "dev" {
+			t.Errorf("expected labels to be persisted, got %v", got.Labels)
+		}
+
+		// 调度器已认领任务，定义更新不覆盖状态
+		if err := tasks.UpdateStatusWithInstanceEvent("def", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", nil); err != nil {
+			t.Fatalf("failed to start task: %v", err)
+		}
+		update := *task
+		update.Name = "renamed"
+		update.Priority = model.TaskPriorityHigh
+		update.InputParams = map[string]string{"cmd": "date"}
+		update.MaxRetries = 5
+		update.Labels = map[string]string{"env": "prod", "team": "ops"}
+		if err := tasks.UpdateDefinition(&update, model.TaskStatusPending); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("expected ErrStatusMismatch for stale status, got %v", err)
+		}
+		if err := tasks.UpdateDefinition(&update, model.TaskStatusRunning); err != nil {
+			t.Fatalf("failed to update definition: %v", err)
+		}
+
+		got, _ := tasks.GetByID("def")
+		if got.Status != model.TaskStatusRunning || got.ExecutedBy != "inst-1" {
+			t.Errorf("expected execution state to be kept, got %s by %q", got.Status, got.ExecutedBy)
+		}
+		if got.Name != "renamed" || got.Priority != model.TaskPriorityHigh || got.InputParams["cmd"] != "date" || got.MaxRetries != 5 {
+			t.Errorf("unexpected definition after update: %+v", got)
+		}
+		if len(got.Labels) != 2 || got.Labels["env"] != "prod" {
+			t.Errorf("unexpected labels after update: %v", got.Labels)
+		}
+	})
+}
+
+func TestTaskStore_Delete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		now := time.Now().UTC().Truncate(time.Second)
+		upstream := newStoreTask("upstream", model.TaskPriorityNormal, now)
+		other := newStoreTask("other", model.TaskPriorityNormal, now)
+		downstream := newStoreTask("downstream", model.TaskPriorityNormal, now.Add(time.Second))
+		downstream.Dependencies = []string{"upstream", "other"}
+		downstream.DependencyPolicies = map[string]model.DependencyFailurePolicy{"upstream": model.DependencyFailureIgnore}
+		for _, task := range []*model.Task{upstream, other, downstream} {
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		// 关联数据：事件（创建时写入）、评论、附件、日志和一次运行记录
+		if err := tasks.AddComment(&model.TaskComment{ID: "c1", TaskID: "upstream", Author: "alice", Body: "lgtm", CreatedAt: now}); err != nil {
+			t.Fatalf("AddComment: %v", err)
+		}
+		if err := tasks.AddAttachment(&model.TaskAttachment{ID: "a1", TaskID: "upstream", Filename: "out.txt", StorageKey: "tasks/upstream/a1", CreatedAt: now}); err != nil {
+			t.Fatalf("AddAttachment: %v", err)
+		}
+		if err := tasks.AppendLogs([]model.TaskLogLine{{TaskID: "upstream", Seq: 1, Timestamp: now, Line: "hello"}}); err != nil {
+			t.Fatalf("AppendLogs: %v", err)
+		}
+		if err := tasks.UpdateStatusWithEvent("upstream", model.TaskStatusPending, model.TaskStatusRunning, "alice", "start"); err != nil {
+			t.Fatalf("failed to start task: %v", err)
+		}
+		if err := tasks.CompleteTask("upstream", model.TaskStatusRunning, nil, "tasks/upstream/output.json", "scheduler", "done", "", nil); err != nil {
+			t.Fatalf("CompleteTask: %v", err)
+		}
+		if _, err := tasks.RerunTask("upstream", model.TaskStatusSucceeded, "alice", "rerun", ""); err != nil {
+			t.Fatalf("RerunTask: %v", err)
+		}
+
+		// 仍被未结束的任务依赖时不删除任何数据，错误中列出这些任务
+		_, err := tasks.Delete("upstream", false, "alice", "req-1")
+		var dependentsErr *DependentsError
+		if !errors.Is(err, ErrTaskHasDependents) || !errors.As(err, &dependentsErr) || !strings.Contains(err.Error(), "downstream (PENDING)") {
+			t.Fatalf("expected a DependentsError naming downstream, got %v", err)
+		}
+		if want := []BlockedDependent{{ID: "downstream", Name: "task downstream", Status: model.TaskStatusPending}}; dependentsErr.TaskID != "upstream" ||
+			!slices.Equal(dependentsErr.Dependents, want) {
+			t.Errorf("unexpected blocked dependents of %s: %+v", dependentsErr.TaskID, dependentsErr.Dependents)
+		}
+		if got, err := tasks.GetByID("upstream"); err != nil || len(got.Events) == 0 || len(got.Comments) != 1 || len(got.Runs) != 1 {
+			t.Fatalf("expected upstream kept intact, got %+v (%v)", got, err)
+		}
+
+		before, _ := tasks.GetByID("downstream")
+		deleted, err := tasks.Delete("upstream", true, "alice", "req-1")
+		if err != nil {
+			t.Fatalf("forced Delete: %v", err)
+		}
+		// 附件和转存输出的对象 key 交给调用方清理
+		if !slices.Equal(deleted.AttachmentKeys, []string{"tasks/upstream/a1"}) || !slices.Equal(deleted.OutputRefs, []string{"tasks/upstream/output.json"}) ||
+			!slices.Equal(deleted.Dependents, []string{"downstream"}) {
+			t.Errorf("unexpected deleted task: %+v", deleted)
+		}
+		if _, err := tasks.GetByID("upstream"); !errors.Is(err, ErrTaskNotFound) {
+			t.Errorf("expected ErrTaskNotFound after delete, got %v", err)
+		}
+		after, _ := tasks.GetByID("downstream")
+		if !slices.Equal(after.Dependencies, []string{"other"}) || len(after.DependencyPolicies) != 0 || after.Version != before.Version+1 {
+			t.Errorf("expected the edge removed from downstream, got dependencies %v, policies %v, version %d -> %d",
+				after.Dependencies, after.DependencyPolicies, before.Version, after.Version)
+		}
+		if dependents, _ := tasks.ListDependents([]string{"upstream"}, nil); len(dependents) != 0 {
+			t.Errorf("expected no dependents of the deleted task, got %d", len(dependents))
+		}
+		// 移除依赖记录在下游任务的事件中
+		events, _ := tasks.GetEventsByTaskID("downstream")
+		if idx := slices.IndexFunc(events, func(e model.TaskEvent) bool { return e.Message == "dependency upstream removed: upstream task deleted" }); idx < 0 ||
+			events[idx].Operator != "alice" || events[idx].CorrelationID != "req-1" || events[idx].ToStatus != model.TaskStatusPending {
+			t.Errorf("expected the dependency removal recorded on downstream, got %+v", events)
+		}
+		outbox, _ := tasks.ListOutboxEventsAfter(0, 100)
+		if last := outbox[len(outbox)-1]; last.TaskID != "downstream" || last.EventType != model.OutboxEventTaskDependencyRemoved {
+			t.Errorf("unexpected dependency removal outbox event: %+v", last)
+		}
+
+		// 没有残留的关联数据，同 ID 重新创建的任务不会继承旧历史
+		if ids, _ := tasks.ListOrphanedEventTaskIDs("", 10); len(ids) != 0 {
+			t.Errorf("expected no orphaned events, got %v", ids)
+		}
+		if err := tasks.Create(newStoreTask("upstream", model.TaskPriorityNormal, now)); err != nil {
+			t.Fatalf("failed to recreate task: %v", err)
+		}
+		comments, _ := tasks.GetCommentsByTaskID("upstream")
+		attachments, _ := tasks.ListAttachments("upstream")
+		logs, _ := tasks.GetLogs("upstream", 0, 10)
+		runs, _ := tasks.ListRuns("upstream")
+		if events, _ := tasks.GetEventsByTaskID("upstream"); len(events) != 1 || len(comments)+len(attachments)+len(logs)+len(runs) != 0 {
+			t.Errorf("expected a clean history, got %d events, %d comments, %d attachments, %d log lines, %d runs",
+				len(events), len(comments), len(attachments), len(logs), len(runs))
+		}
+
+		// 下游任务结束后不再阻止删除，其依赖同样被移除
+		if err := tasks.UpdateStatusWithEvent("downstream", model.TaskStatusPending, model.TaskStatusCancelled, "alice", "cancel"); err != nil {
+			t.Fatalf("failed to cancel task: %v", err)
+		}
+		if deleted, err := tasks.Delete("other", false, "alice", ""); err != nil || len(deleted.AttachmentKeys)+len(deleted.OutputRefs) != 0 {
+			t.Fatalf("Delete with only finished dependents: %+v, %v", deleted, err)
+		}
+		if got, _ := tasks.GetByID("downstream"); len(got.Dependencies) != 0 {
+			t.Errorf("expected no dependencies left on downstream, got %v", got.Dependencies)
+		}
+	})
+}
+
+func TestTaskStore_RepairDependencies(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		now := time.Now().UTC().Truncate(time.Second)
+		blocked := newStoreTask("blocked", model.TaskPriorityNormal, now.Add(time.Second))
+		blocked.Dependencies = []string{"deleted", "failed"}
+		blocked.DependencyPolicies = map[string]model.DependencyFailurePolicy{"deleted": model.DependencyFailureIgnore}
+		for _, task := range []*model.Task{newStoreTask("replacement", model.TaskPriorityNormal, now), blocked} {
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		before, _ := tasks.GetByID("blocked")
+		_, lastSeq, _ := tasks.OutboxSeqRange()
+
+		repairs := []model.DependencyRepair{{DependencyID: "deleted", ReplaceWith: "replacement"}, {DependencyID: "failed"}}
+		if err := tasks.RepairDependencies("blocked", repairs, "admin", "upstream deleted", "req-1"); err != nil {
+			t.Fatalf("RepairDependencies: %v", err)
+		}
+		got, _ := tasks.GetByID("blocked")
+		if !slices.Equal(got.Dependencies, []string{"replacement"}) || got.DependencyPolicy("replacement") != model.DependencyFailureIgnore ||
+			len(got.DependencyPolicies) != 1 || got.Version != before.Version+1 {
+			t.Errorf("unexpected repaired task: dependencies %v, policies %v, version %d -> %d",
+				got.Dependencies, got.DependencyPolicies, before.Version, got.Version)
+		}
+		events, _ := tasks.ListOutboxEventsAfter(lastSeq, 10)
+		if len(events) != 1 || events[0].EventType != model.OutboxEventTaskDependenciesRepaired || events[0].Operator != "admin" ||
+			events[0].CorrelationID != "req-1" || events[0].Message != "dependencies repaired (deleted -> replacement, failed removed): upstream deleted" {
+			t.Errorf("unexpected repair events: %+v", events)
+		}
+		if dependents, _ := tasks.ListDependents([]string{"replacement"}, nil); len(dependents) != 1 || dependents[0].ID != "blocked" {
+			t.Errorf("expected blocked to depend on replacement, got %v", dependents)
+		}
+
+		// 依赖已被修改时修复不再适用，任务不变
+		if err := tasks.RepairDependencies("blocked", repairs, "admin", "", ""); !errors.Is(err, ErrDependenciesChanged) {
+			t.Errorf("expected ErrDependenciesChanged for a stale repair, got %v", err)
+		}
+		if err := tasks.UpdateStatusWithEvent("blocked", model.TaskStatusPending, model.TaskStatusCancelled, "alice", "cancel"); err != nil {
+			t.Fatalf("failed to cancel task: %v", err)
+		}
+		if err := tasks.RepairDependencies("blocked", []model.DependencyRepair{{DependencyID: "replacement"}}, "admin", "", ""); !errors.Is(err, ErrStatusMismatch) {
+			t.Errorf("expected ErrStatusMismatch for a cancelled task, got %v", err)
+		}
+		if got, _ := tasks.GetByID("blocked"); !slices.Equal(got.Dependencies, []string{"replacement"}) {
+			t.Errorf("expected dependencies unchanged, got %v", got.Dependencies)
+		}
+	})
+}
+
+func TestTaskStore_ListByFilterInLists(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		for _, spec := range []struct {
+			id       string
+			status   model.TaskStatus
+			priority model.TaskPriority
+			taskType string
+		}{
+			{"a", model.TaskStatusPending, model.TaskPriorityLow, "shell"},
+			{"b", model.TaskStatusRunning, model.TaskPriorityHigh, "http"},
+			{"c", model.TaskStatusFailed, model.TaskPriorityHigh, "shell"},
+			{"d", model.TaskStatusSucceeded, model.TaskPriorityNormal, "etl"},
+		} {
+			task := newStoreTask(spec.id, spec.priority, time.Now())
+			task.Status = spec.status
+			task.TaskType = spec.taskType
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		for _, tc := range []struct {
+			name   string
+			filter TaskFilter
+			want   string
+		}{
+			{"statuses", TaskFilter{Statuses: []model.TaskStatus{model.TaskStatusPending, model.TaskStatusRunning}}, "a,b"},
+			{"priorities", TaskFilter{Priorities: []model.TaskPriority{model.TaskPriorityHigh}}, "b,c"},
+			{"task types", TaskFilter{TaskTypes: []string{"shell", "etl"}}, "a,c,d"},
+			{"combined", TaskFilter{TaskTypes: []string{"shell", "http"}, Statuses: []model.TaskStatus{model.TaskStatusRunning, model.TaskStatusFailed}}, "b,c"},
+		} {
+			list, total, err := tasks.ListByFilter(tc.filter)
+			if err != nil {
+				t.Fatalf("%s: ListByFilter: %v", tc.name, err)
+			}
+			ids := make([]string, len(list))
+			for i, task := range list {
+				ids[i] = task.ID
+			}
+			sort.Strings(ids)
+			if got := strings.Join(ids, ","); got != tc.want || total != len(list) {
+				t.Errorf("%s: got %s (total %d), want %s", tc.name, got, total, tc.want)
+			}
+		}
+	})
+}
+
+func TestTaskStore_OutboxSeq(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		if first, last, err := tasks.OutboxSeqRange(); err != nil || first != 1 || last != 0 {
+			t.Fatalf("expected empty range (1, 0), got (%d, %d) %v", first, last, err)
+		}
+
+		if err := tasks.Create(newStoreTask("seq", model.TaskPriorityNormal, time.Now())); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		steps := [][2]model.TaskStatus{
+			{model.TaskStatusPending, model.TaskStatusRunning},
+			{model.TaskStatusRunning, model.TaskStatusSucceeded},
+		}
+		for _, s := range steps {
+			if err := tasks.UpdateStatusWithEvent("seq", s[0], s[1], "scheduler", "step"); err != nil {
+				t.Fatalf("failed to update status: %v", err)
+			}
+		}
+
+		all, err := tasks.ListOutboxEventsAfter(0, 10)
+		if err != nil || len(all) != 2 || all[0].Seq <= 0 || all[1].Seq <= all[0].Seq {
+			t.Fatalf("expected 2 events with increasing seq, got %+v (%v)", all, err)
+		}
+		if after, _ := tasks.ListOutboxEventsAfter(all[0].Seq, 10); len(after) != 1 || after[0].ID != all[1].ID {
+			t.Errorf("expected only the second event after seq %d, got %+v", all[0].Seq, after)
+		}
+		if first, last, _ := tasks.OutboxSeqRange(); first != all[0].Seq || last != all[1].Seq {
+			t.Errorf("expected range (%d, %d), got (%d, %d)", all[0].Seq, all[1].Seq, first, last)
+		}
+
+		// 清理后序号不复用，first 指向下一个序号
+		now := time.Now()
+		for _, e := range all {
+			if err := tasks.MarkOutboxEventDelivered(e.ID, now); err != nil {
+				t.Fatalf("failed to mark delivered: %v", err)
+			}
+		}
+		if _, err := tasks.PurgeDeliveredOutboxEvents(now.Add(time.Second)); err != nil {
+			t.Fatalf("failed to purge: %v", err)
+		}
+		if first, last, _ := tasks.OutboxSeqRange(); first != all[1].Seq+1 || last != all[1].Seq {
+			t.Errorf("expected range (%d, %d) after purge, got (%d, %d)", all[1].Seq+1, all[1].Seq, first, last)
+		}
+		if err := tasks.MarkSLABreached("seq", now, "sla", "breached", ""); err != nil {
+			t.Fatalf("failed to mark SLA breach: %v", err)
+		}
+		if events, _ := tasks.ListOutboxEventsAfter(0, 10); len(events) != 1 || events[0].Seq != all[1].Seq+1 {
+			t.Errorf("expected the next event to continue the sequence, got %+v", events)
+		}
+	})
+}
+
+func TestTaskStore_ListEventsAfter(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		for _, id := range []string{"a", "b"} {
+			if err := tasks.Create(newStoreTask(id, model.TaskPriorityNormal, time.Now())); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		if err := tasks.UpdateStatusWithEvent("a", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start"); err != nil {
+			t.Fatalf("failed to update status: %v", err)
+		}
+		if err := tasks.AddEvent(&model.TaskEvent{ID: "note", TaskID: "b", Message: "note", Timestamp: time.Now()}); err != nil {
+			t.Fatalf("AddEvent: %v", err)
+		}
+
+		// 所有任务的事件按写入顺序编号，创建任务同样记录事件
+		all, err := tasks.ListEventsAfter(0, 10)
+		if err != nil {
+			t.Fatalf("ListEventsAfter: %v", err)
+		}
+		var got []string
+		for i, e := range all {
+			got = append(got, e.TaskID+":"+e.Message)
+			if i > 0 && e.Seq <= all[i-1].Seq {
+				t.Errorf("expected increasing seq, got %d after %d", e.Seq, all[i-1].Seq)
+			}
+		}
+		want := []string{"a:task created", "b:task created", "a:start", "b:note"}
+		if !slices.Equal(got, want) {
+			t.Fatalf("expected events %v, got %v", want, got)
+		}
+		if all[0].FromStatus != model.TaskStatusUnspecified || all[0].ToStatus != model.TaskStatusPending || all[0].Operator != "alice" {
+			t.Errorf("unexpected creation event: %+v", all[0])
+		}
+
+		page, _ := tasks.ListEventsAfter(all[0].Seq, 2)
+		if len(page) != 2 || page[0].Seq != all[1].Seq || page[1].Seq != all[2].Seq {
+			t.Errorf("expected the next two events after seq %d, got %+v", all[0].Seq, page)
+		}
+		if rest, _ := tasks.ListEventsAfter(all[3].Seq, 10); len(rest) != 0 {
+			t.Errorf("expected no events after the last seq, got %+v", rest)
+		}
+		if events, _ := tasks.GetEventsByTaskID("b"); len(events) != 2 || events[1].Seq != all[3].Seq {
+			t.Errorf("expected task events to carry their seq, got %+v", events)
+		}
+	})
+}
+
+func TestTaskStore_EventMetadata(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		if err := tasks.Create(newStoreTask("meta", model.TaskPriorityNormal, time.Now())); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		claim := map[string]string{model.EventMetaAttempt: "1", model.EventMetaWorker: "inst-1"}
+		if err := tasks.UpdateStatusWithInstanceEvent("meta", model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "start", "inst-1", claim); err != nil {
+			t.Fatalf("failed to update status: %v", err)
+		}
+		failure := map[string]string{model.EventMetaAttempt: "1", model.EventMetaDurationMs: "1200", model.EventMetaErrorClass: "fatal"}
+		if err := tasks.FailTask("meta", "boom", model.ErrorClassFatal, "scheduler", "failed", "inst-1", failure); err != nil {
+			t.Fatalf("FailTask: %v", err)
+		}
+		claim[model.EventMetaAttempt] = "changed"
+
+		// 元数据随事件持久化，创建事件没有元数据；调用方之后修改传入的 map 不影响已写入的事件
+		events, err := tasks.ListTaskEvents("meta", EventFilter{})
+		if err != nil {
+			t.Fatalf("ListTaskEvents: %v", err)
+		}
+		if len(events) != 3 || events[0].Metadata != nil {
+			t.Fatalf("unexpected events %+v", events)
+		}
+		if !maps.Equal(events[1].Metadata, map[string]string{model.EventMetaAttempt: "1", model.EventMetaWorker: "inst-1"}) ||
+			!maps.Equal(events[2].Metadata, failure) {
+			t.Errorf("unexpected event metadata %+v, %+v", events[1].Metadata, events[2].Metadata)
+		}
+	})
+}
+
+func TestTaskStore_ListPendingOnlyReadyTasks(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		upstream := map[string]model.TaskStatus{
+			"up-pending": model.TaskStatusPending,
+			"up-running": model.TaskStatusRunning,
+			"up-done":    model.TaskStatusSucceeded,
+			"up-failed":  model.TaskStatusFailed,
+			"up-waiting": model.TaskStatusWaitingInput,
+		}
+		for id, status := range upstream {
+			task := newStoreTask(id, model.TaskPriorityLow, base)
+			task.Status = status
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		downstream := []struct {
+			id       string
+			deps     []string
+			policies map[string]model.DependencyFailurePolicy
+		}{
+			{id: "no-deps"},
+			{id: "wait-pending", deps: []string{"up-done", "up-pending"}},
+			{id: "wait-running", deps: []string{"up-running"}},
+			{id: "wait-input", deps: []string{"up-waiting"}},
+			{id: "deps-done", deps: []string{"up-done"}},
+			{id: "to-skip", deps: []string{"up-failed"}},
+			{id: "ignore-failed", deps: []string{"up-failed"}, policies: map[string]model.DependencyFailurePolicy{"up-failed": model.DependencyFailureIgnore}},
+			{id: "wait-failed", deps: []string{"up-failed"}, policies: map[string]model.DependencyFailurePolicy{"up-failed": model.DependencyFailureWait}},
+			{id: "missing-dep", deps: []string{"nope"}},
+		}
+		for i, d := range downstream {
+			task := newStoreTask(d.id, model.TaskPriorityHigh, base.Add(time.Duration(i+1)*time.Minute))
+			task.Dependencies = d.deps
+			task.DependencyPolicies = d.policies
+			if err := tasks.Create(task); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+
+		pendingIDs := func() []string {
+			pending, err := tasks.ListPending(100, time.Now())
+			if err != nil {
+				t.Fatalf("ListPending: %v", err)
+			}
+			var ids []string
+			for _, task := range pending {
+				ids = append(ids, task.ID)
+			}
+			return ids
+		}
+		// 上游未结束、或未成功且按 wait 处理的任务不返回；按 skip 处理的任务返回给调度器标记跳过，
+		// 上游不存在的任务返回给调度器报错
+		want := []string{"no-deps", "deps-done", "to-skip", "ignore-failed", "missing-dep", "up-pending"}
+		if got := pendingIDs(); !slices.Equal(got, want) {
+			t.Fatalf("expected pending %v, got %v", want, got)
+		}
+
+		// 上游结束后下游立即可见
+		if err := tasks.UpdateStatusWithEvent("up-pending", model.TaskStatusPending, model.TaskStatusSucceeded, "test", "done"); err != nil {
+			t.Fatalf("failed to complete upstream: %v", err)
+		}
+		want = []string{"no-deps", "wait-pending", "deps-done", "to-skip", "ignore-failed", "missing-dep"}
+		if got := pendingIDs(); !slices.Equal(got, want) {
+			t.Errorf("expected pending %v after upstream succeeded, got %v", want, got)
+		}
+
+		// 直接依赖上游的任务，不论依赖是否已满足
+		dependentIDs := func(ids []string, statusFilter *model.TaskStatus) []string {
+			dependents, err := tasks.ListDependents(ids, statusFilter)
+			if err != nil {
+				t.Fatalf("ListDependents: %v", err)
+			}
+			var got []string
+			for _, task := range dependents {
+				got = append(got, task.ID)
+			}
+			return got
+		}
+		if got, want := dependentIDs([]string{"up-failed"}, nil), []string{"to-skip", "ignore-failed", "wait-failed"}; !slices.Equal(got, want) {
+			t.Errorf("expected dependents %v, got %v", want, got)
+		}
+		if err := tasks.UpdateStatus("deps-done", model.TaskStatusPending, model.TaskStatusRunning); err != nil {
+			t.Fatalf("failed to start task: %v", err)
+		}
+		pending := model.TaskStatusPending
+		if got, want := dependentIDs([]string{"up-done", "up-pending"}, &pending), []string{"wait-pending"}; !slices.Equal(got, want) {
+			t.Errorf("expected pending dependents %v, got %v", want, got)
+		}
+		if got := dependentIDs([]string{"no-deps"}, nil); len(got) != 0 {
+			t.Errorf("expected no dependents, got %v", got)
+		}
+	})
+}
+
+func TestTaskStore_TimestampsStoredInUTC(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		shanghai := time.FixedZone("UTC+8", 8*3600)
+		newYork := time.FixedZone("UTC-5", -5*3600)
+		seattle := time.FixedZone("UTC-8", -8*3600)
+		created := time.Date(2024, 3, 1, 8, 0, 0, 0, shanghai) // 00:00Z
+		if err := tasks.Create(newStoreTask("tz", model.TaskPriorityNormal, created)); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		// 不同时区写入的事件：02:00Z 和 01:00Z，带偏移量按字符串比较时与过滤时间的先后都相反
+		for i, at := range []time.Time{
+			time.Date(2024, 2, 29, 18, 0, 0, 0, seattle),
+			time.Date(2024, 3, 1, 9, 0, 0, 0, shanghai),
+		} {
+			event := &model.TaskEvent{ID: fmt.Sprintf("tz%d", i+1), TaskID: "tz", Message: fmt.Sprintf("tz%d", i+1), Timestamp: at}
+			if err := tasks.AddEvent(event); err != nil {
+				t.Fatalf("failed to add event: %v", err)
+			}
+		}
+
+		got, err := tasks.GetByID("tz")
+		if err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+		if got.CreatedAt.Location() != time.UTC || !got.CreatedAt.Equal(created) {
+			t.Errorf("expected created_at %v in UTC, got %v", created.UTC(), got.CreatedAt)
+		}
+
+		// 时间过滤按实际时刻比较，与写入时的时区无关
+		events, err := tasks.ListTaskEvents("tz", EventFilter{After: time.Date(2024, 2, 29, 20, 30, 0, 0, newYork)})
+		if err != nil {
+			t.Fatalf("ListTaskEvents: %v", err)
+		}
+		if len(events) != 1 || events[0].Message != "tz1" {
+			t.Fatalf("expected only tz1 after 01:30Z, got %+v", events)
+		}
+		if events[0].Timestamp.Location() != time.UTC || !events[0].Timestamp.Equal(time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected event timestamp 02:00Z, got %v", events[0].Timestamp)
+		}
+	})
+}
+
+func TestTaskStore_ListTaskEventsAndCompact(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		now := time.Now()
+		for _, id := range []string{"a", "b"} {
+			if err := tasks.Create(newStoreTask(id, model.TaskPriorityNormal, now)); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		for i, age := range []time.Duration{4 * time.Hour, 3 * time.Hour, 2 * time.Hour, time.Hour} {
+			event := &model.TaskEvent{ID: fmt.Sprintf("e%d", i+1), TaskID: "a", Message: fmt.Sprintf("e%d", i+1), Timestamp: now.Add(-age)}
+			if err := tasks.AddEvent(event); err != nil {
+				t.Fatalf("AddEvent: %v", err)
+			}
+		}
+		if err := tasks.AddEvent(&model.TaskEvent{ID: "old", TaskID: "b", Message: "old", Timestamp: now.Add(-5 * time.Hour)}); err != nil {
+			t.Fatalf("AddEvent: %v", err)
+		}
+
+		messages := func(events []model.TaskEvent) []string {
+			var got []string
+			for _, e := range events {
+				got = append(got, e.Message)
+			}
+			return got
+		}
+
+		page, err := tasks.ListTaskEvents("a", EventFilter{Limit: 2})
+		if err != nil {
+			t.Fatalf("ListTaskEvents: %v", err)
+		}
+		if got := messages(page); !slices.Equal(got, []string{"task created", "e1"}) {
+			t.Fatalf("unexpected first page %v", got)
+		}
+		next, _ := tasks.ListTaskEvents("a", EventFilter{AfterSeq: page[1].Seq, Limit: 2})
+		if got := messages(next); !slices.Equal(got, []string{"e2", "e3"}) {
+			t.Errorf("unexpected second page %v", got)
+		}
+		ranged, _ := tasks.ListTaskEvents("a", EventFilter{After: now.Add(-210 * time.Minute), Before: now.Add(-90 * time.Minute)})
+		if got := messages(ranged); !slices.Equal(got, []string{"e2", "e3"}) {
+			t.Errorf("unexpected events in time range %v", got)
+		}
+
+		// 早于截止时间且不在最近 2 条之内的事件被删除，事件少于 2 条的任务不受影响
+		removed, err := tasks.CompactEvents(now.Add(-150*time.Minute), 2)
+		if err != nil {
+			t.Fatalf("CompactEvents: %v", err)
+		}
+		if removed != 2 {
+			t.Errorf("expected 2 events removed, got %d", removed)
+		}
+		if events, _ := tasks.ListTaskEvents("a", EventFilter{}); !slices.Equal(messages(events), []string{"task created", "e3", "e4"}) {
+			t.Errorf("unexpected events after compaction %v", messages(events))
+		}
+		if events, _ := tasks.GetEventsByTaskID("b"); len(events) != 2 {
+			t.Errorf("expected events of task b kept, got %v", messages(events))
+		}
+	})
+}
+
+func TestTaskStore_Snapshot(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		if seq, err := tasks.LatestEventSeq(); err != nil || seq != 0 {
+			t.Fatalf("expected no events yet, got %d (%v)", seq, err)
+		}
+		for _, id := range []string{"c", "a", "b"} {
+			if err := tasks.Create(newStoreTask(id, model.TaskPriorityNormal, time.Now())); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		events, _ := tasks.ListEventsAfter(0, 10)
+		if seq, err := tasks.LatestEventSeq(); err != nil || len(events) != 3 || seq != events[2].Seq {
+			t.Errorf("expected the latest seq to match the last event, got %d (%v), events %+v", seq, err, events)
+		}
+
+		page, err := tasks.ListAfterID("", 2)
+		if err != nil || len(page) != 2 || page[0].ID != "a" || page[1].ID != "b" || len(page[0].Events) != 0 {
+			t.Fatalf("expected the first page [a b] without events, got %+v (%v)", page, err)
+		}
+		if rest, _ := tasks.ListAfterID(page[1].ID, 2); len(rest) != 1 || rest[0].ID != "c" {
+			t.Errorf("expected the second page [c], got %+v", rest)
+		}
+	})
+}
+
+func TestTaskStore_SchedulerCheckpoint(t *testing.T) {
+	forEachStore(t, func(t *testing.T, tasks TaskStore, teams TeamStore) {
+		if cp, err := tasks.GetSchedulerCheckpoint("node-a"); err != nil || cp != nil {
+			t.Fatalf("expected no checkpoint yet, got %+v (%v)", cp, err)
+		}
+
+		savedAt := time.Now().Truncate(time.Second)
+		cp := &model.SchedulerCheckpoint{Name: "node-a", SavedAt: savedAt, BaseWorkers: 10, WorkerCount: 4, ScheduledCount: 7, FinishedCount: 5}
+		if err := tasks.SaveSchedulerCheckpoint(cp); err != nil {
+			t.Fatalf("failed to save checkpoint: %v", err)
+		}
+		// 再次保存覆盖原有检查点
+		cp.WorkerCount, cp.AutoscaleLowTicks = 6, 2
+		if err := tasks.SaveSchedulerCheckpoint(cp); err != nil {
+			t.Fatalf("failed to save checkpoint: %v", err)
+		}
+
+		got, err := tasks.GetSchedulerCheckpoint("node-a")
+		if err != nil || got == nil {
+			t.Fatalf("failed to get checkpoint: %+v (%v)", got, err)
+		}
+		if !got.SavedAt.Equal(savedAt) || got.BaseWorkers != 10 || got.WorkerCount != 6 || got.AutoscaleLowTicks != 2 ||
+			got.ScheduledCount != 7 || got.FinishedCount != 5 {
+			t.Errorf("unexpected checkpoint: %+v", got)
+		}
+		if cp, _ := tasks.GetSchedulerCheckpoint("node-b"); cp != nil {
+			t.Errorf("expected checkpoints to be kept per name, got %+v", cp)
+		}
+	})
+}
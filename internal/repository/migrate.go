@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect 数据库方言，决定使用的迁移脚本目录和占位符
+type Dialect string
+
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+//go:embed migrations
+var migrationFS embed.FS
+
+// Migration 一个版本化的迁移脚本，文件名格式为 <版本号>_<名称>.sql
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// LoadMigrations 按版本号升序加载方言对应的内嵌迁移脚本
+func LoadMigrations(dialect Dialect) ([]Migration, error) {
+	dir := path.Join("migrations", string(dialect))
+	entries, err := fs.ReadDir(migrationFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported dialect %q: %w", dialect, err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		prefix, rest, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		if prev, dup := seen[version]; dup {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, prev, name)
+		}
+		seen[version] = name
+
+		data, err := migrationFS.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: rest, SQL: string(data)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate 在 schema_migrations 表中记录已执行的版本，按顺序执行尚未执行的迁移，返回本次执行的迁移。
+// 每个迁移在单独的事务中执行，失败时回滚该迁移并停止
+func Migrate(db *sql.DB, dialect Dialect) ([]Migration, error) {
+	return migrateTo(db, dialect, math.MaxInt)
+}
+
+// migrateTo 执行版本不超过 target 的迁移，恢复备份时先迁移到备份的版本
+func migrateTo(db *sql.DB, dialect Dialect, target int) ([]Migration, error) {
+	migrations, err := LoadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+		if err := applyMigration(db, dialect, m); err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// SchemaVersion 当前已执行的最高迁移版本，未执行过迁移时返回 0
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+func applyMigration(db *sql.DB, dialect Dialect, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range splitStatements(m.SQL) {
+		if _, err := tx.Exec(stmt); err != nil {
+			// 早期 InitSchema 建出的 SQLite 库可能已有该列，且 SQLite 不支持 ADD COLUMN IF NOT EXISTS
+			if dialect == DialectSQLite && strings.Contains(err.Error(), "duplicate column name") {
+				continue
+			}
+			tx.Rollback()
+			return err
+		}
+	}
+
+	insert := `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`
+	if dialect == DialectPostgres {
+		insert = `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`
+	}
+	if _, err := tx.Exec(insert, m.Version, m.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// splitStatements 按行尾分号拆分迁移脚本，去掉整行注释和空语句
+func splitStatements(script string) []string {
+	var stmts []string
+	var b strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(b.String()))
+			b.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(b.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+func TestMigrate_FreshDatabase(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	migrations, err := LoadMigrations(DialectSQLite)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	version, err := SchemaVersion(db.DB())
+	if err != nil {
+		t.Fatalf("failed to get schema version: %v", err)
+	}
+	if want := migrations[len(migrations)-1].Version; version != want {
+		t.Errorf("expected schema version %d, got %d", want, version)
+	}
+
+	// 再次执行不应重复迁移
+	applied, err := db.Migrate()
+	if err != nil {
+		t.Fatalf("failed to re-run migrations: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected no migrations on second run, got %d", len(applied))
+	}
+}
+
+func TestMigrate_AdoptsLegacySchema(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "taskflow_legacy_*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	db, err := NewSQLite(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("failed to create SQLite: %v", err)
+	}
+	defer db.Close()
+
+	// 模拟早期 InitSchema 建出的库：没有 schema_migrations，已有部分新增列
+	initial, err := LoadMigrations(DialectSQLite)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := db.DB().Exec(initial[0].SQL); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	if _, err := db.DB().Exec(`ALTER TABLE tasks ADD COLUMN team_id TEXT`); err != nil {
+		t.Fatalf("failed to add legacy column: %v", err)
+	}
+
+	applied, err := db.Migrate()
+	if err != nil {
+		t.Fatalf("failed to migrate legacy database: %v", err)
+	}
+	if len(applied) != len(initial) {
+		t.Errorf("expected %d migrations applied, got %d", len(initial), len(applied))
+	}
+
+	repo := NewTaskRepository(db)
+	task := &model.Task{Name: "legacy", Status: model.TaskStatusPending, TeamID: "team-1", RetryPolicy: &model.RetryPolicy{MaxAttempts: 2}}
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task after migration: %v", err)
+	}
+	got, err := repo.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if got.TeamID != "team-1" || got.RetryPolicy == nil || got.RetryPolicy.MaxAttempts != 2 {
+		t.Errorf("unexpected task after migration: %+v", got)
+	}
+}
+
+func TestMigrate_NormalizesTimestampsToUTC(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+	task := &model.Task{ID: "legacy-tz", Name: "legacy", Status: model.TaskStatusPending, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	// 模拟旧版本按服务器本地时区写入的时间
+	if _, err := db.DB().Exec(`UPDATE tasks SET created_at = '2024-03-01T02:30:00+08:00', completed_at = '2024-03-01T10:00:00-05:30' WHERE id = ?`, task.ID); err != nil {
+		t.Fatalf("failed to write legacy timestamps: %v", err)
+	}
+
+	migrations, err := LoadMigrations(DialectSQLite)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	var normalize *Migration
+	for i := range migrations {
+		if migrations[i].Name == "utc_timestamps" {
+			normalize = &migrations[i]
+		}
+	}
+	if normalize == nil {
+		t.Fatal("utc_timestamps migration not found")
+	}
+	for _, stmt := range splitStatements(normalize.SQL) {
+		if _, err := db.DB().Exec(stmt); err != nil {
+			t.Fatalf("failed to run %q: %v", stmt, err)
+		}
+	}
+
+	var createdAt, completedAt string
+	if err := db.DB().QueryRow(`SELECT created_at, completed_at FROM tasks WHERE id = ?`, task.ID).Scan(&createdAt, &completedAt); err != nil {
+		t.Fatalf("failed to read timestamps: %v", err)
+	}
+	if createdAt != "2024-02-29T18:30:00Z" || completedAt != "2024-03-01T15:30:00Z" {
+		t.Errorf("expected UTC timestamps, got created_at=%s completed_at=%s", createdAt, completedAt)
+	}
+}
+
+func TestLoadMigrations_DialectsInSync(t *testing.T) {
+	sqlite, err := LoadMigrations(DialectSQLite)
+	if err != nil {
+		t.Fatalf("failed to load sqlite migrations: %v", err)
+	}
+	postgres, err := LoadMigrations(DialectPostgres)
+	if err != nil {
+		t.Fatalf("failed to load postgres migrations: %v", err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("expected same number of migrations, got sqlite=%d postgres=%d", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("migration %d differs: sqlite=%d_%s postgres=%d_%s", i, sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+
+	if _, err := LoadMigrations("mysql"); err == nil {
+		t.Error("expected error for unsupported dialect")
+	}
+}
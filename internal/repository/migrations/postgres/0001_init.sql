@@ -0,0 +1,39 @@
+-- 初始表结构：任务与状态事件
+CREATE TABLE IF NOT EXISTS tasks (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	description TEXT,
+	status INTEGER NOT NULL DEFAULT 1,
+	priority INTEGER NOT NULL DEFAULT 2,
+	task_type TEXT,
+	input_params TEXT,
+	output_result TEXT,
+	dependencies TEXT,
+	retry_count INTEGER NOT NULL DEFAULT 0,
+	max_retries INTEGER NOT NULL DEFAULT 0,
+	error_message TEXT,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	started_at TEXT,
+	completed_at TEXT,
+	created_by TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+CREATE INDEX IF NOT EXISTS idx_tasks_priority ON tasks(priority);
+CREATE INDEX IF NOT EXISTS idx_tasks_created_by ON tasks(created_by);
+CREATE INDEX IF NOT EXISTS idx_tasks_created_at ON tasks(created_at);
+
+CREATE TABLE IF NOT EXISTS task_events (
+	id TEXT PRIMARY KEY,
+	task_id TEXT NOT NULL,
+	from_status INTEGER NOT NULL,
+	to_status INTEGER NOT NULL,
+	message TEXT,
+	timestamp TEXT NOT NULL,
+	operator TEXT,
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_events_task_id ON task_events(task_id);
+CREATE INDEX IF NOT EXISTS idx_task_events_timestamp ON task_events(timestamp);
@@ -0,0 +1,2 @@
+-- 按依赖 ID 配置上游任务未成功时下游任务的处理方式（JSON），未配置的依赖为 skip
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS dependency_policies TEXT;
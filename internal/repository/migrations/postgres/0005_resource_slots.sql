@@ -0,0 +1,2 @@
+-- 任务运行时占用的资源槽位，0 表示默认的 1 个
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS resource_slots INTEGER NOT NULL DEFAULT 0;
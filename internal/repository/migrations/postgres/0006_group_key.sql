@@ -0,0 +1,2 @@
+-- 分组键相同的任务串行执行
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS group_key TEXT;
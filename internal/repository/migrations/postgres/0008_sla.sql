@@ -0,0 +1,4 @@
+-- SLA 截止时间（UTC 定宽格式，可按字符串比较）和违约记录时间
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sla_deadline TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sla_breached_at TEXT;
+CREATE INDEX IF NOT EXISTS idx_tasks_sla_deadline ON tasks(sla_deadline);
@@ -0,0 +1,4 @@
+-- 任务列表排序：默认按优先级降序、创建时间降序，也可按更新时间排序
+-- （created_at、status、priority、completed_at 已有单列索引）
+CREATE INDEX IF NOT EXISTS idx_tasks_priority_created_at ON tasks(priority, created_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_updated_at ON tasks(updated_at);
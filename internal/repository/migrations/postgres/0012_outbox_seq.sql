@@ -0,0 +1,3 @@
+-- 发件箱事件序号：单调递增，作为 WatchTask 的续传令牌
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_seq ON outbox_events(seq);
@@ -0,0 +1,3 @@
+-- 任务事件序号：单调递增，作为变更流的游标
+ALTER TABLE task_events ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_task_events_seq ON task_events(seq);
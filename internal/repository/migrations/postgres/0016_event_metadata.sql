@@ -0,0 +1,2 @@
+-- 任务事件的结构化元数据（JSON 对象：执行次数、执行实例、耗时、错误分类等）
+ALTER TABLE task_events ADD COLUMN IF NOT EXISTS metadata TEXT;
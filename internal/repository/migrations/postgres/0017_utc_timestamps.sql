@@ -0,0 +1,21 @@
+-- 时间统一按 UTC 存储：把以前按服务器本地时区写入、带偏移量的 RFC3339 时间转换为 UTC，
+-- 使按字符串比较的时间范围查询与时间顺序一致。已是 UTC（以 Z 结尾）的值和 NULL 不变
+UPDATE tasks SET created_at = to_char(created_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE created_at NOT LIKE '%Z';
+UPDATE tasks SET updated_at = to_char(updated_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE updated_at NOT LIKE '%Z';
+UPDATE tasks SET started_at = to_char(started_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE started_at NOT LIKE '%Z';
+UPDATE tasks SET completed_at = to_char(completed_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE completed_at NOT LIKE '%Z';
+UPDATE task_events SET "timestamp" = to_char("timestamp"::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE "timestamp" NOT LIKE '%Z';
+UPDATE task_comments SET created_at = to_char(created_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE created_at NOT LIKE '%Z';
+UPDATE task_attachments SET created_at = to_char(created_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE created_at NOT LIKE '%Z';
+UPDATE scheduler_instances SET started_at = to_char(started_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE started_at NOT LIKE '%Z';
+UPDATE scheduler_instances SET heartbeat_at = to_char(heartbeat_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE heartbeat_at NOT LIKE '%Z';
+UPDATE outbox_events SET created_at = to_char(created_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE created_at NOT LIKE '%Z';
+UPDATE outbox_events SET next_attempt_at = to_char(next_attempt_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE next_attempt_at NOT LIKE '%Z';
+UPDATE outbox_events SET delivered_at = to_char(delivered_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE delivered_at NOT LIKE '%Z';
+UPDATE teams SET created_at = to_char(created_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE created_at NOT LIKE '%Z';
+UPDATE team_members SET joined_at = to_char(joined_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE joined_at NOT LIKE '%Z';
+UPDATE secrets SET created_at = to_char(created_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE created_at NOT LIKE '%Z';
+UPDATE secrets SET updated_at = to_char(updated_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE updated_at NOT LIKE '%Z';
+UPDATE task_runs SET started_at = to_char(started_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE started_at NOT LIKE '%Z';
+UPDATE task_runs SET completed_at = to_char(completed_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE completed_at NOT LIKE '%Z';
+UPDATE task_runs SET archived_at = to_char(archived_at::timestamptz AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') WHERE archived_at NOT LIKE '%Z';
@@ -0,0 +1,10 @@
+-- ready_tasks：依赖已全部结束的 PENDING 任务 ID，调度器轮询时只读取可以派发（或需要按依赖策略跳过）的任务。
+-- 有上游仍未结束，或上游未成功且该依赖按 wait 处理的任务不在视图中；视图按任务当前状态计算，上游结束后立即可见
+CREATE OR REPLACE VIEW ready_tasks AS
+SELECT t.id FROM tasks t
+WHERE t.status = 1 AND (t.dependencies IN ('null', '[]') OR NOT EXISTS (
+	SELECT 1 FROM jsonb_array_elements_text(t.dependencies::jsonb) AS d(id)
+	JOIN tasks up ON up.id = d.id
+	WHERE up.status IN (0, 1, 2)
+		OR (up.status <> 3 AND (t.dependency_policies::jsonb ->> up.id) = 'wait')
+));
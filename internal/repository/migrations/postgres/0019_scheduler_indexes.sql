@@ -0,0 +1,9 @@
+-- 调度器热点查询的复合索引：
+-- ListPending 按状态过滤并按优先级降序、创建时间升序取前 N 个，按索引顺序扫描，不再排序全部 PENDING 任务；
+-- 重试退避按 (status, next_run_at) 过滤；按创建者和状态过滤的任务列表使用 (created_by, status)。
+-- 任务依赖保存在 tasks.dependencies 列中，ready_tasks 视图按主键查找上游任务，没有单独的依赖表需要索引
+CREATE INDEX IF NOT EXISTS idx_tasks_status_priority_created_at ON tasks(status, priority DESC, created_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_status_next_run_at ON tasks(status, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_created_by_status ON tasks(created_by, status);
+-- 单列状态索引是上面复合索引的前缀，删除以减少写入开销
+DROP INDEX IF EXISTS idx_tasks_status;
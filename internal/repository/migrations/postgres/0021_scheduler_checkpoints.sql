@@ -0,0 +1,10 @@
+-- 调度器停止时保存的运行状态，按名称恢复
+CREATE TABLE IF NOT EXISTS scheduler_checkpoints (
+	name TEXT PRIMARY KEY,
+	saved_at TEXT NOT NULL,
+	base_workers INTEGER NOT NULL DEFAULT 0,
+	worker_count INTEGER NOT NULL DEFAULT 0,
+	autoscale_low_ticks INTEGER NOT NULL DEFAULT 0,
+	scheduled_count INTEGER NOT NULL DEFAULT 0,
+	finished_count INTEGER NOT NULL DEFAULT 0
+);
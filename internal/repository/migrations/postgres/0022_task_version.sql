@@ -0,0 +1,2 @@
+-- 任务版本号：每次写入任务行时加一，作为 HTTP ETag 和乐观并发控制的依据
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
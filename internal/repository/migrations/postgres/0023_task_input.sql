@@ -0,0 +1,3 @@
+-- 等待输入：执行器请求的输入说明，以及信号送达的输入（JSON）
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS input_request TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS signal_input TEXT;
@@ -0,0 +1,9 @@
+-- ready_tasks：等待外部输入（WAITING_INPUT = 8）的上游同样尚未结束，下游任务不在视图中
+CREATE OR REPLACE VIEW ready_tasks AS
+SELECT t.id FROM tasks t
+WHERE t.status = 1 AND (t.dependencies IN ('null', '[]') OR NOT EXISTS (
+	SELECT 1 FROM jsonb_array_elements_text(t.dependencies::jsonb) AS d(id)
+	JOIN tasks up ON up.id = d.id
+	WHERE up.status IN (0, 1, 2, 8)
+		OR (up.status <> 3 AND (t.dependency_policies::jsonb ->> up.id) = 'wait')
+));
@@ -0,0 +1,4 @@
+-- 任务路由：节点选择器（JSON）和目标实例，以及调度器实例的标签（JSON）
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS node_selector TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS target_worker TEXT;
+ALTER TABLE scheduler_instances ADD COLUMN IF NOT EXISTS labels TEXT;
@@ -0,0 +1,3 @@
+-- 排队时长监控：原地重新运行后重新排队的时间（与 created_at 格式相同，可按字符串比较）和排队超时记录时间
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS queued_at TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS queue_alerted_at TEXT;
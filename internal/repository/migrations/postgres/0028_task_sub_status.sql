@@ -0,0 +1,3 @@
+-- 自定义阶段：执行器上报的子状态和阶段历史（JSON）
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sub_status TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS phases TEXT;
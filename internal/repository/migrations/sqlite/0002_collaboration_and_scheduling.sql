@@ -0,0 +1,94 @@
+-- 团队、评论、附件、日志、调度实例、发件箱，以及任务的执行与重试字段。
+-- 早期 InitSchema 创建的数据库可能已有部分列，重复添加列的错误由迁移器忽略
+ALTER TABLE tasks ADD COLUMN team_id TEXT;
+ALTER TABLE tasks ADD COLUMN executed_by TEXT;
+ALTER TABLE tasks ADD COLUMN output_ref TEXT;
+ALTER TABLE tasks ADD COLUMN retry_policy TEXT;
+ALTER TABLE tasks ADD COLUMN next_run_at TEXT;
+ALTER TABLE tasks ADD COLUMN error_class TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_team_id ON tasks(team_id);
+
+ALTER TABLE task_events ADD COLUMN instance_id TEXT;
+
+CREATE TABLE IF NOT EXISTS task_comments (
+	id TEXT PRIMARY KEY,
+	task_id TEXT NOT NULL,
+	author TEXT,
+	body TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_comments_task_id ON task_comments(task_id, created_at);
+
+CREATE TABLE IF NOT EXISTS task_attachments (
+	id TEXT PRIMARY KEY,
+	task_id TEXT NOT NULL,
+	filename TEXT NOT NULL,
+	content_type TEXT,
+	size INTEGER NOT NULL DEFAULT 0,
+	checksum TEXT,
+	storage_key TEXT NOT NULL,
+	uploaded_by TEXT,
+	created_at TEXT NOT NULL,
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_attachments_task_id ON task_attachments(task_id, created_at);
+
+CREATE TABLE IF NOT EXISTS task_logs (
+	task_id TEXT NOT NULL,
+	seq INTEGER NOT NULL,
+	attempt INTEGER NOT NULL DEFAULT 0,
+	timestamp TEXT NOT NULL,
+	line TEXT NOT NULL,
+	PRIMARY KEY (task_id, seq),
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS scheduler_instances (
+	id TEXT PRIMARY KEY,
+	hostname TEXT,
+	started_at TEXT NOT NULL,
+	heartbeat_at TEXT NOT NULL,
+	worker_count INTEGER NOT NULL DEFAULT 0,
+	busy_workers INTEGER NOT NULL DEFAULT 0,
+	queue_depth INTEGER NOT NULL DEFAULT 0,
+	in_flight_tasks TEXT
+);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+	id TEXT PRIMARY KEY,
+	task_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	from_status INTEGER NOT NULL,
+	to_status INTEGER NOT NULL,
+	message TEXT,
+	operator TEXT,
+	instance_id TEXT,
+	created_at TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TEXT NOT NULL,
+	delivered_at TEXT,
+	last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(delivered_at, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS teams (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	created_by TEXT,
+	created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+	team_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	joined_at TEXT NOT NULL,
+	PRIMARY KEY (team_id, user_id),
+	FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_members_user_id ON team_members(user_id);
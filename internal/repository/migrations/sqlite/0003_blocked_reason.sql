@@ -0,0 +1,2 @@
+-- PENDING 任务暂不能调度的原因（如单例任务类型已有任务在运行）
+ALTER TABLE tasks ADD COLUMN blocked_reason TEXT;
@@ -0,0 +1,8 @@
+-- 命名密钥，value 为 base64 编码的 AES-GCM 密文
+CREATE TABLE IF NOT EXISTS secrets (
+	name TEXT PRIMARY KEY,
+	value TEXT NOT NULL,
+	created_by TEXT,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
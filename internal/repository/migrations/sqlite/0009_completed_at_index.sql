@@ -0,0 +1,2 @@
+-- 按完成时间统计各任务类型的成功与失败数（失败率异常检测）
+CREATE INDEX IF NOT EXISTS idx_tasks_completed_at ON tasks(completed_at);
@@ -0,0 +1,5 @@
+-- 请求 ID：任务记录创建它的请求，事件记录引起它的请求
+ALTER TABLE tasks ADD COLUMN correlation_id TEXT;
+ALTER TABLE task_events ADD COLUMN correlation_id TEXT;
+ALTER TABLE outbox_events ADD COLUMN correlation_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_tasks_correlation_id ON tasks(correlation_id);
@@ -0,0 +1,27 @@
+-- 发件箱事件序号：单调递增且删除后不复用（AUTOINCREMENT），作为 WatchTask 的续传令牌。
+-- SQLite 不能通过 ALTER TABLE 添加自增列，重建表并按原写入顺序复制
+CREATE TABLE outbox_events_new (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL UNIQUE,
+	task_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	from_status INTEGER NOT NULL,
+	to_status INTEGER NOT NULL,
+	message TEXT,
+	operator TEXT,
+	instance_id TEXT,
+	correlation_id TEXT,
+	created_at TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TEXT NOT NULL,
+	delivered_at TEXT,
+	last_error TEXT
+);
+INSERT INTO outbox_events_new (id, task_id, event_type, from_status, to_status, message, operator, instance_id,
+	correlation_id, created_at, attempts, next_attempt_at, delivered_at, last_error)
+SELECT id, task_id, event_type, from_status, to_status, message, operator, instance_id,
+	correlation_id, created_at, attempts, next_attempt_at, delivered_at, last_error
+FROM outbox_events ORDER BY rowid;
+DROP TABLE outbox_events;
+ALTER TABLE outbox_events_new RENAME TO outbox_events;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(delivered_at, next_attempt_at);
@@ -0,0 +1,22 @@
+-- 任务事件序号：单调递增且删除后不复用（AUTOINCREMENT），作为变更流的游标。
+-- SQLite 不能通过 ALTER TABLE 添加自增列，重建表并按原写入顺序复制
+CREATE TABLE task_events_new (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL UNIQUE,
+	task_id TEXT NOT NULL,
+	from_status INTEGER NOT NULL,
+	to_status INTEGER NOT NULL,
+	message TEXT,
+	timestamp TEXT NOT NULL,
+	operator TEXT,
+	instance_id TEXT,
+	correlation_id TEXT,
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+INSERT INTO task_events_new (id, task_id, from_status, to_status, message, timestamp, operator, instance_id, correlation_id)
+SELECT id, task_id, from_status, to_status, message, timestamp, operator, instance_id, correlation_id
+FROM task_events ORDER BY rowid;
+DROP TABLE task_events;
+ALTER TABLE task_events_new RENAME TO task_events;
+CREATE INDEX IF NOT EXISTS idx_task_events_task_id ON task_events(task_id);
+CREATE INDEX IF NOT EXISTS idx_task_events_timestamp ON task_events(timestamp);
@@ -0,0 +1,2 @@
+-- 用户自定义的任务标签（JSON 对象）
+ALTER TABLE tasks ADD COLUMN labels TEXT;
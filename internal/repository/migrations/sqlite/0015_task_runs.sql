@@ -0,0 +1,19 @@
+-- 重新运行：原地重置前保存的历次运行结果，以及克隆出的任务关联的原任务
+ALTER TABLE tasks ADD COLUMN rerun_of TEXT;
+
+CREATE TABLE IF NOT EXISTS task_runs (
+	task_id TEXT NOT NULL,
+	run INTEGER NOT NULL,
+	status INTEGER NOT NULL,
+	output_result TEXT,
+	output_ref TEXT,
+	error_message TEXT,
+	error_class TEXT,
+	retry_count INTEGER NOT NULL DEFAULT 0,
+	executed_by TEXT,
+	started_at TEXT,
+	completed_at TEXT,
+	archived_at TEXT NOT NULL,
+	PRIMARY KEY (task_id, run),
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
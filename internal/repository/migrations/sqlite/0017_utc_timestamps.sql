@@ -0,0 +1,21 @@
+-- 时间统一按 UTC 存储：把以前按服务器本地时区写入、带偏移量的 RFC3339 时间转换为 UTC，
+-- 使按字符串比较的时间范围查询与时间顺序一致。已是 UTC（以 Z 结尾）的值和 NULL 不变
+UPDATE tasks SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at NOT LIKE '%Z';
+UPDATE tasks SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', updated_at) WHERE updated_at NOT LIKE '%Z';
+UPDATE tasks SET started_at = strftime('%Y-%m-%dT%H:%M:%SZ', started_at) WHERE started_at NOT LIKE '%Z';
+UPDATE tasks SET completed_at = strftime('%Y-%m-%dT%H:%M:%SZ', completed_at) WHERE completed_at NOT LIKE '%Z';
+UPDATE task_events SET timestamp = strftime('%Y-%m-%dT%H:%M:%SZ', timestamp) WHERE timestamp NOT LIKE '%Z';
+UPDATE task_comments SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at NOT LIKE '%Z';
+UPDATE task_attachments SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at NOT LIKE '%Z';
+UPDATE scheduler_instances SET started_at = strftime('%Y-%m-%dT%H:%M:%SZ', started_at) WHERE started_at NOT LIKE '%Z';
+UPDATE scheduler_instances SET heartbeat_at = strftime('%Y-%m-%dT%H:%M:%SZ', heartbeat_at) WHERE heartbeat_at NOT LIKE '%Z';
+UPDATE outbox_events SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at NOT LIKE '%Z';
+UPDATE outbox_events SET next_attempt_at = strftime('%Y-%m-%dT%H:%M:%SZ', next_attempt_at) WHERE next_attempt_at NOT LIKE '%Z';
+UPDATE outbox_events SET delivered_at = strftime('%Y-%m-%dT%H:%M:%SZ', delivered_at) WHERE delivered_at NOT LIKE '%Z';
+UPDATE teams SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at NOT LIKE '%Z';
+UPDATE team_members SET joined_at = strftime('%Y-%m-%dT%H:%M:%SZ', joined_at) WHERE joined_at NOT LIKE '%Z';
+UPDATE secrets SET created_at = strftime('%Y-%m-%dT%H:%M:%SZ', created_at) WHERE created_at NOT LIKE '%Z';
+UPDATE secrets SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', updated_at) WHERE updated_at NOT LIKE '%Z';
+UPDATE task_runs SET started_at = strftime('%Y-%m-%dT%H:%M:%SZ', started_at) WHERE started_at NOT LIKE '%Z';
+UPDATE task_runs SET completed_at = strftime('%Y-%m-%dT%H:%M:%SZ', completed_at) WHERE completed_at NOT LIKE '%Z';
+UPDATE task_runs SET archived_at = strftime('%Y-%m-%dT%H:%M:%SZ', archived_at) WHERE archived_at NOT LIKE '%Z';
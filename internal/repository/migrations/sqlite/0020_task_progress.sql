@@ -0,0 +1,5 @@
+-- 执行上下文：执行器上报的进度和心跳，以及执行中提交的子任务关联的父任务
+ALTER TABLE tasks ADD COLUMN progress INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN progress_message TEXT;
+ALTER TABLE tasks ADD COLUMN heartbeat_at TEXT;
+ALTER TABLE tasks ADD COLUMN parent_id TEXT;
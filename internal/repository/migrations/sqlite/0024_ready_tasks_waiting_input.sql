@@ -0,0 +1,10 @@
+-- ready_tasks：等待外部输入（WAITING_INPUT = 8）的上游同样尚未结束，下游任务不在视图中
+DROP VIEW IF EXISTS ready_tasks;
+CREATE VIEW ready_tasks AS
+SELECT t.id FROM tasks t
+WHERE t.status = 1 AND (t.dependencies IN ('null', '[]') OR NOT EXISTS (
+	SELECT 1 FROM json_each(t.dependencies) d
+	JOIN tasks up ON up.id = d.value
+	WHERE up.status IN (0, 1, 2, 8)
+		OR (up.status <> 3 AND json_extract(t.dependency_policies, '$."' || up.id || '"') = 'wait')
+));
@@ -0,0 +1,2 @@
+-- 优先级继承：任务被下游高优先级任务临时提升优先级前自身的优先级，未提升时为 NULL
+ALTER TABLE tasks ADD COLUMN base_priority INTEGER;
@@ -0,0 +1,3 @@
+-- 工作流截止时间（UTC 定宽格式，可按字符串比较），过后工作流中未结束的任务被取消
+ALTER TABLE tasks ADD COLUMN workflow_deadline TEXT;
+CREATE INDEX IF NOT EXISTS idx_tasks_workflow_deadline ON tasks(workflow_deadline);
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// insertOutboxEvent 在事务中写入发件箱事件，立即可投递
+func insertOutboxEvent(tx *sql.Tx, event *model.OutboxEvent, now string) error {
+	_, err := tx.Exec(`INSERT INTO outbox_events (
+		id, task_id, event_type, from_status, to_status, message, operator, instance_id, correlation_id, created_at, next_attempt_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.ID,
+		event.TaskID,
+		event.EventType,
+		event.FromStatus,
+		event.ToStatus,
+		event.Message,
+		event.Operator,
+		nullableString(event.InstanceID),
+		nullableString(event.CorrelationID),
+		now,
+		now,
+	)
+	return err
+}
+
+// ListDueOutboxEvents 列出到期待投递的发件箱事件，按写入顺序。
+// 同一任务较早的事件仍在退避等待时，其后的事件不会列出，以保持任务内的投递顺序
+func (r *TaskRepository) ListDueOutboxEvents(now time.Time, limit int) ([]*model.OutboxEvent, error) {
+	defer r.db.observe("tasks.ListDueOutboxEvents", time.Now(), "limit", limit)
+	ts := formatTime(now)
+	rows, err := r.db.DB().Query(`SELECT `+outboxColumns+`
+	FROM outbox_events o
+	WHERE o.delivered_at IS NULL AND o.next_attempt_at <= ?
+	AND NOT EXISTS (
+		SELECT 1 FROM outbox_events e
+		WHERE e.task_id = o.task_id AND e.delivered_at IS NULL AND e.seq < o.seq AND e.next_attempt_at > ?
+	)
+	ORDER BY o.seq ASC LIMIT ?`, ts, ts, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+// ListOutboxEventsAfter 按序号升序列出序号大于 afterSeq 的发件箱事件，不论是否已投递
+func (r *TaskRepository) ListOutboxEventsAfter(afterSeq int64, limit int) ([]*model.OutboxEvent, error) {
+	defer r.db.observe("tasks.ListOutboxEventsAfter", time.Now(), "after_seq", afterSeq, "limit", limit)
+	rows, err := r.db.DB().Query(`SELECT `+outboxColumns+` FROM outbox_events o WHERE o.seq > ? ORDER BY o.seq ASC LIMIT ?`, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxEvents(rows)
+}
+
+// OutboxSeqRange 返回仍保留的最小序号和已分配的最大序号；事件都已清理时 first 为 last+1。
+// 序号小于 first 的事件已被清理，无法再从续传令牌补发
+func (r *TaskRepository) OutboxSeqRange() (first, last int64, err error) {
+	defer r.db.observe("tasks.OutboxSeqRange", time.Now())
+	err = r.db.DB().QueryRow(`SELECT COALESCE((SELECT MIN(seq) FROM outbox_events), 0),
+		COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'outbox_events'), 0)`).Scan(&first, &last)
+	if err != nil {
+		return 0, 0, err
+	}
+	if first == 0 {
+		first = last + 1
+	}
+	return first, last, nil
+}
+
+// outboxColumns 发件箱事件查询列（顺序需与 scanOutboxEvents 保持一致）
+const outboxColumns = `o.seq, o.id, o.task_id, o.event_type, o.from_status, o.to_status, o.message, o.operator, o.instance_id,
+		o.correlation_id, o.created_at, o.attempts, o.next_attempt_at, o.last_error`
+
+// scanOutboxEvents 扫描发件箱事件并关闭 rows
+func scanOutboxEvents(rows *sql.Rows) ([]*model.OutboxEvent, error) {
+	defer rows.Close()
+
+	var events []*model.OutboxEvent
+	for rows.Next() {
+		var event model.OutboxEvent
+		var message, operator, instanceID, correlationID, lastError sql.NullString
+		var createdAt, nextAttemptAt string
+		if err := rows.Scan(&event.Seq, &event.ID, &event.TaskID, &event.EventType, &event.FromStatus, &event.ToStatus,
+			&message, &operator, &instanceID, &correlationID, &createdAt, &event.Attempts, &nextAttemptAt, &lastError); err != nil {
+			return nil, err
+		}
+		event.Message = message.String
+		event.Operator = operator.String
+		event.InstanceID = instanceID.String
+		event.CorrelationID = correlationID.String
+		event.LastError = lastError.String
+		event.CreatedAt = parseTimestamp(createdAt)
+		event.NextAttemptAt = parseTimestamp(nextAttemptAt)
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
+
+// MarkOutboxEventDelivered 标记发件箱事件已投递
+func (r *TaskRepository) MarkOutboxEventDelivered(id string, at time.Time) error {
+	defer r.db.observe("tasks.MarkOutboxEventDelivered", time.Now(), "id", id)
+	_, err := r.db.DB().Exec(`UPDATE outbox_events SET delivered_at = ?, attempts = attempts + 1, last_error = NULL WHERE id = ?`,
+		formatTime(at), id)
+	return err
+}
+
+// MarkOutboxEventFailed 记录投递失败并安排下次重试
+func (r *TaskRepository) MarkOutboxEventFailed(id string, lastErr string, nextAttemptAt time.Time) error {
+	defer r.db.observe("tasks.MarkOutboxEventFailed", time.Now(), "id", id)
+	_, err := r.db.DB().Exec(`UPDATE outbox_events SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		lastErr, formatTime(nextAttemptAt), id)
+	return err
+}
+
+// CountPendingOutboxEvents 未投递的发件箱事件数
+func (r *TaskRepository) CountPendingOutboxEvents() (int, error) {
+	defer r.db.observe("tasks.CountPendingOutboxEvents", time.Now())
+	var count int
+	err := r.db.DB().QueryRow(`SELECT COUNT(*) FROM outbox_events WHERE delivered_at IS NULL`).Scan(&count)
+	return count, err
+}
+
+// PurgeDeliveredOutboxEvents 删除在 before 之前已投递的发件箱事件
+func (r *TaskRepository) PurgeDeliveredOutboxEvents(before time.Time) (int64, error) {
+	defer r.db.observe("tasks.PurgeDeliveredOutboxEvents", time.Now(), "before", before)
+	result, err := r.db.DB().Exec(`DELETE FROM outbox_events WHERE delivered_at IS NOT NULL AND delivered_at < ?`,
+		formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// ListWaitingOnDependencies 列出优先级不低于 minPriority、有上游尚未结束（或未成功且按 wait 处理）的 PENDING 任务，
+// 按优先级降序、创建时间升序，供调度器把优先级传递给上游
+func (r *TaskRepository) ListWaitingOnDependencies(minPriority model.TaskPriority, limit int) ([]*model.Task, error) {
+	defer r.db.observe("tasks.ListWaitingOnDependencies", time.Now(), "min_priority", minPriority, "limit", limit)
+	rows, err := r.db.DB().Query(`SELECT `+taskColumns+` FROM tasks WHERE status = ? AND priority >= ?
+	AND dependencies NOT IN ('null', '[]')
+	AND NOT EXISTS (SELECT 1 FROM ready_tasks r WHERE r.id = tasks.id)
+	ORDER BY priority DESC, created_at ASC LIMIT ?`,
+		model.TaskStatusPending, minPriority, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanTasks(rows)
+}
+
+// BoostPriority 把 PENDING 任务的优先级临时提升到 priority，保留提升前的优先级，同一事务中写入事件和 task.priority_boosted 发件箱事件。
+// 任务已不是 PENDING 或优先级已不低于 priority 时返回 ErrStatusMismatch
+func (r *TaskRepository) BoostPriority(taskID string, priority model.TaskPriority, operator, message, instanceID string) error {
+	defer r.db.observe("tasks.BoostPriority", time.Now(), "task_id", taskID, "priority", priority)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		now := formatTime(time.Now())
+		result, err := tx.Exec(`UPDATE tasks SET base_priority = COALESCE(base_priority, priority), priority = ?,
+			updated_at = ?, version = version + 1
+			WHERE id = ? AND status = ? AND priority < ?`,
+			priority, now, taskID, model.TaskStatusPending, priority)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		return insertTaskEvent(tx, model.OutboxEventTaskPriorityBoosted, taskID, model.TaskStatusPending, model.TaskStatusPending, operator, message, instanceID, "", now, nil)
+	})
+}
+
+// RestorePriority 恢复任务被提升前的优先级，同一事务中写入事件和 task.priority_restored 发件箱事件。
+// 任务未被提升时返回 ErrStatusMismatch
+func (r *TaskRepository) RestorePriority(taskID, operator, message, instanceID string) error {
+	defer r.db.observe("tasks.RestorePriority", time.Now(), "task_id", taskID)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		var status model.TaskStatus
+		err := tx.QueryRow(`SELECT status FROM tasks WHERE id = ? AND base_priority IS NOT NULL`, taskID).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrStatusMismatch
+		}
+		if err != nil {
+			return err
+		}
+
+		now := formatTime(time.Now())
+		result, err := tx.Exec(`UPDATE tasks SET priority = base_priority, base_priority = NULL, updated_at = ?, version = version + 1
+			WHERE id = ? AND base_priority IS NOT NULL`, now, taskID)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		return insertTaskEvent(tx, model.OutboxEventTaskPriorityRestored, taskID, status, status, operator, message, instanceID, "", now, nil)
+	})
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// ListQueueTimeCandidates 列出尚未开始执行、开始排队的时间（重新运行后为重新排队的时间，否则为创建时间）不晚于 queuedBefore
+// 且尚未记录排队超时的 PENDING 任务，按开始排队的时间升序。taskTypes 不为空时只列出这些类型，excludeTypes 中的类型不列出，
+// 任务类型不区分大小写
+func (r *TaskRepository) ListQueueTimeCandidates(queuedBefore time.Time, taskTypes, excludeTypes []string, limit int) ([]*model.Task, error) {
+	defer r.db.observe("tasks.ListQueueTimeCandidates", time.Now(), "task_types", taskTypes, "limit", limit)
+	conditions := []string{"status = ?", "started_at IS NULL", "queue_alerted_at IS NULL", "COALESCE(queued_at, created_at) <= ?"}
+	args := []interface{}{model.TaskStatusPending, formatTime(queuedBefore)}
+	if len(taskTypes) > 0 {
+		conditions = append(conditions, "LOWER(task_type) IN ("+inPlaceholders(len(taskTypes))+")")
+		for _, t := range taskTypes {
+			args = append(args, strings.ToLower(t))
+		}
+	}
+	if len(excludeTypes) > 0 {
+		conditions = append(conditions, "LOWER(task_type) NOT IN ("+inPlaceholders(len(excludeTypes))+")")
+		for _, t := range excludeTypes {
+			args = append(args, strings.ToLower(t))
+		}
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.DB().Query(`SELECT `+taskColumns+` FROM tasks WHERE `+strings.Join(conditions, " AND ")+`
+	ORDER BY COALESCE(queued_at, created_at) ASC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanTasks(rows)
+}
+
+// MarkQueueTimeExceeded 记录任务排队超时的时间，escalateTo 高于任务自身优先级时按 model.Task.EscalatePriority 提高优先级，
+// 同一事务中写入事件和 task.queue_time_exceeded 发件箱事件。任务已开始执行或已记录过排队超时时返回 ErrStatusMismatch，
+// 多个实例同时检查时只有一个成功
+func (r *TaskRepository) MarkQueueTimeExceeded(taskID string, at time.Time, escalateTo model.TaskPriority, operator, message, instanceID string) error {
+	defer r.db.observe("tasks.MarkQueueTimeExceeded", time.Now(), "task_id", taskID, "escalate_to", escalateTo)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		var task model.Task
+		var basePriority sql.NullInt32
+		err := tx.QueryRow(`SELECT priority, base_priority FROM tasks
+			WHERE id = ? AND status = ? AND started_at IS NULL AND queue_alerted_at IS NULL`,
+			taskID, model.TaskStatusPending).Scan(&task.Priority, &basePriority)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrStatusMismatch
+		}
+		if err != nil {
+			return err
+		}
+		task.BasePriority = model.TaskPriority(basePriority.Int32)
+		task.EscalatePriority(escalateTo, at)
+
+		now := formatTime(at)
+		result, err := tx.Exec(`UPDATE tasks SET queue_alerted_at = ?, priority = ?, base_priority = ?, updated_at = ?, version = version + 1
+			WHERE id = ? AND status = ? AND queue_alerted_at IS NULL`,
+			at.UTC().Format(utcMillisLayout), task.Priority, nullablePriority(task.BasePriority), now, taskID, model.TaskStatusPending)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		return insertTaskEvent(tx, model.OutboxEventTaskQueueTimeExceeded, taskID, model.TaskStatusPending, model.TaskStatusPending, operator, message, instanceID, "", now, nil)
+	})
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+)
+
+// ReplicatedTaskStore 读写分离的任务仓储。写入和默认读取都走主库，调度器的读写路径不受影响；
+// Stale 返回的视图把列表、搜索和统计等重查询分到延迟不超过容忍度的只读副本，没有合适的副本时读主库。
+// 副本延迟由 Monitor 按任务事件测量：主库中副本尚未同步的最早事件距今的时间
+type ReplicatedTaskStore struct {
+	TaskStore
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// replica 只读副本及最近测量的延迟
+type replica struct {
+	name  string
+	store TaskStore
+	lag   atomic.Int64 // 纳秒，小于 0 表示尚未测量或不可用
+}
+
+// NewReplicatedTaskStore 创建读写分离的任务仓储，replicas 以名称（如 DSN）为键，名称用于日志和指标。
+// 副本在 Monitor 首次测量前不参与读取
+func NewReplicatedTaskStore(primary TaskStore, replicas map[string]TaskStore) *ReplicatedTaskStore {
+	s := &ReplicatedTaskStore{TaskStore: primary}
+	for name, store := range replicas {
+		r := &replica{name: name, store: store}
+		r.lag.Store(-1)
+		s.replicas = append(s.replicas, r)
+	}
+	sort.Slice(s.replicas, func(i, j int) bool { return s.replicas[i].name < s.replicas[j].name })
+	return s
+}
+
+var _ TaskStore = (*ReplicatedTaskStore)(nil)
+
+// Monitor 按 interval 测量各副本的延迟，直到 ctx 取消
+func (s *ReplicatedTaskStore) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, r := range s.replicas {
+			s.measure(r)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// measure 测量副本延迟：读取副本的最新事件序号，主库中此后的第一个事件距今的时间即为延迟，没有则已同步。
+// 不产生事件的字段修改不计入延迟
+func (s *ReplicatedTaskStore) measure(r *replica) {
+	seq, err := r.store.LatestEventSeq()
+	if err != nil {
+		if r.lag.Swap(-1) >= 0 {
+			logger.Warnf("Read replica %s unavailable: %v", r.name, err)
+		}
+		metrics.SetReplicaLag(r.name, -1)
+		return
+	}
+	events, err := s.TaskStore.ListEventsAfter(seq, 1)
+	if err != nil {
+		logger.Errorf("Failed to measure lag of read replica %s: %v", r.name, err)
+		return
+	}
+	var lag time.Duration
+	if len(events) > 0 {
+		lag = max(time.Since(events[0].Timestamp), 0)
+	}
+	r.lag.Store(int64(lag))
+	metrics.SetReplicaLag(r.name, lag)
+}
+
+// ReplicaLag 返回各副本最近测量的延迟，小于 0 表示尚未测量或不可用
+func (s *ReplicatedTaskStore) ReplicaLag() map[string]time.Duration {
+	lags := make(map[string]time.Duration, len(s.replicas))
+	for _, r := range s.replicas {
+		lags[r.name] = time.Duration(r.lag.Load())
+	}
+	return lags
+}
+
+// Stale 返回容忍 maxStaleness 延迟的读取视图，重查询分到副本，其余方法与本仓储相同。maxStaleness 不大于 0 时返回本仓储
+func (s *ReplicatedTaskStore) Stale(maxStaleness time.Duration) TaskStore {
+	if maxStaleness <= 0 {
+		return s
+	}
+	return &staleTaskStore{TaskStore: s, replicated: s, maxStaleness: maxStaleness}
+}
+
+// pick 轮询选择延迟不超过 maxStaleness 的副本，没有时返回 nil
+func (s *ReplicatedTaskStore) pick(maxStaleness time.Duration) *replica {
+	n := len(s.replicas)
+	if n == 0 {
+		return nil
+	}
+	start := int(s.next.Add(1) % uint64(n))
+	for i := range n {
+		r := s.replicas[(start+i)%n]
+		if lag := r.lag.Load(); lag >= 0 && time.Duration(lag) <= maxStaleness {
+			return r
+		}
+	}
+	return nil
+}
+
+// StaleReader 支持容忍延迟读取的任务仓储
+type StaleReader interface {
+	Stale(maxStaleness time.Duration) TaskStore
+}
+
+// StaleReads 返回 store 容忍 maxStaleness 延迟的读取视图，store 不支持时原样返回。
+// 调用方按查询对新鲜度的要求选择容忍度，如任务列表可容忍数秒，统计可容忍更久
+func StaleReads(store TaskStore, maxStaleness time.Duration) TaskStore {
+	if r, ok := store.(StaleReader); ok {
+		return r.Stale(maxStaleness)
+	}
+	return store
+}
+
+// staleTaskStore 容忍延迟的读取视图：重查询先读副本，副本出错时标记为不可用并改读 TaskStore，其余方法直接转发
+type staleTaskStore struct {
+	TaskStore
+	replicated   *ReplicatedTaskStore
+	maxStaleness time.Duration
+}
+
+// read 在选中的副本上执行查询，没有合适的副本或副本出错时在 TaskStore 上执行
+func (v *staleTaskStore) read(query func(TaskStore) error) error {
+	if r := v.replicated.pick(v.maxStaleness); r != nil {
+		err := query(r.store)
+		if err == nil {
+			metrics.RecordReplicaRead("replica")
+			return nil
+		}
+		// 下次测量成功前不再选择该副本
+		r.lag.Store(-1)
+		logger.Warnf("Read replica %s failed, falling back to primary: %v", r.name, err)
+	}
+	metrics.RecordReplicaRead("primary")
+	return query(v.TaskStore)
+}
+
+// List 分页列出任务，优先读副本
+func (v *staleTaskStore) List(limit, offset int, statusFilter *model.TaskStatus) (tasks []*model.Task, err error) {
+	err = v.read(func(store TaskStore) (err error) {
+		tasks, err = store.List(limit, offset, statusFilter)
+		return err
+	})
+	return tasks, err
+}
+
+// ListByCreator 按创建者列出任务，优先读副本
+func (v *staleTaskStore) ListByCreator(createdBy string, limit, offset int) (tasks []*model.Task, err error) {
+	err = v.read(func(store TaskStore) (err error) {
+		tasks, err = store.ListByCreator(createdBy, limit, offset)
+		return err
+	})
+	return tasks, err
+}
+
+// ListByFilter 多条件过滤查询，优先读副本
+func (v *staleTaskStore) ListByFilter(filter TaskFilter) (tasks []*model.Task, total int, err error) {
+	err = v.read(func(store TaskStore) (err error) {
+		tasks, total, err = store.ListByFilter(filter)
+		return err
+	})
+	return tasks, total, err
+}
+
+// Search 关键词搜索，优先读副本
+func (v *staleTaskStore) Search(keyword string, limit, offset int) (tasks []*model.Task, err error) {
+	err = v.read(func(store TaskStore) (err error) {
+		tasks, err = store.Search(keyword, limit, offset)
+		return err
+	})
+	return tasks, err
+}
+
+// Count 统计任务数量，优先读副本
+func (v *staleTaskStore) Count(statusFilter *model.TaskStatus) (n int, err error) {
+	err = v.read(func(store TaskStore) (err error) {
+		n, err = store.Count(statusFilter)
+		return err
+	})
+	return n, err
+}
+
+// RecentDurations 最近完成任务的耗时，优先读副本
+func (v *staleTaskStore) RecentDurations(taskType string, limit int) (durations []time.Duration, err error) {
+	err = v.read(func(store TaskStore) (err error) {
+		durations, err = store.RecentDurations(taskType, limit)
+		return err
+	})
+	return durations, err
+}
+
+// ListTaskTypes 列出任务类型，优先读副本
+func (v *staleTaskStore) ListTaskTypes() (types []string, err error) {
+	err = v.read(func(store TaskStore) (err error) {
+		types, err = store.ListTaskTypes()
+		return err
+	})
+	return types, err
+}
+
+// CountOutcomesByType 按任务类型统计结束结果，优先读副本
+func (v *staleTaskStore) CountOutcomesByType(from, to time.Time) (counts map[string]OutcomeCount, err error) {
+	err = v.read(func(store TaskStore) (err error) {
+		counts, err = store.CountOutcomesByType(from, to)
+		return err
+	})
+	return counts, err
+}
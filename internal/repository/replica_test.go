@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// failingReplica 列表查询总是失败的副本
+type failingReplica struct {
+	TaskStore
+}
+
+func (f failingReplica) ListByFilter(TaskFilter) ([]*model.Task, int, error) {
+	return nil, 0, errors.New("replica connection lost")
+}
+
+func TestReplicatedTaskStore(t *testing.T) {
+	primary, _ := NewMemoryRepositories()
+	replicaStore, _ := NewMemoryRepositories()
+	store := NewReplicatedTaskStore(primary, map[string]TaskStore{"replica-1": replicaStore})
+
+	// 写入只到主库，副本尚未同步
+	if err := store.Create(newStoreTask("t1", model.TaskPriorityNormal, time.Now())); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if n, _ := replicaStore.Count(nil); n != 0 {
+		t.Fatalf("expected writes to skip the replica, got %d tasks", n)
+	}
+	count := func(store TaskStore) int {
+		t.Helper()
+		_, total, err := store.ListByFilter(TaskFilter{})
+		if err != nil {
+			t.Fatalf("ListByFilter: %v", err)
+		}
+		return total
+	}
+
+	// 首次测量前不读副本
+	if n := count(store.Stale(time.Hour)); n != 1 {
+		t.Errorf("expected the primary before the replica is measured, got %d tasks", n)
+	}
+
+	store.measure(store.replicas[0])
+	if lag := store.ReplicaLag()["replica-1"]; lag < 0 || lag > time.Minute {
+		t.Fatalf("expected a small measured lag, got %s", lag)
+	}
+	if n := count(store.Stale(time.Hour)); n != 0 {
+		t.Errorf("expected the stale view to read the replica, got %d tasks", n)
+	}
+	if n := count(store); n != 1 {
+		t.Errorf("expected default reads from the primary, got %d tasks", n)
+	}
+	if n := count(StaleReads(store, 0)); n != 1 {
+		t.Errorf("expected zero tolerance to read the primary, got %d tasks", n)
+	}
+	if view := StaleReads(primary, time.Hour); view != TaskStore(primary) {
+		t.Errorf("expected stores without replicas to be returned as is")
+	}
+
+	// 副本落后超过容忍度时读主库
+	if err := primary.AddEvent(&model.TaskEvent{ID: "e-old", TaskID: "t1", Timestamp: time.Now().Add(-10 * time.Minute)}); err != nil {
+		t.Fatalf("failed to add event: %v", err)
+	}
+	if err := replicaStore.Create(newStoreTask("t1", model.TaskPriorityNormal, time.Now())); err != nil {
+		t.Fatalf("failed to sync replica: %v", err)
+	}
+	store.measure(store.replicas[0])
+	if lag := store.ReplicaLag()["replica-1"]; lag < 10*time.Minute {
+		t.Fatalf("expected the lag of the unsynced event, got %s", lag)
+	}
+	if view := store.Stale(time.Minute); count(view) != 1 || store.pick(time.Minute) != nil {
+		t.Errorf("expected a replica lagging beyond tolerance to be skipped")
+	}
+	if store.pick(time.Hour) == nil {
+		t.Errorf("expected the replica within a larger tolerance")
+	}
+
+	// 经过缓存的仓储同样支持容忍延迟读取
+	cached := NewCachedTaskStore(store, NewLRUTaskCache(10, 0))
+	if _, ok := StaleReads(cached, time.Hour).(*staleTaskStore); !ok {
+		t.Errorf("expected the cached store to forward stale reads")
+	}
+
+	// 副本出错时改读主库并在下次测量前停用
+	failing := NewReplicatedTaskStore(primary, map[string]TaskStore{"replica-2": failingReplica{replicaStore}})
+	failing.measure(failing.replicas[0])
+	if n := count(failing.Stale(time.Hour)); n != 1 {
+		t.Errorf("expected fallback to the primary, got %d tasks", n)
+	}
+	if lag := failing.ReplicaLag()["replica-2"]; lag >= 0 {
+		t.Errorf("expected the failed replica to be marked unavailable, got %s", lag)
+	}
+}
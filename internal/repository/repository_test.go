@@ -1,14 +1,18 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"taskflow/internal/model"
 )
 
 // setupTestDB 创建测试数据库
-func setupTestDB(t *testing.T) (*SQLite, func()) {
+func setupTestDB(t testing.TB) (*SQLite, func()) {
 	tmpFile, err := os.CreateTemp("", "taskflow_test_*.db")
 	if err != nil {
 		t.Fatalf("failed to create temp file: %v", err)
@@ -34,6 +38,33 @@ func setupTestDB(t *testing.T) (*SQLite, func()) {
 	return db, cleanup
 }
 
+func TestSQLiteDSN(t *testing.T) {
+	cases := map[string]string{
+		"/data/taskflow.db":                       "/data/taskflow.db?_busy_timeout=5000&_journal_mode=WAL",
+		"/data/taskflow.db?_busy_timeout=100":     "/data/taskflow.db?_busy_timeout=100&_journal_mode=WAL",
+		"/data/taskflow.db?_journal_mode=DELETE":  "/data/taskflow.db?_journal_mode=DELETE&_busy_timeout=5000",
+		"file:/data/replica.db?mode=ro":           "file:/data/replica.db?mode=ro&_busy_timeout=5000",
+		":memory:":                                ":memory:?_busy_timeout=5000",
+		"file::memory:?cache=shared&_timeout=200": "file::memory:?cache=shared&_timeout=200",
+	}
+	for dsn, want := range cases {
+		if got := sqliteDSN(dsn); got != want {
+			t.Errorf("sqliteDSN(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	var mode string
+	var timeout int
+	if err := db.DB().QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal_mode = %q, %v", mode, err)
+	}
+	if err := db.DB().QueryRow(`PRAGMA busy_timeout`).Scan(&timeout); err != nil || timeout != 5000 {
+		t.Errorf("busy_timeout = %d, %v", timeout, err)
+	}
+}
+
 func TestTaskRepository_Create(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -97,14 +128,56 @@ func TestTaskRepository_GetByID(t *testing.T) {
 
 	// 测试不存在的任务
 	notFound, err := repo.GetByID("non-existent")
-	if err != nil {
-		t.Fatalf("error should be nil for not found: %v", err)
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
 	}
 	if notFound != nil {
 		t.Error("task should be nil for non-existent ID")
 	}
 }
 
+func TestTaskRepository_GetByIDs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	for _, id := range []string{"batch-1", "batch-2", "batch-3"} {
+		task := model.NewTask(id, "desc", model.TaskPriorityNormal, "test", nil, nil, 3, "test")
+		task.ID = id
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	// 结果保持请求顺序，不存在的 ID 对应 nil
+	tasks, err := repo.GetByIDs([]string{"batch-3", "missing", "batch-1"})
+	if err != nil {
+		t.Fatalf("failed to get tasks: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(tasks))
+	}
+	if tasks[0] == nil || tasks[0].ID != "batch-3" {
+		t.Errorf("expected batch-3 first, got %v", tasks[0])
+	}
+	if tasks[1] != nil {
+		t.Errorf("expected nil for missing id, got %v", tasks[1])
+	}
+	if tasks[2] == nil || tasks[2].ID != "batch-1" {
+		t.Errorf("expected batch-1 last, got %v", tasks[2])
+	}
+
+	// 空请求
+	tasks, err = repo.GetByIDs(nil)
+	if err != nil {
+		t.Fatalf("failed to get tasks: %v", err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("expected no results, got %d", len(tasks))
+	}
+}
+
 func TestTaskRepository_Update(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -152,14 +225,14 @@ func TestTaskRepository_Delete(t *testing.T) {
 	}
 
 	// 删除任务
-	if err := repo.Delete("delete-test-1"); err != nil {
+	if _, err := repo.Delete("delete-test-1", false, "test", ""); err != nil {
 		t.Fatalf("failed to delete task: %v", err)
 	}
 
 	// 验证删除
 	deleted, err := repo.GetByID("delete-test-1")
-	if err != nil {
-		t.Fatalf("error should be nil: %v", err)
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("expected ErrTaskNotFound, got %v", err)
 	}
 	if deleted != nil {
 		t.Error("task should be nil after delete")
@@ -244,7 +317,7 @@ func TestTaskRepository_ListPending(t *testing.T) {
 	}
 
 	// 列出待处理任务
-	pending, err := repo.ListPending(10)
+	pending, err := repo.ListPending(10, time.Now())
 	if err != nil {
 		t.Fatalf("failed to list pending tasks: %v", err)
 	}
@@ -253,6 +326,101 @@ func TestTaskRepository_ListPending(t *testing.T) {
 	}
 }
 
+func TestTaskRepository_ListPendingUsesSchedulerIndex(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	rows, err := db.DB().Query(`EXPLAIN QUERY PLAN `+listPendingQuery, model.TaskStatusPending, time.Now().UTC().Format(utcMillisLayout), 10)
+	if err != nil {
+		t.Fatalf("explain failed: %v", err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		plan = append(plan, detail)
+	}
+	joined := strings.Join(plan, "\n")
+	// 按复合索引顺序扫描，不对全部 PENDING 任务排序
+	if !strings.Contains(joined, "idx_tasks_status_priority_created_at") || strings.Contains(joined, "TEMP B-TREE") {
+		t.Errorf("expected ListPending to walk idx_tasks_status_priority_created_at without sorting, got plan:\n%s", joined)
+	}
+}
+
+func TestTaskRepository_ScheduleRetry(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	task := model.NewTask("Retry Task", "desc", model.TaskPriorityNormal, "test", nil, nil, 0, "test")
+	task.ID = "retry-test-1"
+	task.RetryPolicy = &model.RetryPolicy{MaxAttempts: 3, Backoff: model.BackoffFixed, InitialDelayMs: 60000}
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if err := repo.UpdateStatusWithEvent(task.ID, model.TaskStatusPending, model.TaskStatusRunning, "test", "started"); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+
+	next := time.Now().Add(time.Minute)
+	if err := repo.ScheduleRetry(task.ID, model.TaskStatusRunning, 0, &next, "boom", model.ErrorClassRetryable, "scheduler", "retry 1/2", "", nil); err != nil {
+		t.Fatalf("ScheduleRetry failed: %v", err)
+	}
+
+	got, err := repo.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if got.Status != model.TaskStatusPending || got.RetryCount != 1 || got.ErrorMessage != "boom" || got.ErrorClass != model.ErrorClassRetryable {
+		t.Errorf("unexpected task after retry: status=%s retry=%d error=%q class=%q", got.Status, got.RetryCount, got.ErrorMessage, got.ErrorClass)
+	}
+	if got.NextRunAt == nil || got.NextRunAt.Sub(next).Abs() > time.Millisecond {
+		t.Errorf("expected next run at %s, got %v", next, got.NextRunAt)
+	}
+	if got.RetryPolicy == nil || got.RetryPolicy.InitialDelayMs != 60000 {
+		t.Errorf("retry policy not persisted: %+v", got.RetryPolicy)
+	}
+
+	// 退避未到期的任务不在待调度列表中
+	pending, err := repo.ListPending(10, time.Now())
+	if err != nil {
+		t.Fatalf("failed to list pending tasks: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected task in backoff to be skipped, got %d pending", len(pending))
+	}
+	// 以退避到期的时刻查询时出现在列表中
+	if pending, _ := repo.ListPending(10, next.Add(time.Second)); len(pending) != 1 {
+		t.Errorf("expected task to be listed once its backoff has elapsed, got %d pending", len(pending))
+	}
+
+	// 状态不是 RUNNING 时拒绝
+	if err := repo.ScheduleRetry(task.ID, model.TaskStatusRunning, 1, nil, "boom", "", "scheduler", "retry 2/2", "", nil); err == nil {
+		t.Error("expected status mismatch error")
+	}
+
+	// 读到的重试次数已过期时拒绝，不会重复累加
+	if err := repo.UpdateStatusWithEvent(task.ID, model.TaskStatusPending, model.TaskStatusRunning, "test", "started"); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	if err := repo.ScheduleRetry(task.ID, model.TaskStatusRunning, 0, nil, "boom", "", "scheduler", "retry 2/2", "", nil); err != ErrStatusMismatch {
+		t.Errorf("expected ErrStatusMismatch for stale retry count, got %v", err)
+	}
+	if err := repo.ScheduleRetry(task.ID, model.TaskStatusRunning, 1, nil, "boom", "", "scheduler", "retry 2/2", "", nil); err != nil {
+		t.Fatalf("ScheduleRetry failed: %v", err)
+	}
+	got, _ = repo.GetByID(task.ID)
+	if got.RetryCount != 2 {
+		t.Errorf("expected retry count 2, got %d", got.RetryCount)
+	}
+}
+
 func TestTaskRepository_Count(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -364,14 +532,14 @@ func TestTaskRepository_UpdateStatusWithEvent(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to get events: %v", err)
 	}
-	if len(events) != 1 {
-		t.Errorf("expected 1 event, got %d", len(events))
+	if len(events) != 2 {
+		t.Fatalf("expected creation and status events, got %d", len(events))
 	}
-	if events[0].Message != "starting task" {
-		t.Errorf("expected message 'starting task', got '%s'", events[0].Message)
+	if events[1].Message != "starting task" {
+		t.Errorf("expected message 'starting task', got '%s'", events[1].Message)
 	}
-	if events[0].Operator != "test-operator" {
-		t.Errorf("expected operator 'test-operator', got '%s'", events[0].Operator)
+	if events[1].Operator != "test-operator" {
+		t.Errorf("expected operator 'test-operator', got '%s'", events[1].Operator)
 	}
 }
 
@@ -392,9 +560,10 @@ func TestTaskRepository_AddEvent(t *testing.T) {
 	event := &model.TaskEvent{
 		ID:         "event-1",
 		TaskID:     "addevent-test-1",
-		FromStatus: model.TaskStatusUnspecified,
+		FromStatus: model.TaskStatusPending,
 		ToStatus:   model.TaskStatusPending,
-		Message:    "task created",
+		Message:    "note",
+		Timestamp:  time.Now(),
 		Operator:   "system",
 	}
 	err := repo.AddEvent(event)
@@ -407,8 +576,56 @@ func TestTaskRepository_AddEvent(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to get events: %v", err)
 	}
-	if len(events) != 1 {
-		t.Errorf("expected 1 event, got %d", len(events))
+	if len(events) != 2 || events[0].Message != "task created" || events[1].ID != "event-1" {
+		t.Errorf("expected creation and added events, got %+v", events)
+	}
+}
+
+func TestTaskRepository_Comments(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	task := model.NewTask("Comment Test", "desc", model.TaskPriorityNormal, "test", nil, nil, 3, "test")
+	task.ID = "comment-test-1"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 按时间顺序添加两条评论
+	now := time.Now()
+	for i, body := range []string{"investigating", "root cause: upstream timeout"} {
+		comment := &model.TaskComment{
+			ID:        fmt.Sprintf("comment-%d", i),
+			TaskID:    "comment-test-1",
+			Author:    "oncall",
+			Body:      body,
+			CreatedAt: now,
+		}
+		if err := repo.AddComment(comment); err != nil {
+			t.Fatalf("failed to add comment: %v", err)
+		}
+	}
+
+	comments, err := repo.GetCommentsByTaskID("comment-test-1")
+	if err != nil {
+		t.Fatalf("failed to get comments: %v", err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("expected 2 comments, got %d", len(comments))
+	}
+	if comments[0].Body != "investigating" || comments[1].Author != "oncall" {
+		t.Errorf("unexpected comments: %+v", comments)
+	}
+
+	// GetByID 随事件一起加载评论
+	got, err := repo.GetByID("comment-test-1")
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if len(got.Comments) != 2 {
+		t.Errorf("expected 2 comments on task, got %d", len(got.Comments))
 	}
 }
 
@@ -511,3 +728,317 @@ func TestTaskRepository_ListByFilter(t *testing.T) {
 		t.Errorf("expected 2 results, got %d", len(results3))
 	}
 }
+
+func TestTaskRepository_Logs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	task := model.NewTask("Log Test", "desc", model.TaskPriorityNormal, "test", nil, nil, 3, "test")
+	task.ID = "log-test-1"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	var lines []model.TaskLogLine
+	for i := int64(1); i <= 5; i++ {
+		lines = append(lines, model.TaskLogLine{TaskID: task.ID, Seq: i, Attempt: 1, Timestamp: time.Now(), Line: fmt.Sprintf("line %d", i)})
+	}
+	if err := repo.AppendLogs(lines); err != nil {
+		t.Fatalf("failed to append logs: %v", err)
+	}
+
+	if seq, err := repo.LastLogSeq(task.ID); err != nil || seq != 5 {
+		t.Fatalf("expected last seq 5, got %d (%v)", seq, err)
+	}
+
+	got, err := repo.GetLogs(task.ID, 2, 2)
+	if err != nil {
+		t.Fatalf("failed to get logs: %v", err)
+	}
+	if len(got) != 2 || got[0].Seq != 3 || got[1].Line != "line 4" {
+		t.Errorf("unexpected logs: %+v", got)
+	}
+
+	// 只保留最近 2 行
+	if err := repo.TrimLogs(task.ID, 2); err != nil {
+		t.Fatalf("failed to trim logs: %v", err)
+	}
+	got, err = repo.GetLogs(task.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to get logs: %v", err)
+	}
+	if len(got) != 2 || got[0].Seq != 4 {
+		t.Errorf("expected seq 4-5 after trim, got %+v", got)
+	}
+
+	// 无日志的任务
+	if seq, err := repo.LastLogSeq("missing"); err != nil || seq != 0 {
+		t.Errorf("expected 0 for task without logs, got %d (%v)", seq, err)
+	}
+}
+
+func TestTaskRepository_SchedulerInstances(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	task := model.NewTask("Instance Test", "desc", model.TaskPriorityNormal, "test", nil, nil, 0, "test")
+	task.ID = "instance-test-1"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 转为 RUNNING 时记录执行实例，事件记录实例 ID
+	if err := repo.UpdateStatusWithInstanceEvent(task.ID, model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "task scheduled", "node-a", nil); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	got, err := repo.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if got.ExecutedBy != "node-a" {
+		t.Errorf("expected executed_by node-a, got %q", got.ExecutedBy)
+	}
+	if n := len(got.Events); n == 0 || got.Events[n-1].InstanceID != "node-a" {
+		t.Errorf("expected last event from node-a, got %+v", got.Events)
+	}
+
+	started := time.Now().Add(-time.Minute)
+	inst := &model.SchedulerInstance{
+		ID:            "node-a",
+		Hostname:      "host-a",
+		StartedAt:     started,
+		HeartbeatAt:   started,
+		WorkerCount:   4,
+		InFlightTasks: []string{task.ID},
+		Labels:        map[string]string{"gpu": "true"},
+	}
+	if err := repo.UpsertSchedulerInstance(inst); err != nil {
+		t.Fatalf("failed to upsert instance: %v", err)
+	}
+
+	// 再次心跳更新计数，启动时间保持不变
+	inst.HeartbeatAt = time.Now()
+	inst.BusyWorkers = 1
+	inst.StartedAt = time.Now()
+	if err := repo.UpsertSchedulerInstance(inst); err != nil {
+		t.Fatalf("failed to upsert instance: %v", err)
+	}
+
+	instances, err := repo.ListSchedulerInstances()
+	if err != nil {
+		t.Fatalf("failed to list instances: %v", err)
+	}
+	if len(instances) != 1 {
+		t.Fatalf("expected 1 instance, got %d", len(instances))
+	}
+	if i := instances[0]; i.BusyWorkers != 1 || i.StartedAt.Unix() != started.Unix() || len(i.InFlightTasks) != 1 || i.Labels["gpu"] != "true" {
+		t.Errorf("unexpected instance: %+v", i)
+	}
+
+	if err := repo.DeleteSchedulerInstance("node-a"); err != nil {
+		t.Fatalf("failed to delete instance: %v", err)
+	}
+	if instances, _ := repo.ListSchedulerInstances(); len(instances) != 0 {
+		t.Errorf("expected no instances after delete, got %d", len(instances))
+	}
+}
+
+func TestTaskRepository_OutboxWrittenWithStatusChange(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+
+	task := model.NewTask("Outbox Test", "desc", model.TaskPriorityNormal, "test", nil, nil, 0, "test")
+	task.ID = "outbox-test-1"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	if err := repo.UpdateStatusWithInstanceEvent(task.ID, model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "task scheduled", "node-a", nil); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	// 状态不匹配时事务回滚，不写入发件箱
+	if err := repo.UpdateStatusWithEvent(task.ID, model.TaskStatusPending, model.TaskStatusCancelled, "user", "cancel"); err == nil {
+		t.Fatal("expected status mismatch error")
+	}
+
+	now := time.Now()
+	events, err := repo.ListDueOutboxEvents(now, 10)
+	if err != nil {
+		t.Fatalf("failed to list outbox events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 outbox event, got %d", len(events))
+	}
+	e := events[0]
+	if e.TaskID != task.ID || e.FromStatus != model.TaskStatusPending || e.ToStatus != model.TaskStatusRunning || e.InstanceID != "node-a" {
+		t.Errorf("unexpected outbox event: %+v", e)
+	}
+
+	// 事件 ID 与任务事件一致，供消费者去重
+	taskEvents, _ := repo.GetEventsByTaskID(task.ID)
+	if len(taskEvents) != 2 || taskEvents[1].ID != e.ID {
+		t.Errorf("expected outbox ID to match task event, got %+v", taskEvents)
+	}
+
+	if err := repo.MarkOutboxEventDelivered(e.ID, now); err != nil {
+		t.Fatalf("failed to mark delivered: %v", err)
+	}
+	if count, _ := repo.CountPendingOutboxEvents(); count != 0 {
+		t.Errorf("expected no pending events, got %d", count)
+	}
+	if n, err := repo.PurgeDeliveredOutboxEvents(now.Add(time.Second)); err != nil || n != 1 {
+		t.Errorf("expected 1 purged event, got %d (%v)", n, err)
+	}
+}
+
+func TestTaskRepository_IntegrityRepairs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTaskRepository(db)
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, id := range []string{"kept", "deleted-a", "deleted-b"} {
+		if err := repo.Create(newStoreTask(id, model.TaskPriorityNormal, now)); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	// 模拟旧版本 Delete 只删除任务行、事件残留的数据
+	for _, id := range []string{"deleted-a", "deleted-b"} {
+		if _, err := db.DB().Exec(`DELETE FROM tasks WHERE id = ?`, id); err != nil {
+			t.Fatalf("failed to delete task: %v", err)
+		}
+	}
+
+	ids, err := repo.ListOrphanedEventTaskIDs("", 10)
+	if err != nil || len(ids) != 2 || ids[0] != "deleted-a" {
+		t.Fatalf("expected both deleted tasks, got %v (%v)", ids, err)
+	}
+	if ids, _ := repo.ListOrphanedEventTaskIDs("deleted-a", 10); len(ids) != 1 || ids[0] != "deleted-b" {
+		t.Errorf("expected the page after deleted-a, got %v", ids)
+	}
+	if n, err := repo.DeleteOrphanedEvents([]string{"kept", "deleted-a"}); err != nil || n != 1 {
+		t.Errorf("expected 1 orphaned event deleted, got %d (%v)", n, err)
+	}
+	if events, _ := repo.GetEventsByTaskID("kept"); len(events) != 1 {
+		t.Errorf("expected events of existing tasks kept, got %d", len(events))
+	}
+
+	// 补记完成时间只作用于状态相同且没有完成时间的任务
+	if err := repo.SetCompletedAt("kept", model.TaskStatusFailed, now); !errors.Is(err, ErrStatusMismatch) {
+		t.Errorf("expected ErrStatusMismatch for a different status, got %v", err)
+	}
+	if err := repo.SetCompletedAt("kept", model.TaskStatusPending, now); err != nil {
+		t.Fatalf("SetCompletedAt: %v", err)
+	}
+	if task, _ := repo.GetByID("kept"); task.CompletedAt == nil || !task.CompletedAt.Equal(now) {
+		t.Errorf("expected completed_at %s, got %v", now, task.CompletedAt)
+	}
+	if err := repo.SetCompletedAt("kept", model.TaskStatusPending, now); !errors.Is(err, ErrStatusMismatch) {
+		t.Errorf("expected ErrStatusMismatch once completed_at is set, got %v", err)
+	}
+}
+
+func TestFormatSlowQueryParams(t *testing.T) {
+	status := model.TaskStatusRunning
+	var noStatus *model.TaskStatus
+
+	got := formatParams([]interface{}{"limit", 10, "status", &status, "priority", noStatus})
+	if want := "limit=10 status=RUNNING priority=<nil>"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	filter := TaskFilter{Status: &status, TeamID: "team-1", Keyword: "deploy", PageSize: 20}
+	if want := "{status=RUNNING team_id=team-1 keyword=deploy page_size=20 page_index=0}"; filter.String() != want {
+		t.Errorf("expected %q, got %q", want, filter.String())
+	}
+}
+
+// seedBenchTasks 创建 n 个 PENDING 任务
+func seedBenchTasks(b *testing.B, repo TaskStore, n int) []string {
+	b.Helper()
+	ids := make([]string, n)
+	now := time.Now()
+	for i := range ids {
+		ids[i] = fmt.Sprintf("bench-%d", i)
+		if err := repo.Create(newStoreTask(ids[i], model.TaskPriorityNormal, now.Add(time.Duration(i)*time.Millisecond))); err != nil {
+			b.Fatalf("failed to create task: %v", err)
+		}
+	}
+	return ids
+}
+
+func BenchmarkTaskRepository_Create(b *testing.B) {
+	db, cleanup := setupTestDB(b)
+	defer cleanup()
+	repo := NewTaskRepository(db)
+	now := time.Now()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := repo.Create(newStoreTask(fmt.Sprintf("bench-%d", i), model.TaskPriorityNormal, now)); err != nil {
+			b.Fatalf("failed to create task: %v", err)
+		}
+	}
+}
+
+func BenchmarkTaskRepository_GetByID(b *testing.B) {
+	db, cleanup := setupTestDB(b)
+	defer cleanup()
+	repo := NewTaskRepository(db)
+	ids := seedBenchTasks(b, repo, 1000)
+
+	for _, bc := range []struct {
+		name  string
+		store TaskStore
+	}{
+		{"sqlite", repo},
+		{"cached", NewCachedTaskStore(repo, NewLRUTaskCache(len(ids), time.Minute))},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := bc.store.GetByID(ids[i%len(ids)]); err != nil {
+					b.Fatalf("failed to get task: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTaskRepository_UpdateStatusWithEvent(b *testing.B) {
+	db, cleanup := setupTestDB(b)
+	defer cleanup()
+	repo := NewTaskRepository(db)
+	ids := seedBenchTasks(b, repo, 100)
+
+	// 每个任务在 PENDING 与 RUNNING 之间往返
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		from, to := model.TaskStatusPending, model.TaskStatusRunning
+		if (i/len(ids))%2 == 1 {
+			from, to = to, from
+		}
+		if err := repo.UpdateStatusWithEvent(ids[i%len(ids)], from, to, "bench", "bench"); err != nil {
+			b.Fatalf("failed to update status: %v", err)
+		}
+	}
+}
+
+func BenchmarkTaskRepository_ListPending(b *testing.B) {
+	db, cleanup := setupTestDB(b)
+	defer cleanup()
+	repo := NewTaskRepository(db)
+	seedBenchTasks(b, repo, 1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.ListPending(100, time.Now()); err != nil {
+			b.Fatalf("failed to list pending tasks: %v", err)
+		}
+	}
+}
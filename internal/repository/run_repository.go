@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// RerunTask 把已结束的任务从 fromStatus 原地重置为 PENDING 重新运行。同一事务中把本次运行的状态、输出、
+// 错误和重试次数保存为一条运行记录，清除执行状态和结果、重试次数归零，并记录状态事件；
+// 返回保存的运行序号。状态已变化时返回 ErrStatusMismatch
+func (r *TaskRepository) RerunTask(taskID string, fromStatus model.TaskStatus, operator, message, correlationID string) (int32, error) {
+	defer r.db.observe("tasks.RerunTask", time.Now(), "task_id", taskID, "from", fromStatus)
+	emptyOutput, err := r.db.fields.encrypt(taskID, "output_result", "null")
+	if err != nil {
+		return 0, fmt.Errorf("encrypt output_result: %w", err)
+	}
+
+	var run int32
+	err = r.db.ExecTx(func(tx *sql.Tx) error {
+		now := formatTime(time.Now())
+		if err := tx.QueryRow(`SELECT COALESCE(MAX(run), 0) + 1 FROM task_runs WHERE task_id = ?`, taskID).Scan(&run); err != nil {
+			return err
+		}
+
+		// 输出沿用任务行中的密文，按同一任务 ID 和字段名解密
+		result, err := tx.Exec(`INSERT INTO task_runs (task_id, run, status, output_result, output_ref, error_message, error_class,
+			retry_count, executed_by, started_at, completed_at, archived_at)
+			SELECT id, ?, status, output_result, output_ref, error_message, error_class,
+			retry_count, executed_by, started_at, completed_at, ?
+			FROM tasks WHERE id = ? AND status = ?`,
+			run, now, taskID, fromStatus)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`UPDATE tasks SET status = ?, updated_at = ?, version = version + 1, output_result = ?, output_ref = NULL,
+			error_message = '', error_class = NULL, retry_count = 0, next_run_at = NULL, executed_by = NULL,
+			started_at = NULL, completed_at = NULL, blocked_reason = NULL, input_request = NULL, signal_input = NULL,
+			priority = COALESCE(base_priority, priority), base_priority = NULL, queued_at = ?, queue_alerted_at = NULL,
+			sub_status = NULL, phases = NULL, workflow_deadline = NULL
+			WHERE id = ?`,
+			model.TaskStatusPending, now, emptyOutput, now, taskID); err != nil {
+			return err
+		}
+
+		return insertTaskEvent(tx, model.OutboxEventTaskStatusChanged, taskID, fromStatus, model.TaskStatusPending, operator, message, "", correlationID, now, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return run, nil
+}
+
+// ListRuns 获取任务原地重新运行前保存的历次运行记录，按运行序号升序
+func (r *TaskRepository) ListRuns(taskID string) ([]model.TaskRun, error) {
+	defer r.db.observe("tasks.ListRuns", time.Now(), "task_id", taskID)
+	rows, err := r.db.DB().Query(`SELECT task_id, run, status, output_result, output_ref, error_message, error_class,
+		retry_count, executed_by, started_at, completed_at, archived_at
+	FROM task_runs WHERE task_id = ? ORDER BY run ASC`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []model.TaskRun
+	for rows.Next() {
+		var run model.TaskRun
+		var outputResult, outputRef, errorMessage, errorClass, executedBy, startedAt, completedAt sql.NullString
+		var archivedAt string
+		if err := rows.Scan(&run.TaskID, &run.Run, &run.Status, &outputResult, &outputRef, &errorMessage, &errorClass,
+			&run.RetryCount, &executedBy, &startedAt, &completedAt, &archivedAt); err != nil {
+			return nil, err
+		}
+		run.OutputRef = outputRef.String
+		run.ErrorMessage = errorMessage.String
+		run.ErrorClass = model.ErrorClass(errorClass.String)
+		run.ExecutedBy = executedBy.String
+		run.StartedAt, _ = parseTime(startedAt.String)
+		run.CompletedAt, _ = parseTime(completedAt.String)
+		run.ArchivedAt = parseTimestamp(archivedAt)
+		if outputResult.Valid {
+			output, err := r.db.fields.decrypt(taskID, "output_result", outputResult.String)
+			if err != nil {
+				return nil, err
+			}
+			json.Unmarshal([]byte(output), &run.OutputResult)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// SecretRepository 密钥仓储，只保存密文
+type SecretRepository struct {
+	db *SQLite
+}
+
+// NewSecretRepository 创建密钥仓储
+func NewSecretRepository(db *SQLite) *SecretRepository {
+	return &SecretRepository{db: db}
+}
+
+// Put 创建或覆盖密钥，覆盖时保留创建者和创建时间
+func (r *SecretRepository) Put(secret *model.Secret) error {
+	defer r.db.observe("secrets.Put", time.Now(), "name", secret.Name)
+	_, err := r.db.DB().Exec(`INSERT INTO secrets (name, value, created_by, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		secret.Name,
+		base64.StdEncoding.EncodeToString(secret.Ciphertext),
+		nullableString(secret.CreatedBy),
+		formatTime(secret.CreatedAt),
+		formatTime(secret.UpdatedAt),
+	)
+	return err
+}
+
+// Get 根据名称获取密钥，不存在时返回 nil
+func (r *SecretRepository) Get(name string) (*model.Secret, error) {
+	defer r.db.observe("secrets.Get", time.Now(), "name", name)
+	secret, err := scanSecret(r.db.DB().QueryRow(`SELECT name, value, created_by, created_at, updated_at FROM secrets WHERE name = ?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return secret, err
+}
+
+// List 按名称列出所有密钥
+func (r *SecretRepository) List() ([]*model.Secret, error) {
+	defer r.db.observe("secrets.List", time.Now())
+	rows, err := r.db.DB().Query(`SELECT name, value, created_by, created_at, updated_at FROM secrets ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var secrets []*model.Secret
+	for rows.Next() {
+		secret, err := scanSecret(rows)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, rows.Err()
+}
+
+// Delete 删除密钥，不存在时返回 false
+func (r *SecretRepository) Delete(name string) (bool, error) {
+	defer r.db.observe("secrets.Delete", time.Now(), "name", name)
+	result, err := r.db.DB().Exec(`DELETE FROM secrets WHERE name = ?`, name)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// scanSecret 扫描密钥行
+func scanSecret(row interface{ Scan(...interface{}) error }) (*model.Secret, error) {
+	var secret model.Secret
+	var value, createdAt, updatedAt string
+	var createdBy sql.NullString
+	if err := row.Scan(&secret.Name, &value, &createdBy, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	secret.Ciphertext = ciphertext
+	secret.CreatedBy = createdBy.String
+	secret.CreatedAt = parseTimestamp(createdAt)
+	secret.UpdatedAt = parseTimestamp(updatedAt)
+	return &secret, nil
+}
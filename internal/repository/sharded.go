@@ -0,0 +1,1037 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// 分片仓储的错误
+var (
+	// ErrCrossShard 操作涉及不同分片的任务（依赖其他命名空间分片中的任务、把任务改到其他分片的团队）
+	ErrCrossShard = errors.New("tasks in different shards")
+	// ErrShardedCursor 事件和发件箱的序号在各分片独立分配，分片部署不支持按序号续读的接口
+	ErrShardedCursor = errors.New("event sequence cursors are not supported across shards")
+)
+
+// maxShardOwners 缓存的任务所在分片数上限，超过时清空后按需重新定位
+const maxShardOwners = 100000
+
+// ShardedTaskStore 按命名空间把任务分布到多个仓储的路由层。命名空间即任务归属的团队 ID：配置了分片的团队的任务
+// 写入该分片，其余任务（包括未归属团队的任务）写入默认分片。按任务 ID 的操作路由到任务所在的分片，
+// 列表和统计并发查询全部分片后合并，排序和分页与单库一致。
+//
+// 团队成员关系、调度器实例和检查点只保存在默认分片；依赖必须位于同一分片；
+// 事件和发件箱序号在各分片独立分配，ListEventsAfter、LatestEventSeq、ListOutboxEventsAfter、OutboxSeqRange
+// 返回 ErrShardedCursor。单例、反亲和和分组互斥跨分片检查，但检查与认领不在同一事务中
+type ShardedTaskStore struct {
+	primary    TaskStore
+	teams      TeamStore // 默认分片的团队仓储，用于改写 MemberOf、VisibleTo 条件
+	namespaces map[string]TaskStore
+	stores     []TaskStore // 默认分片在前，其余按命名空间排序
+	storeNames []string    // 与 stores 对应的命名空间，默认分片为空
+
+	ownersMu sync.Mutex
+	owners   map[string]TaskStore // 任务 ID -> 所在分片
+}
+
+var _ TaskStore = (*ShardedTaskStore)(nil)
+
+// NewShardedTaskStore 创建分片仓储，shards 为命名空间（团队 ID）到分片仓储的映射，teams 为默认分片的团队仓储
+func NewShardedTaskStore(primary TaskStore, teams TeamStore, shards map[string]TaskStore) *ShardedTaskStore {
+	s := &ShardedTaskStore{
+		primary:    primary,
+		teams:      teams,
+		namespaces: make(map[string]TaskStore, len(shards)),
+		stores:     []TaskStore{primary},
+		storeNames: []string{""},
+		owners:     make(map[string]TaskStore),
+	}
+	names := make([]string, 0, len(shards))
+	for name := range shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.namespaces[name] = shards[name]
+		s.stores = append(s.stores, shards[name])
+		s.storeNames = append(s.storeNames, name)
+	}
+	return s
+}
+
+// route 团队的任务写入的分片
+func (s *ShardedTaskStore) route(teamID string) TaskStore {
+	if store, ok := s.namespaces[teamID]; ok {
+		return store
+	}
+	return s.primary
+}
+
+// locate 查找任务所在的分片，任务不存在时 found 为 false
+func (s *ShardedTaskStore) locate(taskID string) (store TaskStore, found bool, err error) {
+	s.ownersMu.Lock()
+	store, found = s.owners[taskID]
+	s.ownersMu.Unlock()
+	if found {
+		return store, true, nil
+	}
+
+	hits, err := fanOut(s.stores, func(store TaskStore) (bool, error) {
+		tasks, err := store.GetByIDs([]string{taskID})
+		return err == nil && tasks[0] != nil, err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	for i, hit := range hits {
+		if hit {
+			s.remember(taskID, s.stores[i])
+			return s.stores[i], true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// owner 任务所在的分片，任务不存在时返回默认分片，由其按单库的约定报告不存在
+func (s *ShardedTaskStore) owner(taskID string) (TaskStore, error) {
+	store, found, err := s.locate(taskID)
+	if err != nil || !found {
+		return s.primary, err
+	}
+	return store, nil
+}
+
+// remember 记录任务所在的分片
+func (s *ShardedTaskStore) remember(taskID string, store TaskStore) {
+	s.ownersMu.Lock()
+	defer s.ownersMu.Unlock()
+	if len(s.owners) >= maxShardOwners {
+		clear(s.owners)
+	}
+	s.owners[taskID] = store
+}
+
+// forget 删除任务后清除其分片记录
+func (s *ShardedTaskStore) forget(taskID string) {
+	s.ownersMu.Lock()
+	defer s.ownersMu.Unlock()
+	delete(s.owners, taskID)
+}
+
+// fanOut 对每个分片（或分片上的查询）并发执行 fn，结果与 items 顺序一致，有分片出错时返回第一个错误
+func fanOut[S, T any](items []S, fn func(S) (T, error)) ([]T, error) {
+	results := make([]T, len(items))
+	errs := make([]error, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = fn(item)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// mergeTasks 合并各分片已排序的结果，取值相同的任务按 ID 升序（与单库一致），再按 limit/offset 截取
+func mergeTasks(lists [][]*model.Task, less func(a, b *model.Task) bool, limit, offset int) []*model.Task {
+	var tasks []*model.Task
+	for _, list := range lists {
+		tasks = append(tasks, list...)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if less(tasks[i], tasks[j]) {
+			return true
+		}
+		if less(tasks[j], tasks[i]) {
+			return false
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return paginate(tasks, limit, offset)
+}
+
+// shardWindow 合并分页时每个分片需要返回的条数，limit < 0 表示不限制
+func shardWindow(limit, offset int) int {
+	if limit < 0 {
+		return limit
+	}
+	return limit + max(offset, 0)
+}
+
+// listTasks 在全部分片上执行 limit/offset 分页的列表查询并合并
+func (s *ShardedTaskStore) listTasks(limit, offset int, less func(a, b *model.Task) bool, query func(store TaskStore, limit int) ([]*model.Task, error)) ([]*model.Task, error) {
+	window := shardWindow(limit, offset)
+	lists, err := fanOut(s.stores, func(store TaskStore) ([]*model.Task, error) {
+		return query(store, window)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mergeTasks(lists, less, limit, offset), nil
+}
+
+// Create 按团队写入对应分片，依赖其他分片中的任务时返回 ErrCrossShard
+func (s *ShardedTaskStore) Create(task *model.Task) error {
+	target := s.route(task.TeamID)
+	for _, dep := range task.Dependencies {
+		store, found, err := s.locate(dep)
+		if err != nil {
+			return err
+		}
+		if found && store != target {
+			return fmt.Errorf("%w: dependency %s is stored outside namespace %q", ErrCrossShard, dep, task.TeamID)
+		}
+	}
+	if err := target.Create(task); err != nil {
+		return err
+	}
+	s.remember(task.ID, target)
+	return nil
+}
+
+// GetByID 根据 ID 获取任务，不存在时返回 ErrTaskNotFound
+func (s *ShardedTaskStore) GetByID(id string) (*model.Task, error) {
+	store, err := s.owner(id)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetByID(id)
+}
+
+// GetByIDs 在全部分片上批量获取任务，结果与 ids 顺序一致，不存在的任务对应位置为 nil
+func (s *ShardedTaskStore) GetByIDs(ids []string) ([]*model.Task, error) {
+	lists, err := fanOut(s.stores, func(store TaskStore) ([]*model.Task, error) {
+		return store.GetByIDs(ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.Task, len(ids))
+	for i, list := range lists {
+		for j, task := range list {
+			if task != nil && result[j] == nil {
+				result[j] = task
+				s.remember(task.ID, s.stores[i])
+			}
+		}
+	}
+	return result, nil
+}
+
+// ListAfterID 按 ID 升序列出 ID 大于 afterID 的任务
+func (s *ShardedTaskStore) ListAfterID(afterID string, limit int) ([]*model.Task, error) {
+	return s.listTasks(limit, 0, byID, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.ListAfterID(afterID, limit)
+	})
+}
+
+// Update 更新任务，团队变化导致任务需要换到其他分片时返回 ErrCrossShard
+func (s *ShardedTaskStore) Update(task *model.Task) error {
+	store, err := s.owner(task.ID)
+	if err != nil {
+		return err
+	}
+	if target := s.route(task.TeamID); target != store {
+		stored, err := store.GetByIDs([]string{task.ID})
+		if err != nil {
+			return err
+		}
+		if stored[0] != nil && s.route(stored[0].TeamID) != target {
+			return fmt.Errorf("%w: task %s cannot move to namespace %q", ErrCrossShard, task.ID, task.TeamID)
+		}
+	}
+	return store.Update(task)
+}
+
+// UpdateDefinition 只写入任务定义中可由用户修改的字段
+func (s *ShardedTaskStore) UpdateDefinition(task *model.Task, expectedStatus model.TaskStatus) error {
+	store, err := s.owner(task.ID)
+	if err != nil {
+		return err
+	}
+	return store.UpdateDefinition(task, expectedStatus)
+}
+
+// Delete 删除任务，依赖位于同一分片，由任务所在分片检查和移除
+func (s *ShardedTaskStore) Delete(id string, force bool, operator, correlationID string) (*DeletedTask, error) {
+	store, err := s.owner(id)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := store.Delete(id, force, operator, correlationID)
+	if err != nil {
+		return nil, err
+	}
+	s.forget(id)
+	return deleted, nil
+}
+
+// RepairDependencies 修复待处理任务的依赖，改为依赖的任务须与任务位于同一分片，否则返回 ErrCrossShard
+func (s *ShardedTaskStore) RepairDependencies(taskID string, repairs []model.DependencyRepair, operator, reason, correlationID string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	for _, r := range repairs {
+		if r.ReplaceWith == "" {
+			continue
+		}
+		target, found, err := s.locate(r.ReplaceWith)
+		if err != nil {
+			return err
+		}
+		if found && target != store {
+			return fmt.Errorf("%w: dependency %s is stored in another shard than task %s", ErrCrossShard, r.ReplaceWith, taskID)
+		}
+	}
+	return store.RepairDependencies(taskID, repairs, operator, reason, correlationID)
+}
+
+// List 列出任务（分页），按创建时间降序
+func (s *ShardedTaskStore) List(limit, offset int, statusFilter *model.TaskStatus) ([]*model.Task, error) {
+	return s.listTasks(limit, offset, newestFirst, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.List(limit, 0, statusFilter)
+	})
+}
+
+// ListByStatus 根据状态列出任务
+func (s *ShardedTaskStore) ListByStatus(status model.TaskStatus, limit int) ([]*model.Task, error) {
+	return s.List(limit, 0, &status)
+}
+
+// ListByCreator 根据创建者列出任务
+func (s *ShardedTaskStore) ListByCreator(createdBy string, limit, offset int) ([]*model.Task, error) {
+	return s.listTasks(limit, offset, newestFirst, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.ListByCreator(createdBy, limit, 0)
+	})
+}
+
+// ListPending 列出可调度的任务，按优先级降序、创建时间升序
+func (s *ShardedTaskStore) ListPending(limit int, now time.Time) ([]*model.Task, error) {
+	return s.listTasks(limit, 0, func(a, b *model.Task) bool {
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	}, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.ListPending(limit, now)
+	})
+}
+
+// ListDependents 列出直接依赖 taskIDs 中任一任务的任务，按创建时间排序
+func (s *ShardedTaskStore) ListDependents(taskIDs []string, statusFilter *model.TaskStatus) ([]*model.Task, error) {
+	return s.listTasks(-1, 0, func(a, b *model.Task) bool { return a.CreatedAt.Before(b.CreatedAt) },
+		func(store TaskStore, _ int) ([]*model.Task, error) {
+			return store.ListDependents(taskIDs, statusFilter)
+		})
+}
+
+// ListByFilter 在可能有匹配任务的分片上查询并合并，总数为各分片之和
+func (s *ShardedTaskStore) ListByFilter(filter TaskFilter) ([]*model.Task, int, error) {
+	if !filter.SortBy.Valid() {
+		return nil, 0, fmt.Errorf("%w: %q", ErrInvalidSortField, filter.SortBy)
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	if filter.PageIndex < 0 {
+		filter.PageIndex = 0
+	}
+
+	memberships := make(map[string]map[string]bool)
+	for _, user := range []string{filter.MemberOf, filter.VisibleTo} {
+		if user == "" || memberships[user] != nil {
+			continue
+		}
+		teamIDs, err := s.teams.ListTeamIDsByUser(user)
+		if err != nil {
+			return nil, 0, err
+		}
+		memberships[user] = make(map[string]bool, len(teamIDs))
+		for _, id := range teamIDs {
+			memberships[user][id] = true
+		}
+	}
+
+	type query struct {
+		store  TaskStore
+		filter TaskFilter
+	}
+	var queries []query
+	for i, store := range s.stores {
+		if f, ok := shardFilter(filter, s.storeNames[i], memberships); ok {
+			f.PageIndex, f.PageSize = 0, (filter.PageIndex+1)*filter.PageSize
+			queries = append(queries, query{store, f})
+		}
+	}
+
+	type page struct {
+		tasks []*model.Task
+		total int
+	}
+	pages, err := fanOut(queries, func(q query) (page, error) {
+		tasks, total, err := q.store.ListByFilter(q.filter)
+		return page{tasks, total}, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	lists := make([][]*model.Task, len(pages))
+	total := 0
+	for i, p := range pages {
+		lists[i] = p.tasks
+		total += p.total
+	}
+	return mergeTasks(lists, filter.less, filter.PageSize, filter.PageIndex*filter.PageSize), total, nil
+}
+
+// shardFilter 把 filter 改写为命名空间分片上的查询，分片中不可能有匹配的任务时返回 false。
+// 团队成员关系只保存在默认分片，命名空间分片中的任务都属于该团队，MemberOf、VisibleTo 按 memberships
+// （用户 -> 所在团队）改写为团队和创建者条件；默认分片上的查询不变
+func shardFilter(filter TaskFilter, namespace string, memberships map[string]map[string]bool) (TaskFilter, bool) {
+	if namespace == "" {
+		return filter, true
+	}
+	if filter.TeamID != "" && filter.TeamID != namespace {
+		return filter, false
+	}
+	if filter.MemberOf != "" {
+		if !memberships[filter.MemberOf][namespace] {
+			return filter, false
+		}
+		filter.MemberOf = ""
+	}
+	if user := filter.VisibleTo; user != "" {
+		filter.VisibleTo, filter.IncludeUnassigned = "", false
+		if !memberships[user][namespace] {
+			if filter.CreatedBy != "" && filter.CreatedBy != user {
+				return filter, false
+			}
+			filter.CreatedBy = user
+		}
+	}
+	return filter, true
+}
+
+// Search 搜索任务，按创建时间降序
+func (s *ShardedTaskStore) Search(keyword string, limit, offset int) ([]*model.Task, error) {
+	return s.listTasks(limit, offset, newestFirst, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.Search(keyword, limit, 0)
+	})
+}
+
+// Count 各分片任务数之和
+func (s *ShardedTaskStore) Count(statusFilter *model.TaskStatus) (int, error) {
+	counts, err := fanOut(s.stores, func(store TaskStore) (int, error) {
+		return store.Count(statusFilter)
+	})
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total, nil
+}
+
+// RecentDurations 最近成功完成的任务的执行耗时，按完成时间降序
+func (s *ShardedTaskStore) RecentDurations(taskType string, limit int) ([]time.Duration, error) {
+	succeeded := model.TaskStatusSucceeded
+	filter := TaskFilter{TaskType: taskType, Status: &succeeded, SortBy: SortByCompletedAt, SortDesc: true, PageSize: limit}
+	tasks, err := s.listTasks(limit, 0, filter.less, func(store TaskStore, limit int) ([]*model.Task, error) {
+		f := filter
+		f.PageSize = limit
+		tasks, _, err := store.ListByFilter(f)
+		return tasks, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var durations []time.Duration
+	for _, t := range tasks {
+		if t.StartedAt != nil && t.CompletedAt != nil && !t.CompletedAt.Before(*t.StartedAt) {
+			durations = append(durations, t.CompletedAt.Sub(*t.StartedAt))
+		}
+	}
+	return durations, nil
+}
+
+// ListTaskTypes 各分片任务类型的并集，按名称排序
+func (s *ShardedTaskStore) ListTaskTypes() ([]string, error) {
+	lists, err := fanOut(s.stores, func(store TaskStore) ([]string, error) {
+		return store.ListTaskTypes()
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var types []string
+	for _, list := range lists {
+		for _, t := range list {
+			if !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// CountOutcomesByType 各分片按任务类型统计的成功与失败任务数之和
+func (s *ShardedTaskStore) CountOutcomesByType(from, to time.Time) (map[string]OutcomeCount, error) {
+	results, err := fanOut(s.stores, func(store TaskStore) (map[string]OutcomeCount, error) {
+		return store.CountOutcomesByType(from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]OutcomeCount)
+	for _, result := range results {
+		for taskType, c := range result {
+			sum := counts[taskType]
+			sum.Succeeded += c.Succeeded
+			sum.Failed += c.Failed
+			counts[taskType] = sum
+		}
+	}
+	return counts, nil
+}
+
+// AddEvent 写入任务所在分片
+func (s *ShardedTaskStore) AddEvent(event *model.TaskEvent) error {
+	store, err := s.owner(event.TaskID)
+	if err != nil {
+		return err
+	}
+	return store.AddEvent(event)
+}
+
+// GetEventsByTaskID 获取任务的事件
+func (s *ShardedTaskStore) GetEventsByTaskID(taskID string) ([]model.TaskEvent, error) {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetEventsByTaskID(taskID)
+}
+
+// ListEventsAfter 事件序号在各分片独立分配，返回 ErrShardedCursor
+func (s *ShardedTaskStore) ListEventsAfter(afterSeq int64, limit int) ([]model.TaskEvent, error) {
+	return nil, ErrShardedCursor
+}
+
+// ListTaskEvents 按条件列出任务的事件
+func (s *ShardedTaskStore) ListTaskEvents(taskID string, filter EventFilter) ([]model.TaskEvent, error) {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return store.ListTaskEvents(taskID, filter)
+}
+
+// CompactEvents 在各分片上压缩事件，返回删除的总数
+func (s *ShardedTaskStore) CompactEvents(before time.Time, keepLatest int) (int64, error) {
+	return sumInt64(s.stores, func(store TaskStore) (int64, error) {
+		return store.CompactEvents(before, keepLatest)
+	})
+}
+
+// ListOrphanedEventTaskIDs 各分片中有事件但任务已不存在、ID 大于 afterID 的任务 ID，合并后按 ID 升序取前 limit 个
+func (s *ShardedTaskStore) ListOrphanedEventTaskIDs(afterID string, limit int) ([]string, error) {
+	lists, err := fanOut(s.stores, func(store TaskStore) ([]string, error) {
+		return store.ListOrphanedEventTaskIDs(afterID, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, list := range lists {
+		ids = append(ids, list...)
+	}
+	sort.Strings(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+// DeleteOrphanedEvents 在各分片上删除任务已不存在的事件，返回删除的总数
+func (s *ShardedTaskStore) DeleteOrphanedEvents(taskIDs []string) (int64, error) {
+	return sumInt64(s.stores, func(store TaskStore) (int64, error) {
+		return store.DeleteOrphanedEvents(taskIDs)
+	})
+}
+
+// SetCompletedAt 补记任务的完成时间
+func (s *ShardedTaskStore) SetCompletedAt(taskID string, status model.TaskStatus, at time.Time) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.SetCompletedAt(taskID, status, at)
+}
+
+// LatestEventSeq 事件序号在各分片独立分配，返回 ErrShardedCursor
+func (s *ShardedTaskStore) LatestEventSeq() (int64, error) {
+	return 0, ErrShardedCursor
+}
+
+// sumInt64 各分片结果之和
+func sumInt64(stores []TaskStore, fn func(TaskStore) (int64, error)) (int64, error) {
+	results, err := fanOut(stores, fn)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, n := range results {
+		total += n
+	}
+	return total, nil
+}
+
+// UpdateStatus 条件更新任务状态
+func (s *ShardedTaskStore) UpdateStatus(id string, fromStatus, toStatus model.TaskStatus) error {
+	store, err := s.owner(id)
+	if err != nil {
+		return err
+	}
+	return store.UpdateStatus(id, fromStatus, toStatus)
+}
+
+// UpdateStatusWithEvent 条件更新状态并记录事件
+func (s *ShardedTaskStore) UpdateStatusWithEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.UpdateStatusWithEvent(taskID, fromStatus, toStatus, operator, message)
+}
+
+// UpdateStatusWithCorrelatedEvent 条件更新状态并记录带请求 ID 的事件
+func (s *ShardedTaskStore) UpdateStatusWithCorrelatedEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, correlationID string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.UpdateStatusWithCorrelatedEvent(taskID, fromStatus, toStatus, operator, message, correlationID)
+}
+
+// UpdateStatusWithInstanceEvent 条件更新状态并记录带调度器实例 ID 的事件
+func (s *ShardedTaskStore) UpdateStatusWithInstanceEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID string, meta map[string]string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.UpdateStatusWithInstanceEvent(taskID, fromStatus, toStatus, operator, message, instanceID, meta)
+}
+
+// ScheduleRetry 安排自动重试
+func (s *ShardedTaskStore) ScheduleRetry(taskID string, fromStatus model.TaskStatus, retryCount int32, nextRunAt *time.Time, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.ScheduleRetry(taskID, fromStatus, retryCount, nextRunAt, errMsg, errClass, operator, message, instanceID, meta)
+}
+
+// FailTask 记录任务最终失败
+func (s *ShardedTaskStore) FailTask(taskID, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.FailTask(taskID, errMsg, errClass, operator, message, instanceID, meta)
+}
+
+// CompleteTask 记录任务成功完成
+func (s *ShardedTaskStore) CompleteTask(taskID string, fromStatus model.TaskStatus, output map[string]string, outputRef, operator, message, instanceID string, meta map[string]string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.CompleteTask(taskID, fromStatus, output, outputRef, operator, message, instanceID, meta)
+}
+
+// ClaimExclusive 先检查其他分片中互斥的任务，再在任务所在分片认领
+func (s *ShardedTaskStore) ClaimExclusive(taskID string, excl Exclusion, operator, message, instanceID string, meta map[string]string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	if !excl.IsZero() {
+		for _, other := range s.stores {
+			if other == store {
+				continue
+			}
+			if err := other.RunningConflict(taskID, excl); err != nil {
+				return err
+			}
+		}
+	}
+	return store.ClaimExclusive(taskID, excl, operator, message, instanceID, meta)
+}
+
+// RunningConflict 在全部分片中检查互斥的任务
+func (s *ShardedTaskStore) RunningConflict(taskID string, excl Exclusion) error {
+	_, err := fanOut(s.stores, func(store TaskStore) (struct{}, error) {
+		return struct{}{}, store.RunningConflict(taskID, excl)
+	})
+	return err
+}
+
+// SetBlockedReason 记录任务暂不能调度的原因
+func (s *ShardedTaskStore) SetBlockedReason(taskID, reason string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.SetBlockedReason(taskID, reason)
+}
+
+// UpdateProgress 记录执行进度
+func (s *ShardedTaskStore) UpdateProgress(taskID string, progress int32, message string, at time.Time) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.UpdateProgress(taskID, progress, message, at)
+}
+
+// UpdateSubStatus 记录执行器上报的自定义阶段
+func (s *ShardedTaskStore) UpdateSubStatus(taskID, subStatus, message string, at time.Time, operator, instanceID string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.UpdateSubStatus(taskID, subStatus, message, at, operator, instanceID)
+}
+
+// Heartbeat 刷新执行心跳
+func (s *ShardedTaskStore) Heartbeat(taskID string, at time.Time) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.Heartbeat(taskID, at)
+}
+
+// SuspendForInput 挂起任务等待外部输入
+func (s *ShardedTaskStore) SuspendForInput(taskID, prompt, operator, message, instanceID string, meta map[string]string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.SuspendForInput(taskID, prompt, operator, message, instanceID, meta)
+}
+
+// SignalTask 向等待输入的任务送达输入
+func (s *ShardedTaskStore) SignalTask(taskID string, payload map[string]string, operator, message, correlationID string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.SignalTask(taskID, payload, operator, message, correlationID)
+}
+
+// RerunTask 保存本次运行的结果后原地重置任务
+func (s *ShardedTaskStore) RerunTask(taskID string, fromStatus model.TaskStatus, operator, message, correlationID string) (int32, error) {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return 0, err
+	}
+	return store.RerunTask(taskID, fromStatus, operator, message, correlationID)
+}
+
+// ListRuns 列出任务的历次运行
+func (s *ShardedTaskStore) ListRuns(taskID string) ([]model.TaskRun, error) {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return store.ListRuns(taskID)
+}
+
+// ListWaitingOnDependencies 各分片中依赖尚未全部结束的高优先级 PENDING 任务，按优先级降序、创建时间升序
+func (s *ShardedTaskStore) ListWaitingOnDependencies(minPriority model.TaskPriority, limit int) ([]*model.Task, error) {
+	return s.listTasks(limit, 0, func(a, b *model.Task) bool {
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	}, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.ListWaitingOnDependencies(minPriority, limit)
+	})
+}
+
+// BoostPriority 临时提升任务的优先级
+func (s *ShardedTaskStore) BoostPriority(taskID string, priority model.TaskPriority, operator, message, instanceID string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.BoostPriority(taskID, priority, operator, message, instanceID)
+}
+
+// RestorePriority 恢复任务被提升前的优先级
+func (s *ShardedTaskStore) RestorePriority(taskID, operator, message, instanceID string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.RestorePriority(taskID, operator, message, instanceID)
+}
+
+// ListQueueTimeCandidates 各分片中排队超过上限的候选任务，按开始排队的时间升序
+func (s *ShardedTaskStore) ListQueueTimeCandidates(queuedBefore time.Time, taskTypes, excludeTypes []string, limit int) ([]*model.Task, error) {
+	return s.listTasks(limit, 0, byQueuedSince, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.ListQueueTimeCandidates(queuedBefore, taskTypes, excludeTypes, limit)
+	})
+}
+
+// MarkQueueTimeExceeded 记录任务排队超时
+func (s *ShardedTaskStore) MarkQueueTimeExceeded(taskID string, at time.Time, escalateTo model.TaskPriority, operator, message, instanceID string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.MarkQueueTimeExceeded(taskID, at, escalateTo, operator, message, instanceID)
+}
+
+// ListWorkflowDeadlineExceeded 各分片中所属工作流已过截止时间的未结束任务，按截止时间升序
+func (s *ShardedTaskStore) ListWorkflowDeadlineExceeded(now time.Time, limit int) ([]*model.Task, error) {
+	return s.listTasks(limit, 0, byWorkflowDeadline, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.ListWorkflowDeadlineExceeded(now, limit)
+	})
+}
+
+// ListSLABreachCandidates 各分片中已过 SLA 截止时间的任务，按截止时间升序
+func (s *ShardedTaskStore) ListSLABreachCandidates(now, since time.Time, limit int) ([]*model.Task, error) {
+	return s.listTasks(limit, 0, bySLADeadline, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.ListSLABreachCandidates(now, since, limit)
+	})
+}
+
+// MarkSLABreached 记录任务违约
+func (s *ShardedTaskStore) MarkSLABreached(taskID string, at time.Time, operator, message, instanceID string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.MarkSLABreached(taskID, at, operator, message, instanceID)
+}
+
+// ListSLATasks 各分片中截止时间在 [from, to] 内的任务，按截止时间升序
+func (s *ShardedTaskStore) ListSLATasks(from, to time.Time, limit int) ([]*model.Task, error) {
+	return s.listTasks(limit, 0, bySLADeadline, func(store TaskStore, limit int) ([]*model.Task, error) {
+		return store.ListSLATasks(from, to, limit)
+	})
+}
+
+// AddComment 添加任务评论
+func (s *ShardedTaskStore) AddComment(comment *model.TaskComment) error {
+	store, err := s.owner(comment.TaskID)
+	if err != nil {
+		return err
+	}
+	return store.AddComment(comment)
+}
+
+// GetCommentsByTaskID 获取任务的评论
+func (s *ShardedTaskStore) GetCommentsByTaskID(taskID string) ([]model.TaskComment, error) {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetCommentsByTaskID(taskID)
+}
+
+// AddAttachment 添加任务附件
+func (s *ShardedTaskStore) AddAttachment(a *model.TaskAttachment) error {
+	store, err := s.owner(a.TaskID)
+	if err != nil {
+		return err
+	}
+	return store.AddAttachment(a)
+}
+
+// GetAttachment 获取任务附件
+func (s *ShardedTaskStore) GetAttachment(taskID, id string) (*model.TaskAttachment, error) {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetAttachment(taskID, id)
+}
+
+// ListAttachments 列出任务附件
+func (s *ShardedTaskStore) ListAttachments(taskID string) ([]*model.TaskAttachment, error) {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return store.ListAttachments(taskID)
+}
+
+// DeleteAttachment 删除任务附件
+func (s *ShardedTaskStore) DeleteAttachment(taskID, id string) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.DeleteAttachment(taskID, id)
+}
+
+// AppendLogs 按任务所在分片分组写入执行日志
+func (s *ShardedTaskStore) AppendLogs(lines []model.TaskLogLine) error {
+	groups := make(map[TaskStore][]model.TaskLogLine)
+	var order []TaskStore
+	for _, line := range lines {
+		store, err := s.owner(line.TaskID)
+		if err != nil {
+			return err
+		}
+		if _, ok := groups[store]; !ok {
+			order = append(order, store)
+		}
+		groups[store] = append(groups[store], line)
+	}
+	for _, store := range order {
+		if err := store.AppendLogs(groups[store]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLogs 读取任务的执行日志
+func (s *ShardedTaskStore) GetLogs(taskID string, afterSeq int64, limit int) ([]model.TaskLogLine, error) {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetLogs(taskID, afterSeq, limit)
+}
+
+// LastLogSeq 任务执行日志的最大序号
+func (s *ShardedTaskStore) LastLogSeq(taskID string) (int64, error) {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return 0, err
+	}
+	return store.LastLogSeq(taskID)
+}
+
+// TrimLogs 只保留任务最近的执行日志
+func (s *ShardedTaskStore) TrimLogs(taskID string, keep int64) error {
+	store, err := s.owner(taskID)
+	if err != nil {
+		return err
+	}
+	return store.TrimLogs(taskID, keep)
+}
+
+// UpsertSchedulerInstance 调度器实例保存在默认分片
+func (s *ShardedTaskStore) UpsertSchedulerInstance(inst *model.SchedulerInstance) error {
+	return s.primary.UpsertSchedulerInstance(inst)
+}
+
+// ListSchedulerInstances 列出调度器实例
+func (s *ShardedTaskStore) ListSchedulerInstances() ([]*model.SchedulerInstance, error) {
+	return s.primary.ListSchedulerInstances()
+}
+
+// DeleteSchedulerInstance 删除调度器实例
+func (s *ShardedTaskStore) DeleteSchedulerInstance(id string) error {
+	return s.primary.DeleteSchedulerInstance(id)
+}
+
+// SaveSchedulerCheckpoint 调度器检查点保存在默认分片
+func (s *ShardedTaskStore) SaveSchedulerCheckpoint(cp *model.SchedulerCheckpoint) error {
+	return s.primary.SaveSchedulerCheckpoint(cp)
+}
+
+// GetSchedulerCheckpoint 读取调度器检查点
+func (s *ShardedTaskStore) GetSchedulerCheckpoint(name string) (*model.SchedulerCheckpoint, error) {
+	return s.primary.GetSchedulerCheckpoint(name)
+}
+
+// ListDueOutboxEvents 各分片中待投递的发件箱事件，按写入时间归并；同一任务的事件在同一分片，保持分片内的顺序
+func (s *ShardedTaskStore) ListDueOutboxEvents(now time.Time, limit int) ([]*model.OutboxEvent, error) {
+	lists, err := fanOut(s.stores, func(store TaskStore) ([]*model.OutboxEvent, error) {
+		return store.ListDueOutboxEvents(now, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var events []*model.OutboxEvent
+	for limit < 0 || len(events) < limit {
+		next := -1
+		for i, list := range lists {
+			if len(list) > 0 && (next < 0 || list[0].CreatedAt.Before(lists[next][0].CreatedAt)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+		events = append(events, lists[next][0])
+		lists[next] = lists[next][1:]
+	}
+	return events, nil
+}
+
+// ListOutboxEventsAfter 发件箱序号在各分片独立分配，返回 ErrShardedCursor
+func (s *ShardedTaskStore) ListOutboxEventsAfter(afterSeq int64, limit int) ([]*model.OutboxEvent, error) {
+	return nil, ErrShardedCursor
+}
+
+// OutboxSeqRange 发件箱序号在各分片独立分配，返回 ErrShardedCursor
+func (s *ShardedTaskStore) OutboxSeqRange() (first, last int64, err error) {
+	return 0, 0, ErrShardedCursor
+}
+
+// MarkOutboxEventDelivered 事件只存在于一个分片，其余分片上的更新不命中
+func (s *ShardedTaskStore) MarkOutboxEventDelivered(id string, at time.Time) error {
+	_, err := fanOut(s.stores, func(store TaskStore) (struct{}, error) {
+		return struct{}{}, store.MarkOutboxEventDelivered(id, at)
+	})
+	return err
+}
+
+// MarkOutboxEventFailed 记录投递失败并安排下次重试
+func (s *ShardedTaskStore) MarkOutboxEventFailed(id string, lastErr string, nextAttemptAt time.Time) error {
+	_, err := fanOut(s.stores, func(store TaskStore) (struct{}, error) {
+		return struct{}{}, store.MarkOutboxEventFailed(id, lastErr, nextAttemptAt)
+	})
+	return err
+}
+
+// CountPendingOutboxEvents 各分片待投递事件数之和
+func (s *ShardedTaskStore) CountPendingOutboxEvents() (int, error) {
+	n, err := sumInt64(s.stores, func(store TaskStore) (int64, error) {
+		n, err := store.CountPendingOutboxEvents()
+		return int64(n), err
+	})
+	return int(n), err
+}
+
+// PurgeDeliveredOutboxEvents 在各分片上清理已投递的事件，返回删除的总数
+func (s *ShardedTaskStore) PurgeDeliveredOutboxEvents(before time.Time) (int64, error) {
+	return sumInt64(s.stores, func(store TaskStore) (int64, error) {
+		return store.PurgeDeliveredOutboxEvents(before)
+	})
+}
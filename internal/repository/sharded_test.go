@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+func TestShardedTaskStore(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	primary, teams := NewMemoryRepositories()
+	etl := NewTaskRepository(db)
+	ml, _ := NewMemoryRepositories()
+	store := NewShardedTaskStore(primary, teams, map[string]TaskStore{"etl": etl, "ml": ml})
+
+	for _, team := range []*model.Team{{ID: "etl", Name: "ETL"}, {ID: "ml", Name: "ML"}} {
+		if err := teams.Create(team); err != nil {
+			t.Fatalf("failed to create team: %v", err)
+		}
+	}
+	if err := teams.AddMember("etl", "alice"); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	create := func(id, teamID, createdBy string, priority model.TaskPriority, minute int, deps ...string) error {
+		task := newStoreTask(id, priority, base.Add(time.Duration(minute)*time.Minute))
+		task.TeamID, task.CreatedBy, task.Dependencies = teamID, createdBy, deps
+		return store.Create(task)
+	}
+	for _, c := range []struct {
+		id, team, creator string
+		priority          model.TaskPriority
+		minute            int
+	}{
+		{"p1", "", "bob", model.TaskPriorityNormal, 1},
+		{"e1", "etl", "bob", model.TaskPriorityHigh, 2},
+		{"m1", "ml", "bob", model.TaskPriorityLow, 3},
+		{"e2", "etl", "carol", model.TaskPriorityNormal, 4},
+		{"o1", "other", "carol", model.TaskPriorityNormal, 5},
+		{"m2", "ml", "carol", model.TaskPriorityNormal, 6},
+	} {
+		if err := create(c.id, c.team, c.creator, c.priority, c.minute); err != nil {
+			t.Fatalf("failed to create %s: %v", c.id, err)
+		}
+	}
+
+	// 按团队写入分片，未配置分片的团队写入默认分片
+	for shard, ids := range map[TaskStore][]string{primary: {"p1", "o1"}, etl: {"e1", "e2"}, ml: {"m1", "m2"}} {
+		if n, _ := shard.Count(nil); n != len(ids) {
+			t.Errorf("expected %v in shard, got %d tasks", ids, n)
+		}
+		for _, id := range ids {
+			if _, err := shard.GetByID(id); err != nil {
+				t.Errorf("expected %s in its shard: %v", id, err)
+			}
+		}
+	}
+
+	// 新建的路由层没有缓存，按 ID 的读写定位到所在分片
+	store = NewShardedTaskStore(primary, teams, map[string]TaskStore{"etl": etl, "ml": ml})
+	if task, err := store.GetByID("e2"); err != nil || task.TeamID != "etl" {
+		t.Fatalf("expected e2 from the etl shard, got %+v (%v)", task, err)
+	}
+	if _, err := store.GetByID("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+	if err := store.UpdateStatusWithEvent("m1", model.TaskStatusPending, model.TaskStatusCancelled, "alice", "cancel"); err != nil {
+		t.Fatalf("failed to update m1: %v", err)
+	}
+	if task, _ := ml.GetByID("m1"); task.Status != model.TaskStatusCancelled || len(task.Events) == 0 {
+		t.Errorf("expected m1 cancelled in the ml shard, got %+v", task)
+	}
+	if tasks, err := store.GetByIDs([]string{"m2", "missing", "p1", "e1"}); err != nil || tasks[0].ID != "m2" || tasks[1] != nil || tasks[2].ID != "p1" || tasks[3].ID != "e1" {
+		t.Errorf("unexpected GetByIDs result: %v (%v)", tasks, err)
+	}
+
+	// 依赖必须在同一分片，任务不能换到其他分片
+	if err := create("e3", "etl", "bob", model.TaskPriorityNormal, 30, "p1"); !errors.Is(err, ErrCrossShard) {
+		t.Errorf("expected ErrCrossShard for a dependency in another shard, got %v", err)
+	}
+	if err := create("e3", "etl", "bob", model.TaskPriorityNormal, 30, "e1"); err != nil {
+		t.Errorf("expected dependency within the shard to be allowed, got %v", err)
+	}
+	moved, _ := store.GetByID("e2")
+	moved.TeamID = "ml"
+	if err := store.Update(moved); !errors.Is(err, ErrCrossShard) {
+		t.Errorf("expected ErrCrossShard when moving a task to another shard, got %v", err)
+	}
+	if err := store.RepairDependencies("e3", []model.DependencyRepair{{DependencyID: "e1", ReplaceWith: "p1"}}, "admin", "", ""); !errors.Is(err, ErrCrossShard) {
+		t.Errorf("expected ErrCrossShard when re-pointing a dependency to another shard, got %v", err)
+	}
+
+	ids := func(tasks []*model.Task) []string {
+		var got []string
+		for _, task := range tasks {
+			got = append(got, task.ID)
+		}
+		return got
+	}
+
+	// 跨分片分页与单库一致
+	all, total, err := store.ListByFilter(TaskFilter{SortBy: SortByCreatedAt, PageSize: 100})
+	if err != nil || total != 7 || len(all) != 7 {
+		t.Fatalf("expected 7 tasks, got %v total %d (%v)", ids(all), total, err)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].CreatedAt.Before(all[i-1].CreatedAt) {
+			t.Fatalf("expected ascending creation time, got %v", ids(all))
+		}
+	}
+	var paged []*model.Task
+	for page := 0; page < 4; page++ {
+		tasks, total, err := store.ListByFilter(TaskFilter{SortBy: SortByCreatedAt, PageSize: 2, PageIndex: page})
+		if err != nil || total != 7 {
+			t.Fatalf("page %d: total %d (%v)", page, total, err)
+		}
+		paged = append(paged, tasks...)
+	}
+	if !slices.Equal(ids(paged), ids(all)) {
+		t.Errorf("expected pages %v to match %v", ids(paged), ids(all))
+	}
+	if tasks, total, _ := store.ListByFilter(TaskFilter{TeamID: "ml"}); total != 2 || len(tasks) != 2 {
+		t.Errorf("expected the ml tasks, got %v", ids(tasks))
+	}
+
+	// 团队成员关系只在默认分片，命名空间分片按成员关系改写可见性条件
+	visible := func(f TaskFilter) []string {
+		f.SortBy, f.PageSize = SortByCreatedAt, 100
+		tasks, _, err := store.ListByFilter(f)
+		if err != nil {
+			t.Fatalf("ListByFilter(%v): %v", f, err)
+		}
+		got := ids(tasks)
+		slices.Sort(got)
+		return got
+	}
+	if got := visible(TaskFilter{MemberOf: "alice"}); !slices.Equal(got, []string{"e1", "e2", "e3"}) {
+		t.Errorf("expected alice's team tasks, got %v", got)
+	}
+	if got := visible(TaskFilter{VisibleTo: "carol"}); !slices.Equal(got, []string{"e2", "m2", "o1"}) {
+		t.Errorf("expected carol's own tasks, got %v", got)
+	}
+	if got := visible(TaskFilter{VisibleTo: "alice", IncludeUnassigned: true}); !slices.Equal(got, []string{"e1", "e2", "e3", "p1"}) {
+		t.Errorf("expected alice's team and unassigned tasks, got %v", got)
+	}
+
+	// 统计合并各分片
+	if n, err := store.Count(nil); err != nil || n != 7 {
+		t.Errorf("expected 7 tasks, got %d (%v)", n, err)
+	}
+	if types, err := store.ListTaskTypes(); err != nil || !slices.Equal(types, []string{"shell"}) {
+		t.Errorf("expected merged task types, got %v (%v)", types, err)
+	}
+	pending, err := store.ListPending(3, time.Now())
+	if err != nil || !slices.Equal(ids(pending), []string{"e1", "p1", "e2"}) {
+		t.Errorf("expected the 3 most urgent ready tasks, got %v (%v)", ids(pending), err)
+	}
+
+	// 互斥跨分片检查
+	if err := store.ClaimExclusive("p1", Exclusion{}, "scheduler", "run", "node-a", nil); err != nil {
+		t.Fatalf("failed to claim p1: %v", err)
+	}
+	if err := store.ClaimExclusive("m2", Exclusion{TaskTypes: []string{"shell"}}, "scheduler", "run", "node-a", nil); !errors.Is(err, ErrExclusionBusy) {
+		t.Errorf("expected ErrExclusionBusy from a task running in another shard, got %v", err)
+	}
+
+	if _, err := store.LatestEventSeq(); !errors.Is(err, ErrShardedCursor) {
+		t.Errorf("expected ErrShardedCursor, got %v", err)
+	}
+}
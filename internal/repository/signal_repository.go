@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// SuspendForInput 把执行器请求外部输入的任务从 RUNNING 挂起为 WAITING_INPUT，同一事务中写入输入说明和状态事件；
+// 任务已不在运行（如已被取消）时返回 ErrStatusMismatch
+func (r *TaskRepository) SuspendForInput(taskID, prompt, operator, message, instanceID string, meta map[string]string) error {
+	defer r.db.observe("tasks.SuspendForInput", time.Now(), "task_id", taskID)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		now := formatTime(model.Now())
+		result, err := tx.Exec(`UPDATE tasks SET status = ?, updated_at = ?, version = version + 1, input_request = ?
+			WHERE id = ? AND status = ?`,
+			model.TaskStatusWaitingInput, now, prompt, taskID, model.TaskStatusRunning)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		return insertStatusEvent(tx, taskID, model.TaskStatusRunning, model.TaskStatusWaitingInput, operator, message, instanceID, now, meta)
+	})
+}
+
+// SignalTask 向 WAITING_INPUT 的任务送达输入：同一事务中把 payload 按键合并到已送达的输入、清除输入说明、
+// 把任务重新置为 PENDING 并记录状态事件。送达的输入与 input_params 一样在启用列加密时加密存储。任务已不在等待输入（如已被其他信号唤醒或被取消）时返回 ErrStatusMismatch
+func (r *TaskRepository) SignalTask(taskID string, payload map[string]string, operator, message, correlationID string) error {
+	defer r.db.observe("tasks.SignalTask", time.Now(), "task_id", taskID)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		var existing sql.NullString
+		err := tx.QueryRow(`SELECT signal_input FROM tasks WHERE id = ? AND status = ?`, taskID, model.TaskStatusWaitingInput).Scan(&existing)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrStatusMismatch
+		}
+		if err != nil {
+			return err
+		}
+		var input map[string]string
+		if existing.Valid {
+			plaintext, err := r.db.fields.decrypt(taskID, "signal_input", existing.String)
+			if err != nil {
+				return err
+			}
+			json.Unmarshal([]byte(plaintext), &input)
+		}
+		merged, _ := json.Marshal(model.MergeSignalInput(input, payload))
+		encrypted, err := r.db.fields.encrypt(taskID, "signal_input", string(merged))
+		if err != nil {
+			return fmt.Errorf("encrypt signal_input: %w", err)
+		}
+
+		// 条件更新保证并发的信号只有一个生效，其余的返回 ErrStatusMismatch
+		now := formatTime(model.Now())
+		result, err := tx.Exec(`UPDATE tasks SET status = ?, updated_at = ?, version = version + 1, input_request = NULL, signal_input = ?
+			WHERE id = ? AND status = ?`,
+			model.TaskStatusPending, now, encrypted, taskID, model.TaskStatusWaitingInput)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		return insertTaskEvent(tx, model.OutboxEventTaskStatusChanged, taskID, model.TaskStatusWaitingInput, model.TaskStatusPending, operator, message, "", correlationID, now, nil)
+	})
+}
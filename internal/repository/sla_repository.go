@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// ErrSLAAlreadyMarked 任务不存在或已记录过 SLA 违约
+var ErrSLAAlreadyMarked = errors.New("task not found or SLA breach already recorded")
+
+// ListSLABreachCandidates 列出截止时间已过、尚未记录违约的任务：未结束的任务全部列出，
+// 已结束的任务只列出截止时间在 since 之后的（更早的已在之前的检查中处理过）。是否违约由调用方按 model.Task.SLABreached 判断
+func (r *TaskRepository) ListSLABreachCandidates(now, since time.Time, limit int) ([]*model.Task, error) {
+	defer r.db.observe("tasks.ListSLABreachCandidates", time.Now(), "limit", limit)
+	query := `SELECT ` + taskColumns + ` FROM tasks
+	WHERE sla_breached_at IS NULL AND sla_deadline IS NOT NULL AND sla_deadline <= ?
+	AND (status IN (?, ?) OR sla_deadline > ?)
+	ORDER BY sla_deadline ASC LIMIT ?`
+
+	rows, err := r.db.DB().Query(query, now.UTC().Format(utcMillisLayout),
+		model.TaskStatusPending, model.TaskStatusRunning, since.UTC().Format(utcMillisLayout), limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanTasks(rows)
+}
+
+// MarkSLABreached 记录任务违约时间，同一事务中写入事件和 task.sla_breached 发件箱事件。
+// 已记录过违约时返回 ErrSLAAlreadyMarked，多个实例同时检查时只有一个成功
+func (r *TaskRepository) MarkSLABreached(taskID string, at time.Time, operator, message, instanceID string) error {
+	defer r.db.observe("tasks.MarkSLABreached", time.Now(), "task_id", taskID)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		var status model.TaskStatus
+		err := tx.QueryRow(`SELECT status FROM tasks WHERE id = ? AND sla_breached_at IS NULL`, taskID).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSLAAlreadyMarked
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`UPDATE tasks SET sla_breached_at = ?, version = version + 1 WHERE id = ? AND sla_breached_at IS NULL`,
+			at.UTC().Format(utcMillisLayout), taskID)
+		if err != nil {
+			return err
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return ErrSLAAlreadyMarked
+		}
+
+		return insertTaskEvent(tx, model.OutboxEventTaskSLABreached, taskID, status, status, operator, message, instanceID, "", formatTime(at), nil)
+	})
+}
+
+// ListSLATasks 列出截止时间在 [from, to] 内的任务，按截止时间升序
+func (r *TaskRepository) ListSLATasks(from, to time.Time, limit int) ([]*model.Task, error) {
+	defer r.db.observe("tasks.ListSLATasks", time.Now(), "from", from, "to", to, "limit", limit)
+	query := `SELECT ` + taskColumns + ` FROM tasks
+	WHERE sla_deadline >= ? AND sla_deadline <= ?
+	ORDER BY sla_deadline ASC, id ASC LIMIT ?`
+
+	rows, err := r.db.DB().Query(query, from.UTC().Format(utcMillisLayout), to.UTC().Format(utcMillisLayout), limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanTasks(rows)
+}
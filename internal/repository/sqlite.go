@@ -2,6 +2,8 @@ package repository
 
 import (
 	"database/sql"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -9,12 +11,18 @@ import (
 
 // SQLite SQLite 数据库
 type SQLite struct {
-	db *sql.DB
+	db                 *sql.DB
+	slowQueryThreshold time.Duration
+	fields             *FieldEncryptor // 任务敏感列加密，nil 表示不加密
 }
 
-// NewSQLite 创建 SQLite 实例
+// sqliteBusyTimeout 写锁被占用时等待的时间，超时后才返回 SQLITE_BUSY
+const sqliteBusyTimeout = 5 * time.Second
+
+// NewSQLite 创建 SQLite 实例。DSN 未指定时为每个连接设置 busy_timeout，文件数据库使用 WAL 日志模式，
+// 调度器、执行器上报进度和 API 请求并发写入时等待写锁而不是立即返回 database is locked
 func NewSQLite(dsn string) (*SQLite, error) {
-	db, err := sql.Open("sqlite3", dsn)
+	db, err := sql.Open("sqlite3", sqliteDSN(dsn))
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +37,27 @@ func NewSQLite(dsn string) (*SQLite, error) {
 		return nil, err
 	}
 
-	return &SQLite{db: db}, nil
+	return &SQLite{db: db, slowQueryThreshold: DefaultSlowQueryThreshold}, nil
+}
+
+// sqliteDSN 为 DSN 补充未指定的 _busy_timeout 和 _journal_mode 参数；内存数据库和只读连接不切换到 WAL
+func sqliteDSN(dsn string) string {
+	var params []string
+	if !strings.Contains(dsn, "_busy_timeout=") && !strings.Contains(dsn, "_timeout=") {
+		params = append(params, "_busy_timeout="+strconv.Itoa(int(sqliteBusyTimeout.Milliseconds())))
+	}
+	inMemory := strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+	if !strings.Contains(dsn, "_journal_mode=") && !strings.Contains(dsn, "_journal=") && !inMemory && !strings.Contains(dsn, "mode=ro") {
+		params = append(params, "_journal_mode=WAL")
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + strings.Join(params, "&")
 }
 
 // Close 关闭数据库连接
@@ -42,50 +70,14 @@ func (s *SQLite) DB() *sql.DB {
 	return s.db
 }
 
-// InitSchema 初始化数据库表结构
+// Migrate 执行尚未执行的 SQLite 迁移，返回本次执行的迁移
+func (s *SQLite) Migrate() ([]Migration, error) {
+	return Migrate(s.db, DialectSQLite)
+}
+
+// InitSchema 初始化数据库表结构（执行全部迁移）
 func (s *SQLite) InitSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS tasks (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		description TEXT,
-		status INTEGER NOT NULL DEFAULT 1,
-		priority INTEGER NOT NULL DEFAULT 2,
-		task_type TEXT,
-		input_params TEXT,
-		output_result TEXT,
-		dependencies TEXT,
-		retry_count INTEGER NOT NULL DEFAULT 0,
-		max_retries INTEGER NOT NULL DEFAULT 0,
-		error_message TEXT,
-		created_at TEXT NOT NULL,
-		updated_at TEXT NOT NULL,
-		started_at TEXT,
-		completed_at TEXT,
-		created_by TEXT
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
-	CREATE INDEX IF NOT EXISTS idx_tasks_priority ON tasks(priority);
-	CREATE INDEX IF NOT EXISTS idx_tasks_created_by ON tasks(created_by);
-	CREATE INDEX IF NOT EXISTS idx_tasks_created_at ON tasks(created_at);
-
-	CREATE TABLE IF NOT EXISTS task_events (
-		id TEXT PRIMARY KEY,
-		task_id TEXT NOT NULL,
-		from_status INTEGER NOT NULL,
-		to_status INTEGER NOT NULL,
-		message TEXT,
-		timestamp TEXT NOT NULL,
-		operator TEXT,
-		FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_task_events_task_id ON task_events(task_id);
-	CREATE INDEX IF NOT EXISTS idx_task_events_timestamp ON task_events(timestamp);
-	`
-
-	_, err := s.db.Exec(schema)
+	_, err := s.Migrate()
 	return err
 }
 
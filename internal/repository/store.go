@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// ErrTaskNotFound GetByID 查询的任务不存在
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrTaskHasDependents Delete 未指定 force 时要删除的任务仍被未结束的任务依赖，具体的下游任务见 DependentsError
+var ErrTaskHasDependents = errors.New("task has active dependents")
+
+// BlockedDependent 阻止删除的下游任务
+type BlockedDependent struct {
+	ID     string
+	Name   string
+	Status model.TaskStatus
+}
+
+// DependentsError 要删除的任务仍被未结束的任务依赖，删除后这些任务的依赖检查会因上游任务不存在而报错。
+// errors.Is(err, ErrTaskHasDependents) 成立
+type DependentsError struct {
+	TaskID     string
+	Dependents []BlockedDependent // 按任务 ID 排序
+}
+
+func (e *DependentsError) Error() string {
+	blocked := make([]string, len(e.Dependents))
+	for i, d := range e.Dependents {
+		blocked[i] = fmt.Sprintf("%s (%s)", d.ID, d.Status)
+	}
+	return fmt.Sprintf("%s: %s is required by %s", ErrTaskHasDependents, e.TaskID, strings.Join(blocked, ", "))
+}
+
+// Is 使 errors.Is(err, ErrTaskHasDependents) 成立
+func (e *DependentsError) Is(target error) bool {
+	return target == ErrTaskHasDependents
+}
+
+// activeDependentsError dependents 中有未结束的任务时返回列出这些任务的 DependentsError，否则返回 nil
+func activeDependentsError(taskID string, dependents []*model.Task) error {
+	var blocked []BlockedDependent
+	for _, task := range dependents {
+		if !task.Status.IsTerminal() {
+			blocked = append(blocked, BlockedDependent{ID: task.ID, Name: task.Name, Status: task.Status})
+		}
+	}
+	if len(blocked) == 0 {
+		return nil
+	}
+	return &DependentsError{TaskID: taskID, Dependents: blocked}
+}
+
+// DeletedTask Delete 删除的任务留在存储之外的数据和受影响的下游任务。数据库只保存对象 key，
+// 调用方在删除成功后从 BlobStore 清理这些对象，并重新评估下游任务的调度
+type DeletedTask struct {
+	AttachmentKeys []string // 附件在附件存储中的对象 key
+	OutputRefs     []string // 转存的任务输出（含历次运行）在产物存储中的对象 key，已去重
+	Dependents     []string // 移除了对该任务依赖的下游任务 ID，按任务 ID 排序
+}
+
+// dependencyRemovedMessage 强制删除上游任务时记录在下游任务上的事件说明
+func dependencyRemovedMessage(taskID string) string {
+	return fmt.Sprintf("dependency %s removed: upstream task deleted", taskID)
+}
+
+// FindTask 兼容旧约定的 GetByID：任务不存在时返回 nil, nil，供把缺失视为正常情况的调用方使用
+func FindTask(store TaskStore, id string) (*model.Task, error) {
+	task, err := store.GetByID(id)
+	if errors.Is(err, ErrTaskNotFound) {
+		return nil, nil
+	}
+	return task, err
+}
+
+// TaskStore 任务仓储接口，由 SQLite（TaskRepository）和内存（MemoryTaskRepository）实现。
+// GetByID 查询不到任务时返回 ErrTaskNotFound，其余查询不到单个对象时返回 nil, nil；
+// 条件状态更新未命中时返回 ErrStatusMismatch
+type TaskStore interface {
+	// 任务
+	Create(task *model.Task) error
+	GetByID(id string) (*model.Task, error)
+	GetByIDs(ids []string) ([]*model.Task, error)
+	ListAfterID(afterID string, limit int) ([]*model.Task, error)
+	Update(task *model.Task) error
+	UpdateDefinition(task *model.Task, expectedStatus model.TaskStatus) error
+	Delete(id string, force bool, operator, correlationID string) (*DeletedTask, error)
+	RepairDependencies(taskID string, repairs []model.DependencyRepair, operator, reason, correlationID string) error
+	List(limit, offset int, statusFilter *model.TaskStatus) ([]*model.Task, error)
+	ListByStatus(status model.TaskStatus, limit int) ([]*model.Task, error)
+	ListByCreator(createdBy string, limit, offset int) ([]*model.Task, error)
+	ListPending(limit int, now time.Time) ([]*model.Task, error)
+	ListDependents(taskIDs []string, statusFilter *model.TaskStatus) ([]*model.Task, error)
+	ListByFilter(filter TaskFilter) ([]*model.Task, int, error)
+	Search(keyword string, limit, offset int) ([]*model.Task, error)
+	Count(statusFilter *model.TaskStatus) (int, error)
+	RecentDurations(taskType string, limit int) ([]time.Duration, error)
+	ListTaskTypes() ([]string, error)
+	CountOutcomesByType(from, to time.Time) (map[string]OutcomeCount, error)
+
+	// 状态变更与事件；进入终态时同时记录完成时间
+	AddEvent(event *model.TaskEvent) error
+	GetEventsByTaskID(taskID string) ([]model.TaskEvent, error)
+	ListEventsAfter(afterSeq int64, limit int) ([]model.TaskEvent, error)
+	ListTaskEvents(taskID string, filter EventFilter) ([]model.TaskEvent, error)
+	CompactEvents(before time.Time, keepLatest int) (int64, error)
+	LatestEventSeq() (int64, error)
+	UpdateStatus(id string, fromStatus, toStatus model.TaskStatus) error
+	UpdateStatusWithEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message string) error
+	UpdateStatusWithCorrelatedEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, correlationID string) error
+	UpdateStatusWithInstanceEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID string, meta map[string]string) error
+	ScheduleRetry(taskID string, fromStatus model.TaskStatus, retryCount int32, nextRunAt *time.Time, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error
+	FailTask(taskID, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error
+	CompleteTask(taskID string, fromStatus model.TaskStatus, output map[string]string, outputRef, operator, message, instanceID string, meta map[string]string) error
+	ClaimExclusive(taskID string, excl Exclusion, operator, message, instanceID string, meta map[string]string) error
+	RunningConflict(taskID string, excl Exclusion) error
+	SetBlockedReason(taskID, reason string) error
+	UpdateProgress(taskID string, progress int32, message string, at time.Time) error
+	UpdateSubStatus(taskID, subStatus, message string, at time.Time, operator, instanceID string) error
+	Heartbeat(taskID string, at time.Time) error
+
+	// 等待输入：执行器挂起任务，信号送达输入后任务重新进入 PENDING
+	SuspendForInput(taskID, prompt, operator, message, instanceID string, meta map[string]string) error
+	SignalTask(taskID string, payload map[string]string, operator, message, correlationID string) error
+
+	// 重新运行：原地重置前保存本次运行的结果
+	RerunTask(taskID string, fromStatus model.TaskStatus, operator, message, correlationID string) (int32, error)
+	ListRuns(taskID string) ([]model.TaskRun, error)
+
+	// 优先级继承：下游高优先级任务等待时临时提升上游任务的优先级，上游开始执行后恢复
+	ListWaitingOnDependencies(minPriority model.TaskPriority, limit int) ([]*model.Task, error)
+	BoostPriority(taskID string, priority model.TaskPriority, operator, message, instanceID string) error
+	RestorePriority(taskID, operator, message, instanceID string) error
+
+	// 排队时长监控：尚未开始执行的任务排队超过上限时告警，可选提高优先级
+	ListQueueTimeCandidates(queuedBefore time.Time, taskTypes, excludeTypes []string, limit int) ([]*model.Task, error)
+	MarkQueueTimeExceeded(taskID string, at time.Time, escalateTo model.TaskPriority, operator, message, instanceID string) error
+
+	// 工作流截止时间：截止时间已过的工作流中未结束的任务由监控取消
+	ListWorkflowDeadlineExceeded(now time.Time, limit int) ([]*model.Task, error)
+
+	// SLA
+	ListSLABreachCandidates(now, since time.Time, limit int) ([]*model.Task, error)
+	MarkSLABreached(taskID string, at time.Time, operator, message, instanceID string) error
+	ListSLATasks(from, to time.Time, limit int) ([]*model.Task, error)
+
+	// 评论与附件
+	AddComment(comment *model.TaskComment) error
+	GetCommentsByTaskID(taskID string) ([]model.TaskComment, error)
+	AddAttachment(a *model.TaskAttachment) error
+	GetAttachment(taskID, id string) (*model.TaskAttachment, error)
+	ListAttachments(taskID string) ([]*model.TaskAttachment, error)
+	DeleteAttachment(taskID, id string) error
+
+	// 执行日志
+	AppendLogs(lines []model.TaskLogLine) error
+	GetLogs(taskID string, afterSeq int64, limit int) ([]model.TaskLogLine, error)
+	LastLogSeq(taskID string) (int64, error)
+	TrimLogs(taskID string, keep int64) error
+
+	// 调度器实例
+	UpsertSchedulerInstance(inst *model.SchedulerInstance) error
+	ListSchedulerInstances() ([]*model.SchedulerInstance, error)
+	DeleteSchedulerInstance(id string) error
+	SaveSchedulerCheckpoint(cp *model.SchedulerCheckpoint) error
+	GetSchedulerCheckpoint(name string) (*model.SchedulerCheckpoint, error)
+
+	// 发件箱
+	ListDueOutboxEvents(now time.Time, limit int) ([]*model.OutboxEvent, error)
+	ListOutboxEventsAfter(afterSeq int64, limit int) ([]*model.OutboxEvent, error)
+	OutboxSeqRange() (first, last int64, err error)
+	MarkOutboxEventDelivered(id string, at time.Time) error
+	MarkOutboxEventFailed(id string, lastErr string, nextAttemptAt time.Time) error
+	CountPendingOutboxEvents() (int, error)
+	PurgeDeliveredOutboxEvents(before time.Time) (int64, error)
+
+	// 数据完整性修复
+	ListOrphanedEventTaskIDs(afterID string, limit int) ([]string, error)
+	DeleteOrphanedEvents(taskIDs []string) (int64, error)
+	SetCompletedAt(taskID string, status model.TaskStatus, at time.Time) error
+}
+
+// TeamStore 团队仓储接口，由 SQLite（TeamRepository）和内存（MemoryTeamRepository）实现
+type TeamStore interface {
+	Create(team *model.Team) error
+	GetByID(id string) (*model.Team, error)
+	Delete(id string) error
+	AddMember(teamID, userID string) error
+	RemoveMember(teamID, userID string) error
+	ListMembers(teamID string) ([]string, error)
+	ListTeamIDsByUser(userID string) ([]string, error)
+	ListByUser(userID string) ([]*model.Team, error)
+}
+
+// SecretStore 密钥仓储接口，由 SQLite（SecretRepository）和内存（MemorySecretRepository）实现，只保存密文
+type SecretStore interface {
+	Put(secret *model.Secret) error
+	Get(name string) (*model.Secret, error)
+	List() ([]*model.Secret, error)
+	Delete(name string) (bool, error)
+}
+
+var (
+	_ TaskStore   = (*TaskRepository)(nil)
+	_ TeamStore   = (*TeamRepository)(nil)
+	_ SecretStore = (*SecretRepository)(nil)
+)
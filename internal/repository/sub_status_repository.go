@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// UpdateSubStatus 记录 RUNNING 任务进入的自定义阶段（子状态），同时作为一次心跳。阶段的执行次数取任务当前的执行次数，
+// 按 model.Task.EnterPhase 追加阶段历史；进入新阶段时同一事务中写入 task.sub_status_changed 事件，
+// 同名阶段只更新说明。任务已不是 RUNNING 时返回 ErrStatusMismatch
+func (r *TaskRepository) UpdateSubStatus(taskID, subStatus, message string, at time.Time, operator, instanceID string) error {
+	defer r.db.observe("tasks.UpdateSubStatus", time.Now(), "task_id", taskID, "sub_status", subStatus)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		var task model.Task
+		var current, phases sql.NullString
+		err := tx.QueryRow(`SELECT retry_count, sub_status, phases FROM tasks WHERE id = ? AND status = ?`,
+			taskID, model.TaskStatusRunning).Scan(&task.RetryCount, &current, &phases)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrStatusMismatch
+		}
+		if err != nil {
+			return err
+		}
+		task.SubStatus = current.String
+		if phases.Valid {
+			json.Unmarshal([]byte(phases.String), &task.Phases)
+		}
+		entered := task.EnterPhase(model.TaskPhase{Name: subStatus, Message: message, Attempt: task.RetryCount + 1, EnteredAt: at.UTC()})
+		encoded, _ := json.Marshal(task.Phases)
+
+		now := formatTime(at)
+		result, err := tx.Exec(`UPDATE tasks SET sub_status = ?, phases = ?, heartbeat_at = ?, updated_at = ?, version = version + 1
+			WHERE id = ? AND status = ?`,
+			subStatus, string(encoded), now, now, taskID, model.TaskStatusRunning)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+		if !entered {
+			return nil
+		}
+
+		return insertTaskEvent(tx, model.OutboxEventTaskSubStatusChanged, taskID, model.TaskStatusRunning, model.TaskStatusRunning,
+			operator, model.PhaseEventMessage(subStatus, message), instanceID, "", now, nil)
+	})
+}
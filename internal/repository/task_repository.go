@@ -5,12 +5,28 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"taskflow/internal/idgen"
 	"taskflow/internal/model"
 )
 
+// taskColumns tasks 表查询列（顺序需与 scanTask 保持一致）
+const taskColumns = `id, name, description, status, priority, task_type,
+		input_params, output_result, dependencies, retry_count,
+		max_retries, error_message, created_at, updated_at,
+		started_at, completed_at, created_by, team_id, executed_by, output_ref,
+		retry_policy, next_run_at, error_class, blocked_reason, dependency_policies,
+		resource_slots, group_key, sla_deadline, sla_breached_at, correlation_id, labels, rerun_of,
+		parent_id, progress, progress_message, heartbeat_at, version, input_request, signal_input,
+		node_selector, target_worker, base_priority, queued_at, queue_alerted_at, sub_status, phases,
+		workflow_deadline`
+
+// ErrStatusMismatch 条件状态更新未命中：任务不存在或状态已被并发修改
+var ErrStatusMismatch = errors.New("task not found or status mismatch")
+
 // TaskRepository 任务仓储
 type TaskRepository struct {
 	db *SQLite
@@ -21,54 +37,80 @@ func NewTaskRepository(db *SQLite) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
-// Create 创建任务
+// Create 创建任务，并在同一事务中记录创建事件；新任务的版本号为 1
 func (r *TaskRepository) Create(task *model.Task) error {
-	inputParams, _ := json.Marshal(task.InputParams)
-	outputResult, _ := json.Marshal(task.OutputResult)
+	defer r.db.observe("tasks.Create", time.Now(), "id", task.ID)
+	inputParams, outputResult, err := r.encodeSensitiveFields(task)
+	if err != nil {
+		return err
+	}
 	dependencies, _ := json.Marshal(task.Dependencies)
 
 	query := `INSERT INTO tasks (
 		id, name, description, status, priority, task_type,
 		input_params, output_result, dependencies, retry_count,
 		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		started_at, completed_at, created_by, team_id, retry_policy,
+		dependency_policies, resource_slots, group_key, sla_deadline, correlation_id, labels, rerun_of, parent_id,
+		node_selector, target_worker, workflow_deadline
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := r.db.DB().Exec(query,
+	args := []interface{}{
 		task.ID,
 		task.Name,
 		task.Description,
 		task.Status,
 		task.Priority,
 		task.TaskType,
-		string(inputParams),
-		string(outputResult),
+		inputParams,
+		outputResult,
 		string(dependencies),
 		task.RetryCount,
 		task.MaxRetries,
 		task.ErrorMessage,
-		task.CreatedAt.Format(time.RFC3339),
-		task.UpdatedAt.Format(time.RFC3339),
+		formatTime(task.CreatedAt),
+		formatTime(task.UpdatedAt),
 		nullableTime(task.StartedAt),
 		nullableTime(task.CompletedAt),
 		task.CreatedBy,
-	)
+		task.TeamID,
+		nullableRetryPolicy(task.RetryPolicy),
+		nullableDependencyPolicies(task.DependencyPolicies),
+		task.ResourceSlots,
+		nullableString(task.GroupKey),
+		nullableUTCTime(task.SLADeadline),
+		nullableString(task.CorrelationID),
+		nullableLabels(task.Labels),
+		nullableString(task.RerunOf),
+		nullableString(task.ParentID),
+		nullableLabels(task.NodeSelector),
+		nullableString(task.TargetWorker),
+		nullableUTCTime(task.WorkflowDeadline),
+	}
 
-	return err
+	task.Version = 1
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+		created := model.NewCreatedEvent(task)
+		_, err := tx.Exec(`INSERT INTO task_events (id, task_id, from_status, to_status, message, timestamp, operator, instance_id, correlation_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, NULL, ?)`,
+			created.ID, created.TaskID, created.FromStatus, created.ToStatus, created.Message,
+			formatTime(created.Timestamp), created.Operator, nullableString(created.CorrelationID))
+		return err
+	})
 }
 
-// GetByID 根据 ID 获取任务
+// GetByID 根据 ID 获取任务，不存在时返回 ErrTaskNotFound
 func (r *TaskRepository) GetByID(id string) (*model.Task, error) {
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks WHERE id = ?`
+	defer r.db.observe("tasks.GetByID", time.Now(), "id", id)
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = ?`
 
 	task, err := r.scanTask(r.db.DB().QueryRow(query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+			return nil, ErrTaskNotFound
 		}
 		return nil, err
 	}
@@ -80,13 +122,70 @@ func (r *TaskRepository) GetByID(id string) (*model.Task, error) {
 	}
 	task.Events = events
 
+	// 加载评论
+	comments, err := r.GetCommentsByTaskID(id)
+	if err != nil {
+		return nil, err
+	}
+	task.Comments = comments
+
+	// 加载历次运行
+	runs, err := r.ListRuns(id)
+	if err != nil {
+		return nil, err
+	}
+	task.Runs = runs
+
 	return task, nil
 }
 
-// Update 更新任务
+// GetByIDs 批量获取任务（不含事件），结果与 ids 顺序一致，不存在的任务对应位置为 nil
+func (r *TaskRepository) GetByIDs(ids []string) ([]*model.Task, error) {
+	defer r.db.observe("tasks.GetByIDs", time.Now(), "ids", len(ids))
+	found := make(map[string]*model.Task, len(ids))
+
+	// SQLite 单条语句参数数量有限，分批查询
+	const batchSize = 500
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		placeholders := inPlaceholders(len(chunk))
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+
+		rows, err := r.db.DB().Query(`SELECT `+taskColumns+` FROM tasks WHERE id IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		tasks, err := r.scanTasks(rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			found[task.ID] = task
+		}
+	}
+
+	result := make([]*model.Task, len(ids))
+	for i, id := range ids {
+		result[i] = found[id]
+	}
+	return result, nil
+}
+
+// Update 更新任务，SLA 违约时间只由 MarkSLABreached 写入
 func (r *TaskRepository) Update(task *model.Task) error {
-	inputParams, _ := json.Marshal(task.InputParams)
-	outputResult, _ := json.Marshal(task.OutputResult)
+	defer r.db.observe("tasks.Update", time.Now(), "id", task.ID)
+	inputParams, outputResult, err := r.encodeSensitiveFields(task)
+	if err != nil {
+		return err
+	}
 	dependencies, _ := json.Marshal(task.Dependencies)
 
 	query := `UPDATE tasks SET 
@@ -94,45 +193,156 @@ func (r *TaskRepository) Update(task *model.Task) error {
 		task_type = ?, input_params = ?, output_result = ?,
 		dependencies = ?, retry_count = ?, max_retries = ?,
 		error_message = ?, updated_at = ?, started_at = ?,
-		completed_at = ?, created_by = ?
+		completed_at = ?, created_by = ?, team_id = ?,
+		output_ref = ?, retry_policy = ?, next_run_at = ?,
+		error_class = ?, blocked_reason = ?, dependency_policies = ?,
+		resource_slots = ?, group_key = ?, sla_deadline = ?,
+		labels = ?, node_selector = ?, target_worker = ?, version = version + 1
 	WHERE id = ?`
 
-	_, err := r.db.DB().Exec(query,
+	_, err = r.db.DB().Exec(query,
 		task.Name,
 		task.Description,
 		task.Status,
 		task.Priority,
 		task.TaskType,
-		string(inputParams),
-		string(outputResult),
+		inputParams,
+		outputResult,
 		string(dependencies),
 		task.RetryCount,
 		task.MaxRetries,
 		task.ErrorMessage,
-		task.UpdatedAt.Format(time.RFC3339),
+		formatTime(task.UpdatedAt),
 		nullableTime(task.StartedAt),
 		nullableTime(task.CompletedAt),
 		task.CreatedBy,
+		task.TeamID,
+		nullableString(task.OutputRef),
+		nullableRetryPolicy(task.RetryPolicy),
+		nullableUTCTime(task.NextRunAt),
+		nullableString(string(task.ErrorClass)),
+		nullableString(task.BlockedReason),
+		nullableDependencyPolicies(task.DependencyPolicies),
+		task.ResourceSlots,
+		nullableString(task.GroupKey),
+		nullableUTCTime(task.SLADeadline),
+		nullableLabels(task.Labels),
+		nullableLabels(task.NodeSelector),
+		nullableString(task.TargetWorker),
 		task.ID,
 	)
 
 	return err
 }
 
-// Delete 删除任务
-func (r *TaskRepository) Delete(id string) error {
-	query := `DELETE FROM tasks WHERE id = ?`
-	_, err := r.db.DB().Exec(query, id)
-	return err
+// UpdateDefinition 只写入任务定义中可由用户修改的字段（名称、描述、优先级、参数、重试上限、标签），
+// 任务状态已不是 expectedStatus 时返回 ErrStatusMismatch，不影响调度器并发写入的状态和执行结果
+func (r *TaskRepository) UpdateDefinition(task *model.Task, expectedStatus model.TaskStatus) error {
+	defer r.db.observe("tasks.UpdateDefinition", time.Now(), "id", task.ID)
+	inputParams, _ := json.Marshal(task.InputParams)
+	encrypted, err := r.db.fields.encrypt(task.ID, "input_params", string(inputParams))
+	if err != nil {
+		return fmt.Errorf("encrypt input_params: %w", err)
+	}
+
+	result, err := r.db.DB().Exec(`UPDATE tasks SET name = ?, description = ?, priority = ?, input_params = ?,
+		max_retries = ?, labels = ?, base_priority = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND status = ?`,
+		task.Name, task.Description, task.Priority, encrypted,
+		task.MaxRetries, nullableLabels(task.Labels), nullablePriority(task.BasePriority), formatTime(task.UpdatedAt),
+		task.ID, expectedStatus)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+// Delete 在同一事务中删除任务及其事件、评论、附件、日志和历次运行（SQLite 连接未开启外键约束，不会级联删除），
+// 并从依赖该任务的任务的依赖和依赖失败处理方式中移除该任务，在这些任务上记录 dependency_removed 事件。
+// 有未结束的任务依赖该任务且 force 为 false 时不删除，返回列出这些任务的 *DependentsError。
+// 发件箱事件保留，供订阅者消费。任务不存在时只清理残留的关联数据
+func (r *TaskRepository) Delete(id string, force bool, operator, correlationID string) (*DeletedTask, error) {
+	defer r.db.observe("tasks.Delete", time.Now(), "id", id, "force", force)
+	var deleted *DeletedTask
+	err := r.db.ExecTx(func(tx *sql.Tx) error {
+		deleted = &DeletedTask{}
+		rows, err := tx.Query(`SELECT id, name, status, dependencies, dependency_policies FROM tasks
+			WHERE id != ? AND EXISTS (SELECT 1 FROM json_each(tasks.dependencies) d WHERE d.value = ?) ORDER BY id`, id, id)
+		if err != nil {
+			return err
+		}
+		var dependents []*model.Task
+		for rows.Next() {
+			var task model.Task
+			var dependencies string
+			var policies sql.NullString
+			if err := rows.Scan(&task.ID, &task.Name, &task.Status, &dependencies, &policies); err != nil {
+				rows.Close()
+				return err
+			}
+			json.Unmarshal([]byte(dependencies), &task.Dependencies)
+			if policies.Valid {
+				json.Unmarshal([]byte(policies.String), &task.DependencyPolicies)
+			}
+			dependents = append(dependents, &task)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if !force {
+			if err := activeDependentsError(id, dependents); err != nil {
+				return err
+			}
+		}
+
+		// 对象 key 在删除前收集，事务提交后由调用方清理
+		if rows, err = tx.Query(`SELECT storage_key FROM task_attachments WHERE task_id = ? ORDER BY created_at, id`, id); err != nil {
+			return err
+		}
+		if deleted.AttachmentKeys, err = scanStrings(rows); err != nil {
+			return err
+		}
+		if rows, err = tx.Query(`SELECT output_ref FROM tasks WHERE id = ? AND COALESCE(output_ref, '') != ''
+			UNION SELECT output_ref FROM task_runs WHERE task_id = ? AND COALESCE(output_ref, '') != ''`, id, id); err != nil {
+			return err
+		}
+		if deleted.OutputRefs, err = scanStrings(rows); err != nil {
+			return err
+		}
+
+		now := formatTime(model.Now())
+		for _, task := range dependents {
+			task.RemoveDependency(id)
+			dependencies, _ := json.Marshal(task.Dependencies)
+			if _, err := tx.Exec(`UPDATE tasks SET dependencies = ?, dependency_policies = ?, updated_at = ?, version = version + 1 WHERE id = ?`,
+				string(dependencies), nullableDependencyPolicies(task.DependencyPolicies), now, task.ID); err != nil {
+				return err
+			}
+			if err := insertTaskEvent(tx, model.OutboxEventTaskDependencyRemoved, task.ID, task.Status, task.Status,
+				operator, dependencyRemovedMessage(id), "", correlationID, now, nil); err != nil {
+				return err
+			}
+			deleted.Dependents = append(deleted.Dependents, task.ID)
+		}
+		for _, table := range []string{"task_events", "task_comments", "task_attachments", "task_logs", "task_runs"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE task_id = ?`, id); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(`DELETE FROM tasks WHERE id = ?`, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
 }
 
 // List 列出任务（分页）
 func (r *TaskRepository) List(limit, offset int, statusFilter *model.TaskStatus) ([]*model.Task, error) {
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks`
+	defer r.db.observe("tasks.List", time.Now(), "limit", limit, "offset", offset, "status", statusFilter)
+	query := `SELECT ` + taskColumns + ` FROM tasks`
 
 	var args []interface{}
 	if statusFilter != nil {
@@ -146,18 +356,7 @@ func (r *TaskRepository) List(limit, offset int, statusFilter *model.TaskStatus)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var tasks []*model.Task
-	for rows.Next() {
-		task, err := r.scanTask(rows)
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
-	}
-
-	return tasks, rows.Err()
+	return r.scanTasks(rows)
 }
 
 // ListByStatus 根据状态列出任务
@@ -167,58 +366,83 @@ func (r *TaskRepository) ListByStatus(status model.TaskStatus, limit int) ([]*mo
 
 // ListByCreator 根据创建者列出任务
 func (r *TaskRepository) ListByCreator(createdBy string, limit, offset int) ([]*model.Task, error) {
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks WHERE created_by = ? ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	defer r.db.observe("tasks.ListByCreator", time.Now(), "created_by", createdBy, "limit", limit, "offset", offset)
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE created_by = ? ORDER BY created_at DESC LIMIT ? OFFSET ?`
 
 	rows, err := r.db.DB().Query(query, createdBy, limit, offset)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var tasks []*model.Task
-	for rows.Next() {
-		task, err := r.scanTask(rows)
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
-	}
-
-	return tasks, rows.Err()
+	return r.scanTasks(rows)
 }
 
-// ListPending 列出待处理任务（可被调度）
-func (r *TaskRepository) ListPending(limit int) ([]*model.Task, error) {
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks WHERE status = ? ORDER BY priority DESC, created_at ASC LIMIT ?`
-
-	rows, err := r.db.DB().Query(query, model.TaskStatusPending, limit)
+// listPendingQuery 按 idx_tasks_status_priority_created_at 的顺序扫描 PENDING 任务，
+// 逐行检查 ready_tasks，取满 LIMIT 即停止，不需要排序全部 PENDING 任务
+const listPendingQuery = `SELECT ` + taskColumns + ` FROM tasks WHERE status = ?
+	AND (next_run_at IS NULL OR next_run_at <= ?)
+	AND EXISTS (SELECT 1 FROM ready_tasks r WHERE r.id = tasks.id)
+	ORDER BY priority DESC, created_at ASC LIMIT ?`
+
+// ListPending 列出待处理任务（可被调度），跳过重试退避在 now 时尚未到期的任务，以及 ready_tasks 视图之外、
+// 上游依赖尚未结束（或未成功且按 wait 处理）的任务
+func (r *TaskRepository) ListPending(limit int, now time.Time) ([]*model.Task, error) {
+	defer r.db.observe("tasks.ListPending", time.Now(), "limit", limit)
+	rows, err := r.db.DB().Query(listPendingQuery, model.TaskStatusPending, now.UTC().Format(utcMillisLayout), limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return r.scanTasks(rows)
+}
 
-	var tasks []*model.Task
-	for rows.Next() {
-		task, err := r.scanTask(rows)
+// ListDependents 列出直接依赖 taskIDs 中任一任务的任务，statusFilter 不为空时只返回该状态的任务，按创建时间排序
+func (r *TaskRepository) ListDependents(taskIDs []string, statusFilter *model.TaskStatus) ([]*model.Task, error) {
+	defer r.db.observe("tasks.ListDependents", time.Now(), "ids", len(taskIDs), "status", statusFilter)
+	found := make(map[string]*model.Task)
+
+	// 与 GetByIDs 相同，分批查询
+	const batchSize = 500
+	for start := 0; start < len(taskIDs); start += batchSize {
+		chunk := taskIDs[start:min(start+batchSize, len(taskIDs))]
+		query := `SELECT ` + taskColumns + ` FROM tasks
+	WHERE EXISTS (SELECT 1 FROM json_each(tasks.dependencies) d WHERE d.value IN (` + inPlaceholders(len(chunk)) + `))`
+		args := make([]interface{}, 0, len(chunk)+1)
+		for _, id := range chunk {
+			args = append(args, id)
+		}
+		if statusFilter != nil {
+			query += " AND status = ?"
+			args = append(args, *statusFilter)
+		}
+
+		rows, err := r.db.DB().Query(query, args...)
 		if err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, task)
+		tasks, err := r.scanTasks(rows)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			found[task.ID] = task
+		}
 	}
 
-	return tasks, rows.Err()
+	result := make([]*model.Task, 0, len(found))
+	for _, task := range found {
+		result = append(result, task)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
 }
 
 // Count 统计任务数量
 func (r *TaskRepository) Count(statusFilter *model.TaskStatus) (int, error) {
+	defer r.db.observe("tasks.Count", time.Now(), "status", statusFilter)
 	query := "SELECT COUNT(*) FROM tasks"
 	var args []interface{}
 	if statusFilter != nil {
@@ -231,11 +455,105 @@ func (r *TaskRepository) Count(statusFilter *model.TaskStatus) (int, error) {
 	return count, err
 }
 
-// AddEvent 添加任务事件
+// RecentDurations 返回指定类型最近 limit 个成功任务的执行耗时（完成时间减开始时间），按完成时间倒序
+func (r *TaskRepository) RecentDurations(taskType string, limit int) ([]time.Duration, error) {
+	defer r.db.observe("tasks.RecentDurations", time.Now(), "task_type", taskType, "limit", limit)
+	query := `SELECT started_at, completed_at FROM tasks
+	WHERE task_type = ? AND status = ? AND started_at IS NOT NULL AND completed_at IS NOT NULL
+	ORDER BY completed_at DESC LIMIT ?`
+
+	rows, err := r.db.DB().Query(query, taskType, model.TaskStatusSucceeded, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var durations []time.Duration
+	for rows.Next() {
+		var startedAt, completedAt string
+		if err := rows.Scan(&startedAt, &completedAt); err != nil {
+			return nil, err
+		}
+		start, err1 := parseTime(startedAt)
+		end, err2 := parseTime(completedAt)
+		if err1 != nil || err2 != nil || start == nil || end == nil || end.Before(*start) {
+			continue
+		}
+		durations = append(durations, end.Sub(*start))
+	}
+	return durations, rows.Err()
+}
+
+// OutcomeCount 某一任务类型在时间窗口内结束的任务数：成功计入 Succeeded，失败和超时计入 Failed
+type OutcomeCount struct {
+	Succeeded int
+	Failed    int
+}
+
+// Total 有结果的任务数
+func (c OutcomeCount) Total() int {
+	return c.Succeeded + c.Failed
+}
+
+// CountOutcomesByType 按任务类型统计完成时间在 [from, to) 内的成功与失败任务数，取消和跳过的任务不计入
+func (r *TaskRepository) CountOutcomesByType(from, to time.Time) (map[string]OutcomeCount, error) {
+	defer r.db.observe("tasks.CountOutcomesByType", time.Now(), "from", from, "to", to)
+	query := `SELECT task_type, status, COUNT(*) FROM tasks
+	WHERE status IN (?, ?, ?) AND completed_at >= ? AND completed_at < ?
+	GROUP BY task_type, status`
+
+	rows, err := r.db.DB().Query(query, model.TaskStatusSucceeded, model.TaskStatusFailed, model.TaskStatusTimeout,
+		formatTime(from), formatTime(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]OutcomeCount)
+	for rows.Next() {
+		var taskType string
+		var status model.TaskStatus
+		var n int
+		if err := rows.Scan(&taskType, &status, &n); err != nil {
+			return nil, err
+		}
+		c := counts[taskType]
+		if status == model.TaskStatusSucceeded {
+			c.Succeeded += n
+		} else {
+			c.Failed += n
+		}
+		counts[taskType] = c
+	}
+	return counts, rows.Err()
+}
+
+// ListTaskTypes 列出已有任务使用过的任务类型（不含空类型），按名称排序
+func (r *TaskRepository) ListTaskTypes() ([]string, error) {
+	defer r.db.observe("tasks.ListTaskTypes", time.Now())
+	rows, err := r.db.DB().Query(`SELECT DISTINCT task_type FROM tasks WHERE task_type != '' ORDER BY task_type`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+// AddEvent 添加任务事件，未指定请求 ID 时沿用任务的
 func (r *TaskRepository) AddEvent(event *model.TaskEvent) error {
+	defer r.db.observe("tasks.AddEvent", time.Now(), "task_id", event.TaskID)
 	query := `INSERT INTO task_events (
-		id, task_id, from_status, to_status, message, timestamp, operator
-	) VALUES (?, ?, ?, ?, ?, ?, ?)`
+		id, task_id, from_status, to_status, message, timestamp, operator, instance_id, correlation_id, metadata
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, (SELECT correlation_id FROM tasks WHERE id = ?)), ?)`
 
 	_, err := r.db.DB().Exec(query,
 		event.ID,
@@ -243,8 +561,12 @@ func (r *TaskRepository) AddEvent(event *model.TaskEvent) error {
 		event.FromStatus,
 		event.ToStatus,
 		event.Message,
-		event.Timestamp.Format(time.RFC3339),
+		formatTime(event.Timestamp),
 		event.Operator,
+		nullableString(event.InstanceID),
+		nullableString(event.CorrelationID),
+		event.TaskID,
+		nullableMetadata(event.Metadata),
 	)
 
 	return err
@@ -252,20 +574,48 @@ func (r *TaskRepository) AddEvent(event *model.TaskEvent) error {
 
 // GetEventsByTaskID 获取任务的所有事件
 func (r *TaskRepository) GetEventsByTaskID(taskID string) ([]model.TaskEvent, error) {
-	query := `SELECT id, task_id, from_status, to_status, message, timestamp, operator
-	FROM task_events WHERE task_id = ? ORDER BY timestamp ASC`
+	defer r.db.observe("tasks.GetEventsByTaskID", time.Now(), "task_id", taskID)
+	query := `SELECT ` + eventColumns + ` FROM task_events WHERE task_id = ? ORDER BY timestamp ASC, seq ASC`
 
 	rows, err := r.db.DB().Query(query, taskID)
 	if err != nil {
 		return nil, err
 	}
+	return scanEvents(rows)
+}
+
+// ListEventsAfter 按序号升序列出所有任务中序号大于 afterSeq 的事件
+func (r *TaskRepository) ListEventsAfter(afterSeq int64, limit int) ([]model.TaskEvent, error) {
+	defer r.db.observe("tasks.ListEventsAfter", time.Now(), "after_seq", afterSeq, "limit", limit)
+	rows, err := r.db.DB().Query(`SELECT `+eventColumns+` FROM task_events WHERE seq > ? ORDER BY seq ASC LIMIT ?`, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// LatestEventSeq 返回已分配的最大事件序号，没有事件时为 0
+func (r *TaskRepository) LatestEventSeq() (int64, error) {
+	defer r.db.observe("tasks.LatestEventSeq", time.Now())
+	var seq int64
+	err := r.db.DB().QueryRow(`SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'task_events'), 0)`).Scan(&seq)
+	return seq, err
+}
+
+// eventColumns 任务事件查询列（顺序需与 scanEvents 保持一致）
+const eventColumns = `seq, id, task_id, from_status, to_status, message, timestamp, operator, instance_id, correlation_id, metadata`
+
+// scanEvents 扫描任务事件并关闭 rows
+func scanEvents(rows *sql.Rows) ([]model.TaskEvent, error) {
 	defer rows.Close()
 
 	var events []model.TaskEvent
 	for rows.Next() {
 		var event model.TaskEvent
 		var timestamp string
+		var instanceID, correlationID, metadata sql.NullString
 		err := rows.Scan(
+			&event.Seq,
 			&event.ID,
 			&event.TaskID,
 			&event.FromStatus,
@@ -273,21 +623,46 @@ func (r *TaskRepository) GetEventsByTaskID(taskID string) ([]model.TaskEvent, er
 			&event.Message,
 			&timestamp,
 			&event.Operator,
+			&instanceID,
+			&correlationID,
+			&metadata,
 		)
 		if err != nil {
 			return nil, err
 		}
-		event.Timestamp, _ = time.Parse(time.RFC3339, timestamp)
+		event.Timestamp = parseTimestamp(timestamp)
+		event.InstanceID = instanceID.String
+		event.CorrelationID = correlationID.String
+		if metadata.Valid {
+			json.Unmarshal([]byte(metadata.String), &event.Metadata)
+		}
 		events = append(events, event)
 	}
 
 	return events, rows.Err()
 }
 
-// UpdateStatus 原子更新任务状态
+// ListAfterID 按 ID 升序列出 ID 大于 afterID 的任务（不含事件），用于分页遍历全部任务
+func (r *TaskRepository) ListAfterID(afterID string, limit int) ([]*model.Task, error) {
+	defer r.db.observe("tasks.ListAfterID", time.Now(), "after_id", afterID, "limit", limit)
+	rows, err := r.db.DB().Query(`SELECT `+taskColumns+` FROM tasks WHERE id > ? ORDER BY id ASC LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanTasks(rows)
+}
+
+// UpdateStatus 原子更新任务状态，进入终态时记录完成时间
 func (r *TaskRepository) UpdateStatus(id string, fromStatus, toStatus model.TaskStatus) error {
-	query := `UPDATE tasks SET status = ?, updated_at = ? WHERE id = ? AND status = ?`
-	result, err := r.db.DB().Exec(query, toStatus, time.Now().Format(time.RFC3339), id, fromStatus)
+	defer r.db.observe("tasks.UpdateStatus", time.Now(), "id", id, "from", fromStatus, "to", toStatus)
+	now := formatTime(time.Now())
+	set := `status = ?, updated_at = ?, version = version + 1`
+	args := []interface{}{toStatus, now}
+	if toStatus.IsTerminal() {
+		set += `, completed_at = ?`
+		args = append(args, now)
+	}
+	result, err := r.db.DB().Exec(`UPDATE tasks SET `+set+` WHERE id = ? AND status = ?`, append(args, id, fromStatus)...)
 	if err != nil {
 		return err
 	}
@@ -297,7 +672,7 @@ func (r *TaskRepository) UpdateStatus(id string, fromStatus, toStatus model.Task
 		return err
 	}
 	if rows == 0 {
-		return errors.New("task not found or status mismatch")
+		return ErrStatusMismatch
 	}
 
 	return nil
@@ -305,10 +680,43 @@ func (r *TaskRepository) UpdateStatus(id string, fromStatus, toStatus model.Task
 
 // UpdateStatusWithEvent 原子更新任务状态并记录事件
 func (r *TaskRepository) UpdateStatusWithEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message string) error {
+	return r.UpdateStatusWithInstanceEvent(taskID, fromStatus, toStatus, operator, message, "", nil)
+}
+
+// UpdateStatusWithCorrelatedEvent 原子更新任务状态并记录事件，事件记录引起变更的请求 ID
+func (r *TaskRepository) UpdateStatusWithCorrelatedEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, correlationID string) error {
+	return r.updateStatus(taskID, fromStatus, toStatus, operator, message, "", correlationID, nil)
+}
+
+// UpdateStatusWithInstanceEvent 原子更新任务状态并记录带调度器实例 ID 和元数据的事件，
+// 转为 RUNNING 时同时记录执行实例并清空上次执行的进度和心跳，离开 PENDING 时清除阻塞原因
+func (r *TaskRepository) UpdateStatusWithInstanceEvent(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID string, meta map[string]string) error {
+	return r.updateStatus(taskID, fromStatus, toStatus, operator, message, instanceID, "", meta)
+}
+
+// updateStatus 条件更新任务状态并记录事件，correlationID 为空时事件沿用任务的请求 ID
+func (r *TaskRepository) updateStatus(taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID, correlationID string, meta map[string]string) error {
+	defer r.db.observe("tasks.UpdateStatusWithInstanceEvent", time.Now(), "task_id", taskID, "from", fromStatus, "to", toStatus)
 	return r.db.ExecTx(func(tx *sql.Tx) error {
 		// 更新状态
-		query := `UPDATE tasks SET status = ?, updated_at = ? WHERE id = ? AND status = ?`
-		result, err := tx.Exec(query, toStatus, time.Now().Format(time.RFC3339), taskID, fromStatus)
+		now := formatTime(time.Now())
+		set := `status = ?, updated_at = ?, version = version + 1`
+		args := []interface{}{toStatus, now}
+		if toStatus == model.TaskStatusRunning && instanceID != "" {
+			set += `, executed_by = ?`
+			args = append(args, instanceID)
+		}
+		if toStatus == model.TaskStatusRunning {
+			set += `, ` + resetProgress
+		}
+		if fromStatus == model.TaskStatusPending {
+			set += `, blocked_reason = NULL`
+		}
+		if toStatus.IsTerminal() {
+			set += `, completed_at = ?`
+			args = append(args, now)
+		}
+		result, err := tx.Exec(`UPDATE tasks SET `+set+` WHERE id = ? AND status = ?`, append(args, taskID, fromStatus)...)
 		if err != nil {
 			return err
 		}
@@ -318,27 +726,265 @@ func (r *TaskRepository) UpdateStatusWithEvent(taskID string, fromStatus, toStat
 			return err
 		}
 		if rows == 0 {
-			return errors.New("task not found or status mismatch")
+			return ErrStatusMismatch
 		}
 
-		// 添加事件
-		eventID := fmt.Sprintf("%s_%d", taskID, time.Now().UnixNano())
-		eventQuery := `INSERT INTO task_events (id, task_id, from_status, to_status, message, timestamp, operator)
-			VALUES (?, ?, ?, ?, ?, ?, ?)`
-		_, err = tx.Exec(eventQuery, eventID, taskID, fromStatus, toStatus, message, time.Now().Format(time.RFC3339), operator)
+		return insertTaskEvent(tx, model.OutboxEventTaskStatusChanged, taskID, fromStatus, toStatus, operator, message, instanceID, correlationID, now, meta)
+	})
+}
 
-		return err
+// ScheduleRetry 把失败的任务从 fromStatus（自动重试为 RUNNING，手动重试为 FAILED）重置为 PENDING，
+// 重试次数由调用方读到的 retryCount 加一，是唯一累加重试次数的地方。同一事务中写入最近错误及其分类、
+// 最早可调度时间（nextRunAt 为空表示立即可调度）和状态事件；状态或重试次数已变化时返回 ErrStatusMismatch
+func (r *TaskRepository) ScheduleRetry(taskID string, fromStatus model.TaskStatus, retryCount int32, nextRunAt *time.Time, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error {
+	defer r.db.observe("tasks.ScheduleRetry", time.Now(), "task_id", taskID, "retry_count", retryCount)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		now := formatTime(time.Now())
+		result, err := tx.Exec(`UPDATE tasks SET status = ?, updated_at = ?, version = version + 1, completed_at = NULL, retry_count = retry_count + 1,
+			error_message = ?, error_class = ?, next_run_at = ?
+			WHERE id = ? AND status = ? AND retry_count = ?`,
+			model.TaskStatusPending, now, errMsg, nullableString(string(errClass)), nullableUTCTime(nextRunAt), taskID, fromStatus, retryCount)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		return insertStatusEvent(tx, taskID, fromStatus, model.TaskStatusPending, operator, message, instanceID, now, meta)
+	})
+}
+
+// FailTask 把执行失败的任务从 RUNNING 标记为 FAILED，同一事务中写入错误、错误分类和状态事件
+func (r *TaskRepository) FailTask(taskID, errMsg string, errClass model.ErrorClass, operator, message, instanceID string, meta map[string]string) error {
+	defer r.db.observe("tasks.FailTask", time.Now(), "task_id", taskID)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		now := formatTime(time.Now())
+		result, err := tx.Exec(`UPDATE tasks SET status = ?, updated_at = ?, version = version + 1, completed_at = ?, error_message = ?, error_class = ?, next_run_at = NULL
+			WHERE id = ? AND status = ?`,
+			model.TaskStatusFailed, now, now, errMsg, nullableString(string(errClass)), taskID, model.TaskStatusRunning)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		return insertStatusEvent(tx, taskID, model.TaskStatusRunning, model.TaskStatusFailed, operator, message, instanceID, now, meta)
+	})
+}
+
+// CompleteTask 把任务从 fromStatus 标记为 SUCCEEDED，同一事务中写入输出（或产物存储中的 outputRef）、
+// 清除上次失败的错误并记录状态事件；任务已被取消等状态变化时返回 ErrStatusMismatch，输出不会写入
+func (r *TaskRepository) CompleteTask(taskID string, fromStatus model.TaskStatus, output map[string]string, outputRef, operator, message, instanceID string, meta map[string]string) error {
+	defer r.db.observe("tasks.CompleteTask", time.Now(), "task_id", taskID)
+	outputJSON, _ := json.Marshal(output)
+	outputResult, err := r.db.fields.encrypt(taskID, "output_result", string(outputJSON))
+	if err != nil {
+		return fmt.Errorf("encrypt output_result: %w", err)
+	}
+
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		now := formatTime(time.Now())
+		result, err := tx.Exec(`UPDATE tasks SET status = ?, updated_at = ?, version = version + 1, completed_at = ?, output_result = ?, output_ref = ?,
+			error_message = '', error_class = NULL, next_run_at = NULL
+			WHERE id = ? AND status = ?`,
+			model.TaskStatusSucceeded, now, now, outputResult, nullableString(outputRef), taskID, fromStatus)
+		if err != nil {
+			return err
+		}
+		if err := checkRowsAffected(result); err != nil {
+			return err
+		}
+
+		return insertStatusEvent(tx, taskID, fromStatus, model.TaskStatusSucceeded, operator, message, instanceID, now, meta)
 	})
 }
 
+// ClaimExclusive 认领任务（PENDING -> RUNNING）并记录事件。有满足互斥条件的其他 RUNNING 任务时不认领，
+// 返回包装了 ErrExclusionBusy 的错误；检查与认领在同一条语句中完成，多个调度实例并发认领也只有一个成功
+func (r *TaskRepository) ClaimExclusive(taskID string, excl Exclusion, operator, message, instanceID string, meta map[string]string) error {
+	defer r.db.observe("tasks.ClaimExclusive", time.Now(), "task_id", taskID, "task_types", excl.TaskTypes, "group_key", excl.GroupKey)
+	conflict, conflictArgs := excl.condition()
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		now := formatTime(time.Now())
+		args := []interface{}{model.TaskStatusRunning, now, nullableString(instanceID), taskID, model.TaskStatusPending, model.TaskStatusRunning, taskID}
+		result, err := tx.Exec(`UPDATE tasks SET status = ?, updated_at = ?, version = version + 1, executed_by = ?, blocked_reason = NULL, `+resetProgress+`
+			WHERE id = ? AND status = ?
+			AND NOT EXISTS (SELECT 1 FROM tasks WHERE status = ? AND id != ? AND (`+conflict+`))`,
+			append(args, conflictArgs...)...)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			if err := runningConflict(tx.QueryRow, taskID, excl); err != nil {
+				return err
+			}
+			return ErrStatusMismatch
+		}
+
+		return insertStatusEvent(tx, taskID, model.TaskStatusPending, model.TaskStatusRunning, operator, message, instanceID, now, meta)
+	})
+}
+
+// RunningConflict 检查是否有满足互斥条件的其他任务正在运行，有时返回与 ClaimExclusive 相同的包装了 ErrExclusionBusy 的错误
+func (r *TaskRepository) RunningConflict(taskID string, excl Exclusion) error {
+	defer r.db.observe("tasks.RunningConflict", time.Now(), "task_id", taskID, "task_types", excl.TaskTypes, "group_key", excl.GroupKey)
+	return runningConflict(r.db.DB().QueryRow, taskID, excl)
+}
+
+// runningConflict 查询一个与 taskID 互斥的 RUNNING 任务，没有时返回 nil
+func runningConflict(queryRow func(query string, args ...interface{}) *sql.Row, taskID string, excl Exclusion) error {
+	if excl.IsZero() {
+		return nil
+	}
+	conflict, conflictArgs := excl.condition()
+	var running model.Task
+	var groupKey sql.NullString
+	err := queryRow(`SELECT id, task_type, group_key FROM tasks WHERE status = ? AND id != ? AND (`+conflict+`) LIMIT 1`,
+		append([]interface{}{model.TaskStatusRunning, taskID}, conflictArgs...)...).Scan(&running.ID, &running.TaskType, &groupKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	running.GroupKey = groupKey.String
+	return exclusionBusyError(excl, &running)
+}
+
+// resetProgress 开始新一次执行时清空上次执行上报的进度、心跳和子状态，阶段历史保留
+const resetProgress = `progress = 0, progress_message = NULL, heartbeat_at = NULL, sub_status = NULL`
+
+// UpdateProgress 记录 RUNNING 任务的执行进度（0 到 100）和说明，同时作为一次心跳；任务已不是 RUNNING 时返回 ErrStatusMismatch
+func (r *TaskRepository) UpdateProgress(taskID string, progress int32, message string, at time.Time) error {
+	defer r.db.observe("tasks.UpdateProgress", time.Now(), "task_id", taskID, "progress", progress)
+	result, err := r.db.DB().Exec(`UPDATE tasks SET progress = ?, progress_message = ?, heartbeat_at = ?, version = version + 1 WHERE id = ? AND status = ?`,
+		progress, nullableString(message), formatTime(at), taskID, model.TaskStatusRunning)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+// Heartbeat 记录 RUNNING 任务的执行器心跳时间；任务已不是 RUNNING 时返回 ErrStatusMismatch
+func (r *TaskRepository) Heartbeat(taskID string, at time.Time) error {
+	defer r.db.observe("tasks.Heartbeat", time.Now(), "task_id", taskID)
+	result, err := r.db.DB().Exec(`UPDATE tasks SET heartbeat_at = ?, version = version + 1 WHERE id = ? AND status = ?`,
+		formatTime(at), taskID, model.TaskStatusRunning)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+// SetBlockedReason 记录 PENDING 任务暂不能调度的原因，reason 为空表示清除；任务已不是 PENDING 或原因未变化时忽略
+func (r *TaskRepository) SetBlockedReason(taskID, reason string) error {
+	defer r.db.observe("tasks.SetBlockedReason", time.Now(), "task_id", taskID)
+	_, err := r.db.DB().Exec(`UPDATE tasks SET blocked_reason = ?, version = version + 1 WHERE id = ? AND status = ? AND blocked_reason IS NOT ?`,
+		nullableString(reason), taskID, model.TaskStatusPending, nullableString(reason))
+	return err
+}
+
+// checkRowsAffected 条件更新未命中时返回状态不匹配错误
+func checkRowsAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrStatusMismatch
+	}
+	return nil
+}
+
+// insertStatusEvent 在事务中记录状态变更事件，并写入发件箱与状态变更同时提交或回滚
+func insertStatusEvent(tx *sql.Tx, taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID, now string, meta map[string]string) error {
+	return insertTaskEvent(tx, model.OutboxEventTaskStatusChanged, taskID, fromStatus, toStatus, operator, message, instanceID, "", now, meta)
+}
+
+// insertTaskEvent 写入任务事件和对应类型的发件箱事件，correlationID 为空时沿用任务的请求 ID
+func insertTaskEvent(tx *sql.Tx, eventType, taskID string, fromStatus, toStatus model.TaskStatus, operator, message, instanceID, correlationID, now string, meta map[string]string) error {
+	if correlationID == "" {
+		var taskCorrelationID sql.NullString
+		if err := tx.QueryRow(`SELECT correlation_id FROM tasks WHERE id = ?`, taskID).Scan(&taskCorrelationID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		correlationID = taskCorrelationID.String
+	}
+
+	eventID := idgen.NewID()
+	eventQuery := `INSERT INTO task_events (id, task_id, from_status, to_status, message, timestamp, operator, instance_id, correlation_id, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(eventQuery, eventID, taskID, fromStatus, toStatus, message, now, operator,
+		nullableString(instanceID), nullableString(correlationID), nullableMetadata(meta)); err != nil {
+		return err
+	}
+
+	return insertOutboxEvent(tx, &model.OutboxEvent{
+		ID:            eventID,
+		TaskID:        taskID,
+		EventType:     eventType,
+		FromStatus:    fromStatus,
+		ToStatus:      toStatus,
+		Message:       message,
+		Operator:      operator,
+		InstanceID:    instanceID,
+		CorrelationID: correlationID,
+	}, now)
+}
+
+// AddComment 添加任务评论
+func (r *TaskRepository) AddComment(comment *model.TaskComment) error {
+	defer r.db.observe("tasks.AddComment", time.Now(), "task_id", comment.TaskID)
+	query := `INSERT INTO task_comments (id, task_id, author, body, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := r.db.DB().Exec(query,
+		comment.ID,
+		comment.TaskID,
+		comment.Author,
+		comment.Body,
+		formatTime(comment.CreatedAt),
+	)
+	return err
+}
+
+// GetCommentsByTaskID 获取任务的所有评论（按时间顺序）
+func (r *TaskRepository) GetCommentsByTaskID(taskID string) ([]model.TaskComment, error) {
+	defer r.db.observe("tasks.GetCommentsByTaskID", time.Now(), "task_id", taskID)
+	query := `SELECT id, task_id, author, body, created_at
+	FROM task_comments WHERE task_id = ? ORDER BY created_at ASC, rowid ASC`
+
+	rows, err := r.db.DB().Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []model.TaskComment
+	for rows.Next() {
+		var comment model.TaskComment
+		var author sql.NullString
+		var createdAt string
+		if err := rows.Scan(&comment.ID, &comment.TaskID, &author, &comment.Body, &createdAt); err != nil {
+			return nil, err
+		}
+		comment.Author = author.String
+		comment.CreatedAt = parseTimestamp(createdAt)
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
 // Search 搜索任务
 func (r *TaskRepository) Search(keyword string, limit, offset int) ([]*model.Task, error) {
+	defer r.db.observe("tasks.Search", time.Now(), "keyword", keyword, "limit", limit, "offset", offset)
 	searchPattern := "%" + keyword + "%"
-	query := `SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks 
+	query := `SELECT ` + taskColumns + ` FROM tasks 
 	WHERE name LIKE ? OR description LIKE ? OR task_type LIKE ?
 	ORDER BY created_at DESC LIMIT ? OFFSET ?`
 
@@ -346,18 +992,23 @@ func (r *TaskRepository) Search(keyword string, limit, offset int) ([]*model.Tas
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return r.scanTasks(rows)
+}
 
-	var tasks []*model.Task
-	for rows.Next() {
-		task, err := r.scanTask(rows)
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
-	}
+// encodeSensitiveFields 编码 input_params 和 output_result，启用列加密时加密
+func (r *TaskRepository) encodeSensitiveFields(task *model.Task) (string, string, error) {
+	inputParams, _ := json.Marshal(task.InputParams)
+	outputResult, _ := json.Marshal(task.OutputResult)
 
-	return tasks, rows.Err()
+	input, err := r.db.fields.encrypt(task.ID, "input_params", string(inputParams))
+	if err != nil {
+		return "", "", fmt.Errorf("encrypt input_params: %w", err)
+	}
+	output, err := r.db.fields.encrypt(task.ID, "output_result", string(outputResult))
+	if err != nil {
+		return "", "", fmt.Errorf("encrypt output_result: %w", err)
+	}
+	return input, output, nil
 }
 
 // scanTask 扫描任务行
@@ -366,6 +1017,14 @@ func (r *TaskRepository) scanTask(row interface{ Scan(...interface{}) error }) (
 	var inputParams, outputResult, dependencies string
 	var createdAt, updatedAt string
 	var startedAt, completedAt sql.NullString
+	var teamID, executedBy, outputRef, retryPolicy, nextRunAt, errorClass, blockedReason, dependencyPolicies, groupKey sql.NullString
+	var slaDeadline, slaBreachedAt, correlationID, labels, rerunOf sql.NullString
+	var parentID, progressMessage, heartbeatAt, inputRequest, signalInput sql.NullString
+	var nodeSelector, targetWorker sql.NullString
+	var basePriority sql.NullInt32
+	var queuedAt, queueAlertedAt sql.NullString
+	var subStatus, phases sql.NullString
+	var workflowDeadline sql.NullString
 
 	err := row.Scan(
 		&task.ID,
@@ -385,13 +1044,57 @@ func (r *TaskRepository) scanTask(row interface{ Scan(...interface{}) error }) (
 		&startedAt,
 		&completedAt,
 		&task.CreatedBy,
+		&teamID,
+		&executedBy,
+		&outputRef,
+		&retryPolicy,
+		&nextRunAt,
+		&errorClass,
+		&blockedReason,
+		&dependencyPolicies,
+		&task.ResourceSlots,
+		&groupKey,
+		&slaDeadline,
+		&slaBreachedAt,
+		&correlationID,
+		&labels,
+		&rerunOf,
+		&parentID,
+		&task.Progress,
+		&progressMessage,
+		&heartbeatAt,
+		&task.Version,
+		&inputRequest,
+		&signalInput,
+		&nodeSelector,
+		&targetWorker,
+		&basePriority,
+		&queuedAt,
+		&queueAlertedAt,
+		&subStatus,
+		&phases,
+		&workflowDeadline,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	task.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-	task.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	task.TeamID = teamID.String
+	task.ExecutedBy = executedBy.String
+	task.OutputRef = outputRef.String
+	task.ErrorClass = model.ErrorClass(errorClass.String)
+	task.BlockedReason = blockedReason.String
+	task.GroupKey = groupKey.String
+	task.CorrelationID = correlationID.String
+	task.RerunOf = rerunOf.String
+	task.ParentID = parentID.String
+	task.ProgressMessage = progressMessage.String
+	task.InputRequest = inputRequest.String
+	task.TargetWorker = targetWorker.String
+	task.SubStatus = subStatus.String
+	task.BasePriority = model.TaskPriority(basePriority.Int32)
+	task.CreatedAt = parseTimestamp(createdAt)
+	task.UpdatedAt = parseTimestamp(updatedAt)
 
 	if startedAt.Valid {
 		task.StartedAt, _ = parseTime(startedAt.String)
@@ -399,7 +1102,57 @@ func (r *TaskRepository) scanTask(row interface{ Scan(...interface{}) error }) (
 	if completedAt.Valid {
 		task.CompletedAt, _ = parseTime(completedAt.String)
 	}
+	if nextRunAt.Valid {
+		task.NextRunAt, _ = parseTime(nextRunAt.String)
+	}
+	if slaDeadline.Valid {
+		task.SLADeadline, _ = parseTime(slaDeadline.String)
+	}
+	if slaBreachedAt.Valid {
+		task.SLABreachedAt, _ = parseTime(slaBreachedAt.String)
+	}
+	if queuedAt.Valid {
+		task.QueuedAt, _ = parseTime(queuedAt.String)
+	}
+	if queueAlertedAt.Valid {
+		task.QueueAlertedAt, _ = parseTime(queueAlertedAt.String)
+	}
+	if workflowDeadline.Valid {
+		task.WorkflowDeadline, _ = parseTime(workflowDeadline.String)
+	}
+	if heartbeatAt.Valid {
+		task.HeartbeatAt, _ = parseTime(heartbeatAt.String)
+	}
+	if retryPolicy.Valid {
+		task.RetryPolicy = &model.RetryPolicy{}
+		json.Unmarshal([]byte(retryPolicy.String), task.RetryPolicy)
+	}
+	if dependencyPolicies.Valid {
+		json.Unmarshal([]byte(dependencyPolicies.String), &task.DependencyPolicies)
+	}
+	if labels.Valid {
+		json.Unmarshal([]byte(labels.String), &task.Labels)
+	}
+	if nodeSelector.Valid {
+		json.Unmarshal([]byte(nodeSelector.String), &task.NodeSelector)
+	}
+	if phases.Valid {
+		json.Unmarshal([]byte(phases.String), &task.Phases)
+	}
 
+	if inputParams, err = r.db.fields.decrypt(task.ID, "input_params", inputParams); err != nil {
+		return nil, err
+	}
+	if outputResult, err = r.db.fields.decrypt(task.ID, "output_result", outputResult); err != nil {
+		return nil, err
+	}
+	if signalInput.Valid {
+		plaintext, err := r.db.fields.decrypt(task.ID, "signal_input", signalInput.String)
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(plaintext), &task.SignalInput)
+	}
 	json.Unmarshal([]byte(inputParams), &task.InputParams)
 	json.Unmarshal([]byte(outputResult), &task.OutputResult)
 	json.Unmarshal([]byte(dependencies), &task.Dependencies)
@@ -407,12 +1160,107 @@ func (r *TaskRepository) scanTask(row interface{ Scan(...interface{}) error }) (
 	return &task, nil
 }
 
+// scanTasks 扫描多行任务并关闭 rows
+func (r *TaskRepository) scanTasks(rows *sql.Rows) ([]*model.Task, error) {
+	defer rows.Close()
+
+	var tasks []*model.Task
+	for rows.Next() {
+		task, err := r.scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// nullableString 空字符串存为 NULL
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// inPlaceholders IN 列表的参数占位符，如 "?,?,?"
+func inPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
 // nullableTime 处理可空时间
 func nullableTime(t *time.Time) interface{} {
 	if t == nil {
 		return nil
 	}
-	return t.Format(time.RFC3339)
+	return formatTime(*t)
+}
+
+// formatTime 时间统一转换为 UTC 后按 RFC3339 存储，不同时区的实例写入的时间可以直接按字符串比较
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseTimestamp 解析存储的 RFC3339 时间并转换为 UTC，无法解析时返回零值
+func parseTimestamp(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t.UTC()
+}
+
+// utcMillisLayout 定宽的 UTC 毫秒时间格式，字符串比较与时间顺序一致
+const utcMillisLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// nullableUTCTime 可空时间按 utcMillisLayout 存储
+func nullableUTCTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(utcMillisLayout)
+}
+
+// nullableRetryPolicy 重试策略序列化为 JSON，未设置时存为 NULL
+func nullableRetryPolicy(p *model.RetryPolicy) interface{} {
+	if p == nil {
+		return nil
+	}
+	data, _ := json.Marshal(p)
+	return string(data)
+}
+
+// nullableDependencyPolicies 依赖失败处理方式序列化为 JSON，未配置时存为 NULL
+func nullableDependencyPolicies(p map[string]model.DependencyFailurePolicy) interface{} {
+	if len(p) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(p)
+	return string(data)
+}
+
+// nullablePriority 未设置的优先级存 NULL
+func nullablePriority(p model.TaskPriority) interface{} {
+	if p == model.TaskPriorityUnspecified {
+		return nil
+	}
+	return int32(p)
+}
+
+// nullableLabels 标签编码为 JSON，没有标签时存 NULL
+func nullableLabels(labels map[string]string) interface{} {
+	if len(labels) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(labels)
+	return string(data)
+}
+
+// nullableMetadata 事件元数据编码为 JSON，没有元数据时存 NULL
+func nullableMetadata(meta map[string]string) interface{} {
+	if len(meta) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(meta)
+	return string(data)
 }
 
 // parseTime 解析时间
@@ -424,22 +1272,141 @@ func parseTime(s string) (*time.Time, error) {
 	if err != nil {
 		return nil, err
 	}
+	t = t.UTC()
 	return &t, nil
 }
 
 // BuildTaskFilter 构建任务过滤条件
 type TaskFilter struct {
-	Status    *model.TaskStatus
-	Priority  *model.TaskPriority
-	TaskType  string
-	CreatedBy string
-	Keyword   string
-	PageSize  int
-	PageIndex int
+	Status            *model.TaskStatus
+	Priority          *model.TaskPriority
+	TaskType          string
+	Statuses          []model.TaskStatus   // 状态为其中之一，与 Status 同时设置时需同时满足
+	Priorities        []model.TaskPriority // 优先级为其中之一
+	TaskTypes         []string             // 任务类型为其中之一
+	CreatedBy         string
+	TeamID            string // 按归属团队过滤
+	MemberOf          string // 仅返回该用户所在团队的任务（"我的团队任务"）
+	VisibleTo         string // 仅返回该用户可见的任务：本人创建或所在团队的任务
+	IncludeUnassigned bool   // 与 VisibleTo 同时使用：未归属团队的任务也可见
+	CorrelationID     string // 按创建任务的请求 ID 过滤
+	Keyword           string
+	CreatedAfter      time.Time     // 创建时间不早于该时间，零值表示不限
+	CreatedBefore     time.Time     // 创建时间早于该时间
+	CompletedAfter    time.Time     // 完成时间不早于该时间，未完成的任务不返回
+	CompletedBefore   time.Time     // 完成时间早于该时间，未完成的任务不返回
+	UpdatedSince      time.Time     // 更新时间不早于该时间，用于增量同步
+	SortBy            TaskSortField // 排序字段，为空时按优先级降序、创建时间降序
+	SortDesc          bool          // 与 SortBy 同时使用：降序排列
+	PageSize          int
+	PageIndex         int
+}
+
+// TaskSortField 任务列表排序字段
+type TaskSortField string
+
+// 支持的排序字段，均有对应的索引
+const (
+	SortByCreatedAt   TaskSortField = "created_at"
+	SortByUpdatedAt   TaskSortField = "updated_at"
+	SortByPriority    TaskSortField = "priority"
+	SortByStatus      TaskSortField = "status"
+	SortByCompletedAt TaskSortField = "completed_at"
+)
+
+// TaskSortFields 排序字段白名单
+var TaskSortFields = []TaskSortField{SortByCreatedAt, SortByUpdatedAt, SortByPriority, SortByStatus, SortByCompletedAt}
+
+// ErrInvalidSortField 排序字段不在白名单内
+var ErrInvalidSortField = errors.New("invalid sort field")
+
+// Valid 是否为白名单内的排序字段，空值表示默认排序
+func (f TaskSortField) Valid() bool {
+	if f == "" {
+		return true
+	}
+	for _, field := range TaskSortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// orderBy 构建 ORDER BY 子句。排序字段只能来自白名单；没有完成时间的任务总是排在最后，
+// 取值相同的任务按 ID 升序，保证分页结果稳定（与内存实现一致）
+func (f TaskFilter) orderBy() (string, error) {
+	if !f.SortBy.Valid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSortField, f.SortBy)
+	}
+	if f.SortBy == "" {
+		return "ORDER BY priority DESC, created_at DESC, id ASC", nil
+	}
+	dir := "ASC"
+	if f.SortDesc {
+		dir = "DESC"
+	}
+	if f.SortBy == SortByCompletedAt {
+		return fmt.Sprintf("ORDER BY completed_at IS NULL, completed_at %s, id ASC", dir), nil
+	}
+	return fmt.Sprintf("ORDER BY %s %s, id ASC", f.SortBy, dir), nil
+}
+
+// String 列出已设置的过滤条件，用于慢查询日志
+func (f TaskFilter) String() string {
+	var parts []string
+	if f.Status != nil {
+		parts = append(parts, "status="+f.Status.String())
+	}
+	if f.Priority != nil {
+		parts = append(parts, "priority="+f.Priority.String())
+	}
+	if len(f.Statuses) > 0 {
+		parts = append(parts, fmt.Sprintf("statuses=%v", f.Statuses))
+	}
+	if len(f.Priorities) > 0 {
+		parts = append(parts, fmt.Sprintf("priorities=%v", f.Priorities))
+	}
+	if len(f.TaskTypes) > 0 {
+		parts = append(parts, "task_types="+strings.Join(f.TaskTypes, ","))
+	}
+	for _, kv := range [][2]string{
+		{"task_type", f.TaskType}, {"created_by", f.CreatedBy}, {"team_id", f.TeamID},
+		{"member_of", f.MemberOf}, {"visible_to", f.VisibleTo}, {"correlation_id", f.CorrelationID}, {"keyword", f.Keyword},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+kv[1])
+		}
+	}
+	if f.VisibleTo != "" && f.IncludeUnassigned {
+		parts = append(parts, "include_unassigned=true")
+	}
+	for _, kv := range []struct {
+		name string
+		t    time.Time
+	}{
+		{"created_after", f.CreatedAfter}, {"created_before", f.CreatedBefore},
+		{"completed_after", f.CompletedAfter}, {"completed_before", f.CompletedBefore}, {"updated_since", f.UpdatedSince},
+	} {
+		if !kv.t.IsZero() {
+			parts = append(parts, kv.name+"="+formatTime(kv.t))
+		}
+	}
+	if f.SortBy != "" {
+		parts = append(parts, fmt.Sprintf("sort_by=%s sort_desc=%t", f.SortBy, f.SortDesc))
+	}
+	parts = append(parts, fmt.Sprintf("page_size=%d page_index=%d", f.PageSize, f.PageIndex))
+	return "{" + strings.Join(parts, " ") + "}"
 }
 
 // ListByFilter 按条件过滤任务
 func (r *TaskRepository) ListByFilter(filter TaskFilter) ([]*model.Task, int, error) {
+	defer r.db.observe("tasks.ListByFilter", time.Now(), "filter", filter)
+	orderBy, err := filter.orderBy()
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// 构建 WHERE 子句
 	conditions := []string{}
 	var args []interface{}
@@ -456,15 +1423,68 @@ func (r *TaskRepository) ListByFilter(filter TaskFilter) ([]*model.Task, int, er
 		conditions = append(conditions, "task_type = ?")
 		args = append(args, filter.TaskType)
 	}
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status IN ("+inPlaceholders(len(filter.Statuses))+")")
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if len(filter.Priorities) > 0 {
+		conditions = append(conditions, "priority IN ("+inPlaceholders(len(filter.Priorities))+")")
+		for _, priority := range filter.Priorities {
+			args = append(args, priority)
+		}
+	}
+	if len(filter.TaskTypes) > 0 {
+		conditions = append(conditions, "task_type IN ("+inPlaceholders(len(filter.TaskTypes))+")")
+		for _, taskType := range filter.TaskTypes {
+			args = append(args, taskType)
+		}
+	}
 	if filter.CreatedBy != "" {
 		conditions = append(conditions, "created_by = ?")
 		args = append(args, filter.CreatedBy)
 	}
+	if filter.TeamID != "" {
+		conditions = append(conditions, "team_id = ?")
+		args = append(args, filter.TeamID)
+	}
+	if filter.MemberOf != "" {
+		conditions = append(conditions, "team_id IN (SELECT team_id FROM team_members WHERE user_id = ?)")
+		args = append(args, filter.MemberOf)
+	}
+	if filter.VisibleTo != "" {
+		visible := "created_by = ? OR team_id IN (SELECT team_id FROM team_members WHERE user_id = ?)"
+		if filter.IncludeUnassigned {
+			visible += " OR COALESCE(team_id, '') = ''"
+		}
+		conditions = append(conditions, "("+visible+")")
+		args = append(args, filter.VisibleTo, filter.VisibleTo)
+	}
+	if filter.CorrelationID != "" {
+		conditions = append(conditions, "correlation_id = ?")
+		args = append(args, filter.CorrelationID)
+	}
 	if filter.Keyword != "" {
 		searchPattern := "%" + filter.Keyword + "%"
 		conditions = append(conditions, "(name LIKE ? OR description LIKE ?)")
 		args = append(args, searchPattern, searchPattern)
 	}
+	for _, r := range []struct {
+		cond string
+		t    time.Time
+	}{
+		{"created_at >= ?", filter.CreatedAfter},
+		{"created_at < ?", filter.CreatedBefore},
+		{"completed_at >= ?", filter.CompletedAfter},
+		{"completed_at < ?", filter.CompletedBefore},
+		{"updated_at >= ?", filter.UpdatedSince},
+	} {
+		if !r.t.IsZero() {
+			conditions = append(conditions, r.cond)
+			args = append(args, formatTime(r.t))
+		}
+	}
 
 	// 构建查询
 	whereClause := ""
@@ -489,11 +1509,7 @@ func (r *TaskRepository) ListByFilter(filter TaskFilter) ([]*model.Task, int, er
 	offset := filter.PageIndex * filter.PageSize
 
 	// 查询列表
-	listQuery := fmt.Sprintf(`SELECT id, name, description, status, priority, task_type,
-		input_params, output_result, dependencies, retry_count,
-		max_retries, error_message, created_at, updated_at,
-		started_at, completed_at, created_by
-	FROM tasks %s ORDER BY priority DESC, created_at DESC LIMIT ? OFFSET ?`, whereClause)
+	listQuery := fmt.Sprintf(`SELECT ` + taskColumns + ` FROM tasks %s %s LIMIT ? OFFSET ?`, whereClause, orderBy)
 
 	args = append(args, filter.PageSize, offset)
 
@@ -501,16 +1517,10 @@ func (r *TaskRepository) ListByFilter(filter TaskFilter) ([]*model.Task, int, er
 	if err != nil {
 		return nil, 0, err
 	}
-	defer rows.Close()
-
-	var tasks []*model.Task
-	for rows.Next() {
-		task, err := r.scanTask(rows)
-		if err != nil {
-			return nil, 0, err
-		}
-		tasks = append(tasks, task)
+	tasks, err := r.scanTasks(rows)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return tasks, total, rows.Err()
+	return tasks, total, nil
 }
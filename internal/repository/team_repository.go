@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// TeamRepository 团队仓储
+type TeamRepository struct {
+	db *SQLite
+}
+
+// NewTeamRepository 创建团队仓储
+func NewTeamRepository(db *SQLite) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+// Create 创建团队（连同初始成员）
+func (r *TeamRepository) Create(team *model.Team) error {
+	defer r.db.observe("teams.Create", time.Now(), "id", team.ID)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO teams (id, name, created_by, created_at) VALUES (?, ?, ?, ?)`,
+			team.ID, team.Name, team.CreatedBy, formatTime(team.CreatedAt))
+		if err != nil {
+			return err
+		}
+
+		for _, userID := range team.Members {
+			if err := addMember(tx, team.ID, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetByID 根据 ID 获取团队（含成员）
+func (r *TeamRepository) GetByID(id string) (*model.Team, error) {
+	defer r.db.observe("teams.GetByID", time.Now(), "id", id)
+	var team model.Team
+	var createdBy sql.NullString
+	var createdAt string
+
+	err := r.db.DB().QueryRow(`SELECT id, name, created_by, created_at FROM teams WHERE id = ?`, id).
+		Scan(&team.ID, &team.Name, &createdBy, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	team.CreatedBy = createdBy.String
+	team.CreatedAt = parseTimestamp(createdAt)
+
+	members, err := r.ListMembers(id)
+	if err != nil {
+		return nil, err
+	}
+	team.Members = members
+
+	return &team, nil
+}
+
+// Delete 删除团队及其成员关系
+func (r *TeamRepository) Delete(id string) error {
+	defer r.db.observe("teams.Delete", time.Now(), "id", id)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM team_members WHERE team_id = ?`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM teams WHERE id = ?`, id)
+		return err
+	})
+}
+
+// AddMember 添加团队成员（已存在时忽略）
+func (r *TeamRepository) AddMember(teamID, userID string) error {
+	defer r.db.observe("teams.AddMember", time.Now(), "team_id", teamID, "user_id", userID)
+	return r.db.ExecTx(func(tx *sql.Tx) error {
+		return addMember(tx, teamID, userID)
+	})
+}
+
+// RemoveMember 移除团队成员
+func (r *TeamRepository) RemoveMember(teamID, userID string) error {
+	defer r.db.observe("teams.RemoveMember", time.Now(), "team_id", teamID, "user_id", userID)
+	_, err := r.db.DB().Exec(`DELETE FROM team_members WHERE team_id = ? AND user_id = ?`, teamID, userID)
+	return err
+}
+
+// ListMembers 列出团队成员
+func (r *TeamRepository) ListMembers(teamID string) ([]string, error) {
+	defer r.db.observe("teams.ListMembers", time.Now(), "team_id", teamID)
+	rows, err := r.db.DB().Query(`SELECT user_id FROM team_members WHERE team_id = ? ORDER BY joined_at ASC`, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return scanStrings(rows)
+}
+
+// ListTeamIDsByUser 列出用户所属的团队 ID
+func (r *TeamRepository) ListTeamIDsByUser(userID string) ([]string, error) {
+	defer r.db.observe("teams.ListTeamIDsByUser", time.Now(), "user_id", userID)
+	rows, err := r.db.DB().Query(`SELECT team_id FROM team_members WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanStrings(rows)
+}
+
+// ListByUser 列出用户所属的团队
+func (r *TeamRepository) ListByUser(userID string) ([]*model.Team, error) {
+	defer r.db.observe("teams.ListByUser", time.Now(), "user_id", userID)
+	ids, err := r.ListTeamIDsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := make([]*model.Team, 0, len(ids))
+	for _, id := range ids {
+		team, err := r.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if team != nil {
+			teams = append(teams, team)
+		}
+	}
+	return teams, nil
+}
+
+// addMember 在事务中添加成员
+func addMember(tx *sql.Tx, teamID, userID string) error {
+	_, err := tx.Exec(`INSERT OR IGNORE INTO team_members (team_id, user_id, joined_at) VALUES (?, ?, ?)`,
+		teamID, userID, formatTime(time.Now()))
+	return err
+}
+
+// scanStrings 扫描单列字符串结果并关闭 rows
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+func TestTeamRepository_CreateAndMembers(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewTeamRepository(db)
+
+	team := &model.Team{
+		ID:        "team-1",
+		Name:      "platform",
+		Members:   []string{"alice", "bob"},
+		CreatedBy: "alice",
+		CreatedAt: time.Now(),
+	}
+	if err := repo.Create(team); err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+
+	got, err := repo.GetByID("team-1")
+	if err != nil {
+		t.Fatalf("failed to get team: %v", err)
+	}
+	if got == nil || len(got.Members) != 2 {
+		t.Fatalf("expected team with 2 members, got %+v", got)
+	}
+
+	// 重复添加应被忽略
+	if err := repo.AddMember("team-1", "bob"); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	if err := repo.AddMember("team-1", "carol"); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	members, _ := repo.ListMembers("team-1")
+	if len(members) != 3 {
+		t.Errorf("expected 3 members, got %d", len(members))
+	}
+
+	if err := repo.RemoveMember("team-1", "alice"); err != nil {
+		t.Fatalf("failed to remove member: %v", err)
+	}
+	teamIDs, _ := repo.ListTeamIDsByUser("alice")
+	if len(teamIDs) != 0 {
+		t.Errorf("expected alice to have no teams, got %v", teamIDs)
+	}
+
+	// 不存在的团队
+	notFound, err := repo.GetByID("non-existent")
+	if err != nil {
+		t.Fatalf("error should be nil for not found: %v", err)
+	}
+	if notFound != nil {
+		t.Error("team should be nil for non-existent ID")
+	}
+}
+
+func TestTaskRepository_ListByFilter_Team(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	taskRepo := NewTaskRepository(db)
+	teamRepo := NewTeamRepository(db)
+
+	if err := teamRepo.Create(&model.Team{ID: "team-a", Name: "a", Members: []string{"alice"}, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+	if err := teamRepo.Create(&model.Team{ID: "team-b", Name: "b", Members: []string{"bob"}, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+
+	tasks := []*model.Task{
+		model.NewTask("Task A1", "desc", model.TaskPriorityNormal, "test", nil, nil, 3, "bob"),
+		model.NewTask("Task A2", "desc", model.TaskPriorityNormal, "test", nil, nil, 3, "bob"),
+		model.NewTask("Task B1", "desc", model.TaskPriorityNormal, "test", nil, nil, 3, "bob"),
+		model.NewTask("No Team", "desc", model.TaskPriorityNormal, "test", nil, nil, 3, "bob"),
+	}
+	tasks[0].ID, tasks[0].TeamID = "team-task-1", "team-a"
+	tasks[1].ID, tasks[1].TeamID = "team-task-2", "team-a"
+	tasks[2].ID, tasks[2].TeamID = "team-task-3", "team-b"
+	tasks[3].ID = "team-task-4"
+	for _, task := range tasks {
+		if err := taskRepo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	// 按团队过滤
+	results, total, err := taskRepo.ListByFilter(TaskFilter{TeamID: "team-b", PageSize: 10})
+	if err != nil {
+		t.Fatalf("failed to filter: %v", err)
+	}
+	if total != 1 || results[0].TeamID != "team-b" {
+		t.Errorf("expected 1 team-b task, got %d", total)
+	}
+
+	// 我的团队任务
+	results, total, err = taskRepo.ListByFilter(TaskFilter{MemberOf: "alice", PageSize: 10})
+	if err != nil {
+		t.Fatalf("failed to filter: %v", err)
+	}
+	if total != 2 || len(results) != 2 {
+		t.Errorf("expected 2 tasks visible via alice's teams, got %d", total)
+	}
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// ListWorkflowDeadlineExceeded 列出所属工作流的截止时间不晚于 now、尚未结束的任务，按截止时间升序
+func (r *TaskRepository) ListWorkflowDeadlineExceeded(now time.Time, limit int) ([]*model.Task, error) {
+	defer r.db.observe("tasks.ListWorkflowDeadlineExceeded", time.Now(), "limit", limit)
+	query := `SELECT ` + taskColumns + ` FROM tasks
+	WHERE workflow_deadline IS NOT NULL AND workflow_deadline <= ? AND status IN (?, ?, ?)
+	ORDER BY workflow_deadline ASC LIMIT ?`
+
+	rows, err := r.db.DB().Query(query, now.UTC().Format(utcMillisLayout),
+		model.TaskStatusPending, model.TaskStatusRunning, model.TaskStatusWaitingInput, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanTasks(rows)
+}
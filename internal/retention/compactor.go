@@ -0,0 +1,74 @@
+// Package retention 任务事件保留：定期删除超过保留期的任务事件，
+// 每个任务最近的若干条事件无论新旧都保留，长期运行、多次重试的任务事件不会无限增长
+package retention
+
+import (
+	"context"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/logger"
+	"taskflow/internal/repository"
+)
+
+// Options 压缩参数
+type Options struct {
+	CheckInterval time.Duration // 压缩间隔，默认 1 小时
+	MaxAge        time.Duration // 事件保留时长，0 表示不压缩
+	KeepLatest    int           // 每个任务始终保留的最近事件数
+	Clock         clock.Clock   // 计算保留期截止时间和压缩间隔的时钟，默认 clock.Real
+}
+
+// Compactor 任务事件压缩器。删除是幂等的，多个实例同时运行不会多删
+type Compactor struct {
+	repo repository.TaskStore
+	opts Options
+}
+
+// NewCompactor 创建压缩器
+func NewCompactor(repo repository.TaskStore, opts Options) *Compactor {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = time.Hour
+	}
+	if opts.KeepLatest < 0 {
+		opts.KeepLatest = 0
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+	return &Compactor{repo: repo, opts: opts}
+}
+
+// Run 定期压缩，直到 ctx 取消
+func (c *Compactor) Run(ctx context.Context) {
+	ticker := c.opts.Clock.NewTicker(c.opts.CheckInterval)
+	defer ticker.Stop()
+
+	logger.Infof("Event compactor started, keeping %s and the latest %d event(s) per task", c.opts.MaxAge, c.opts.KeepLatest)
+	for {
+		c.Compact()
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("Event compactor stopped")
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Compact 压缩一轮，返回删除的事件数
+func (c *Compactor) Compact() int64 {
+	if c.opts.MaxAge <= 0 {
+		return 0
+	}
+	n, err := c.repo.CompactEvents(c.opts.Clock.Now().Add(-c.opts.MaxAge), c.opts.KeepLatest)
+	if err != nil {
+		logger.Errorf("Failed to compact task events: %v", err)
+		return 0
+	}
+	if n > 0 {
+		logger.Infof("Compacted %d task event(s)", n)
+	}
+	return n
+}
@@ -0,0 +1,52 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+func TestCompactor_Compact(t *testing.T) {
+	repo, _ := repository.NewMemoryRepositories()
+	now := time.Now()
+
+	task := model.NewTask("long-lived", "", model.TaskPriorityNormal, "report", nil, nil, 0, "alice")
+	task.ID = "long-lived"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for i := 10; i > 0; i-- {
+		if err := repo.AddEvent(&model.TaskEvent{TaskID: task.ID, Message: "retry", Timestamp: now.Add(-time.Duration(i) * 24 * time.Hour)}); err != nil {
+			t.Fatalf("add event: %v", err)
+		}
+	}
+
+	// 未设置保留时长时不压缩
+	if n := NewCompactor(repo, Options{KeepLatest: 3}).Compact(); n != 0 {
+		t.Fatalf("expected no compaction without max age, got %d", n)
+	}
+
+	fake := clock.NewFake(now)
+	c := NewCompactor(repo, Options{MaxAge: 7 * 24 * time.Hour, KeepLatest: 3, Clock: fake})
+	// 10 天前到 8 天前的 3 条事件超过保留期被删除，其余事件（含刚写入的创建事件）保留
+	if n := c.Compact(); n != 3 {
+		t.Fatalf("expected 3 events compacted, got %d", n)
+	}
+	events, _ := repo.GetEventsByTaskID(task.ID)
+	if len(events) != 8 {
+		t.Errorf("expected 8 events kept, got %d", len(events))
+	}
+
+	// 所有事件都超过保留期时仍保留最近的 3 条
+	fake.Advance(365 * 24 * time.Hour)
+	c.Compact()
+	if events, _ := repo.GetEventsByTaskID(task.ID); len(events) != 3 {
+		t.Errorf("expected the latest 3 events kept, got %d", len(events))
+	}
+	if n := c.Compact(); n != 0 {
+		t.Errorf("expected compaction to be idempotent, got %d", n)
+	}
+}
@@ -0,0 +1,196 @@
+// Package secrets 命名密钥：值以 AES-256-GCM 加密后保存在 SecretStore 中，
+// 任务 InputParams 通过 ${secret:NAME} 引用，只在执行器上下文中解密替换
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// MasterKeySize 主密钥长度（AES-256）
+const MasterKeySize = 32
+
+var (
+	// ErrNotFound 引用的密钥不存在
+	ErrNotFound = errors.New("secret not found")
+	// ErrInvalidName 密钥名称不合法
+	ErrInvalidName = errors.New("secret name must be 1-128 characters of letters, digits, '_', '-' or '.'")
+)
+
+var (
+	namePattern      = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+	referencePattern = regexp.MustCompile(`\$\{secret:([A-Za-z0-9_.-]{1,128})\}`)
+)
+
+// ParseMasterKey 解析 base64 编码的主密钥
+func ParseMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %w", err)
+	}
+	if len(key) != MasterKeySize {
+		return nil, fmt.Errorf("invalid master key: expected %d bytes, got %d", MasterKeySize, len(key))
+	}
+	return key, nil
+}
+
+// ValidateName 校验密钥名称
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return ErrInvalidName
+	}
+	return nil
+}
+
+// References 返回 params 中引用的密钥名称（去重）
+func References(params map[string]string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, v := range params {
+		for _, m := range referencePattern.FindAllStringSubmatch(v, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// Manager 加解密并管理命名密钥，明文只在 Put 和 ResolveParams 中出现
+type Manager struct {
+	store repository.SecretStore
+	aead  cipher.AEAD
+}
+
+// NewManager 创建密钥管理器，masterKey 为 32 字节 AES-256 密钥
+func NewManager(store repository.SecretStore, masterKey []byte) (*Manager, error) {
+	if len(masterKey) != MasterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", MasterKeySize, len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{store: store, aead: aead}, nil
+}
+
+// Put 加密并保存密钥，同名密钥的值被覆盖
+func (m *Manager) Put(name, value, createdBy string) (*model.Secret, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := m.encrypt(name, []byte(value))
+	if err != nil {
+		return nil, err
+	}
+	now := model.Now()
+	secret := &model.Secret{
+		Name:       name,
+		Ciphertext: ciphertext,
+		CreatedBy:  createdBy,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := m.store.Put(secret); err != nil {
+		return nil, err
+	}
+
+	// 覆盖时仓储保留原创建者和创建时间
+	stored, err := m.store.Get(name)
+	if err != nil || stored == nil {
+		return secret, err
+	}
+	return stored, nil
+}
+
+// Delete 删除密钥，不存在时返回 ErrNotFound
+func (m *Manager) Delete(name string) error {
+	ok, err := m.store.Delete(name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List 列出密钥元数据（不含值）
+func (m *Manager) List() ([]*model.Secret, error) {
+	return m.store.List()
+}
+
+// Resolve 解密单个密钥
+func (m *Manager) Resolve(name string) (string, error) {
+	secret, err := m.store.Get(name)
+	if err != nil {
+		return "", err
+	}
+	if secret == nil {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	plaintext, err := m.decrypt(name, secret.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret %s: %w", name, err)
+	}
+	return string(plaintext), nil
+}
+
+// ResolveParams 返回替换了 ${secret:NAME} 引用的参数副本和引用的明文（用于在日志中掩盖），没有引用时原样返回 params
+func (m *Manager) ResolveParams(params map[string]string) (map[string]string, []string, error) {
+	names := References(params)
+	if len(names) == 0 {
+		return params, nil, nil
+	}
+
+	values := make(map[string]string, len(names))
+	plaintexts := make([]string, 0, len(names))
+	for _, name := range names {
+		v, err := m.Resolve(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		values[name] = v
+		plaintexts = append(plaintexts, v)
+	}
+
+	resolved := make(map[string]string, len(params))
+	for k, v := range params {
+		resolved[k] = referencePattern.ReplaceAllStringFunc(v, func(ref string) string {
+			return values[referencePattern.FindStringSubmatch(ref)[1]]
+		})
+	}
+	return resolved, plaintexts, nil
+}
+
+// encrypt 加密，密文格式为 nonce || AES-GCM 密文，密钥名称作为附加数据防止密文被挪用到其他名称
+func (m *Manager) encrypt(name string, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return m.aead.Seal(nonce, nonce, plaintext, []byte(name)), nil
+}
+
+// decrypt 解密 encrypt 生成的密文
+func (m *Manager) decrypt(name string, ciphertext []byte) ([]byte, error) {
+	size := m.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, errors.New("ciphertext too short")
+	}
+	return m.aead.Open(nil, ciphertext[:size], ciphertext[size:], []byte(name))
+}
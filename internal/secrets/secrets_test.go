@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"taskflow/internal/repository"
+)
+
+func newTestManager(t *testing.T) (*Manager, *repository.MemorySecretRepository) {
+	t.Helper()
+	store := repository.NewMemorySecretRepository()
+	m, err := NewManager(store, bytes.Repeat([]byte{7}, MasterKeySize))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m, store
+}
+
+func TestManager_PutAndResolve(t *testing.T) {
+	m, store := newTestManager(t)
+
+	if _, err := m.Put("db.password", "hunter2", "alice"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	stored, _ := store.Get("db.password")
+	if stored == nil || bytes.Contains(stored.Ciphertext, []byte("hunter2")) {
+		t.Fatalf("expected ciphertext to be stored, got %v", stored)
+	}
+
+	got, err := m.Resolve("db.password")
+	if err != nil || got != "hunter2" {
+		t.Fatalf("Resolve = %q, %v", got, err)
+	}
+
+	// 覆盖保留创建者
+	secret, err := m.Put("db.password", "correct horse", "bob")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if secret.CreatedBy != "alice" {
+		t.Errorf("CreatedBy = %q, want alice", secret.CreatedBy)
+	}
+	if got, _ := m.Resolve("db.password"); got != "correct horse" {
+		t.Errorf("Resolve after overwrite = %q", got)
+	}
+
+	if err := m.Delete("db.password"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := m.Delete("db.password"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete missing = %v, want ErrNotFound", err)
+	}
+}
+
+func TestManager_CiphertextBoundToName(t *testing.T) {
+	m, store := newTestManager(t)
+	if _, err := m.Put("a", "value", ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	// 把 a 的密文挪到 b 下，解密应失败
+	a, _ := store.Get("a")
+	a.Name = "b"
+	store.Put(a)
+	if _, err := m.Resolve("b"); err == nil {
+		t.Fatal("expected decrypt to fail for moved ciphertext")
+	}
+}
+
+func TestManager_ResolveParams(t *testing.T) {
+	m, _ := newTestManager(t)
+	m.Put("token", "s3cr3t", "")
+
+	params := map[string]string{
+		"auth":  "Bearer ${secret:token}",
+		"plain": "value",
+	}
+	resolved, values, err := m.ResolveParams(params)
+	if err != nil {
+		t.Fatalf("ResolveParams: %v", err)
+	}
+	if resolved["auth"] != "Bearer s3cr3t" || resolved["plain"] != "value" {
+		t.Errorf("resolved = %v", resolved)
+	}
+	if len(values) != 1 || values[0] != "s3cr3t" {
+		t.Errorf("values = %v", values)
+	}
+	if params["auth"] != "Bearer ${secret:token}" {
+		t.Errorf("input params modified: %v", params)
+	}
+
+	_, _, err = m.ResolveParams(map[string]string{"x": "${secret:missing}"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing reference = %v, want ErrNotFound", err)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"a", "DB_PASSWORD", "api-key.v2"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "has space", "a/b", "${x}"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) = nil, want error", name)
+		}
+	}
+}
+
+func TestParseMasterKey(t *testing.T) {
+	if _, err := ParseMasterKey("c2hvcnQ="); err == nil {
+		t.Error("expected error for short key")
+	}
+	if _, err := ParseMasterKey("not base64!"); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+	if _, err := ParseMasterKey("AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="); err != nil {
+		t.Errorf("ParseMasterKey: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/logger"
+	"taskflow/internal/middleware"
+	pb "taskflow/proto"
+)
+
+// registerAdminRoutes 注册 /admin 管理端点，要求携带 ADMIN_TOKEN。
+// 备份受 SERVER_TIMEOUT 限制，超出时用 cmd/backup 在数据库所在主机上备份
+func (s *Server) registerAdminRoutes(router gin.IRouter) {
+	admin := router.Group("/admin", middleware.BearerToken(s.cfg.Server.AdminToken))
+	admin.GET("/backup", s.handleBackup)
+	admin.GET("/integrity", s.handleIntegrityReport)
+	admin.POST("/integrity", s.handleIntegrityCheck)
+	if s.taskHandler != nil {
+		admin.POST("/tasks/:id/dependencies", s.handleRepairTaskDependencies)
+	}
+}
+
+// repairDependenciesBody 修复依赖请求体，replace_with 为空时移除该依赖
+type repairDependenciesBody struct {
+	Repairs []struct {
+		DependencyID string `json:"dependency_id" binding:"required"`
+		ReplaceWith  string `json:"replace_with"`
+	} `json:"repairs" binding:"required,min=1,dive"`
+	Reason string `json:"reason"`
+}
+
+// handleBackup 下载数据库备份：format=sqlite（默认）为文件快照，format=json 为逻辑导出；
+// 启用分片时 shard 指定分片的命名空间，为空时备份主库
+func (s *Server) handleBackup(c *gin.Context) {
+	shard := c.Query("shard")
+	db, ok := s.databases[shard]
+	if !ok {
+		if len(s.databases) == 0 {
+			errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodeInvalidState, "backup requires sqlite storage")
+			return
+		}
+		errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodeNotFound, fmt.Sprintf("shard %s not found", shard))
+		return
+	}
+
+	name := "taskflow-" + time.Now().UTC().Format("20060102T150405Z")
+	if shard != "" {
+		name += "-" + shard
+	}
+	switch format := c.DefaultQuery("format", "sqlite"); format {
+	case "sqlite":
+		dir, err := os.MkdirTemp("", "taskflow-backup-")
+		if err != nil {
+			errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodeUnknown, err.Error())
+			return
+		}
+		defer os.RemoveAll(dir)
+		snapshot := filepath.Join(dir, "backup.db")
+		if err := db.Snapshot(snapshot); err != nil {
+			logger.Errorf("Backup failed: %v", err)
+			errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodeDBError, err.Error())
+			return
+		}
+		c.FileAttachment(snapshot, name+".db")
+	case "json":
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.jsonl"`, name))
+		// 响应头已发出，导出中途失败只能记录日志，客户端收到的文件不完整、恢复时报错
+		if header, err := db.Export(c.Writer); err != nil {
+			logger.Errorf("Backup export failed: %v", err)
+		} else {
+			logger.Infof("Backup exported: schema version %d", header.SchemaVersion)
+		}
+	default:
+		errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodeInvalidParam, fmt.Sprintf("format must be sqlite or json, got %s", format))
+	}
+}
+
+// handleIntegrityReport 最近一轮完整性检查（定期或按需）的报告
+func (s *Server) handleIntegrityReport(c *gin.Context) {
+	report := s.integrity.LastReport()
+	if report == nil {
+		errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodeNotFound, "no integrity check has run yet")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleIntegrityCheck 立即运行一轮完整性检查并返回报告，repair=true 时同时修复。
+// 已有检查在运行时等待其结束；大库的检查可能超过 SERVER_TIMEOUT，改用 INTEGRITY_CHECK_INTERVAL 定期检查后查看报告
+func (s *Server) handleIntegrityCheck(c *gin.Context) {
+	report, err := s.integrity.Check(c.Query("repair") == "true")
+	if err != nil {
+		logger.Errorf("Integrity check failed: %v", err)
+		errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodeDBError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// handleRepairTaskDependencies 修复待处理任务的依赖，任务事件中的操作者记为 admin；If-Match 指定任务须处于的版本
+func (s *Server) handleRepairTaskDependencies(c *gin.Context) {
+	expectedVersion, err := middleware.IfMatchVersion(c)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+	var req repairDependenciesBody
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	repairs := make([]*pb.DependencyRepair, len(req.Repairs))
+	for i, r := range req.Repairs {
+		repairs[i] = &pb.DependencyRepair{DependencyId: r.DependencyID, ReplaceWith: r.ReplaceWith}
+	}
+	task, err := s.taskHandler.RepairDependencies(c.Request.Context(), &pb.RepairTaskDependenciesRequest{
+		Id:              c.Param("id"),
+		Repairs:         repairs,
+		Reason:          req.Reason,
+		ExpectedVersion: expectedVersion,
+	}, "admin")
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	c.Header("ETag", middleware.VersionETag(task.Version))
+	middleware.Respond(c, http.StatusOK, task)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskflow/internal/middleware"
+)
+
+// registerDebugRoutes 注册 /debug/pprof 和 /debug/runtime，要求携带 DEBUG_TOKEN。
+// /debug/pprof 不经过 timeout：采样类 profile（profile、trace）按 seconds 持续采样，
+// net/http/pprof 自行把写超时延长到 SERVER_TIMEOUT 加 seconds
+func registerDebugRoutes(router *gin.Engine, token string, timeout gin.HandlerFunc) {
+	debug := router.Group("/debug", middleware.BearerToken(token))
+	debug.GET("/runtime", timeout, handleRuntimeStats)
+	debug.GET("/pprof/*name", handlePprof)
+	debug.POST("/pprof/*name", handlePprof)
+}
+
+// handlePprof 按名称分发到 net/http/pprof 的处理函数，其余名称（goroutine、heap、block 等）由 Index 处理
+func handlePprof(c *gin.Context) {
+	switch strings.TrimPrefix(c.Param("name"), "/") {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		// Index 按 /debug/pprof/ 之后的路径查找 profile
+		pprof.Index(c.Writer, c.Request)
+	}
+}
+
+// runtimeStats /debug/runtime 的响应
+type runtimeStats struct {
+	GoVersion      string  `json:"go_version"`
+	GOMAXPROCS     int     `json:"gomaxprocs"`
+	NumCPU         int     `json:"num_cpu"`
+	Goroutines     int     `json:"goroutines"`
+	HeapAllocBytes uint64  `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64  `json:"heap_inuse_bytes"`
+	HeapObjects    uint64  `json:"heap_objects"`
+	SysBytes       uint64  `json:"sys_bytes"`
+	NextGCBytes    uint64  `json:"next_gc_bytes"`
+	NumGC          uint32  `json:"num_gc"`
+	LastGC         string  `json:"last_gc,omitempty"`
+	LastGCPauseNs  uint64  `json:"last_gc_pause_ns"`
+	GCPauseTotalNs uint64  `json:"gc_pause_total_ns"`
+	GCCPUFraction  float64 `json:"gc_cpu_fraction"`
+}
+
+// handleRuntimeStats 返回协程数、堆和 GC 概况，排查调度停顿时先看协程是否堆积、GC 暂停是否过长
+func handleRuntimeStats(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := runtimeStats{
+		GoVersion:      runtime.Version(),
+		GOMAXPROCS:     runtime.GOMAXPROCS(0),
+		NumCPU:         runtime.NumCPU(),
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapInuseBytes: mem.HeapInuse,
+		HeapObjects:    mem.HeapObjects,
+		SysBytes:       mem.Sys,
+		NextGCBytes:    mem.NextGC,
+		NumGC:          mem.NumGC,
+		GCPauseTotalNs: mem.PauseTotalNs,
+		GCCPUFraction:  mem.GCCPUFraction,
+	}
+	if mem.NumGC > 0 {
+		stats.LastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339Nano)
+		stats.LastGCPauseNs = mem.PauseNs[(mem.NumGC+255)%256]
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,440 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"taskflow/internal/apiversion"
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/middleware"
+	"taskflow/internal/model"
+	"taskflow/internal/openapi"
+	pb "taskflow/proto"
+)
+
+// apiRoute 一个 API 路由：注册路由和生成 OpenAPI 文档共用同一份描述
+type apiRoute struct {
+	openapi.Route
+	handler gin.HandlerFunc
+}
+
+// streaming 事件流路由保持连接直到任务结束或客户端断开，不受请求超时限制
+func (r apiRoute) streaming() bool {
+	return r.ContentType == "text/event-stream"
+}
+
+// createTaskBody 创建任务请求体
+type createTaskBody struct {
+	Name               string            `json:"name" binding:"required"`
+	Description        string            `json:"description"`
+	Priority           int32             `json:"priority" binding:"gte=0,lte=4"`
+	TaskType           string            `json:"task_type"`
+	InputParams        map[string]string `json:"input_params"`
+	Dependencies       []string          `json:"dependencies"`
+	DependencyPolicies map[string]string `json:"dependency_policies"`
+	MaxRetries         int32             `json:"max_retries" binding:"gte=0"`
+	ResourceSlots      int32             `json:"resource_slots" binding:"gte=0"`
+	CreatedBy          string            `json:"created_by"`
+	TeamID             string            `json:"team_id"`
+	GroupKey           string            `json:"group_key"`
+	SLADeadline        int64             `json:"sla_deadline" binding:"gte=0"`
+	SLASeconds         int64             `json:"sla_seconds" binding:"gte=0"`
+	RetryPolicy        *pb.RetryPolicy   `json:"retry_policy"`
+	Labels             map[string]string `json:"labels"`
+	NodeSelector       map[string]string `json:"node_selector"`
+	TargetWorker       string            `json:"target_worker"`
+	WorkflowDeadline   int64             `json:"workflow_deadline" binding:"gte=0"`
+	WorkflowTimeout    int64             `json:"workflow_timeout_seconds" binding:"gte=0"`
+}
+
+// toPB 转换为创建任务的 Protobuf 请求
+func (b *createTaskBody) toPB() *pb.CreateTaskRequest {
+	return &pb.CreateTaskRequest{
+		Name:                   b.Name,
+		Description:            b.Description,
+		Priority:               pb.TaskPriority(b.Priority),
+		TaskType:               b.TaskType,
+		InputParams:            b.InputParams,
+		Dependencies:           b.Dependencies,
+		DependencyPolicies:     b.DependencyPolicies,
+		MaxRetries:             b.MaxRetries,
+		ResourceSlots:          b.ResourceSlots,
+		CreatedBy:              b.CreatedBy,
+		TeamId:                 b.TeamID,
+		GroupKey:               b.GroupKey,
+		SlaDeadline:            b.SLADeadline,
+		SlaSeconds:             b.SLASeconds,
+		RetryPolicy:            b.RetryPolicy,
+		Labels:                 b.Labels,
+		NodeSelector:           b.NodeSelector,
+		TargetWorker:           b.TargetWorker,
+		WorkflowDeadline:       b.WorkflowDeadline,
+		WorkflowTimeoutSeconds: b.WorkflowTimeout,
+	}
+}
+
+// updateTaskBody 更新任务请求体。设置 update_mask（逗号分隔的字段名）时按掩码更新任务定义
+type updateTaskBody struct {
+	Status       int32             `json:"status" binding:"gte=0,lte=6"`
+	OutputResult map[string]string `json:"output_result"`
+	ErrorMessage string            `json:"error_message"`
+	RetryCount   int32             `json:"retry_count"`
+	UpdateMask   string            `json:"update_mask"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Priority     int32             `json:"priority"`
+	InputParams  map[string]string `json:"input_params"`
+	MaxRetries   int32             `json:"max_retries"`
+	Labels       map[string]string `json:"labels"`
+}
+
+// rerunTaskBody 重新运行任务请求体，mode 为 reset（默认）或 clone
+type rerunTaskBody struct {
+	Mode string `json:"mode" binding:"omitempty,oneof=reset clone"`
+}
+
+// signalTaskBody 向等待输入的任务送达信号请求体
+type signalTaskBody struct {
+	Payload map[string]string `json:"payload" binding:"required,min=1"`
+}
+
+// subStatusBody 上报自定义阶段请求体
+type subStatusBody struct {
+	SubStatus string `json:"sub_status" binding:"required"`
+	Message   string `json:"message"`
+}
+
+// cancelWorkflowBody 取消任务所在工作流请求体
+type cancelWorkflowBody struct {
+	Reason string `json:"reason" binding:"max=1024"`
+}
+
+// cancelCorrelationWorkflowBody 取消同一请求创建的任务请求体
+type cancelCorrelationWorkflowBody struct {
+	CorrelationID string `json:"correlation_id" binding:"required"`
+	Reason        string `json:"reason" binding:"max=1024"`
+}
+
+// getTasksBody 批量获取任务请求体
+type getTasksBody struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=1000"`
+}
+
+// addCommentBody 添加评论请求体
+type addCommentBody struct {
+	Author string `json:"author"`
+	Body   string `json:"body" binding:"required,max=4096"`
+}
+
+// resizeWorkerPoolBody 调整工作池请求体
+type resizeWorkerPoolBody struct {
+	Size int32 `json:"size" binding:"required,gte=1"`
+}
+
+// planTaskBody 调度模拟中的任务定义
+type planTaskBody struct {
+	ID                  string   `json:"id" binding:"required"`
+	Name                string   `json:"name"`
+	TaskType            string   `json:"task_type"`
+	Priority            int32    `json:"priority" binding:"gte=0,lte=4"`
+	Dependencies        []string `json:"dependencies"`
+	EstimatedDurationMs int64    `json:"estimated_duration_ms" binding:"gte=0"`
+}
+
+// planScheduleBody 调度模拟请求体
+type planScheduleBody struct {
+	Tasks             []planTaskBody `json:"tasks" binding:"required,min=1,dive"`
+	Workers           int32          `json:"workers" binding:"gte=0"`
+	DefaultDurationMs int64          `json:"default_duration_ms" binding:"gte=0"`
+}
+
+// createTeamBody 创建团队请求体
+type createTeamBody struct {
+	Name      string   `json:"name" binding:"required"`
+	Members   []string `json:"members"`
+	CreatedBy string   `json:"created_by"`
+}
+
+// addTeamMemberBody 添加团队成员请求体
+type addTeamMemberBody struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// putSecretBody 写入密钥请求体
+type putSecretBody struct {
+	Value     string `json:"value" binding:"required"`
+	CreatedBy string `json:"created_by"`
+}
+
+// archiveResponse 归档结果
+type archiveResponse struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+// taskStatsResponse 各状态任务数量
+type taskStatsResponse struct {
+	Total        int `json:"total"`
+	Pending      int `json:"pending"`
+	Running      int `json:"running"`
+	Succeeded    int `json:"succeeded"`
+	Failed       int `json:"failed"`
+	Cancelled    int `json:"cancelled"`
+	Skipped      int `json:"skipped"`
+	WaitingInput int `json:"waiting_input"`
+}
+
+// 条件请求头
+var (
+	ifNoneMatchHeader = openapi.Param{Name: "If-None-Match", Type: "string", Description: "上次响应的 ETag，未变化时返回 304"}
+	ifMatchHeader     = openapi.Param{Name: "If-Match", Type: "string", Description: "获取任务时响应的 ETag，任务已被修改时返回 412"}
+)
+
+// apiRoutes 每个 API 版本注册的路由，路径相对于 /api/<版本>
+func (s *Server) apiRoutes() []apiRoute {
+	return []apiRoute{
+		// 任务列表
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks", Tag: "Tasks", Summary: "分页列出任务",
+			Query: []openapi.Param{
+				{Name: "page", Type: "integer", Description: "页码，从 1 开始"},
+				{Name: "page_size", Type: "integer", Description: "每页数量，默认 20"},
+				{Name: "keyword", Type: "string", Description: "按名称和描述搜索"},
+				{Name: "type", Type: "string", Description: "任务类型，可重复或以逗号分隔"},
+				{Name: "status", Type: "string", Description: "任务状态编号，可重复或以逗号分隔，如 1,2"},
+				{Name: "priority", Type: "string", Description: "任务优先级编号，可重复或以逗号分隔"},
+				{Name: "team_id", Type: "string", Description: "所属团队"},
+				{Name: "correlation_id", Type: "string", Description: "创建任务的请求 ID（X-Request-ID）"},
+				{Name: "sort_by", Type: "string", Description: "排序字段：created_at、updated_at、priority、status 或 completed_at，默认按优先级和创建时间降序"},
+				{Name: "sort_desc", Type: "boolean", Description: "与 sort_by 同时使用：降序排列"},
+				{Name: "created_after", Type: "string", Description: "创建时间不早于该时间（Unix 秒、RFC3339 或 tz 时区的本地时间）"},
+				{Name: "created_before", Type: "string", Description: "创建时间早于该时间（Unix 秒、RFC3339 或 tz 时区的本地时间）"},
+				{Name: "completed_after", Type: "string", Description: "完成时间不早于该时间（Unix 秒、RFC3339 或 tz 时区的本地时间），未完成的任务不返回"},
+				{Name: "completed_before", Type: "string", Description: "完成时间早于该时间（Unix 秒、RFC3339 或 tz 时区的本地时间），未完成的任务不返回"},
+				{Name: "updated_since", Type: "string", Description: "更新时间不早于该时间（Unix 秒、RFC3339 或 tz 时区的本地时间）"},
+				{Name: "tz", Type: "string", Description: "解释不带偏移量的本地时间使用的 IANA 时区，如 Asia/Shanghai，默认 UTC"},
+			},
+			Header:   []openapi.Param{ifNoneMatchHeader},
+			Response: &pb.ListTasksResponse{}, NotModified: true}, s.handleListTasks},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks", Tag: "Tasks", Summary: "创建任务",
+			Body: createTaskBody{}, Status: http.StatusCreated, Response: &pb.Task{}}, s.handleCreateTask},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/execute", Tag: "Tasks", Summary: "创建任务并等待其结束，输出随响应返回；等待超时时 completed 为 false，任务继续执行",
+			Query: []openapi.Param{
+				{Name: "timeout", Type: "string", Description: "最长等待时长，如 25s，默认 30s、上限 5m；须短于请求超时（SERVER_TIMEOUT，默认 30s），否则返回 400"},
+			},
+			Body: createTaskBody{}, Response: &pb.ExecuteTaskSyncResponse{}}, s.handleExecuteTaskSync},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/batch-get", Tag: "Tasks", Summary: "按 ID 批量获取任务",
+			Body: getTasksBody{}, Response: &pb.GetTasksResponse{}}, s.handleGetTasks},
+
+		// 单个任务操作
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id", Tag: "Tasks", Summary: "获取任务",
+			Query: []openapi.Param{
+				{Name: "include_events", Type: "boolean", Description: "是否包含状态变更事件"},
+				{Name: "unredacted", Type: "boolean", Description: "返回未脱敏的输入参数（需管理员）"},
+				{Name: "wait_for", Type: "string", Description: "长轮询：等到任务进入其中任一状态（状态名如 SUCCEEDED 或状态值，可重复或以逗号分隔）后再返回"},
+				{Name: "timeout", Type: "string", Description: "与 wait_for 同时使用的最长等待时长，如 25s，默认 30s、上限 5m；须短于请求超时（SERVER_TIMEOUT，默认 30s），否则返回 400。超时后返回任务的当前状态"},
+			},
+			Header:   []openapi.Param{ifNoneMatchHeader},
+			Response: &pb.Task{}, NotModified: true}, s.handleGetTask},
+		{openapi.Route{Method: http.MethodPut, Path: "/tasks/:id", Tag: "Tasks", Summary: "更新任务",
+			Header: []openapi.Param{ifMatchHeader},
+			Body:   updateTaskBody{}, Response: &pb.Task{}}, s.handleUpdateTask},
+		{openapi.Route{Method: http.MethodDelete, Path: "/tasks/:id", Tag: "Tasks", Summary: "删除未在运行的任务及其事件、评论、附件、日志和历次运行",
+			Query: []openapi.Param{
+				{Name: "force", Type: "boolean", Description: "仍被未结束的任务依赖时也删除，并从这些任务中移除对它的依赖"},
+			},
+			Header: []openapi.Param{ifMatchHeader},
+			Status: http.StatusNoContent}, s.handleDeleteTask},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/:id/rerun", Tag: "Tasks", Summary: "重新运行已结束的任务：reset 原地重置并保存本次运行，clone 创建关联的新任务",
+			Header: []openapi.Param{ifMatchHeader},
+			Body:   rerunTaskBody{}, Response: &pb.Task{}}, s.handleRerunTask},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/:id/signal", Tag: "Tasks", Summary: "向等待输入（WAITING_INPUT）的任务送达输入，任务重新进入 PENDING 并再次执行",
+			Header: []openapi.Param{ifMatchHeader},
+			Body:   signalTaskBody{}, Response: &pb.Task{}}, s.handleSignalTask},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/:id/sub-status", Tag: "Tasks", Summary: "外部执行器上报运行中任务进入的自定义阶段（子状态），任务状态不变",
+			Body: subStatusBody{}, Response: &pb.Task{}}, s.handleSetTaskSubStatus},
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/transitions", Tag: "Tasks", Summary: "说明任务当前可以转换到的状态、触发每个转换的接口，以及调用者可以触发其中哪些",
+			Response: &pb.AllowedTransitions{}}, s.handleGetAllowedTransitions},
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/export", Tag: "Tasks", Summary: "导出任务（含事件）为 JSON 附件",
+			Response: &pb.Task{}, Raw: true}, s.handleExportTask},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/:id/archive", Tag: "Tasks", Summary: "把任务导出写入产物存储",
+			Status: http.StatusCreated, Response: archiveResponse{}}, s.handleArchiveTask},
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/output", Tag: "Tasks", Summary: "获取任务输出（含已转存的大输出）",
+			Response: map[string]string{}, Raw: true}, s.handleGetTaskOutput},
+
+		// 任务评论
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/comments", Tag: "Comments", Summary: "列出任务评论",
+			Response: &pb.ListTaskCommentsResponse{}}, s.handleListTaskComments},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/:id/comments", Tag: "Comments", Summary: "添加任务评论",
+			Body: addCommentBody{}, Status: http.StatusCreated, Response: &pb.TaskComment{}}, s.handleAddTaskComment},
+
+		// 任务附件
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/attachments", Tag: "Attachments", Summary: "列出任务附件",
+			Response: &pb.ListAttachmentsResponse{}}, s.handleListAttachments},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/:id/attachments", Tag: "Attachments", Summary: "上传附件",
+			Form: []openapi.Param{
+				{Name: "file", Type: "file", Description: "附件内容", Required: true},
+				{Name: "uploaded_by", Type: "string", Description: "上传者"},
+			},
+			Status: http.StatusCreated, Response: &model.TaskAttachment{}}, s.handleUploadAttachment},
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/attachments/:attachment_id", Tag: "Attachments", Summary: "下载附件内容",
+			ContentType: "application/octet-stream", Raw: true}, s.handleDownloadAttachment},
+		{openapi.Route{Method: http.MethodDelete, Path: "/tasks/:id/attachments/:attachment_id", Tag: "Attachments", Summary: "删除附件",
+			Status: http.StatusNoContent}, s.handleDeleteAttachment},
+
+		// 任务状态事件
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/events", Tag: "Tasks", Summary: "分页获取任务状态事件",
+			Query: []openapi.Param{
+				{Name: "after_seq", Type: "integer", Description: "只返回序号大于该值的事件"},
+				{Name: "limit", Type: "integer", Description: "返回条数上限"},
+				{Name: "timestamp_after", Type: "string", Description: "事件时间不早于该时间（Unix 秒、RFC3339 或 tz 时区的本地时间）"},
+				{Name: "timestamp_before", Type: "string", Description: "事件时间早于该时间（Unix 秒、RFC3339 或 tz 时区的本地时间）"},
+				{Name: "tz", Type: "string", Description: "解释不带偏移量的本地时间使用的 IANA 时区，如 Asia/Shanghai，默认 UTC"},
+			},
+			Response: &pb.ListTaskEventsResponse{}}, s.handleListTaskEvents},
+
+		// 任务执行日志
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/logs", Tag: "Logs", Summary: "分页获取任务执行日志",
+			Query: []openapi.Param{
+				{Name: "after_seq", Type: "integer", Description: "只返回序号大于该值的日志"},
+				{Name: "limit", Type: "integer", Description: "返回条数上限"},
+			},
+			Response: &pb.GetTaskLogsResponse{}}, s.handleGetTaskLogs},
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/logs/stream", Tag: "Logs", Summary: "以 Server-Sent Events 跟踪任务日志（log、error、end 事件）",
+			Query:       []openapi.Param{{Name: "after_seq", Type: "integer", Description: "从该序号之后开始推送"}},
+			ContentType: "text/event-stream", Raw: true}, s.handleTailTaskLogs},
+
+		// 任务统计
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/stats", Tag: "Tasks", Summary: "各状态任务数量",
+			Response: taskStatsResponse{}}, s.handleTaskStats},
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/stats/durations", Tag: "Tasks", Summary: "各任务类型最近成功任务的执行耗时统计",
+			Query: []openapi.Param{
+				{Name: "task_type", Type: "string", Description: "任务类型，可重复；为空时返回所有有成功记录的类型"},
+				{Name: "window", Type: "integer", Description: "参与统计的最近成功任务数，默认 100，最大 1000"},
+				{Name: "include_samples", Type: "boolean", Description: "返回每个样本的耗时"},
+			},
+			Response: &pb.GetDurationStatsResponse{}}, s.handleDurationStats},
+
+		// 工作流时间线
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/timeline", Tag: "Tasks", Summary: "与任务通过依赖相连的全部任务的排队、开始、结束时间和关键路径，用于绘制甘特图",
+			Response: &pb.TaskTimeline{}}, s.handleGetTaskTimeline},
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/timeline", Tag: "Tasks", Summary: "同一请求创建的全部任务的时间线",
+			Query:    []openapi.Param{{Name: "correlation_id", Type: "string", Description: "创建任务的请求 ID", Required: true}},
+			Response: &pb.TaskTimeline{}}, s.handleGetCorrelationTimeline},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/:id/workflow/cancel", Tag: "Tasks", Summary: "取消与任务通过依赖相连的全部未结束任务，运行中的任务先停止执行",
+			Body: cancelWorkflowBody{}, Response: &pb.CancelWorkflowResponse{}}, s.handleCancelWorkflow},
+		{openapi.Route{Method: http.MethodPost, Path: "/tasks/workflow/cancel", Tag: "Tasks", Summary: "取消同一请求创建的全部未结束任务",
+			Body: cancelCorrelationWorkflowBody{}, Response: &pb.CancelWorkflowResponse{}}, s.handleCancelCorrelationWorkflow},
+
+		// 变更流
+		{openapi.Route{Method: http.MethodGet, Path: "/changes", Tag: "Changes", Summary: "按序号分页获取所有任务的事件，用于增量同步",
+			Query: []openapi.Param{
+				{Name: "since_seq", Type: "integer", Description: "只返回序号大于该值的事件，0 表示从头开始"},
+				{Name: "limit", Type: "integer", Description: "返回条数上限，默认 1000，最大 10000"},
+			},
+			Response: &pb.GetChangeFeedResponse{}}, s.handleGetChangeFeed},
+		{openapi.Route{Method: http.MethodGet, Path: "/changes/snapshot", Tag: "Changes", Summary: "按 ID 分页获取任务快照及其变更游标，用于建立镜像",
+			Query: []openapi.Param{
+				{Name: "cursor", Type: "integer", Description: "第一页为 0，之后传回第一页响应中的 cursor"},
+				{Name: "after_id", Type: "string", Description: "上一页的 last_id"},
+				{Name: "page_size", Type: "integer", Description: "每页任务数，默认 500，最大 5000"},
+			},
+			Response: &pb.SnapshotTasksResponse{}}, s.handleSnapshotTasks},
+		{openapi.Route{Method: http.MethodGet, Path: "/changes/stream", Tag: "Changes", Summary: "以 Server-Sent Events 推送游标之后的变更（change、error 事件）",
+			Query:       []openapi.Param{{Name: "since_seq", Type: "integer", Description: "从该序号之后开始推送，通常为快照的 cursor"}},
+			ContentType: "text/event-stream", Raw: true}, s.handleStreamChanges},
+
+		// SLA
+		{openapi.Route{Method: http.MethodGet, Path: "/sla/report", Tag: "SLA", Summary: "截止时间在窗口内的任务的 SLA 达成情况和违约任务",
+			Query: []openapi.Param{
+				{Name: "from", Type: "string", Description: "截止时间下限（Unix 秒、RFC3339 或 tz 时区的本地时间），默认 to 之前 24 小时"},
+				{Name: "to", Type: "string", Description: "截止时间上限（Unix 秒、RFC3339 或 tz 时区的本地时间），默认当前时间"},
+				{Name: "tz", Type: "string", Description: "解释不带偏移量的本地时间使用的 IANA 时区，如 Asia/Shanghai，默认 UTC"},
+				{Name: "task_type", Type: "string", Description: "只统计该任务类型"},
+			},
+			Response: &pb.SLAReport{}}, s.handleSLAReport},
+
+		// 调度器工作池
+		{openapi.Route{Method: http.MethodGet, Path: "/scheduler/workers", Tag: "Scheduler", Summary: "获取工作池状态",
+			Response: &pb.WorkerPoolStatus{}}, s.handleGetWorkerPool},
+		{openapi.Route{Method: http.MethodPut, Path: "/scheduler/workers", Tag: "Scheduler", Summary: "调整工作池大小",
+			Body: resizeWorkerPoolBody{}, Response: &pb.WorkerPoolStatus{}}, s.handleResizeWorkerPool},
+		{openapi.Route{Method: http.MethodGet, Path: "/scheduler/instances", Tag: "Scheduler", Summary: "列出调度器实例",
+			Query:    []openapi.Param{{Name: "include_stale", Type: "boolean", Description: "包含心跳超时的实例"}},
+			Response: &pb.ListSchedulerInstancesResponse{}}, s.handleListSchedulerInstances},
+		{openapi.Route{Method: http.MethodGet, Path: "/tasks/:id/scheduling", Tag: "Scheduler", Summary: "说明任务当前为什么没有运行（依赖、退避、暂停窗口、资源、互斥、队列等）",
+			Response: &pb.SchedulingDecision{}}, s.handleGetSchedulingDecision},
+		{openapi.Route{Method: http.MethodPost, Path: "/scheduler/plan", Tag: "Scheduler", Summary: "模拟调度一组任务定义，返回开始顺序、关键路径和预计总耗时",
+			Body: planScheduleBody{}, Response: &pb.PlanScheduleResponse{}}, s.handlePlanSchedule},
+
+		// 团队
+		{openapi.Route{Method: http.MethodGet, Path: "/teams", Tag: "Teams", Summary: "列出用户所在团队",
+			Query:    []openapi.Param{{Name: "user_id", Type: "string", Description: "用户 ID"}},
+			Response: &pb.ListTeamsResponse{}}, s.handleListTeams},
+		{openapi.Route{Method: http.MethodPost, Path: "/teams", Tag: "Teams", Summary: "创建团队",
+			Body: createTeamBody{}, Status: http.StatusCreated, Response: &pb.Team{}}, s.handleCreateTeam},
+		{openapi.Route{Method: http.MethodPost, Path: "/teams/:id/members", Tag: "Teams", Summary: "添加团队成员",
+			Body: addTeamMemberBody{}, Response: &pb.Team{}}, s.handleAddTeamMember},
+		{openapi.Route{Method: http.MethodDelete, Path: "/teams/:id/members/:user_id", Tag: "Teams", Summary: "移除团队成员",
+			Response: &pb.Team{}}, s.handleRemoveTeamMember},
+
+		// 命名密钥（只返回元数据）
+		{openapi.Route{Method: http.MethodGet, Path: "/secrets", Tag: "Secrets", Summary: "列出密钥元数据",
+			Response: &pb.ListSecretsResponse{}}, s.handleListSecrets},
+		{openapi.Route{Method: http.MethodPut, Path: "/secrets/:name", Tag: "Secrets", Summary: "创建或覆盖密钥",
+			Body: putSecretBody{}, Response: &pb.Secret{}}, s.handlePutSecret},
+		{openapi.Route{Method: http.MethodDelete, Path: "/secrets/:name", Tag: "Secrets", Summary: "删除密钥",
+			Status: http.StatusNoContent}, s.handleDeleteSecret},
+	}
+}
+
+// apiVersions 服务提供的 API 版本：v1 为原有接口，v2 起成功响应包裹在版本信封中
+func (s *Server) apiVersions() apiversion.Set {
+	v1 := apiversion.Version{Name: apiversion.V1, Link: s.cfg.API.DeprecationLink}
+	v1.DeprecatedAt, v1.SunsetAt = s.cfg.API.V1Schedule()
+	return apiversion.Set{v1, {Name: apiversion.V2}}
+}
+
+// registerRoutes 为每个 API 版本注册一组路由，破坏性变更只在新版本的路由组中生效；
+// /swagger 提供由同一份路由表生成的 OpenAPI 文档。事件流以外的路由经过 timeout
+func (s *Server) registerRoutes(router *gin.Engine, timeout gin.HandlerFunc) {
+	versions := s.apiVersions()
+	routes := s.apiRoutes()
+	format := middleware.ResponseFormat{Naming: s.cfg.API.ResponseNaming, Envelope: s.cfg.API.ResponseEnvelope}
+	for _, v := range versions {
+		group := router.Group("/api/"+v.Name, middleware.APIVersion(v, versions), middleware.ResponseFormatter(format))
+		for _, r := range routes {
+			if r.streaming() {
+				group.Handle(r.Method, r.Path, r.handler)
+			} else {
+				group.Handle(r.Method, r.Path, timeout, r.handler)
+			}
+		}
+	}
+
+	doc := s.OpenAPI()
+	router.GET("/swagger", timeout, func(c *gin.Context) {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		if err := openapi.WriteUI(c.Writer, doc.Info.Title, "/swagger/openapi.json"); err != nil {
+			errorcode.HandleGinError(c, err)
+		}
+	})
+	router.GET("/swagger/openapi.json", timeout, func(c *gin.Context) {
+		c.JSON(200, doc)
+	})
+}
+
+// OpenAPI 生成 HTTP API 的 OpenAPI 文档，不需要启动服务
+func (s *Server) OpenAPI() *openapi.Document {
+	routes := s.apiRoutes()
+	specs := make([]openapi.Route, len(routes))
+	for i, r := range routes {
+		specs[i] = r.Route
+	}
+	versions := s.apiVersions()
+	return openapi.Build(openapi.Info{
+		Title:       "TaskFlow API",
+		Description: "任务调度服务 HTTP API，支持的版本：" + versions.Names(),
+		Version:     versions[len(versions)-1].Name,
+	}, versions, specs, errorcode.GinErrorResponse{})
+}
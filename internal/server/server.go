@@ -2,39 +2,71 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"net"
 	"os"
 	"os/signal"
 	path2 "path"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // tz 查询参数按 IANA 时区名解析，不依赖运行环境的时区数据库
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip" // 注册 gzip 压缩器，客户端以 grpc.UseCompressor(gzip.Name) 压缩请求，响应使用相同的压缩器
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
+	"taskflow/internal/anomaly"
 	"taskflow/internal/config"
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/grpc_middleware"
 	"taskflow/internal/handler"
+	"taskflow/internal/idgen"
+	"taskflow/internal/integrity"
 	"taskflow/internal/logger"
 	"taskflow/internal/middleware"
 	"taskflow/internal/model"
+	"taskflow/internal/notify"
+	"taskflow/internal/outbox"
+	"taskflow/internal/queuetime"
+	"taskflow/internal/redact"
+	"taskflow/internal/redis"
 	"taskflow/internal/repository"
+	"taskflow/internal/retention"
+	"taskflow/internal/secrets"
+	"taskflow/internal/sla"
+	"taskflow/internal/storage"
+	"taskflow/internal/workflow"
 	pb "taskflow/proto"
 )
 
 // Server HTTP/gRPC服务封装
 type Server struct {
-	cfg        *config.Config
-	httpServer *http.Server
-	grpcServer *grpc.Server
-	started    bool
-	startMutex sync.Mutex
-	taskHandler *handler.TaskHandler
-	taskRepo    *repository.TaskRepository
+	cfg           *config.Config
+	httpServer    *http.Server
+	grpcServer    *grpc.Server
+	started       bool
+	startMutex    sync.Mutex
+	taskHandler   *handler.TaskHandler
+	taskRepo      repository.TaskStore
+	teamRepo      repository.TeamStore
+	stopRelay     context.CancelFunc            // 发件箱中继未启用时为 nil
+	stopSLA       context.CancelFunc            // SLA 监控未启用时为 nil
+	stopQueueTime context.CancelFunc            // 排队时长监控未启用时为 nil
+	stopWorkflow  context.CancelFunc            // 工作流截止时间监控未启用时为 nil
+	stopAnomaly   context.CancelFunc            // 失败率异常检测未启用时为 nil
+	stopEvents    context.CancelFunc            // 任务事件压缩未启用时为 nil
+	stopCache     context.CancelFunc            // 任务缓存未轮询事件时为 nil
+	stopIntegrity context.CancelFunc            // 未启用定期完整性检查时为 nil
+	integrity     *integrity.Checker            // 数据完整性检查器，定期检查和 /admin/integrity 共用
+	databases     map[string]*repository.SQLite // 可备份的数据库，见 storeSet
 }
 
 // NewServer 创建服务实例
@@ -53,37 +85,175 @@ func (s *Server) Start() error {
 		return fmt.Errorf("server already started")
 	}
 
-	// 获取数据库路径（支持环境变量 TASKFLOW_DB_PATH）
-	dbPath := s.cfg.Server.DBPath
-	// 处理用户主目录
-	if strings.HasPrefix(dbPath, "~") {
-		homeDir, err := os.UserHomeDir()
+	// 任务和事件 ID 生成器
+	ids, err := idgen.New(s.cfg.IDs.Generator, int64(s.cfg.IDs.NodeID))
+	if err != nil {
+		return fmt.Errorf("failed to init id generator: %w", err)
+	}
+	idgen.SetDefault(ids)
+
+	stores, err := s.openStorage()
+	if err != nil {
+		return err
+	}
+	// 任务读穿缓存
+	if s.cfg.Cache.Enabled {
+		s.cacheTasks(stores)
+	}
+	defer stores.close()
+
+	taskRepo := stores.tasks
+	s.taskRepo = taskRepo
+	s.teamRepo = stores.teams
+	s.databases = stores.databases
+	s.taskHandler = handler.NewTaskHandler(taskRepo, s.teamRepo)
+	s.taskHandler.SetStaleReads(time.Duration(s.cfg.Database.ReplicaMaxStaleness)*time.Second, s.statsStaleness())
+
+	// 任务状态变更通知
+	notifier, err := notify.NewNotifier(s.cfg.Notifications)
+	if err != nil {
+		return fmt.Errorf("failed to init notifier: %w", err)
+	}
+	s.taskHandler.SetNotifier(notifier)
+
+	// 发件箱中继：至少一次投递任务状态变更事件
+	if s.cfg.Outbox.Enabled {
+		sinks, err := outbox.NewSinks(s.cfg.Outbox, func(id string) (*model.Task, error) {
+			return repository.FindTask(taskRepo, id)
+		}, s.taskHandler.EncodeTaskChangeEvent)
 		if err != nil {
-			homeDir = "."
+			return fmt.Errorf("failed to init outbox sinks: %w", err)
 		}
-		dbPath = path2.Join(homeDir, strings.TrimPrefix(dbPath, "~/"))
+		relay := outbox.NewRelay(taskRepo, sinks, outbox.Options{
+			PollInterval: time.Duration(s.cfg.Outbox.PollInterval) * time.Millisecond,
+			BatchSize:    s.cfg.Outbox.BatchSize,
+			Retention:    time.Duration(s.cfg.Outbox.Retention) * time.Hour,
+		})
+		relayCtx, cancel := context.WithCancel(context.Background())
+		s.stopRelay = cancel
+		go relay.Run(relayCtx)
 	}
 
-	// 确保目录存在
-	dbDir := path2.Dir(dbPath)
-	if err := os.MkdirAll(dbDir, 0755); err != nil {
-		return fmt.Errorf("failed to create db directory: %w", err)
+	// SLA 监控：记录超过截止时间仍未成功完成的任务，并推送给通知渠道和 WatchTask 订阅者
+	if s.cfg.SLA.CheckInterval > 0 {
+		monitor := sla.NewMonitor(taskRepo, notifier, sla.Options{
+			CheckInterval: time.Duration(s.cfg.SLA.CheckInterval) * time.Second,
+			BatchSize:     s.cfg.SLA.BatchSize,
+			OnBreach:      s.taskHandler.PublishSLABreach,
+		})
+		slaCtx, cancel := context.WithCancel(context.Background())
+		s.stopSLA = cancel
+		go monitor.Run(slaCtx)
 	}
 
-	db, err := repository.NewSQLite(dbPath)
+	// 排队时长监控：尚未开始执行的任务排队超过上限时告警，可选提高优先级
+	if qt := s.cfg.QueueTime; qt.CheckInterval > 0 && (qt.MaxAge > 0 || len(qt.MaxAgeByType) > 0) {
+		maxAgeByType := make(map[string]time.Duration, len(qt.MaxAgeByType))
+		for taskType, maxAge := range qt.MaxAgeByType {
+			maxAgeByType[taskType] = time.Duration(maxAge) * time.Second
+		}
+		escalateTo, _ := model.ParseTaskPriority(qt.EscalatePriority) // 已由配置校验，为空时不提高
+		monitor := queuetime.NewMonitor(taskRepo, notifier, queuetime.Options{
+			CheckInterval: time.Duration(qt.CheckInterval) * time.Second,
+			BatchSize:     qt.BatchSize,
+			MaxAge:        time.Duration(qt.MaxAge) * time.Second,
+			MaxAgeByType:  maxAgeByType,
+			EscalateTo:    escalateTo,
+		})
+		queueTimeCtx, cancel := context.WithCancel(context.Background())
+		s.stopQueueTime = cancel
+		go monitor.Run(queueTimeCtx)
+	}
+
+	// 工作流截止时间监控：取消超过截止时间的工作流中未结束的任务，运行中任务的执行由调度器实例在心跳时停止
+	if s.cfg.Workflow.CheckInterval > 0 {
+		monitor := workflow.NewMonitor(taskRepo, notifier, workflow.Options{
+			CheckInterval: time.Duration(s.cfg.Workflow.CheckInterval) * time.Second,
+			BatchSize:     s.cfg.Workflow.BatchSize,
+			OnCancel:      s.taskHandler.PublishTaskChange,
+		})
+		workflowCtx, cancel := context.WithCancel(context.Background())
+		s.stopWorkflow = cancel
+		go monitor.Run(workflowCtx)
+	}
+
+	// 失败率异常检测：任务类型的失败率显著高于基线时告警
+	if s.cfg.Anomaly.CheckInterval > 0 {
+		detector := anomaly.NewDetector(repository.StaleReads(taskRepo, s.statsStaleness()), notifier, anomaly.Options{
+			CheckInterval:   time.Duration(s.cfg.Anomaly.CheckInterval) * time.Second,
+			Window:          time.Duration(s.cfg.Anomaly.Window) * time.Second,
+			BaselineWindows: s.cfg.Anomaly.BaselineWindows,
+			Threshold:       s.cfg.Anomaly.Threshold,
+			MinSamples:      s.cfg.Anomaly.MinSamples,
+		})
+		anomalyCtx, cancel := context.WithCancel(context.Background())
+		s.stopAnomaly = cancel
+		go detector.Run(anomalyCtx)
+	}
+
+	// 任务事件保留：删除超过保留期的事件，每个任务最近的事件始终保留
+	if s.cfg.Events.Retention > 0 {
+		compactor := retention.NewCompactor(taskRepo, retention.Options{
+			CheckInterval: time.Duration(s.cfg.Events.CompactInterval) * time.Second,
+			MaxAge:        time.Duration(s.cfg.Events.Retention) * time.Hour,
+			KeepLatest:    s.cfg.Events.KeepLatest,
+		})
+		eventsCtx, cancel := context.WithCancel(context.Background())
+		s.stopEvents = cancel
+		go compactor.Run(eventsCtx)
+	}
+
+	// 数据完整性检查：管理端点按需运行，配置检查间隔时定期运行
+	s.integrity = integrity.NewChecker(taskRepo, integrity.Options{
+		CheckInterval: time.Duration(s.cfg.Integrity.CheckInterval) * time.Second,
+		Repair:        s.cfg.Integrity.Repair,
+		LeaseTimeout:  time.Duration(s.cfg.Integrity.LeaseTimeout) * time.Second,
+	})
+	if s.cfg.Integrity.CheckInterval > 0 {
+		integrityCtx, cancel := context.WithCancel(context.Background())
+		s.stopIntegrity = cancel
+		go s.integrity.Run(integrityCtx)
+	}
+
+	// 任务附件存储
+	blobs, err := storage.NewBlobStore(s.cfg.Attachments.BlobStore())
 	if err != nil {
-		return fmt.Errorf("failed to init database: %w", err)
+		return fmt.Errorf("failed to init attachment store: %w", err)
 	}
-	defer db.Close()
+	s.taskHandler.SetAttachmentStore(blobs, handler.AttachmentPolicy{
+		MaxSize:      s.cfg.Attachments.MaxSize,
+		AllowedTypes: s.cfg.Attachments.AllowedTypes,
+	})
 
-	// 初始化表结构
-	if err := db.InitSchema(); err != nil {
-		return fmt.Errorf("failed to init schema: %w", err)
+	// 产物存储（转存的任务输出、任务归档）
+	artifacts, err := storage.NewBlobStore(s.cfg.Artifacts.BlobStore())
+	if err != nil {
+		return fmt.Errorf("failed to init artifact store: %w", err)
 	}
+	s.taskHandler.SetArtifactStore(artifacts)
 
-	taskRepo := repository.NewTaskRepository(db)
-	s.taskRepo = taskRepo
-	s.taskHandler = handler.NewTaskHandler(taskRepo)
+	// 敏感参数脱敏
+	redactor, err := redact.New(s.cfg.Redaction.SensitiveKeys)
+	if err != nil {
+		return fmt.Errorf("failed to init redaction: %w", err)
+	}
+	s.taskHandler.SetRedaction(redactor, s.cfg.Redaction.AdminUsers)
+
+	// 任务可见性：认证用户只能访问本人创建或所在团队的任务
+	s.taskHandler.SetAccessControl(s.cfg.Access.AdminUsers, s.cfg.Access.PublicUnassigned)
+
+	// 命名密钥，未配置主密钥时禁用
+	if s.cfg.Secrets.MasterKey != "" {
+		key, err := secrets.ParseMasterKey(s.cfg.Secrets.MasterKey)
+		if err != nil {
+			return fmt.Errorf("failed to init secrets: %w", err)
+		}
+		manager, err := secrets.NewManager(stores.secrets, key)
+		if err != nil {
+			return fmt.Errorf("failed to init secrets: %w", err)
+		}
+		s.taskHandler.SetSecretManager(manager)
+	}
 
 	// 启动 gRPC 服务器
 	if err := s.startGRPC(); err != nil {
@@ -98,11 +268,230 @@ func (s *Server) Start() error {
 	s.started = true
 	logger.Infof("Server started: gRPC=%s, HTTP=%s", s.cfg.GetGRPCAddr(), s.cfg.GetHTTPAddr())
 
+	// waitForShutdown 退出时需要重新获取启动锁，等待信号期间先释放
+	s.startMutex.Unlock()
 	s.waitForShutdown()
+	s.startMutex.Lock()
 
 	return nil
 }
 
+// storeSet 按配置打开的仓储，close 在服务退出时调用
+type storeSet struct {
+	tasks     repository.TaskStore
+	teams     repository.TeamStore
+	secrets   repository.SecretStore
+	databases map[string]*repository.SQLite // 可备份的 SQLite 数据库，主库为 ""、分片按命名空间，memory 存储时为空
+	close     func() error
+}
+
+// openStorage 按配置打开任务、团队和密钥仓储
+func (s *Server) openStorage() (*storeSet, error) {
+	if s.cfg.Server.Storage == "memory" {
+		logger.Warnf("Using in-memory storage, tasks will not be persisted")
+		taskRepo, teamRepo := repository.NewMemoryRepositories()
+		return &storeSet{
+			tasks:   taskRepo,
+			teams:   teamRepo,
+			secrets: repository.NewMemorySecretRepository(),
+			close:   func() error { return nil },
+		}, nil
+	}
+
+	// 任务敏感列加密
+	fields, err := newFieldEncryptor(s.cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init field encryption: %w", err)
+	}
+
+	// 获取数据库路径（支持环境变量 TASKFLOW_DB_PATH）
+	db, err := s.openSQLite(s.cfg.Server.DBPath, fields)
+	if err != nil {
+		return nil, err
+	}
+	dbs := []*repository.SQLite{db}
+	databases := map[string]*repository.SQLite{"": db}
+	closeAll := func() error {
+		var errs []error
+		for _, db := range dbs {
+			errs = append(errs, db.Close())
+		}
+		return errors.Join(errs...)
+	}
+
+	taskRepo := repository.NewTaskRepository(db)
+	teamRepo := repository.NewTeamRepository(db)
+	var tasks repository.TaskStore = taskRepo
+	taskRepos := []*repository.TaskRepository{taskRepo}
+
+	// 按命名空间分片的任务数据库，团队和密钥留在主数据库
+	if len(s.cfg.Sharding.Shards) > 0 {
+		shards := make(map[string]repository.TaskStore, len(s.cfg.Sharding.Shards))
+		for _, shard := range s.cfg.Sharding.Shards {
+			shardDB, err := s.openSQLite(shard.DBPath, fields)
+			if err != nil {
+				closeAll()
+				return nil, fmt.Errorf("shard %s: %w", shard.Namespace, err)
+			}
+			dbs = append(dbs, shardDB)
+			databases[shard.Namespace] = shardDB
+			shardRepo := repository.NewTaskRepository(shardDB)
+			shards[shard.Namespace] = shardRepo
+			taskRepos = append(taskRepos, shardRepo)
+		}
+		tasks = repository.NewShardedTaskStore(taskRepo, teamRepo, shards)
+		logger.Infof("Task sharding enabled: %d namespace shards", len(shards))
+	}
+
+	// 只读副本：列表、搜索和统计在延迟允许时读副本，写入和调度器读取走主库
+	stopMonitor := func() {}
+	if len(s.cfg.Database.ReadReplicas) > 0 {
+		replicas := make(map[string]repository.TaskStore, len(s.cfg.Database.ReadReplicas))
+		for _, replicaPath := range s.cfg.Database.ReadReplicas {
+			replicaDB, err := s.openReplica(replicaPath, fields)
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			dbs = append(dbs, replicaDB)
+			replicas[replicaPath] = repository.NewTaskRepository(replicaDB)
+		}
+		replicated := repository.NewReplicatedTaskStore(taskRepo, replicas)
+		ctx, cancel := context.WithCancel(context.Background())
+		stopMonitor = cancel
+		go replicated.Monitor(ctx, time.Duration(s.cfg.Database.ReplicaCheckInterval)*time.Millisecond)
+		tasks = replicated
+		logger.Infof("Read replicas enabled: %d replicas", len(replicas))
+	}
+
+	if fields != nil {
+		for _, repo := range taskRepos {
+			go rotateFieldEncryption(repo, fields.PrimaryKeyID())
+		}
+	}
+
+	return &storeSet{
+		tasks:     tasks,
+		teams:     teamRepo,
+		secrets:   repository.NewSecretRepository(db),
+		databases: databases,
+		close: func() error {
+			stopMonitor()
+			return closeAll()
+		},
+	}, nil
+}
+
+// openReplica 以只读方式打开副本数据库，副本的表结构由复制工具从主库同步，不执行迁移
+func (s *Server) openReplica(dbPath string, fields *repository.FieldEncryptor) (*repository.SQLite, error) {
+	dbPath = config.ExpandPath(dbPath)
+	db, err := repository.NewSQLite("file:" + dbPath + "?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read replica %s: %w", dbPath, err)
+	}
+	db.SetSlowQueryThreshold(time.Duration(s.cfg.Database.SlowQueryThreshold) * time.Millisecond)
+	db.SetFieldEncryptor(fields)
+	return db, nil
+}
+
+// openSQLite 打开 SQLite 数据库并执行迁移，fields 非 nil 时加密任务敏感列
+func (s *Server) openSQLite(dbPath string, fields *repository.FieldEncryptor) (*repository.SQLite, error) {
+	dbPath = config.ExpandPath(dbPath)
+
+	// 确保目录存在
+	dbDir := path2.Dir(dbPath)
+	if err := os.MkdirAll(dbDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	db, err := repository.NewSQLite(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init database: %w", err)
+	}
+	db.SetSlowQueryThreshold(time.Duration(s.cfg.Database.SlowQueryThreshold) * time.Millisecond)
+	db.SetFieldEncryptor(fields)
+
+	// 执行数据库迁移
+	migrations, err := db.Migrate()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database %s: %w", dbPath, err)
+	}
+	for _, m := range migrations {
+		logger.Infof("Applied database migration %d_%s to %s", m.Version, m.Name, dbPath)
+	}
+	return db, nil
+}
+
+// cacheTasks 为任务仓储加读穿缓存。memory 后端轮询任务事件，失效其他实例修改的任务
+func (s *Server) cacheTasks(stores *storeSet) {
+	cfg := s.cfg.Cache
+	ttl := time.Duration(cfg.TTL) * time.Second
+	logger.Infof("Task cache enabled: backend=%s, ttl=%s", cfg.Backend, ttl)
+
+	if cfg.Backend == "redis" {
+		cache := repository.NewRedisTaskCache(redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		}, "taskflow:task:", ttl)
+		closeStores := stores.close
+		stores.close = func() error {
+			cache.Close()
+			return closeStores()
+		}
+		stores.tasks = repository.NewCachedTaskStore(stores.tasks, cache)
+		return
+	}
+
+	cached := repository.NewCachedTaskStore(stores.tasks, repository.NewLRUTaskCache(cfg.Size, ttl))
+	stores.tasks = cached
+	if cfg.EventPollInterval > 0 && len(s.cfg.Sharding.Shards) > 0 {
+		logger.Warnf("Task cache event polling is not supported with sharding, entries expire after %s", ttl)
+	} else if cfg.EventPollInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopCache = cancel
+		go cached.FollowEvents(ctx, time.Duration(cfg.EventPollInterval)*time.Millisecond)
+	}
+}
+
+// statsStaleness 统计查询可容忍的只读副本延迟
+func (s *Server) statsStaleness() time.Duration {
+	return time.Duration(s.cfg.Database.ReplicaStatsMaxStaleness) * time.Second
+}
+
+// newFieldEncryptor 按配置创建任务敏感列加密器，未配置主密钥时返回 nil
+func newFieldEncryptor(cfg config.DatabaseConfig) (*repository.FieldEncryptor, error) {
+	if cfg.FieldEncryptionKey == "" {
+		return nil, nil
+	}
+	primary, err := repository.ParseFieldKey(cfg.FieldEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	previous := make([]repository.FieldKey, 0, len(cfg.FieldDecryptionKeys))
+	for _, k := range cfg.FieldDecryptionKeys {
+		key, err := repository.ParseFieldKey(k)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, key)
+	}
+	return repository.NewFieldEncryptor(primary, previous...)
+}
+
+// rotateFieldEncryption 把旧主密钥加密或未加密的任务敏感列改为由当前主密钥保护
+func rotateFieldEncryption(repo *repository.TaskRepository, keyID string) {
+	n, err := repo.RotateFieldEncryption(100)
+	if err != nil {
+		logger.Errorf("Failed to rotate field encryption to key %s: %v", keyID, err)
+		return
+	}
+	if n > 0 {
+		logger.Infof("Re-encrypted sensitive fields of %d tasks with key %s", n, keyID)
+	}
+}
+
 // startGRPC 启动gRPC服务
 func (s *Server) startGRPC() error {
 	lis, err := net.Listen("tcp", s.cfg.GetGRPCAddr())
@@ -110,9 +499,26 @@ func (s *Server) startGRPC() error {
 		return fmt.Errorf("failed to listen on gRPC: %w", err)
 	}
 
+	// 拦截器链：recovery → request ID → 日志 → 指标 → 并发限制
+	interceptors := []grpc_middleware.ServerOption{
+		grpc_middleware.WithRecovery(),
+		grpc_middleware.WithRequestID(),
+		grpc_middleware.WithLogger(nil),
+		grpc_middleware.WithMaxInFlight(s.cfg.Server.MaxConns),
+		grpc_middleware.WithMaxStreams(s.cfg.Server.MaxStreams),
+		grpc_middleware.WithAPIVersions(s.apiVersions()),
+	}
+	if s.cfg.Features.EnableMetrics {
+		interceptors = append(interceptors, grpc_middleware.WithMetrics())
+	}
+	opts, err := grpc_middleware.GetUnaryServerOptions(interceptors...)
+	if err != nil {
+		return fmt.Errorf("failed to build gRPC interceptors: %w", err)
+	}
+
 	// 创建 gRPC 服务器
-	s.grpcServer = grpc.NewServer()
-	
+	s.grpcServer = grpc.NewServer(opts...)
+
 	// 注册 TaskService
 	pb.RegisterTaskServiceServer(s.grpcServer, s.taskHandler)
 
@@ -134,6 +540,28 @@ func (s *Server) startHTTP() error {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	router := s.newRouter()
+
+	s.httpServer = &http.Server{
+		Addr:           s.cfg.GetHTTPAddr(),
+		Handler:        router,
+		ReadTimeout:    s.cfg.GetTimeout(),
+		WriteTimeout:   s.cfg.GetTimeout(),
+		MaxHeaderBytes: 1 << 20,
+	}
+
+	go func() {
+		logger.Infof("HTTP server listening on %s", s.cfg.GetHTTPAddr())
+		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("HTTP server error: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// newRouter 创建 HTTP 路由：公共中间件、健康检查、指标、调试和管理端点以及 API 路由
+func (s *Server) newRouter() *gin.Engine {
 	router := gin.New()
 	router.RemoveExtraSlash = true
 	router.Use(
@@ -141,90 +569,92 @@ func (s *Server) startHTTP() error {
 		middleware.Logger(),
 		middleware.RequestID(),
 		middleware.CORS(),
-		middleware.Timeout(s.cfg.GetTimeout()),
+		middleware.MaxInFlight(s.cfg.Server.MaxConns, "/health", "/metrics"),
+		middleware.Compress(middleware.CompressOptions{
+			MinSize:      s.cfg.Compression.MinSize,
+			Level:        s.cfg.Compression.Level,
+			ContentTypes: s.cfg.Compression.ContentTypes,
+		}),
 	)
 
+	// 超时控制只作用于普通请求：事件流、pprof 采样等长时间的请求注册在 Timeout 之外，由处理函数调整写超时
+	timeout := middleware.Timeout(s.cfg.GetTimeout())
+	limited := router.Group("/", timeout)
+
 	// 健康检查
-	router.GET("/health", func(c *gin.Context) {
+	limited.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
 	// Prometheus 指标端点
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	limited.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
-	// 注册 API 路由
-	if s.taskHandler != nil {
-		s.registerRoutes(router)
+	// 调试端点，须同时开启调试模式并配置令牌
+	if s.cfg.Server.EnableDebug {
+		if s.cfg.Server.DebugToken != "" {
+			registerDebugRoutes(router, s.cfg.Server.DebugToken, timeout)
+			logger.Infof("Debug endpoints enabled at /debug/pprof/ and /debug/runtime")
+		} else {
+			logger.Warnf("ENABLE_DEBUG is set but DEBUG_TOKEN is empty, debug endpoints are disabled")
+		}
 	}
 
-	s.httpServer = &http.Server{
-		Addr:           s.cfg.GetHTTPAddr(),
-		Handler:        router,
-		ReadTimeout:    s.cfg.GetTimeout(),
-		WriteTimeout:   s.cfg.GetTimeout(),
-		MaxHeaderBytes: 1 << 20,
+	// 管理端点，须配置令牌
+	if s.cfg.Server.AdminToken != "" {
+		s.registerAdminRoutes(limited)
+		logger.Infof("Admin endpoints enabled at /admin/backup, /admin/integrity and /admin/tasks/:id/dependencies")
 	}
 
-	go func() {
-		logger.Infof("HTTP server listening on %s", s.cfg.GetHTTPAddr())
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Errorf("HTTP server error: %v", err)
-		}
-	}()
-
-	return nil
-}
+	// 注册 API 路由
+	if s.taskHandler != nil {
+		s.registerRoutes(router, timeout)
+	}
 
-// registerRoutes 注册路由
-func (s *Server) registerRoutes(router *gin.Engine) {
-	// 任务列表
-	router.GET("/api/v1/tasks", s.handleListTasks)
-	router.POST("/api/v1/tasks", s.handleCreateTask)
-	
-	// 单个任务操作
-	router.GET("/api/v1/tasks/:id", s.handleGetTask)
-	router.PUT("/api/v1/tasks/:id", s.handleUpdateTask)
-	
-	// 任务统计
-	router.GET("/api/v1/tasks/stats", s.handleTaskStats)
+	return router
 }
 
 // handleCreateTask 创建任务
 func (s *Server) handleCreateTask(c *gin.Context) {
-	var req struct {
-		Name         string            `json:"name" binding:"required"`
-		Description  string            `json:"description"`
-		Priority     int32             `json:"priority"`
-		TaskType     string            `json:"task_type"`
-		InputParams  map[string]string `json:"input_params"`
-		Dependencies []string          `json:"dependencies"`
-		MaxRetries   int32             `json:"max_retries"`
-		CreatedBy    string            `json:"created_by"`
+	var req createTaskBody
+
+	if !errorcode.BindJSON(c, &req) {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+	task, err := s.taskHandler.CreateTask(c.Request.Context(), req.toPB())
+	if err != nil {
+		errorcode.HandleGinError(c, err)
 		return
 	}
 
-	pbReq := &pb.CreateTaskRequest{
-		Name:         req.Name,
-		Description:  req.Description,
-		Priority:     pb.TaskPriority(req.Priority),
-		TaskType:     req.TaskType,
-		InputParams:  req.InputParams,
-		Dependencies: req.Dependencies,
-		MaxRetries:   req.MaxRetries,
-		CreatedBy:    req.CreatedBy,
+	middleware.Respond(c, 201, task)
+}
+
+// handleExecuteTaskSync 创建任务并等待其结束，timeout 查询参数为最长等待时长
+func (s *Server) handleExecuteTaskSync(c *gin.Context) {
+	verr := errorcode.NewValidationError()
+	timeoutMs := bindWaitTimeout(c, verr, s.cfg.GetTimeout())
+	if verr.HasErrors() {
+		errorcode.HandleGinError(c, verr)
+		return
+	}
+
+	var req createTaskBody
+
+	if !errorcode.BindJSON(c, &req) {
+		return
 	}
 
-	task, err := s.taskHandler.CreateTask(c.Request.Context(), pbReq)
+	resp, err := s.taskHandler.ExecuteTaskSync(c.Request.Context(), &pb.ExecuteTaskSyncRequest{
+		Task:      req.toPB(),
+		TimeoutMs: timeoutMs,
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		errorcode.HandleGinError(c, err)
 		return
 	}
 
-	c.JSON(201, task)
+	middleware.Respond(c, 200, resp)
 }
 
 // handleListTasks 列出任务
@@ -232,33 +662,62 @@ func (s *Server) handleListTasks(c *gin.Context) {
 	page := int32(parseInt(c.Query("page"), 1))
 	pageSize := int32(parseInt(c.Query("page_size"), 20))
 	keyword := c.Query("keyword")
-	taskType := c.Query("type")
-	statusVal := c.Query("status")
-	priorityStr := c.Query("priority")
 
 	req := &pb.ListTasksRequest{
-		Page:     page,
-		PageSize: pageSize,
-		Keyword:  keyword,
-		TaskType: taskType,
+		Page:          page,
+		PageSize:      pageSize,
+		Keyword:       keyword,
+		TaskTypes:     queryList(c, "type"),
+		TeamId:        c.Query("team_id"),
+		CorrelationId: c.Query("correlation_id"),
+		SortBy:        c.Query("sort_by"),
+		SortDesc:      c.Query("sort_desc") == "true",
 	}
 
-	if statusVal != "" {
-		if v := parseInt(statusVal, -1); v > 0 {
-			req.StatusFilter = []pb.TaskStatus{pb.TaskStatus(v)}
+	// status 和 priority 可重复或以逗号分隔，匹配其中任意一个
+	verr := errorcode.NewValidationError()
+	for _, v := range queryList(c, "status") {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			verr.Add("status", "integer", "must be a status number")
+			continue
+		}
+		req.StatusFilter = append(req.StatusFilter, pb.TaskStatus(n))
+	}
+	for _, v := range queryList(c, "priority") {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			verr.Add("priority", "integer", "must be a priority number")
+			continue
 		}
+		req.Priorities = append(req.Priorities, pb.TaskPriority(n))
 	}
-	if priorityStr != "" {
-		req.Priority = pb.TaskPriority(parseInt(priorityStr, 0))
+	bindTimeQuery(c, verr, []timeQueryParam{
+		{"created_after", &req.CreatedAfter}, {"created_before", &req.CreatedBefore},
+		{"completed_after", &req.CompletedAfter}, {"completed_before", &req.CompletedBefore},
+		{"updated_since", &req.UpdatedSince},
+	})
+	if verr.HasErrors() {
+		errorcode.HandleGinError(c, verr)
+		return
 	}
 
 	resp, err := s.taskHandler.ListTasks(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		errorcode.HandleGinError(c, err)
 		return
 	}
 
-	c.JSON(200, resp)
+	// 列表的 ETag 由总数和本页任务的 ID、版本号计算，任何一个任务被写入或增删任务后随之变化
+	parts := []string{strconv.Itoa(int(resp.Total))}
+	for _, t := range resp.Tasks {
+		parts = append(parts, t.Id+"@"+strconv.FormatInt(t.Version, 10))
+	}
+	if middleware.NotModified(c, middleware.HashETag(parts...)) {
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
 }
 
 // handleGetTask 获取任务
@@ -269,88 +728,841 @@ func (s *Server) handleGetTask(c *gin.Context) {
 	req := &pb.GetTaskRequest{
 		Id:            id,
 		IncludeEvents: includeEvents,
+		Unredacted:    c.Query("unredacted") == "true",
+	}
+
+	// wait_for 可重复或以逗号分隔
+	verr := errorcode.NewValidationError()
+	for _, v := range queryList(c, "wait_for") {
+		status, err := model.ParseTaskStatus(v)
+		if err != nil {
+			verr.Add("wait_for", "enum", "must be a task status name such as SUCCEEDED or a status number")
+			continue
+		}
+		req.WaitFor = append(req.WaitFor, pb.TaskStatus(status))
+	}
+	if req.TimeoutMs = bindWaitTimeout(c, verr, s.cfg.GetTimeout()); req.TimeoutMs > 0 && len(req.WaitFor) == 0 {
+		verr.Add("timeout", "requires", "only applies together with wait_for")
+	}
+	if verr.HasErrors() {
+		errorcode.HandleGinError(c, verr)
+		return
 	}
 
 	task, err := s.taskHandler.GetTask(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		errorcode.HandleGinError(c, err)
 		return
 	}
 
-	c.JSON(200, task)
+	// 评论和清理过期事件不改变任务的版本号，带事件和评论的响应另由事件序号和每条评论的 ID、时间、内容计算 ETag
+	etag := middleware.VersionETag(task.Version)
+	if includeEvents {
+		parts := []string{strconv.FormatInt(task.Version, 10), strconv.Itoa(len(task.Events))}
+		for _, e := range task.Events {
+			parts = append(parts, strconv.FormatInt(e.Seq, 10))
+		}
+		for _, cm := range task.Comments {
+			parts = append(parts, cm.Id+"@"+strconv.FormatInt(cm.CreatedAt, 10), cm.Body)
+		}
+		etag = middleware.HashETag(parts...)
+	}
+	if middleware.NotModified(c, etag) {
+		return
+	}
+
+	middleware.Respond(c, 200, task)
 }
 
-// handleUpdateTask 更新任务
-func (s *Server) handleUpdateTask(c *gin.Context) {
+// handleExportTask 导出任务（含事件和评论）为 JSON 附件
+func (s *Server) handleExportTask(c *gin.Context) {
 	id := c.Param("id")
 
-	var req struct {
-		Status       int32             `json:"status"`
-		OutputResult map[string]string `json:"output_result"`
-		ErrorMessage string            `json:"error_message"`
-		RetryCount   int32             `json:"retry_count"`
+	task, err := s.taskHandler.GetTask(c.Request.Context(), &pb.GetTaskRequest{
+		Id:            id,
+		IncludeEvents: true,
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"code": 1001, "message": "invalid request: " + err.Error()})
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="task-%s.json"`, id))
+	c.JSON(200, task)
+}
+
+// handleArchiveTask 把任务导出写入产物存储
+func (s *Server) handleArchiveTask(c *gin.Context) {
+	key, size, err := s.taskHandler.ArchiveTask(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errorcode.HandleGinError(c, err)
 		return
 	}
 
-	pbReq := &pb.UpdateTaskRequest{
-		Id:           id,
-		Status:       pb.TaskStatus(req.Status),
-		OutputResult: req.OutputResult,
-		ErrorMessage: req.ErrorMessage,
-		RetryCount:   req.RetryCount,
+	middleware.Respond(c, 201, archiveResponse{Key: key, Size: size})
+}
+
+// handleGetTaskOutput 获取任务输出，包括已转存到产物存储的大输出
+func (s *Server) handleGetTaskOutput(c *gin.Context) {
+	rc, err := s.taskHandler.OpenTaskOutput(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
 	}
+	defer rc.Close()
 
-	task, err := s.taskHandler.UpdateTask(c.Request.Context(), pbReq)
+	c.DataFromReader(200, -1, "application/json", rc, nil)
+}
+
+// handleListTaskComments 列出任务评论
+func (s *Server) handleListTaskComments(c *gin.Context) {
+	resp, err := s.taskHandler.ListTaskComments(c.Request.Context(), &pb.ListTaskCommentsRequest{
+		TaskId: c.Param("id"),
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"code": 500, "message": err.Error()})
+		errorcode.HandleGinError(c, err)
 		return
 	}
 
-	c.JSON(200, task)
+	middleware.Respond(c, 200, resp)
 }
 
-// handleTaskStats 任务统计
-func (s *Server) handleTaskStats(c *gin.Context) {
-	// 获取各状态的任务数量
-	pending := model.TaskStatusPending
-	running := model.TaskStatusRunning
-	succeeded := model.TaskStatusSucceeded
-	failed := model.TaskStatusFailed
-	cancelled := model.TaskStatusCancelled
+// handleAddTaskComment 添加任务评论
+func (s *Server) handleAddTaskComment(c *gin.Context) {
+	var req addCommentBody
+
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
 
-	pendingCount, _ := s.taskRepo.Count(&pending)
-	runningCount, _ := s.taskRepo.Count(&running)
-	succeededCount, _ := s.taskRepo.Count(&succeeded)
-	failedCount, _ := s.taskRepo.Count(&failed)
-	cancelledCount, _ := s.taskRepo.Count(&cancelled)
-
-	total := pendingCount + runningCount + succeededCount + failedCount + cancelledCount
-
-	c.JSON(200, gin.H{
-		"total":      total,
-		"pending":    pendingCount,
-		"running":    runningCount,
-		"succeeded":  succeededCount,
-		"failed":     failedCount,
-		"cancelled":  cancelledCount,
+	comment, err := s.taskHandler.AddTaskComment(c.Request.Context(), &pb.AddTaskCommentRequest{
+		TaskId: c.Param("id"),
+		Author: req.Author,
+		Body:   req.Body,
 	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 201, comment)
 }
 
-// waitForShutdown 等待退出信号并优雅关闭
-func (s *Server) waitForShutdown() {
-	stopCh := make(chan os.Signal, 1)
-	signal.Notify(stopCh, syscall.SIGINT, syscall.SIGTERM)
-	<-stopCh
+// handleListAttachments 列出任务附件
+func (s *Server) handleListAttachments(c *gin.Context) {
+	resp, err := s.taskHandler.ListAttachments(c.Request.Context(), &pb.ListAttachmentsRequest{
+		TaskId: c.Param("id"),
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
 
-	logger.Info("Shutting down server...")
+	middleware.Respond(c, 200, resp)
+}
 
-	gracefulTimeout := 10 * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), gracefulTimeout)
-	defer cancel()
+// handleUploadAttachment 上传附件（multipart/form-data，文件字段名 file）
+func (s *Server) handleUploadAttachment(c *gin.Context) {
+	maxSize := s.cfg.Attachments.MaxSize
+	if maxSize <= 0 {
+		maxSize = config.DefaultAttachmentMaxSize
+	}
+	// 为 multipart 头部预留 1MB
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodePayloadTooLarge, fmt.Sprintf("attachment exceeds %d bytes", maxSize))
+			return
+		}
+		errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodeInvalidParam, "file is required: "+err.Error())
+		return
+	}
+	if fileHeader.Size > maxSize {
+		errorcode.HandleGinErrorWithCode(c, errorcode.ErrCodePayloadTooLarge, fmt.Sprintf("attachment exceeds %d bytes", maxSize))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+	defer file.Close()
+
+	attachment, err := s.taskHandler.StoreAttachment(c.Request.Context(), c.Param("id"),
+		fileHeader.Filename, fileHeader.Header.Get("Content-Type"), file, c.PostForm("uploaded_by"))
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 201, attachment)
+}
+
+// handleDownloadAttachment 下载附件内容
+func (s *Server) handleDownloadAttachment(c *gin.Context) {
+	attachment, rc, err := s.taskHandler.OpenAttachment(c.Request.Context(), c.Param("id"), c.Param("attachment_id"))
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(200, attachment.Size, attachment.ContentType, rc, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}),
+		"ETag":                `"` + attachment.Checksum + `"`,
+	})
+}
+
+// handleDeleteAttachment 删除附件
+func (s *Server) handleDeleteAttachment(c *gin.Context) {
+	_, err := s.taskHandler.DeleteAttachment(c.Request.Context(), &pb.DeleteAttachmentRequest{
+		TaskId:       c.Param("id"),
+		AttachmentId: c.Param("attachment_id"),
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	c.Status(204)
+}
+
+// handleGetWorkerPool 获取工作池状态
+func (s *Server) handleGetWorkerPool(c *gin.Context) {
+	resp, err := s.taskHandler.GetWorkerPool(c.Request.Context(), &pb.GetWorkerPoolRequest{})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleResizeWorkerPool 调整工作池大小
+func (s *Server) handleResizeWorkerPool(c *gin.Context) {
+	var req resizeWorkerPoolBody
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	resp, err := s.taskHandler.ResizeWorkerPool(c.Request.Context(), &pb.ResizeWorkerPoolRequest{Size: req.Size})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleGetSchedulingDecision 说明任务当前为什么没有运行
+func (s *Server) handleGetSchedulingDecision(c *gin.Context) {
+	resp, err := s.taskHandler.GetSchedulingDecision(c.Request.Context(), &pb.GetSchedulingDecisionRequest{TaskId: c.Param("id")})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleGetAllowedTransitions 任务当前可以转换到的状态，以及调用者可以触发其中哪些
+func (s *Server) handleGetAllowedTransitions(c *gin.Context) {
+	resp, err := s.taskHandler.GetAllowedTransitions(c.Request.Context(), &pb.GetAllowedTransitionsRequest{TaskId: c.Param("id")})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleGetTaskTimeline 与任务通过依赖相连的任务的时间线
+func (s *Server) handleGetTaskTimeline(c *gin.Context) {
+	s.respondTimeline(c, &pb.GetTaskTimelineRequest{TaskId: c.Param("id")})
+}
+
+// handleGetCorrelationTimeline 同一请求创建的任务的时间线
+func (s *Server) handleGetCorrelationTimeline(c *gin.Context) {
+	s.respondTimeline(c, &pb.GetTaskTimelineRequest{CorrelationId: c.Query("correlation_id")})
+}
+
+// respondTimeline 查询时间线并写入响应
+func (s *Server) respondTimeline(c *gin.Context, req *pb.GetTaskTimelineRequest) {
+	resp, err := s.taskHandler.GetTaskTimeline(c.Request.Context(), req)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleCancelWorkflow 取消与任务通过依赖相连的工作流
+func (s *Server) handleCancelWorkflow(c *gin.Context) {
+	var req cancelWorkflowBody
+	if c.Request.ContentLength != 0 && !errorcode.BindJSON(c, &req) {
+		return
+	}
+	s.respondCancelWorkflow(c, &pb.CancelWorkflowRequest{TaskId: c.Param("id"), Reason: req.Reason})
+}
+
+// handleCancelCorrelationWorkflow 取消同一请求创建的任务
+func (s *Server) handleCancelCorrelationWorkflow(c *gin.Context) {
+	var req cancelCorrelationWorkflowBody
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+	s.respondCancelWorkflow(c, &pb.CancelWorkflowRequest{CorrelationId: req.CorrelationID, Reason: req.Reason})
+}
+
+// respondCancelWorkflow 取消工作流并写入响应
+func (s *Server) respondCancelWorkflow(c *gin.Context, req *pb.CancelWorkflowRequest) {
+	resp, err := s.taskHandler.CancelWorkflow(c.Request.Context(), req)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleListSchedulerInstances 列出调度器实例
+func (s *Server) handleListSchedulerInstances(c *gin.Context) {
+	resp, err := s.taskHandler.ListSchedulerInstances(c.Request.Context(), &pb.ListSchedulerInstancesRequest{
+		IncludeStale: c.Query("include_stale") == "true",
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handlePlanSchedule 模拟调度一组任务定义，不创建任务
+func (s *Server) handlePlanSchedule(c *gin.Context) {
+	var req planScheduleBody
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	pbReq := &pb.PlanScheduleRequest{Workers: req.Workers, DefaultDurationMs: req.DefaultDurationMs}
+	for _, t := range req.Tasks {
+		pbReq.Tasks = append(pbReq.Tasks, &pb.PlanTask{
+			Id:                  t.ID,
+			Name:                t.Name,
+			TaskType:            t.TaskType,
+			Priority:            pb.TaskPriority(t.Priority),
+			Dependencies:        t.Dependencies,
+			EstimatedDurationMs: t.EstimatedDurationMs,
+		})
+	}
+	resp, err := s.taskHandler.PlanSchedule(c.Request.Context(), pbReq)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleGetTaskLogs 分页获取任务执行日志
+func (s *Server) handleGetTaskLogs(c *gin.Context) {
+	resp, err := s.taskHandler.GetTaskLogs(c.Request.Context(), &pb.GetTaskLogsRequest{
+		TaskId:   c.Param("id"),
+		AfterSeq: int64(parseInt(c.Query("after_seq"), 0)),
+		Limit:    int32(parseInt(c.Query("limit"), 0)),
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleListTaskEvents 分页获取任务状态事件
+func (s *Server) handleListTaskEvents(c *gin.Context) {
+	req := &pb.ListTaskEventsRequest{
+		TaskId:   c.Param("id"),
+		AfterSeq: int64(parseInt(c.Query("after_seq"), 0)),
+		Limit:    int32(parseInt(c.Query("limit"), 0)),
+	}
+	verr := errorcode.NewValidationError()
+	bindTimeQuery(c, verr, []timeQueryParam{
+		{"timestamp_after", &req.TimestampAfter}, {"timestamp_before", &req.TimestampBefore},
+	})
+	if verr.HasErrors() {
+		errorcode.HandleGinError(c, verr)
+		return
+	}
+
+	resp, err := s.taskHandler.ListTaskEvents(c.Request.Context(), req)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleTailTaskLogs 以 Server-Sent Events 跟踪任务日志，任务结束后发送 end 事件
+func (s *Server) handleTailTaskLogs(c *gin.Context) {
+	started := false
+	startStream := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+	}
+
+	clearWriteDeadline(c)
+	err := s.taskHandler.TailLogs(c.Request.Context(), c.Param("id"), int64(parseInt(c.Query("after_seq"), 0)),
+		func(line *pb.TaskLogLine) error {
+			startStream()
+			c.SSEvent("log", line)
+			c.Writer.Flush()
+			return nil
+		})
+	if err != nil {
+		if !started {
+			errorcode.HandleGinError(c, err)
+			return
+		}
+		c.SSEvent("error", gin.H{"message": err.Error()})
+		return
+	}
+
+	startStream()
+	c.SSEvent("end", gin.H{})
+}
+
+// handleGetTasks 按 ID 批量获取任务
+func (s *Server) handleGetTasks(c *gin.Context) {
+	var req getTasksBody
+
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	resp, err := s.taskHandler.GetTasks(c.Request.Context(), &pb.GetTasksRequest{Ids: req.IDs})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleUpdateTask 更新任务，If-Match 指定任务须处于的版本
+func (s *Server) handleUpdateTask(c *gin.Context) {
+	id := c.Param("id")
+	expectedVersion, err := middleware.IfMatchVersion(c)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	var req updateTaskBody
+
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	pbReq := &pb.UpdateTaskRequest{
+		Id:           id,
+		Status:       pb.TaskStatus(req.Status),
+		OutputResult: req.OutputResult,
+		ErrorMessage: req.ErrorMessage,
+		RetryCount:   req.RetryCount,
+		Name:         req.Name,
+		Description:  req.Description,
+		Priority:     pb.TaskPriority(req.Priority),
+		InputParams:  req.InputParams,
+		MaxRetries:   req.MaxRetries,
+		Labels:       req.Labels,
+
+		ExpectedVersion: expectedVersion,
+	}
+	// update_mask 与 FieldMask 的 JSON 形式一致，为逗号分隔的字段名
+	if paths := splitList(req.UpdateMask); len(paths) > 0 {
+		pbReq.UpdateMask = &fieldmaskpb.FieldMask{Paths: paths}
+	}
+
+	task, err := s.taskHandler.UpdateTask(c.Request.Context(), pbReq)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	c.Header("ETag", middleware.VersionETag(task.Version))
+	middleware.Respond(c, 200, task)
+}
+
+// rerunModes REST 请求中的重新运行方式
+var rerunModes = map[string]pb.RerunMode{
+	"":      pb.RerunMode_RERUN_MODE_RESET,
+	"reset": pb.RerunMode_RERUN_MODE_RESET,
+	"clone": pb.RerunMode_RERUN_MODE_CLONE,
+}
+
+// handleRerunTask 重新运行已结束的任务，If-Match 指定原任务须处于的版本
+func (s *Server) handleRerunTask(c *gin.Context) {
+	expectedVersion, err := middleware.IfMatchVersion(c)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+	var req rerunTaskBody
+	if c.Request.ContentLength != 0 && !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	task, err := s.taskHandler.RerunTask(c.Request.Context(), &pb.RerunTaskRequest{
+		Id:              c.Param("id"),
+		Mode:            rerunModes[req.Mode],
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	// 原地重置时响应即原任务的新状态；克隆出的新任务不是请求的资源，不设置 ETag
+	if task.Id == c.Param("id") {
+		c.Header("ETag", middleware.VersionETag(task.Version))
+	}
+	middleware.Respond(c, 200, task)
+}
+
+// handleDeleteTask 删除任务，force=true 时强制删除仍被依赖的任务；If-Match 指定任务须处于的版本
+func (s *Server) handleDeleteTask(c *gin.Context) {
+	expectedVersion, err := middleware.IfMatchVersion(c)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	_, err = s.taskHandler.DeleteTask(c.Request.Context(), &pb.DeleteTaskRequest{
+		Id:              c.Param("id"),
+		Force:           c.Query("force") == "true",
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleSignalTask 向等待输入的任务送达信号，If-Match 指定任务须处于的版本
+func (s *Server) handleSignalTask(c *gin.Context) {
+	expectedVersion, err := middleware.IfMatchVersion(c)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+	var req signalTaskBody
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	task, err := s.taskHandler.SignalTask(c.Request.Context(), &pb.SignalTaskRequest{
+		Id:              c.Param("id"),
+		Payload:         req.Payload,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	c.Header("ETag", middleware.VersionETag(task.Version))
+	middleware.Respond(c, 200, task)
+}
+
+// handleSetTaskSubStatus 外部执行器上报运行中任务进入的自定义阶段
+func (s *Server) handleSetTaskSubStatus(c *gin.Context) {
+	var req subStatusBody
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	task, err := s.taskHandler.SetTaskSubStatus(c.Request.Context(), &pb.SetTaskSubStatusRequest{
+		Id:        c.Param("id"),
+		SubStatus: req.SubStatus,
+		Message:   req.Message,
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	c.Header("ETag", middleware.VersionETag(task.Version))
+	middleware.Respond(c, 200, task)
+}
+
+// handleDurationStats 各任务类型的执行耗时统计
+func (s *Server) handleDurationStats(c *gin.Context) {
+	resp, err := s.taskHandler.GetDurationStats(c.Request.Context(), &pb.GetDurationStatsRequest{
+		TaskTypes:      c.QueryArray("task_type"),
+		Window:         int32(parseInt(c.Query("window"), 0)),
+		IncludeSamples: c.Query("include_samples") == "true",
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleSLAReport SLA 报告
+func (s *Server) handleSLAReport(c *gin.Context) {
+	req := &pb.GetSLAReportRequest{TaskType: c.Query("task_type")}
+	verr := errorcode.NewValidationError()
+	bindTimeQuery(c, verr, []timeQueryParam{{"from", &req.From}, {"to", &req.To}})
+	if verr.HasErrors() {
+		errorcode.HandleGinError(c, verr)
+		return
+	}
+
+	resp, err := s.taskHandler.GetSLAReport(c.Request.Context(), req)
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleGetChangeFeed 全局变更流
+func (s *Server) handleGetChangeFeed(c *gin.Context) {
+	resp, err := s.taskHandler.GetChangeFeed(c.Request.Context(), &pb.GetChangeFeedRequest{
+		SinceSeq: int64(parseInt(c.Query("since_seq"), 0)),
+		Limit:    int32(parseInt(c.Query("limit"), 0)),
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleSnapshotTasks 任务快照分页
+func (s *Server) handleSnapshotTasks(c *gin.Context) {
+	resp, err := s.taskHandler.SnapshotTasks(c.Request.Context(), &pb.SnapshotTasksRequest{
+		Cursor:   int64(parseInt(c.Query("cursor"), 0)),
+		AfterId:  c.Query("after_id"),
+		PageSize: int32(parseInt(c.Query("page_size"), 0)),
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleStreamChanges 以 Server-Sent Events 推送变更，直到客户端断开
+func (s *Server) handleStreamChanges(c *gin.Context) {
+	started := false
+	startStream := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+	}
+
+	clearWriteDeadline(c)
+	err := s.taskHandler.FollowChanges(c.Request.Context(), int64(parseInt(c.Query("since_seq"), 0)),
+		func(change *pb.TaskChange) error {
+			startStream()
+			c.SSEvent("change", change)
+			c.Writer.Flush()
+			return nil
+		})
+	if err != nil && c.Request.Context().Err() == nil {
+		if !started {
+			errorcode.HandleGinError(c, err)
+			return
+		}
+		c.SSEvent("error", gin.H{"message": err.Error()})
+	}
+}
+
+// clearWriteDeadline 清除 http.Server 的 WriteTimeout，事件流在 SERVER_TIMEOUT 之后仍可继续推送；
+// 不支持写超时的 ResponseWriter（如测试中的 ResponseRecorder）忽略
+func clearWriteDeadline(c *gin.Context) {
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+}
+
+// handleTaskStats 任务统计
+func (s *Server) handleTaskStats(c *gin.Context) {
+	// 获取各状态的任务数量
+	pending := model.TaskStatusPending
+	running := model.TaskStatusRunning
+	succeeded := model.TaskStatusSucceeded
+	failed := model.TaskStatusFailed
+	cancelled := model.TaskStatusCancelled
+	skipped := model.TaskStatusSkipped
+	waitingInput := model.TaskStatusWaitingInput
+
+	reads := repository.StaleReads(s.taskRepo, s.statsStaleness())
+	pendingCount, _ := reads.Count(&pending)
+	runningCount, _ := reads.Count(&running)
+	succeededCount, _ := reads.Count(&succeeded)
+	failedCount, _ := reads.Count(&failed)
+	cancelledCount, _ := reads.Count(&cancelled)
+	skippedCount, _ := reads.Count(&skipped)
+	waitingInputCount, _ := reads.Count(&waitingInput)
+
+	total := pendingCount + runningCount + succeededCount + failedCount + cancelledCount + skippedCount + waitingInputCount
+
+	middleware.Respond(c, 200, taskStatsResponse{
+		Total:        total,
+		Pending:      pendingCount,
+		Running:      runningCount,
+		Succeeded:    succeededCount,
+		Failed:       failedCount,
+		Cancelled:    cancelledCount,
+		Skipped:      skippedCount,
+		WaitingInput: waitingInputCount,
+	})
+}
+
+// handleCreateTeam 创建团队
+func (s *Server) handleCreateTeam(c *gin.Context) {
+	var req createTeamBody
+
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	team, err := s.taskHandler.CreateTeam(c.Request.Context(), &pb.CreateTeamRequest{
+		Name:      req.Name,
+		Members:   req.Members,
+		CreatedBy: req.CreatedBy,
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 201, team)
+}
+
+// handleListTeams 列出用户所在团队
+func (s *Server) handleListTeams(c *gin.Context) {
+	resp, err := s.taskHandler.ListTeams(c.Request.Context(), &pb.ListTeamsRequest{UserId: c.Query("user_id")})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handleAddTeamMember 添加团队成员
+func (s *Server) handleAddTeamMember(c *gin.Context) {
+	var req addTeamMemberBody
+
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	team, err := s.taskHandler.AddTeamMember(c.Request.Context(), &pb.TeamMemberRequest{
+		TeamId: c.Param("id"),
+		UserId: req.UserID,
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, team)
+}
+
+// handleRemoveTeamMember 移除团队成员
+func (s *Server) handleRemoveTeamMember(c *gin.Context) {
+	team, err := s.taskHandler.RemoveTeamMember(c.Request.Context(), &pb.TeamMemberRequest{
+		TeamId: c.Param("id"),
+		UserId: c.Param("user_id"),
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, team)
+}
+
+// handleListSecrets 列出密钥元数据
+func (s *Server) handleListSecrets(c *gin.Context) {
+	resp, err := s.taskHandler.ListSecrets(c.Request.Context(), &pb.ListSecretsRequest{})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, resp)
+}
+
+// handlePutSecret 创建或覆盖密钥
+func (s *Server) handlePutSecret(c *gin.Context) {
+	var req putSecretBody
+
+	if !errorcode.BindJSON(c, &req) {
+		return
+	}
+
+	secret, err := s.taskHandler.PutSecret(c.Request.Context(), &pb.PutSecretRequest{
+		Name:      c.Param("name"),
+		Value:     req.Value,
+		CreatedBy: req.CreatedBy,
+	})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	middleware.Respond(c, 200, secret)
+}
+
+// handleDeleteSecret 删除密钥
+func (s *Server) handleDeleteSecret(c *gin.Context) {
+	_, err := s.taskHandler.DeleteSecret(c.Request.Context(), &pb.DeleteSecretRequest{Name: c.Param("name")})
+	if err != nil {
+		errorcode.HandleGinError(c, err)
+		return
+	}
+
+	c.Status(204)
+}
+
+// waitForShutdown 等待退出信号并优雅关闭
+func (s *Server) waitForShutdown() {
+	stopCh := make(chan os.Signal, 1)
+	signal.Notify(stopCh, syscall.SIGINT, syscall.SIGTERM)
+	<-stopCh
+
+	logger.Info("Shutting down server...")
+
+	gracefulTimeout := 10 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), gracefulTimeout)
+	defer cancel()
 
 	s.startMutex.Lock()
 	s.started = false
@@ -371,6 +1583,32 @@ func (s *Server) waitForShutdown() {
 		}
 	}
 
+	// 停止发件箱中继，未投递的事件保留在发件箱中，下次启动后继续投递
+	if s.stopRelay != nil {
+		s.stopRelay()
+	}
+	if s.stopSLA != nil {
+		s.stopSLA()
+	}
+	if s.stopQueueTime != nil {
+		s.stopQueueTime()
+	}
+	if s.stopWorkflow != nil {
+		s.stopWorkflow()
+	}
+	if s.stopAnomaly != nil {
+		s.stopAnomaly()
+	}
+	if s.stopEvents != nil {
+		s.stopEvents()
+	}
+	if s.stopCache != nil {
+		s.stopCache()
+	}
+	if s.stopIntegrity != nil {
+		s.stopIntegrity()
+	}
+
 	// 同步日志
 	logger.Sync()
 	logger.Info("Server stopped")
@@ -381,6 +1619,99 @@ func (s *Server) GetHTTPAddr() string {
 	return s.cfg.GetHTTPAddr()
 }
 
+// queryList 读取可重复出现、也可以逗号分隔的查询参数，忽略空值
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, v := range c.QueryArray(name) {
+		values = append(values, splitList(v)...)
+	}
+	return values
+}
+
+// splitList 拆分逗号分隔的列表，忽略空值
+func splitList(s string) []string {
+	var values []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// timeQueryParam 时间查询参数名及解析结果（Unix 秒）的写入位置
+type timeQueryParam struct {
+	name string
+	dst  *int64
+}
+
+// bindTimeQuery 解析时间范围查询参数，不带偏移量的本地时间按 tz 参数（IANA 时区名，默认 UTC）解释，
+// 无法解析的参数记录到 verr
+func bindTimeQuery(c *gin.Context, verr *errorcode.ValidationError, params []timeQueryParam) {
+	loc, err := parseLocation(c.Query("tz"))
+	if err != nil {
+		verr.Add("tz", "timezone", "must be an IANA time zone name such as Asia/Shanghai")
+		return
+	}
+	for _, p := range params {
+		v, err := parseTimeQuery(c.Query(p.name), loc)
+		if err != nil {
+			verr.Add(p.name, "time", "must be Unix seconds, an RFC3339 timestamp or a local time such as 2006-01-02T15:04:05")
+			continue
+		}
+		*p.dst = v
+	}
+}
+
+// parseLocation 解析时区名，未设置时为 UTC
+func parseLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// localTimeLayouts 不带时区偏移量的时间格式，按请求指定的时区解释
+var localTimeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// parseTimeQuery 解析时间查询参数，支持 Unix 秒、RFC3339 和 loc 时区的本地时间，返回 Unix 秒，未设置时返回 0
+func parseTimeQuery(s string, loc *time.Location) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return sec, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q", s)
+}
+
+// bindWaitTimeout 解析等待时长查询参数 timeout（Go 时长格式，如 30s、2m），返回毫秒数，未设置时返回 0。
+// 等待受请求超时 limit 限制（0 表示不限制），不短于 limit 的时长和无法解析的参数记录到 verr
+func bindWaitTimeout(c *gin.Context, verr *errorcode.ValidationError, limit time.Duration) int64 {
+	v := c.Query("timeout")
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		verr.Add("timeout", "duration", "must be a positive duration such as 30s")
+		return 0
+	}
+	if limit > 0 && d >= limit {
+		verr.Add("timeout", "max", fmt.Sprintf("must be shorter than the request timeout %s", limit))
+		return 0
+	}
+	return max(d.Milliseconds(), 1)
+}
+
 // parseInt 解析整数
 func parseInt(s string, defaultVal int) int {
 	if s == "" {
@@ -393,3 +1724,4 @@ func parseInt(s string, defaultVal int) int {
 	}
 	return n
 }
+
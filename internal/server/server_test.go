@@ -0,0 +1,301 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"taskflow/internal/config"
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/handler"
+	"taskflow/internal/middleware"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+	pb "taskflow/proto"
+)
+
+func TestHandleDeleteTask(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, teams := repository.NewMemoryRepositories()
+	s := &Server{taskHandler: handler.NewTaskHandler(repo, teams)}
+	router := gin.New()
+	router.DELETE("/tasks/:id", s.handleDeleteTask)
+
+	now := time.Now()
+	for _, task := range []*model.Task{
+		{ID: "upstream", Name: "upstream", Status: model.TaskStatusFailed, CreatedAt: now, UpdatedAt: now},
+		{ID: "downstream", Name: "downstream", Status: model.TaskStatusPending, Dependencies: []string{"upstream"}, CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("Create(%s): %v", task.ID, err)
+		}
+	}
+	upstream, _ := repo.GetByID("upstream")
+
+	do := func(target, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, target, nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 仍被未结束的任务依赖时返回 409，errors 列出这些任务
+	w := do("/tasks/upstream", "")
+	var body errorcode.GinErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d %s", w.Code, w.Body.String())
+	}
+	if body.Code != errorcode.ErrCodeTaskHasDependents || body.Reason != "TASK_HAS_DEPENDENTS" || len(body.Errors) != 1 ||
+		body.Errors[0] != (errorcode.FieldViolation{Field: "downstream", Rule: "active_dependent", Message: "downstream is PENDING"}) {
+		t.Errorf("unexpected error response: %s", w.Body.String())
+	}
+
+	for _, tt := range []struct {
+		target, ifMatch string
+		want            int
+	}{
+		{"/tasks/missing", "", http.StatusNotFound},
+		{"/tasks/upstream?force=true", `"99"`, http.StatusPreconditionFailed},
+		{"/tasks/upstream?force=true", middleware.VersionETag(upstream.Version), http.StatusNoContent},
+		{"/tasks/upstream?force=true", "", http.StatusNotFound},
+	} {
+		if w := do(tt.target, tt.ifMatch); w.Code != tt.want {
+			t.Errorf("DELETE %s (If-Match %q): got %d %s, want %d", tt.target, tt.ifMatch, w.Code, w.Body.String(), tt.want)
+		}
+	}
+	if got, _ := repo.GetByID("downstream"); len(got.Dependencies) != 0 {
+		t.Errorf("expected the dependency removed from downstream, got %v", got.Dependencies)
+	}
+}
+
+// newTestHTTPServer 按 cfg 启动完整的 HTTP 路由，ReadTimeout、WriteTimeout 同 startHTTP
+func newTestHTTPServer(t *testing.T, cfg config.ServerConfig) (*handler.TaskHandler, *repository.MemoryTaskRepository, *httptest.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo, teams := repository.NewMemoryRepositories()
+	h := handler.NewTaskHandler(repo, teams)
+	s := &Server{cfg: &config.Config{Server: cfg}, taskHandler: h}
+	srv := httptest.NewUnstartedServer(s.newRouter())
+	srv.Config.ReadTimeout = s.cfg.GetTimeout()
+	srv.Config.WriteTimeout = s.cfg.GetTimeout()
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return h, repo, srv
+}
+
+// readEvents 逐行读取事件流，读到包含 want 的行时返回，流结束或超时时测试失败
+func readEvents(t *testing.T, lines <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream closed before %q", want)
+			}
+			if strings.Contains(line, want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
+
+// streamLines 在后台逐行读取响应体
+func streamLines(resp *http.Response) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+func TestStreamChanges_OutlivesRequestTimeout(t *testing.T) {
+	h, _, srv := newTestHTTPServer(t, config.ServerConfig{Timeout: 1})
+	ctx := context.Background()
+	if _, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "early"}); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/changes/stream")
+	if err != nil {
+		t.Fatalf("GET /changes/stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	lines := streamLines(resp)
+	readEvents(t, lines, "early")
+
+	// 超过请求超时和写超时之后的变更仍推送到同一连接
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := h.CreateTask(ctx, &pb.CreateTaskRequest{Name: "late"}); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	readEvents(t, lines, "late")
+}
+
+func TestTailTaskLogs_OutlivesRequestTimeout(t *testing.T) {
+	h, repo, srv := newTestHTTPServer(t, config.ServerConfig{Timeout: 1})
+	task, err := h.CreateTask(context.Background(), &pb.CreateTaskRequest{Name: "tail"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	appendLog := func(seq int64, line string) {
+		l := model.TaskLogLine{TaskID: task.Id, Seq: seq, Attempt: 1, Timestamp: time.Now(), Line: line}
+		if err := repo.AppendLogs([]model.TaskLogLine{l}); err != nil {
+			t.Fatalf("AppendLogs: %v", err)
+		}
+		h.PublishTaskLog(l)
+	}
+	appendLog(1, "first")
+
+	resp, err := http.Get(srv.URL + "/api/v1/tasks/" + task.Id + "/logs/stream")
+	if err != nil {
+		t.Fatalf("GET /logs/stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	lines := streamLines(resp)
+	readEvents(t, lines, "first")
+
+	// 运行时间超过请求超时的任务，日志仍推送到同一连接
+	time.Sleep(1500 * time.Millisecond)
+	appendLog(2, "second")
+	readEvents(t, lines, "second")
+}
+
+func TestDebugProfile_OutlivesRequestTimeout(t *testing.T) {
+	_, _, srv := newTestHTTPServer(t, config.ServerConfig{Timeout: 1, EnableDebug: true, DebugToken: "debug-secret"})
+
+	// 采样时长超过请求超时和写超时，仍返回完整的 profile
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/debug/pprof/profile?seconds=2", nil)
+	req.Header.Set("Authorization", "Bearer debug-secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /debug/pprof/profile: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/octet-stream" || len(body) == 0 {
+		t.Fatalf("unexpected profile response: %d %s (%d bytes, %v)", resp.StatusCode, resp.Header.Get("Content-Type"), len(body), err)
+	}
+}
+
+func TestGetTask_WaitLongerThanRequestTimeout(t *testing.T) {
+	h, _, srv := newTestHTTPServer(t, config.ServerConfig{Timeout: 1})
+	task, err := h.CreateTask(context.Background(), &pb.CreateTaskRequest{Name: "wait"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// 等待时长不短于请求超时时返回 400，而不是在请求超时处被截断
+	for _, tt := range []struct {
+		timeout string
+		want    int
+	}{
+		{"1s", http.StatusBadRequest},
+		{"5m", http.StatusBadRequest},
+		{"200ms", http.StatusOK},
+	} {
+		start := time.Now()
+		resp, err := http.Get(srv.URL + "/api/v1/tasks/" + task.Id + "?wait_for=SUCCEEDED&timeout=" + tt.timeout)
+		if err != nil {
+			t.Fatalf("GET timeout=%s: %v", tt.timeout, err)
+		}
+		var body errorcode.GinErrorResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("timeout=%s: got %d, want %d", tt.timeout, resp.StatusCode, tt.want)
+		}
+		if tt.want == http.StatusBadRequest && (len(body.Errors) != 1 || body.Errors[0].Field != "timeout" || body.Errors[0].Rule != "max") {
+			t.Errorf("timeout=%s: unexpected errors %+v", tt.timeout, body.Errors)
+		}
+		if tt.want == http.StatusOK && time.Since(start) < 200*time.Millisecond {
+			t.Errorf("timeout=%s: returned after %s", tt.timeout, time.Since(start))
+		}
+	}
+}
+
+func TestExecuteTaskSync_WaitLongerThanRequestTimeout(t *testing.T) {
+	_, repo, srv := newTestHTTPServer(t, config.ServerConfig{Timeout: 1})
+
+	// 等待时长不短于请求超时时返回 400，且不创建任务
+	resp, err := http.Post(srv.URL+"/api/v1/tasks/execute?timeout=2s", "application/json", strings.NewReader(`{"name":"sync"}`))
+	if err != nil {
+		t.Fatalf("POST /tasks/execute: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	if n, _ := repo.Count(nil); n != 0 {
+		t.Errorf("expected no task created, got %d", n)
+	}
+
+	resp, err = http.Post(srv.URL+"/api/v1/tasks/execute?timeout=200ms", "application/json", strings.NewReader(`{"name":"sync"}`))
+	if err != nil {
+		t.Fatalf("POST /tasks/execute: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandleGetTask_ETagWithEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo, teams := repository.NewMemoryRepositories()
+	s := &Server{cfg: &config.Config{}, taskHandler: handler.NewTaskHandler(repo, teams)}
+	router := gin.New()
+	router.GET("/tasks/:id", s.handleGetTask)
+
+	old := time.Now().Add(-time.Hour)
+	if err := repo.Create(&model.Task{ID: "t1", Name: "t1", Status: model.TaskStatusPending, CreatedAt: old, UpdatedAt: old}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	repo.AddEvent(&model.TaskEvent{ID: "e1", TaskID: "t1", Message: "note", Timestamp: old})
+	repo.AddComment(&model.TaskComment{ID: "c1", TaskID: "t1", Author: "alice", Body: "first", CreatedAt: old})
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/tasks/t1?include_events=true", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	etag := get("").Header().Get("ETag")
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for an unchanged task, got %d", w.Code)
+	}
+
+	// 创建事件被清理、新事件写入后事件数和版本号都不变，ETag 仍须变化
+	repo.CompactEvents(time.Now(), 1)
+	repo.AddEvent(&model.TaskEvent{ID: "e2", TaskID: "t1", Message: "note", Timestamp: time.Now()})
+	w := get(etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("expected a new ETag after the events changed, got %d %s", w.Code, w.Header().Get("ETag"))
+	}
+}
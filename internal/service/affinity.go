@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// SetSingletonTaskTypes 设置单例任务类型：同一类型同时最多一个任务 RUNNING（如备份），
+// 其余任务保持 PENDING 并记录 BlockedReason，运行中的任务结束后再调度。须在 Start 之前调用
+func (s *Scheduler) SetSingletonTaskTypes(taskTypes ...string) {
+	s.singletonTypes = make(map[string]bool, len(taskTypes))
+	for _, t := range taskTypes {
+		s.singletonTypes[t] = true
+	}
+}
+
+// isSingleton 任务类型是否为单例
+func (s *Scheduler) isSingleton(taskType string) bool {
+	return s.singletonTypes[taskType]
+}
+
+// SetAntiAffinity 设置一组反亲和的任务类型：其中任一类型的任务 RUNNING 时，组内其他类型的任务保持 PENDING
+// 并记录 BlockedReason（同类型之间不受限制，需要时配合 SetSingletonTaskTypes）。可多次调用设置多组，须在 Start 之前调用
+func (s *Scheduler) SetAntiAffinity(taskTypes ...string) {
+	if s.antiAffinity == nil {
+		s.antiAffinity = make(map[string][]string)
+	}
+	for _, t := range taskTypes {
+		for _, other := range taskTypes {
+			if other != t {
+				s.antiAffinity[t] = append(s.antiAffinity[t], other)
+			}
+		}
+	}
+}
+
+// exclusion 认领任务时的互斥条件：单例类型、反亲和类型和任务的分组键
+func (s *Scheduler) exclusion(task *model.Task) repository.Exclusion {
+	excl := repository.Exclusion{GroupKey: task.GroupKey}
+	if s.isSingleton(task.TaskType) {
+		excl.TaskTypes = append(excl.TaskTypes, task.TaskType)
+	}
+	excl.TaskTypes = append(excl.TaskTypes, s.antiAffinity[task.TaskType]...)
+	return excl
+}
+
+// claim 认领任务（PENDING -> RUNNING）。有互斥的任务在运行时不认领，
+// 记录阻塞原因并返回 repository.ErrExclusionBusy
+func (s *Scheduler) claim(task *model.Task) error {
+	excl := s.exclusion(task)
+	if excl.IsZero() {
+		return s.repo.UpdateStatusWithInstanceEvent(task.ID, model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "task scheduled", s.instanceID, s.executionMetadata(task))
+	}
+
+	err := s.repo.ClaimExclusive(task.ID, excl, "scheduler", "task scheduled", s.instanceID, s.executionMetadata(task))
+	if errors.Is(err, repository.ErrExclusionBusy) && task.BlockedReason != err.Error() {
+		if err := s.repo.SetBlockedReason(task.ID, err.Error()); err != nil {
+			logger.Errorf("Failed to record blocked reason for task %s: %v", task.ID, err)
+		}
+	}
+	return err
+}
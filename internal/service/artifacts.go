@@ -0,0 +1,49 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/storage"
+)
+
+// OutputKey 任务输出在产物存储中的 key
+func OutputKey(taskID string) string {
+	return fmt.Sprintf("tasks/%s/output.json", taskID)
+}
+
+// SetArtifactStore 设置产物存储，JSON 编码后超过 maxInline 字节的任务输出
+// 写入存储并只在数据库中保留对象 key（Task.OutputRef）。maxInline <= 0 表示始终转存，须在 Start 之前调用
+func (s *Scheduler) SetArtifactStore(store storage.BlobStore, maxInline int) {
+	s.artifacts = store
+	s.maxInlineOutput = maxInline
+}
+
+// storeOutput 设置任务输出，过大时转存到产物存储。写入失败时保留内联输出
+func (s *Scheduler) storeOutput(task *model.Task, result map[string]string) {
+	task.OutputResult = result
+	task.OutputRef = ""
+
+	store, maxInline := s.artifacts, s.maxInlineOutput
+	if store == nil || len(result) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil || (maxInline > 0 && len(data) <= maxInline) {
+		return
+	}
+
+	// 停止调度器时仍在收尾的任务也要写入输出，不使用调度器 ctx
+	key := OutputKey(task.ID)
+	if err := store.Put(context.Background(), key, bytes.NewReader(data), int64(len(data)), "application/json"); err != nil {
+		logger.Errorf("Failed to store output of task %s, keeping it inline: %v", task.ID, err)
+		return
+	}
+	task.OutputResult = nil
+	task.OutputRef = key
+}
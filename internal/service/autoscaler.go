@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/config"
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+)
+
+// 自动伸缩默认值
+const (
+	defaultAutoscaleInterval       = 5 * time.Second
+	defaultAutoscaleScaleDownAfter = 3  // 连续低利用率周期数
+	maxScalingDecisions            = 20 // 保留的最近伸缩决策数
+)
+
+// AutoscaleConfig 工作池自动伸缩配置
+type AutoscaleConfig struct {
+	MinWorkers     int
+	MaxWorkers     int
+	Interval       time.Duration // 评估间隔，默认 5s
+	ScaleDownAfter int           // 利用率低于 50% 持续多少个周期后缩容，默认 3
+}
+
+// AutoscaleConfigFromWorker 从 Worker 配置构造自动伸缩配置，未启用时返回 nil
+func AutoscaleConfigFromWorker(w config.WorkerConfig) *AutoscaleConfig {
+	if !w.AutoScale {
+		return nil
+	}
+	return &AutoscaleConfig{
+		MinWorkers: w.MinScale,
+		MaxWorkers: w.MaxScale,
+	}
+}
+
+// ScalingDecision 伸缩决策记录
+type ScalingDecision struct {
+	Time        time.Time `json:"time"`
+	From        int       `json:"from"`
+	To          int       `json:"to"`
+	Reason      string    `json:"reason"`
+	QueueDepth  int       `json:"queue_depth"`
+	BusyWorkers int       `json:"busy_workers"`
+	AvgExecMs   int64     `json:"avg_exec_ms"`
+}
+
+// AutoscaleStatus 自动伸缩状态
+type AutoscaleStatus struct {
+	MinWorkers  int               `json:"min_workers"`
+	MaxWorkers  int               `json:"max_workers"`
+	QueueDepth  int               `json:"queue_depth"`
+	BusyWorkers int               `json:"busy_workers"`
+	AvgExecMs   int64             `json:"avg_exec_ms"`
+	Decisions   []ScalingDecision `json:"decisions"` // 最近的伸缩决策，按时间升序
+}
+
+// autoscaler 根据队列深度和平均执行时间调整工作池大小
+type autoscaler struct {
+	cfg   AutoscaleConfig
+	pool  *WorkerPool
+	clock clock.Clock
+
+	mu        sync.Mutex
+	lowTicks  int
+	decisions []ScalingDecision
+}
+
+// newAutoscaler 创建自动伸缩器并补全默认值
+func newAutoscaler(cfg AutoscaleConfig, pool *WorkerPool) *autoscaler {
+	if cfg.MinWorkers < 1 {
+		cfg.MinWorkers = 1
+	}
+	if cfg.MaxWorkers < cfg.MinWorkers {
+		cfg.MaxWorkers = cfg.MinWorkers
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultAutoscaleInterval
+	}
+	if cfg.ScaleDownAfter <= 0 {
+		cfg.ScaleDownAfter = defaultAutoscaleScaleDownAfter
+	}
+	return &autoscaler{cfg: cfg, pool: pool, clock: clock.Real}
+}
+
+// run 周期性评估，直到 ctx 取消
+func (a *autoscaler) run(ctx context.Context) {
+	ticker := a.clock.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			a.evaluate()
+		}
+	}
+}
+
+// evaluate 评估一次并在需要时调整工作池，返回本次决策（未调整时为 nil）
+func (a *autoscaler) evaluate() *ScalingDecision {
+	size := a.pool.Size()
+	queue := a.pool.QueueDepth()
+	busy := a.pool.Busy()
+	avg := a.pool.AvgExecTime()
+
+	a.mu.Lock()
+	to, reason := a.decide(size, busy, queue, avg)
+	if to == size {
+		a.mu.Unlock()
+		return nil
+	}
+
+	decision := ScalingDecision{
+		Time:        a.clock.Now(),
+		From:        size,
+		To:          to,
+		Reason:      reason,
+		QueueDepth:  queue,
+		BusyWorkers: busy,
+		AvgExecMs:   avg.Milliseconds(),
+	}
+	a.decisions = append(a.decisions, decision)
+	if len(a.decisions) > maxScalingDecisions {
+		a.decisions = a.decisions[len(a.decisions)-maxScalingDecisions:]
+	}
+	a.mu.Unlock()
+
+	a.pool.Resize(to)
+
+	direction := "up"
+	if to < size {
+		direction = "down"
+	}
+	metrics.RecordWorkerScaling(direction)
+	metrics.RecordWorkerPoolSize(to)
+	logger.Infof("Worker pool scaled %s from %d to %d: %s", direction, size, to, reason)
+
+	return &decision
+}
+
+// decide 计算目标 worker 数（调用方持有 a.mu）
+func (a *autoscaler) decide(size, busy, queue int, avg time.Duration) (int, string) {
+	switch {
+	case size < a.cfg.MinWorkers:
+		return a.cfg.MinWorkers, "below minimum"
+	case size > a.cfg.MaxWorkers:
+		return a.cfg.MaxWorkers, "above maximum"
+	}
+
+	// 有积压且 worker 全忙：扩容到能在一个评估周期内消化积压
+	if queue > 0 && busy >= size {
+		a.lowTicks = 0
+		extra := queue
+		if avg > 0 {
+			extra = int(math.Ceil(float64(queue) * float64(avg) / float64(a.cfg.Interval)))
+		}
+		if extra < 1 {
+			extra = 1
+		}
+		to := size + extra
+		if to > a.cfg.MaxWorkers {
+			to = a.cfg.MaxWorkers
+		}
+		return to, fmt.Sprintf("queue depth %d with all %d workers busy, avg exec %s", queue, size, avg.Round(time.Millisecond))
+	}
+
+	// 利用率持续低于 50%：向忙碌 worker 数减半收缩
+	if queue == 0 && busy*2 < size {
+		a.lowTicks++
+		if a.lowTicks < a.cfg.ScaleDownAfter {
+			return size, ""
+		}
+		a.lowTicks = 0
+		to := (size + busy) / 2
+		if to < a.cfg.MinWorkers {
+			to = a.cfg.MinWorkers
+		}
+		return to, fmt.Sprintf("utilization %d/%d below 50%% for %d intervals", busy, size, a.cfg.ScaleDownAfter)
+	}
+
+	a.lowTicks = 0
+	return size, ""
+}
+
+// lowUtilizationTicks 已累计的低利用率周期数
+func (a *autoscaler) lowUtilizationTicks() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lowTicks
+}
+
+// setLowUtilizationTicks 恢复检查点中的低利用率周期数
+func (a *autoscaler) setLowUtilizationTicks(n int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lowTicks = n
+}
+
+// status 返回自动伸缩状态快照
+func (a *autoscaler) status() *AutoscaleStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return &AutoscaleStatus{
+		MinWorkers:  a.cfg.MinWorkers,
+		MaxWorkers:  a.cfg.MaxWorkers,
+		QueueDepth:  a.pool.QueueDepth(),
+		BusyWorkers: a.pool.Busy(),
+		AvgExecMs:   a.pool.AvgExecTime().Milliseconds(),
+		Decisions:   append([]ScalingDecision(nil), a.decisions...),
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// waitFor 轮询直到条件满足或超时
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("condition not met before timeout")
+}
+
+func TestAutoscaler_ScaleUpOnBacklog(t *testing.T) {
+	release := make(chan struct{})
+	pool := newWorkerPool(1, 10)
+	pool.Run(func(taskID string) { <-release })
+	defer pool.Stop()
+	defer close(release)
+
+	a := newAutoscaler(AutoscaleConfig{MinWorkers: 1, MaxWorkers: 3, Interval: time.Second}, pool)
+
+	for _, id := range []string{"t1", "t2", "t3", "t4", "t5"} {
+		pool.Submit(id)
+	}
+	waitFor(t, func() bool { return pool.Busy() == 1 })
+
+	// 积压 4 个任务、没有执行时间样本：一次扩到上限
+	decision := a.evaluate()
+	if decision == nil || decision.From != 1 || decision.To != 3 {
+		t.Fatalf("expected scale up 1 -> 3, got %+v", decision)
+	}
+	if pool.Size() != 3 {
+		t.Errorf("expected pool size 3, got %d", pool.Size())
+	}
+	waitFor(t, func() bool { return pool.Busy() == 3 })
+
+	// 已达上限，不再扩容
+	if decision := a.evaluate(); decision != nil {
+		t.Errorf("expected no decision at max size, got %+v", decision)
+	}
+	if got := a.status().Decisions; len(got) != 1 || got[0].QueueDepth != 4 {
+		t.Errorf("expected 1 recorded decision with queue depth 4, got %+v", got)
+	}
+}
+
+func TestAutoscaler_ScaleDownWhenIdle(t *testing.T) {
+	executed := make(chan string, 1)
+	pool := newWorkerPool(4, 10)
+	pool.Run(func(taskID string) { executed <- taskID })
+	defer pool.Stop()
+
+	a := newAutoscaler(AutoscaleConfig{MinWorkers: 1, MaxWorkers: 4, ScaleDownAfter: 2}, pool)
+
+	// 第一个低利用率周期不缩容
+	if decision := a.evaluate(); decision != nil {
+		t.Fatalf("expected no decision on first idle interval, got %+v", decision)
+	}
+	if decision := a.evaluate(); decision == nil || decision.To != 2 {
+		t.Fatalf("expected scale down to 2, got %+v", decision)
+	}
+
+	a.evaluate()
+	if decision := a.evaluate(); decision == nil || decision.To != 1 {
+		t.Fatalf("expected scale down to min 1, got %+v", decision)
+	}
+	if pool.Size() != 1 {
+		t.Errorf("expected pool size 1, got %d", pool.Size())
+	}
+
+	// 缩容后剩余 worker 仍然处理任务
+	pool.Submit("after-shrink")
+	select {
+	case <-executed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("task not executed after scale down")
+	}
+}
+
+func TestScheduler_AutoscaleStatus(t *testing.T) {
+	svc, _, cleanup := setupTestService(t)
+	defer cleanup()
+
+	s := svc.Scheduler()
+	if err := s.SetAutoscale(AutoscaleConfig{MinWorkers: 2, MaxWorkers: 6}); err != nil {
+		t.Fatalf("SetAutoscale failed: %v", err)
+	}
+
+	status := s.GetStatus()
+	if status.WorkerCount != 2 {
+		t.Errorf("expected worker count 2, got %d", status.WorkerCount)
+	}
+	if status.Autoscale == nil || status.Autoscale.MinWorkers != 2 || status.Autoscale.MaxWorkers != 6 {
+		t.Errorf("unexpected autoscale status %+v", status.Autoscale)
+	}
+}
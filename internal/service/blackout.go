@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+)
+
+// BlackoutWindow 暂停调度的时间窗口（如维护窗口）。窗口内匹配的任务保持 PENDING 并记录 BlockedReason，
+// 窗口结束后自动恢复调度。Start/End 表示一次性窗口 [Start, End)；DailyStart/DailyEnd 表示周期窗口，
+// 在 Weekdays 中的每一天（为空表示每天）从 DailyStart 持续到 DailyEnd，DailyEnd 不晚于 DailyStart 时延续到次日
+type BlackoutWindow struct {
+	Name      string   // 窗口名称，记录在阻塞原因中
+	TaskTypes []string // 适用的任务类型，为空表示所有任务
+
+	Start time.Time
+	End   time.Time
+
+	Weekdays   []time.Weekday
+	DailyStart string         // HH:MM
+	DailyEnd   string         // HH:MM
+	Location   *time.Location // 周期窗口使用的时区，默认 UTC
+}
+
+// Validate 校验窗口参数：必须且只能设置一次性窗口或周期窗口之一
+func (w *BlackoutWindow) Validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("blackout window name is required")
+	}
+	oneOff := !w.Start.IsZero() || !w.End.IsZero()
+	recurring := w.DailyStart != "" || w.DailyEnd != ""
+	switch {
+	case oneOff && recurring:
+		return fmt.Errorf("blackout window %s: set either start/end or daily_start/daily_end, not both", w.Name)
+	case oneOff:
+		if !w.End.After(w.Start) {
+			return fmt.Errorf("blackout window %s: end must be after start", w.Name)
+		}
+	case recurring:
+		from, err := parseClock(w.DailyStart)
+		if err != nil {
+			return fmt.Errorf("blackout window %s: daily_start: %w", w.Name, err)
+		}
+		to, err := parseClock(w.DailyEnd)
+		if err != nil {
+			return fmt.Errorf("blackout window %s: daily_end: %w", w.Name, err)
+		}
+		if from == to {
+			return fmt.Errorf("blackout window %s: daily_start and daily_end must differ", w.Name)
+		}
+	default:
+		return fmt.Errorf("blackout window %s: start/end or daily_start/daily_end is required", w.Name)
+	}
+	return nil
+}
+
+// appliesTo 窗口是否适用于该任务类型
+func (w *BlackoutWindow) appliesTo(taskType string) bool {
+	if len(w.TaskTypes) == 0 {
+		return true
+	}
+	for _, t := range w.TaskTypes {
+		if t == taskType {
+			return true
+		}
+	}
+	return false
+}
+
+// activeUntil now 处于窗口内时返回窗口结束时间
+func (w *BlackoutWindow) activeUntil(now time.Time) (time.Time, bool) {
+	if !w.Start.IsZero() {
+		return w.End, !now.Before(w.Start) && now.Before(w.End)
+	}
+
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	from, _ := parseClock(w.DailyStart)
+	to, _ := parseClock(w.DailyEnd)
+	local := now.In(loc)
+
+	// 跨午夜的窗口可能从前一天开始
+	for _, offset := range []int{0, -1} {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, loc)
+		if !w.onWeekday(day.Weekday()) {
+			continue
+		}
+		start := day.Add(from)
+		end := day.Add(to)
+		if to <= from {
+			end = end.Add(24 * time.Hour)
+		}
+		if !local.Before(start) && local.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// onWeekday 周期窗口是否在这一天开始
+func (w *BlackoutWindow) onWeekday(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range w.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock 解析 HH:MM，返回距当天零点的时长
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// SetBlackoutWindows 设置暂停调度的时间窗口，替换之前的设置。须在 Start 之前调用
+func (s *Scheduler) SetBlackoutWindows(windows ...BlackoutWindow) error {
+	for i := range windows {
+		if err := windows[i].Validate(); err != nil {
+			return err
+		}
+	}
+	s.blackoutWindows = append([]BlackoutWindow(nil), windows...)
+	return nil
+}
+
+// inBlackout 任务处于暂停调度窗口内时记录阻塞原因，并安排在窗口结束时唤醒调度器
+func (s *Scheduler) inBlackout(task *model.Task, now time.Time) bool {
+	reason, end, active := s.activeBlackout(task.TaskType, now)
+	if !active {
+		return false
+	}
+	if task.BlockedReason != reason {
+		if err := s.repo.SetBlockedReason(task.ID, reason); err != nil {
+			logger.Errorf("Failed to record blocked reason for task %s: %v", task.ID, err)
+		}
+	}
+	s.wakeAt(end)
+	return true
+}
+
+// activeBlackout now 处于适用于该任务类型的暂停调度窗口内时返回阻塞原因和窗口结束时间
+func (s *Scheduler) activeBlackout(taskType string, now time.Time) (reason string, end time.Time, active bool) {
+	for i := range s.blackoutWindows {
+		w := &s.blackoutWindows[i]
+		if !w.appliesTo(taskType) {
+			continue
+		}
+		if end, active := w.activeUntil(now); active {
+			return fmt.Sprintf("blackout window %s until %s", w.Name, end.UTC().Format(time.RFC3339)), end, true
+		}
+	}
+	return "", time.Time{}, false
+}
+
+// wakeAt 在 at 时刻唤醒调度器，同一时刻只安排一次
+func (s *Scheduler) wakeAt(at time.Time) {
+	key := at.UnixNano()
+	s.blackoutWakesMu.Lock()
+	defer s.blackoutWakesMu.Unlock()
+	if s.blackoutWakes[key] {
+		return
+	}
+	if s.blackoutWakes == nil {
+		s.blackoutWakes = make(map[int64]bool)
+	}
+	s.blackoutWakes[key] = true
+
+	s.clock.AfterFunc(at.Sub(s.clock.Now()), func() {
+		s.blackoutWakesMu.Lock()
+		delete(s.blackoutWakes, key)
+		s.blackoutWakesMu.Unlock()
+		s.Wake()
+	})
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+)
+
+// defaultCancelGracePeriod 取消运行中任务时等待执行器清理的默认时长
+const defaultCancelGracePeriod = 10 * time.Second
+
+// ErrTaskCancelled 任务被取消时执行上下文的取消原因，执行器可通过 context.Cause 判断
+var ErrTaskCancelled = errors.New("task cancelled")
+
+// execution 运行中任务的取消句柄
+type execution struct {
+	cancel    context.CancelCauseFunc
+	done      chan struct{} // 执行器返回时关闭
+	cancelled bool          // 由 CancelExecution 取消（受 executionsMu 保护）
+}
+
+// startExecution 为任务创建可取消的执行上下文并登记，返回的 finish 须在执行器返回后调用
+func (s *Scheduler) startExecution(taskID string) (context.Context, func() (cancelled bool)) {
+	base := s.ctx
+	if base == nil {
+		base = context.Background()
+	}
+	ctx, cancel := context.WithCancelCause(base)
+	exec := &execution{cancel: cancel, done: make(chan struct{})}
+
+	s.executionsMu.Lock()
+	s.executions[taskID] = exec
+	s.executionsMu.Unlock()
+
+	finish := func() bool {
+		s.executionsMu.Lock()
+		delete(s.executions, taskID)
+		cancelled := exec.cancelled
+		s.executionsMu.Unlock()
+
+		cancel(nil)
+		close(exec.done)
+		return cancelled
+	}
+	return ctx, finish
+}
+
+// CancelExecution 取消运行中任务的执行上下文，并等待执行器返回（最多等待宽限期），
+// 以便执行器在记录 CANCELLED 事件之前完成清理。任务未在执行时返回 false
+func (s *Scheduler) CancelExecution(taskID string) bool {
+	s.executionsMu.Lock()
+	exec, ok := s.executions[taskID]
+	if ok {
+		exec.cancelled = true
+	}
+	grace := s.cancelGrace
+	s.executionsMu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	exec.cancel(ErrTaskCancelled)
+	select {
+	case <-exec.done:
+	case <-time.After(grace):
+		logger.Warnf("Task %s did not stop within %s after cancellation", taskID, grace)
+	}
+	return true
+}
+
+// cancelAbandonedExecutions 取消已被其他进程记录为 CANCELLED 的任务（如随工作流被取消）在本实例的执行上下文，
+// 执行器据此停止，执行结果被忽略。取消方已记录任务状态，这里不再写入；随实例心跳检查
+func (s *Scheduler) cancelAbandonedExecutions(taskIDs []string) {
+	if len(taskIDs) == 0 {
+		return
+	}
+	tasks, err := s.repo.GetByIDs(taskIDs)
+	if err != nil {
+		logger.Errorf("Failed to check in-flight tasks of scheduler instance %s: %v", s.instanceID, err)
+		return
+	}
+	for _, task := range tasks {
+		if task == nil || task.Status != model.TaskStatusCancelled {
+			continue
+		}
+		s.executionsMu.Lock()
+		exec, ok := s.executions[task.ID]
+		if ok {
+			exec.cancelled = true
+		}
+		s.executionsMu.Unlock()
+
+		if ok {
+			logger.Infof("Task %s was cancelled by another instance, stopping its execution", task.ID)
+			exec.cancel(ErrTaskCancelled)
+		}
+	}
+}
+
+// SetCancelGracePeriod 设置取消运行中任务时等待执行器清理的时长
+func (s *Scheduler) SetCancelGracePeriod(d time.Duration) {
+	s.executionsMu.Lock()
+	defer s.executionsMu.Unlock()
+	s.cancelGrace = d
+}
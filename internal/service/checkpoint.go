@@ -0,0 +1,80 @@
+package service
+
+import (
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+)
+
+// checkpointMaxAge 检查点的有效期，停止更久后负载可能已经变化，按配置重新开始
+const checkpointMaxAge = time.Hour
+
+// SetCheckpointName 设置检查点名称，须在 Start 之前调用。设置后停止时以该名称保存工作池大小、自动伸缩进度和调度计数，
+// 以相同名称首次启动时恢复，避免重启后计数归零、工作池回到初始大小。名称须在共享同一存储的调度器之间唯一
+// （如固定的节点名），为空时不保存也不恢复
+func (s *Scheduler) SetCheckpointName(name string) {
+	s.checkpointName = name
+}
+
+// saveCheckpoint 保存停止时的运行状态，调用时工作池已停止
+func (s *Scheduler) saveCheckpoint(pool *WorkerPool) {
+	if s.checkpointName == "" {
+		return
+	}
+	status := s.state.status()
+	cp := &model.SchedulerCheckpoint{
+		Name:           s.checkpointName,
+		SavedAt:        s.clock.Now(),
+		BaseWorkers:    s.baseWorkers,
+		WorkerCount:    pool.Size(),
+		ScheduledCount: status.ScheduledCnt,
+		FinishedCount:  status.FinishedCnt,
+	}
+	if s.autoscaler != nil {
+		cp.AutoscaleLowTicks = s.autoscaler.lowUtilizationTicks()
+	}
+	if err := s.repo.SaveSchedulerCheckpoint(cp); err != nil {
+		logger.Errorf("Failed to save scheduler checkpoint %s: %v", s.checkpointName, err)
+		return
+	}
+	logger.Infof("Scheduler checkpoint %s saved: %d workers, %d scheduled, %d finished",
+		cp.Name, cp.WorkerCount, cp.ScheduledCount, cp.FinishedCount)
+}
+
+// restoreCheckpoint 首次启动时恢复检查点，调用方持有 s.mu。配置的工作池大小与保存时不同则不恢复 worker 数，
+// 自动伸缩时 worker 数限制在伸缩范围内；超过 checkpointMaxAge 的检查点忽略
+func (s *Scheduler) restoreCheckpoint() {
+	s.baseWorkers = s.workerPool.Size()
+	if s.checkpointName == "" {
+		return
+	}
+	cp, err := s.repo.GetSchedulerCheckpoint(s.checkpointName)
+	if err != nil {
+		logger.Errorf("Failed to load scheduler checkpoint %s: %v", s.checkpointName, err)
+		return
+	}
+	if cp == nil {
+		return
+	}
+	if age := s.clock.Now().Sub(cp.SavedAt); age > checkpointMaxAge {
+		logger.Infof("Scheduler checkpoint %s ignored: saved %s ago", cp.Name, age.Round(time.Second))
+		return
+	}
+
+	s.state.restoreCounts(cp.ScheduledCount, cp.FinishedCount)
+	if cp.BaseWorkers == s.baseWorkers && cp.WorkerCount > 0 {
+		workers := cp.WorkerCount
+		if s.autoscaler != nil {
+			workers = min(max(workers, s.autoscaler.cfg.MinWorkers), s.autoscaler.cfg.MaxWorkers)
+		}
+		if err := s.workerPool.Resize(workers); err != nil {
+			logger.Errorf("Failed to restore worker pool size from checkpoint %s: %v", cp.Name, err)
+		}
+	}
+	if s.autoscaler != nil {
+		s.autoscaler.setLowUtilizationTicks(cp.AutoscaleLowTicks)
+	}
+	logger.Infof("Scheduler checkpoint %s restored: %d workers, %d scheduled, %d finished",
+		cp.Name, s.workerPool.Size(), cp.ScheduledCount, cp.FinishedCount)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// ErrorKind 任务服务错误分类
+type ErrorKind string
+
+const (
+	KindNotFound          ErrorKind = "not_found"          // 任务不存在
+	KindInvalidArgument   ErrorKind = "invalid_argument"   // 参数不合法，如依赖的任务不存在
+	KindInvalidTransition ErrorKind = "invalid_transition" // 当前状态不允许该操作
+	KindNotEditable       ErrorKind = "not_editable"       // 当前状态不允许修改该字段
+	KindDependencyCycle   ErrorKind = "dependency_cycle"   // 任务依赖存在环
+	KindQuotaExceeded     ErrorKind = "quota_exceeded"     // 超出配额
+	KindConflict          ErrorKind = "conflict"           // 并发修改冲突，重新读取后重试
+)
+
+// Error 任务服务的类型化错误。用 errors.Is 与同类哨兵错误（如 ErrNotFound）比较，
+// handler 通过 ErrorCode 映射为统一的错误码、HTTP 状态码和 gRPC 状态码
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Err     error
+}
+
+// 各类错误的哨兵值，只用于 errors.Is 比较
+var (
+	ErrNotFound          = &Error{Kind: KindNotFound}
+	ErrInvalidArgument   = &Error{Kind: KindInvalidArgument}
+	ErrInvalidTransition = &Error{Kind: KindInvalidTransition}
+	ErrNotEditable       = &Error{Kind: KindNotEditable}
+	ErrDependencyCycle   = &Error{Kind: KindDependencyCycle}
+	ErrQuotaExceeded     = &Error{Kind: KindQuotaExceeded}
+	ErrConflict          = &Error{Kind: KindConflict}
+)
+
+// newError 创建类型化错误
+func newError(kind ErrorKind, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = string(e.Kind)
+	}
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 同类错误相等，使 errors.Is(err, ErrNotFound) 等判断成立
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind
+}
+
+// ErrorCode 实现 errorcode.Coder
+func (e *Error) ErrorCode() errorcode.ErrorCode {
+	switch e.Kind {
+	case KindNotFound:
+		return errorcode.ErrCodeTaskNotFound
+	case KindInvalidArgument:
+		return errorcode.ErrCodeInvalidParam
+	case KindInvalidTransition:
+		return errorcode.ErrCodeTaskInvalidTransition
+	case KindNotEditable:
+		return errorcode.ErrCodeTaskFieldNotEditable
+	case KindDependencyCycle:
+		return errorcode.ErrCodeTaskDependencyCycle
+	case KindQuotaExceeded:
+		return errorcode.ErrCodeQuotaExceeded
+	case KindConflict:
+		return errorcode.ErrCodeConflict
+	default:
+		return errorcode.ErrCodeUnknown
+	}
+}
+
+// taskNotFound 任务不存在，同时可用 errors.Is 与 repository.ErrTaskNotFound 比较
+func taskNotFound(id string) error {
+	return &Error{Kind: KindNotFound, Message: "task " + id, Err: repository.ErrTaskNotFound}
+}
+
+// getTask 获取任务，不存在时返回 KindNotFound 错误
+func getTask(repo repository.TaskStore, id string) (*model.Task, error) {
+	task, err := repo.GetByID(id)
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		return nil, taskNotFound(id)
+	}
+	return task, err
+}
+
+// storeError 将仓储的条件更新失败转换为冲突错误、跨分片操作转换为参数错误，其余错误原样返回
+func storeError(err error) error {
+	if errors.Is(err, repository.ErrStatusMismatch) {
+		return &Error{Kind: KindConflict, Message: "task status changed concurrently", Err: err}
+	}
+	if errors.Is(err, repository.ErrCrossShard) {
+		return &Error{Kind: KindInvalidArgument, Message: "task cannot reference another shard", Err: err}
+	}
+	return err
+}
+
+// joinFieldErrors 把字段错误合并为一条消息
+func joinFieldErrors(errs []model.FieldError) string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errorcode "taskflow/internal/error"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+func TestTaskService_TypedErrors(t *testing.T) {
+	service, _, cleanup := setupTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := service.CancelTask(ctx, "missing", "test"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	_, err := service.CreateTask(ctx, "dep", "", model.TaskPriorityNormal, "test", nil, []string{"missing"}, 0, "test")
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for unknown dependency, got %v", err)
+	}
+
+	task, err := service.CreateTask(ctx, "t", "", model.TaskPriorityNormal, "test", nil, nil, 0, "test")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if err := service.CancelTask(ctx, task.ID, "test"); err != nil {
+		t.Fatalf("failed to cancel task: %v", err)
+	}
+	err = service.CancelTask(ctx, task.ID, "test")
+	if !errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrInvalidTransition, got %v", err)
+	}
+}
+
+func TestError_Mapping(t *testing.T) {
+	cases := []struct {
+		err      error
+		code     errorcode.ErrorCode
+		http     int
+		grpc     codes.Code
+		reason   string
+		sentinel error
+	}{
+		{taskNotFound("t1"), errorcode.ErrCodeTaskNotFound, http.StatusNotFound, codes.NotFound, "TASK_NOT_FOUND", ErrNotFound},
+		{newError(KindInvalidTransition, "x"), errorcode.ErrCodeTaskInvalidTransition, http.StatusConflict, codes.FailedPrecondition, "TASK_INVALID_TRANSITION", ErrInvalidTransition},
+		{newError(KindDependencyCycle, "x"), errorcode.ErrCodeTaskDependencyCycle, http.StatusBadRequest, codes.InvalidArgument, "TASK_DEPENDENCY_CYCLE", ErrDependencyCycle},
+		{newError(KindQuotaExceeded, "x"), errorcode.ErrCodeQuotaExceeded, http.StatusTooManyRequests, codes.ResourceExhausted, "QUOTA_EXCEEDED", ErrQuotaExceeded},
+		{storeError(repository.ErrStatusMismatch), errorcode.ErrCodeConflict, http.StatusConflict, codes.Aborted, "CONFLICT", ErrConflict},
+		{errors.New("boom"), errorcode.ErrCodeUnknown, http.StatusInternalServerError, codes.Unknown, "UNKNOWN", nil},
+	}
+	for _, tc := range cases {
+		wrapped := fmt.Errorf("handler: %w", tc.err)
+		if tc.sentinel != nil && !errors.Is(wrapped, tc.sentinel) {
+			t.Errorf("%v: expected errors.Is(%v)", tc.err, tc.sentinel)
+		}
+
+		te := errorcode.FromError(wrapped)
+		if te.Code != tc.code || te.HTTPStatus != tc.http || te.ToGinResponse().Reason != tc.reason {
+			t.Errorf("%v: got code=%d http=%d reason=%s", tc.err, te.Code, te.HTTPStatus, te.ToGinResponse().Reason)
+		}
+
+		// 经 gRPC 传输后仍能还原错误码和详情
+		st := status.Convert(errorcode.ToGRPCError(wrapped))
+		if st.Code() != tc.grpc {
+			t.Errorf("%v: grpc code = %s, want %s", tc.err, st.Code(), tc.grpc)
+		}
+		if back := errorcode.FromGRPCStatus(st); back.Code != tc.code || back.Detail != te.Detail {
+			t.Errorf("%v: round trip got code=%d detail=%q", tc.err, back.Code, back.Detail)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/redact"
+	"taskflow/internal/repository"
+)
+
+// 任务日志限制
+const (
+	maxTaskLogLines   = 10000 // 每个任务保留的日志行数（环形缓冲）
+	taskLogTrimEvery  = 500   // 每写入多少行清理一次旧日志
+	maxTaskLogLineLen = 8192  // 单行最大字节数，超出截断
+)
+
+// TaskLogListener 任务日志监听器
+type TaskLogListener func(line model.TaskLogLine)
+
+// ErrNoScheduler 执行上下文不属于调度器中的一次执行（如直接调用执行器），无法上报进度、读取密钥或提交子任务
+var ErrNoScheduler = errors.New("execution context is not attached to a scheduler")
+
+// ExecutionContext 执行上下文，执行器通过 ExecutionContextFrom 获取，用于写入任务日志、上报进度和心跳、
+// 读取密钥和提交子任务，不需要直接访问仓储
+type ExecutionContext struct {
+	TaskID  string
+	Attempt int32
+
+	ctx       context.Context
+	scheduler *Scheduler
+	task      *model.Task // 执行中的任务，InputParams 保留密钥引用
+
+	mu      sync.Mutex
+	seq     int64
+	persist func(line model.TaskLogLine)
+	masked  []string // 写入前替换为掩码的敏感值
+}
+
+type executionContextKey struct{}
+
+// discardExecutionContext 不在调度器中执行时使用的空上下文
+var discardExecutionContext = &ExecutionContext{}
+
+// ExecutionContextFrom 从 context 中获取执行上下文，不存在时返回丢弃日志的空上下文
+func ExecutionContextFrom(ctx context.Context) *ExecutionContext {
+	if ec, ok := ctx.Value(executionContextKey{}).(*ExecutionContext); ok {
+		return ec
+	}
+	return discardExecutionContext
+}
+
+// withExecutionContext 将执行上下文放入 context，返回的 context 同时作为 ec.Context()
+func withExecutionContext(ctx context.Context, ec *ExecutionContext) context.Context {
+	ctx = context.WithValue(ctx, executionContextKey{}, ec)
+	ec.ctx = ctx
+	return ctx
+}
+
+// Log 写入日志，多行文本按行拆分
+func (e *ExecutionContext) Log(text string) {
+	if e.persist == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	text = redact.Text(text, e.masked)
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if len(line) > maxTaskLogLineLen {
+			line = line[:maxTaskLogLineLen]
+		}
+		e.seq++
+		e.persist(model.TaskLogLine{
+			TaskID:    e.TaskID,
+			Seq:       e.seq,
+			Attempt:   e.Attempt,
+			Timestamp: model.Now(),
+			Line:      line,
+		})
+	}
+}
+
+// Logf 格式化写入日志
+func (e *ExecutionContext) Logf(format string, args ...interface{}) {
+	e.Log(fmt.Sprintf(format, args...))
+}
+
+// Logger 带任务 ID 和执行次数字段的进程日志，用于不需要出现在任务日志中的诊断信息
+func (e *ExecutionContext) Logger() *zap.SugaredLogger {
+	return logger.With("task_id", e.TaskID, "attempt", e.Attempt)
+}
+
+// Context 本次执行的 context，任务被取消、执行超时或调度器停止时结束。
+// 执行器应在长时间操作中检查它，不在调度器中执行时返回 context.Background()
+func (e *ExecutionContext) Context() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+	return e.ctx
+}
+
+// Canceled 本次执行是否已被取消或超时
+func (e *ExecutionContext) Canceled() bool {
+	return e.Context().Err() != nil
+}
+
+// Progress 上报本次执行的进度（0 到 100）和说明，同时刷新心跳；说明中的敏感值替换为掩码。
+// 任务已不在运行（如已被取消）时返回 repository.ErrStatusMismatch，不在调度器中执行时忽略，写入失败时见 reportError
+func (e *ExecutionContext) Progress(percent int, message string) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("progress must be between 0 and 100, got %d", percent)
+	}
+	if e.scheduler == nil {
+		return nil
+	}
+	e.mu.Lock()
+	message = redact.Text(message, e.masked)
+	e.mu.Unlock()
+	return e.reportError("progress", e.scheduler.repo.UpdateProgress(e.TaskID, int32(percent), message, e.scheduler.clock.Now()))
+}
+
+// SetSubStatus 上报本次执行进入的自定义阶段（如 UPLOADING、VALIDATING）和说明，同时刷新心跳；说明中的敏感值替换为掩码。
+// 子状态只用于展示，任务状态仍为 RUNNING。任务已不在运行时返回 repository.ErrStatusMismatch，不在调度器中执行时忽略，
+// 写入失败时见 reportError
+func (e *ExecutionContext) SetSubStatus(subStatus, message string) error {
+	if err := model.ValidateSubStatus(subStatus, message); err != nil {
+		return err
+	}
+	if e.scheduler == nil {
+		return nil
+	}
+	e.mu.Lock()
+	message = redact.Text(message, e.masked)
+	e.mu.Unlock()
+	return e.reportError("sub-status", e.scheduler.repo.UpdateSubStatus(e.TaskID, subStatus, message, e.scheduler.clock.Now(), "scheduler", e.scheduler.instanceID))
+}
+
+// Heartbeat 刷新心跳时间，表明执行器仍在工作。任务已不在运行时返回 repository.ErrStatusMismatch，不在调度器中执行时忽略，
+// 写入失败时见 reportError
+func (e *ExecutionContext) Heartbeat() error {
+	if e.scheduler == nil {
+		return nil
+	}
+	return e.reportError("heartbeat", e.scheduler.repo.Heartbeat(e.TaskID, e.scheduler.clock.Now()))
+}
+
+// reportError 进度、子状态和心跳只用于展示和卡死检测，写入失败（如数据库繁忙）时记录日志后忽略，
+// 避免执行器把它作为执行错误返回导致任务失败；只有任务已不在运行时返回 repository.ErrStatusMismatch，执行器可据此停止
+func (e *ExecutionContext) reportError(what string, err error) error {
+	if err == nil || errors.Is(err, repository.ErrStatusMismatch) {
+		return err
+	}
+	e.Logger().Warnf("Failed to record %s: %v", what, err)
+	return nil
+}
+
+// Secret 读取密钥明文，明文此后在任务日志和进度说明中替换为掩码。
+// 密钥未启用、不存在或无法解密时返回 Fatal 错误，执行器直接返回它时任务不再重试
+func (e *ExecutionContext) Secret(name string) (string, error) {
+	if e.scheduler == nil {
+		return "", ErrNoScheduler
+	}
+	if e.scheduler.secretManager == nil {
+		return "", Fatal(errors.New("secrets are not enabled"))
+	}
+	value, err := e.scheduler.secretManager.Resolve(name)
+	if err != nil {
+		return "", Fatal(err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.masked = append(e.masked, value)
+	return value, nil
+}
+
+// SubmitChild 提交子任务：ParentID 指向当前任务，未设置的创建者、团队、优先级和请求 ID 沿用当前任务，
+// 子任务属于当前任务的工作流，截止时间不晚于当前任务的工作流截止时间；
+// 状态为 PENDING，未设置 ID 时自动生成。子任务独立调度，当前任务不等待它完成
+func (e *ExecutionContext) SubmitChild(child *model.Task) error {
+	if e.scheduler == nil {
+		return ErrNoScheduler
+	}
+	parent := e.task
+	child.ParentID = parent.ID
+	child.Status = model.TaskStatusPending
+	if child.CreatedBy == "" {
+		child.CreatedBy = parent.CreatedBy
+	}
+	if child.TeamID == "" {
+		child.TeamID = parent.TeamID
+	}
+	if child.Priority == model.TaskPriorityUnspecified {
+		child.Priority = parent.Priority
+	}
+	if child.CorrelationID == "" {
+		child.CorrelationID = parent.CorrelationID
+	}
+	child.InheritWorkflowDeadline(parent.WorkflowDeadline)
+	if err := e.scheduler.submitTask(child); err != nil {
+		return err
+	}
+	e.Logf("submitted child task %s (%s)", child.ID, child.TaskType)
+	return nil
+}
+
+// Input 之前挂起等待输入时由信号送达的输入，按键合并了历次信号；没有送达过输入时返回空映射。
+// 执行器可据此判断所需输入是否已送达，未送达时返回 WaitForInput 挂起任务
+func (e *ExecutionContext) Input() map[string]string {
+	if e.task == nil {
+		return map[string]string{}
+	}
+	return model.MergeSignalInput(nil, e.task.SignalInput)
+}
+
+// SetRedactor 设置敏感参数脱敏器，执行器写入的任务日志中敏感参数值替换为掩码。须在 Start 之前调用
+func (s *Scheduler) SetRedactor(r *redact.Redactor) {
+	s.redactor = r
+}
+
+// newExecutionContext 为 task 的一次执行创建上下文，日志 seq 接续之前的尝试，masked 中的值在日志中替换为掩码
+func (s *Scheduler) newExecutionContext(task *model.Task, masked []string) *ExecutionContext {
+	lastSeq, err := s.repo.LastLogSeq(task.ID)
+	if err != nil {
+		logger.Errorf("Failed to load last log seq for task %s: %v", task.ID, err)
+	}
+
+	return &ExecutionContext{
+		TaskID:    task.ID,
+		Attempt:   task.RetryCount + 1,
+		scheduler: s,
+		task:      task,
+		seq:       lastSeq,
+		persist:   s.appendTaskLog,
+		masked:    masked,
+	}
+}
+
+// appendTaskLog 持久化日志行并推送给监听器
+func (s *Scheduler) appendTaskLog(line model.TaskLogLine) {
+	if err := s.repo.AppendLogs([]model.TaskLogLine{line}); err != nil {
+		logger.Errorf("Failed to append log for task %s: %v", line.TaskID, err)
+	}
+	if line.Seq%taskLogTrimEvery == 0 {
+		if err := s.repo.TrimLogs(line.TaskID, maxTaskLogLines); err != nil {
+			logger.Errorf("Failed to trim logs for task %s: %v", line.TaskID, err)
+		}
+	}
+
+	s.listenersMu.RLock()
+	listeners := s.logListeners
+	s.listenersMu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(line)
+	}
+}
+
+// OnTaskLog 注册任务日志监听器
+func (s *Scheduler) OnTaskLog(listener TaskLogListener) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.logListeners = append(s.logListeners, listener)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"taskflow/internal/model"
+)
+
+// rateLimitBackoffFactor 限流错误的退避倍数（相对重试策略的正常退避）
+const rateLimitBackoffFactor = 4
+
+// ExecutionError 带分类的执行错误，执行器通过 Retryable、Fatal、RateLimited 构造
+type ExecutionError struct {
+	Class      model.ErrorClass
+	RetryAfter time.Duration // 限流时建议的最短等待时间，0 表示未指定
+	Err        error
+}
+
+// Error 实现 error 接口
+func (e *ExecutionError) Error() string {
+	if e.Err == nil {
+		return string(e.Class)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 标记暂时性错误：即使未设置重试策略，也按默认退避在 MaxRetries 内重试，
+// 且不受策略中 RetryableErrors 的限制
+func Retryable(err error) error {
+	return &ExecutionError{Class: model.ErrorClassRetryable, Err: err}
+}
+
+// Fatal 标记不可恢复的错误：任务立即失败，不再重试
+func Fatal(err error) error {
+	return &ExecutionError{Class: model.ErrorClassFatal, Err: err}
+}
+
+// RateLimited 标记限流错误：以正常退避的数倍重试，且至少等待 retryAfter
+func RateLimited(err error, retryAfter time.Duration) error {
+	return &ExecutionError{Class: model.ErrorClassRateLimited, RetryAfter: retryAfter, Err: err}
+}
+
+// InputRequest 执行器请求外部输入，由 WaitForInput 构造。任务挂起为 WAITING_INPUT，不计为失败也不重试；
+// 信号送达输入后任务重新进入 PENDING 并再次执行，执行器通过 ExecutionContext.Input 读取送达的输入
+type InputRequest struct {
+	Prompt string // 需要的输入说明，通过任务的 input_request 展示给调用方
+}
+
+// Error 实现 error 接口
+func (e *InputRequest) Error() string {
+	return "task is waiting for input: " + e.Prompt
+}
+
+// WaitForInput 执行器返回它以挂起任务，等待外部通过信号送达 prompt 描述的输入
+func WaitForInput(prompt string) error {
+	return &InputRequest{Prompt: prompt}
+}
+
+// maxPanicStack 记录在事件元数据中的调用栈最大字节数
+const maxPanicStack = 8 << 10
+
+// PanicError 执行任务时发生的 panic，按未分类的执行失败处理（是否重试由重试策略决定），
+// 调用栈记录在失败或重试事件的元数据中
+type PanicError struct {
+	Value any
+	Stack string
+}
+
+// newPanicError 由 recover 的值和当前调用栈构造，调用栈过长时截断
+func newPanicError(value any, stack []byte) *PanicError {
+	if len(stack) > maxPanicStack {
+		stack = append(stack[:maxPanicStack:maxPanicStack], "\n...(truncated)"...)
+	}
+	return &PanicError{Value: value, Stack: string(stack)}
+}
+
+// Error 实现 error 接口
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task execution panicked: %v", e.Value)
+}
+
+// ClassifyError 获取执行错误的分类，未标记的超时错误归为 timeout
+func ClassifyError(err error) (model.ErrorClass, time.Duration) {
+	var execErr *ExecutionError
+	if errors.As(err, &execErr) {
+		return execErr.Class, execErr.RetryAfter
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return model.ErrorClassTimeout, 0
+	}
+	return model.ErrorClassUnknown, 0
+}
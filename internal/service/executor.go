@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+)
+
+// Executor 任务执行器，按任务类型注册到调度器
+type Executor interface {
+	Execute(ctx context.Context, task *model.Task) (map[string]string, error)
+}
+
+// ExecutorFunc 函数形式的执行器
+type ExecutorFunc func(ctx context.Context, task *model.Task) (map[string]string, error)
+
+// Execute 实现 Executor 接口
+func (f ExecutorFunc) Execute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	return f(ctx, task)
+}
+
+// ExecutorRegistry 执行器注册表
+type ExecutorRegistry struct {
+	mu        sync.RWMutex
+	executors map[string]Executor
+	fallback  Executor
+}
+
+// NewExecutorRegistry 创建执行器注册表，未注册的任务类型使用默认执行器
+func NewExecutorRegistry() *ExecutorRegistry {
+	return &ExecutorRegistry{
+		executors: make(map[string]Executor),
+		fallback:  ExecutorFunc(defaultExecute),
+	}
+}
+
+// Register 注册任务类型的执行器
+func (r *ExecutorRegistry) Register(taskType string, exec Executor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executors[taskType] = exec
+}
+
+// SetFallback 设置默认执行器
+func (r *ExecutorRegistry) SetFallback(exec Executor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = exec
+}
+
+// Get 获取任务类型对应的执行器
+func (r *ExecutorRegistry) Get(taskType string) Executor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if exec, ok := r.executors[taskType]; ok {
+		return exec
+	}
+	return r.fallback
+}
+
+// defaultExecute 默认执行器：模拟执行
+func defaultExecute(ctx context.Context, task *model.Task) (map[string]string, error) {
+	logger.Infof("Running task %s of type %s", task.ID, task.TaskType)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	return map[string]string{
+		"status": "completed",
+		"output": "task executed successfully",
+	}, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+)
+
+// defaultHeartbeatInterval 调度器实例心跳间隔
+const defaultHeartbeatInterval = 10 * time.Second
+
+// newInstanceID 生成调度器实例 ID（主机名 + UUID）
+func newInstanceID() (id, hostname string) {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return hostname + "-" + uuid.New().String(), hostname
+}
+
+// InstanceID 当前调度器实例 ID，记录在其执行的任务和产生的事件上
+func (s *Scheduler) InstanceID() string {
+	return s.instanceID
+}
+
+// SetHeartbeatInterval 设置实例心跳间隔，须在 Start 之前调用
+func (s *Scheduler) SetHeartbeatInterval(interval time.Duration) {
+	s.heartbeatInterval = interval
+}
+
+// SetWorkerLabels 设置实例标签（如 gpu=true、region=eu），替换之前的设置。声明了节点选择器的任务
+// 只由标签包含其全部键值的实例认领，声明了目标实例的任务只由实例 ID 或主机名与之相同的实例认领。须在 Start 之前调用
+func (s *Scheduler) SetWorkerLabels(labels map[string]string) error {
+	if err := model.ValidateLabels(labels); err != nil {
+		return err
+	}
+	s.workerLabels = maps.Clone(labels)
+	return nil
+}
+
+// heartbeatLoop 周期性写入实例心跳，ctx 取消后关闭 done
+func (s *Scheduler) heartbeatLoop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := s.clock.NewTicker(s.heartbeatInterval)
+	defer ticker.Stop()
+
+	s.heartbeat()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			s.heartbeat()
+		}
+	}
+}
+
+// deregisterInstance 等待心跳循环退出后删除实例记录
+func (s *Scheduler) deregisterInstance(done chan struct{}) {
+	<-done
+	if err := s.repo.DeleteSchedulerInstance(s.instanceID); err != nil {
+		logger.Errorf("Failed to deregister scheduler instance %s: %v", s.instanceID, err)
+	}
+}
+
+// heartbeat 写入一次实例心跳，并停止已被其他进程取消的任务的执行
+func (s *Scheduler) heartbeat() {
+	size, busy, queueDepth, _ := s.WorkerPoolStats()
+	inst := &model.SchedulerInstance{
+		ID:            s.instanceID,
+		Hostname:      s.hostname,
+		StartedAt:     s.startedAt,
+		HeartbeatAt:   s.clock.Now(),
+		WorkerCount:   size,
+		BusyWorkers:   busy,
+		QueueDepth:    queueDepth,
+		InFlightTasks: s.inFlightTasks(),
+		Labels:        s.workerLabels,
+	}
+	if err := s.repo.UpsertSchedulerInstance(inst); err != nil {
+		logger.Errorf("Failed to record heartbeat for scheduler instance %s: %v", s.instanceID, err)
+	}
+	s.cancelAbandonedExecutions(inst.InFlightTasks)
+}
+
+// inFlightTasks 正在本实例执行的任务 ID（已排序）
+func (s *Scheduler) inFlightTasks() []string {
+	s.executionsMu.Lock()
+	ids := make([]string, 0, len(s.executions))
+	for id := range s.executions {
+		ids = append(ids, id)
+	}
+	s.executionsMu.Unlock()
+
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// SetPriorityInheritance 启用优先级继承：优先级不低于 minPriority 的 PENDING 任务等待上游时，把优先级更低的 PENDING 上游
+// （沿依赖链传递）临时提升到与之相同，上游开始执行时恢复原优先级，提升和恢复都记录为任务事件。
+// TaskPriorityUnspecified 表示不启用（默认）。须在 Start 之前调用
+func (s *Scheduler) SetPriorityInheritance(minPriority model.TaskPriority) error {
+	if minPriority < model.TaskPriorityUnspecified || minPriority > model.TaskPriorityUrgent {
+		return fmt.Errorf("unknown priority %d", minPriority)
+	}
+	s.inheritFrom = minPriority
+	return nil
+}
+
+// inheritPriorities 每轮轮询前把等待上游的高优先级任务的优先级传递给上游，使上游按提升后的优先级参与本轮调度
+func (s *Scheduler) inheritPriorities() {
+	if s.inheritFrom == model.TaskPriorityUnspecified {
+		return
+	}
+	waiting, err := s.repo.ListWaitingOnDependencies(s.inheritFrom, s.maxPending)
+	if err != nil {
+		logger.Errorf("Failed to list tasks waiting on dependencies: %v", err)
+		return
+	}
+	for _, task := range waiting {
+		s.boostUpstream(task)
+	}
+}
+
+// boostUpstream 沿依赖链把 task 的优先级传递给优先级更低的 PENDING 上游。优先级已不低于 task 的上游不再向上传递：
+// 它的上游要么已被提升，要么在它自己等待时由 inheritPriorities 处理
+func (s *Scheduler) boostUpstream(task *model.Task) {
+	queue := []*model.Task{task}
+	visited := map[string]bool{task.ID: true}
+	for len(queue) > 0 {
+		down := queue[0]
+		queue = queue[1:]
+
+		deps, err := s.repo.GetByIDs(down.Dependencies)
+		if err != nil {
+			logger.Errorf("Failed to get dependencies of task %s: %v", down.ID, err)
+			return
+		}
+		for _, dep := range deps {
+			if dep == nil || visited[dep.ID] || dep.Status != model.TaskStatusPending || dep.Priority >= task.Priority {
+				continue
+			}
+			visited[dep.ID] = true
+
+			message := fmt.Sprintf("priority boosted from %s to %s: task %s depends on it", dep.Priority, task.Priority, down.ID)
+			err := s.repo.BoostPriority(dep.ID, task.Priority, "scheduler", message, s.instanceID)
+			if errors.Is(err, repository.ErrStatusMismatch) {
+				continue // 已被认领或被并发提升
+			}
+			if err != nil {
+				logger.Errorf("Failed to boost priority of task %s: %v", dep.ID, err)
+				continue
+			}
+			logger.Infof("Task %s %s", dep.ID, message)
+			queue = append(queue, dep)
+		}
+	}
+}
+
+// restorePriority 被提升优先级的任务开始执行后恢复原优先级，重试时按原优先级排队，仍有下游在等待时再次提升
+func (s *Scheduler) restorePriority(task *model.Task) {
+	message := fmt.Sprintf("priority restored to %s after the task started", task.BasePriority)
+	err := s.repo.RestorePriority(task.ID, "scheduler", message, s.instanceID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrStatusMismatch) {
+			logger.Errorf("Failed to restore priority of task %s: %v", task.ID, err)
+		}
+		return
+	}
+	task.Priority, task.BasePriority = task.BasePriority, model.TaskPriorityUnspecified
+}
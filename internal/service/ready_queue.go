@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/queue"
+)
+
+// readyQueueRetryDelay 就绪队列操作失败后的重试间隔
+const readyQueueRetryDelay = time.Second
+
+// SetReadyQueue 设置就绪任务队列，须在 Start 之前调用。
+// 启用后调度器把依赖已满足的任务 ID 推入队列，各实例的消费者弹出后
+// 通过 PENDING -> RUNNING 条件更新认领任务，任务状态仍以数据库为准
+func (s *Scheduler) SetReadyQueue(q queue.Queue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readyQueue = q
+}
+
+// enqueueReady 推入就绪任务 ID
+func (s *Scheduler) enqueueReady(taskID string) error {
+	if err := s.readyQueue.Push(s.ctx, taskID); err != nil {
+		logger.Errorf("Failed to push task %s to ready queue: %v", taskID, err)
+		return err
+	}
+	return nil
+}
+
+// consumeReadyQueue 从就绪队列弹出任务并提交到本地工作池，ctx 取消后关闭 done
+func (s *Scheduler) consumeReadyQueue(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	for {
+		// 暂停或工作池饱和时不弹出，任务留在队列中供其他实例认领
+		for !s.state.dispatching() || s.workerPool.Saturated() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.slotFreed:
+			case <-time.After(readyQueueRetryDelay):
+			}
+		}
+
+		taskID, err := s.readyQueue.Pop(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Errorf("Failed to pop from ready queue: %v", err)
+			if !sleepContext(ctx, readyQueueRetryDelay) {
+				return
+			}
+			continue
+		}
+
+		s.dispatchFromQueue(ctx, taskID)
+	}
+}
+
+// dispatchFromQueue 重新检查任务是否可调度后认领执行；
+// 任务已被其他实例认领或已取消时直接丢弃，工作池饱和时推回队列
+func (s *Scheduler) dispatchFromQueue(ctx context.Context, taskID string) {
+	// 弹出期间被暂停时推回队列；正在停止时任务仍为 PENDING，由兜底轮询再次推入
+	if !s.state.enter() {
+		if ctx.Err() == nil {
+			s.pushBack(ctx, taskID)
+		}
+		return
+	}
+	defer s.state.leave()
+
+	task, err := s.readyTask(taskID)
+	if err != nil || task == nil {
+		return
+	}
+
+	if err := s.dispatch(task); errors.Is(err, ErrWorkerPoolSaturated) {
+		metrics.RecordSchedulerBackpressure("pushed_back")
+		s.pushBack(ctx, taskID)
+	}
+}
+
+// pushBack 把未能派发的任务推回就绪队列
+func (s *Scheduler) pushBack(ctx context.Context, taskID string) {
+	if err := s.readyQueue.Push(ctx, taskID); err != nil {
+		// 任务仍为 PENDING，兜底轮询会再次推入
+		logger.Errorf("Failed to push task %s back to ready queue: %v", taskID, err)
+	}
+}
+
+// sleepContext 等待 d 或 ctx 取消，ctx 取消时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"fmt"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+)
+
+// SetResourceCapacity 设置本调度器实例的资源槽位预算：RUNNING 任务占用的槽位（Task.ResourceSlots）之和
+// 不超过 capacity，放不下的任务保持 PENDING 并记录 BlockedReason。需求超过整个预算的任务在没有其他任务
+// 占用槽位时独占运行。0 表示不限制。须在 Start 之前调用
+func (s *Scheduler) SetResourceCapacity(capacity int) error {
+	if capacity < 0 {
+		return fmt.Errorf("resource capacity must be non-negative, got %d", capacity)
+	}
+	s.resourceCapacity = capacity
+	metrics.RecordSchedulerResources(0, capacity)
+	return nil
+}
+
+// reserveResources 为任务预留资源槽位，预算不足时记录阻塞原因并返回 false
+func (s *Scheduler) reserveResources(task *model.Task) bool {
+	if s.resourceCapacity == 0 {
+		return true
+	}
+	need := task.Slots()
+
+	s.resourcesMu.Lock()
+	fits := s.resourcesInUse == 0 || s.resourcesInUse+need <= s.resourceCapacity
+	if fits {
+		s.resourcesInUse += need
+		s.reservations[task.ID] = need
+	}
+	inUse := s.resourcesInUse
+	s.resourcesMu.Unlock()
+
+	if fits {
+		metrics.RecordSchedulerResources(inUse, s.resourceCapacity)
+		return true
+	}
+
+	reason := s.resourceReason(need)
+	if task.BlockedReason != reason {
+		if err := s.repo.SetBlockedReason(task.ID, reason); err != nil {
+			logger.Errorf("Failed to record blocked reason for task %s: %v", task.ID, err)
+		}
+	}
+	return false
+}
+
+// releaseResources 释放任务预留的资源槽位，任务没有预留时返回 false
+func (s *Scheduler) releaseResources(taskID string) bool {
+	s.resourcesMu.Lock()
+	need, ok := s.reservations[taskID]
+	if ok {
+		delete(s.reservations, taskID)
+		s.resourcesInUse -= need
+	}
+	inUse := s.resourcesInUse
+	s.resourcesMu.Unlock()
+
+	if ok {
+		metrics.RecordSchedulerResources(inUse, s.resourceCapacity)
+	}
+	return ok
+}
+
+// resourcesAvailable 当前是否能为需要 need 个槽位的任务预留资源，不做预留
+func (s *Scheduler) resourcesAvailable(need int) bool {
+	if s.resourceCapacity == 0 {
+		return true
+	}
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+	return s.resourcesInUse == 0 || s.resourcesInUse+need <= s.resourceCapacity
+}
+
+// resourceReason 资源槽位不足时的阻塞原因
+func (s *Scheduler) resourceReason(need int) string {
+	return fmt.Sprintf("waiting for %d resource slots (capacity %d)", need, s.resourceCapacity)
+}
+
+// resourceUsage 当前占用的资源槽位和预算
+func (s *Scheduler) resourceUsage() (inUse, capacity int) {
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+	return s.resourcesInUse, s.resourceCapacity
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"taskflow/internal/logger"
+	"taskflow/internal/model"
+)
+
+// retryDelay 按错误分类和任务的重试策略判断失败后是否自动重试，返回重试前的等待时间。
+// fatal 错误不重试；retryable 和 rate_limited 错误在未设置策略时使用默认策略；
+// 其余错误只在设置了策略且匹配 RetryableErrors / RetryOnTimeout 时重试
+func retryDelay(task *model.Task, errMsg string, errClass model.ErrorClass, retryAfter time.Duration) (time.Duration, bool) {
+	policy := task.RetryPolicy
+	switch errClass {
+	case model.ErrorClassFatal:
+		return 0, false
+	case model.ErrorClassRetryable, model.ErrorClassRateLimited:
+		if policy == nil {
+			policy = &model.RetryPolicy{}
+		}
+	default:
+		if policy == nil || !policy.Retryable(errMsg, errClass == model.ErrorClassTimeout) {
+			return 0, false
+		}
+	}
+
+	attempt := task.RetryCount + 1
+	if attempt >= task.MaxAttempts() {
+		return 0, false
+	}
+
+	delay := policy.Delay(attempt)
+	if errClass == model.ErrorClassRateLimited {
+		delay = min(max(delay*rateLimitBackoffFactor, retryAfter, model.DefaultRetryInitialDelay), model.MaxRetryDelay)
+	}
+	return delay, true
+}
+
+// scheduleRetry 把失败的任务重置为 PENDING，退避到期后唤醒调度器；meta 为失败这次执行的事件元数据，可以为 nil。
+// 重试次数由仓储在状态变更的同一次写入中累加，任务已被其他实例处理时返回 ErrStatusMismatch
+func (s *Scheduler) scheduleRetry(task *model.Task, errMsg string, errClass model.ErrorClass, delay time.Duration, meta map[string]string) error {
+	attempt := task.RetryCount + 1
+	var nextRunAt *time.Time
+	if delay > 0 {
+		t := s.clock.Now().Add(delay)
+		nextRunAt = &t
+	}
+
+	if meta != nil {
+		meta[model.EventMetaRetryDelayMs] = strconv.FormatInt(delay.Milliseconds(), 10)
+	}
+
+	message := fmt.Sprintf("retry %d/%d after %s: %s", attempt, task.MaxAttempts()-1, delay, classifiedMessage(errClass, errMsg))
+	if err := s.repo.ScheduleRetry(task.ID, model.TaskStatusRunning, task.RetryCount, nextRunAt, errMsg, errClass, "scheduler", message, s.instanceID, meta); err != nil {
+		return err
+	}
+	logger.Infof("Task %s failed, retrying in %s (attempt %d)", task.ID, delay, attempt+1)
+
+	task.Status = model.TaskStatusPending
+	task.RetryCount = attempt
+	task.NextRunAt = nextRunAt
+	task.ErrorMessage = errMsg
+	task.ErrorClass = errClass
+	s.emitTaskChange(task, model.TaskStatusRunning, model.TaskStatusPending)
+
+	if delay > 0 {
+		s.clock.AfterFunc(delay, s.Wake)
+	} else {
+		s.Wake()
+	}
+	return nil
+}
+
+// classifiedMessage 在事件消息前标注错误分类，未分类的错误保持原样
+func classifiedMessage(errClass model.ErrorClass, errMsg string) string {
+	if errClass == model.ErrorClassUnknown {
+		return errMsg
+	}
+	return fmt.Sprintf("[%s] %s", errClass, errMsg)
+}
@@ -2,103 +2,145 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"taskflow/internal/clock"
 	"taskflow/internal/logger"
 	"taskflow/internal/metrics"
 	"taskflow/internal/model"
+	"taskflow/internal/notify"
+	"taskflow/internal/queue"
+	"taskflow/internal/redact"
 	"taskflow/internal/repository"
+	"taskflow/internal/secrets"
+	"taskflow/internal/storage"
 )
 
 // Scheduler 任务调度器
 type Scheduler struct {
-	repo            *repository.TaskRepository
+	repo            repository.TaskStore
 	stateMachine    *StateMachine
 	depChecker      *DefaultDependencyChecker
 	workerPool      *WorkerPool
+	autoscaler      *autoscaler // 未启用自动伸缩时为 nil
+	executors       *ExecutorRegistry
+	clock           clock.Clock // 轮询、重试退避、暂停窗口和心跳使用的时钟
 	pollingInterval time.Duration
 	maxPending      int
+	wakeCh          chan struct{} // 事件唤醒，容量 1 以合并突发唤醒
+	deferred        int32         // 因工作池饱和推迟了调度（原子操作），队列腾出空位时唤醒
+
+	// 就绪任务队列（可选），启用后轮询只负责推入，由消费者弹出执行
+	readyQueue   queue.Queue
+	slotFreed    chan struct{} // 工作池队列腾出空位，容量 1
+	consumerDone chan struct{}
+
+	// 实例标识与心跳；workerLabels 为实例标签，与任务的节点选择器匹配
+	instanceID        string
+	hostname          string
+	workerLabels      map[string]string
+	startedAt         time.Time
+	heartbeatInterval time.Duration
+	heartbeatDone     chan struct{}
+
+	// 检查点：停止时保存运行状态，首次启动时恢复；baseWorkers 为首次启动时按配置的工作池大小
+	checkpointName string
+	baseWorkers    int
+
+	// 状态变更订阅
+	listenersMu  sync.RWMutex
+	listeners    []TaskChangeListener
+	logListeners []TaskLogListener
+	notifier     *notify.Notifier
+
+	// 产物存储（可选），用于转存过大的任务输出
+	artifacts       storage.BlobStore
+	maxInlineOutput int
+
+	// 密钥管理器（可选），执行前解析 InputParams 中的密钥引用
+	secretManager *secrets.Manager
+
+	// 敏感参数脱敏（可选），执行日志中掩盖敏感参数值
+	redactor *redact.Redactor
+
+	// 单例任务类型：同类型同时最多一个任务 RUNNING；反亲和：任务类型 -> 不能同时运行的其他类型
+	singletonTypes map[string]bool
+	antiAffinity   map[string][]string
+
+	// 暂停调度的时间窗口（如维护窗口），窗口内匹配的任务保持 PENDING
+	blackoutWindows []BlackoutWindow
+	blackoutWakesMu sync.Mutex
+	blackoutWakes   map[int64]bool // 已安排在窗口结束时唤醒的时刻
+
+	// 优先级继承：不低于该优先级的等待任务临时提升上游的优先级，UNSPECIFIED 表示不启用
+	inheritFrom model.TaskPriority
+
+	// 资源槽位预算，0 表示不限制；reservations 记录 RUNNING 任务预留的槽位
+	resourceCapacity int
+	resourcesMu      sync.Mutex
+	resourcesInUse   int
+	reservations     map[string]int
+
+	// mu 串行化 Start/Stop 并保护工作池、伸缩器和 ctx；运行阶段和计数在 state 中
+	mu     sync.RWMutex
+	state  *schedulerState
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 运行中任务的取消句柄
+	executionsMu sync.Mutex
+	executions   map[string]*execution
+	cancelGrace  time.Duration
+}
 
-	mu      sync.RWMutex
-	running bool
-	ctx     context.Context
-	cancel  context.CancelFunc
+// ErrWorkerPoolSaturated 工作池队列已满，任务保持 PENDING 等待下次调度
+var ErrWorkerPoolSaturated = errors.New("worker pool saturated")
 
-	// 状态
-	statusMu     sync.RWMutex
-	pendingCnt   int
-	runningCnt   int
-	scheduledCnt int
-	finishedCnt  int
-}
+// TaskChangeListener 任务状态变更监听器
+type TaskChangeListener func(task *model.Task, from, to model.TaskStatus)
 
 // SchedulerStatus 调度器状态
 type SchedulerStatus struct {
-	IsRunning   bool   `json:"is_running"`
-	PendingCnt  int    `json:"pending_count"`
-	RunningCnt  int    `json:"running_count"`
-	ScheduledCnt int   `json:"scheduled_count"`
-	FinishedCnt int    `json:"finished_count"`
-	WorkerCount int    `json:"worker_count"`
-}
-
-// WorkerPool 工作池
-type WorkerPool struct {
-	size    int
-	workers chan struct{}
-	tasks   chan string // task IDs
-	wg      sync.WaitGroup
-}
-
-// NewWorkerPool 创建工作池
-func NewWorkerPool(size int) *WorkerPool {
-	return &WorkerPool{
-		size:    size,
-		workers: make(chan struct{}, size),
-		tasks:   make(chan string, size*2),
-	}
-}
-
-// Run 开始处理任务
-func (wp *WorkerPool) Run(handler func(taskID string)) {
-	for i := 0; i < wp.size; i++ {
-		wp.wg.Add(1)
-		go func() {
-			defer wp.wg.Done()
-			for taskID := range wp.tasks {
-				handler(taskID)
-			}
-		}()
-	}
-}
-
-// Submit 提交任务
-func (wp *WorkerPool) Submit(taskID string) bool {
-	select {
-	case wp.tasks <- taskID:
-		return true
-	default:
-		return false
-	}
-}
-
-// Stop 停止工作池
-func (wp *WorkerPool) Stop() {
-	close(wp.tasks)
-	wp.wg.Wait()
+	IsRunning    bool `json:"is_running"`
+	IsPaused     bool `json:"is_paused"` // 暂停派发新任务，运行中的任务继续执行
+	PendingCnt   int  `json:"pending_count"`
+	RunningCnt   int  `json:"running_count"`
+	ScheduledCnt int  `json:"scheduled_count"`
+	FinishedCnt  int  `json:"finished_count"`
+	WorkerCount  int  `json:"worker_count"`
+
+	ResourceCapacity   int `json:"resource_capacity"`     // 资源槽位预算，0 表示不限制
+	ResourceSlotsInUse int `json:"resource_slots_in_use"` // RUNNING 任务占用的资源槽位
+
+	Autoscale *AutoscaleStatus `json:"autoscale,omitempty"` // 未启用自动伸缩时为空
 }
 
 // NewScheduler 创建调度器
-func NewScheduler(repo *repository.TaskRepository) *Scheduler {
+func NewScheduler(repo repository.TaskStore) *Scheduler {
 	s := &Scheduler{
 		repo:            repo,
 		stateMachine:    NewStateMachine(),
 		depChecker:      NewDefaultDependencyChecker(repo),
+		executors:       NewExecutorRegistry(),
+		clock:           clock.Real,
+		state:           newSchedulerState(),
 		pollingInterval: 5 * time.Second,
 		maxPending:      100,
+		wakeCh:          make(chan struct{}, 1),
+		slotFreed:       make(chan struct{}, 1),
+		executions:      make(map[string]*execution),
+		reservations:    make(map[string]int),
+		cancelGrace:     defaultCancelGracePeriod,
+
+		heartbeatInterval: defaultHeartbeatInterval,
 	}
+	s.instanceID, s.hostname = newInstanceID()
 
 	// 默认 10 个 worker
 	s.workerPool = NewWorkerPool(10)
@@ -111,78 +153,154 @@ func NewScheduler(repo *repository.TaskRepository) *Scheduler {
 
 // setupTaskHandler 设置任务处理函数
 func (s *Scheduler) setupTaskHandler() {
-	s.workerPool.Run(func(taskID string) {
-		s.executeTask(taskID)
-	})
+	s.workerPool.dequeue = s.onQueueSlotFreed
+	s.workerPool.Run(s.executeTask)
 }
 
-// Start 启动调度器
+// Start 启动调度器，已在运行时直接返回；正在停止时等待停止完成后重新启动
 func (s *Scheduler) Start(ctx context.Context) {
-	s.mu.Lock()
-	if s.running {
+	// 进入停止阶段须持有 s.mu，持锁后仍在停止说明等待期间又开始了一次停止
+	for {
+		s.state.awaitSettled()
+		s.mu.Lock()
+		if s.state.currentPhase() != schedulerStopping {
+			break
+		}
 		s.mu.Unlock()
+	}
+	defer s.mu.Unlock()
+
+	if s.state.currentPhase() == schedulerRunning {
 		return
 	}
 
-	s.ctx, s.cancel = context.WithCancel(ctx)
-	s.running = true
-	s.mu.Unlock()
+	// ctx 等字段在进入运行阶段前设置，TrySchedule 进入后即可读取
+	runCtx, cancel := context.WithCancel(ctx)
+	s.ctx, s.cancel = runCtx, cancel
+	s.heartbeatDone = make(chan struct{})
+	s.consumerDone = nil
+	if s.readyQueue != nil {
+		s.consumerDone = make(chan struct{})
+	}
+	// 停止后重新启动时恢复工作池的分发循环，首次启动时工作池已在运行
+	s.workerPool.Run(s.executeTask)
+	// 进程内重新启动时运行状态仍在内存中，只在首次启动时恢复检查点
+	if s.startedAt.IsZero() {
+		s.restoreCheckpoint()
+	}
+	s.startedAt = s.clock.Now()
+	s.state.start()
 
-	// 启动轮询循环
-	go s.pollingLoop()
+	// 启动轮询循环、就绪队列消费者和实例心跳
+	go s.pollingLoop(runCtx)
+	if s.consumerDone != nil {
+		go s.consumeReadyQueue(runCtx, s.consumerDone)
+	}
+	go s.heartbeatLoop(runCtx, s.heartbeatDone)
+
+	if s.autoscaler != nil {
+		go s.autoscaler.run(runCtx)
+	}
+	metrics.RecordWorkerPoolSize(s.workerPool.Size())
 
-	logger.Infof("Scheduler started")
+	logger.Infof("Scheduler %s started", s.instanceID)
 }
 
-// Stop 停止调度器
+// Stop 停止调度器，等待进行中的派发返回、工作池中的任务执行完毕并保存检查点，之后可再次 Start。
+// 未运行时直接返回，并发调用时都在停止完成后返回
 func (s *Scheduler) Stop() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if !s.running {
+	if !s.state.beginStop() {
+		s.mu.Unlock()
+		s.state.awaitSettled()
 		return
 	}
-
 	s.cancel()
-	s.running = false
-	s.workerPool.Stop()
+	pool, consumerDone, heartbeatDone := s.workerPool, s.consumerDone, s.heartbeatDone
+	s.mu.Unlock()
+
+	// 停止期间 Start 等待停止完成，以下等待无需持有 s.mu，GetStatus 和心跳不会被阻塞。
+	// 先等待进行中的派发和就绪队列消费者退出，避免其在工作池停止后提交任务
+	s.state.waitIdle()
+	if consumerDone != nil {
+		<-consumerDone
+	}
+	pool.Stop()
+	s.saveCheckpoint(pool)
+	s.deregisterInstance(heartbeatDone)
+	s.state.finishStop()
 
 	logger.Infof("Scheduler stopped")
 }
 
+// Pause 暂停派发新任务，运行中的任务继续执行，返回时进行中的派发已结束。
+// 暂停期间创建的任务保持 PENDING，Stop 会清除暂停；调度器未运行时返回 ErrSchedulerNotRunning
+func (s *Scheduler) Pause() error {
+	if err := s.state.setPaused(true); err != nil {
+		return err
+	}
+	s.state.waitIdle()
+	logger.Infof("Scheduler %s paused", s.instanceID)
+	return nil
+}
+
+// Resume 恢复派发并立即评估待处理任务；调度器未运行时返回 ErrSchedulerNotRunning
+func (s *Scheduler) Resume() error {
+	if err := s.state.setPaused(false); err != nil {
+		return err
+	}
+	s.Wake()
+	logger.Infof("Scheduler %s resumed", s.instanceID)
+	return nil
+}
+
 // GetStatus 获取调度器状态
 func (s *Scheduler) GetStatus() SchedulerStatus {
-	s.statusMu.RLock()
-	defer s.statusMu.RUnlock()
-
-	return SchedulerStatus{
-		IsRunning:   s.running,
-		PendingCnt:  s.pendingCnt,
-		RunningCnt:  s.runningCnt,
-		ScheduledCnt: s.scheduledCnt,
-		FinishedCnt: s.finishedCnt,
-		WorkerCount: s.workerPool.size,
+	status := s.state.status()
+	status.ResourceSlotsInUse, status.ResourceCapacity = s.resourceUsage()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status.WorkerCount = s.workerPool.Size()
+	if s.autoscaler != nil {
+		status.Autoscale = s.autoscaler.status()
 	}
+	return status
 }
 
-// pollingLoop 轮询待处理任务
-func (s *Scheduler) pollingLoop() {
-	ticker := time.NewTicker(s.pollingInterval)
+// pollingLoop 事件唤醒时立即评估待处理任务，轮询仅作为兜底扫描
+func (s *Scheduler) pollingLoop(ctx context.Context) {
+	ticker := s.clock.NewTicker(s.pollingInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-s.ctx.Done():
+		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			s.pollPendingTasks()
+		case <-s.wakeCh:
+			s.pollPendingTasks(ctx)
+		case <-ticker.C():
+			s.pollPendingTasks(ctx)
 		}
 	}
 }
 
+// Wake 唤醒调度器立即评估可调度任务（非阻塞，多次唤醒会合并）
+func (s *Scheduler) Wake() {
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
 // pollPendingTasks 轮询并调度待处理任务
-func (s *Scheduler) pollPendingTasks() {
-	tasks, err := s.repo.ListPending(s.maxPending)
+func (s *Scheduler) pollPendingTasks(ctx context.Context) {
+	// 暂停期间不查询待处理任务，恢复时由 Resume 唤醒
+	if !s.state.dispatching() {
+		return
+	}
+	s.inheritPriorities()
+	tasks, err := s.repo.ListPending(s.maxPending, s.clock.Now())
 	if err != nil {
 		logger.Errorf("Failed to list pending tasks: %v", err)
 		return
@@ -190,89 +308,243 @@ func (s *Scheduler) pollPendingTasks() {
 
 	for _, task := range tasks {
 		select {
-		case <-s.ctx.Done():
+		case <-ctx.Done():
 			return
 		default:
-			s.TrySchedule(task.ID)
+		}
+		// 工作池饱和时停止本轮调度，剩余任务保持 PENDING，队列腾出空位后再唤醒
+		if err := s.TrySchedule(task.ID); errors.Is(err, ErrWorkerPoolSaturated) {
+			break
 		}
 	}
 
-	s.statusMu.Lock()
-	s.pendingCnt = len(tasks)
-	s.statusMu.Unlock()
+	pending, running := s.state.setPending(len(tasks))
 
 	// 更新 Prometheus 指标
-	metrics.RecordTaskStatus("pending", s.pendingCnt)
-	metrics.RecordTaskStatus("running", s.runningCnt)
+	metrics.RecordTaskStatus("pending", pending)
+	metrics.RecordTaskStatus("running", running)
 }
 
-// TrySchedule 尝试调度任务
+// TrySchedule 尝试调度任务，调度器未在运行或已暂停时直接返回
 func (s *Scheduler) TrySchedule(taskID string) error {
-	s.statusMu.RLock()
-	running := s.running
-	s.statusMu.RUnlock()
-
-	if !running {
+	if !s.state.enter() {
 		return nil
 	}
+	defer s.state.leave()
+
+	task, err := s.readyTask(taskID)
+	if err != nil || task == nil {
+		return err
+	}
+
+	// 启用就绪队列时只推入任务 ID，由消费者弹出后认领执行；带路由约束的任务可能被不匹配的实例弹出，
+	// 由匹配的实例直接认领，不进入共享的就绪队列
+	if s.readyQueue != nil && !task.HasRouting() {
+		return s.enqueueReady(taskID)
+	}
+	return s.dispatch(task)
+}
+
+// readyTask 获取依赖已满足且仍为 PENDING 的任务，不可调度时返回 nil；
+// 上游依赖最终未成功、下游已不可能满足依赖时将任务标记为 SKIPPED
+func (s *Scheduler) readyTask(taskID string) (*model.Task, error) {
+	// 获取任务
+	task, err := repository.FindTask(s.repo, taskID)
+	if err != nil || task == nil {
+		return nil, err
+	}
+
+	// 检查任务状态
+	if task.Status != model.TaskStatusPending {
+		return nil, nil
+	}
 
 	// 检查依赖
-	ready, err := s.depChecker.CheckDependencies(taskID)
+	ready, unreachable, stalled, err := s.depChecker.Evaluate(task)
 	if err != nil {
 		logger.Infof("Failed to check dependencies for task %s: %v", taskID, err)
-		return err
+		return nil, err
+	}
+	if unreachable != nil {
+		s.skipTask(task, unreachable)
+		return nil, nil
 	}
 	if !ready {
-		return nil // 依赖未满足，等待
+		s.recordStalledDependencies(task, stalled)
+		return nil, nil // 依赖未满足，等待
 	}
 
-	// 获取任务
-	task, err := s.repo.GetByID(taskID)
+	// 重试退避期间和暂停调度窗口内不调度
+	now := s.clock.Now()
+	if task.NextRunAt != nil && task.NextRunAt.After(now) {
+		return nil, nil
+	}
+	if s.inBlackout(task, now) {
+		return nil, nil
+	}
+	// 路由约束不匹配本实例时保持 PENDING，由匹配的实例认领；不记录 BlockedReason，避免各实例互相覆盖
+	if task.RoutingMismatch(s.instanceID, s.hostname, s.workerLabels) != "" {
+		return nil, nil
+	}
+	return task, nil
+}
+
+// refreshStalledDependents 上游进入或离开未成功的终态（失败、取消、超时、跳过，或被重试、重新运行）后，
+// 重新评估直接依赖它的 PENDING 任务并更新 BlockedReason。这些任务不在 ListPending 的结果中，调度器轮询时不会评估它们
+func (s *Scheduler) refreshStalledDependents(upstreamID string) {
+	pending := model.TaskStatusPending
+	dependents, err := s.repo.ListDependents([]string{upstreamID}, &pending)
 	if err != nil {
-		return err
+		logger.Errorf("Failed to list dependents of task %s: %v", upstreamID, err)
+		return
 	}
-	if task == nil {
-		return nil
+	for _, task := range dependents {
+		s.refreshStalledReason(task)
 	}
+}
 
-	// 检查任务状态
-	if task.Status != model.TaskStatusPending {
+// refreshStalledReason 评估 PENDING 任务的依赖，依赖未满足时更新 BlockedReason；
+// 依赖已满足或需要跳过的任务留给调度器处理
+func (s *Scheduler) refreshStalledReason(task *model.Task) {
+	ready, unreachable, stalled, err := s.depChecker.Evaluate(task)
+	if err != nil || unreachable != nil || ready {
+		return
+	}
+	s.recordStalledDependencies(task, stalled)
+}
+
+// recordStalledDependencies 依赖未满足时更新任务的 BlockedReason：有按 wait 处理且最终未成功的上游时列出这些上游及其状态，
+// 使其与普通的等待区分开；上游被重试后清除
+func (s *Scheduler) recordStalledDependencies(task *model.Task, stalled []model.DependencyState) {
+	reason := ""
+	if len(stalled) > 0 {
+		reason = "dependencies did not succeed: " + describeDependencies(stalled)
+	}
+	if task.BlockedReason != reason {
+		if err := s.repo.SetBlockedReason(task.ID, reason); err != nil {
+			logger.Errorf("Failed to record blocked reason for task %s: %v", task.ID, err)
+		}
+	}
+}
+
+// skipTask 上游依赖最终未成功时把 PENDING 任务标记为 SKIPPED 并记录原因，
+// 随后唤醒调度器，使跳过继续向该任务的下游传播
+func (s *Scheduler) skipTask(task *model.Task, dep *model.Task) {
+	message := fmt.Sprintf("skipped: dependency %s is %s", dep.ID, dep.Status)
+	if err := s.repo.UpdateStatusWithInstanceEvent(task.ID, model.TaskStatusPending, model.TaskStatusSkipped, "scheduler", message, s.instanceID, nil); err != nil {
+		logger.Errorf("Failed to skip task %s: %v", task.ID, err)
+		return
+	}
+	logger.Infof("Task %s %s", task.ID, message)
+
+	task.Status = model.TaskStatusSkipped
+	task.BlockedReason = ""
+	s.emitTaskChange(task, model.TaskStatusPending, model.TaskStatusSkipped)
+	s.checkDependentTasks(task.ID)
+}
+
+// dispatch 认领任务（PENDING -> RUNNING）并提交到本地工作池
+func (s *Scheduler) dispatch(task *model.Task) error {
+	taskID := task.ID
+
+	// 工作池已满：推迟调度，不改变任务状态
+	if s.workerPool.Saturated() {
+		s.deferScheduling(taskID)
+		return ErrWorkerPoolSaturated
+	}
+
+	// 资源槽位不足：保持 PENDING，运行中的任务释放槽位后再调度
+	if !s.reserveResources(task) {
 		return nil
 	}
 
-	// 原子更新状态为 RUNNING
-	err = s.repo.UpdateStatusWithEvent(taskID, model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "task scheduled")
-	if err != nil {
+	// 原子更新状态为 RUNNING；有互斥的任务（单例类型、反亲和类型、同一分组）在运行时保持 PENDING
+	if err := s.claim(task); err != nil {
+		s.releaseResources(taskID)
+		if errors.Is(err, repository.ErrExclusionBusy) {
+			return nil
+		}
 		logger.Infof("Failed to schedule task %s: %v", taskID, err)
 		return err
 	}
 
-	// 提交到工作池
-	if s.workerPool.Submit(taskID) {
-		s.statusMu.Lock()
-		s.scheduledCnt++
-		s.statusMu.Unlock()
-		logger.Infof("Task %s scheduled", taskID)
+	task.MarkRunning()
+	task.ExecutedBy = s.instanceID
+	if task.BasePriority != model.TaskPriorityUnspecified {
+		s.restorePriority(task)
 	}
+	s.emitTaskChange(task, model.TaskStatusPending, model.TaskStatusRunning)
+
+	// 提交到工作池；并发调度导致队列在检查后被占满时退回 PENDING，不让任务滞留在 RUNNING
+	if !s.workerPool.Submit(taskID) {
+		s.releaseResources(taskID)
+		s.requeue(task)
+		return ErrWorkerPoolSaturated
+	}
+
+	s.state.addScheduled()
+	logger.Infof("Task %s scheduled", taskID)
 
 	return nil
 }
 
+// deferScheduling 记录因工作池饱和推迟的调度
+func (s *Scheduler) deferScheduling(taskID string) {
+	atomic.StoreInt32(&s.deferred, 1)
+	metrics.RecordSchedulerBackpressure("deferred")
+	logger.Infof("Worker pool saturated, deferring task %s", taskID)
+}
+
+// requeue 提交失败时将任务从 RUNNING 退回 PENDING
+func (s *Scheduler) requeue(task *model.Task) {
+	atomic.StoreInt32(&s.deferred, 1)
+	metrics.RecordSchedulerBackpressure("requeued")
+
+	err := s.repo.UpdateStatusWithInstanceEvent(task.ID, model.TaskStatusRunning, model.TaskStatusPending, "scheduler", "requeued: worker pool saturated", s.instanceID, s.executionMetadata(task))
+	if err != nil {
+		logger.Errorf("Failed to requeue task %s: %v", task.ID, err)
+		return
+	}
+	logger.Infof("Worker pool saturated, task %s requeued", task.ID)
+
+	task.Status = model.TaskStatusPending
+	task.StartedAt = nil
+	s.emitTaskChange(task, model.TaskStatusRunning, model.TaskStatusPending)
+}
+
+// onQueueSlotFreed 工作池队列腾出空位时，若之前有推迟的调度则唤醒调度器
+func (s *Scheduler) onQueueSlotFreed() {
+	select {
+	case s.slotFreed <- struct{}{}:
+	default:
+	}
+	if atomic.CompareAndSwapInt32(&s.deferred, 1, 0) {
+		s.Wake()
+	}
+}
+
 // executeTask 执行任务
 func (s *Scheduler) executeTask(taskID string) {
 	startTime := time.Now()
 
-	s.statusMu.Lock()
-	s.runningCnt++
-	s.statusMu.Unlock()
+	// 执行器之外（保存输出、监听器等）的 panic 不终止进程，任务仍为 RUNNING 时记为失败
+	defer func() {
+		if r := recover(); r != nil {
+			s.failPanickedTask(taskID, newPanicError(r, debug.Stack()))
+		}
+	}()
 
+	// 释放资源槽位后唤醒调度器，调度等待槽位的任务
 	defer func() {
-		s.statusMu.Lock()
-		s.runningCnt--
-		s.statusMu.Unlock()
+		if s.releaseResources(taskID) {
+			s.Wake()
+		}
+	}()
 
+	s.state.addRunning(1)
+	defer func() {
 		// 更新 Prometheus 指标
-		metrics.RecordTaskStatus("running", s.runningCnt)
+		metrics.RecordTaskStatus("running", s.state.addRunning(-1))
 	}()
 
 	logger.Infof("Executing task %s", taskID)
@@ -281,10 +553,15 @@ func (s *Scheduler) executeTask(taskID string) {
 	task, err := s.repo.GetByID(taskID)
 	if err != nil {
 		logger.Errorf("Failed to get task %s: %v", taskID, err)
-		metrics.RecordTaskError(task.TaskType, "get_error")
+		metrics.RecordTaskError("", "get_error")
 		return
 	}
 
+	// 有互斥条件的任务结束后唤醒调度器，调度被它阻塞的任务
+	if !s.exclusion(task).IsZero() {
+		defer s.Wake()
+	}
+
 	// 检查是否被取消
 	if task.Status == model.TaskStatusCancelled {
 		logger.Infof("Task %s was cancelled", taskID)
@@ -292,60 +569,118 @@ func (s *Scheduler) executeTask(taskID string) {
 	}
 
 	// 执行业务逻辑（这里应该是可扩展的 handler）
-	result, err := s.executeTaskHandler(task)
-	duration := time.Since(startTime).Seconds()
+	ctx, finish := s.startExecution(taskID)
+	result, err := s.executeTaskHandler(ctx, task)
+	elapsed := time.Since(startTime)
+	duration := elapsed.Seconds()
+
+	// 被取消的任务由取消方记录 CANCELLED，忽略执行结果
+	if finish() {
+		logger.Infof("Task %s execution cancelled", taskID)
+		metrics.RecordTaskDuration(task.TaskType, "cancelled", duration)
+		return
+	}
 
+	meta := s.executionMetadata(task)
+	meta[model.EventMetaDurationMs] = strconv.FormatInt(elapsed.Milliseconds(), 10)
+	var inputReq *InputRequest
+	if errors.As(err, &inputReq) {
+		// 执行器请求外部输入，挂起任务等待信号
+		s.suspendForInput(task, inputReq.Prompt, meta)
+		metrics.RecordTaskDuration(task.TaskType, "waiting_input", duration)
+		return
+	}
 	if err != nil {
 		// 执行失败，更新状态
-		s.handleTaskFailure(taskID, err.Error())
+		s.handleTaskFailure(taskID, err, meta)
 		metrics.RecordTaskDuration(task.TaskType, "failed", duration)
 		metrics.RecordTaskError(task.TaskType, "execution_error")
 		return
 	}
 
 	// 执行成功
-	s.handleTaskSuccess(taskID, result)
+	s.handleTaskSuccess(taskID, result, meta)
 	metrics.RecordTaskDuration(task.TaskType, "succeeded", duration)
 }
 
-// executeTaskHandler 实际执行任务逻辑
-func (s *Scheduler) executeTaskHandler(task *model.Task) (map[string]string, error) {
-	// TODO: 实现具体的任务执行逻辑
-	// 这里可以扩展为根据 task.TaskType 调用不同的处理器
+// failPanickedTask 记录执行过程中 recover 的 panic，任务仍为 RUNNING 时标记为失败，调用栈写入失败事件
+func (s *Scheduler) failPanickedTask(taskID string, perr *PanicError) {
+	logger.Errorf("Recovered panic while executing task %s: %v\n%s", taskID, perr.Value, perr.Stack)
+	metrics.RecordTaskError("", "panic")
 
-	logger.Infof("Running task %s of type %s", task.ID, task.TaskType)
+	meta := map[string]string{
+		model.EventMetaWorker:     s.instanceID,
+		model.EventMetaPanicStack: perr.Stack,
+	}
+	msg := perr.Error()
+	err := s.repo.FailTask(taskID, msg, model.ErrorClassUnknown, "scheduler", msg, s.instanceID, meta)
+	if errors.Is(err, repository.ErrStatusMismatch) {
+		return // 已记录结果（如在通知监听器时 panic），保持原状态
+	}
+	if err != nil {
+		logger.Errorf("Failed to mark panicked task %s as failed: %v", taskID, err)
+		return
+	}
+	s.checkDependentTasks(taskID)
 
-	// 模拟执行
-	time.Sleep(100 * time.Millisecond)
+	// 监听器可能正是 panic 的来源，再次 panic 时只记录日志
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("Task change listener panicked for task %s: %v", taskID, r)
+		}
+	}()
+	if task, err := s.repo.GetByID(taskID); err == nil && task != nil {
+		s.emitTaskChange(task, model.TaskStatusRunning, model.TaskStatusFailed)
+	}
+}
 
-	// 返回结果
+// executionMetadata 执行相关状态事件的元数据：第几次执行和执行实例
+func (s *Scheduler) executionMetadata(task *model.Task) map[string]string {
 	return map[string]string{
-		"status": "completed",
-		"output": "task executed successfully",
-	}, nil
+		model.EventMetaAttempt: strconv.Itoa(int(task.RetryCount) + 1),
+		model.EventMetaWorker:  s.instanceID,
+	}
 }
 
-// handleTaskSuccess 处理任务成功
-func (s *Scheduler) handleTaskSuccess(taskID string, result map[string]string) {
-	err := s.repo.UpdateStatusWithEvent(taskID, model.TaskStatusRunning, model.TaskStatusSucceeded, "scheduler", "task completed")
+// executeTaskHandler 根据任务类型调用注册的执行器
+func (s *Scheduler) executeTaskHandler(ctx context.Context, task *model.Task) (result map[string]string, err error) {
+	// 执行器 panic 按执行失败处理，不影响工作池中的其他任务
+	defer func() {
+		if r := recover(); r != nil {
+			perr := newPanicError(r, debug.Stack())
+			logger.Errorf("Executor for task %s panicked: %v\n%s", task.ID, r, perr.Stack)
+			metrics.RecordTaskError(task.TaskType, "panic")
+			result, err = nil, perr
+		}
+	}()
+
+	resolved, secretValues, err := s.resolveSecrets(task)
 	if err != nil {
-		logger.Errorf("Failed to update task %s status: %v", taskID, err)
-		return
+		return nil, err
 	}
+	// 任务日志中掩盖敏感参数、信号送达的敏感输入和密钥明文
+	masked := append(s.redactor.SensitiveValues(resolved.InputParams), secretValues...)
+	masked = append(masked, s.redactor.SensitiveValues(task.SignalInput)...)
+	ctx = withExecutionContext(ctx, s.newExecutionContext(task, masked))
+	return s.executors.Get(task.TaskType).Execute(ctx, resolved)
+}
 
-	// 更新任务输出结果
-	task, err := s.repo.GetByID(taskID)
-	if err == nil && task != nil {
-		task.OutputResult = result
-		s.repo.Update(task)
+// handleTaskSuccess 处理任务成功，meta 为本次执行的事件元数据
+func (s *Scheduler) handleTaskSuccess(taskID string, result map[string]string, meta map[string]string) {
+	// 过大的输出先转存到产物存储，状态和输出在同一次写入中更新，与取消并发时不会只写入一半
+	output := &model.Task{ID: taskID}
+	s.storeOutput(output, result)
+	if err := s.repo.CompleteTask(taskID, model.TaskStatusRunning, output.OutputResult, output.OutputRef, "scheduler", "task completed", s.instanceID, meta); err != nil {
+		logger.Errorf("Failed to complete task %s: %v", taskID, err)
+		return
 	}
 
-	s.statusMu.Lock()
-	s.finishedCnt++
-	s.statusMu.Unlock()
+	if task, err := s.repo.GetByID(taskID); err == nil && task != nil {
+		s.emitTaskChange(task, model.TaskStatusRunning, model.TaskStatusSucceeded)
+	}
 
 	// 更新 Prometheus 指标
-	metrics.RecordTaskStatus("succeeded", s.finishedCnt)
+	metrics.RecordTaskStatus("succeeded", s.state.addFinished())
 
 	logger.Infof("Task %s succeeded", taskID)
 
@@ -353,47 +688,175 @@ func (s *Scheduler) handleTaskSuccess(taskID string, result map[string]string) {
 	s.checkDependentTasks(taskID)
 }
 
-// handleTaskFailure 处理任务失败
-func (s *Scheduler) handleTaskFailure(taskID string, errMsg string) {
+// suspendForInput 把执行器请求外部输入的任务挂起为 WAITING_INPUT，meta 为本次执行的事件元数据
+func (s *Scheduler) suspendForInput(task *model.Task, prompt string, meta map[string]string) {
+	err := s.repo.SuspendForInput(task.ID, prompt, "scheduler", "waiting for input", s.instanceID, meta)
+	if errors.Is(err, repository.ErrStatusMismatch) {
+		return // 挂起前任务已被取消等，保持原状态
+	}
+	if err != nil {
+		logger.Errorf("Failed to suspend task %s for input: %v", task.ID, err)
+		return
+	}
+	logger.Infof("Task %s is waiting for input", task.ID)
+
+	if task, err := s.repo.GetByID(task.ID); err == nil && task != nil {
+		s.emitTaskChange(task, model.TaskStatusRunning, model.TaskStatusWaitingInput)
+	}
+}
+
+// handleTaskFailure 处理任务失败，meta 为本次执行的事件元数据，补充错误分类后写入失败或重试事件
+func (s *Scheduler) handleTaskFailure(taskID string, execErr error, meta map[string]string) {
 	task, err := s.repo.GetByID(taskID)
 	if err != nil || task == nil {
 		return
 	}
+	errMsg := execErr.Error()
+	errClass, retryAfter := ClassifyError(execErr)
+	if errClass != model.ErrorClassUnknown {
+		meta[model.EventMetaErrorClass] = string(errClass)
+	}
+	var perr *PanicError
+	if errors.As(execErr, &perr) {
+		meta[model.EventMetaPanicStack] = perr.Stack
+	}
 
-	// 检查是否可以重试
-	if task.CanRetry() {
-		// 重置为 Pending，等待下次调度
-		err = s.repo.UpdateStatusWithEvent(taskID, model.TaskStatusRunning, model.TaskStatusPending, "scheduler", fmt.Sprintf("retry: %s", errMsg))
-		logger.Infof("Task %s failed, will retry (attempt %d/%d)", taskID, task.RetryCount+1, task.MaxRetries)
-	} else {
-		// 标记为失败
-		err = s.repo.UpdateStatusWithEvent(taskID, model.TaskStatusRunning, model.TaskStatusFailed, "scheduler", errMsg)
-		logger.Infof("Task %s failed permanently", taskID)
-		metrics.RecordTaskError(task.TaskType, "permanent_failure")
+	// 按错误分类和重试策略自动重试
+	if delay, ok := retryDelay(task, errMsg, errClass, retryAfter); ok {
+		if err := s.scheduleRetry(task, errMsg, errClass, delay, meta); err != nil {
+			logger.Errorf("Failed to schedule retry for task %s: %v", taskID, err)
+		}
+		return
 	}
 
-	if err != nil {
+	// 不重试或重试次数用尽，标记为失败
+	if err := s.repo.FailTask(taskID, errMsg, errClass, "scheduler", classifiedMessage(errClass, errMsg), s.instanceID, meta); err != nil {
 		logger.Errorf("Failed to update task %s status: %v", taskID, err)
+		return
 	}
+	logger.Infof("Task %s failed permanently after %d attempt(s)", taskID, task.RetryCount+1)
+	metrics.RecordTaskError(task.TaskType, "permanent_failure")
+
+	task.Status = model.TaskStatusFailed
+	task.ErrorMessage = errMsg
+	task.ErrorClass = errClass
+	s.emitTaskChange(task, model.TaskStatusRunning, model.TaskStatusFailed)
+
+	// 下游任务可能因此被跳过
+	s.checkDependentTasks(taskID)
 }
 
-// checkDependentTasks 检查依赖此任务的其他任务
+// checkDependentTasks 任务完成后唤醒调度器评估下游任务
 func (s *Scheduler) checkDependentTasks(completedTaskID string) {
-	// TODO: 实现依赖查询
-	// 目前需要通过其他方式触发下游任务调度
 	logger.Infof("Checking dependent tasks for %s", completedTaskID)
+	s.Wake()
 }
 
-// SetWorkerCount 设置 worker 数量
+// SetWorkerCount 设置 worker 数量，调度器未运行时同样生效；启用自动伸缩时由伸缩器管理，调用无效
 func (s *Scheduler) SetWorkerCount(count int) {
+	if err := s.ResizeWorkerPool(count); err != nil {
+		logger.Warnf("SetWorkerCount(%d) ignored: %v", count, err)
+	}
+}
+
+// ResizeWorkerPool 调整 worker 数量，立即生效且不丢弃已排队的任务
+func (s *Scheduler) ResizeWorkerPool(size int) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.autoscaler != nil {
+		return fmt.Errorf("worker pool is autoscaled between %d and %d workers", s.autoscaler.cfg.MinWorkers, s.autoscaler.cfg.MaxWorkers)
+	}
+	from := s.workerPool.Size()
+	if err := s.workerPool.Resize(size); err != nil {
+		return err
+	}
+
+	metrics.RecordWorkerPoolSize(size)
+	logger.Infof("Worker pool resized from %d to %d", from, size)
+	return nil
+}
+
+// WorkerPoolStats 返回工作池大小、忙碌 worker 数、排队任务数以及是否自动伸缩
+func (s *Scheduler) WorkerPoolStats() (size, busy, queueDepth int, autoscaled bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.workerPool.Size(), s.workerPool.Busy(), s.workerPool.QueueDepth(), s.autoscaler != nil
+}
+
+// SetAutoscale 启用工作池自动伸缩，须在 Start 之前调用
+func (s *Scheduler) SetAutoscale(cfg AutoscaleConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 停止旧的 worker pool
-	if s.running {
-		s.workerPool.Stop()
-		s.workerPool = NewWorkerPool(count)
-		s.setupTaskHandler()
+	if s.state.currentPhase() != schedulerIdle {
+		return fmt.Errorf("autoscale must be configured before the scheduler starts")
+	}
+
+	// 按伸缩上限重建工作池，队列容量随最大 worker 数调整
+	a := newAutoscaler(cfg, nil)
+	s.workerPool.Stop()
+	s.workerPool = newWorkerPool(a.cfg.MinWorkers, a.cfg.MaxWorkers*2)
+	s.setupTaskHandler()
+	a.pool = s.workerPool
+	a.clock = s.clock
+	s.autoscaler = a
+
+	return nil
+}
+
+// RegisterExecutor 注册任务类型的执行器
+func (s *Scheduler) RegisterExecutor(taskType string, exec Executor) {
+	s.executors.Register(taskType, exec)
+}
+
+// OnTaskChange 注册任务状态变更监听器
+func (s *Scheduler) OnTaskChange(listener TaskChangeListener) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// emitTaskChange 通知监听器和通知器
+func (s *Scheduler) emitTaskChange(task *model.Task, from, to model.TaskStatus) {
+	s.listenersMu.RLock()
+	listeners := s.listeners
+	notifier := s.notifier
+	s.listenersMu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(task, from, to)
+	}
+	notifier.NotifyTaskChange(task, from, to)
+
+	// 按 wait 处理的下游任务记录或清除阻塞原因
+	if endedUnsuccessfully(from) || endedUnsuccessfully(to) {
+		s.refreshStalledDependents(task.ID)
+	}
+}
+
+// endedUnsuccessfully 任务已结束但未成功
+func endedUnsuccessfully(status model.TaskStatus) bool {
+	return status.IsTerminal() && status != model.TaskStatusSucceeded
+}
+
+// SetNotifier 设置任务状态变更通知器
+func (s *Scheduler) SetNotifier(n *notify.Notifier) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.notifier = n
+}
+
+// SetClock 替换调度器、状态机和自动伸缩器使用的时钟，须在 Start 之前调用。测试中注入 clock.Fake
+// 后轮询、重试退避、暂停窗口和心跳只随 Fake.Advance 推进；执行耗时等指标仍按真实时间统计
+func (s *Scheduler) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+	s.stateMachine.clock = c
+	if s.autoscaler != nil {
+		s.autoscaler.clock = c
 	}
 }
 
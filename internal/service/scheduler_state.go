@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrSchedulerNotRunning 调度器未运行，暂停和恢复只能在运行时进行
+var ErrSchedulerNotRunning = errors.New("scheduler is not running")
+
+// schedulerPhase 调度器生命周期阶段
+type schedulerPhase int
+
+const (
+	schedulerIdle     schedulerPhase = iota // 未启动或已停止，可以启动
+	schedulerRunning                        // 运行中
+	schedulerStopping                       // 正在停止，等待进行中的调度和工作池中的任务结束
+)
+
+// schedulerState 调度器的运行阶段、暂停标记和计数，全部由 mu 保护，GetStatus 一次加锁即得到一致的快照。
+// 派发新任务（TrySchedule、就绪队列消费）通过 enter/leave 登记，停止和暂停时等待进行中的派发返回，
+// 避免任务在工作池停止后或暂停后被提交
+type schedulerState struct {
+	mu       sync.Mutex
+	changed  *sync.Cond // 阶段变化或 inflight 归零时广播
+	phase    schedulerPhase
+	paused   bool
+	inflight int // 进行中的派发数
+
+	pendingCnt   int
+	runningCnt   int
+	scheduledCnt int
+	finishedCnt  int
+}
+
+// newSchedulerState 创建未启动的调度器状态
+func newSchedulerState() *schedulerState {
+	st := &schedulerState{}
+	st.changed = sync.NewCond(&st.mu)
+	return st
+}
+
+// currentPhase 当前阶段
+func (st *schedulerState) currentPhase() schedulerPhase {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.phase
+}
+
+// awaitSettled 等待进行中的停止完成
+func (st *schedulerState) awaitSettled() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for st.phase == schedulerStopping {
+		st.changed.Wait()
+	}
+}
+
+// start 进入运行阶段，重新启动时清除暂停
+func (st *schedulerState) start() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.phase = schedulerRunning
+	st.paused = false
+}
+
+// beginStop 由运行中进入停止阶段，此后 enter 返回 false；未在运行时返回 false
+func (st *schedulerState) beginStop() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.phase != schedulerRunning {
+		return false
+	}
+	st.phase = schedulerStopping
+	st.changed.Broadcast()
+	return true
+}
+
+// finishStop 停止完成，回到可启动阶段
+func (st *schedulerState) finishStop() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.phase = schedulerIdle
+	st.paused = false
+	st.changed.Broadcast()
+}
+
+// setPaused 设置暂停标记，未在运行时返回 ErrSchedulerNotRunning
+func (st *schedulerState) setPaused(paused bool) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.phase != schedulerRunning {
+		return ErrSchedulerNotRunning
+	}
+	st.paused = paused
+	return nil
+}
+
+// dispatching 是否可以派发新任务
+func (st *schedulerState) dispatching() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.phase == schedulerRunning && !st.paused
+}
+
+// waitIdle 等待进行中的派发全部返回
+func (st *schedulerState) waitIdle() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for st.inflight > 0 {
+		st.changed.Wait()
+	}
+}
+
+// enter 登记一次派发，未在运行或已暂停时返回 false；返回 true 时须调用 leave
+func (st *schedulerState) enter() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.phase != schedulerRunning || st.paused {
+		return false
+	}
+	st.inflight++
+	return true
+}
+
+// leave 结束一次派发
+func (st *schedulerState) leave() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.inflight--
+	if st.inflight == 0 {
+		st.changed.Broadcast()
+	}
+}
+
+// setPending 记录本轮轮询到的待处理任务数，返回待处理数和执行中的任务数
+func (st *schedulerState) setPending(n int) (pending, running int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.pendingCnt = n
+	return st.pendingCnt, st.runningCnt
+}
+
+// addRunning 调整执行中的任务数，返回调整后的值
+func (st *schedulerState) addRunning(delta int) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.runningCnt += delta
+	return st.runningCnt
+}
+
+// addScheduled 累加已调度的任务数
+func (st *schedulerState) addScheduled() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.scheduledCnt++
+}
+
+// addFinished 累加成功结束的任务数，返回累加后的值
+func (st *schedulerState) addFinished() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.finishedCnt++
+	return st.finishedCnt
+}
+
+// restoreCounts 恢复检查点中的调度计数
+func (st *schedulerState) restoreCounts(scheduled, finished int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.scheduledCnt, st.finishedCnt = scheduled, finished
+}
+
+// status 运行状态和计数的快照
+func (st *schedulerState) status() SchedulerStatus {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return SchedulerStatus{
+		IsRunning:    st.phase == schedulerRunning,
+		IsPaused:     st.paused,
+		PendingCnt:   st.pendingCnt,
+		RunningCnt:   st.runningCnt,
+		ScheduledCnt: st.scheduledCnt,
+		FinishedCnt:  st.finishedCnt,
+	}
+}
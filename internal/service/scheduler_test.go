@@ -0,0 +1,2215 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/model"
+	"taskflow/internal/queue"
+	"taskflow/internal/redact"
+	"taskflow/internal/repository"
+	"taskflow/internal/secrets"
+	"taskflow/internal/storage"
+)
+
+func TestScheduler_BackpressureKeepsTasksPending(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	release := make(chan struct{})
+	svc.RegisterExecutor("blocking", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		<-release
+		return nil, nil
+	}))
+
+	// 1 个 worker、队列容量 1：最多 1 个执行、1 个等待许可、1 个排队
+	s := svc.Scheduler()
+	s.workerPool.Stop()
+	s.workerPool = newWorkerPool(1, 1)
+	s.setupTaskHandler()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		task, err := svc.CreateTask(ctx, "blocking", "", model.TaskPriorityNormal, "blocking", nil, nil, 0, "testuser")
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	countByStatus := func() map[model.TaskStatus]int {
+		counts := make(map[model.TaskStatus]int)
+		for _, id := range ids {
+			task, err := repo.GetByID(id)
+			if err != nil {
+				t.Fatalf("failed to get task: %v", err)
+			}
+			counts[task.Status]++
+		}
+		return counts
+	}
+
+	s.SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+	s.Wake()
+
+	// 饱和后剩余任务保持 PENDING，而不是滞留在无人执行的 RUNNING
+	waitFor(t, func() bool {
+		counts := countByStatus()
+		return counts[model.TaskStatusRunning] == 3 && counts[model.TaskStatusPending] == 2
+	})
+
+	// 放行后队列腾出空位，推迟的任务被自动调度
+	close(release)
+	waitFor(t, func() bool { return countByStatus()[model.TaskStatusSucceeded] == len(ids) })
+}
+
+func TestScheduler_RequeueOnSubmitFailure(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	task, err := svc.CreateTask(context.Background(), "requeued", "", model.TaskPriorityNormal, "test", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if err := repo.UpdateStatusWithEvent(task.ID, model.TaskStatusPending, model.TaskStatusRunning, "scheduler", "task scheduled"); err != nil {
+		t.Fatalf("failed to mark running: %v", err)
+	}
+
+	var changes []model.TaskStatus
+	s := svc.Scheduler()
+	s.OnTaskChange(func(task *model.Task, from, to model.TaskStatus) {
+		changes = append(changes, to)
+	})
+	s.requeue(task)
+
+	got, err := repo.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if got.Status != model.TaskStatusPending {
+		t.Errorf("expected PENDING after requeue, got %s", got.Status)
+	}
+	if len(changes) != 1 || changes[0] != model.TaskStatusPending {
+		t.Errorf("expected one PENDING change event, got %v", changes)
+	}
+}
+
+func TestScheduler_CancelRunningTask(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	started := make(chan struct{})
+	cleanedUp := make(chan error, 1)
+	svc.RegisterExecutor("cancellable", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		close(started)
+		<-ctx.Done()
+		// 模拟清理耗时，CancelTask 须等待清理完成
+		time.Sleep(20 * time.Millisecond)
+		cleanedUp <- context.Cause(ctx)
+		return nil, ctx.Err()
+	}))
+
+	svc.Scheduler().SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+
+	task, err := svc.CreateTask(ctx, "cancellable", "", model.TaskPriorityNormal, "cancellable", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	<-started
+
+	if err := svc.CancelTask(ctx, task.ID, "testuser"); err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+
+	// CancelTask 返回时执行器已完成清理，取消原因可通过 context.Cause 获取
+	select {
+	case cause := <-cleanedUp:
+		if cause != ErrTaskCancelled {
+			t.Errorf("expected cause ErrTaskCancelled, got %v", cause)
+		}
+	default:
+		t.Fatal("executor cleanup did not finish before CancelTask returned")
+	}
+
+	got, err := repo.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if got.Status != model.TaskStatusCancelled {
+		t.Errorf("expected CANCELLED, got %s", got.Status)
+	}
+	// 执行器返回的错误不应记录为失败事件
+	last := got.Events[len(got.Events)-1]
+	if last.ToStatus != model.TaskStatusCancelled || len(got.Events) != 3 {
+		t.Errorf("unexpected events after cancel: %+v", got.Events)
+	}
+}
+
+func TestScheduler_LateResultDoesNotOverrideCancellation(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	svc.RegisterExecutor("late", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		close(started)
+		<-release
+		return map[string]string{"result": "late"}, nil
+	}))
+
+	svc.Scheduler().SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+
+	task, err := svc.CreateTask(ctx, "late", "", model.TaskPriorityNormal, "late", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	<-started
+
+	// 其他实例在执行期间取消了任务，执行器随后仍返回成功
+	if err := repo.UpdateStatusWithEvent(task.ID, model.TaskStatusRunning, model.TaskStatusCancelled, "testuser", "task cancelled"); err != nil {
+		t.Fatalf("failed to cancel task: %v", err)
+	}
+	close(release)
+	waitFor(t, func() bool { return svc.Scheduler().GetStatus().RunningCnt == 0 })
+
+	got, _ := repo.GetByID(task.ID)
+	if got.Status != model.TaskStatusCancelled || len(got.OutputResult) != 0 {
+		t.Errorf("expected CANCELLED without output, got %s %v", got.Status, got.OutputResult)
+	}
+	if last := got.Events[len(got.Events)-1]; last.ToStatus != model.TaskStatusCancelled {
+		t.Errorf("unexpected events after late result: %+v", got.Events)
+	}
+}
+
+func TestScheduler_HeartbeatStopsExecutionsCancelledElsewhere(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	svc.RegisterExecutor("long", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		close(started)
+		<-ctx.Done()
+		stopped <- context.Cause(ctx)
+		return nil, ctx.Err()
+	}))
+
+	s := svc.Scheduler()
+	s.SetPollingInterval(time.Hour)
+	s.SetHeartbeatInterval(10 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	task, err := svc.CreateTask(ctx, "long", "", model.TaskPriorityNormal, "long", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	<-started
+
+	// 其他进程（如工作流截止时间监控）记录了取消，下一次实例心跳时停止执行器
+	if err := repo.UpdateStatusWithEvent(task.ID, model.TaskStatusRunning, model.TaskStatusCancelled, "workflow-monitor", "workflow deadline exceeded"); err != nil {
+		t.Fatalf("failed to cancel task: %v", err)
+	}
+	select {
+	case cause := <-stopped:
+		if cause != ErrTaskCancelled {
+			t.Errorf("expected cause ErrTaskCancelled, got %v", cause)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("executor was not cancelled after the task was cancelled elsewhere")
+	}
+	waitFor(t, func() bool { return s.GetStatus().RunningCnt == 0 })
+
+	// 执行器返回的错误不记录为失败
+	got, _ := repo.GetByID(task.ID)
+	if last := got.Events[len(got.Events)-1]; got.Status != model.TaskStatusCancelled || last.Operator != "workflow-monitor" {
+		t.Errorf("expected the task to stay CANCELLED, got %s with events %+v", got.Status, got.Events)
+	}
+}
+
+func TestScheduler_CancelGracePeriod(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	svc.RegisterExecutor("stubborn", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		close(started)
+		<-release // 忽略取消
+		return map[string]string{"done": "true"}, nil
+	}))
+
+	s := svc.Scheduler()
+	s.SetPollingInterval(time.Hour)
+	s.SetCancelGracePeriod(50 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	task, err := svc.CreateTask(ctx, "stubborn", "", model.TaskPriorityNormal, "stubborn", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	<-started
+
+	// 执行器不响应取消时，宽限期后仍记录 CANCELLED
+	if err := svc.CancelTask(ctx, task.ID, "testuser"); err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+	close(release)
+
+	waitFor(t, func() bool {
+		s.executionsMu.Lock()
+		defer s.executionsMu.Unlock()
+		return len(s.executions) == 0
+	})
+	got, err := repo.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if got.Status != model.TaskStatusCancelled {
+		t.Errorf("expected result of cancelled execution to be ignored, got %s", got.Status)
+	}
+}
+
+func TestScheduler_InstanceHeartbeat(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	s := svc.Scheduler()
+	if !strings.Contains(s.InstanceID(), "-") {
+		t.Fatalf("unexpected instance ID %q", s.InstanceID())
+	}
+
+	s.SetPollingInterval(time.Hour)
+	s.SetHeartbeatInterval(10 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	task, err := svc.CreateTask(ctx, "identified", "", model.TaskPriorityNormal, "test", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	waitFor(t, func() bool {
+		got, err := repo.GetByID(task.ID)
+		return err == nil && got.Status == model.TaskStatusSucceeded
+	})
+
+	// 执行实例和调度事件记录实例 ID
+	got, _ := repo.GetByID(task.ID)
+	if got.ExecutedBy != s.InstanceID() {
+		t.Errorf("expected executed_by %s, got %q", s.InstanceID(), got.ExecutedBy)
+	}
+	for _, e := range got.Events {
+		if e.Operator == "scheduler" && e.InstanceID != s.InstanceID() {
+			t.Errorf("scheduler event without instance ID: %+v", e)
+		}
+	}
+
+	waitFor(t, func() bool {
+		instances, err := repo.ListSchedulerInstances()
+		return err == nil && len(instances) == 1 && instances[0].ID == s.InstanceID() && instances[0].WorkerCount == 10
+	})
+
+	// 停止后注销实例
+	svc.StopScheduler()
+	if instances, _ := repo.ListSchedulerInstances(); len(instances) != 0 {
+		t.Errorf("expected instance removed after stop, got %+v", instances)
+	}
+}
+
+func TestScheduler_CheckpointRestoredOnRestart(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	s := svc.Scheduler()
+	s.SetCheckpointName("node-a")
+	s.SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+
+	task, err := svc.CreateTask(ctx, "checkpointed", "", model.TaskPriorityNormal, "test", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	waitFor(t, func() bool {
+		got, err := repo.GetByID(task.ID)
+		return err == nil && got.Status == model.TaskStatusSucceeded
+	})
+	if err := s.ResizeWorkerPool(3); err != nil {
+		t.Fatalf("ResizeWorkerPool failed: %v", err)
+	}
+	svc.StopScheduler()
+
+	cp, err := repo.GetSchedulerCheckpoint("node-a")
+	if err != nil || cp == nil {
+		t.Fatalf("expected checkpoint saved on stop, got %+v (%v)", cp, err)
+	}
+	if cp.BaseWorkers != 10 || cp.WorkerCount != 3 || cp.ScheduledCount != 1 || cp.FinishedCount != 1 {
+		t.Errorf("unexpected checkpoint: %+v", cp)
+	}
+
+	start := func(name string, configure func(*Scheduler)) *Scheduler {
+		t.Helper()
+		s := NewScheduler(repo)
+		s.SetCheckpointName(name)
+		s.SetPollingInterval(time.Hour)
+		if configure != nil {
+			configure(s)
+		}
+		s.Start(ctx)
+		t.Cleanup(s.Stop)
+		return s
+	}
+
+	// 以相同名称重新启动：恢复运行中调整过的工作池大小和计数
+	status := start("node-a", nil).GetStatus()
+	if status.WorkerCount != 3 || status.ScheduledCnt != 1 || status.FinishedCnt != 1 {
+		t.Errorf("expected checkpoint restored, got %+v", status)
+	}
+
+	// 配置的工作池大小变化后按新配置启动，计数仍然恢复
+	status = start("node-a", func(s *Scheduler) { s.SetWorkerCount(5) }).GetStatus()
+	if status.WorkerCount != 5 || status.ScheduledCnt != 1 {
+		t.Errorf("expected configured worker count with restored counts, got %+v", status)
+	}
+
+	// 自动伸缩时恢复的 worker 数限制在伸缩范围内
+	if err := repo.SaveSchedulerCheckpoint(&model.SchedulerCheckpoint{
+		Name: "autoscaled", SavedAt: time.Now(), BaseWorkers: 2, WorkerCount: 8, AutoscaleLowTicks: 2,
+	}); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+	autoscaled := start("autoscaled", func(s *Scheduler) {
+		if err := s.SetAutoscale(AutoscaleConfig{MinWorkers: 2, MaxWorkers: 4, Interval: time.Hour}); err != nil {
+			t.Fatalf("SetAutoscale failed: %v", err)
+		}
+	})
+	if size := autoscaled.GetStatus().WorkerCount; size != 4 || autoscaled.autoscaler.lowUtilizationTicks() != 2 {
+		t.Errorf("expected 4 workers and 2 low ticks, got %d and %d", size, autoscaled.autoscaler.lowUtilizationTicks())
+	}
+
+	// 过期的检查点被忽略
+	if err := repo.SaveSchedulerCheckpoint(&model.SchedulerCheckpoint{
+		Name: "stale", SavedAt: time.Now().Add(-2 * checkpointMaxAge), BaseWorkers: 10, WorkerCount: 3, ScheduledCount: 9,
+	}); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+	if status := start("stale", nil).GetStatus(); status.WorkerCount != 10 || status.ScheduledCnt != 0 {
+		t.Errorf("expected stale checkpoint ignored, got %+v", status)
+	}
+}
+
+func TestScheduler_ReadyQueueSharedAcrossInstances(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	counting := ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		mu.Lock()
+		runs[task.ID]++
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		return nil, nil
+	})
+
+	// 两个实例共享同一个就绪队列和数据库
+	q := queue.NewMemoryQueue()
+	s1 := svc.Scheduler()
+	s1.SetReadyQueue(q)
+	svc.RegisterExecutor("counted", counting)
+
+	s2 := NewScheduler(repo)
+	s2.SetReadyQueue(q)
+	s2.RegisterExecutor("counted", counting)
+
+	for _, s := range []*Scheduler{s1, s2} {
+		s.SetPollingInterval(20 * time.Millisecond)
+	}
+	svc.StartScheduler(ctx)
+	s2.Start(ctx)
+	defer s2.Stop()
+
+	var ids []string
+	for i := 0; i < 20; i++ {
+		task, err := svc.CreateTask(ctx, "queued", "", model.TaskPriorityNormal, "counted", nil, nil, 0, "testuser")
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	waitFor(t, func() bool {
+		for _, id := range ids {
+			task, err := repo.GetByID(id)
+			if err != nil || task.Status != model.TaskStatusSucceeded {
+				return false
+			}
+		}
+		return true
+	})
+
+	// 重复推入和两个实例并发弹出都不会导致任务重复执行
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range ids {
+		if runs[id] != 1 {
+			t.Errorf("task %s executed %d times", id, runs[id])
+		}
+	}
+	for _, id := range ids {
+		task, _ := repo.GetByID(id)
+		if task.ExecutedBy != s1.InstanceID() && task.ExecutedBy != s2.InstanceID() {
+			t.Errorf("task %s executed by unknown instance %q", id, task.ExecutedBy)
+		}
+	}
+}
+
+func TestScheduler_RoutesTasksByWorkerLabels(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	noop := ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		return nil, nil
+	})
+
+	// 两个实例共享就绪队列和数据库，只有 s2 带 gpu 标签
+	q := queue.NewMemoryQueue()
+	s1 := svc.Scheduler()
+	s1.SetReadyQueue(q)
+	svc.RegisterExecutor("routed", noop)
+
+	s2 := NewScheduler(repo)
+	s2.SetReadyQueue(q)
+	s2.RegisterExecutor("routed", noop)
+	if err := s2.SetWorkerLabels(map[string]string{"gpu": "true", "region": "eu"}); err != nil {
+		t.Fatalf("SetWorkerLabels: %v", err)
+	}
+	if err := s2.SetWorkerLabels(map[string]string{"-bad": "x"}); err == nil {
+		t.Error("expected an error for an invalid label key")
+	}
+	for _, s := range []*Scheduler{s1, s2} {
+		s.SetPollingInterval(20 * time.Millisecond)
+	}
+	svc.StartScheduler(ctx)
+
+	submit := func(selector map[string]string, target string) string {
+		task := model.NewTask("routed", "", model.TaskPriorityNormal, "routed", nil, nil, 0, "testuser")
+		task.NodeSelector = selector
+		task.TargetWorker = target
+		if err := svc.SubmitTask(ctx, task); err != nil {
+			t.Fatalf("failed to submit task: %v", err)
+		}
+		return task.ID
+	}
+
+	// 没有匹配的实例时任务保持 PENDING，调度决策说明路由不匹配
+	gpu := submit(map[string]string{"gpu": "true"}, "")
+	time.Sleep(100 * time.Millisecond)
+	if task, _ := repo.GetByID(gpu); task.Status != model.TaskStatusPending || task.BlockedReason != "" {
+		t.Fatalf("expected routed task to stay pending without a blocked reason, got %s %q", task.Status, task.BlockedReason)
+	}
+	decision, err := s1.GetSchedulingDecision(gpu)
+	if err != nil {
+		t.Fatalf("GetSchedulingDecision: %v", err)
+	}
+	if decision.Dispatchable || len(decision.Reasons) != 1 || decision.Reasons[0].Code != model.ReasonWorkerMismatch {
+		t.Errorf("expected a worker_mismatch reason, got %+v", decision.Reasons)
+	}
+
+	s2.Start(ctx)
+	defer s2.Stop()
+
+	ids := map[string]string{gpu: s2.InstanceID()}
+	for i := 0; i < 5; i++ {
+		ids[submit(map[string]string{"gpu": "true", "region": "eu"}, "")] = s2.InstanceID()
+		ids[submit(nil, s1.InstanceID())] = s1.InstanceID()
+	}
+	waitFor(t, func() bool {
+		for id := range ids {
+			task, err := repo.GetByID(id)
+			if err != nil || task.Status != model.TaskStatusSucceeded {
+				return false
+			}
+		}
+		return true
+	})
+	for id, want := range ids {
+		if task, _ := repo.GetByID(id); task.ExecutedBy != want {
+			t.Errorf("task %s executed by %q, want %q", id, task.ExecutedBy, want)
+		}
+	}
+
+	// 心跳记录实例标签
+	instances, _ := repo.ListSchedulerInstances()
+	for _, inst := range instances {
+		if inst.ID == s2.InstanceID() && inst.Labels["gpu"] != "true" {
+			t.Errorf("expected heartbeat to record worker labels, got %v", inst.Labels)
+		}
+	}
+}
+
+func TestScheduler_PriorityInheritance(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	var order []string
+	svc.RegisterExecutor("ordered", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		mu.Lock()
+		order = append(order, task.Name)
+		mu.Unlock()
+		return nil, nil
+	}))
+
+	s := svc.Scheduler()
+	if err := s.SetPriorityInheritance(model.TaskPriority(9)); err == nil {
+		t.Error("expected an error for an unknown priority")
+	}
+	if err := s.SetPriorityInheritance(model.TaskPriorityHigh); err != nil {
+		t.Fatalf("SetPriorityInheritance: %v", err)
+	}
+	s.SetWorkerCount(1)
+	s.SetPollingInterval(10 * time.Millisecond)
+
+	// 调度器启动前创建：先创建的普通任务，以及 URGENT 任务依赖的 LOW 依赖链 root <- mid <- urgent
+	create := func(name string, priority model.TaskPriority, deps ...string) *model.Task {
+		task, err := svc.CreateTask(ctx, name, "", priority, "ordered", nil, deps, 0, "testuser")
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		return task
+	}
+	for i := 0; i < 3; i++ {
+		create("filler-"+strconv.Itoa(i), model.TaskPriorityNormal)
+	}
+	root := create("root", model.TaskPriorityLow)
+	mid := create("mid", model.TaskPriorityLow, root.ID)
+	urgent := create("urgent", model.TaskPriorityUrgent, mid.ID)
+
+	svc.StartScheduler(ctx)
+	waitFor(t, func() bool {
+		task, _ := repo.GetByID(urgent.ID)
+		return task.Status == model.TaskStatusSucceeded
+	})
+
+	// 被提升的上游排在先创建的普通任务之前执行
+	mu.Lock()
+	if len(order) == 0 || order[0] != "root" {
+		t.Errorf("expected the boosted upstream to run first, got %v", order)
+	}
+	mu.Unlock()
+
+	// 提升和恢复都记录为事件，开始执行后恢复原优先级
+	for _, id := range []string{root.ID, mid.ID} {
+		task, _ := repo.GetByID(id)
+		if task.Priority != model.TaskPriorityLow || task.BasePriority != model.TaskPriorityUnspecified {
+			t.Errorf("task %s: expected priority LOW restored, got %s from %s", task.Name, task.Priority, task.BasePriority)
+		}
+		var boosted, restored bool
+		for _, e := range task.Events {
+			boosted = boosted || strings.HasPrefix(e.Message, "priority boosted from LOW to URGENT: task ")
+			restored = restored || e.Message == "priority restored to LOW after the task started"
+		}
+		if !boosted || !restored {
+			t.Errorf("task %s: expected boost and restore events, got %+v", task.Name, task.Events)
+		}
+	}
+}
+
+func TestScheduler_OffloadsLargeOutputToArtifactStore(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.RegisterExecutor("report", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		return map[string]string{"report": task.InputParams["report"]}, nil
+	}))
+
+	store := storage.NewMemoryStore()
+	svc.Scheduler().SetArtifactStore(store, 64)
+	svc.Scheduler().SetPollingInterval(10 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	small, err := svc.CreateTask(ctx, "small", "", model.TaskPriorityNormal, "report", map[string]string{"report": "ok"}, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	large, err := svc.CreateTask(ctx, "large", "", model.TaskPriorityNormal, "report", map[string]string{"report": strings.Repeat("x", 100)}, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 输出在状态变为 SUCCEEDED 之后写入
+	waitFor(t, func() bool {
+		a, _ := repo.GetByID(small.ID)
+		b, _ := repo.GetByID(large.ID)
+		return len(a.OutputResult) > 0 && b.OutputRef != ""
+	})
+
+	// 小输出保持内联
+	got, _ := repo.GetByID(small.ID)
+	if got.OutputRef != "" || got.OutputResult["report"] != "ok" {
+		t.Errorf("expected inline output, got ref=%q output=%v", got.OutputRef, got.OutputResult)
+	}
+
+	// 大输出转存，数据库只保留 key
+	got, _ = repo.GetByID(large.ID)
+	if got.OutputRef != OutputKey(large.ID) || len(got.OutputResult) != 0 {
+		t.Fatalf("expected offloaded output, got ref=%q output=%v", got.OutputRef, got.OutputResult)
+	}
+	rc, err := store.Get(ctx, got.OutputRef)
+	if err != nil {
+		t.Fatalf("failed to read offloaded output: %v", err)
+	}
+	defer rc.Close()
+	var output map[string]string
+	if err := json.NewDecoder(rc).Decode(&output); err != nil || len(output["report"]) != 100 {
+		t.Errorf("unexpected offloaded output %v: %v", output, err)
+	}
+}
+
+func TestScheduler_RetryPolicyRetriesWithBackoff(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	attempts := make(map[string][]time.Time)
+	svc.RegisterExecutor("flaky", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[task.ID] = append(attempts[task.ID], time.Now())
+		if task.InputParams["error"] != "" && len(attempts[task.ID]) < 3 {
+			return nil, errors.New(task.InputParams["error"])
+		}
+		return map[string]string{"attempts": strconv.Itoa(len(attempts[task.ID]))}, nil
+	}))
+
+	createWithPolicy := func(name, errMsg string, policy *model.RetryPolicy) *model.Task {
+		task := model.NewTask(name, "", model.TaskPriorityNormal, "flaky", map[string]string{"error": errMsg}, nil, 0, "testuser")
+		task.ID = name
+		task.RetryPolicy = policy
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		return task
+	}
+
+	retried := createWithPolicy("retried", "connection refused", &model.RetryPolicy{
+		MaxAttempts: 3, Backoff: model.BackoffFixed, InitialDelayMs: 50,
+	})
+	fatal := createWithPolicy("fatal", "invalid input", &model.RetryPolicy{
+		MaxAttempts: 3, Backoff: model.BackoffNone, RetryableErrors: []string{"connection"},
+	})
+	exhausted := createWithPolicy("exhausted", "connection refused", &model.RetryPolicy{
+		MaxAttempts: 2, Backoff: model.BackoffNone,
+	})
+
+	svc.Scheduler().SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+	svc.Scheduler().Wake()
+
+	status := func(id string) model.TaskStatus {
+		task, _ := repo.GetByID(id)
+		return task.Status
+	}
+	waitFor(t, func() bool {
+		return status(retried.ID) == model.TaskStatusSucceeded &&
+			status(fatal.ID) == model.TaskStatusFailed &&
+			status(exhausted.ID) == model.TaskStatusFailed
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// 可重试错误按固定退避重试，直到第三次执行成功
+	got, _ := repo.GetByID(retried.ID)
+	if len(attempts[retried.ID]) != 3 || got.RetryCount != 2 {
+		t.Errorf("expected 3 attempts and retry count 2, got %d attempts, retry count %d", len(attempts[retried.ID]), got.RetryCount)
+	}
+	for i := 1; i < len(attempts[retried.ID]); i++ {
+		if gap := attempts[retried.ID][i].Sub(attempts[retried.ID][i-1]); gap < 50*time.Millisecond {
+			t.Errorf("retry %d ran after %s, expected at least 50ms backoff", i, gap)
+		}
+	}
+
+	// 不匹配可重试错误的失败立即终止；达到最大执行次数后不再重试
+	if n := len(attempts[fatal.ID]); n != 1 {
+		t.Errorf("expected non-retryable error to run once, got %d", n)
+	}
+	if n := len(attempts[exhausted.ID]); n != 2 {
+		t.Errorf("expected 2 attempts before exhausting policy, got %d", n)
+	}
+
+	// 执行相关的事件带有第几次执行、执行实例、耗时和重试等待时间
+	events, _ := repo.GetEventsByTaskID(retried.ID)
+	retries := 0
+	var timeline []string
+	for _, e := range events {
+		if e.FromStatus == model.TaskStatusRunning && e.ToStatus == model.TaskStatusPending && strings.HasPrefix(e.Message, "retry ") {
+			retries++
+			if e.Metadata[model.EventMetaRetryDelayMs] != "50" || e.Metadata[model.EventMetaDurationMs] == "" {
+				t.Errorf("unexpected retry event metadata: %+v", e.Metadata)
+			}
+		}
+		if e.Metadata != nil {
+			if e.Metadata[model.EventMetaWorker] != svc.Scheduler().instanceID {
+				t.Errorf("expected worker %s, got %+v", svc.Scheduler().instanceID, e.Metadata)
+			}
+			timeline = append(timeline, e.ToStatus.String()+"#"+e.Metadata[model.EventMetaAttempt])
+		}
+	}
+	if retries != 2 {
+		t.Errorf("expected 2 retry events, got %d: %+v", retries, events)
+	}
+	want := []string{"RUNNING#1", "PENDING#1", "RUNNING#2", "PENDING#2", "RUNNING#3", "SUCCEEDED#3"}
+	if strings.Join(timeline, ",") != strings.Join(want, ",") {
+		t.Errorf("expected event timeline %v, got %v", want, timeline)
+	}
+}
+
+func TestScheduler_FakeClockDrivesRetryBackoff(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	svc.SetClock(fake)
+
+	var attempts atomic.Int32
+	svc.RegisterExecutor("flaky", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		if attempts.Add(1) == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return map[string]string{"ok": "true"}, nil
+	}))
+
+	ctx := context.Background()
+	svc.StartScheduler(ctx)
+	task := model.NewTask("flaky", "", model.TaskPriorityNormal, "flaky", nil, nil, 0, "testuser")
+	task.RetryPolicy = &model.RetryPolicy{MaxAttempts: 2, Backoff: model.BackoffFixed, InitialDelayMs: time.Hour.Milliseconds(), MaxDelayMs: time.Hour.Milliseconds()}
+	if err := svc.SubmitTask(ctx, task); err != nil {
+		t.Fatalf("failed to submit task: %v", err)
+	}
+	if !task.CreatedAt.Equal(start) {
+		t.Errorf("expected created_at from the injected clock, got %s", task.CreatedAt)
+	}
+
+	// 首次失败后按注入的时钟计算下次运行时间
+	waitFor(t, func() bool {
+		got, _ := repo.GetByID(task.ID)
+		return got.Status == model.TaskStatusPending && got.RetryCount == 1
+	})
+	got, _ := repo.GetByID(task.ID)
+	if got.NextRunAt == nil || !got.NextRunAt.Equal(start.Add(time.Hour)) {
+		t.Fatalf("expected next run at %s, got %v", start.Add(time.Hour), got.NextRunAt)
+	}
+
+	// 退避到期前推进时钟会触发兜底轮询，但任务不会执行
+	fake.Advance(59 * time.Minute)
+	svc.Scheduler().Wake()
+	time.Sleep(50 * time.Millisecond)
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("expected task to wait for its backoff, got %d attempts", n)
+	}
+
+	// 到期时刻的唤醒定时器在 Advance 中触发
+	fake.Advance(time.Minute)
+	waitFor(t, func() bool {
+		got, _ := repo.GetByID(task.ID)
+		return got.Status == model.TaskStatusSucceeded
+	})
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestScheduler_RetryHonoursMaxRetries(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var runs atomic.Int32
+	svc.RegisterExecutor("always-failing", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		runs.Add(1)
+		return nil, Retryable(errors.New("upstream unavailable"))
+	}))
+
+	// 策略未设置 MaxAttempts 时由 MaxRetries 限制重试次数
+	task := model.NewTask("always-failing", "", model.TaskPriorityNormal, "always-failing", nil, nil, 2, "testuser")
+	task.RetryPolicy = &model.RetryPolicy{Backoff: model.BackoffNone}
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	svc.Scheduler().SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+	svc.Scheduler().Wake()
+
+	waitFor(t, func() bool {
+		got, _ := repo.GetByID(task.ID)
+		return got.Status == model.TaskStatusFailed
+	})
+
+	got, _ := repo.GetByID(task.ID)
+	if n := runs.Load(); n != 3 || got.RetryCount != 2 {
+		t.Errorf("expected 3 runs and retry count 2, got %d runs, retry count %d", n, got.RetryCount)
+	}
+	if got.CanRetry() {
+		t.Error("expected retries to be exhausted")
+	}
+}
+
+func TestRetryDelay_ErrorClassification(t *testing.T) {
+	policy := &model.RetryPolicy{MaxAttempts: 3, Backoff: model.BackoffFixed, InitialDelayMs: 100, RetryableErrors: []string{"connection"}}
+	task := &model.Task{RetryPolicy: policy}
+
+	tests := []struct {
+		name        string
+		err         error
+		wantRetry   bool
+		wantAtLeast time.Duration
+	}{
+		{"unclassified match", errors.New("connection reset"), true, 100 * time.Millisecond},
+		{"unclassified mismatch", errors.New("bad input"), false, 0},
+		{"retryable overrides patterns", Retryable(errors.New("bad gateway")), true, 100 * time.Millisecond},
+		{"fatal overrides patterns", Fatal(errors.New("connection refused")), false, 0},
+		{"rate limited backs off longer", RateLimited(errors.New("429"), 0), true, time.Second},
+		{"rate limited honours retry-after", RateLimited(errors.New("429"), time.Minute), true, time.Minute},
+		{"timeout without flag", context.DeadlineExceeded, false, 0},
+	}
+	for _, tt := range tests {
+		class, retryAfter := ClassifyError(tt.err)
+		delay, ok := retryDelay(task, tt.err.Error(), class, retryAfter)
+		if ok != tt.wantRetry || delay < tt.wantAtLeast {
+			t.Errorf("%s: got retry=%v delay=%s, want retry=%v delay>=%s", tt.name, ok, delay, tt.wantRetry, tt.wantAtLeast)
+		}
+	}
+
+	// 未设置策略时，只有显式标记为可重试的错误在 MaxRetries 内重试
+	plain := &model.Task{MaxRetries: 1}
+	if _, ok := retryDelay(plain, "boom", model.ErrorClassUnknown, 0); ok {
+		t.Error("expected unclassified error not to retry without a policy")
+	}
+	if _, ok := retryDelay(plain, "boom", model.ErrorClassRetryable, 0); !ok {
+		t.Error("expected retryable error to retry within MaxRetries")
+	}
+	plain.RetryCount = 1
+	if _, ok := retryDelay(plain, "boom", model.ErrorClassRetryable, 0); ok {
+		t.Error("expected retryable error not to exceed MaxRetries")
+	}
+}
+
+func TestScheduler_RecordsErrorClassification(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.RegisterExecutor("classified", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		switch task.Name {
+		case "fatal":
+			return nil, Fatal(errors.New("invalid credentials"))
+		default:
+			return nil, RateLimited(errors.New("too many requests"), time.Minute)
+		}
+	}))
+
+	svc.Scheduler().SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+
+	fatal, err := svc.CreateTask(ctx, "fatal", "", model.TaskPriorityNormal, "classified", nil, nil, 3, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	limited, err := svc.CreateTask(ctx, "limited", "", model.TaskPriorityNormal, "classified", nil, nil, 3, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		a, _ := repo.GetByID(fatal.ID)
+		b, _ := repo.GetByID(limited.ID)
+		return a.Status == model.TaskStatusFailed && b.Status == model.TaskStatusPending && b.RetryCount == 1
+	})
+
+	// fatal 错误立即失败，分类记录在任务和事件上
+	got, _ := repo.GetByID(fatal.ID)
+	if got.ErrorClass != model.ErrorClassFatal || got.ErrorMessage != "invalid credentials" {
+		t.Errorf("unexpected fatal task: class=%q error=%q", got.ErrorClass, got.ErrorMessage)
+	}
+	events, _ := repo.GetEventsByTaskID(fatal.ID)
+	if last := events[len(events)-1]; last.ToStatus != model.TaskStatusFailed || last.Message != "[fatal] invalid credentials" ||
+		last.Metadata[model.EventMetaErrorClass] != string(model.ErrorClassFatal) {
+		t.Errorf("unexpected failure event: %+v", last)
+	}
+
+	// 限流错误至少等待 RetryAfter 后重试
+	got, _ = repo.GetByID(limited.ID)
+	if got.ErrorClass != model.ErrorClassRateLimited || got.NextRunAt == nil || time.Until(*got.NextRunAt) < 50*time.Second {
+		t.Errorf("unexpected rate limited task: class=%q next_run_at=%v", got.ErrorClass, got.NextRunAt)
+	}
+	events, _ = repo.GetEventsByTaskID(limited.ID)
+	if last := events[len(events)-1]; !strings.Contains(last.Message, "[rate_limited] too many requests") {
+		t.Errorf("unexpected retry event: %+v", last)
+	}
+}
+
+func TestScheduler_ExecutorPanicFailsTask(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.RegisterExecutor("panicky", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		if task.Name == "panic" {
+			panic("nil map write")
+		}
+		return map[string]string{"ok": "true"}, nil
+	}))
+	svc.Scheduler().SetWorkerCount(1)
+	svc.Scheduler().SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+
+	panicked, err := svc.CreateTask(ctx, "panic", "", model.TaskPriorityNormal, "panicky", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	// 同一个 worker 之后仍能执行其他任务
+	after, err := svc.CreateTask(ctx, "after", "", model.TaskPriorityNormal, "panicky", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		a, _ := repo.GetByID(panicked.ID)
+		b, _ := repo.GetByID(after.ID)
+		return a.Status == model.TaskStatusFailed && b.Status == model.TaskStatusSucceeded
+	})
+	if got, _ := repo.GetByID(panicked.ID); got.ErrorMessage != "task execution panicked: nil map write" {
+		t.Errorf("unexpected error message %q", got.ErrorMessage)
+	}
+	// 调用栈记录在失败事件中
+	events, _ := repo.GetEventsByTaskID(panicked.ID)
+	if last := events[len(events)-1]; last.ToStatus != model.TaskStatusFailed || !strings.Contains(last.Metadata[model.EventMetaPanicStack], "scheduler_test.go") {
+		t.Errorf("expected panic stack in failure event, got %+v", last)
+	}
+}
+
+// panickingBlobStore 写入时 panic 的产物存储
+type panickingBlobStore struct{ storage.BlobStore }
+
+func (panickingBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	panic("blob store exploded")
+}
+
+func TestScheduler_PanicOutsideExecutorFailsRunningTask(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.RegisterExecutor("report", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		return map[string]string{"report": task.Name}, nil
+	}))
+	svc.Scheduler().SetArtifactStore(panickingBlobStore{}, 0)
+	svc.Scheduler().SetWorkerCount(1)
+	svc.Scheduler().SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+
+	first, err := svc.CreateTask(ctx, "first", "", model.TaskPriorityNormal, "report", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	second, err := svc.CreateTask(ctx, "second", "", model.TaskPriorityNormal, "report", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 保存输出时 panic，任务不会停留在 RUNNING，worker 继续执行后续任务
+	waitFor(t, func() bool {
+		a, _ := repo.GetByID(first.ID)
+		b, _ := repo.GetByID(second.ID)
+		return a.Status == model.TaskStatusFailed && b.Status == model.TaskStatusFailed
+	})
+	got, _ := repo.GetByID(first.ID)
+	if got.ErrorMessage != "task execution panicked: blob store exploded" {
+		t.Errorf("unexpected error message %q", got.ErrorMessage)
+	}
+	events, _ := repo.GetEventsByTaskID(first.ID)
+	if last := events[len(events)-1]; !strings.Contains(last.Metadata[model.EventMetaPanicStack], "storeOutput") {
+		t.Errorf("expected panic stack in failure event, got %+v", last)
+	}
+	if status := svc.Scheduler().GetStatus(); status.RunningCnt != 0 {
+		t.Errorf("expected no running tasks, got %d", status.RunningCnt)
+	}
+}
+
+func TestScheduler_SingletonTaskTypeRunsOneAtATime(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var active, maxActive int32
+	release := make(chan struct{})
+	svc.RegisterExecutor("backup", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		<-release
+		return map[string]string{"ok": "true"}, nil
+	}))
+
+	svc.Scheduler().SetSingletonTaskTypes("backup")
+	svc.Scheduler().SetPollingInterval(20 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	first, err := svc.CreateTask(ctx, "backup-1", "", model.TaskPriorityNormal, "backup", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	waitFor(t, func() bool {
+		task, _ := repo.GetByID(first.ID)
+		return task.Status == model.TaskStatusRunning
+	})
+	second, err := svc.CreateTask(ctx, "backup-2", "", model.TaskPriorityNormal, "backup", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 第二个任务保持 PENDING，并说明被哪个任务阻塞
+	waitFor(t, func() bool {
+		task, _ := repo.GetByID(second.ID)
+		return task.Status == model.TaskStatusPending && strings.Contains(task.BlockedReason, first.ID)
+	})
+
+	close(release)
+	waitFor(t, func() bool {
+		task, _ := repo.GetByID(second.ID)
+		return task.Status == model.TaskStatusSucceeded
+	})
+	if got, _ := repo.GetByID(second.ID); got.BlockedReason != "" {
+		t.Errorf("expected blocked reason to be cleared, got %q", got.BlockedReason)
+	}
+	if maxActive := atomic.LoadInt32(&maxActive); maxActive != 1 {
+		t.Errorf("expected at most 1 concurrent backup task, got %d", maxActive)
+	}
+}
+
+func TestScheduler_SkipsTasksWithFailedDependencies(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.RegisterExecutor("flaky", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		if task.ID == "upstream" {
+			return nil, Fatal(errors.New("disk full"))
+		}
+		return map[string]string{"ok": "true"}, nil
+	}))
+
+	newTask := func(id string, deps []string, policies map[string]model.DependencyFailurePolicy) {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "flaky", nil, deps, 0, "testuser")
+		task.ID = id
+		task.DependencyPolicies = policies
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	newTask("upstream", nil, nil)
+	newTask("skipped", []string{"upstream"}, nil)
+	newTask("transitive", []string{"skipped"}, nil)
+	newTask("ignored", []string{"upstream"}, map[string]model.DependencyFailurePolicy{"upstream": model.DependencyFailureIgnore})
+	newTask("waiting", []string{"upstream"}, map[string]model.DependencyFailurePolicy{"upstream": model.DependencyFailureWait})
+	newTask("cancelled", nil, nil)
+	newTask("after-cancelled", []string{"cancelled"}, map[string]model.DependencyFailurePolicy{"cancelled": model.DependencyFailureWait})
+	if err := svc.CancelTask(ctx, "cancelled", "testuser"); err != nil {
+		t.Fatalf("failed to cancel task: %v", err)
+	}
+
+	svc.Scheduler().SetPollingInterval(20 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	status := func(id string) model.TaskStatus {
+		task, _ := repo.GetByID(id)
+		return task.Status
+	}
+	waitFor(t, func() bool {
+		return status("transitive") == model.TaskStatusSkipped && status("ignored") == model.TaskStatusSucceeded
+	})
+
+	// 跳过沿依赖链传播，事件说明是哪个上游导致的
+	events, _ := repo.GetEventsByTaskID("skipped")
+	if last := events[len(events)-1]; last.ToStatus != model.TaskStatusSkipped || last.Message != "skipped: dependency upstream is FAILED" {
+		t.Errorf("unexpected skip event: %+v", last)
+	}
+	events, _ = repo.GetEventsByTaskID("transitive")
+	if last := events[len(events)-1]; last.Message != "skipped: dependency skipped is SKIPPED" {
+		t.Errorf("unexpected transitive skip event: %+v", last)
+	}
+
+	// wait 策略的依赖保持 PENDING，等待上游被手动重试；BlockedReason 列出未成功的上游及其状态
+	waitFor(t, func() bool {
+		task, _ := repo.GetByID("after-cancelled")
+		return task.BlockedReason != ""
+	})
+	if task, _ := repo.GetByID("waiting"); task.Status != model.TaskStatusPending || task.BlockedReason != "dependencies did not succeed: upstream (FAILED, wait)" {
+		t.Errorf("expected waiting task to stay PENDING with blocked reason, got %s %q", task.Status, task.BlockedReason)
+	}
+	if task, _ := repo.GetByID("after-cancelled"); task.BlockedReason != "dependencies did not succeed: cancelled (CANCELLED, wait)" {
+		t.Errorf("unexpected blocked reason: %q", task.BlockedReason)
+	}
+
+	// 上游重新运行成功后下游被调度，阻塞原因随之清除
+	if _, err := svc.RerunTask(ctx, "cancelled", model.RerunModeReset, "testuser"); err != nil {
+		t.Fatalf("RerunTask failed: %v", err)
+	}
+	waitFor(t, func() bool { return status("after-cancelled") == model.TaskStatusSucceeded })
+	if task, _ := repo.GetByID("after-cancelled"); task.BlockedReason != "" {
+		t.Errorf("expected blocked reason to be cleared, got %q", task.BlockedReason)
+	}
+}
+
+func TestBlackoutWindow_ActiveUntil(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", s, err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name   string
+		window BlackoutWindow
+		now    string
+		active bool
+		end    string
+	}{
+		{"one-off inside", BlackoutWindow{Start: at("2026-03-01T10:00:00Z"), End: at("2026-03-01T12:00:00Z")}, "2026-03-01T11:00:00Z", true, "2026-03-01T12:00:00Z"},
+		{"one-off end exclusive", BlackoutWindow{Start: at("2026-03-01T10:00:00Z"), End: at("2026-03-01T12:00:00Z")}, "2026-03-01T12:00:00Z", false, ""},
+		{"daily inside", BlackoutWindow{DailyStart: "02:00", DailyEnd: "04:00"}, "2026-03-01T03:30:00Z", true, "2026-03-01T04:00:00Z"},
+		{"daily outside", BlackoutWindow{DailyStart: "02:00", DailyEnd: "04:00"}, "2026-03-01T05:00:00Z", false, ""},
+		{"overnight after midnight", BlackoutWindow{DailyStart: "23:00", DailyEnd: "01:00"}, "2026-03-02T00:30:00Z", true, "2026-03-02T01:00:00Z"},
+		{"weekday filter uses start day", BlackoutWindow{Weekdays: []time.Weekday{time.Sunday}, DailyStart: "23:00", DailyEnd: "01:00"}, "2026-03-02T00:30:00Z", true, "2026-03-02T01:00:00Z"},
+		{"weekday filter excludes", BlackoutWindow{Weekdays: []time.Weekday{time.Saturday}, DailyStart: "02:00", DailyEnd: "04:00"}, "2026-03-01T03:00:00Z", false, ""},
+		{"location", BlackoutWindow{DailyStart: "02:00", DailyEnd: "04:00", Location: shanghai}, "2026-03-01T19:00:00Z", true, "2026-03-01T20:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, active := tt.window.activeUntil(at(tt.now))
+			if active != tt.active {
+				t.Fatalf("activeUntil(%s) active = %v, expected %v", tt.now, active, tt.active)
+			}
+			if active && !end.Equal(at(tt.end)) {
+				t.Errorf("activeUntil(%s) end = %s, expected %s", tt.now, end, tt.end)
+			}
+		})
+	}
+
+	invalid := []BlackoutWindow{
+		{Name: "empty"},
+		{Name: "reversed", Start: at("2026-03-01T12:00:00Z"), End: at("2026-03-01T10:00:00Z")},
+		{Name: "bad clock", DailyStart: "25:00", DailyEnd: "01:00"},
+		{Name: "both", Start: at("2026-03-01T10:00:00Z"), End: at("2026-03-01T12:00:00Z"), DailyStart: "02:00", DailyEnd: "04:00"},
+	}
+	for _, w := range invalid {
+		if err := w.Validate(); err == nil {
+			t.Errorf("expected validation error for window %s", w.Name)
+		}
+	}
+}
+
+func TestScheduler_BlackoutWindowDefersDispatch(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.RegisterExecutor("maintenance", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		return map[string]string{"ok": "true"}, nil
+	}))
+
+	now := time.Now()
+	err := svc.Scheduler().SetBlackoutWindows(BlackoutWindow{
+		Name:      "db-maintenance",
+		TaskTypes: []string{"maintenance"},
+		Start:     now.Add(-time.Minute),
+		End:       now.Add(300 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("failed to set blackout windows: %v", err)
+	}
+	svc.Scheduler().SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+
+	blocked, err := svc.CreateTask(ctx, "blocked", "", model.TaskPriorityNormal, "maintenance", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	other, err := svc.CreateTask(ctx, "other", "", model.TaskPriorityNormal, "other", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 窗口只适用于 maintenance 类型，其他任务照常调度
+	waitFor(t, func() bool {
+		task, _ := repo.GetByID(other.ID)
+		return task.Status == model.TaskStatusSucceeded
+	})
+	got, _ := repo.GetByID(blocked.ID)
+	if got.Status != model.TaskStatusPending || !strings.Contains(got.BlockedReason, "db-maintenance") {
+		t.Fatalf("expected task to be held by blackout window, got status=%s reason=%q", got.Status, got.BlockedReason)
+	}
+
+	// 窗口结束后无需轮询即自动恢复调度
+	waitFor(t, func() bool {
+		task, _ := repo.GetByID(blocked.ID)
+		return task.Status == model.TaskStatusSucceeded
+	})
+	if got, _ := repo.GetByID(blocked.ID); got.BlockedReason != "" {
+		t.Errorf("expected blocked reason to be cleared, got %q", got.BlockedReason)
+	}
+}
+
+func TestScheduler_ResourceCapacityLimitsConcurrentSlots(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var inUse, maxInUse int32
+	release := make(chan struct{})
+	svc.RegisterExecutor("render", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		n := atomic.AddInt32(&inUse, task.ResourceSlots)
+		defer atomic.AddInt32(&inUse, -task.ResourceSlots)
+		for {
+			m := atomic.LoadInt32(&maxInUse)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInUse, m, n) {
+				break
+			}
+		}
+		<-release
+		return map[string]string{"ok": "true"}, nil
+	}))
+
+	if err := svc.Scheduler().SetResourceCapacity(3); err != nil {
+		t.Fatalf("failed to set resource capacity: %v", err)
+	}
+	svc.Scheduler().SetPollingInterval(20 * time.Millisecond)
+
+	ids := []string{"heavy-1", "heavy-2", "heavy-3"}
+	for _, id := range ids {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "render", nil, nil, 0, "testuser")
+		task.ID = id
+		task.ResourceSlots = 2
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	svc.StartScheduler(ctx)
+
+	// 每个任务占 2 个槽位，预算 3 时只能运行一个，其余记录等待原因
+	waitFor(t, func() bool {
+		running, blocked := 0, 0
+		for _, id := range ids {
+			task, _ := repo.GetByID(id)
+			if task.Status == model.TaskStatusRunning {
+				running++
+			}
+			if strings.Contains(task.BlockedReason, "resource slots") {
+				blocked++
+			}
+		}
+		return running == 1 && blocked == 2
+	})
+	status := svc.GetSchedulerStatus()
+	if status.ResourceCapacity != 3 || status.ResourceSlotsInUse != 2 {
+		t.Errorf("unexpected resource usage: %d/%d", status.ResourceSlotsInUse, status.ResourceCapacity)
+	}
+
+	close(release)
+	waitFor(t, func() bool {
+		for _, id := range ids {
+			task, _ := repo.GetByID(id)
+			if task.Status != model.TaskStatusSucceeded {
+				return false
+			}
+		}
+		return true
+	})
+	if got := atomic.LoadInt32(&maxInUse); got != 2 {
+		t.Errorf("expected at most 2 slots in use, got %d", got)
+	}
+	waitFor(t, func() bool {
+		return svc.GetSchedulerStatus().ResourceSlotsInUse == 0
+	})
+}
+
+func TestScheduler_GroupKeyAndAntiAffinity(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	running := make(map[string]bool)
+	var violations []string
+	release := make(chan struct{})
+	exec := ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		conflicts := map[string]string{"deploy": "migrate", "migrate": "deploy"}
+		mu.Lock()
+		if running[conflicts[task.TaskType]] || task.GroupKey != "" && running[task.GroupKey] {
+			violations = append(violations, task.ID)
+		}
+		running[task.TaskType] = true
+		running[task.GroupKey] = task.GroupKey != ""
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		delete(running, task.TaskType)
+		delete(running, task.GroupKey)
+		mu.Unlock()
+		return map[string]string{"ok": "true"}, nil
+	})
+	for _, taskType := range []string{"deploy", "migrate", "report"} {
+		svc.RegisterExecutor(taskType, exec)
+	}
+
+	svc.Scheduler().SetAntiAffinity("deploy", "migrate")
+	svc.Scheduler().SetPollingInterval(20 * time.Millisecond)
+
+	create := func(id, taskType, groupKey string) {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, taskType, nil, nil, 0, "testuser")
+		task.ID = id
+		task.GroupKey = groupKey
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	create("deploy-1", "deploy", "")
+	create("migrate-1", "migrate", "")
+	create("report-1", "report", "tenant-a")
+	create("report-2", "report", "tenant-a")
+	svc.StartScheduler(ctx)
+
+	// deploy 与 migrate 反亲和，同一分组的 report 串行，各有一个任务等待
+	waitFor(t, func() bool {
+		blocked := 0
+		for _, id := range []string{"deploy-1", "migrate-1", "report-1", "report-2"} {
+			task, _ := repo.GetByID(id)
+			if task.Status == model.TaskStatusPending && task.BlockedReason != "" {
+				blocked++
+			}
+		}
+		return blocked == 2
+	})
+
+	close(release)
+	waitFor(t, func() bool {
+		for _, id := range []string{"deploy-1", "migrate-1", "report-1", "report-2"} {
+			task, _ := repo.GetByID(id)
+			if task.Status != model.TaskStatusSucceeded {
+				return false
+			}
+		}
+		return true
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if len(violations) > 0 {
+		t.Errorf("tasks started while a conflicting task was running: %v", violations)
+	}
+}
+
+func TestScheduler_ResolvesSecretsAtExecutionTime(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	manager, err := secrets.NewManager(repository.NewMemorySecretRepository(), bytes.Repeat([]byte{1}, secrets.MasterKeySize))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if _, err := manager.Put("api-token", "s3cr3t", "admin"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	ctx := context.Background()
+	var mu sync.Mutex
+	seen := make(map[string]string)
+	svc.RegisterExecutor("call", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		mu.Lock()
+		seen[task.ID] = task.InputParams["auth"]
+		mu.Unlock()
+		return nil, nil
+	}))
+	svc.Scheduler().SetSecretManager(manager)
+	svc.Scheduler().SetPollingInterval(10 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	ok, err := svc.CreateTask(ctx, "ok", "", model.TaskPriorityNormal, "call", map[string]string{"auth": "Bearer ${secret:api-token}"}, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	missing, err := svc.CreateTask(ctx, "missing", "", model.TaskPriorityNormal, "call", map[string]string{"auth": "${secret:nope}"}, nil, 3, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		a, _ := repo.GetByID(ok.ID)
+		b, _ := repo.GetByID(missing.ID)
+		return a.Status == model.TaskStatusSucceeded && b.Status == model.TaskStatusFailed
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if seen[ok.ID] != "Bearer s3cr3t" {
+		t.Errorf("executor saw %q, want resolved secret", seen[ok.ID])
+	}
+	if _, ran := seen[missing.ID]; ran {
+		t.Error("executor should not run when a referenced secret is missing")
+	}
+
+	// 仓储中只保留引用
+	got, _ := repo.GetByID(ok.ID)
+	if got.InputParams["auth"] != "Bearer ${secret:api-token}" {
+		t.Errorf("stored input params = %v, want reference", got.InputParams)
+	}
+
+	// 密钥缺失属于不可重试错误
+	got, _ = repo.GetByID(missing.ID)
+	if got.ErrorClass != model.ErrorClassFatal || got.RetryCount != 0 {
+		t.Errorf("expected fatal failure without retries, got class=%s retries=%d", got.ErrorClass, got.RetryCount)
+	}
+}
+
+func TestScheduler_RedactsSensitiveValuesInTaskLogs(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	manager, err := secrets.NewManager(repository.NewMemorySecretRepository(), bytes.Repeat([]byte{1}, secrets.MasterKeySize))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	manager.Put("api-token", "s3cr3t", "admin")
+	redactor, err := redact.New([]string{"*password*"})
+	if err != nil {
+		t.Fatalf("redact.New: %v", err)
+	}
+
+	ctx := context.Background()
+	svc.RegisterExecutor("leaky", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		ExecutionContextFrom(ctx).Logf("connecting as %s with %s", task.InputParams["password"], task.InputParams["auth"])
+		return nil, nil
+	}))
+	svc.Scheduler().SetSecretManager(manager)
+	svc.Scheduler().SetRedactor(redactor)
+	svc.Scheduler().SetPollingInterval(10 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	task, err := svc.CreateTask(ctx, "leaky", "", model.TaskPriorityNormal, "leaky",
+		map[string]string{"password": "hunter2", "auth": "${secret:api-token}"}, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	waitFor(t, func() bool {
+		got, _ := repo.GetByID(task.ID)
+		return got.Status == model.TaskStatusSucceeded
+	})
+
+	lines, err := repo.GetLogs(task.ID, 0, 10)
+	if err != nil || len(lines) != 1 {
+		t.Fatalf("GetLogs = %v, %v", lines, err)
+	}
+	if want := "connecting as " + redact.Mask + " with " + redact.Mask; lines[0].Line != want {
+		t.Errorf("log line = %q, want %q", lines[0].Line, want)
+	}
+}
+
+func TestScheduler_ExecutionContextHelpers(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	manager, err := secrets.NewManager(repository.NewMemorySecretRepository(), bytes.Repeat([]byte{1}, secrets.MasterKeySize))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	manager.Put("api-token", "s3cr3t", "admin")
+
+	ctx := context.Background()
+	childID := make(chan string, 1)
+	svc.RegisterExecutor("parent", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		ec := ExecutionContextFrom(ctx)
+		if ec.Context() != ctx || ec.Canceled() {
+			return nil, errors.New("unexpected execution context")
+		}
+		token, err := ec.Secret("api-token")
+		if err != nil {
+			return nil, err
+		}
+		if err := ec.Progress(50, "using "+token); err != nil {
+			return nil, err
+		}
+		if err := ec.SetSubStatus("UPLOADING", "to "+token); err != nil {
+			return nil, err
+		}
+		if err := ec.SetSubStatus("VALIDATING", ""); err != nil {
+			return nil, err
+		}
+		if err := ec.Heartbeat(); err != nil {
+			return nil, err
+		}
+		if _, err := ec.Secret("nope"); err == nil {
+			return nil, errors.New("expected error for a missing secret")
+		} else if class, _ := ClassifyError(err); class != model.ErrorClassFatal {
+			return nil, errors.New("expected fatal error for a missing secret, got " + err.Error())
+		}
+		child := model.NewTask("child", "", model.TaskPriorityUnspecified, "child", nil, nil, 0, "")
+		if err := ec.SubmitChild(child); err != nil {
+			return nil, err
+		}
+		childID <- child.ID
+		return nil, nil
+	}))
+	svc.RegisterExecutor("child", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		return nil, nil
+	}))
+	svc.Scheduler().SetSecretManager(manager)
+	svc.Scheduler().SetPollingInterval(10 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	parent := model.NewTask("parent", "", model.TaskPriorityHigh, "parent", nil, nil, 0, "alice")
+	parent.CorrelationID = "req-1"
+	workflowDeadline := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	parent.WorkflowDeadline = &workflowDeadline
+	if err := svc.SubmitTask(ctx, parent); err != nil {
+		t.Fatalf("failed to submit task: %v", err)
+	}
+	waitFor(t, func() bool {
+		got, _ := repo.GetByID(parent.ID)
+		return got.Status.IsTerminal()
+	})
+
+	got, _ := repo.GetByID(parent.ID)
+	if got.Status != model.TaskStatusSucceeded {
+		t.Fatalf("parent finished as %s: %s", got.Status, got.ErrorMessage)
+	}
+	// 进度说明中的密钥明文替换为掩码
+	if got.Progress != 50 || got.ProgressMessage != "using "+redact.Mask || got.HeartbeatAt == nil {
+		t.Errorf("unexpected progress: %d %q %v", got.Progress, got.ProgressMessage, got.HeartbeatAt)
+	}
+	// 子状态和阶段历史在任务结束后保留，任务状态不受影响
+	if got.SubStatus != "VALIDATING" || len(got.Phases) != 2 || got.Phases[0].Message != "to "+redact.Mask || got.Phases[1].Attempt != 1 {
+		t.Errorf("unexpected sub-status %q with phases %+v", got.SubStatus, got.Phases)
+	}
+
+	id := <-childID
+	waitFor(t, func() bool {
+		child, _ := repo.GetByID(id)
+		return child.Status == model.TaskStatusSucceeded
+	})
+	child, _ := repo.GetByID(id)
+	if child.ParentID != parent.ID || child.CreatedBy != "alice" || child.Priority != model.TaskPriorityHigh || child.CorrelationID != "req-1" ||
+		child.WorkflowDeadline == nil || !child.WorkflowDeadline.Equal(workflowDeadline) {
+		t.Errorf("child did not inherit from parent: parent=%q created_by=%q priority=%s correlation=%q workflow_deadline=%v",
+			child.ParentID, child.CreatedBy, child.Priority, child.CorrelationID, child.WorkflowDeadline)
+	}
+
+	// 不在调度器中执行时进度被忽略，提交子任务返回 ErrNoScheduler
+	ec := ExecutionContextFrom(ctx)
+	if err := ec.Progress(10, ""); err != nil {
+		t.Errorf("expected detached Progress to be ignored, got %v", err)
+	}
+	if err := ec.SubmitChild(model.NewTask("orphan", "", model.TaskPriorityNormal, "child", nil, nil, 0, "")); !errors.Is(err, ErrNoScheduler) {
+		t.Errorf("expected ErrNoScheduler, got %v", err)
+	}
+	if err := ec.Progress(101, ""); err == nil {
+		t.Error("expected error for out-of-range progress")
+	}
+	if err := ec.SetSubStatus("", ""); err == nil {
+		t.Error("expected error for an empty sub-status")
+	}
+}
+
+func TestScheduler_WaitForInputResumesOnSignal(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var executions atomic.Int32
+	svc.RegisterExecutor("approval", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		executions.Add(1)
+		input := ExecutionContextFrom(ctx).Input()
+		if input["approved"] == "" {
+			return nil, WaitForInput("approve deployment")
+		}
+		return map[string]string{"approved": input["approved"]}, nil
+	}))
+	svc.Scheduler().SetPollingInterval(10 * time.Millisecond)
+	svc.StartScheduler(ctx)
+
+	task, err := svc.CreateTask(ctx, "deploy", "", model.TaskPriorityNormal, "approval", nil, nil, 0, "alice")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	waitFor(t, func() bool {
+		got, _ := repo.GetByID(task.ID)
+		return got.Status == model.TaskStatusWaitingInput
+	})
+	got, _ := repo.GetByID(task.ID)
+	if got.InputRequest != "approve deployment" || got.RetryCount != 0 || got.ErrorMessage != "" {
+		t.Errorf("unexpected waiting task: %+v", got)
+	}
+
+	// 只有等待输入的任务可以接收信号，空的输入被拒绝
+	if _, err := svc.SignalTask(ctx, task.ID, nil, "bob"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument for an empty payload, got %v", err)
+	}
+	if _, err := svc.SignalTask(ctx, task.ID, map[string]string{"approved": "bob"}, "bob"); err != nil {
+		t.Fatalf("SignalTask: %v", err)
+	}
+	if _, err := svc.SignalTask(ctx, task.ID, map[string]string{"approved": "eve"}, "eve"); !errors.Is(err, ErrInvalidTransition) && !errors.Is(err, ErrConflict) {
+		t.Errorf("expected a second signal to be rejected, got %v", err)
+	}
+
+	waitFor(t, func() bool {
+		got, _ := repo.GetByID(task.ID)
+		return got.Status.IsTerminal()
+	})
+	got, _ = repo.GetByID(task.ID)
+	if got.Status != model.TaskStatusSucceeded || got.OutputResult["approved"] != "bob" || got.InputRequest != "" {
+		t.Errorf("unexpected resumed task: %+v", got)
+	}
+	if n := executions.Load(); n != 2 {
+		t.Errorf("expected 2 executions, got %d", n)
+	}
+}
+
+func TestScheduler_ConcurrentStartStopTrySchedule(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	s := svc.Scheduler()
+	s.SetPollingInterval(time.Millisecond)
+
+	var ids []string
+	for i := 0; i < 20; i++ {
+		task := model.NewTask("concurrent", "", model.TaskPriorityNormal, "test", nil, nil, 0, "testuser")
+		task.ID = "concurrent-" + strconv.Itoa(i)
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	// 调度和查询状态贯穿启动、停止全过程
+	done := make(chan struct{})
+	var workers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, id := range ids {
+					s.TrySchedule(id)
+				}
+				s.GetStatus()
+			}
+		}()
+	}
+
+	var lifecycle sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		lifecycle.Add(1)
+		go func() {
+			defer lifecycle.Done()
+			s.Start(ctx)
+		}()
+	}
+	lifecycle.Wait()
+	if !s.GetStatus().IsRunning {
+		t.Fatal("expected scheduler to be running after Start")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		lifecycle.Add(1)
+		go func() {
+			defer lifecycle.Done()
+			s.Stop()
+		}()
+	}
+	lifecycle.Wait()
+	close(done)
+	workers.Wait()
+
+	// 停止后不再认领任务
+	status := s.GetStatus()
+	if status.IsRunning {
+		t.Fatal("expected scheduler to be stopped")
+	}
+	if status.RunningCnt != 0 {
+		t.Errorf("expected no running tasks after stop, got %d", status.RunningCnt)
+	}
+	before, err := repo.ListPending(len(ids), time.Now())
+	if err != nil {
+		t.Fatalf("failed to list pending tasks: %v", err)
+	}
+	for _, task := range before {
+		if err := s.TrySchedule(task.ID); err != nil {
+			t.Errorf("TrySchedule after stop returned %v", err)
+		}
+	}
+	after, _ := repo.ListPending(len(ids), time.Now())
+	if len(after) != len(before) {
+		t.Errorf("expected %d tasks to stay pending after stop, got %d", len(before), len(after))
+	}
+	for _, id := range ids {
+		if task, _ := repo.GetByID(id); task.Status == model.TaskStatusRunning {
+			t.Errorf("task %s left RUNNING after stop", id)
+		}
+	}
+}
+
+func TestScheduler_RestartAfterStop(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	s := svc.Scheduler()
+	s.SetPollingInterval(20 * time.Millisecond)
+
+	// 启动、停止交替多次，每轮创建的任务都被执行
+	for round := 0; round < 3; round++ {
+		svc.StartScheduler(ctx)
+		task, err := svc.CreateTask(ctx, "round-"+strconv.Itoa(round), "", model.TaskPriorityNormal, "test", nil, nil, 0, "testuser")
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		waitFor(t, func() bool {
+			got, err := repo.GetByID(task.ID)
+			return err == nil && got.Status == model.TaskStatusSucceeded
+		})
+		svc.StopScheduler()
+		svc.StopScheduler() // 重复停止直接返回
+		if s.GetStatus().IsRunning {
+			t.Fatalf("round %d: expected scheduler to be stopped", round)
+		}
+	}
+
+	// 停止期间创建的任务在重新启动后被调度
+	task, err := svc.CreateTask(ctx, "while-stopped", "", model.TaskPriorityNormal, "test", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if got, _ := repo.GetByID(task.ID); got.Status != model.TaskStatusPending {
+		t.Fatalf("expected task to stay pending while stopped, got %s", got.Status)
+	}
+	svc.StartScheduler(ctx)
+	waitFor(t, func() bool {
+		got, err := repo.GetByID(task.ID)
+		return err == nil && got.Status == model.TaskStatusSucceeded
+	})
+}
+
+func TestScheduler_PauseAndResume(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	s := svc.Scheduler()
+	s.SetPollingInterval(20 * time.Millisecond)
+	if err := svc.PauseScheduler(); !errors.Is(err, ErrSchedulerNotRunning) {
+		t.Fatalf("expected ErrSchedulerNotRunning before start, got %v", err)
+	}
+
+	release := make(chan struct{})
+	svc.RegisterExecutor("blocking", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		<-release
+		return nil, nil
+	}))
+	svc.StartScheduler(ctx)
+
+	running, err := svc.CreateTask(ctx, "running", "", model.TaskPriorityNormal, "blocking", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	waitFor(t, func() bool {
+		got, err := repo.GetByID(running.ID)
+		return err == nil && got.Status == model.TaskStatusRunning
+	})
+
+	if err := svc.PauseScheduler(); err != nil {
+		t.Fatalf("PauseScheduler failed: %v", err)
+	}
+	status := s.GetStatus()
+	if !status.IsRunning || !status.IsPaused {
+		t.Fatalf("expected running and paused, got %+v", status)
+	}
+
+	// 暂停期间新任务保持 PENDING，运行中的任务继续执行
+	queued, err := svc.CreateTask(ctx, "queued", "", model.TaskPriorityNormal, "test", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	close(release)
+	waitFor(t, func() bool {
+		got, err := repo.GetByID(running.ID)
+		return err == nil && got.Status == model.TaskStatusSucceeded
+	})
+	time.Sleep(60 * time.Millisecond)
+	if got, _ := repo.GetByID(queued.ID); got.Status != model.TaskStatusPending {
+		t.Fatalf("expected task to stay pending while paused, got %s", got.Status)
+	}
+
+	if err := svc.ResumeScheduler(); err != nil {
+		t.Fatalf("ResumeScheduler failed: %v", err)
+	}
+	waitFor(t, func() bool {
+		got, err := repo.GetByID(queued.ID)
+		return err == nil && got.Status == model.TaskStatusSucceeded
+	})
+	if s.GetStatus().IsPaused {
+		t.Error("expected scheduler to be resumed")
+	}
+
+	// 停止会清除暂停
+	if err := svc.PauseScheduler(); err != nil {
+		t.Fatalf("PauseScheduler failed: %v", err)
+	}
+	svc.StopScheduler()
+	svc.StartScheduler(ctx)
+	if status := s.GetStatus(); !status.IsRunning || status.IsPaused {
+		t.Errorf("expected restarted scheduler to be unpaused, got %+v", status)
+	}
+}
+
+func TestScheduler_GetSchedulingDecision(t *testing.T) {
+	svc, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	s := svc.Scheduler()
+	now := time.Now()
+	if err := s.SetBlackoutWindows(BlackoutWindow{
+		Name: "freeze", TaskTypes: []string{"report"}, Start: now.Add(-time.Minute), End: now.Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to set blackout windows: %v", err)
+	}
+	s.SetSingletonTaskTypes("backup")
+
+	create := func(id, taskType string, deps []string, policies map[string]model.DependencyFailurePolicy) *model.Task {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, taskType, nil, deps, 0, "testuser")
+		task.ID = id
+		task.DependencyPolicies = policies
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		return task
+	}
+	codes := func(id string) []model.SchedulingReasonCode {
+		t.Helper()
+		d, err := s.GetSchedulingDecision(id)
+		if err != nil {
+			t.Fatalf("GetSchedulingDecision(%s): %v", id, err)
+		}
+		out := make([]model.SchedulingReasonCode, len(d.Reasons))
+		for i, r := range d.Reasons {
+			out[i] = r.Code
+		}
+		if d.Dispatchable != (len(out) == 0) {
+			t.Errorf("%s: dispatchable=%v with reasons %v", id, d.Dispatchable, out)
+		}
+		return out
+	}
+
+	create("plain", "test", nil, nil)
+	if got := codes("plain"); !slices.Equal(got, []model.SchedulingReasonCode{model.ReasonSchedulerStopped}) {
+		t.Fatalf("expected only scheduler_stopped before start, got %v", got)
+	}
+
+	// 暂停后调度器不会认领任务，各项原因保持稳定
+	s.SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+	defer svc.StopScheduler()
+	if err := svc.PauseScheduler(); err != nil {
+		t.Fatalf("PauseScheduler failed: %v", err)
+	}
+	paused := model.ReasonSchedulerPaused
+
+	create("backup-running", "backup", nil, nil)
+	if err := repo.UpdateStatus("backup-running", model.TaskStatusPending, model.TaskStatusRunning); err != nil {
+		t.Fatalf("failed to start task: %v", err)
+	}
+	create("backup-next", "backup", nil, nil)
+	create("failed", "test", nil, nil)
+	if err := repo.UpdateStatus("failed", model.TaskStatusPending, model.TaskStatusFailed); err != nil {
+		t.Fatalf("failed to fail task: %v", err)
+	}
+	create("throttled", "test", nil, nil)
+	if err := repo.UpdateStatus("throttled", model.TaskStatusPending, model.TaskStatusRunning); err != nil {
+		t.Fatalf("failed to start task: %v", err)
+	}
+	nextRun := now.Add(time.Hour)
+	if err := repo.ScheduleRetry("throttled", model.TaskStatusRunning, 0, &nextRun, "429", model.ErrorClassRateLimited, "system", "", "", nil); err != nil {
+		t.Fatalf("failed to schedule retry: %v", err)
+	}
+	create("report", "report", nil, nil)
+	create("child", "test", []string{"backup-running", "failed", "missing"}, nil)
+	create("waiting", "test", []string{"failed"}, map[string]model.DependencyFailurePolicy{"failed": model.DependencyFailureWait})
+
+	for id, want := range map[string][]model.SchedulingReasonCode{
+		"plain":          {paused},
+		"backup-running": {model.ReasonNotPending},
+		"backup-next":    {paused, model.ReasonExclusion},
+		"throttled":      {paused, model.ReasonRateLimited},
+		"report":         {paused, model.ReasonBlackoutWindow},
+		"child":          {paused, model.ReasonDependencyFailed, model.ReasonDependenciesUnmet},
+		"waiting":        {paused, model.ReasonDependenciesUnmet},
+	} {
+		if got := codes(id); !slices.Equal(got, want) {
+			t.Errorf("%s: expected reasons %v, got %v", id, want, got)
+		}
+	}
+
+	// 依赖原因列出具体的上游任务及其状态
+	d, _ := s.GetSchedulingDecision("child")
+	failed, unmet := d.Reasons[1], d.Reasons[2]
+	if len(failed.Dependencies) != 1 || failed.Dependencies[0].TaskID != "failed" || failed.Dependencies[0].Status != model.TaskStatusFailed {
+		t.Errorf("unexpected failed dependencies: %+v", failed.Dependencies)
+	}
+	if !strings.Contains(unmet.Message, "backup-running (RUNNING)") || !strings.Contains(unmet.Message, "missing (not found)") {
+		t.Errorf("unexpected unmet dependency message: %q", unmet.Message)
+	}
+	if d, _ := s.GetSchedulingDecision("report"); d.Reasons[1].Until == nil || !strings.Contains(d.Reasons[1].Message, "freeze") {
+		t.Errorf("expected blackout reason to name the window and its end, got %+v", d.Reasons[1])
+	}
+	if d, _ := s.GetSchedulingDecision("throttled"); d.Reasons[1].Until == nil || !d.Reasons[1].Until.Equal(nextRun.Truncate(time.Millisecond)) {
+		t.Errorf("expected rate limit reason to expire at %v, got %+v", nextRun, d.Reasons[1])
+	}
+
+	if err := svc.ResumeScheduler(); err != nil {
+		t.Fatalf("ResumeScheduler failed: %v", err)
+	}
+	if got := codes("backup-next"); !slices.Equal(got, []model.SchedulingReasonCode{model.ReasonExclusion}) {
+		t.Errorf("expected only exclusion after resume, got %v", got)
+	}
+
+	if _, err := s.GetSchedulingDecision("nonexistent"); err == nil {
+		t.Error("expected error for unknown task")
+	}
+}
+
+// blockingGetStore 首次查询 blockID 时通知 entered 并等待 release 关闭
+type blockingGetStore struct {
+	repository.TaskStore
+	blockID string
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingGetStore) GetByID(id string) (*model.Task, error) {
+	if id == b.blockID {
+		b.once.Do(func() {
+			close(b.entered)
+			<-b.release
+		})
+	}
+	return b.TaskStore.GetByID(id)
+}
+
+// busyProgressStore 进度、子状态和心跳写入总是返回数据库繁忙
+type busyProgressStore struct {
+	repository.TaskStore
+}
+
+var errDatabaseLocked = errors.New("database is locked")
+
+func (busyProgressStore) UpdateProgress(string, int32, string, time.Time) error {
+	return errDatabaseLocked
+}
+
+func (busyProgressStore) UpdateSubStatus(string, string, string, time.Time, string, string) error {
+	return errDatabaseLocked
+}
+
+func (busyProgressStore) Heartbeat(string, time.Time) error { return errDatabaseLocked }
+
+func TestScheduler_ProgressWriteFailuresDoNotFailTask(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	task := model.NewTask("busy", "", model.TaskPriorityNormal, "reporter", nil, nil, 0, "testuser")
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	s := NewScheduler(busyProgressStore{TaskStore: repo})
+	s.RegisterExecutor("reporter", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		ec := ExecutionContextFrom(ctx)
+		if err := ec.Progress(50, "halfway"); err != nil {
+			return nil, err
+		}
+		if err := ec.SetSubStatus("UPLOADING", ""); err != nil {
+			return nil, err
+		}
+		return nil, ec.Heartbeat()
+	}))
+	s.SetPollingInterval(10 * time.Millisecond)
+	s.Start(context.Background())
+	defer s.Stop()
+
+	waitFor(t, func() bool {
+		got, _ := repo.GetByID(task.ID)
+		return got.Status.IsTerminal()
+	})
+	if got, _ := repo.GetByID(task.ID); got.Status != model.TaskStatusSucceeded {
+		t.Errorf("expected failed progress writes ignored, task is %s: %s", got.Status, got.ErrorMessage)
+	}
+}
+
+func TestScheduler_StopWaitsForInflightTrySchedule(t *testing.T) {
+	_, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	task := model.NewTask("inflight", "", model.TaskPriorityNormal, "test", nil, nil, 0, "testuser")
+	task.ID = "inflight"
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	store := &blockingGetStore{TaskStore: repo, blockID: task.ID, entered: make(chan struct{}), release: make(chan struct{})}
+	s := NewScheduler(store)
+	s.SetPollingInterval(time.Hour)
+	s.Start(context.Background())
+
+	scheduled := make(chan error, 1)
+	go func() { scheduled <- s.TrySchedule(task.ID) }()
+	<-store.entered
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+
+	// 调度进行中时 Stop 不关闭工作池，状态查询不被阻塞且已反映停止
+	waitFor(t, func() bool { return !s.GetStatus().IsRunning })
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while TrySchedule was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(store.release)
+	if err := <-scheduled; err != nil {
+		t.Fatalf("TrySchedule returned %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return after TrySchedule finished")
+	}
+
+	// 停止前进入的调度提交到工作池，Stop 返回时任务已执行结束，不会滞留在 RUNNING
+	got, err := repo.GetByID(task.ID)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	if got.Status == model.TaskStatusPending || got.Status == model.TaskStatusRunning {
+		t.Errorf("expected in-flight task to be executed before stop returned, got %s", got.Status)
+	}
+}
+
+// BenchmarkScheduler_Dispatch 边创建边调度，报告创建到开始执行的延迟分位数和吞吐
+func BenchmarkScheduler_Dispatch(b *testing.B) {
+	svc, _, cleanup := setupTestService(b)
+	defer cleanup()
+
+	ctx := context.Background()
+	svc.RegisterExecutor("noop", ExecutorFunc(func(ctx context.Context, task *model.Task) (map[string]string, error) {
+		return nil, nil
+	}))
+
+	var mu sync.Mutex
+	createdAt := make(map[string]time.Time, b.N)
+	delays := make([]time.Duration, 0, b.N)
+	done := make(chan struct{}, b.N)
+	s := svc.Scheduler()
+	s.OnTaskChange(func(task *model.Task, from, to model.TaskStatus) {
+		switch {
+		case to == model.TaskStatusRunning:
+			mu.Lock()
+			if at, ok := createdAt[task.Name]; ok {
+				delays = append(delays, time.Since(at))
+			}
+			mu.Unlock()
+		case to.IsTerminal():
+			done <- struct{}{}
+		}
+	})
+	s.SetPollingInterval(time.Hour)
+	svc.StartScheduler(ctx)
+
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		name := "bench-" + strconv.Itoa(i)
+		mu.Lock()
+		createdAt[name] = time.Now()
+		mu.Unlock()
+		if _, err := svc.CreateTask(ctx, name, "", model.TaskPriorityNormal, "noop", nil, nil, 0, "bench"); err != nil {
+			b.Fatalf("failed to create task: %v", err)
+		}
+		s.Wake()
+	}
+	for i := 0; i < b.N; i++ {
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			b.Fatalf("only %d of %d tasks finished", i, b.N)
+		}
+	}
+	elapsed := time.Since(start)
+	b.StopTimer()
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	if len(delays) > 0 {
+		b.ReportMetric(float64(delays[len(delays)/2].Microseconds())/1000, "p50-dispatch-ms")
+		b.ReportMetric(float64(delays[len(delays)*99/100].Microseconds())/1000, "p99-dispatch-ms")
+	}
+	b.ReportMetric(float64(b.N)/elapsed.Seconds(), "tasks/s")
+}
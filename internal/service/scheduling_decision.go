@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+// GetSchedulingDecision 按调度路径逐项检查任务，返回当前阻止它被认领的全部原因（调度器运行状态、依赖、重试退避、
+// 暂停窗口、路由约束、资源槽位、互斥任务、工作池队列和单轮评估上限）。只读：不记录阻塞原因、不改变任务状态。
+// 路由约束、资源槽位和工作池队列按本实例评估，多实例部署时其他实例的评估可能不同；任务不存在时返回 KindNotFound 错误
+func (s *Scheduler) GetSchedulingDecision(taskID string) (*model.SchedulingDecision, error) {
+	task, err := getTask(s.repo, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	decision := &model.SchedulingDecision{
+		TaskID:      task.ID,
+		Status:      task.Status,
+		InstanceID:  s.instanceID,
+		EvaluatedAt: now,
+	}
+	addReason := func(r model.SchedulingReason) {
+		decision.Reasons = append(decision.Reasons, r)
+	}
+
+	if task.Status != model.TaskStatusPending {
+		addReason(model.SchedulingReason{Code: model.ReasonNotPending, Message: "task is " + task.Status.String()})
+		return decision, nil
+	}
+
+	switch {
+	case s.state.currentPhase() != schedulerRunning:
+		addReason(model.SchedulingReason{Code: model.ReasonSchedulerStopped, Message: "scheduler is not running"})
+	case !s.state.dispatching():
+		addReason(model.SchedulingReason{Code: model.ReasonSchedulerPaused, Message: "scheduler is paused"})
+	}
+
+	reasons, err := s.dependencyReasons(task)
+	if err != nil {
+		return nil, err
+	}
+	decision.Reasons = append(decision.Reasons, reasons...)
+
+	if task.NextRunAt != nil && task.NextRunAt.After(now) {
+		until := *task.NextRunAt
+		if task.ErrorClass == model.ErrorClassRateLimited {
+			addReason(model.SchedulingReason{Code: model.ReasonRateLimited, Until: &until,
+				Message: "rate limited by the previous attempt, retrying after " + formatDecisionTime(until)})
+		} else {
+			addReason(model.SchedulingReason{Code: model.ReasonRetryBackoff, Until: &until,
+				Message: fmt.Sprintf("retry %d backing off until %s", task.RetryCount, formatDecisionTime(until))})
+		}
+	}
+
+	if reason, end, active := s.activeBlackout(task.TaskType, now); active {
+		addReason(model.SchedulingReason{Code: model.ReasonBlackoutWindow, Message: reason, Until: &end})
+	}
+
+	if reason := task.RoutingMismatch(s.instanceID, s.hostname, s.workerLabels); reason != "" {
+		addReason(model.SchedulingReason{Code: model.ReasonWorkerMismatch, Message: reason + ", waiting for a matching scheduler instance"})
+	}
+
+	if !s.resourcesAvailable(task.Slots()) {
+		addReason(model.SchedulingReason{Code: model.ReasonResourceSlots, Message: s.resourceReason(task.Slots())})
+	}
+
+	if err := s.repo.RunningConflict(task.ID, s.exclusion(task)); errors.Is(err, repository.ErrExclusionBusy) {
+		addReason(model.SchedulingReason{Code: model.ReasonExclusion, Message: err.Error()})
+	} else if err != nil {
+		return nil, err
+	}
+
+	if s.workerPool.Saturated() {
+		addReason(model.SchedulingReason{Code: model.ReasonQueueFull, Message: "worker pool queue is full"})
+	}
+
+	// 其余条件都满足时，检查任务是否在一轮评估读取的范围内
+	if len(decision.Reasons) == 0 {
+		pending, err := s.repo.ListPending(s.maxPending, now)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(pending, func(t *model.Task) bool { return t.ID == task.ID }) {
+			addReason(model.SchedulingReason{Code: model.ReasonBacklog,
+				Message: fmt.Sprintf("more than %d ready tasks with higher priority or earlier creation are evaluated first", s.maxPending)})
+		}
+	}
+
+	decision.Dispatchable = len(decision.Reasons) == 0
+	return decision, nil
+}
+
+// dependencyReasons 列出未满足的依赖，规则与 DefaultDependencyChecker.Evaluate 一致：
+// 上游最终未成功且按 skip 处理时为 dependency_failed，其余未满足的依赖（含按 wait 处理的失败上游）为 dependencies_unmet
+func (s *Scheduler) dependencyReasons(task *model.Task) ([]model.SchedulingReason, error) {
+	if len(task.Dependencies) == 0 {
+		return nil, nil
+	}
+	deps, err := s.repo.GetByIDs(task.Dependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	var unmet, failed []model.DependencyState
+	for i, dep := range deps {
+		state := model.DependencyState{TaskID: task.Dependencies[i], Policy: task.DependencyPolicy(task.Dependencies[i])}
+		switch {
+		case dep == nil:
+			unmet = append(unmet, state)
+		case dep.Status == model.TaskStatusSucceeded:
+		case !dep.Status.IsTerminal():
+			state.Status = dep.Status
+			unmet = append(unmet, state)
+		case state.Policy == model.DependencyFailureIgnore:
+		case state.Policy == model.DependencyFailureWait:
+			state.Status = dep.Status
+			unmet = append(unmet, state)
+		default:
+			state.Status = dep.Status
+			failed = append(failed, state)
+		}
+	}
+
+	var reasons []model.SchedulingReason
+	if len(failed) > 0 {
+		reasons = append(reasons, model.SchedulingReason{Code: model.ReasonDependencyFailed, Dependencies: failed,
+			Message: "dependencies did not succeed, task will be skipped: " + describeDependencies(failed)})
+	}
+	if len(unmet) > 0 {
+		reasons = append(reasons, model.SchedulingReason{Code: model.ReasonDependenciesUnmet, Dependencies: unmet,
+			Message: "waiting for dependencies: " + describeDependencies(unmet)})
+	}
+	return reasons, nil
+}
+
+// describeDependencies 形如 "a (RUNNING), b (FAILED, wait)"，不存在的上游显示为 not found
+func describeDependencies(deps []model.DependencyState) string {
+	parts := make([]string, len(deps))
+	for i, d := range deps {
+		switch {
+		case d.Status == model.TaskStatusUnspecified:
+			parts[i] = d.TaskID + " (not found)"
+		case d.Status.IsTerminal():
+			parts[i] = fmt.Sprintf("%s (%s, %s)", d.TaskID, d.Status, d.Policy)
+		default:
+			parts[i] = fmt.Sprintf("%s (%s)", d.TaskID, d.Status)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatDecisionTime 调度决策说明中的时间格式
+func formatDecisionTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
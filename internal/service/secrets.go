@@ -0,0 +1,33 @@
+package service
+
+import (
+	"errors"
+
+	"taskflow/internal/model"
+	"taskflow/internal/secrets"
+)
+
+// SetSecretManager 设置密钥管理器，执行前把 InputParams 中的 ${secret:NAME} 引用替换为明文。
+// 替换只作用于交给执行器的任务副本，仓储和接口中的参数始终是引用。须在 Start 之前调用
+func (s *Scheduler) SetSecretManager(m *secrets.Manager) {
+	s.secretManager = m
+}
+
+// resolveSecrets 返回替换了密钥引用的任务副本和解析出的明文，没有引用时返回原任务。
+// 密钥不存在或无法解密时返回 Fatal 错误，重试无法恢复
+func (s *Scheduler) resolveSecrets(task *model.Task) (*model.Task, []string, error) {
+	if len(secrets.References(task.InputParams)) == 0 {
+		return task, nil, nil
+	}
+	if s.secretManager == nil {
+		return nil, nil, Fatal(errors.New("task references secrets but secrets are not enabled"))
+	}
+
+	params, values, err := s.secretManager.ResolveParams(task.InputParams)
+	if err != nil {
+		return nil, nil, Fatal(err)
+	}
+	resolved := *task
+	resolved.InputParams = params
+	return &resolved, values, nil
+}
@@ -1,21 +1,22 @@
 package service
 
 import (
-	"fmt"
+	"taskflow/internal/clock"
 	"taskflow/internal/model"
-	"time"
 )
 
 // StateMachine 任务状态机
 type StateMachine struct {
 	// transitions 定义有效状态转换
 	transitions map[model.TaskStatus][]model.TaskStatus
+	clock       clock.Clock // 记录开始、结束和更新时间
 }
 
 // NewStateMachine 创建状态机
 func NewStateMachine() *StateMachine {
 	sm := &StateMachine{
 		transitions: make(map[model.TaskStatus][]model.TaskStatus),
+		clock:       clock.Real,
 	}
 	sm.initTransitions()
 	return sm
@@ -23,18 +24,26 @@ func NewStateMachine() *StateMachine {
 
 // initTransitions 初始化有效状态转换
 func (sm *StateMachine) initTransitions() {
-	// PENDING 可以转换到 RUNNING, CANCELLED
+	// PENDING 可以转换到 RUNNING, CANCELLED, SKIPPED (上游依赖最终未成功)
 	sm.transitions[model.TaskStatusPending] = []model.TaskStatus{
 		model.TaskStatusRunning,
 		model.TaskStatusCancelled,
+		model.TaskStatusSkipped,
 	}
 
-	// RUNNING 可以转换到 SUCCEEDED, FAILED, TIMEOUT, CANCELLED
+	// RUNNING 可以转换到 SUCCEEDED, FAILED, TIMEOUT, CANCELLED, WAITING_INPUT (执行器请求外部输入)
 	sm.transitions[model.TaskStatusRunning] = []model.TaskStatus{
 		model.TaskStatusSucceeded,
 		model.TaskStatusFailed,
 		model.TaskStatusTimeout,
 		model.TaskStatusCancelled,
+		model.TaskStatusWaitingInput,
+	}
+
+	// WAITING_INPUT 可以转换到 PENDING (收到信号), CANCELLED
+	sm.transitions[model.TaskStatusWaitingInput] = []model.TaskStatus{
+		model.TaskStatusPending,
+		model.TaskStatusCancelled,
 	}
 
 	// FAILED 可以转换到 PENDING (重试), CANCELLED
@@ -43,10 +52,11 @@ func (sm *StateMachine) initTransitions() {
 		model.TaskStatusCancelled,
 	}
 
-	// 终态: SUCCEEDED, CANCELLED, TIMEOUT 不能转换到其他状态
+	// 终态: SUCCEEDED, CANCELLED, TIMEOUT, SKIPPED 不能转换到其他状态
 	sm.transitions[model.TaskStatusSucceeded] = []model.TaskStatus{}
 	sm.transitions[model.TaskStatusCancelled] = []model.TaskStatus{}
 	sm.transitions[model.TaskStatusTimeout] = []model.TaskStatus{}
+	sm.transitions[model.TaskStatusSkipped] = []model.TaskStatus{}
 
 	// UNSPECIFIED 是初始态，可以转到 PENDING
 	sm.transitions[model.TaskStatusUnspecified] = []model.TaskStatus{
@@ -75,7 +85,7 @@ func (sm *StateMachine) Transition(task *model.Task, toStatus model.TaskStatus,
 
 	// 验证转换
 	if !sm.CanTransition(fromStatus, toStatus) {
-		return fmt.Errorf("invalid state transition from %s to %s", fromStatus, toStatus)
+		return newError(KindInvalidTransition, "invalid state transition from %s to %s", fromStatus, toStatus)
 	}
 
 	// 执行转换前的钩子
@@ -110,7 +120,7 @@ func (sm *StateMachine) preTransition(task *model.Task, from, to model.TaskStatu
 
 // postTransition 转换后钩子
 func (sm *StateMachine) postTransition(task *model.Task, from, to model.TaskStatus, operator string) {
-	now := time.Now()
+	now := sm.clock.Now()
 
 	switch to {
 	case model.TaskStatusRunning:
@@ -118,8 +128,6 @@ func (sm *StateMachine) postTransition(task *model.Task, from, to model.TaskStat
 	case model.TaskStatusSucceeded:
 		task.CompletedAt = &now
 		task.ErrorMessage = ""
-	case model.TaskStatusFailed:
-		task.RetryCount++
 	case model.TaskStatusCancelled:
 		// 取消时记录时间
 		if task.StartedAt != nil && task.CompletedAt == nil {
@@ -129,6 +137,8 @@ func (sm *StateMachine) postTransition(task *model.Task, from, to model.TaskStat
 		if task.StartedAt != nil && task.CompletedAt == nil {
 			task.CompletedAt = &now
 		}
+	case model.TaskStatusSkipped:
+		task.CompletedAt = &now
 	}
 
 	task.UpdatedAt = now
@@ -144,5 +154,6 @@ func (sm *StateMachine) IsTerminal(status model.TaskStatus) bool {
 	return status == model.TaskStatusSucceeded ||
 		status == model.TaskStatusFailed ||
 		status == model.TaskStatusCancelled ||
-		status == model.TaskStatusTimeout
+		status == model.TaskStatusTimeout ||
+		status == model.TaskStatusSkipped
 }
@@ -17,6 +17,7 @@ func TestStateMachine_CanTransition(t *testing.T) {
 	}{
 		{"PENDING -> RUNNING", model.TaskStatusPending, model.TaskStatusRunning, true},
 		{"PENDING -> CANCELLED", model.TaskStatusPending, model.TaskStatusCancelled, true},
+		{"PENDING -> SKIPPED", model.TaskStatusPending, model.TaskStatusSkipped, true},
 		{"PENDING -> SUCCEEDED", model.TaskStatusPending, model.TaskStatusSucceeded, false},
 		{"PENDING -> FAILED", model.TaskStatusPending, model.TaskStatusFailed, false},
 
@@ -24,8 +25,14 @@ func TestStateMachine_CanTransition(t *testing.T) {
 		{"RUNNING -> FAILED", model.TaskStatusRunning, model.TaskStatusFailed, true},
 		{"RUNNING -> TIMEOUT", model.TaskStatusRunning, model.TaskStatusTimeout, true},
 		{"RUNNING -> CANCELLED", model.TaskStatusRunning, model.TaskStatusCancelled, true},
+		{"RUNNING -> WAITING_INPUT", model.TaskStatusRunning, model.TaskStatusWaitingInput, true},
 		{"RUNNING -> PENDING", model.TaskStatusRunning, model.TaskStatusPending, false},
 
+		{"WAITING_INPUT -> PENDING", model.TaskStatusWaitingInput, model.TaskStatusPending, true},
+		{"WAITING_INPUT -> CANCELLED", model.TaskStatusWaitingInput, model.TaskStatusCancelled, true},
+		{"WAITING_INPUT -> RUNNING", model.TaskStatusWaitingInput, model.TaskStatusRunning, false},
+		{"WAITING_INPUT -> SUCCEEDED", model.TaskStatusWaitingInput, model.TaskStatusSucceeded, false},
+
 		{"FAILED -> PENDING", model.TaskStatusFailed, model.TaskStatusPending, true},
 		{"FAILED -> CANCELLED", model.TaskStatusFailed, model.TaskStatusCancelled, true},
 		{"FAILED -> RUNNING", model.TaskStatusFailed, model.TaskStatusRunning, false},
@@ -33,6 +40,8 @@ func TestStateMachine_CanTransition(t *testing.T) {
 		{"SUCCEEDED -> any", model.TaskStatusSucceeded, model.TaskStatusPending, false},
 		{"CANCELLED -> any", model.TaskStatusCancelled, model.TaskStatusPending, false},
 		{"TIMEOUT -> any", model.TaskStatusTimeout, model.TaskStatusPending, false},
+		{"SKIPPED -> any", model.TaskStatusSkipped, model.TaskStatusPending, false},
+		{"RUNNING -> SKIPPED", model.TaskStatusRunning, model.TaskStatusSkipped, false},
 
 		{"UNSPECIFIED -> PENDING", model.TaskStatusUnspecified, model.TaskStatusPending, true},
 	}
@@ -110,8 +119,9 @@ func TestStateMachine_TransitionHooks(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if task2.RetryCount != 1 {
-		t.Errorf("expected RetryCount = 1, got %d", task2.RetryCount)
+	// 重试次数只在重新排队时累加
+	if task2.RetryCount != 0 {
+		t.Errorf("expected RetryCount = 0, got %d", task2.RetryCount)
 	}
 
 	// 测试 PENDING -> CANCELLED 转换
@@ -131,14 +141,14 @@ func TestStateMachine_GetAllowedTransitions(t *testing.T) {
 
 	// PENDING 允许的转换
 	pendingTransitions := sm.GetAllowedTransitions(model.TaskStatusPending)
-	if len(pendingTransitions) != 2 {
-		t.Errorf("expected 2 allowed transitions from PENDING, got %d", len(pendingTransitions))
+	if len(pendingTransitions) != 3 {
+		t.Errorf("expected 3 allowed transitions from PENDING, got %d", len(pendingTransitions))
 	}
 
 	// RUNNING 允许的转换
 	runningTransitions := sm.GetAllowedTransitions(model.TaskStatusRunning)
-	if len(runningTransitions) != 4 {
-		t.Errorf("expected 4 allowed transitions from RUNNING, got %d", len(runningTransitions))
+	if len(runningTransitions) != 5 {
+		t.Errorf("expected 5 allowed transitions from RUNNING, got %d", len(runningTransitions))
 	}
 
 	// 终态不允许转换
@@ -162,6 +172,8 @@ func TestStateMachine_IsTerminal(t *testing.T) {
 		{model.TaskStatusFailed, true},
 		{model.TaskStatusCancelled, true},
 		{model.TaskStatusTimeout, true},
+		{model.TaskStatusSkipped, true},
+		{model.TaskStatusWaitingInput, false},
 	}
 
 	for _, tt := range tests {
@@ -2,23 +2,25 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
 
-	"github.com/google/uuid"
+	"taskflow/internal/clock"
+	"taskflow/internal/idgen"
 	"taskflow/internal/logger"
 	"taskflow/internal/model"
+	"taskflow/internal/notify"
 	"taskflow/internal/repository"
 )
 
 // TaskService 任务服务
 type TaskService struct {
-	repo      *repository.TaskRepository
+	repo      repository.TaskStore
 	scheduler *Scheduler
 }
 
 // NewTaskService 创建任务服务
-func NewTaskService(repo *repository.TaskRepository) *TaskService {
+func NewTaskService(repo repository.TaskStore) *TaskService {
 	return &TaskService{
 		repo:      repo,
 		scheduler: NewScheduler(repo),
@@ -27,92 +29,96 @@ func NewTaskService(repo *repository.TaskRepository) *TaskService {
 
 // CreateTask 创建任务
 func (s *TaskService) CreateTask(ctx context.Context, name, description string, priority model.TaskPriority, taskType string, inputParams map[string]string, dependencies []string, maxRetries int32, createdBy string) (*model.Task, error) {
-	// 验证依赖任务是否存在
-	for _, depID := range dependencies {
-		depTask, err := s.repo.GetByID(depID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get dependency task: %w", err)
-		}
-		if depTask == nil {
-			return nil, fmt.Errorf("dependency task not found: %s", depID)
-		}
+	task := model.NewTask(name, description, priority, taskType, inputParams, dependencies, maxRetries, createdBy)
+	if err := s.SubmitTask(ctx, task); err != nil {
+		return nil, err
 	}
+	return task, nil
+}
 
-	task := model.NewTask(name, description, priority, taskType, inputParams, dependencies, maxRetries, createdBy)
-	task.ID = uuid.New().String()
+// SubmitTask 保存调用方构造好的任务并尝试调度，未设置 ID 时自动生成；创建时间按调度器的时钟记录
+func (s *TaskService) SubmitTask(ctx context.Context, task *model.Task) error {
+	return s.scheduler.submitTask(task)
+}
 
-	if err := s.repo.Create(task); err != nil {
-		return nil, fmt.Errorf("failed to create task: %w", err)
+// submitTask 校验依赖后保存任务并尝试调度，TaskService.SubmitTask 和执行上下文提交子任务共用
+func (s *Scheduler) submitTask(task *model.Task) error {
+	// 验证依赖任务是否存在
+	for _, depID := range task.Dependencies {
+		if _, err := s.repo.GetByID(depID); errors.Is(err, repository.ErrTaskNotFound) {
+			return newError(KindInvalidArgument, "dependency task not found: %s", depID)
+		} else if err != nil {
+			return fmt.Errorf("failed to get dependency task: %w", err)
+		}
 	}
 
-	// 记录创建事件
-	s.recordEvent(task, model.TaskStatusUnspecified, model.TaskStatusPending, "task created", createdBy)
+	if task.ID == "" {
+		task.ID = idgen.NewID()
+	}
+	task.CreatedAt = s.clock.Now()
+	task.UpdatedAt = task.CreatedAt
+
+	// 仓储在同一事务中记录创建事件
+	if err := s.repo.Create(task); errors.Is(err, repository.ErrCrossShard) {
+		return storeError(err)
+	} else if err != nil {
+		return fmt.Errorf("failed to create task: %w", err)
+	}
 
 	// 检查是否可以调度
-	if len(dependencies) == 0 {
-		s.scheduler.TrySchedule(task.ID)
+	if len(task.Dependencies) == 0 {
+		s.TrySchedule(task.ID)
+	} else {
+		s.refreshStalledReason(task)
+		s.Wake()
 	}
 
-	return task, nil
+	return nil
 }
 
-// GetTask 获取任务
+// GetTask 获取任务，不存在时返回 ErrNotFound 类错误
 func (s *TaskService) GetTask(ctx context.Context, id string) (*model.Task, error) {
-	return s.repo.GetByID(id)
+	return getTask(s.repo, id)
 }
 
-// UpdateTask 更新任务
-func (s *TaskService) UpdateTask(ctx context.Context, id string, updates map[string]interface{}, operator string) (*model.Task, error) {
-	task, err := s.repo.GetByID(id)
-	if err != nil {
-		return nil, err
-	}
-	if task == nil {
-		return nil, fmt.Errorf("task not found: %s", id)
+// UpdateTask 按字段掩码更新任务定义（名称、描述、优先级、参数、重试上限、标签）。
+// 掩码或取值不合法时返回 KindInvalidArgument，任务当前状态不允许修改其中的字段时返回 KindNotEditable；
+// 状态变更通过 CancelTask、RetryTask 等进行
+func (s *TaskService) UpdateTask(ctx context.Context, id string, update model.TaskUpdate) (*model.Task, error) {
+	if errs := update.Validate(); len(errs) > 0 {
+		return nil, newError(KindInvalidArgument, "%s", joinFieldErrors(errs))
 	}
 
-	// 应用更新
-	if status, ok := updates["status"].(model.TaskStatus); ok {
-		if err := s.scheduler.stateMachine.Transition(task, status, operator); err != nil {
-			return nil, err
-		}
-		task.Status = status
-
-		// 检查依赖任务的完成状态
-		if status == model.TaskStatusSucceeded {
-			s.checkAndScheduleDependencies(task)
-		}
+	task, err := getTask(s.repo, id)
+	if err != nil {
+		return nil, err
 	}
-
-	if result, ok := updates["output_result"].(map[string]string); ok {
-		task.OutputResult = result
+	if errs := update.CheckStatus(task.Status); len(errs) > 0 {
+		return nil, newError(KindNotEditable, "%s", joinFieldErrors(errs))
 	}
 
-	if errMsg, ok := updates["error_message"].(string); ok {
-		task.ErrorMessage = errMsg
+	update.Apply(task)
+	task.UpdatedAt = s.scheduler.clock.Now()
+	if err := s.repo.UpdateDefinition(task, task.Status); err != nil {
+		return nil, storeError(err)
 	}
 
-	task.UpdatedAt = time.Now()
-
-	if err := s.repo.Update(task); err != nil {
-		return nil, err
+	// 待调度任务的优先级可能变化，重新评估
+	if task.Status == model.TaskStatusPending {
+		s.scheduler.Wake()
 	}
-
 	return task, nil
 }
 
 // CancelTask 取消任务
 func (s *TaskService) CancelTask(ctx context.Context, id, operator string) error {
-	task, err := s.repo.GetByID(id)
+	task, err := getTask(s.repo, id)
 	if err != nil {
 		return err
 	}
-	if task == nil {
-		return fmt.Errorf("task not found: %s", id)
-	}
 
 	if task.IsTerminal() {
-		return fmt.Errorf("cannot cancel terminal task")
+		return newError(KindInvalidTransition, "cannot cancel terminal task %s", id)
 	}
 
 	fromStatus := task.Status
@@ -120,33 +126,120 @@ func (s *TaskService) CancelTask(ctx context.Context, id, operator string) error
 		return err
 	}
 
+	// 运行中的任务先取消执行上下文，等执行器清理完再记录 CANCELLED
+	if fromStatus == model.TaskStatusRunning {
+		s.scheduler.CancelExecution(id)
+	}
+
 	// 保存到数据库
-	return s.repo.UpdateStatusWithEvent(id, fromStatus, model.TaskStatusCancelled, operator, "task cancelled")
+	if err := s.repo.UpdateStatusWithInstanceEvent(id, fromStatus, model.TaskStatusCancelled, operator, "task cancelled", s.scheduler.instanceID, nil); err != nil {
+		return storeError(err)
+	}
+
+	s.scheduler.emitTaskChange(task, fromStatus, model.TaskStatusCancelled)
+	s.checkAndScheduleDependencies(task)
+	return nil
 }
 
 // RetryTask 重试任务
 func (s *TaskService) RetryTask(ctx context.Context, id, operator string) error {
-	task, err := s.repo.GetByID(id)
+	task, err := getTask(s.repo, id)
 	if err != nil {
 		return err
 	}
-	if task == nil {
-		return fmt.Errorf("task not found: %s", id)
-	}
 
 	if !task.CanRetry() {
-		return fmt.Errorf("task cannot be retried")
+		return newError(KindInvalidTransition, "task %s cannot be retried", id)
 	}
 
 	// 重置为 Pending 状态
 	fromStatus := task.Status
-	retryMsg := fmt.Sprintf("retry attempt %d", task.RetryCount+1)
+	retryCount := task.RetryCount
+	retryMsg := fmt.Sprintf("retry attempt %d/%d", retryCount+1, task.MaxAttempts()-1)
 	if err := s.scheduler.stateMachine.Transition(task, model.TaskStatusPending, retryMsg); err != nil {
 		return err
 	}
 
-	// 保存到数据库
-	return s.repo.UpdateStatusWithEvent(id, fromStatus, model.TaskStatusPending, operator, retryMsg)
+	// 重试次数和状态在同一次写入中更新，并发重试时只有一个成功
+	if err := s.repo.ScheduleRetry(id, fromStatus, retryCount, nil, task.ErrorMessage, task.ErrorClass, operator, retryMsg, s.scheduler.instanceID, nil); err != nil {
+		return storeError(err)
+	}
+	task.RetryCount = retryCount + 1
+
+	s.scheduler.emitTaskChange(task, fromStatus, model.TaskStatusPending)
+	s.scheduler.Wake()
+	return nil
+}
+
+// RerunTask 重新运行已结束（成功、取消或超时）的任务，返回将要执行的任务。
+// RerunModeReset 保存本次运行的结果后把原任务重置为 PENDING，重试次数归零；
+// RerunModeClone 按原任务定义创建新任务，RerunOf 指向原任务，原任务保持不变
+func (s *TaskService) RerunTask(ctx context.Context, id string, mode model.RerunMode, operator string) (*model.Task, error) {
+	task, err := getTask(s.repo, id)
+	if err != nil {
+		return nil, err
+	}
+	if !task.CanRerun() {
+		return nil, newError(KindInvalidTransition, "task %s is %s, only SUCCEEDED, CANCELLED or TIMEOUT tasks can be rerun", id, task.Status)
+	}
+
+	switch mode {
+	case model.RerunModeReset:
+		fromStatus := task.Status
+		run, err := s.repo.RerunTask(id, fromStatus, operator, "task rerun", "")
+		if err != nil {
+			return nil, storeError(err)
+		}
+		logger.Infof("Task %s rerun in place, previous run saved as #%d", id, run)
+
+		task, err = getTask(s.repo, id)
+		if err != nil {
+			return nil, err
+		}
+		s.scheduler.emitTaskChange(task, fromStatus, model.TaskStatusPending)
+		s.scheduler.Wake()
+		return task, nil
+
+	case model.RerunModeClone:
+		clone := task.CloneForRerun(operator)
+		if err := s.SubmitTask(ctx, clone); err != nil {
+			return nil, err
+		}
+		return clone, nil
+
+	default:
+		return nil, newError(KindInvalidArgument, "unknown rerun mode %d", mode)
+	}
+}
+
+// SignalTask 向等待输入（WAITING_INPUT）的任务送达输入，返回重新进入 PENDING 的任务。
+// payload 与之前送达的输入按键合并，任务再次执行时执行器通过 ExecutionContext.Input 读取
+func (s *TaskService) SignalTask(ctx context.Context, id string, payload map[string]string, operator string) (*model.Task, error) {
+	task, err := getTask(s.repo, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != model.TaskStatusWaitingInput {
+		return nil, newError(KindInvalidTransition, "task %s is %s, only WAITING_INPUT tasks can be signalled", id, task.Status)
+	}
+	if err := model.ValidateSignalPayload(task.SignalInput, payload); err != nil {
+		return nil, newError(KindInvalidArgument, "%v", err)
+	}
+
+	if err := s.repo.SignalTask(id, payload, operator, "input received", ""); err != nil {
+		return nil, storeError(err)
+	}
+	if task, err = getTask(s.repo, id); err != nil {
+		return nil, err
+	}
+	s.scheduler.emitTaskChange(task, model.TaskStatusWaitingInput, model.TaskStatusPending)
+	s.scheduler.Wake()
+	return task, nil
+}
+
+// SetClock 替换调度器、状态机和任务服务使用的时钟，须在 StartScheduler 之前调用
+func (s *TaskService) SetClock(c clock.Clock) {
+	s.scheduler.SetClock(c)
 }
 
 // StartScheduler 启动调度器
@@ -159,32 +252,45 @@ func (s *TaskService) StopScheduler() {
 	s.scheduler.Stop()
 }
 
+// PauseScheduler 暂停派发新任务，运行中的任务继续执行
+func (s *TaskService) PauseScheduler() error {
+	return s.scheduler.Pause()
+}
+
+// ResumeScheduler 恢复派发任务
+func (s *TaskService) ResumeScheduler() error {
+	return s.scheduler.Resume()
+}
+
+// Scheduler 获取任务调度器
+func (s *TaskService) Scheduler() *Scheduler {
+	return s.scheduler
+}
+
+// RegisterExecutor 注册任务类型的执行器
+func (s *TaskService) RegisterExecutor(taskType string, exec Executor) {
+	s.scheduler.RegisterExecutor(taskType, exec)
+}
+
+// SetNotifier 设置任务状态变更通知器
+func (s *TaskService) SetNotifier(n *notify.Notifier) {
+	s.scheduler.SetNotifier(n)
+}
+
 // GetSchedulerStatus 获取调度器状态
 func (s *TaskService) GetSchedulerStatus() SchedulerStatus {
 	return s.scheduler.GetStatus()
 }
 
-// recordEvent 记录任务事件
-func (s *TaskService) recordEvent(task *model.Task, fromStatus, toStatus model.TaskStatus, message, operator string) {
-	event := &model.TaskEvent{
-		ID:         fmt.Sprintf("%s_%d", task.ID, time.Now().UnixNano()),
-		TaskID:     task.ID,
-		FromStatus: fromStatus,
-		ToStatus:   toStatus,
-		Message:    message,
-		Timestamp:  time.Now(),
-		Operator:   operator,
-	}
-	if err := s.repo.AddEvent(event); err != nil {
-		logger.Errorf("failed to record event: %v", err)
-	}
+// GetSchedulingDecision 说明任务当前为什么没有被调度，见 Scheduler.GetSchedulingDecision
+func (s *TaskService) GetSchedulingDecision(ctx context.Context, id string) (*model.SchedulingDecision, error) {
+	return s.scheduler.GetSchedulingDecision(id)
 }
 
-// checkAndScheduleDependencies 检查并调度依赖任务
+// checkAndScheduleDependencies 任务完成后唤醒调度器评估下游任务
 func (s *TaskService) checkAndScheduleDependencies(completedTask *model.Task) {
-	// 查找所有依赖此任务的任务
-	// 这里需要实现依赖查询逻辑，暂时简化处理
 	logger.Infof("Task %s completed, checking dependencies", completedTask.ID)
+	s.scheduler.Wake()
 }
 
 // ListTasks 列出任务
@@ -202,6 +308,11 @@ func (s *TaskService) GetTaskEvents(ctx context.Context, taskID string) ([]model
 	return s.repo.GetEventsByTaskID(taskID)
 }
 
+// GetTaskLogs 获取任务执行日志（seq 大于 afterSeq 的行）
+func (s *TaskService) GetTaskLogs(ctx context.Context, taskID string, afterSeq int64, limit int) ([]model.TaskLogLine, error) {
+	return s.repo.GetLogs(taskID, afterSeq, limit)
+}
+
 // DependencyChecker 依赖检查器接口
 type DependencyChecker interface {
 	CheckDependencies(taskID string) (bool, error)
@@ -209,42 +320,59 @@ type DependencyChecker interface {
 
 // DefaultDependencyChecker 默认依赖检查器
 type DefaultDependencyChecker struct {
-	repo *repository.TaskRepository
+	repo repository.TaskStore
 }
 
-func NewDefaultDependencyChecker(repo *repository.TaskRepository) *DefaultDependencyChecker {
+func NewDefaultDependencyChecker(repo repository.TaskStore) *DefaultDependencyChecker {
 	return &DefaultDependencyChecker{repo: repo}
 }
 
-// CheckDependencies 检查任务的所有依赖是否都已完成
+// CheckDependencies 检查任务的所有依赖是否都已满足
 func (c *DefaultDependencyChecker) CheckDependencies(taskID string) (bool, error) {
-	task, err := c.repo.GetByID(taskID)
+	task, err := getTask(c.repo, taskID)
 	if err != nil {
 		return false, err
 	}
-	if task == nil {
-		return false, fmt.Errorf("task not found: %s", taskID)
-	}
 
+	ready, _, _, err := c.Evaluate(task)
+	return ready, err
+}
+
+// Evaluate 评估任务的依赖。上游成功、或按 ignore 处理的上游已进入终态时该依赖满足；
+// 按 skip 处理的上游最终未成功时返回该上游任务，表示下游任务已不可能满足依赖；
+// 按 wait 处理的上游最终未成功（如被取消、重试耗尽）时在 stalled 中返回，下游任务要等上游被手动重试
+func (c *DefaultDependencyChecker) Evaluate(task *model.Task) (ready bool, unreachable *model.Task, stalled []model.DependencyState, err error) {
 	// 没有依赖，直接可调度
 	if len(task.Dependencies) == 0 {
-		return true, nil
+		return true, nil, nil, nil
 	}
 
-	// 检查所有依赖任务是否都已完成
-	for _, depID := range task.Dependencies {
-		depTask, err := c.repo.GetByID(depID)
-		if err != nil {
-			return false, err
-		}
+	deps, err := c.repo.GetByIDs(task.Dependencies)
+	if err != nil {
+		return false, nil, nil, err
+	}
+
+	ready = true
+	for i, depTask := range deps {
 		if depTask == nil {
-			return false, fmt.Errorf("dependency task not found: %s", depID)
+			return false, nil, nil, newError(KindNotFound, "dependency task not found: %s", task.Dependencies[i])
 		}
-		// 只有成功完成的任务才能触发下游
-		if depTask.Status != model.TaskStatusSucceeded {
-			return false, nil
+		switch {
+		case depTask.Status == model.TaskStatusSucceeded:
+		case !depTask.Status.IsTerminal():
+			ready = false
+		default:
+			// 上游已结束但未成功，按依赖边配置处理
+			switch policy := task.DependencyPolicy(depTask.ID); policy {
+			case model.DependencyFailureIgnore:
+			case model.DependencyFailureWait:
+				ready = false
+				stalled = append(stalled, model.DependencyState{TaskID: depTask.ID, Status: depTask.Status, Policy: policy})
+			default:
+				return false, depTask, nil, nil
+			}
 		}
 	}
 
-	return true, nil
+	return ready, nil, stalled, nil
 }
@@ -2,15 +2,18 @@ package service
 
 import (
 	"context"
+	"errors"
 	"os"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"taskflow/internal/model"
 	"taskflow/internal/repository"
 )
 
-func setupTestService(t *testing.T) (*TaskService, *repository.TaskRepository, func()) {
+func setupTestService(t testing.TB) (*TaskService, *repository.TaskRepository, func()) {
 	tmpFile, err := os.CreateTemp("", "taskflow_service_test_*.db")
 	if err != nil {
 		t.Fatalf("failed to create temp file: %v", err)
@@ -34,7 +37,10 @@ func setupTestService(t *testing.T) (*TaskService, *repository.TaskRepository, f
 	cleanup := func() {
 		service.StopScheduler()
 		db.Close()
-		os.Remove(tmpFile.Name())
+		// 仍有未关闭的连接时 WAL 文件不会随关闭删除
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(tmpFile.Name() + suffix)
+		}
 	}
 
 	return service, repo, cleanup
@@ -163,8 +169,8 @@ func TestTaskService_GetTask(t *testing.T) {
 
 	// 获取不存在的任务
 	notFound, err := service.GetTask(ctx, "non-existent")
-	if err != nil {
-		t.Fatalf("error should be nil for not found: %v", err)
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, repository.ErrTaskNotFound) {
+		t.Fatalf("expected not found error, got %v", err)
 	}
 	if notFound != nil {
 		t.Error("task should be nil for non-existent ID")
@@ -195,17 +201,41 @@ func TestTaskService_UpdateTask(t *testing.T) {
 		t.Fatalf("failed to create task: %v", err)
 	}
 
-	// 更新任务
-	updates := map[string]interface{}{
-		"status":       model.TaskStatusRunning,
-		"output_result": map[string]string{"result": "success"},
+	// 只更新掩码中的字段
+	update := model.TaskUpdate{
+		Mask:        []string{model.UpdateFieldName, model.UpdateFieldPriority, model.UpdateFieldLabels},
+		Name:        "Renamed",
+		Description: "ignored",
+		Priority:    model.TaskPriorityHigh,
+		Labels:      map[string]string{"env": "prod"},
 	}
-	updated, err := service.UpdateTask(ctx, task.ID, updates, "test-operator")
+	updated, err := service.UpdateTask(ctx, task.ID, update)
 	if err != nil {
 		t.Fatalf("failed to update task: %v", err)
 	}
-	if updated.Status != model.TaskStatusRunning {
-		t.Errorf("expected status RUNNING, got %v", updated.Status)
+	got, _ := service.GetTask(ctx, task.ID)
+	for _, task := range []*model.Task{updated, got} {
+		if task.Name != "Renamed" || task.Description != "original" || task.Priority != model.TaskPriorityHigh || task.Labels["env"] != "prod" {
+			t.Errorf("unexpected task after update: %+v", task)
+		}
+	}
+
+	// 未知字段和非法取值一并报告
+	_, err = service.UpdateTask(ctx, task.ID, model.TaskUpdate{Mask: []string{"status", model.UpdateFieldMaxRetries}, MaxRetries: -1})
+	if !errors.Is(err, ErrInvalidArgument) || !strings.Contains(err.Error(), "status") || !strings.Contains(err.Error(), "max_retries") {
+		t.Errorf("expected invalid argument for unknown field and negative max_retries, got %v", err)
+	}
+
+	// 任务结束后不能再修改参数，名称仍可修改
+	if err := service.CancelTask(ctx, task.ID, "test-operator"); err != nil {
+		t.Fatalf("failed to cancel task: %v", err)
+	}
+	_, err = service.UpdateTask(ctx, task.ID, model.TaskUpdate{Mask: []string{model.UpdateFieldParams}, Params: map[string]string{"k": "v"}})
+	if !errors.Is(err, ErrNotEditable) {
+		t.Errorf("expected not editable updating params of a cancelled task, got %v", err)
+	}
+	if _, err := service.UpdateTask(ctx, task.ID, model.TaskUpdate{Mask: []string{model.UpdateFieldDescription}, Description: "done"}); err != nil {
+		t.Errorf("failed to update description of a cancelled task: %v", err)
 	}
 }
 
@@ -303,6 +333,87 @@ func TestTaskService_RetryTask(t *testing.T) {
 	if updated.Status != model.TaskStatusPending {
 		t.Errorf("expected status PENDING after retry, got %v", updated.Status)
 	}
+	if updated.RetryCount != 1 {
+		t.Errorf("expected retry count 1 after retry, got %d", updated.RetryCount)
+	}
+}
+
+func TestTaskService_RetryTaskHonoursMaxRetries(t *testing.T) {
+	service, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	task := model.NewTask("Retry Limit", "desc", model.TaskPriorityNormal, "test", nil, nil, 2, "testuser")
+	task.Status = model.TaskStatusFailed
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 每次手动重试后任务重新失败，共可重试 MaxRetries 次
+	for i := 0; i < 2; i++ {
+		if err := service.RetryTask(ctx, task.ID, "test-operator"); err != nil {
+			t.Fatalf("retry %d failed: %v", i+1, err)
+		}
+		if err := repo.UpdateStatus(task.ID, model.TaskStatusPending, model.TaskStatusFailed); err != nil {
+			t.Fatalf("failed to fail task: %v", err)
+		}
+	}
+
+	if err := service.RetryTask(ctx, task.ID, "test-operator"); err == nil {
+		t.Error("expected retry to be rejected after MaxRetries")
+	}
+	got, _ := repo.GetByID(task.ID)
+	if got.Status != model.TaskStatusFailed || got.RetryCount != 2 {
+		t.Errorf("expected FAILED with retry count 2, got %s with %d", got.Status, got.RetryCount)
+	}
+}
+
+func TestTaskService_RerunTask(t *testing.T) {
+	service, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	task := model.NewTask("Rerun", "desc", model.TaskPriorityHigh, "test", map[string]string{"k": "v"}, nil, 2, "testuser")
+	task.Status = model.TaskStatusSucceeded
+	task.RetryCount = 2
+	task.OutputResult = map[string]string{"result": "first"}
+	task.Labels = map[string]string{"env": "prod"}
+	if err := repo.Create(task); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	// 克隆：原任务不变，新任务关联原任务
+	clone, err := service.RerunTask(ctx, task.ID, model.RerunModeClone, "alice")
+	if err != nil {
+		t.Fatalf("clone rerun failed: %v", err)
+	}
+	if clone.ID == task.ID || clone.RerunOf != task.ID || clone.Status != model.TaskStatusPending || clone.CreatedBy != "alice" ||
+		clone.InputParams["k"] != "v" || clone.Labels["env"] != "prod" || clone.RetryCount != 0 || clone.OutputResult != nil {
+		t.Errorf("unexpected clone: %+v", clone)
+	}
+	if original, _ := repo.GetByID(task.ID); original.Status != model.TaskStatusSucceeded || original.OutputResult["result"] != "first" {
+		t.Errorf("clone rerun changed the original task: %+v", original)
+	}
+
+	// 原地重置：保存本次运行，重试次数归零
+	reset, err := service.RerunTask(ctx, task.ID, model.RerunModeReset, "alice")
+	if err != nil {
+		t.Fatalf("reset rerun failed: %v", err)
+	}
+	if reset.ID != task.ID || reset.Status != model.TaskStatusPending || reset.RetryCount != 0 || len(reset.OutputResult) != 0 {
+		t.Errorf("unexpected reset task: %+v", reset)
+	}
+	if len(reset.Runs) != 1 || reset.Runs[0].OutputResult["result"] != "first" || reset.Runs[0].RetryCount != 2 {
+		t.Errorf("expected previous run to be saved, got %+v", reset.Runs)
+	}
+
+	// 未结束或失败的任务不能重新运行
+	if _, err := service.RerunTask(ctx, task.ID, model.RerunModeReset, "alice"); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected invalid transition rerunning a pending task, got %v", err)
+	}
+	if _, err := service.RerunTask(ctx, "missing", model.RerunModeReset, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
 }
 
 func TestTaskService_Scheduler(t *testing.T) {
@@ -348,6 +459,60 @@ func TestTaskService_Scheduler(t *testing.T) {
 	_ = task // silence unused warning
 }
 
+func TestScheduler_WakeUpSchedulesDependents(t *testing.T) {
+	service, repo, cleanup := setupTestService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// 兜底轮询足够长，任务只能通过事件唤醒被调度
+	service.Scheduler().SetPollingInterval(time.Hour)
+	service.StartScheduler(ctx)
+
+	upstream, err := service.CreateTask(ctx, "upstream", "", model.TaskPriorityNormal, "test", nil, nil, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create upstream task: %v", err)
+	}
+	downstream, err := service.CreateTask(ctx, "downstream", "", model.TaskPriorityNormal, "test", nil, []string{upstream.ID}, 0, "testuser")
+	if err != nil {
+		t.Fatalf("failed to create downstream task: %v", err)
+	}
+
+	// 上游完成后应唤醒调度器执行下游
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		task, err := repo.GetByID(downstream.ID)
+		if err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+		if task.Status == model.TaskStatusSucceeded {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("downstream task was not scheduled after upstream completed")
+}
+
+func TestScheduler_WakeIsNonBlocking(t *testing.T) {
+	service, _, cleanup := setupTestService(t)
+	defer cleanup()
+
+	// 调度器未启动时多次唤醒不应阻塞
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			service.Scheduler().Wake()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wake blocked")
+	}
+}
+
 func TestTaskService_ListTasks(t *testing.T) {
 	service, _, cleanup := setupTestService(t)
 	defer cleanup()
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// execTimeEWMAAlpha 平均执行时间的指数加权系数
+const execTimeEWMAAlpha = 0.2
+
+// resizableSemaphore 容量可在运行时调整的信号量
+type resizableSemaphore struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int
+	held  int
+}
+
+// newResizableSemaphore 创建信号量
+func newResizableSemaphore(limit int) *resizableSemaphore {
+	s := &resizableSemaphore{limit: limit}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// acquire 获取一个许可，容量不足时阻塞
+func (s *resizableSemaphore) acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.held >= s.limit {
+		s.cond.Wait()
+	}
+	s.held++
+}
+
+// release 释放一个许可
+func (s *resizableSemaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held--
+	s.cond.Broadcast()
+}
+
+// resize 调整容量；缩容时已持有的许可不受影响，释放到新容量以下后才会发放新许可
+func (s *resizableSemaphore) resize(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = limit
+	s.cond.Broadcast()
+}
+
+// counts 返回容量和已持有的许可数
+func (s *resizableSemaphore) counts() (limit, held int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit, s.held
+}
+
+// WorkerPool 工作池，并发数由可调整的信号量控制，调整即时生效且不丢弃排队任务
+type WorkerPool struct {
+	mu      sync.Mutex
+	handler func(taskID string)
+	dequeue func() // 任务出队（队列腾出空位）时回调，须在 Run 之前设置
+	started bool
+	quit    chan struct{} // Stop 时关闭，通知分发循环退出
+	done    chan struct{} // 分发循环退出时关闭
+	sem     *resizableSemaphore
+	tasks   chan string // task IDs，Stop 不关闭，停止后可再次 Run
+	waiting int64       // 已出队、等待许可的任务数（原子操作）
+	wg      sync.WaitGroup
+
+	avgExecTime int64 // 平均执行时间（纳秒，原子操作）
+}
+
+// NewWorkerPool 创建工作池，队列容量为 worker 数的两倍
+func NewWorkerPool(size int) *WorkerPool {
+	return newWorkerPool(size, size*2)
+}
+
+// newWorkerPool 创建指定队列容量的工作池
+func newWorkerPool(size, queueSize int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &WorkerPool{
+		sem:   newResizableSemaphore(size),
+		tasks: make(chan string, queueSize),
+	}
+}
+
+// Run 开始处理任务，已在运行时忽略；Stop 之后可再次调用
+func (wp *WorkerPool) Run(handler func(taskID string)) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if wp.started {
+		return
+	}
+	wp.handler = handler
+	wp.started = true
+	wp.quit = make(chan struct{})
+	wp.done = make(chan struct{})
+	go wp.dispatch(wp.quit, wp.done)
+}
+
+// dispatch 按许可分发队列中的任务；quit 关闭后分发完已排队的任务，等待执行中的任务完成
+func (wp *WorkerPool) dispatch(quit, done chan struct{}) {
+	defer close(done)
+
+	for {
+		select {
+		case taskID := <-wp.tasks:
+			wp.start(taskID)
+		case <-quit:
+			for {
+				select {
+				case taskID := <-wp.tasks:
+					wp.start(taskID)
+				default:
+					wp.wg.Wait()
+					return
+				}
+			}
+		}
+	}
+}
+
+// start 取得许可后在新协程中执行任务
+func (wp *WorkerPool) start(taskID string) {
+	if wp.dequeue != nil {
+		wp.dequeue()
+	}
+	atomic.AddInt64(&wp.waiting, 1)
+	wp.sem.acquire()
+	atomic.AddInt64(&wp.waiting, -1)
+
+	wp.wg.Add(1)
+	go func() {
+		defer wp.wg.Done()
+		defer wp.sem.release()
+		wp.execute(taskID)
+	}()
+}
+
+// execute 执行任务并记录执行时间
+func (wp *WorkerPool) execute(taskID string) {
+	start := time.Now()
+	wp.handler(taskID)
+	wp.observeExecTime(time.Since(start))
+}
+
+// observeExecTime 更新平均执行时间
+func (wp *WorkerPool) observeExecTime(d time.Duration) {
+	for {
+		old := atomic.LoadInt64(&wp.avgExecTime)
+		next := int64(d)
+		if old != 0 {
+			next = int64(execTimeEWMAAlpha*float64(d) + (1-execTimeEWMAAlpha)*float64(old))
+		}
+		if atomic.CompareAndSwapInt64(&wp.avgExecTime, old, next) {
+			return
+		}
+	}
+}
+
+// Resize 调整 worker 数量，立即生效；缩容时执行中的任务继续完成，队列中的任务不受影响
+func (wp *WorkerPool) Resize(size int) error {
+	if size < 1 {
+		return fmt.Errorf("worker count must be positive, got %d", size)
+	}
+	wp.sem.resize(size)
+	return nil
+}
+
+// Submit 提交任务
+func (wp *WorkerPool) Submit(taskID string) bool {
+	select {
+	case wp.tasks <- taskID:
+		return true
+	default:
+		return false
+	}
+}
+
+// Saturated 队列是否已满，已满时 Submit 会失败
+func (wp *WorkerPool) Saturated() bool {
+	return len(wp.tasks) >= cap(wp.tasks)
+}
+
+// Size 当前 worker 数量
+func (wp *WorkerPool) Size() int {
+	limit, _ := wp.sem.counts()
+	return limit
+}
+
+// Busy 正在执行任务的 worker 数量
+func (wp *WorkerPool) Busy() int {
+	_, held := wp.sem.counts()
+	return held
+}
+
+// QueueDepth 排队等待执行的任务数
+func (wp *WorkerPool) QueueDepth() int {
+	return len(wp.tasks) + int(atomic.LoadInt64(&wp.waiting))
+}
+
+// AvgExecTime 平均执行时间（指数加权）
+func (wp *WorkerPool) AvgExecTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&wp.avgExecTime))
+}
+
+// Stop 停止工作池，已排队的任务执行完后返回；未在运行时直接返回
+func (wp *WorkerPool) Stop() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if !wp.started {
+		return
+	}
+	wp.started = false
+	close(wp.quit)
+	<-wp.done
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkerPool_ResizeLive(t *testing.T) {
+	release := make(chan struct{})
+	pool := newWorkerPool(1, 10)
+	pool.Run(func(taskID string) { <-release })
+
+	for _, id := range []string{"t1", "t2", "t3", "t4"} {
+		if !pool.Submit(id) {
+			t.Fatalf("submit %s failed", id)
+		}
+	}
+	waitFor(t, func() bool { return pool.Busy() == 1 && pool.QueueDepth() == 3 })
+
+	// 扩容后排队任务立即开始执行，无需重建工作池
+	if err := pool.Resize(3); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	waitFor(t, func() bool { return pool.Busy() == 3 && pool.QueueDepth() == 1 })
+
+	// 缩容不中断执行中的任务，也不丢弃队列
+	if err := pool.Resize(1); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if pool.Size() != 1 || pool.Busy() != 3 || pool.QueueDepth() != 1 {
+		t.Errorf("unexpected pool state after shrink: size=%d busy=%d queue=%d", pool.Size(), pool.Busy(), pool.QueueDepth())
+	}
+
+	if err := pool.Resize(0); err == nil {
+		t.Error("expected error for non-positive size")
+	}
+
+	// 释放后所有任务（包括缩容前排队的）都执行完毕
+	close(release)
+	pool.Stop()
+	if pool.Busy() != 0 || pool.QueueDepth() != 0 {
+		t.Errorf("expected drained pool, got busy=%d queue=%d", pool.Busy(), pool.QueueDepth())
+	}
+}
+
+func TestWorkerPool_ShrinkLimitsConcurrency(t *testing.T) {
+	started := make(chan string, 10)
+	release := make(chan struct{})
+	pool := newWorkerPool(2, 10)
+	pool.Run(func(taskID string) {
+		started <- taskID
+		<-release
+	})
+	defer pool.Stop()
+
+	pool.Submit("t1")
+	pool.Submit("t2")
+	waitFor(t, func() bool { return pool.Busy() == 2 })
+
+	if err := pool.Resize(1); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	pool.Submit("t3")
+	pool.Submit("t4")
+
+	// 两个执行中的任务结束后，新容量为 1，只能再启动一个
+	release <- struct{}{}
+	release <- struct{}{}
+	<-started
+	<-started
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued task not started after shrink")
+	}
+	select {
+	case id := <-started:
+		t.Fatalf("task %s started beyond resized capacity", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+}
+
+func TestScheduler_SetWorkerCountWhileStopped(t *testing.T) {
+	svc, _, cleanup := setupTestService(t)
+	defer cleanup()
+
+	s := svc.Scheduler()
+	s.SetWorkerCount(4)
+	if size, _, _, _ := s.WorkerPoolStats(); size != 4 {
+		t.Errorf("expected worker count 4, got %d", size)
+	}
+
+	// 自动伸缩时拒绝手动调整
+	if err := s.SetAutoscale(AutoscaleConfig{MinWorkers: 1, MaxWorkers: 2}); err != nil {
+		t.Fatalf("SetAutoscale failed: %v", err)
+	}
+	if err := s.ResizeWorkerPool(5); err == nil {
+		t.Error("expected error resizing an autoscaled pool")
+	}
+}
+
+func TestWorkerPool_RestartAfterStop(t *testing.T) {
+	executed := make(chan string, 10)
+	pool := newWorkerPool(1, 10)
+	pool.Run(func(taskID string) { executed <- taskID })
+
+	pool.Submit("t1")
+	pool.Stop()
+	pool.Stop() // 重复停止直接返回
+	if len(executed) != 1 {
+		t.Fatalf("expected queued task to run before Stop returned, got %d", len(executed))
+	}
+	<-executed
+
+	// 停止后再次 Run，队列仍可使用
+	pool.Run(func(taskID string) { executed <- taskID })
+	defer pool.Stop()
+	if !pool.Submit("t2") {
+		t.Fatal("submit after restart failed")
+	}
+	select {
+	case id := <-executed:
+		if id != "t2" {
+			t.Fatalf("unexpected task %s", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("task not executed after restart")
+	}
+}
@@ -0,0 +1,131 @@
+// Package sla SLA 监控：定期检查声明了截止时间的任务，对超过截止时间仍未成功完成的任务
+// （包括仍在运行的任务）记录违约，并发出任务事件、发件箱事件、通知和指标
+package sla
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+	"taskflow/internal/notify"
+	"taskflow/internal/repository"
+)
+
+// Operator 违约事件的操作者
+const Operator = "sla-monitor"
+
+// initialLookback 启动后首次检查时回看的时长：服务停止期间在截止时间之后才结束的任务也会补记违约
+const initialLookback = 24 * time.Hour
+
+// Options 监控参数
+type Options struct {
+	CheckInterval time.Duration     // 检查间隔，默认 30 秒
+	BatchSize     int               // 每轮最多检查的任务数，默认 500
+	InstanceID    string            // 记录在违约事件中的实例 ID
+	OnBreach      func(*model.Task) // 记录违约后调用，如推送给 WatchTask 订阅者
+	Clock         clock.Clock       // 判断违约和检查间隔的时钟，默认 clock.Real
+}
+
+// Monitor SLA 监控。违约只记录一次：多个实例同时检查时由仓储的条件更新保证只有一个实例发出事件和通知
+type Monitor struct {
+	repo     repository.TaskStore
+	notifier *notify.Notifier
+	opts     Options
+
+	lastCheck time.Time
+}
+
+// NewMonitor 创建监控，notifier 可以为 nil
+func NewMonitor(repo repository.TaskStore, notifier *notify.Notifier, opts Options) *Monitor {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = 30 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+	return &Monitor{repo: repo, notifier: notifier, opts: opts}
+}
+
+// Run 定期检查，直到 ctx 取消
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := m.opts.Clock.NewTicker(m.opts.CheckInterval)
+	defer ticker.Stop()
+
+	logger.Infof("SLA monitor started, checking every %s", m.opts.CheckInterval)
+	for {
+		m.Check()
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("SLA monitor stopped")
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Check 检查一轮，返回本轮新记录的违约任务数
+func (m *Monitor) Check() int {
+	now := m.opts.Clock.Now()
+	since := now.Add(-initialLookback)
+	if !m.lastCheck.IsZero() {
+		// 回看两个检查间隔，覆盖上一轮检查后才结束的任务
+		since = m.lastCheck.Add(-2 * m.opts.CheckInterval)
+	}
+
+	tasks, err := m.repo.ListSLABreachCandidates(now, since, m.opts.BatchSize)
+	if err != nil {
+		logger.Errorf("Failed to list SLA breach candidates: %v", err)
+		return 0
+	}
+	m.lastCheck = now
+
+	breached := 0
+	for _, task := range tasks {
+		if !task.SLABreached(now) {
+			continue
+		}
+		if err := m.repo.MarkSLABreached(task.ID, now, Operator, breachMessage(task, now), m.opts.InstanceID); err != nil {
+			if !errors.Is(err, repository.ErrSLAAlreadyMarked) {
+				logger.Errorf("Failed to record SLA breach of task %s: %v", task.ID, err)
+			}
+			continue
+		}
+		breachedAt := now
+		task.SLABreachedAt = &breachedAt
+		breached++
+
+		logger.Warnf("Task %s (%s) breached its SLA: %s", task.ID, task.TaskType, breachMessage(task, now))
+		metrics.RecordSLABreach(task.TaskType, task.Status.String())
+		m.notifier.NotifySLABreach(task, now)
+		if m.opts.OnBreach != nil {
+			m.opts.OnBreach(task)
+		}
+	}
+	return breached
+}
+
+// breachMessage 违约事件说明
+func breachMessage(task *model.Task, now time.Time) string {
+	deadline := task.SLADeadline.Format(time.RFC3339)
+	switch {
+	case !task.IsTerminal():
+		return fmt.Sprintf("SLA deadline %s exceeded by %s while %s", deadline, now.Sub(*task.SLADeadline).Round(time.Second), task.Status)
+	case task.Status != model.TaskStatusSucceeded:
+		return fmt.Sprintf("task ended %s and missed SLA deadline %s", task.Status, deadline)
+	default:
+		end := task.UpdatedAt
+		if task.CompletedAt != nil {
+			end = *task.CompletedAt
+		}
+		return fmt.Sprintf("task succeeded %s after SLA deadline %s", end.Sub(*task.SLADeadline).Round(time.Second), deadline)
+	}
+}
@@ -0,0 +1,87 @@
+package sla
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+func TestMonitor_Check(t *testing.T) {
+	repo, _ := repository.NewMemoryRepositories()
+	now := time.Now()
+
+	add := func(id string, status model.TaskStatus, deadline time.Duration, completed *time.Duration) {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "report", nil, nil, 0, "alice")
+		task.ID = id
+		d := now.Add(deadline)
+		task.SLADeadline = &d
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		task.Status = status
+		if completed != nil {
+			c := now.Add(*completed)
+			task.CompletedAt = &c
+		}
+		if err := repo.Update(task); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+	at := func(d time.Duration) *time.Duration { return &d }
+
+	add("running-late", model.TaskStatusRunning, -time.Minute, nil)
+	add("pending-late", model.TaskStatusPending, -time.Second, nil)
+	add("running-in-time", model.TaskStatusRunning, time.Hour, nil)
+	add("succeeded-in-time", model.TaskStatusSucceeded, -time.Minute, at(-2*time.Minute))
+	add("succeeded-late", model.TaskStatusSucceeded, -time.Minute, at(-30*time.Second))
+	add("failed", model.TaskStatusFailed, -time.Minute, at(-2*time.Minute))
+
+	var notified []string
+	fake := clock.NewFake(now)
+	m := NewMonitor(repo, nil, Options{InstanceID: "inst-1", Clock: fake, OnBreach: func(task *model.Task) {
+		notified = append(notified, task.ID)
+	}})
+
+	if n := m.Check(); n != 4 {
+		t.Fatalf("expected 4 breaches, got %d", n)
+	}
+	sort.Strings(notified)
+	want := []string{"failed", "pending-late", "running-late", "succeeded-late"}
+	for i := range want {
+		if i >= len(notified) || notified[i] != want[i] {
+			t.Fatalf("breached tasks = %v, want %v", notified, want)
+		}
+	}
+
+	task, _ := repo.GetByID("running-late")
+	if task.SLABreachedAt == nil || len(task.Events) != 2 || task.Events[1].Operator != Operator || task.Events[1].InstanceID != "inst-1" {
+		t.Errorf("expected breach to be recorded with an event, got %+v", task)
+	}
+	if got, _ := repo.GetByID("succeeded-in-time"); got.SLABreachedAt != nil {
+		t.Error("task finished in time should not be marked")
+	}
+
+	events, _ := repo.ListDueOutboxEvents(now.Add(time.Second), 10)
+	slaEvents := 0
+	for _, e := range events {
+		if e.EventType == model.OutboxEventTaskSLABreached {
+			slaEvents++
+		}
+	}
+	if slaEvents != 4 {
+		t.Errorf("expected 4 sla outbox events, got %d", slaEvents)
+	}
+
+	// 违约只记录一次；之后超过截止时间的任务在下一轮记录
+	fake.Advance(2 * time.Hour)
+	if n := m.Check(); n != 1 {
+		t.Errorf("expected only running-in-time to breach on the next check, got %d", n)
+	}
+	if n := m.Check(); n != 0 {
+		t.Errorf("expected no new breaches, got %d", n)
+	}
+}
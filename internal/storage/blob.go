@@ -0,0 +1,99 @@
+// Package storage 提供二进制对象（附件、产物等）的可插拔存储后端
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"taskflow/internal/config"
+)
+
+// 存储后端类型
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+	BackendGCS   = "gcs"
+)
+
+// gcsEndpoint Google Cloud Storage 的 S3 兼容（XML API）地址，使用 HMAC 密钥签名
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore 对象存储接口，key 为以 / 分隔的相对路径
+type BlobStore interface {
+	// Put 写入对象，size 为 -1 表示未知长度
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，对象不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// cleanKey 规范化对象 key，拒绝空 key 和越出根目录的路径
+func cleanKey(key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("blob key is empty")
+	}
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != strings.TrimPrefix(key, "/") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return cleaned, nil
+}
+
+// NewBlobStore 按配置创建存储后端，backend 为空或 none 时返回 nil 表示禁用
+func NewBlobStore(cfg config.BlobStoreConfig) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", "none":
+		return nil, nil
+	case BackendLocal:
+		return NewLocalStore(expandHome(cfg.Dir))
+	case BackendS3:
+		return NewS3Store(s3Options(cfg.S3), nil)
+	case BackendGCS:
+		opts := s3Options(cfg.S3)
+		if opts.Endpoint == "" {
+			opts.Endpoint = gcsEndpoint
+		}
+		if opts.Region == "" {
+			opts.Region = "auto"
+		}
+		opts.UsePathStyle = true
+		return NewS3Store(opts, nil)
+	default:
+		return nil, fmt.Errorf("unknown blob backend %q", cfg.Backend)
+	}
+}
+
+// s3Options 转换 S3 配置
+func s3Options(cfg config.S3Config) S3Options {
+	return S3Options{
+		Endpoint:        cfg.Endpoint,
+		Region:          cfg.Region,
+		Bucket:          cfg.Bucket,
+		Prefix:          cfg.Prefix,
+		AccessKeyID:     cfg.AccessKeyID,
+		SecretAccessKey: cfg.SecretAccessKey,
+		UsePathStyle:    cfg.UsePathStyle,
+	}
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(p string) string {
+	if !strings.HasPrefix(p, "~") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
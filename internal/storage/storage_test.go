@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"taskflow/internal/config"
+)
+
+func TestLocalStore_RoundTrip(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "tasks/t1/a1", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	rc, err := store.Get(ctx, "tasks/t1/a1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello" {
+		t.Errorf("expected hello, got %q", data)
+	}
+
+	if err := store.Delete(ctx, "tasks/t1/a1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(ctx, "tasks/t1/a1"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	// 重复删除不报错
+	if err := store.Delete(ctx, "tasks/t1/a1"); err != nil {
+		t.Errorf("expected no error deleting missing blob, got %v", err)
+	}
+}
+
+func TestLocalStore_RejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	for _, key := range []string{"", "../etc/passwd", "a/../../b", "a//b"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("expected error for key %q", key)
+		}
+	}
+}
+
+// fakeS3 内存版 S3 服务，记录收到的请求头
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string
+	headers []http.Header
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.headers = append(f.headers, r.Header.Clone())
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = string(data)
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store_RoundTrip(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string]string)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	store, err := NewS3Store(S3Options{
+		Endpoint:        srv.URL,
+		Region:          "eu-west-1",
+		Bucket:          "artifacts",
+		Prefix:          "prod/",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		UsePathStyle:    true,
+	}, srv.Client())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	store.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "tasks/t1/report.json", strings.NewReader(`{"ok":true}`), 11, "application/json"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	// path-style：/bucket/prefix/key
+	if _, ok := fake.objects["/artifacts/prod/tasks/t1/report.json"]; !ok {
+		t.Fatalf("object stored at unexpected path: %v", fake.objects)
+	}
+
+	rc, err := store.Get(ctx, "tasks/t1/report.json")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != `{"ok":true}` {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	// 请求已签名
+	auth := fake.headers[0].Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240102/eu-west-1/s3/aws4_request") {
+		t.Errorf("unexpected Authorization header %q", auth)
+	}
+	if !strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date") {
+		t.Errorf("unexpected signed headers in %q", auth)
+	}
+	if got := fake.headers[0].Get("X-Amz-Date"); got != "20240102T030405Z" {
+		t.Errorf("unexpected X-Amz-Date %q", got)
+	}
+
+	if err := store.Delete(ctx, "tasks/t1/report.json"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(ctx, "tasks/t1/report.json"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestAWSURIEscape(t *testing.T) {
+	if got := awsURIEscape("/a b/c+d~e", false); got != "/a%20b/c%2Bd~e" {
+		t.Errorf("unexpected path escape %q", got)
+	}
+	if got := awsURIEscape("a/b", true); got != "a%2Fb" {
+		t.Errorf("unexpected query escape %q", got)
+	}
+}
+
+func TestMemoryStore_RoundTrip(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "tasks/t2/output.json", strings.NewReader("b"), 1, "application/json"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Put(ctx, "tasks/t1/output.json", strings.NewReader("a"), 1, "application/json"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if keys := store.Keys(); len(keys) != 2 || keys[0] != "tasks/t1/output.json" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	rc, err := store.Get(ctx, "tasks/t1/output.json")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "a" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	if err := store.Delete(ctx, "tasks/t1/output.json"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(ctx, "tasks/t1/output.json"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Put(ctx, "../escape", strings.NewReader("x"), 1, ""); err == nil {
+		t.Error("expected escaping key to be rejected")
+	}
+}
+
+func TestNewBlobStore_Backends(t *testing.T) {
+	if store, err := NewBlobStore(config.BlobStoreConfig{Backend: "none"}); err != nil || store != nil {
+		t.Errorf("expected disabled store, got %v, %v", store, err)
+	}
+	if _, err := NewBlobStore(config.BlobStoreConfig{Backend: "ftp"}); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+
+	// gcs 使用 S3 兼容接口，默认 endpoint 和 path-style 寻址
+	store, err := NewBlobStore(config.BlobStoreConfig{Backend: BackendGCS, S3: config.S3Config{Bucket: "artifacts"}})
+	if err != nil {
+		t.Fatalf("failed to create gcs store: %v", err)
+	}
+	gcs := store.(*S3Store)
+	if gcs.opts.Endpoint != gcsEndpoint || gcs.opts.Region != "auto" || !gcs.opts.UsePathStyle {
+		t.Errorf("unexpected gcs options: %+v", gcs.opts)
+	}
+
+	local, err := NewBlobStore(config.BlobStoreConfig{Backend: BackendLocal, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create local store: %v", err)
+	}
+	if _, ok := local.(*LocalStore); !ok {
+		t.Errorf("expected *LocalStore, got %T", local)
+	}
+}
@@ -0,0 +1,137 @@
+// Package workflow 工作流截止时间监控：工作流为同一请求创建的任务（correlation_id 相同），以及依赖其中任务的下游任务
+// 和执行中提交的子任务（二者创建时继承截止时间）。截止时间已过时取消工作流中全部未结束的任务，并发出任务事件、通知和指标
+package workflow
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/logger"
+	"taskflow/internal/metrics"
+	"taskflow/internal/model"
+	"taskflow/internal/notify"
+	"taskflow/internal/repository"
+)
+
+// Operator 工作流超时取消事件的操作者
+const Operator = "workflow-monitor"
+
+// Options 监控参数
+type Options struct {
+	CheckInterval time.Duration // 检查间隔，默认 30 秒
+	BatchSize     int           // 每轮最多处理的超时任务数，以及每个工作流最多取消的其他任务数，默认 500
+	InstanceID    string        // 记录在取消事件中的实例 ID
+	// CancelExecution 取消在本进程中运行的任务的执行并等待执行器清理，为 nil 时由执行任务的调度器实例在心跳时停止执行器
+	CancelExecution func(taskID string) bool
+	// OnCancel 取消任务后调用，如推送给 WatchTask 订阅者
+	OnCancel func(task *model.Task, fromStatus, toStatus model.TaskStatus)
+	Clock    clock.Clock // 判断截止时间和检查间隔的时钟，默认 clock.Real
+}
+
+// Monitor 工作流截止时间监控。任务只取消一次：多个实例同时检查时由仓储的条件状态更新保证只有一个实例发出事件和通知
+type Monitor struct {
+	repo     repository.TaskStore
+	notifier *notify.Notifier
+	opts     Options
+}
+
+// NewMonitor 创建监控，notifier 可以为 nil
+func NewMonitor(repo repository.TaskStore, notifier *notify.Notifier, opts Options) *Monitor {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = 30 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+	return &Monitor{repo: repo, notifier: notifier, opts: opts}
+}
+
+// Run 定期检查，直到 ctx 取消
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := m.opts.Clock.NewTicker(m.opts.CheckInterval)
+	defer ticker.Stop()
+
+	logger.Infof("Workflow deadline monitor started, checking every %s", m.opts.CheckInterval)
+	for {
+		m.Check()
+
+		select {
+		case <-ctx.Done():
+			logger.Infof("Workflow deadline monitor stopped")
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Check 检查一轮，返回本轮取消的任务数
+func (m *Monitor) Check() int {
+	now := m.opts.Clock.Now()
+	expired, err := m.repo.ListWorkflowDeadlineExceeded(now, m.opts.BatchSize)
+	if err != nil {
+		logger.Errorf("Failed to list tasks past their workflow deadline: %v", err)
+		return 0
+	}
+
+	cancelled := 0
+	workflows := make(map[string]bool)
+	for _, task := range expired {
+		message := model.WorkflowDeadlineMessage(*task.WorkflowDeadline)
+		if m.cancel(task, message) {
+			cancelled++
+		}
+
+		// 同一请求创建的其他任务属于同一工作流，没有声明截止时间的也一并取消
+		if task.CorrelationID == "" || workflows[task.CorrelationID] {
+			continue
+		}
+		workflows[task.CorrelationID] = true
+		members, _, err := m.repo.ListByFilter(repository.TaskFilter{
+			CorrelationID: task.CorrelationID,
+			Statuses:      model.WorkflowActiveStatuses,
+			SortBy:        repository.SortByCreatedAt,
+			PageSize:      m.opts.BatchSize,
+		})
+		if err != nil {
+			logger.Errorf("Failed to list tasks of workflow %s: %v", task.CorrelationID, err)
+			continue
+		}
+		for _, member := range members {
+			if m.cancel(member, message) {
+				cancelled++
+			}
+		}
+	}
+	return cancelled
+}
+
+// cancel 取消工作流中的一个任务，运行中的任务先取消执行。任务已结束或状态被并发修改时返回 false
+func (m *Monitor) cancel(task *model.Task, message string) bool {
+	from := task.Status
+	if from == model.TaskStatusRunning && m.opts.CancelExecution != nil {
+		m.opts.CancelExecution(task.ID)
+	}
+	err := m.repo.UpdateStatusWithInstanceEvent(task.ID, from, model.TaskStatusCancelled, Operator, message, m.opts.InstanceID, nil)
+	if err != nil {
+		if !errors.Is(err, repository.ErrStatusMismatch) {
+			logger.Errorf("Failed to cancel task %s of an expired workflow: %v", task.ID, err)
+		}
+		return false
+	}
+	task.Status = model.TaskStatusCancelled
+	task.UpdatedAt = m.opts.Clock.Now()
+	task.Version++
+
+	logger.Warnf("Task %s (%s) cancelled: %s", task.ID, task.TaskType, message)
+	metrics.RecordWorkflowDeadlineCancelled(task.TaskType)
+	m.notifier.NotifyTaskChange(task, from, model.TaskStatusCancelled)
+	if m.opts.OnCancel != nil {
+		m.opts.OnCancel(task, from, model.TaskStatusCancelled)
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package workflow
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"taskflow/internal/clock"
+	"taskflow/internal/model"
+	"taskflow/internal/repository"
+)
+
+func TestMonitor_Check(t *testing.T) {
+	repo, _ := repository.NewMemoryRepositories()
+	now := time.Now().UTC().Truncate(time.Second)
+	deadline := now.Add(time.Hour)
+
+	// etl 工作流由请求 req-1 创建，只有入口任务声明了截止时间；report 由另一个请求创建，依赖 etl 中的任务并继承截止时间
+	add := func(id, correlationID string, status model.TaskStatus, workflowDeadline *time.Time) {
+		task := model.NewTask(id, "", model.TaskPriorityNormal, "etl", nil, nil, 0, "alice")
+		task.ID = id
+		task.Status = status
+		task.CorrelationID = correlationID
+		task.WorkflowDeadline = workflowDeadline
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	add("extract", "req-1", model.TaskStatusRunning, &deadline)
+	add("transform", "req-1", model.TaskStatusPending, nil)
+	add("approve", "req-1", model.TaskStatusWaitingInput, nil)
+	add("cleanup", "req-1", model.TaskStatusSucceeded, nil)
+	add("report", "req-2", model.TaskStatusPending, &deadline)
+	add("unrelated", "req-3", model.TaskStatusPending, nil)
+
+	fake := clock.NewFake(now)
+	var cancelledExecutions []string
+	var published []string
+	m := NewMonitor(repo, nil, Options{
+		InstanceID: "inst-1",
+		Clock:      fake,
+		CancelExecution: func(taskID string) bool {
+			cancelledExecutions = append(cancelledExecutions, taskID)
+			return true
+		},
+		OnCancel: func(task *model.Task, from, to model.TaskStatus) {
+			if to != model.TaskStatusCancelled || task.Status != model.TaskStatusCancelled {
+				t.Errorf("unexpected change of %s: %s -> %s", task.ID, from, to)
+			}
+			published = append(published, task.ID)
+		},
+	})
+
+	if n := m.Check(); n != 0 {
+		t.Fatalf("expected nothing cancelled before the deadline, got %d", n)
+	}
+
+	// 截止时间过后取消工作流中全部未结束的任务，运行中的任务先取消执行
+	fake.Advance(time.Hour)
+	if n := m.Check(); n != 4 {
+		t.Fatalf("expected 4 tasks cancelled, got %d", n)
+	}
+	slices.Sort(published)
+	if !slices.Equal(published, []string{"approve", "extract", "report", "transform"}) {
+		t.Errorf("published = %v", published)
+	}
+	if !slices.Equal(cancelledExecutions, []string{"extract"}) {
+		t.Errorf("expected only the running task's execution cancelled, got %v", cancelledExecutions)
+	}
+	for id, want := range map[string]model.TaskStatus{
+		"extract":   model.TaskStatusCancelled,
+		"transform": model.TaskStatusCancelled,
+		"approve":   model.TaskStatusCancelled,
+		"report":    model.TaskStatusCancelled,
+		"cleanup":   model.TaskStatusSucceeded,
+		"unrelated": model.TaskStatusPending,
+	} {
+		if task, _ := repo.GetByID(id); task.Status != want {
+			t.Errorf("%s: status = %s, want %s", id, task.Status, want)
+		}
+	}
+
+	// 每个任务的取消记录为事件，说明超过的截止时间
+	task, _ := repo.GetByID("transform")
+	last := task.Events[len(task.Events)-1]
+	if last.Operator != Operator || last.InstanceID != "inst-1" || last.ToStatus != model.TaskStatusCancelled ||
+		last.Message != "workflow deadline "+deadline.Format(time.RFC3339)+" exceeded" {
+		t.Errorf("unexpected cancel event: %+v", last)
+	}
+	if n := m.Check(); n != 0 {
+		t.Errorf("expected each task to be cancelled once, got %d", n)
+	}
+}
@@ -0,0 +1,12 @@
+// Package e2e 端到端测试：以子进程启动完整的 taskflow 服务（gRPC + HTTP），
+// 在测试进程中用嵌入式引擎作为工作进程，与服务共享同一个临时 SQLite 数据库，
+// 通过 gRPC 客户端驱动 创建 → 调度 → 执行 → 监听 → 重试 → 取消 的完整流程，并断言事件流。
+//
+// 测试代码带 e2e 构建标签，默认的 go test ./... 不运行：
+//
+//	go test -tags e2e ./test/e2e                 # 编译并启动本地服务
+//	make test-e2e-docker                         # 对 docker compose 启动的服务运行
+//
+// 设置 TASKFLOW_E2E_GRPC_ADDR、TASKFLOW_E2E_HTTP_ADDR 和 TASKFLOW_E2E_DB_PATH 时不启动本地服务，
+// 改为连接已运行的服务（如容器），TASKFLOW_E2E_DB_PATH 须是该服务挂载出来的数据库文件。
+package e2e
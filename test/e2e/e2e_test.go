@@ -0,0 +1,182 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	pb "taskflow/proto"
+)
+
+const (
+	pending   = pb.TaskStatus_TASK_STATUS_PENDING
+	running   = pb.TaskStatus_TASK_STATUS_RUNNING
+	succeeded = pb.TaskStatus_TASK_STATUS_SUCCEEDED
+	cancelled = pb.TaskStatus_TASK_STATUS_CANCELLED
+)
+
+// createBlocked 创建依赖一个 e2e-gated 上游的任务，任务在返回的 start 调用前保持 PENDING，便于先订阅再调度
+func createBlocked(t *testing.T, req *pb.CreateTaskRequest) (task *pb.Task, start func()) {
+	t.Helper()
+
+	upstream := stack.createTask(t, &pb.CreateTaskRequest{Name: req.Name + "-upstream", TaskType: taskTypeGated})
+	req.Dependencies = append(req.Dependencies, upstream.Id)
+	task = stack.createTask(t, req)
+	if task.Status != pending {
+		t.Fatalf("Expected PENDING on create, got %s", task.Status)
+	}
+	return task, func() { stack.executor.release(upstream.Id) }
+}
+
+// getJSON 请求 HTTP API 并解码响应
+func getJSON(t *testing.T, path string, out any) {
+	t.Helper()
+
+	resp, err := http.Get(stack.httpBase + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s returned %d: %s", path, resp.StatusCode, body)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		t.Fatalf("GET %s returned invalid JSON: %v\n%s", path, err, body)
+	}
+}
+
+// TestE2E_ScheduleExecuteWatch 上游成功后下游被工作进程调度执行，WatchTask 推送每次状态变更，HTTP API 返回事件和日志
+func TestE2E_ScheduleExecuteWatch(t *testing.T) {
+	task, start := createBlocked(t, &pb.CreateTaskRequest{
+		Name:     "scheduled",
+		TaskType: taskTypeEcho,
+		Priority: pb.TaskPriority_TASK_PRIORITY_HIGH,
+	})
+	w, initial := stack.watch(t, task.Id)
+	if initial.ToStatus != pending || initial.ResumeToken <= 0 {
+		t.Fatalf("Expected a PENDING initial event with a resume token, got %v", initial)
+	}
+
+	start()
+	events := w.expect(t, transition{pending, running}, transition{running, succeeded})
+	if events[0].ResumeToken <= initial.ResumeToken {
+		t.Errorf("Expected resume tokens after the initial one, got %d after %d", events[0].ResumeToken, initial.ResumeToken)
+	}
+	if done := events[1].Task; done.GetStatus() != succeeded || done.OutputResult["attempt"] != "1" {
+		t.Errorf("Expected the SUCCEEDED snapshot with output from the worker, got %v", done)
+	}
+	if n := stack.executor.Attempts(task.Id); n != 1 {
+		t.Errorf("Expected 1 attempt, got %d", n)
+	}
+
+	var history struct {
+		Events []struct {
+			FromStatus int32 `json:"from_status"`
+			ToStatus   int32 `json:"to_status"`
+		} `json:"events"`
+	}
+	getJSON(t, "/api/v1/tasks/"+task.Id+"/events", &history)
+	var trail []transition
+	for _, event := range history.Events {
+		trail = append(trail, transition{pb.TaskStatus(event.FromStatus), pb.TaskStatus(event.ToStatus)})
+	}
+	if want := []transition{{0, pending}, {pending, running}, {running, succeeded}}; fmt.Sprint(trail) != fmt.Sprint(want) {
+		t.Errorf("Expected the persisted event trail %v, got %v", want, trail)
+	}
+
+	var logs struct {
+		Lines []struct {
+			Line string `json:"line"`
+		} `json:"lines"`
+	}
+	getJSON(t, "/api/v1/tasks/"+task.Id+"/logs", &logs)
+	var found bool
+	for _, line := range logs.Lines {
+		found = found || line.Line == "attempt 1"
+	}
+	if !found {
+		t.Errorf("Expected the worker's log line via the server, got %+v", logs.Lines)
+	}
+}
+
+// TestE2E_Retry 执行失败后按重试策略直接回到 PENDING 重新调度，第二次执行成功
+func TestE2E_Retry(t *testing.T) {
+	task, start := createBlocked(t, &pb.CreateTaskRequest{
+		Name:        "flaky",
+		TaskType:    taskTypeEcho,
+		InputParams: map[string]string{"fail_times": "1"},
+		MaxRetries:  1,
+		RetryPolicy: &pb.RetryPolicy{Backoff: "none"},
+	})
+	w, _ := stack.watch(t, task.Id)
+
+	start()
+	events := w.expect(t,
+		transition{pending, running},
+		transition{running, pending},
+		transition{pending, running},
+		transition{running, succeeded},
+	)
+	if done := events[3].Task; done.GetStatus() != succeeded || done.OutputResult["attempt"] != "2" || done.RetryCount != 1 {
+		t.Errorf("Expected success on attempt 2 after 1 retry, got %v", done)
+	}
+
+	resp, err := stack.client.ListTaskEvents(context.Background(), &pb.ListTaskEventsRequest{TaskId: task.Id})
+	if err != nil {
+		t.Fatalf("ListTaskEvents failed: %v", err)
+	}
+	var retried bool
+	for _, event := range resp.Events {
+		retried = retried || event.ToStatus == pending && strings.Contains(event.Message, "attempt 1 failed")
+	}
+	if !retried {
+		t.Errorf("Expected the retry event to record the failure, got %v", resp.Events)
+	}
+	if n := stack.executor.Attempts(task.Id); n != 2 {
+		t.Errorf("Expected 2 attempts, got %d", n)
+	}
+}
+
+// TestE2E_CancelAndRerun 通过 API 取消运行中的任务，工作进程中的执行器随之退出；重新运行后任务成功
+func TestE2E_CancelAndRerun(t *testing.T) {
+	task, start := createBlocked(t, &pb.CreateTaskRequest{Name: "cancelled", TaskType: taskTypeGated})
+	w, _ := stack.watch(t, task.Id)
+
+	start()
+	w.expect(t, transition{pending, running})
+
+	if _, err := stack.client.UpdateTask(context.Background(), &pb.UpdateTaskRequest{Id: task.Id, Status: cancelled}); err != nil {
+		t.Fatalf("UpdateTask CANCELLED failed: %v", err)
+	}
+	w.expect(t, transition{running, cancelled})
+	stack.executor.waitCancelled(t, task.Id)
+
+	// 执行器退出后工作进程的失败结果因状态不匹配被丢弃
+	time.Sleep(200 * time.Millisecond)
+	if got := stack.waitForStatus(t, task.Id, cancelled); got.Status != cancelled {
+		t.Fatalf("Expected the task to stay CANCELLED, got %s", got.Status)
+	}
+
+	rerun, err := stack.client.RerunTask(context.Background(), &pb.RerunTaskRequest{Id: task.Id})
+	if err != nil {
+		t.Fatalf("RerunTask failed: %v", err)
+	}
+	if rerun.Id != task.Id {
+		t.Fatalf("Expected a reset rerun of %s, got %s", task.Id, rerun.Id)
+	}
+	w.expect(t, transition{cancelled, pending}, transition{pending, running})
+
+	stack.executor.release(task.Id)
+	w.expect(t, transition{running, succeeded})
+	if n := stack.executor.Attempts(task.Id); n != 1 {
+		t.Errorf("Expected the rerun to complete once, got %d attempts", n)
+	}
+}
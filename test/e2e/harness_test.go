@@ -0,0 +1,451 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"taskflow/engine"
+	pb "taskflow/proto"
+)
+
+// 连接已运行的服务时设置的环境变量，见包文档
+const (
+	envGRPCAddr = "TASKFLOW_E2E_GRPC_ADDR"
+	envHTTPAddr = "TASKFLOW_E2E_HTTP_ADDR"
+	envDBPath   = "TASKFLOW_E2E_DB_PATH"
+)
+
+// 测试执行器注册的任务类型
+const (
+	taskTypeEcho  = "e2e-echo"  // 立即成功；input_params.fail_times 指定前 N 次失败
+	taskTypeGated = "e2e-gated" // 阻塞直到 stack.release 放行该任务，期间发送心跳以感知服务端的取消
+)
+
+const (
+	waitTimeout    = 10 * time.Second
+	startupTimeout = 30 * time.Second
+)
+
+// stack 所有测试共享的服务和工作进程
+var stack *e2eStack
+
+// e2eStack 服务进程、gRPC 客户端和工作进程
+type e2eStack struct {
+	client   pb.TaskServiceClient
+	httpBase string
+	executor *testExecutor
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(runMain(m))
+}
+
+func runMain(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "taskflow-e2e-")
+	if err != nil {
+		log.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	grpcAddr, httpAddr, dbPath := os.Getenv(envGRPCAddr), os.Getenv(envHTTPAddr), os.Getenv(envDBPath)
+	if grpcAddr == "" || httpAddr == "" || dbPath == "" {
+		srv, err := startServer(dir)
+		if err != nil {
+			log.Fatalf("failed to start server: %v", err)
+		}
+		defer srv.stop()
+		grpcAddr, httpAddr, dbPath = srv.grpcAddr, srv.httpAddr, srv.dbPath
+	}
+	httpBase := "http://" + httpAddr
+	if err := waitHealthy(httpBase); err != nil {
+		log.Fatalf("server did not become healthy: %v", err)
+	}
+
+	// 服务只提供 API，任务由共享数据库的嵌入式引擎执行；服务写入的任务不会唤醒引擎，轮询间隔设短
+	worker, err := engine.New(engine.Options{
+		DSN:          dbPath + "?_busy_timeout=5000&_journal_mode=WAL",
+		PollInterval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		log.Fatalf("failed to create worker engine: %v", err)
+	}
+	executor := newTestExecutor()
+	worker.RegisterExecutor(taskTypeEcho, executor)
+	worker.RegisterExecutor(taskTypeGated, executor)
+	if err := worker.Start(context.Background()); err != nil {
+		log.Fatalf("failed to start worker engine: %v", err)
+	}
+	defer worker.Stop()
+	defer executor.releaseAll()
+
+	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("failed to dial %s: %v", grpcAddr, err)
+	}
+	defer conn.Close()
+
+	stack = &e2eStack{
+		client:   pb.NewTaskServiceClient(conn),
+		httpBase: httpBase,
+		executor: executor,
+	}
+	return m.Run()
+}
+
+// serverProcess 以子进程运行的服务
+type serverProcess struct {
+	cmd      *exec.Cmd
+	logPath  string
+	grpcAddr string
+	httpAddr string
+	dbPath   string
+	exited   chan struct{}
+}
+
+// startServer 编译服务并在 dir 中以空闲端口和临时数据库启动；工作目录设为 dir，不读取仓库中的配置文件
+func startServer(dir string) (*serverProcess, error) {
+	bin := filepath.Join(dir, "taskflow")
+	build := exec.Command("go", "build", "-o", bin, "taskflow")
+	if out, err := build.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("go build: %v\n%s", err, out)
+	}
+
+	grpcPort, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	httpPort, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	srv := &serverProcess{
+		logPath:  filepath.Join(dir, "server.log"),
+		grpcAddr: "127.0.0.1:" + strconv.Itoa(grpcPort),
+		httpAddr: "127.0.0.1:" + strconv.Itoa(httpPort),
+		dbPath:   filepath.Join(dir, "taskflow.db"),
+		exited:   make(chan struct{}),
+	}
+	logFile, err := os.Create(srv.logPath)
+	if err != nil {
+		return nil, err
+	}
+	defer logFile.Close()
+
+	srv.cmd = exec.Command(bin)
+	srv.cmd.Dir = dir
+	srv.cmd.Env = append(os.Environ(),
+		"TASKFLOW_GRPC_ADDR=:"+strconv.Itoa(grpcPort),
+		"TASKFLOW_HTTP_ADDR=:"+strconv.Itoa(httpPort),
+		"TASKFLOW_DB_PATH="+srv.dbPath,
+		"TASKFLOW_STORAGE=sqlite",
+	)
+	srv.cmd.Stdout = logFile
+	srv.cmd.Stderr = logFile
+	if err := srv.cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		srv.cmd.Wait()
+		close(srv.exited)
+	}()
+	return srv, nil
+}
+
+// stop 发送 SIGTERM 等待服务优雅退出，超时后强制结束
+func (s *serverProcess) stop() {
+	s.cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-s.exited:
+	case <-time.After(15 * time.Second):
+		s.cmd.Process.Kill()
+		<-s.exited
+		if out, err := os.ReadFile(s.logPath); err == nil {
+			log.Printf("server did not stop on SIGTERM, log:\n%s", out)
+		}
+	}
+}
+
+// freePort 由系统分配一个空闲端口
+func freePort() (int, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer lis.Close()
+	return lis.Addr().(*net.TCPAddr).Port, nil
+}
+
+// waitHealthy 轮询 /health 直到服务就绪
+func waitHealthy(base string) error {
+	deadline := time.Now().Add(startupTimeout)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(base + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		lastErr = err
+		time.Sleep(100 * time.Millisecond)
+	}
+	return lastErr
+}
+
+// createTask 通过 gRPC 创建任务
+func (s *e2eStack) createTask(t *testing.T, req *pb.CreateTaskRequest) *pb.Task {
+	t.Helper()
+
+	task, err := s.client.CreateTask(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	return task
+}
+
+// waitForStatus 轮询直到任务进入指定状态
+func (s *e2eStack) waitForStatus(t *testing.T, id string, want pb.TaskStatus) *pb.Task {
+	t.Helper()
+
+	deadline := time.Now().Add(waitTimeout)
+	var last *pb.Task
+	for time.Now().Before(deadline) {
+		task, err := s.client.GetTask(context.Background(), &pb.GetTaskRequest{Id: id})
+		if err != nil {
+			t.Fatalf("GetTask failed: %v", err)
+		}
+		if task.Status == want {
+			return task
+		}
+		last = task
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("task %s did not reach %s, last status %s", id, want, last.GetStatus())
+	return nil
+}
+
+// watcher 在后台接收 WatchTask 事件
+type watcher struct {
+	events chan *pb.TaskChangeEvent
+	err    chan error
+}
+
+// watch 订阅任务的变更，收到初始事件（订阅已注册）后返回；任务须处于尚不会被调度的状态，否则初始事件之前的变更会丢失
+func (s *e2eStack) watch(t *testing.T, id string) (*watcher, *pb.TaskChangeEvent) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stream, err := s.client.WatchTask(ctx, &pb.WatchTaskRequest{TaskIds: []string{id}, IncludeInitial: true})
+	if err != nil {
+		t.Fatalf("WatchTask failed: %v", err)
+	}
+	initial, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv initial failed: %v", err)
+	}
+	if initial.ChangeType != "initial" || initial.TaskId != id {
+		t.Fatalf("Unexpected initial event: %v", initial)
+	}
+
+	w := &watcher{events: make(chan *pb.TaskChangeEvent, 64), err: make(chan error, 1)}
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				w.err <- err
+				return
+			}
+			w.events <- event
+		}
+	}()
+	return w, initial
+}
+
+// transition 状态变更事件的起止状态
+type transition struct {
+	from, to pb.TaskStatus
+}
+
+func (tr transition) String() string {
+	return tr.from.String() + " -> " + tr.to.String()
+}
+
+// expect 按顺序接收状态变更事件，直到收到与 want 等长的序列，断言与 want 一致且续传令牌递增。
+// 发件箱投递的事件携带的是投递时的任务快照，可能已晚于事件本身的状态
+func (w *watcher) expect(t *testing.T, want ...transition) []*pb.TaskChangeEvent {
+	t.Helper()
+
+	var got []transition
+	var events []*pb.TaskChangeEvent
+	timeout := time.After(waitTimeout)
+	for len(got) < len(want) {
+		select {
+		case event := <-w.events:
+			if event.ChangeType != "status_changed" {
+				continue
+			}
+			if n := len(events); n > 0 && event.ResumeToken <= events[n-1].ResumeToken {
+				t.Errorf("Expected resume tokens to increase, got %d after %d", event.ResumeToken, events[n-1].ResumeToken)
+			}
+			events = append(events, event)
+			got = append(got, transition{event.FromStatus, event.ToStatus})
+		case err := <-w.err:
+			t.Fatalf("Watch stream ended after %v: %v", got, err)
+		case <-timeout:
+			t.Fatalf("Timed out waiting for %v, got %v", want, got)
+		}
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected transitions %v, got %v", want, got)
+		}
+	}
+	return events
+}
+
+// testExecutor 记录执行次数的测试执行器，e2e-gated 任务逐个放行
+type testExecutor struct {
+	mu        sync.Mutex
+	attempts  map[string]int
+	gates     map[string]chan struct{}
+	cancelled map[string]chan struct{}
+}
+
+func newTestExecutor() *testExecutor {
+	return &testExecutor{
+		attempts:  make(map[string]int),
+		gates:     make(map[string]chan struct{}),
+		cancelled: make(map[string]chan struct{}),
+	}
+}
+
+// Execute 实现 engine.Executor
+func (e *testExecutor) Execute(ctx context.Context, task *engine.Task) (map[string]string, error) {
+	ec := engine.ExecutionContextFrom(ctx)
+
+	if task.TaskType == taskTypeGated {
+		ec.Log("waiting for release")
+		// 服务端取消不经过本进程的调度器，执行器通过心跳发现任务已不在运行
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+	wait:
+		for {
+			select {
+			case <-e.gate(task.ID):
+				break wait
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-ticker.C:
+				if err := ec.Heartbeat(); errors.Is(err, engine.ErrStatusMismatch) {
+					e.markCancelled(task.ID)
+					return nil, err
+				}
+			}
+		}
+		ec.Log("released")
+	}
+
+	e.mu.Lock()
+	e.attempts[task.ID]++
+	attempt := e.attempts[task.ID]
+	e.mu.Unlock()
+
+	ec.Logf("attempt %d", attempt)
+
+	failTimes, _ := strconv.Atoi(task.InputParams["fail_times"])
+	if attempt <= failTimes {
+		return nil, fmt.Errorf("attempt %d failed", attempt)
+	}
+	return map[string]string{"attempt": strconv.Itoa(attempt)}, nil
+}
+
+func (e *testExecutor) gate(taskID string) chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.gates[taskID] == nil {
+		e.gates[taskID] = make(chan struct{})
+	}
+	return e.gates[taskID]
+}
+
+// release 放行 e2e-gated 任务（可重复调用）
+func (e *testExecutor) release(taskID string) {
+	gate := e.gate(taskID)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	select {
+	case <-gate:
+	default:
+		close(gate)
+	}
+}
+
+// releaseAll 放行所有等待中的任务
+func (e *testExecutor) releaseAll() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, gate := range e.gates {
+		select {
+		case <-gate:
+		default:
+			close(gate)
+		}
+	}
+}
+
+func (e *testExecutor) cancelledCh(taskID string) chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancelled[taskID] == nil {
+		e.cancelled[taskID] = make(chan struct{})
+	}
+	return e.cancelled[taskID]
+}
+
+func (e *testExecutor) markCancelled(taskID string) {
+	ch := e.cancelledCh(taskID)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+}
+
+// waitCancelled 等待执行器发现任务已被取消
+func (e *testExecutor) waitCancelled(t *testing.T, taskID string) {
+	t.Helper()
+	select {
+	case <-e.cancelledCh(taskID):
+	case <-time.After(waitTimeout):
+		t.Fatalf("executor of %s did not observe the cancellation", taskID)
+	}
+}
+
+// Attempts 任务执行次数
+func (e *testExecutor) Attempts(taskID string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.attempts[taskID]
+}